@@ -7,19 +7,21 @@ import (
 	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"syscall"
 
 	"github.com/gorilla/mux"
 	"github.com/grafana/agent/pkg/logs"
 	"github.com/grafana/agent/pkg/metrics"
+	"github.com/grafana/agent/pkg/metrics/cluster/configapi"
 	"github.com/grafana/agent/pkg/metrics/instance"
+	"github.com/grafana/agent/pkg/profiles"
 	"github.com/grafana/agent/pkg/traces"
 	"github.com/grafana/agent/pkg/util"
 	"github.com/grafana/agent/pkg/util/server"
 	"github.com/oklog/run"
 	"google.golang.org/grpc"
-	"gopkg.in/yaml.v2"
 
 	"github.com/grafana/agent/pkg/config"
 	"github.com/prometheus/client_golang/prometheus"
@@ -41,6 +43,7 @@ type Entrypoint struct {
 	promMetrics  *metrics.Agent
 	lokiLogs     *logs.Logs
 	tempoTraces  *traces.Traces
+	profiles     *profiles.Profiles
 	integrations config.Integrations
 
 	reloadListener net.Listener
@@ -90,6 +93,11 @@ func NewEntrypoint(logger *util.Logger, cfg *config.Config, reloader Reloader) (
 		return nil, err
 	}
 
+	ep.profiles, err = profiles.New(prometheus.DefaultRegisterer, cfg.Profiles, logger)
+	if err != nil {
+		return nil, err
+	}
+
 	integrationGlobals, err := ep.createIntegrationsGlobals(cfg)
 	if err != nil {
 		return nil, err
@@ -165,6 +173,11 @@ func (ep *Entrypoint) ApplyConfig(cfg config.Config) error {
 		failed = true
 	}
 
+	if err := ep.profiles.ApplyConfig(cfg.Profiles); err != nil {
+		level.Error(ep.log).Log("msg", "failed to update profiles", "err", err)
+		failed = true
+	}
+
 	integrationGlobals, err := ep.createIntegrationsGlobals(&cfg)
 	if err != nil {
 		level.Error(ep.log).Log("msg", "failed to update integrations", "err", err)
@@ -206,28 +219,58 @@ func (ep *Entrypoint) wire(mux *mux.Router, grpc *grpc.Server) {
 		fmt.Fprintf(w, "Agent is Ready.\n")
 	})
 
-	mux.HandleFunc("/-/config", func(rw http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/-/config", ep.configHandler(func(cfg *config.Config) ([]byte, error) {
+		return cfg.MarshalOriginal()
+	})).Methods("GET")
+
+	mux.HandleFunc("/-/config/effective", ep.configHandler(func(cfg *config.Config) ([]byte, error) {
+		hostname, err := instance.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("getting hostname: %w", err)
+		}
+		return cfg.MarshalEffective(hostname)
+	})).Methods("GET")
+
+	mux.HandleFunc("/-/reload", ep.reloadHandler).Methods("GET", "POST")
+}
+
+// configHandler returns a handler which writes the current config marshaled
+// by marshal. Secrets must be redacted by marshal.
+func (ep *Entrypoint) configHandler(marshal func(cfg *config.Config) ([]byte, error)) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
 		ep.mut.Lock()
 		cfg := ep.cfg
 		ep.mut.Unlock()
 
-		if cfg.EnableConfigEndpoints {
-			bb, err := yaml.Marshal(cfg)
-			if err != nil {
-				http.Error(rw, fmt.Sprintf("failed to marshal config: %s", err), http.StatusInternalServerError)
-			} else {
-				_, _ = rw.Write(bb)
-			}
-		} else {
+		if !cfg.EnableConfigEndpoints {
 			rw.WriteHeader(http.StatusNotFound)
 			_, _ = rw.Write([]byte("404 - config endpoint is disabled"))
+			return
 		}
-	})
 
-	mux.HandleFunc("/-/reload", ep.reloadHandler).Methods("GET", "POST")
+		bb, err := marshal(&cfg)
+		if err != nil {
+			http.Error(rw, fmt.Sprintf("failed to marshal config: %s", err), http.StatusInternalServerError)
+			return
+		}
+		rw.Header().Set("Content-Type", "text/yaml; charset=utf-8")
+		_, _ = rw.Write(bb)
+	}
 }
 
 func (ep *Entrypoint) reloadHandler(rw http.ResponseWriter, r *http.Request) {
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			_ = configapi.WriteError(rw, http.StatusBadRequest, fmt.Errorf("invalid dry_run value %q", v))
+			return
+		}
+		if dryRun {
+			ep.dryRunReload(rw)
+			return
+		}
+	}
+
 	success := ep.TriggerReload()
 	if success {
 		rw.WriteHeader(http.StatusOK)
@@ -236,6 +279,36 @@ func (ep *Entrypoint) reloadHandler(rw http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// dryRunReload re-requests the config file and writes the changes applying
+// it would make, without applying it.
+func (ep *Entrypoint) dryRunReload(rw http.ResponseWriter) {
+	level.Info(ep.log).Log("msg", "dry run reload of config file requested")
+
+	cfg, err := ep.reloader()
+	if err != nil {
+		level.Error(ep.log).Log("msg", "failed to reload config file", "err", err)
+		_ = configapi.WriteError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	hostname, err := instance.Hostname()
+	if err != nil {
+		_ = configapi.WriteError(rw, http.StatusInternalServerError, fmt.Errorf("getting hostname: %w", err))
+		return
+	}
+
+	ep.mut.Lock()
+	current := ep.cfg
+	ep.mut.Unlock()
+
+	diff, err := config.DiffConfigs(&current, cfg, hostname)
+	if err != nil {
+		_ = configapi.WriteError(rw, http.StatusBadRequest, err)
+		return
+	}
+	_ = configapi.WriteResponse(rw, http.StatusOK, diff)
+}
+
 // TriggerReload will cause the Entrypoint to re-request the config file and
 // apply the latest config. TriggerReload returns true if the reload was
 // successful.
@@ -267,6 +340,7 @@ func (ep *Entrypoint) Stop() {
 	ep.lokiLogs.Stop()
 	ep.promMetrics.Stop()
 	ep.tempoTraces.Stop()
+	ep.profiles.Stop()
 	ep.srv.Close()
 
 	if ep.reloadServer != nil {
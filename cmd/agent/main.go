@@ -8,6 +8,7 @@ import (
 	util_log "github.com/cortexproject/cortex/pkg/util/log"
 	"github.com/go-kit/log/level"
 	"github.com/grafana/agent/pkg/config"
+	"github.com/grafana/agent/pkg/integrations/node_exporter"
 	"github.com/grafana/agent/pkg/util"
 	"github.com/weaveworks/common/logging"
 
@@ -29,6 +30,10 @@ func init() {
 }
 
 func main() {
+	// Run the node_exporter collectors instead of the agent if the agent
+	// re-executed itself as a node_exporter child process.
+	node_exporter.MaybeRunChild()
+
 	// If Windows is trying to run us as a service, go through that
 	// path instead.
 	if IsWindowsService() {
@@ -62,6 +67,7 @@ func main() {
 	// Subsequent reloads will use cfgLogger.
 	cfgLogger = util.GoKitLogger(logger)
 	cfg.Server.Log = cfgLogger
+	cfg.LogDeprecations(logger)
 
 	ep, err := NewEntrypoint(logger, cfg, reloader)
 	if err != nil {
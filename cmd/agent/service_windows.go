@@ -58,6 +58,7 @@ func (m *AgentService) Execute(args []string, serviceRequests <-chan svc.ChangeR
 	// Subsequent reloads will use cfgLogger.
 	cfgLogger = util.GoKitLogger(logger)
 	cfg.Server.Log = cfgLogger
+	cfg.LogDeprecations(logger)
 
 	entrypointExit := make(chan error)
 
@@ -3,6 +3,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -14,7 +15,10 @@ import (
 	_ "github.com/grafana/agent/pkg/build"
 	"github.com/grafana/agent/pkg/client/grafanacloud"
 	"github.com/grafana/agent/pkg/config"
+	integrations_config "github.com/grafana/agent/pkg/integrations/config"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
 	"github.com/olekukonko/tablewriter"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/common/version"
 
 	"github.com/go-kit/log"
@@ -55,7 +59,11 @@ func main() {
 	cmd.AddCommand(
 		configSyncCmd(),
 		configCheckCmd(),
+		testIntegrationCmd(),
+		testLogsPipelineCmd(),
 		walStatsCmd(),
+		walInspectCmd(),
+		walRepairCmd(),
 		targetStatsCmd(),
 		samplesCmd(),
 		operatorDetachCmd(),
@@ -116,14 +124,16 @@ func configCheckCmd() *cobra.Command {
 		Use:   "config-check [config file]",
 		Short: "Perform basic validation of the given Agent configuration file",
 		Long: `config-check performs basic syntactic validation of the given Agent configuration
-file. The file is checked to ensure the types match the expected configuration types. Optionally,
-${var} style substitutions can be expanded based on the values of the environmental variables.
+file. The file is checked to ensure the types match the expected configuration types. Like the
+Agent, ${var} style substitutions are expanded based on the values of the environmental variables
+unless --expand-env=false is passed.
 
 If the configuration file is valid the exit code will be 0. If the configuration file is invalid
 the exit code will be 1.`,
 		Args: cobra.ExactArgs(1),
-		Run: func(_ *cobra.Command, args []string) {
+		Run: func(cmd *cobra.Command, args []string) {
 			file := args[0]
+			warnExpandEnvDefault(cmd)
 
 			cfg := config.Config{}
 			err := config.LoadFile(file, expandEnv, &cfg)
@@ -136,10 +146,168 @@ the exit code will be 1.`,
 		},
 	}
 
-	cmd.Flags().BoolVarP(&expandEnv, "expand-env", "e", false, "expands ${var} in config according to the values of the environment variables")
+	addExpandEnvFlag(cmd, &expandEnv)
 	return cmd
 }
 
+func testIntegrationCmd() *cobra.Command {
+	var (
+		expandEnv         bool
+		integrationsNext  bool
+		instanceName      string
+		wait              time.Duration
+		timeout           time.Duration
+		integrationsFlags integrations_config.Flags
+	)
+
+	cmd := &cobra.Command{
+		Use:   "test-integration [config file] [integration name]",
+		Short: "Run an integration from an Agent configuration file and scrape it once",
+		Long: `test-integration creates the integration with the given name from the
+integrations block of an Agent configuration file, runs it, and scrapes its
+metrics once. The scraped samples are printed to stdout, and logs of the
+integration are printed to stderr. This can be used to debug the credentials or
+connection settings of an integration without running an Agent.
+
+If the integration is configured multiple times, --instance must select the
+instance to test by its instance name. Only integrations which expose metrics
+can be tested.
+
+The exit code will be 1 if the integration could not be created or scraped.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			file, name := args[0], args[1]
+			logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+			warnExpandEnvDefault(cmd)
+
+			cfg := config.Config{}
+			if err := config.LoadFile(file, expandEnv, &cfg); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to load config: %s\n", err)
+				os.Exit(1)
+			}
+			cfg.Integrations.Flags = integrationsFlags
+
+			hostname, err := os.Hostname()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to get hostname: %s\n", err)
+				os.Exit(1)
+			}
+			globals := integrations_v2.Globals{
+				AgentIdentifier: fmt.Sprintf("%s:%d", hostname, cfg.Server.HTTPListenPort),
+				SubsystemOpts:   integrations_v2.DefaultSubsystemOptions,
+			}
+			globals.SubsystemOpts.Flags = integrationsFlags
+
+			ic, err := agentctl.IntegrationConfig(&cfg, integrationsNext, name, instanceName, globals)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+				os.Exit(1)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), wait+timeout)
+			defer cancel()
+
+			if err := agentctl.TestIntegration(ctx, logger, ic, globals, wait, os.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "integration test failed: %s\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	addExpandEnvFlag(cmd, &expandEnv)
+	cmd.Flags().BoolVar(&integrationsNext, "integrations-next", false, "read the integrations block using the integrations-next schema")
+	cmd.Flags().StringVarP(&instanceName, "instance", "i", "", "instance name of the integration to test")
+	cmd.Flags().DurationVarP(&wait, "wait", "w", 0, "how long to run the integration before scraping it")
+	cmd.Flags().DurationVarP(&timeout, "timeout", "t", time.Minute, "timeout for scraping the integration")
+
+	// Integrations read some settings from the command line flags of the
+	// Agent, which are accepted with the same names.
+	fs := flag.NewFlagSet("integrations", flag.ContinueOnError)
+	integrationsFlags.RegisterFlags(fs)
+	cmd.Flags().AddGoFlagSet(fs)
+	return cmd
+}
+
+func testLogsPipelineCmd() *cobra.Command {
+	var (
+		expandEnv    bool
+		instanceName string
+		lineLabels   map[string]string
+	)
+
+	cmd := &cobra.Command{
+		Use:   "test-logs-pipeline [config file] [job name]",
+		Short: "Run log lines through the pipeline of a logs job from an Agent configuration file",
+		Long: `test-logs-pipeline creates the pipeline stages of the logs job with the given
+name from the logs block of an Agent configuration file and runs log lines read
+from stdin through them, one line per entry. The timestamp, labels, and line of
+each entry are printed to stdout before the first stage and after each stage,
+along with the values extracted by the stages. Nothing is sent to Loki.
+
+Every stage processes all lines before the next stage starts, so lines joined
+by a multiline stage are flushed after the stage.
+
+If the job name is used by multiple logs configs, --instance must select the
+logs config by its name. Labels which would be discovered for the job can be
+set with --label.
+
+Example:
+
+$ echo 'level=info msg="started"' | agentctl test-logs-pipeline -l job=app agent.yaml app`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			file, jobName := args[0], args[1]
+			logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+			warnExpandEnvDefault(cmd)
+
+			cfg := config.Config{}
+			if err := config.LoadFile(file, expandEnv, &cfg); err != nil {
+				fmt.Fprintf(os.Stderr, "failed to load config: %s\n", err)
+				os.Exit(1)
+			}
+
+			p, err := agentctl.LogsPipeline(logger, &cfg, instanceName, jobName)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to create pipeline: %s\n", err)
+				os.Exit(1)
+			}
+
+			lbls := make(model.LabelSet, len(lineLabels))
+			for name, value := range lineLabels {
+				lbls[model.LabelName(name)] = model.LabelValue(value)
+			}
+			if err := lbls.Validate(); err != nil {
+				fmt.Fprintf(os.Stderr, "invalid labels: %s\n", err)
+				os.Exit(1)
+			}
+
+			if err := agentctl.TestLogsPipeline(p, lbls, os.Stdin, os.Stdout); err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	addExpandEnvFlag(cmd, &expandEnv)
+	cmd.Flags().StringVarP(&instanceName, "instance", "i", "", "name of the logs config of the job")
+	cmd.Flags().StringToStringVarP(&lineLabels, "label", "l", nil, "label to add to every log line, in name=value form")
+	return cmd
+}
+
+// addExpandEnvFlag adds the --expand-env flag to cmd. Like the Agent's
+// -config.expand-env flag, it defaults to true.
+func addExpandEnvFlag(cmd *cobra.Command, expandEnv *bool) {
+	cmd.Flags().BoolVarP(expandEnv, "expand-env", "e", true, "expands ${var} in config according to the values of the environment variables")
+}
+
+// warnExpandEnvDefault prints the same warning as the Agent when --expand-env
+// wasn't set explicitly.
+func warnExpandEnvDefault(cmd *cobra.Command) {
+	if !cmd.Flags().Changed("expand-env") {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", config.ExpandEnvDefaultWarning("--expand-env"))
+	}
+}
+
 func samplesCmd() *cobra.Command {
 	var selector string
 
@@ -322,6 +490,137 @@ deletion but then comes back at some point).`,
 	}
 }
 
+func walInspectCmd() *cobra.Command {
+	var topN int
+
+	cmd := &cobra.Command{
+		Use:   "wal-inspect [WAL directory]",
+		Short: "Dump the contents of the WAL",
+		Long: `wal-inspect reads a WAL directory and describes its checkpoint, its segment
+files, the number of series, samples, and exemplars it holds, and the metrics
+and labels with the highest cardinality.
+
+The cardinality of a metric is its number of series. The cardinality of a label
+is its number of unique values across all series.`,
+		Args: cobra.ExactArgs(1),
+
+		Run: func(_ *cobra.Command, args []string) {
+			directory := args[0]
+			if _, err := os.Stat(directory); os.IsNotExist(err) {
+				fmt.Printf("%s does not exist\n", directory)
+				os.Exit(1)
+			} else if err != nil {
+				fmt.Printf("error getting wal: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Check if ./wal is a subdirectory, use that instead.
+			if _, err := os.Stat(filepath.Join(directory, "wal")); err == nil {
+				directory = filepath.Join(directory, "wal")
+			}
+
+			res, err := agentctl.InspectWAL(directory, topN)
+			if err != nil {
+				fmt.Printf("failed to inspect WAL: %v\n", err)
+				os.Exit(1)
+			}
+
+			if res.Checkpoint != nil {
+				fmt.Printf("Checkpoint:         %s (%d bytes)\n", res.Checkpoint.Name, res.Checkpoint.Size)
+			} else {
+				fmt.Printf("Checkpoint:         none\n")
+			}
+			fmt.Printf("Total Series:       %d\n", res.Series)
+			fmt.Printf("Total Samples:      %d\n", res.Samples)
+			fmt.Printf("Total Exemplars:    %d\n", res.Exemplars)
+
+			fmt.Printf("\nSegments:\n")
+			segmentsTable := tablewriter.NewWriter(os.Stdout)
+			segmentsTable.SetHeader([]string{"Segment", "Size"})
+			for _, s := range res.Segments {
+				segmentsTable.Append([]string{fmt.Sprintf("%d", s.Index), fmt.Sprintf("%d", s.Size)})
+			}
+			segmentsTable.Render()
+
+			fmt.Printf("\nTop metrics by series:\n")
+			metricsTable := tablewriter.NewWriter(os.Stdout)
+			metricsTable.SetHeader([]string{"Metric", "Series"})
+			for _, m := range res.TopMetrics {
+				metricsTable.Append([]string{m.Metric, fmt.Sprintf("%d", m.Instances)})
+			}
+			metricsTable.Render()
+
+			fmt.Printf("\nTop labels by unique values:\n")
+			labelsTable := tablewriter.NewWriter(os.Stdout)
+			labelsTable.SetHeader([]string{"Label", "Values"})
+			for _, l := range res.TopLabels {
+				labelsTable.Append([]string{l.Name, fmt.Sprintf("%d", l.Values)})
+			}
+			labelsTable.Render()
+		},
+	}
+
+	cmd.Flags().IntVarP(&topN, "top", "n", 10, "number of metrics and labels to show")
+	return cmd
+}
+
+func walRepairCmd() *cobra.Command {
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "wal-repair [WAL directory]",
+		Short: "Repair a corrupted WAL",
+		Long: `wal-repair reads a WAL directory and checks its segments for corruption. The
+first corrupted segment is truncated at the corruption, and all segments after
+it are deleted. Samples in the deleted data are lost, but the rest of the WAL
+is kept.
+
+The agent using the WAL must be stopped before repairing it.
+
+Corrupted checkpoints can't be repaired. If the checkpoint is corrupted, it
+must be deleted manually.`,
+		Args: cobra.ExactArgs(1),
+
+		Run: func(_ *cobra.Command, args []string) {
+			directory := args[0]
+			if _, err := os.Stat(directory); os.IsNotExist(err) {
+				fmt.Printf("%s does not exist\n", directory)
+				os.Exit(1)
+			} else if err != nil {
+				fmt.Printf("error getting wal: %v\n", err)
+				os.Exit(1)
+			}
+
+			// Check if ./wal is a subdirectory, use that instead.
+			if _, err := os.Stat(filepath.Join(directory, "wal")); err == nil {
+				directory = filepath.Join(directory, "wal")
+			}
+
+			logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+
+			res, err := agentctl.RepairWAL(logger, directory, dryRun)
+			if err != nil {
+				fmt.Printf("failed to repair WAL: %v\n", err)
+				os.Exit(1)
+			}
+			if res.Corruption == nil {
+				fmt.Println("WAL is not corrupted")
+				return
+			}
+
+			fmt.Printf("Corruption:         %v\n", res.Corruption)
+			fmt.Printf("Truncated Segment:  %d\n", res.Corruption.Segment)
+			fmt.Printf("Deleted Segments:   %v\n", res.DeletedSegments)
+			if dryRun {
+				fmt.Println("\nDry run, the WAL was not modified")
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "only report corruption, without modifying the WAL")
+	return cmd
+}
+
 func operatorDetachCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "operator-detach",
@@ -0,0 +1,189 @@
+package agentctl
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/config"
+	v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/common"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+)
+
+// testPrefix is the prefix the HTTP handlers of tested integrations are
+// exposed at.
+const testPrefix = "/integrations/test/"
+
+// IntegrationConfig returns the config of the integration called name from
+// the integrations block of cfg. The block is unmarshaled as an
+// integrations-next block when next is true. Configs of the original
+// integrations are converted into integrations-next configs.
+//
+// instance selects the instance of the integration by its identifier when
+// the integration is configured more than once.
+func IntegrationConfig(cfg *config.Config, next bool, name, instance string, globals v2.Globals) (v2.Config, error) {
+	if cfg.Integrations.IsZero() {
+		return nil, fmt.Errorf("integration %q not found", name)
+	}
+
+	// The integrations block isn't unmarshaled when loading the config, as the
+	// schema depends on command line flags.
+	var configs v2.Configs
+	if next {
+		opts, err := cfg.Integrations.ConfigV2()
+		if err != nil {
+			return nil, fmt.Errorf("invalid integrations: %w", err)
+		}
+		configs = opts.Configs
+	} else {
+		mcfg, err := cfg.Integrations.ConfigV1()
+		if err != nil {
+			return nil, fmt.Errorf("invalid integrations: %w", err)
+		}
+		for _, ic := range mcfg.Integrations {
+			mc := common.MetricsConfig{InstanceKey: ic.Common.InstanceKey}
+			configs = append(configs, metricsutils.CreateShim(ic.Config, mc))
+		}
+	}
+
+	var (
+		found       []v2.Config
+		identifiers []string
+	)
+	for _, c := range configs {
+		if c.Name() != name {
+			continue
+		}
+		id, err := c.Identifier(globals)
+		if err != nil {
+			return nil, fmt.Errorf("could not build identifier for integration %q: %w", name, err)
+		}
+		if instance != "" && id != instance {
+			continue
+		}
+		found = append(found, c)
+		identifiers = append(identifiers, id)
+	}
+
+	switch {
+	case len(found) == 0 && instance != "":
+		return nil, fmt.Errorf("instance %q of integration %q not found", instance, name)
+	case len(found) == 0:
+		return nil, fmt.Errorf("integration %q not found", name)
+	case len(found) > 1:
+		sort.Strings(identifiers)
+		return nil, fmt.Errorf("integration %q has multiple instances, select one of %s", name, strings.Join(identifiers, ", "))
+	}
+	return found[0], nil
+}
+
+// TestIntegration runs the integration created by c and scrapes each of its
+// targets once, writing the scraped samples to w. Scraping starts after wait
+// has elapsed, which allows integrations that collect metrics in the
+// background to run first. Only integrations which expose metrics can be
+// tested.
+func TestIntegration(ctx context.Context, l log.Logger, c v2.Config, globals v2.Globals, wait time.Duration, w io.Writer) error {
+	if err := c.ApplyDefaults(globals); err != nil {
+		return fmt.Errorf("failed to apply defaults: %w", err)
+	}
+	i, err := c.NewIntegration(l, globals)
+	if err != nil {
+		return fmt.Errorf("failed to create integration: %w", err)
+	}
+
+	mi, ok := i.(v2.MetricsIntegration)
+	if !ok {
+		return fmt.Errorf("integration %q does not expose metrics", c.Name())
+	}
+	hi, ok := i.(v2.HTTPIntegration)
+	if !ok {
+		return fmt.Errorf("integration %q does not expose metrics", c.Name())
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- i.RunIntegration(ctx)
+	}()
+
+	select {
+	case <-time.After(wait):
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-exited:
+		return fmt.Errorf("integration exited: %w", err)
+	}
+
+	handler, err := hi.Handler(testPrefix)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP handler: %w", err)
+	}
+	var firstErr error
+	for _, group := range mi.Targets(v2.Endpoint{Host: "localhost", Prefix: testPrefix}) {
+		for _, target := range group.Targets {
+			if err := scrapeTarget(ctx, handler, target, w); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	cancel()
+	if err := <-exited; err != nil && firstErr == nil {
+		firstErr = fmt.Errorf("integration exited: %w", err)
+	}
+	return firstErr
+}
+
+// scrapeTarget scrapes target from handler and writes the scraped samples to
+// w.
+func scrapeTarget(ctx context.Context, handler http.Handler, target model.LabelSet, w io.Writer) error {
+	params := url.Values{}
+	for name, value := range target {
+		if strings.HasPrefix(string(name), model.ParamLabelPrefix) {
+			params.Set(strings.TrimPrefix(string(name), model.ParamLabelPrefix), string(value))
+		}
+	}
+	u := url.URL{Path: string(target[model.MetricsPathLabel]), RawQuery: params.Encode()}
+
+	fmt.Fprintf(w, "# Scraping %s\n", u.String())
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, u.String(), nil).WithContext(ctx)
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return fmt.Errorf("scraping %s failed with status %d: %s", u.String(), rec.Code, strings.TrimSpace(rec.Body.String()))
+	}
+
+	dec := &expfmt.SampleDecoder{
+		Dec:  expfmt.NewDecoder(rec.Body, expfmt.ResponseFormat(rec.Header())),
+		Opts: &expfmt.DecodeOptions{Timestamp: model.Now()},
+	}
+	var samples model.Vector
+	for {
+		var v model.Vector
+		if err := dec.Decode(&v); err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("invalid metrics from %s: %w", u.String(), err)
+		}
+		samples = append(samples, v...)
+	}
+
+	for _, s := range samples {
+		fmt.Fprintf(w, "%s %s\n", s.Metric, s.Value)
+	}
+	fmt.Fprintf(w, "# %d samples\n", len(samples))
+	return nil
+}
@@ -0,0 +1,59 @@
+package agentctl
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/config"
+	v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/stretchr/testify/require"
+
+	// Register the agent integration for both subsystems.
+	_ "github.com/grafana/agent/pkg/integrations/agent"
+	_ "github.com/grafana/agent/pkg/integrations/v2/agent"
+)
+
+func TestTestIntegration(t *testing.T) {
+	globals := v2.Globals{
+		AgentIdentifier: "localhost:12345",
+		SubsystemOpts:   v2.DefaultSubsystemOptions,
+	}
+
+	tt := []struct {
+		name string
+		next bool
+		cfg  string
+	}{
+		{name: "integrations", cfg: "integrations: {agent: {enabled: true}}"},
+		{name: "integrations-next", next: true, cfg: "integrations: {agent: {}}"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg config.Config
+			require.NoError(t, config.LoadBytes([]byte(tc.cfg), false, &cfg))
+
+			c, err := IntegrationConfig(&cfg, tc.next, "agent", "", globals)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			err = TestIntegration(context.Background(), log.NewNopLogger(), c, globals, 0, &buf)
+			require.NoError(t, err)
+			require.Contains(t, buf.String(), "# Scraping /integrations/test/metrics\n")
+			require.Contains(t, buf.String(), "go_goroutines ")
+		})
+	}
+}
+
+func TestIntegrationConfig_NotFound(t *testing.T) {
+	var cfg config.Config
+	require.NoError(t, config.LoadBytes([]byte("integrations: {agent: {enabled: true}}"), false, &cfg))
+
+	_, err := IntegrationConfig(&cfg, false, "node_exporter", "", v2.Globals{})
+	require.EqualError(t, err, `integration "node_exporter" not found`)
+
+	_, err = IntegrationConfig(&cfg, false, "agent", "other:12345", v2.Globals{AgentIdentifier: "localhost:12345"})
+	require.EqualError(t, err, `instance "other:12345" of integration "agent" not found`)
+}
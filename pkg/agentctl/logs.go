@@ -0,0 +1,117 @@
+package agentctl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/config"
+	"github.com/grafana/agent/pkg/logs"
+	"github.com/grafana/agent/pkg/logs/stages"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// LogsPipeline creates the pipeline of the logs job called jobName from the
+// logs block of cfg.
+//
+// instance selects the logs config by name when more than one logs config
+// has a job called jobName.
+func LogsPipeline(l log.Logger, cfg *config.Config, instance, jobName string) (*stages.Pipeline, error) {
+	if cfg.Logs == nil {
+		return nil, fmt.Errorf("logs job %q not found", jobName)
+	}
+
+	var (
+		found stages.PipelineStages
+		names []string
+	)
+	for _, ic := range cfg.Logs.Configs {
+		if instance != "" && ic.Name != instance {
+			continue
+		}
+		stgs, err := ic.PipelineStages(jobName)
+		if errors.Is(err, logs.ErrJobNotFound) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("logs config %s: %w", ic.Name, err)
+		}
+		found = stgs
+		names = append(names, ic.Name)
+	}
+
+	switch {
+	case len(names) == 0 && instance != "":
+		return nil, fmt.Errorf("logs job %q not found in logs config %q", jobName, instance)
+	case len(names) == 0:
+		return nil, fmt.Errorf("logs job %q not found", jobName)
+	case len(names) > 1:
+		sort.Strings(names)
+		return nil, fmt.Errorf("logs job %q is in multiple logs configs, select one of %s", jobName, strings.Join(names, ", "))
+	}
+
+	return stages.NewPipeline(l, found, &jobName, prometheus.NewRegistry())
+}
+
+// TestLogsPipeline runs each line read from r through p as a log line with
+// the given labels and writes the entries after each stage to w. Every stage
+// processes all lines before the next stage starts.
+func TestLogsPipeline(p *stages.Pipeline, lbls model.LabelSet, r io.Reader, w io.Writer) error {
+	var (
+		entries []api.Entry
+		now     = time.Now()
+	)
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		entries = append(entries, api.Entry{
+			Labels: lbls.Clone(),
+			Entry:  logproto.Entry{Timestamp: now, Line: sc.Text()},
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("failed to read log lines: %w", err)
+	}
+
+	fmt.Fprintln(w, "# Input")
+	for _, e := range entries {
+		writeLogEntry(w, e, nil)
+	}
+
+	for i, step := range p.Trace(entries) {
+		fmt.Fprintf(w, "# Stage %d: %s\n", i+1, step.Stage)
+		for _, e := range step.Entries {
+			writeLogEntry(w, e.Entry, e.Extracted)
+		}
+		fmt.Fprintf(w, "# %d entries\n", len(step.Entries))
+	}
+	return nil
+}
+
+// writeLogEntry writes the timestamp, labels, and line of e to w, followed
+// by the extracted values of e, if any.
+func writeLogEntry(w io.Writer, e api.Entry, extracted map[string]interface{}) {
+	fmt.Fprintf(w, "%s %s %s\n", e.Timestamp.Format(time.RFC3339Nano), e.Labels, e.Line)
+	if len(extracted) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(extracted))
+	for k := range extracted {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s=%v", k, extracted[k]))
+	}
+	fmt.Fprintf(w, "  extracted: %s\n", strings.Join(pairs, " "))
+}
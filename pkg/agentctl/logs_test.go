@@ -0,0 +1,50 @@
+package agentctl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/logs/stages"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestTestLogsPipeline(t *testing.T) {
+	var stgs stages.PipelineStages
+	err := yaml.Unmarshal([]byte(`
+- json:
+    expressions:
+      level:
+      msg: message
+- labels:
+    level:
+- output:
+    source: msg
+`), &stgs)
+	require.NoError(t, err)
+
+	jobName := "test"
+	p, err := stages.NewPipeline(log.NewNopLogger(), stgs, &jobName, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	in := strings.NewReader(`{"level":"info","message":"started"}` + "\n")
+	err = TestLogsPipeline(p, model.LabelSet{"job": "test"}, in, &out)
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 14)
+	require.Equal(t, "# Input", lines[0])
+	require.True(t, strings.HasSuffix(lines[1], ` {job="test"} {"level":"info","message":"started"}`), lines[1])
+	require.Equal(t, "# Stage 1: json", lines[2])
+	require.Equal(t, "  extracted: job=test level=info msg=started", lines[4])
+	require.Equal(t, "# Stage 2: labels", lines[6])
+	require.True(t, strings.HasSuffix(lines[7], ` {job="test", level="info"} {"level":"info","message":"started"}`), lines[7])
+	require.Equal(t, "# Stage 3: output", lines[10])
+	require.True(t, strings.HasSuffix(lines[11], ` {job="test", level="info"} started`), lines[11])
+	require.Equal(t, "# 1 entries", lines[13])
+}
@@ -94,11 +94,13 @@ func TestConfigSync_DryRun(t *testing.T) {
 }
 
 type mockFuncPromClient struct {
-	InstancesFunc           func(ctx context.Context) ([]string, error)
-	ListConfigsFunc         func(ctx context.Context) (*configapi.ListConfigurationsResponse, error)
-	GetConfigurationFunc    func(ctx context.Context, name string) (*instance.Config, error)
-	PutConfigurationFunc    func(ctx context.Context, name string, cfg *instance.Config) error
-	DeleteConfigurationFunc func(ctx context.Context, name string) error
+	InstancesFunc            func(ctx context.Context) ([]string, error)
+	ListConfigsFunc          func(ctx context.Context) (*configapi.ListConfigurationsResponse, error)
+	GetConfigurationFunc     func(ctx context.Context, name string) (*instance.Config, error)
+	PutConfigurationFunc     func(ctx context.Context, name string, cfg *instance.Config) error
+	DeleteConfigurationFunc  func(ctx context.Context, name string) error
+	PutConfigurationsFunc    func(ctx context.Context, cfgs []*instance.Config, prune, dryRun bool) (*configapi.PutConfigurationsResponse, error)
+	ExportConfigurationsFunc func(ctx context.Context) ([]*instance.Config, error)
 }
 
 func (m mockFuncPromClient) Instances(ctx context.Context) ([]string, error) {
@@ -135,3 +137,17 @@ func (m mockFuncPromClient) DeleteConfiguration(ctx context.Context, name string
 	}
 	return errors.New("not implemented")
 }
+
+func (m mockFuncPromClient) PutConfigurations(ctx context.Context, cfgs []*instance.Config, prune, dryRun bool) (*configapi.PutConfigurationsResponse, error) {
+	if m.PutConfigurationsFunc != nil {
+		return m.PutConfigurationsFunc(ctx, cfgs, prune, dryRun)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m mockFuncPromClient) ExportConfigurations(ctx context.Context) ([]*instance.Config, error) {
+	if m.ExportConfigurationsFunc != nil {
+		return m.ExportConfigurationsFunc(ctx)
+	}
+	return nil, errors.New("not implemented")
+}
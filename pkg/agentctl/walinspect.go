@@ -0,0 +1,218 @@
+package agentctl
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/tsdb/fileutil"
+	"github.com/prometheus/prometheus/tsdb/record"
+	"github.com/prometheus/prometheus/tsdb/wal"
+)
+
+// WALInspection describes the contents of a WAL.
+type WALInspection struct {
+	// Checkpoint is the most recent checkpoint of the WAL. Checkpoint is nil if
+	// the WAL hasn't been checkpointed yet.
+	Checkpoint *WALCheckpoint
+
+	// Segments holds the non-checkpoint segment files of the WAL, ordered from
+	// oldest to newest.
+	Segments []WALSegment
+
+	// Series is the number of unique series defined across the WAL and its
+	// checkpoint.
+	Series int
+
+	// Samples is the number of samples across the WAL and its checkpoint.
+	Samples int
+
+	// Exemplars is the number of exemplars across the WAL and its checkpoint.
+	Exemplars int
+
+	// TopMetrics holds the metric names with the most series, ordered by
+	// descending number of series.
+	TopMetrics []Cardinality
+
+	// TopLabels holds the label names with the most unique values, ordered by
+	// descending number of values.
+	TopLabels []LabelCardinality
+}
+
+// WALCheckpoint describes a checkpoint directory of a WAL.
+type WALCheckpoint struct {
+	// Name of the checkpoint directory.
+	Name string
+
+	// Index is the index of the last segment included in the checkpoint.
+	Index int
+
+	// Size of the checkpoint in bytes.
+	Size int64
+}
+
+// WALSegment describes a segment file of a WAL.
+type WALSegment struct {
+	Index int
+	Size  int64
+}
+
+// LabelCardinality represents a label name and the number of unique values
+// it has across all series.
+type LabelCardinality struct {
+	Name   string
+	Values int
+}
+
+// InspectWAL reads the WAL in walDir and describes its contents. At most topN
+// metrics and labels are returned in the TopMetrics and TopLabels fields of
+// the result.
+func InspectWAL(walDir string, topN int) (WALInspection, error) {
+	var res WALInspection
+
+	w, err := wal.Open(nil, walDir)
+	if err != nil {
+		return res, err
+	}
+	defer w.Close()
+
+	checkpoint, checkpointIdx, err := wal.LastCheckpoint(walDir)
+	if err != nil && err != record.ErrNotFound {
+		return res, err
+	}
+	if checkpoint != "" {
+		size, err := fileutil.DirSize(checkpoint)
+		if err != nil {
+			return res, err
+		}
+		res.Checkpoint = &WALCheckpoint{
+			Name:  filepath.Base(checkpoint),
+			Index: checkpointIdx,
+			Size:  size,
+		}
+	}
+
+	first, last, err := wal.Segments(walDir)
+	if err != nil {
+		return res, err
+	}
+	for i := first; i <= last && i >= 0; i++ {
+		fi, err := os.Stat(wal.SegmentName(walDir, i))
+		if err != nil {
+			return res, err
+		}
+		res.Segments = append(res.Segments, WALSegment{Index: i, Size: fi.Size()})
+	}
+
+	ins := walInspector{
+		series:      make(map[uint64]struct{}),
+		metrics:     make(map[string]int),
+		labelValues: make(map[string]map[string]struct{}),
+	}
+	if err := walIterate(w, ins.readWAL); err != nil {
+		return res, err
+	}
+
+	res.Series = len(ins.series)
+	res.Samples = ins.samples
+	res.Exemplars = ins.exemplars
+
+	for metric, series := range ins.metrics {
+		res.TopMetrics = append(res.TopMetrics, Cardinality{Metric: metric, Instances: series})
+	}
+	sort.Slice(res.TopMetrics, func(i, j int) bool {
+		if res.TopMetrics[i].Instances != res.TopMetrics[j].Instances {
+			return res.TopMetrics[i].Instances > res.TopMetrics[j].Instances
+		}
+		return res.TopMetrics[i].Metric < res.TopMetrics[j].Metric
+	})
+	if len(res.TopMetrics) > topN {
+		res.TopMetrics = res.TopMetrics[:topN]
+	}
+
+	for name, values := range ins.labelValues {
+		res.TopLabels = append(res.TopLabels, LabelCardinality{Name: name, Values: len(values)})
+	}
+	sort.Slice(res.TopLabels, func(i, j int) bool {
+		if res.TopLabels[i].Values != res.TopLabels[j].Values {
+			return res.TopLabels[i].Values > res.TopLabels[j].Values
+		}
+		return res.TopLabels[i].Name < res.TopLabels[j].Name
+	})
+	if len(res.TopLabels) > topN {
+		res.TopLabels = res.TopLabels[:topN]
+	}
+
+	return res, nil
+}
+
+type walInspector struct {
+	samples   int
+	exemplars int
+
+	// Hashes of the label sets of all series. Series may be defined more than
+	// once across the WAL and its checkpoint, so they're deduplicated by their
+	// labels.
+	series map[uint64]struct{}
+
+	// metric name -> # of series
+	metrics map[string]int
+
+	// label name -> unique values
+	labelValues map[string]map[string]struct{}
+}
+
+func (ins *walInspector) readWAL(r *wal.Reader) error {
+	var dec record.Decoder
+
+	for r.Next() {
+		rec := r.Record()
+
+		switch dec.Type(rec) {
+		case record.Series:
+			series, err := dec.Series(rec, nil)
+			if err != nil {
+				return err
+			}
+			for _, s := range series {
+				ins.addSeries(s.Labels)
+			}
+		case record.Samples:
+			samples, err := dec.Samples(rec, nil)
+			if err != nil {
+				return err
+			}
+			ins.samples += len(samples)
+		case record.Exemplars:
+			exemplars, err := dec.Exemplars(rec, nil)
+			if err != nil {
+				return err
+			}
+			ins.exemplars += len(exemplars)
+		}
+	}
+
+	return r.Err()
+}
+
+func (ins *walInspector) addSeries(lset labels.Labels) {
+	hash := lset.Hash()
+	if _, ok := ins.series[hash]; ok {
+		return
+	}
+	ins.series[hash] = struct{}{}
+
+	ins.metrics[lset.Get(labels.MetricName)]++
+	for _, l := range lset {
+		if l.Name == labels.MetricName {
+			continue
+		}
+		values, ok := ins.labelValues[l.Name]
+		if !ok {
+			values = make(map[string]struct{})
+			ins.labelValues[l.Name] = values
+		}
+		values[l.Value] = struct{}{}
+	}
+}
@@ -0,0 +1,39 @@
+package agentctl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectWAL(t *testing.T) {
+	walDir := setupTestWAL(t)
+	res, err := InspectWAL(walDir, 3)
+	require.NoError(t, err)
+
+	require.NotNil(t, res.Checkpoint)
+	require.Equal(t, "checkpoint.00000001", res.Checkpoint.Name)
+	require.Equal(t, 1, res.Checkpoint.Index)
+
+	var segments []int
+	for _, s := range res.Segments {
+		segments = append(segments, s.Index)
+	}
+	require.Equal(t, []int{0, 1, 2, 3}, segments)
+
+	// The duplicate series in the test WAL is only counted once.
+	require.Equal(t, 20, res.Series)
+	require.Equal(t, 21, res.Samples)
+	require.Equal(t, 0, res.Exemplars)
+
+	require.Equal(t, []Cardinality{
+		{Metric: "metric_0", Instances: 2},
+		{Metric: "metric_1", Instances: 2},
+		{Metric: "metric_2", Instances: 2},
+	}, res.TopMetrics)
+	require.Equal(t, []LabelCardinality{
+		{Name: "initial", Values: 2},
+		{Name: "instance", Values: 1},
+		{Name: "job", Values: 1},
+	}, res.TopLabels)
+}
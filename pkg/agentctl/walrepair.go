@@ -0,0 +1,98 @@
+package agentctl
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/tsdb/record"
+	"github.com/prometheus/prometheus/tsdb/wal"
+)
+
+// WALRepair describes the repair of a WAL.
+type WALRepair struct {
+	// Corruption is the first corruption found in the segments of the WAL.
+	// Corruption is nil if the WAL isn't corrupted.
+	Corruption *wal.CorruptionErr
+
+	// DeletedSegments holds the indexes of the segments which were deleted
+	// because they came after the corrupted segment.
+	DeletedSegments []int
+}
+
+// RepairWAL checks the segments of the WAL in walDir for corruption. If a
+// corrupted segment is found, it is truncated at the corruption and all
+// segments after it are deleted. The WAL is left unmodified when dryRun is
+// true.
+//
+// Corrupted checkpoints can't be repaired, and RepairWAL returns an error if
+// the most recent checkpoint is corrupted.
+func RepairWAL(l log.Logger, walDir string, dryRun bool) (WALRepair, error) {
+	var res WALRepair
+
+	checkpoint, _, err := wal.LastCheckpoint(walDir)
+	if err != nil && err != record.ErrNotFound {
+		return res, err
+	}
+	if checkpoint != "" {
+		sr, err := wal.NewSegmentsReader(checkpoint)
+		if err != nil {
+			return res, err
+		}
+		err = readAll(wal.NewReader(sr))
+		_ = sr.Close()
+		if err != nil {
+			return res, fmt.Errorf("checkpoint %s is corrupted and can't be repaired, it must be deleted manually: %w", checkpoint, err)
+		}
+	}
+
+	first, last, err := wal.Segments(walDir)
+	if err != nil {
+		return res, err
+	}
+	for i := first; i <= last && i >= 0; i++ {
+		s, err := wal.OpenReadSegment(wal.SegmentName(walDir, i))
+		if err != nil {
+			return res, err
+		}
+		sr := wal.NewSegmentBufReader(s)
+		err = readAll(wal.NewReader(sr))
+		_ = sr.Close()
+
+		var cerr *wal.CorruptionErr
+		if errors.As(err, &cerr) {
+			res.Corruption = cerr
+			for j := i + 1; j <= last; j++ {
+				res.DeletedSegments = append(res.DeletedSegments, j)
+			}
+			break
+		} else if err != nil {
+			return res, err
+		}
+	}
+
+	if res.Corruption == nil || dryRun {
+		return res, nil
+	}
+
+	// Opening the WAL for writing creates a new segment after the last one,
+	// which is deleted by the repair along with the other segments after the
+	// corruption.
+	w, err := wal.NewSize(l, nil, walDir, wal.DefaultSegmentSize, true)
+	if err != nil {
+		return res, err
+	}
+	if err := w.Repair(res.Corruption); err != nil {
+		_ = w.Close()
+		return res, fmt.Errorf("failed to repair WAL: %w", err)
+	}
+	return res, w.Close()
+}
+
+// readAll reads all records from r, returning any corruption found.
+func readAll(r *wal.Reader) error {
+	for r.Next() {
+		// Only the error is of interest; records are discarded.
+	}
+	return r.Err()
+}
@@ -0,0 +1,69 @@
+package agentctl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/tsdb/record"
+	"github.com/prometheus/prometheus/tsdb/wal"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepairWAL(t *testing.T) {
+	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		os.RemoveAll(walDir)
+	})
+	walDir = filepath.Join(walDir, "wal")
+
+	// Write a record to each of three segments.
+	w, err := wal.NewSize(log.NewNopLogger(), nil, walDir, wal.DefaultSegmentSize, true)
+	require.NoError(t, err)
+	var encoder record.Encoder
+	for i := 0; i < 3; i++ {
+		buf := encoder.Series([]record.RefSeries{{
+			Ref:    uint64(i + 1),
+			Labels: labels.FromStrings("__name__", "metric", "i", string(rune('a'+i))),
+		}}, nil)
+		require.NoError(t, w.Log(buf))
+		require.NoError(t, w.NextSegment())
+	}
+	require.NoError(t, w.Close())
+
+	// Nothing is repaired in a valid WAL.
+	res, err := RepairWAL(log.NewNopLogger(), walDir, false)
+	require.NoError(t, err)
+	require.Nil(t, res.Corruption)
+
+	// Corrupt the record in the second segment.
+	f, err := os.OpenFile(wal.SegmentName(walDir, 1), os.O_WRONLY, 0666)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte{0xff, 0xff, 0xff, 0xff}, 3)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	res, err = RepairWAL(log.NewNopLogger(), walDir, true)
+	require.NoError(t, err)
+	require.NotNil(t, res.Corruption)
+	require.Equal(t, 1, res.Corruption.Segment)
+	require.Equal(t, []int{2, 3}, res.DeletedSegments)
+	require.FileExists(t, wal.SegmentName(walDir, 3), "dry run must not modify the WAL")
+
+	res, err = RepairWAL(log.NewNopLogger(), walDir, false)
+	require.NoError(t, err)
+	require.NotNil(t, res.Corruption)
+
+	// The WAL is readable after the repair, and only the first record remains.
+	ins, err := InspectWAL(walDir, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, ins.Series)
+
+	res, err = RepairWAL(log.NewNopLogger(), walDir, false)
+	require.NoError(t, err)
+	require.Nil(t, res.Corruption)
+}
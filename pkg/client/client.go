@@ -52,6 +52,16 @@ type PrometheusClient interface {
 	// DeleteConfiguration removes a named configuration from the config
 	// management KV store.
 	DeleteConfiguration(ctx context.Context, name string) error
+
+	// PutConfigurations adds or updates a batch of configurations in the
+	// config management KV store. If prune is true, configurations which
+	// aren't in the batch are removed. If dryRun is true, the batch is only
+	// validated.
+	PutConfigurations(ctx context.Context, cfgs []*instance.Config, prune, dryRun bool) (*configapi.PutConfigurationsResponse, error)
+
+	// ExportConfigurations returns all configurations from the config
+	// management KV store. Secrets in the configurations are scrubbed.
+	ExportConfigurations(ctx context.Context) ([]*instance.Config, error)
 }
 
 type prometheusClient struct {
@@ -129,6 +139,39 @@ func (c *prometheusClient) DeleteConfiguration(ctx context.Context, name string)
 	return unmarshalPrometheusAPIResponse(resp.Body, nil)
 }
 
+func (c *prometheusClient) PutConfigurations(ctx context.Context, cfgs []*instance.Config, prune, dryRun bool) (*configapi.PutConfigurationsResponse, error) {
+	url := fmt.Sprintf("%s/agent/api/v1/configs:batch?prune=%t&dry_run=%t", c.addr, prune, dryRun)
+
+	bb, err := instance.MarshalConfigs(cfgs, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.doRequest(ctx, "POST", url, bytes.NewReader(bb))
+	if err != nil {
+		return nil, err
+	}
+
+	var data configapi.PutConfigurationsResponse
+	err = unmarshalPrometheusAPIResponse(resp.Body, &data)
+	return &data, err
+}
+
+func (c *prometheusClient) ExportConfigurations(ctx context.Context) ([]*instance.Config, error) {
+	url := fmt.Sprintf("%s/agent/api/v1/configs:export", c.addr)
+
+	resp, err := c.doRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var data configapi.ExportConfigurationsResponse
+	if err := unmarshalPrometheusAPIResponse(resp.Body, &data); err != nil {
+		return nil, err
+	}
+	return instance.UnmarshalConfigs(strings.NewReader(data.Value))
+}
+
 func (c *prometheusClient) doRequest(ctx context.Context, method string, url string, body io.Reader) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
@@ -13,9 +13,11 @@ import (
 	"github.com/drone/envsubst/v2"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/grafana/agent/pkg/config/dynamic"
 	"github.com/grafana/agent/pkg/config/features"
 	"github.com/grafana/agent/pkg/logs"
 	"github.com/grafana/agent/pkg/metrics"
+	"github.com/grafana/agent/pkg/profiles"
 	"github.com/grafana/agent/pkg/traces"
 	"github.com/grafana/agent/pkg/util"
 	"github.com/grafana/dskit/kv/consul"
@@ -31,10 +33,12 @@ import (
 var (
 	featRemoteConfigs    = features.Feature("remote-configs")
 	featIntegrationsNext = features.Feature("integrations-next")
+	featDynamicConfig    = features.Feature("dynamic-config")
 
 	allFeatures = []features.Feature{
 		featRemoteConfigs,
 		featIntegrationsNext,
+		featDynamicConfig,
 	}
 )
 
@@ -53,6 +57,11 @@ type Config struct {
 	Integrations VersionedIntegrations `yaml:"integrations,omitempty"`
 	Traces       traces.Config         `yaml:"traces,omitempty"`
 	Logs         *logs.Config          `yaml:"logs,omitempty"`
+	Profiles     profiles.Config       `yaml:"profiles,omitempty"`
+
+	// Include lists files, globs or directories of YAML files merged into
+	// the config file. Only supported for config files read from disk.
+	Include []string `yaml:"include,omitempty"`
 
 	// We support a secondary server just for the /-/reload endpoint, since
 	// invoking /-/reload against the primary server can cause the server
@@ -63,6 +72,14 @@ type Config struct {
 	// Deprecated fields user has used. Generated during UnmarshalYAML.
 	Deprecations []string `yaml:"-"`
 
+	// Warnings about how the config was loaded. Generated during Load.
+	Warnings []string `yaml:"-"`
+
+	// Original is the config as it was loaded, after environment variables
+	// were expanded and included files were merged, but before defaults were
+	// applied. Set by the Load functions.
+	Original []byte `yaml:"-"`
+
 	// Remote config options
 	BasicAuthUser     string `yaml:"-"`
 	BasicAuthPassFile string `yaml:"-"`
@@ -158,12 +175,77 @@ func (c Config) MarshalYAML() (interface{}, error) {
 	return m, nil
 }
 
-// LogDeprecations will log use of any deprecated fields to l as warn-level
-// messages.
+// MarshalOriginal returns the config as it was loaded, with secrets redacted.
+// Secrets are found by unmarshaling the original config into its Go types,
+// so the same fields are redacted as for MarshalEffective.
+func (c *Config) MarshalOriginal() ([]byte, error) {
+	if len(c.Original) == 0 {
+		return nil, nil
+	}
+
+	var typed Config
+	if err := yaml.Unmarshal(c.Original, &typed); err != nil {
+		return nil, err
+	}
+	if err := typed.Integrations.setVersion(c.Integrations.version); err != nil {
+		return nil, err
+	}
+	typedMS, err := typed.MarshalYAML()
+	if err != nil {
+		return nil, err
+	}
+
+	// The typed config always uses the new names of the deprecated blocks.
+	// Add them under the deprecated names too, so the original config can be
+	// redacted whichever names it used.
+	ms := typedMS.(yaml.MapSlice)
+	for _, item := range ms {
+		if deprecated, ok := deprecatedBlockNames[item.Key.(string)]; ok {
+			ms = append(ms, yaml.MapItem{Key: deprecated, Value: item.Value})
+		}
+	}
+	bb, err := yaml.Marshal(ms)
+	if err != nil {
+		return nil, err
+	}
+	return util.RedactYAML(c.Original, bb)
+}
+
+// deprecatedBlockNames maps the names of top-level blocks to their
+// deprecated names.
+var deprecatedBlockNames = map[string]string{
+	"metrics": "prometheus",
+	"logs":    "loki",
+	"traces":  "tempo",
+}
+
+// MarshalEffective returns the config after defaults were applied, with
+// secrets redacted. The instance labels of integrations are resolved as if
+// the Agent was running on the host hostname.
+func (c *Config) MarshalEffective(hostname string) ([]byte, error) {
+	effective := *c
+	if v1 := c.Integrations.configV1; v1 != nil && c.Integrations.version == integrationsVersion1 {
+		resolved := v1.WithInstanceKeys(hostname)
+		effective.Integrations.configV1 = &resolved
+	}
+
+	bb, err := yaml.Marshal(effective)
+	if err != nil {
+		return nil, err
+	}
+	// bb is already typed, so this only redacts headers and URLs.
+	return util.RedactYAML(bb, bb)
+}
+
+// LogDeprecations will log use of any deprecated fields and any other
+// warnings about the config to l as warn-level messages.
 func (c *Config) LogDeprecations(l log.Logger) {
 	for _, d := range c.Deprecations {
 		level.Warn(l).Log("msg", fmt.Sprintf("DEPRECATION NOTICE: %s", d))
 	}
+	for _, w := range c.Warnings {
+		level.Warn(l).Log("msg", w)
+	}
 }
 
 // Validate validates the config, flags, and sets default values.
@@ -178,9 +260,13 @@ func (c *Config) Validate(fs *flag.FlagSet) error {
 		return err
 	}
 
-	// since the Traces config might rely on an existing Loki config
+	// since the Traces config might rely on existing Loki and metrics configs
 	// this check is made here to look for cross config issues before we attempt to load
-	if err := c.Traces.Validate(c.Logs); err != nil {
+	if err := c.Traces.Validate(c.Logs, &c.Metrics); err != nil {
+		return err
+	}
+
+	if err := c.Profiles.Validate(); err != nil {
 		return err
 	}
 
@@ -203,6 +289,7 @@ func (c *Config) RegisterFlags(f *flag.FlagSet) {
 	c.Server.RegisterInstrumentation = true
 	c.Metrics.RegisterFlags(f)
 	c.Server.RegisterFlags(f)
+	c.Integrations.RegisterFlags(f)
 
 	f.StringVar(&c.ReloadAddress, "reload-addr", "127.0.0.1", "address to expose a secondary server for /-/reload on.")
 	f.IntVar(&c.ReloadPort, "reload-port", 0, "port to expose a secondary server for /-/reload on. 0 disables secondary server.")
@@ -212,7 +299,7 @@ func (c *Config) RegisterFlags(f *flag.FlagSet) {
 	f.StringVar(&c.BasicAuthPassFile, "config.url.basic-auth-password-file", "",
 		"path to file containing basic auth password for fetching remote config. (requires remote-configs experiment to be enabled")
 
-	f.BoolVar(&c.EnableConfigEndpoints, "config.enable-read-api", false, "Enables the /-/config and /agent/api/v1/configs/{name} APIs. Be aware that secrets could be exposed by enabling these endpoints!")
+	f.BoolVar(&c.EnableConfigEndpoints, "config.enable-read-api", false, "Enables the /-/config, /-/config/effective and /agent/api/v1/configs/{name} APIs. Be aware that secrets could be exposed by enabling these endpoints!")
 }
 
 // LoadFile reads a file and passes the contents to Load
@@ -221,7 +308,19 @@ func LoadFile(filename string, expandEnvVars bool, c *Config) error {
 	if err != nil {
 		return errors.Wrap(err, "error reading config file")
 	}
-	return LoadBytes(buf, expandEnvVars, c)
+	if expandEnvVars {
+		if buf, err = expandEnv(buf); err != nil {
+			return err
+		}
+	}
+	if buf, err = mergeIncludes(filename, buf, expandEnvVars); err != nil {
+		return err
+	}
+	if err := yaml.UnmarshalStrict(buf, c); err != nil {
+		return err
+	}
+	c.Original = buf
+	return nil
 }
 
 // LoadRemote reads a config from url
@@ -256,7 +355,30 @@ func LoadRemote(url string, expandEnvVars bool, c *Config) error {
 	if err != nil {
 		return fmt.Errorf("error retrieving remote config: %w", err)
 	}
-	return LoadBytes(bb, expandEnvVars, c)
+	if err := LoadBytes(bb, expandEnvVars, c); err != nil {
+		return err
+	}
+	if len(c.Include) > 0 {
+		return fmt.Errorf("include is not supported for remote configs")
+	}
+	return nil
+}
+
+// LoadDynamic renders a config from the Jsonnet file filename and its
+// datasources, and unmarshals it. Environment variables aren't expanded in
+// the rendered config; templates can read them with the env datasource.
+func LoadDynamic(filename string, c *Config) error {
+	buf, err := dynamic.Render(filename)
+	if err != nil {
+		return err
+	}
+	if err := LoadBytes(buf, false, c); err != nil {
+		return err
+	}
+	if len(c.Include) > 0 {
+		return fmt.Errorf("include is not supported for dynamic configs")
+	}
+	return nil
 }
 
 // LoadBytes unmarshals a config from a buffer. Defaults are not
@@ -265,14 +387,27 @@ func LoadRemote(url string, expandEnvVars bool, c *Config) error {
 func LoadBytes(buf []byte, expandEnvVars bool, c *Config) error {
 	// (Optionally) expand with environment variables
 	if expandEnvVars {
-		s, err := envsubst.Eval(string(buf), getenv)
-		if err != nil {
-			return fmt.Errorf("unable to substitute config with environment variables: %w", err)
+		var err error
+		if buf, err = expandEnv(buf); err != nil {
+			return err
 		}
-		buf = []byte(s)
 	}
 	// Unmarshal yaml config
-	return yaml.UnmarshalStrict(buf, c)
+	if err := yaml.UnmarshalStrict(buf, c); err != nil {
+		return err
+	}
+	c.Original = buf
+	return nil
+}
+
+// expandEnv expands ${var} in buf according to the values of the environment
+// variables.
+func expandEnv(buf []byte) ([]byte, error) {
+	s, err := envsubst.Eval(string(buf), getenv)
+	if err != nil {
+		return nil, fmt.Errorf("unable to substitute config with environment variables: %w", err)
+	}
+	return []byte(s), nil
 }
 
 // getenv is a wrapper around os.Getenv that ignores patterns that are numeric
@@ -299,6 +434,9 @@ func getenv(name string) string {
 // args.
 func Load(fs *flag.FlagSet, args []string) (*Config, error) {
 	return load(fs, args, func(url string, expand bool, c *Config) error {
+		if features.Enabled(fs, featDynamicConfig) {
+			return LoadDynamic(url, c)
+		}
 		if features.Enabled(fs, featRemoteConfigs) {
 			return LoadRemote(url, expand, c)
 		}
@@ -319,7 +457,7 @@ func load(fs *flag.FlagSet, args []string, loader func(string, bool, *Config) er
 
 	fs.StringVar(&file, "config.file", "", "configuration file to load")
 	fs.BoolVar(&printVersion, "version", false, "Print this build's version information")
-	fs.BoolVar(&configExpandEnv, "config.expand-env", false, "Expands ${var} in config according to the values of the environment variables.")
+	fs.BoolVar(&configExpandEnv, "config.expand-env", true, "Expands ${var} in config according to the values of the environment variables. Set to false to disable expansion.")
 	cfg.RegisterFlags(fs)
 	features.Register(fs, allFeatures)
 
@@ -338,6 +476,12 @@ func load(fs *flag.FlagSet, args []string, loader func(string, bool, *Config) er
 		return nil, fmt.Errorf("error loading config file %s: %w", file, err)
 	}
 
+	// Expanding environment variables used to be opt-in. Warn users who
+	// didn't choose, as literal $ in their config may have been replaced.
+	if configExpandEnv && !flagSet(fs, "config.expand-env") {
+		cfg.Warnings = append(cfg.Warnings, ExpandEnvDefaultWarning("-config.expand-env"))
+	}
+
 	// Parse the flags again to override any YAML values with command line flag
 	// values.
 	if err := fs.Parse(args); err != nil {
@@ -361,6 +505,26 @@ func load(fs *flag.FlagSet, args []string, loader func(string, bool, *Config) er
 	return &cfg, nil
 }
 
+// ExpandEnvDefaultWarning returns the warning for a config loaded with
+// environment variables expanded because flag, which controls expansion,
+// wasn't set explicitly.
+func ExpandEnvDefaultWarning(flag string) string {
+	return "environment variables in the config file are now expanded by default. " +
+		fmt.Sprintf("Escape a literal $ as $$, or pass %s=false to disable expansion. ", flag) +
+		fmt.Sprintf("Pass %s=true to silence this warning.", flag)
+}
+
+// flagSet returns true if the flag called name was set on the command line.
+func flagSet(fs *flag.FlagSet, name string) bool {
+	var set bool
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
 // CheckSecret is a helper function to ensure the original value is overwritten with <secret>
 func CheckSecret(t *testing.T, rawCfg string, originalValue string) {
 	var cfg = &Config{}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/metrics/instance"
+	"github.com/grafana/agent/pkg/util"
+)
+
+// Diff describes the changes applying a config would make to the running
+// Agent.
+type Diff struct {
+	// Server is true when the HTTP and gRPC server config changed, which
+	// restarts the server.
+	Server bool `json:"server"`
+
+	Metrics      NamesDiff `json:"metrics"`
+	Logs         NamesDiff `json:"logs"`
+	Traces       NamesDiff `json:"traces"`
+	Profiles     NamesDiff `json:"profiles"`
+	Integrations NamesDiff `json:"integrations"`
+}
+
+// NamesDiff lists the instances of a subsystem which would be added, removed
+// or changed by a config. Changed instances are either restarted or, when
+// supported by the subsystem, updated in place.
+type NamesDiff struct {
+	Added     []string `json:"added,omitempty"`
+	Removed   []string `json:"removed,omitempty"`
+	Restarted []string `json:"restarted,omitempty"`
+	Updated   []string `json:"updated,omitempty"`
+}
+
+// DiffConfigs returns the changes applying next would make to an Agent
+// running prev. Both configs must be validated. hostname is used to
+// identify integrations which are named after the Agent.
+func DiffConfigs(prev, next *Config, hostname string) (*Diff, error) {
+	var d Diff
+
+	d.Server = !util.CompareYAML(prev.Server, next.Server)
+
+	d.Metrics = diffNames(metricsInstances(prev), metricsInstances(next), updateMetricsInstance)
+	d.Logs = diffNames(logsInstances(prev), logsInstances(next), nil)
+	d.Traces = diffNames(tracesInstances(prev), tracesInstances(next), nil)
+	d.Profiles = diffNames(profilesInstances(prev), profilesInstances(next), nil)
+
+	prevIntegrations, err := integrationInstances(prev, hostname)
+	if err != nil {
+		return nil, err
+	}
+	nextIntegrations, err := integrationInstances(next, hostname)
+	if err != nil {
+		return nil, err
+	}
+	// None of the integrations can be updated in place.
+	d.Integrations = diffNames(prevIntegrations, nextIntegrations, nil)
+
+	return &d, nil
+}
+
+// diffNames compares two sets of instances keyed by name. canUpdate reports
+// whether a changed instance is updated in place; instances are restarted
+// when canUpdate is nil.
+func diffNames(prev, next map[string]interface{}, canUpdate func(prev, next interface{}) bool) NamesDiff {
+	var d NamesDiff
+	for name, nc := range next {
+		pc, exist := prev[name]
+		switch {
+		case !exist:
+			d.Added = append(d.Added, name)
+		case util.CompareYAML(pc, nc):
+		case canUpdate != nil && canUpdate(pc, nc):
+			d.Updated = append(d.Updated, name)
+		default:
+			d.Restarted = append(d.Restarted, name)
+		}
+	}
+	for name := range prev {
+		if _, exist := next[name]; !exist {
+			d.Removed = append(d.Removed, name)
+		}
+	}
+
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Restarted)
+	sort.Strings(d.Updated)
+	return d
+}
+
+// metricsInstance is a metrics instance along with the global config, as
+// changing the global config changes every instance.
+type metricsInstance struct {
+	Global instance.GlobalConfig `yaml:"global"`
+	Config instance.Config       `yaml:"config"`
+}
+
+// metricsInstances returns the metrics instances of c.
+func metricsInstances(c *Config) map[string]interface{} {
+	res := make(map[string]interface{}, len(c.Metrics.Configs))
+	for _, ic := range c.Metrics.Configs {
+		res[ic.Name] = metricsInstance{Global: c.Metrics.Global, Config: ic}
+	}
+	return res
+}
+
+// updateMetricsInstance returns whether a running metrics instance can be
+// updated from prev to next without restarting it.
+func updateMetricsInstance(prev, next interface{}) bool {
+	return instance.CheckUpdate(prev.(metricsInstance).Config, next.(metricsInstance).Config) == nil
+}
+
+func logsInstances(c *Config) map[string]interface{} {
+	if c.Logs == nil {
+		return nil
+	}
+	res := make(map[string]interface{}, len(c.Logs.Configs))
+	for _, ic := range c.Logs.Configs {
+		res[ic.Name] = ic
+	}
+	return res
+}
+
+func tracesInstances(c *Config) map[string]interface{} {
+	res := make(map[string]interface{}, len(c.Traces.Configs))
+	for _, ic := range c.Traces.Configs {
+		res[ic.Name] = ic
+	}
+	return res
+}
+
+func profilesInstances(c *Config) map[string]interface{} {
+	res := make(map[string]interface{}, len(c.Profiles.Configs))
+	for _, ic := range c.Profiles.Configs {
+		res[ic.Name] = ic
+	}
+	return res
+}
+
+// integrationInstances returns the running integrations of c, keyed the
+// same way as the integrations subsystem identifies them.
+func integrationInstances(c *Config, hostname string) (map[string]interface{}, error) {
+	res := make(map[string]interface{})
+
+	switch {
+	case c.Integrations.configV1 != nil:
+		for _, ic := range c.Integrations.configV1.Integrations {
+			if !ic.Common.Enabled {
+				continue
+			}
+			res[ic.Name()] = ic
+		}
+
+	case c.Integrations.configV2 != nil:
+		globals := v2.Globals{
+			AgentIdentifier: fmt.Sprintf("%s:%d", hostname, c.Server.HTTPListenPort),
+		}
+		for _, ic := range c.Integrations.configV2.Configs {
+			id, err := ic.Identifier(globals)
+			if err != nil {
+				return nil, fmt.Errorf("could not build identifier for integration %q: %w", ic.Name(), err)
+			}
+			res[ic.Name()+"/"+id] = ic
+		}
+	}
+
+	return res, nil
+}
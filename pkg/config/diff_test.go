@@ -0,0 +1,74 @@
+package config
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	prev := loadTestConfig(t, `
+metrics:
+  wal_directory: /tmp/wal
+  configs:
+  - name: a
+  - name: b
+  - name: c
+  - name: e
+    remote_write:
+    - url: http://cortex/api/prom/push
+      basic_auth: {username: admin, password: secret}
+
+integrations:
+  agent:
+    enabled: true`)
+
+	next := loadTestConfig(t, `
+metrics:
+  wal_directory: /tmp/wal
+  configs:
+  - name: b
+    host_filter: true
+  - name: c
+  - name: d
+  - name: e
+    remote_write:
+    - url: http://cortex/api/prom/push
+      basic_auth: {username: admin, password: rotated}
+
+integrations:
+  agent:
+    enabled: false`)
+
+	d, err := DiffConfigs(prev, next, "agent-host")
+	require.NoError(t, err)
+
+	expect := &Diff{
+		Metrics: NamesDiff{
+			Added:     []string{"d"},
+			Removed:   []string{"a"},
+			Restarted: []string{"b"},
+			Updated:   []string{"e"},
+		},
+		Integrations: NamesDiff{
+			Removed: []string{"agent"},
+		},
+	}
+	require.Equal(t, expect, d)
+
+	d, err = DiffConfigs(prev, prev, "agent-host")
+	require.NoError(t, err)
+	require.Equal(t, &Diff{}, d)
+}
+
+func loadTestConfig(t *testing.T, cfg string) *Config {
+	t.Helper()
+
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	c, err := load(fs, []string{"-config.file", "test"}, func(_ string, _ bool, c *Config) error {
+		return LoadBytes([]byte(cfg), false, c)
+	})
+	require.NoError(t, err)
+	return c
+}
@@ -0,0 +1,238 @@
+// Package dynamic renders Grafana Agent config files from Jsonnet templates
+// and datasources.
+package dynamic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	jsonnet "github.com/google/go-jsonnet"
+	"github.com/google/go-jsonnet/ast"
+	"gopkg.in/yaml.v3"
+)
+
+// requestTimeout is the timeout for reading a remote datasource.
+const requestTimeout = 30 * time.Second
+
+// Render evaluates the Jsonnet file filename and returns the resulting config
+// as JSON. Datasources are read every time the config is rendered.
+//
+// Relative imports and files read with the file datasource are relative to
+// the directory of filename.
+func Render(filename string) ([]byte, error) {
+	return (&renderer{}).Render(filename)
+}
+
+// renderer renders configs. awsConfig is used for the s3 and ec2Metadata
+// datasources, and defaults to the config of the default AWS credential
+// chain.
+type renderer struct {
+	awsConfig *aws.Config
+
+	mut  sync.Mutex
+	sess *session.Session
+}
+
+func (r *renderer) Render(filename string) ([]byte, error) {
+	dir := filepath.Dir(filename)
+
+	vm := jsonnet.MakeVM()
+	vm.Importer(&jsonnet.FileImporter{JPaths: []string{dir}})
+	for _, f := range r.nativeFunctions(dir) {
+		vm.NativeFunction(f)
+	}
+
+	out, err := vm.EvaluateFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render config %s: %w", filename, err)
+	}
+	return []byte(out), nil
+}
+
+func (r *renderer) nativeFunctions(dir string) []*jsonnet.NativeFunction {
+	return []*jsonnet.NativeFunction{
+		{
+			Name:   "env",
+			Params: ast.Identifiers{"name", "default"},
+			Func: func(i []interface{}) (interface{}, error) {
+				name, ok := i[0].(string)
+				if !ok {
+					return nil, jsonnet.RuntimeError{Msg: "env name argument must be a string"}
+				}
+				if v, ok := os.LookupEnv(name); ok {
+					return v, nil
+				}
+				if i[1] == nil {
+					return nil, jsonnet.RuntimeError{Msg: fmt.Sprintf("environment variable %s is not set", name)}
+				}
+				return i[1], nil
+			},
+		},
+		{
+			Name:   "file",
+			Params: ast.Identifiers{"path"},
+			Func: func(i []interface{}) (interface{}, error) {
+				path, ok := i[0].(string)
+				if !ok {
+					return nil, jsonnet.RuntimeError{Msg: "file path argument must be a string"}
+				}
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(dir, path)
+				}
+				bb, err := ioutil.ReadFile(path)
+				if err != nil {
+					return nil, jsonnet.RuntimeError{Msg: err.Error()}
+				}
+				return string(bb), nil
+			},
+		},
+		{
+			Name:   "s3",
+			Params: ast.Identifiers{"url"},
+			Func: func(i []interface{}) (interface{}, error) {
+				rawURL, ok := i[0].(string)
+				if !ok {
+					return nil, jsonnet.RuntimeError{Msg: "s3 url argument must be a string"}
+				}
+				contents, err := r.readS3(rawURL)
+				if err != nil {
+					return nil, jsonnet.RuntimeError{Msg: err.Error()}
+				}
+				return contents, nil
+			},
+		},
+		{
+			Name:   "ec2Metadata",
+			Params: ast.Identifiers{"path"},
+			Func: func(i []interface{}) (interface{}, error) {
+				path, ok := i[0].(string)
+				if !ok {
+					return nil, jsonnet.RuntimeError{Msg: "ec2Metadata path argument must be a string"}
+				}
+				contents, err := r.readEC2Metadata(path)
+				if err != nil {
+					return nil, jsonnet.RuntimeError{Msg: err.Error()}
+				}
+				return contents, nil
+			},
+		},
+		{
+			Name:   "parseYAML",
+			Params: ast.Identifiers{"text"},
+			Func: func(i []interface{}) (interface{}, error) {
+				text, ok := i[0].(string)
+				if !ok {
+					return nil, jsonnet.RuntimeError{Msg: "parseYAML text argument must be a string"}
+				}
+				v, err := parseYAML(text)
+				if err != nil {
+					return nil, jsonnet.RuntimeError{Msg: err.Error()}
+				}
+				return v, nil
+			},
+		},
+	}
+}
+
+// parseYAML parses text into the JSON types Jsonnet native functions can
+// return.
+func parseYAML(text string) (interface{}, error) {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(text), &v); err != nil {
+		return nil, err
+	}
+	bb, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var res interface{}
+	err = json.Unmarshal(bb, &res)
+	return res, err
+}
+
+// awsSession lazily creates the AWS session, so the AWS SDK is only
+// configured when a template uses an AWS datasource.
+func (r *renderer) awsSession() (*session.Session, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.sess != nil {
+		return r.sess, nil
+	}
+
+	cfg := r.awsConfig
+	if cfg == nil {
+		cfg = aws.NewConfig()
+	}
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *cfg,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+	r.sess = sess
+	return sess, nil
+}
+
+func (r *renderer) readS3(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid s3 url %s: %w", rawURL, err)
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if u.Scheme != "s3" || u.Host == "" || key == "" {
+		return "", fmt.Errorf("invalid s3 url %s: must be s3://<bucket>/<key>", rawURL)
+	}
+
+	sess, err := r.awsSession()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	resp, err := s3.New(sess).GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(u.Host),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	bb, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", rawURL, err)
+	}
+	return string(bb), nil
+}
+
+func (r *renderer) readEC2Metadata(path string) (string, error) {
+	sess, err := r.awsSession()
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	v, err := ec2metadata.New(sess).GetMetadataWithContext(ctx, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read EC2 metadata %s: %w", path, err)
+	}
+	return v, nil
+}
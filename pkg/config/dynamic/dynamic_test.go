@@ -0,0 +1,133 @@
+package dynamic
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, contents := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+	return dir
+}
+
+func TestRender(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"agent.jsonnet": `
+local lib = import 'lib.libsonnet';
+local env = std.native('env');
+local hosts = std.native('parseYAML')(std.native('file')('hosts.yaml'));
+
+{
+  server: { log_level: env('AGENT_TEST_LOG_LEVEL', 'info') },
+  metrics: {
+    global: {
+      external_labels: {
+        cluster: env('AGENT_TEST_CLUSTER', null),
+        zone: hosts[env('AGENT_TEST_HOSTNAME', null)].zone,
+      },
+    },
+    configs: [lib.instance('default')],
+  },
+}
+`,
+		"lib.libsonnet": `
+{
+  instance(name):: { name: name, scrape_interval: '15s' },
+}
+`,
+		"hosts.yaml": `
+host-a: {zone: us-east-1a}
+host-b: {zone: us-east-1b}
+`,
+	})
+	t.Setenv("AGENT_TEST_CLUSTER", "prod")
+	t.Setenv("AGENT_TEST_HOSTNAME", "host-b")
+
+	out, err := Render(filepath.Join(dir, "agent.jsonnet"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"server": {"log_level": "info"},
+		"metrics": {
+			"global": {"external_labels": {"cluster": "prod", "zone": "us-east-1b"}},
+			"configs": [{"name": "default", "scrape_interval": "15s"}]
+		}
+	}`, string(out))
+}
+
+func TestRender_MissingEnv(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"agent.jsonnet": `{ server: { log_level: std.native('env')('AGENT_TEST_UNSET_VARIABLE', null) } }`,
+	})
+	_, err := Render(filepath.Join(dir, "agent.jsonnet"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "environment variable AGENT_TEST_UNSET_VARIABLE is not set")
+}
+
+func TestRender_AWS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			_, _ = rw.Write([]byte("token"))
+		case r.Method == http.MethodGet && r.URL.Path == "/latest/meta-data/placement/availability-zone":
+			_, _ = rw.Write([]byte("us-east-1a"))
+		case r.Method == http.MethodGet && r.URL.Path == "/configs/agent.yaml":
+			_, _ = rw.Write([]byte("remote_write: [{url: 'http://cortex/api/prom/push'}]"))
+		default:
+			http.NotFound(rw, r)
+		}
+	}))
+	defer srv.Close()
+
+	dir := writeFiles(t, map[string]string{
+		"agent.jsonnet": `
+local remote = std.native('parseYAML')(std.native('s3')('s3://configs/agent.yaml'));
+{
+  metrics: {
+    global: {
+      external_labels: { zone: std.native('ec2Metadata')('placement/availability-zone') },
+      remote_write: remote.remote_write,
+    },
+  },
+}
+`,
+	})
+
+	r := &renderer{
+		awsConfig: aws.NewConfig().
+			WithEndpoint(srv.URL).
+			WithRegion("us-east-1").
+			WithS3ForcePathStyle(true).
+			WithCredentials(credentials.NewStaticCredentials("id", "secret", "")),
+	}
+	out, err := r.Render(filepath.Join(dir, "agent.jsonnet"))
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"metrics": {
+			"global": {
+				"external_labels": {"zone": "us-east-1a"},
+				"remote_write": [{"url": "http://cortex/api/prom/push"}]
+			}
+		}
+	}`, string(out))
+}
+
+func TestRender_InvalidS3URL(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"agent.jsonnet": `{ config: std.native('s3')('https://configs/agent.yaml') }`,
+	})
+	_, err := Render(filepath.Join(dir, "agent.jsonnet"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid s3 url https://configs/agent.yaml: must be s3://<bucket>/<key>")
+}
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// includeConfig is used to read the include directive of a config file
+// without unmarshaling the rest of it.
+type includeConfig struct {
+	Include []string `yaml:"include,omitempty"`
+}
+
+// mergeIncludes merges the files included by the config file filename into
+// its contents buf. buf is returned unchanged if it doesn't include any
+// files.
+//
+// Included files are merged in the order of the include patterns, and files
+// matched by the same pattern are merged in lexical order. Maps are merged
+// recursively, and items of lists are appended, except for items with the
+// same listMergeKeys key as an existing item, which are merged into that
+// item. Any other value replaces the value of previously merged files.
+func mergeIncludes(filename string, buf []byte, expandEnvVars bool) ([]byte, error) {
+	var ic includeConfig
+	if err := yaml.Unmarshal(buf, &ic); err != nil || len(ic.Include) == 0 {
+		// Errors will be reported when unmarshaling the whole config.
+		return buf, nil
+	}
+
+	files, err := includedFiles(filename, ic.Include)
+	if err != nil {
+		return nil, err
+	}
+
+	var merged interface{}
+	if err := yaml.UnmarshalStrict(buf, &merged); err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %w", filename, err)
+	}
+
+	for _, f := range files {
+		contents, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("error reading included config file: %w", err)
+		}
+		if expandEnvVars {
+			contents, err = expandEnv(contents)
+			if err != nil {
+				return nil, fmt.Errorf("error expanding included config file %s: %w", f, err)
+			}
+		}
+
+		var (
+			included interface{}
+			nested   includeConfig
+		)
+		if err := yaml.UnmarshalStrict(contents, &included); err != nil {
+			return nil, fmt.Errorf("error parsing included config file %s: %w", f, err)
+		}
+		if err := yaml.Unmarshal(contents, &nested); err == nil && len(nested.Include) > 0 {
+			return nil, fmt.Errorf("included config file %s must not include other files", f)
+		}
+		merged = mergeYAML(merged, included, "")
+	}
+
+	return yaml.Marshal(merged)
+}
+
+// includedFiles returns the files matched by the include patterns of the
+// config file filename. Relative patterns are relative to the directory of
+// filename. Patterns which are directories match all YAML files in them.
+func includedFiles(filename string, patterns []string) ([]string, error) {
+	var (
+		res  []string
+		seen = map[string]struct{}{}
+	)
+
+	self, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	seen[self] = struct{}{}
+
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(filename), pattern)
+		}
+
+		matches, err := matchInclude(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			abs, err := filepath.Abs(m)
+			if err != nil {
+				return nil, err
+			}
+			if _, exist := seen[abs]; exist {
+				continue
+			}
+			seen[abs] = struct{}{}
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+func matchInclude(pattern string) ([]string, error) {
+	if !strings.ContainsAny(pattern, "*?[") {
+		fi, err := os.Stat(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include %s: %w", pattern, err)
+		}
+		if !fi.IsDir() {
+			return []string{pattern}, nil
+		}
+
+		var res []string
+		for _, ext := range []string{"*.yml", "*.yaml"} {
+			matches, err := filepath.Glob(filepath.Join(pattern, ext))
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, matches...)
+		}
+		sort.Strings(res)
+		return res, nil
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid include %s: %w", pattern, err)
+	}
+
+	res := matches[:0]
+	for _, m := range matches {
+		if fi, err := os.Stat(m); err == nil && !fi.IsDir() {
+			res = append(res, m)
+		}
+	}
+	return res, nil
+}
+
+// listMergeKeys maps the names of lists to the key identifying their items.
+// Items of included files with the same key as an existing item are merged
+// into it. Items of other lists, and items without the key, are appended.
+var listMergeKeys = map[string]string{
+	// Instances of the metrics, logs, traces and profiles subsystems.
+	"configs": "name",
+	// Scrape configs of metrics and logs instances.
+	"scrape_configs": "job_name",
+	// Remote write configs of metrics. Unnamed remote writes are appended.
+	"remote_write": "name",
+}
+
+// mergeYAML merges the YAML value src into dst and returns the result.
+// itemKey is the key identifying the items of src and dst if they are
+// lists.
+func mergeYAML(dst, src interface{}, itemKey string) interface{} {
+	switch s := src.(type) {
+	case nil:
+		return dst
+
+	case map[interface{}]interface{}:
+		d, ok := dst.(map[interface{}]interface{})
+		if !ok {
+			return src
+		}
+		for k, v := range s {
+			if dv, exist := d[k]; exist {
+				name, _ := k.(string)
+				d[k] = mergeYAML(dv, v, listMergeKeys[name])
+			} else {
+				d[k] = v
+			}
+		}
+		return d
+
+	case []interface{}:
+		d, ok := dst.([]interface{})
+		if !ok {
+			return src
+		}
+		for _, item := range s {
+			if idx := keyedItemIndex(d, item, itemKey); idx >= 0 {
+				d[idx] = mergeYAML(d[idx], item, "")
+				continue
+			}
+			d = append(d, item)
+		}
+		return d
+
+	default:
+		return src
+	}
+}
+
+// keyedItemIndex returns the index of the map in items which has the same
+// value for key as item, or -1 if item doesn't have the key or no such map
+// exists.
+func keyedItemIndex(items []interface{}, item interface{}, key string) int {
+	if key == "" {
+		return -1
+	}
+	value, ok := itemKeyValue(item, key)
+	if !ok {
+		return -1
+	}
+	for i, other := range items {
+		if otherValue, ok := itemKeyValue(other, key); ok && otherValue == value {
+			return i
+		}
+	}
+	return -1
+}
+
+func itemKeyValue(item interface{}, key string) (string, bool) {
+	m, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return "", false
+	}
+	value, ok := m[key].(string)
+	return value, ok && value != ""
+}
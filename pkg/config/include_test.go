@@ -0,0 +1,238 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	}
+	return dir
+}
+
+func TestLoadFile_Include(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"agent.yaml": `
+include:
+  - conf.d
+server:
+  log_level: info
+logs:
+  positions_directory: /tmp/positions
+  configs:
+    - name: default
+      clients:
+        - url: http://loki:3100/loki/api/v1/push
+      scrape_configs:
+        - job_name: system
+          static_configs:
+            - labels: {__path__: /var/log/*.log}
+`,
+		"conf.d/10-nginx.yaml": `
+logs:
+  configs:
+    - name: default
+      scrape_configs:
+        - job_name: nginx
+          static_configs:
+            - labels: {__path__: /var/log/nginx/*.log}
+`,
+		"conf.d/20-debug.yml": `
+server:
+  log_level: debug
+`,
+		"conf.d/README.md": `not a config file`,
+	})
+
+	var c Config
+	require.NoError(t, LoadFile(filepath.Join(dir, "agent.yaml"), false, &c))
+
+	require.Equal(t, "debug", c.Server.LogLevel.String())
+	require.Len(t, c.Logs.Configs, 1)
+
+	var jobs []string
+	for _, sc := range c.Logs.Configs[0].ScrapeConfig {
+		jobs = append(jobs, sc.JobName)
+	}
+	require.Equal(t, []string{"system", "nginx"}, jobs)
+}
+
+func TestLoadFile_IncludeExpandEnv(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"agent.yaml": `
+include: ['${AGENT_TEST_INCLUDE_DIR}/*.yaml']
+`,
+		"extra/server.yaml": `
+server:
+  log_level: ${AGENT_TEST_LOG_LEVEL}
+`,
+	})
+	t.Setenv("AGENT_TEST_INCLUDE_DIR", "extra")
+	t.Setenv("AGENT_TEST_LOG_LEVEL", "warn")
+
+	var c Config
+	require.NoError(t, LoadFile(filepath.Join(dir, "agent.yaml"), true, &c))
+	require.Equal(t, "warn", c.Server.LogLevel.String())
+}
+
+func TestLoadFile_IncludeErrors(t *testing.T) {
+	t.Run("missing file", func(t *testing.T) {
+		dir := writeConfigFiles(t, map[string]string{
+			"agent.yaml": "include: [missing.yaml]",
+		})
+		var c Config
+		err := LoadFile(filepath.Join(dir, "agent.yaml"), false, &c)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid include")
+	})
+
+	t.Run("nested include", func(t *testing.T) {
+		dir := writeConfigFiles(t, map[string]string{
+			"agent.yaml": "include: [a.yaml]",
+			"a.yaml":     "include: [b.yaml]",
+			"b.yaml":     "",
+		})
+		var c Config
+		err := LoadFile(filepath.Join(dir, "agent.yaml"), false, &c)
+		require.EqualError(t, err, "included config file "+filepath.Join(dir, "a.yaml")+" must not include other files")
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		dir := writeConfigFiles(t, map[string]string{
+			"agent.yaml": "include: [a.yaml]",
+			"a.yaml":     "server:\n  not_a_field: true",
+		})
+		var c Config
+		require.Error(t, LoadFile(filepath.Join(dir, "agent.yaml"), false, &c))
+	})
+}
+
+func TestMergeYAML(t *testing.T) {
+	var dst, src interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(`
+a: 1
+b: {c: 1, d: [1]}
+configs:
+  - name: x
+    values: [1]
+  - name: y
+other:
+  - name: x
+`), &dst))
+	require.NoError(t, yaml.Unmarshal([]byte(`
+a: 2
+b: {d: [2], e: 1}
+configs:
+  - name: x
+    values: [2]
+  - name: z
+other:
+  - name: x
+`), &src))
+
+	var expect interface{}
+	require.NoError(t, yaml.Unmarshal([]byte(`
+a: 2
+b: {c: 1, d: [1, 2], e: 1}
+configs:
+  - name: x
+    values: [1, 2]
+  - name: y
+  - name: z
+other:
+  - name: x
+  - name: x
+`), &expect))
+
+	require.Equal(t, expect, mergeYAML(dst, src, ""))
+}
+
+func TestLoadFile_IncludeScrapeConfigs(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"agent.yaml": `
+include: [conf.d]
+metrics:
+  wal_directory: /tmp/wal
+  configs:
+    - name: default
+      scrape_configs:
+        - job_name: node
+          static_configs:
+            - targets: [localhost:9100]
+`,
+		"conf.d/node.yaml": `
+metrics:
+  configs:
+    - name: default
+      scrape_configs:
+        - job_name: node
+          scrape_interval: 15s
+        - job_name: mysql
+          static_configs:
+            - targets: [localhost:9104]
+`,
+	})
+
+	var c Config
+	require.NoError(t, LoadFile(filepath.Join(dir, "agent.yaml"), false, &c))
+	require.Len(t, c.Metrics.Configs, 1)
+
+	scs := c.Metrics.Configs[0].ScrapeConfigs
+	require.Len(t, scs, 2)
+
+	// The node job of the included file is merged into the node job of the
+	// config file rather than added as a duplicate job.
+	require.Equal(t, "node", scs[0].JobName)
+	require.Equal(t, "15s", scs[0].ScrapeInterval.String())
+	require.Len(t, scs[0].ServiceDiscoveryConfigs, 1)
+	require.Equal(t, "mysql", scs[1].JobName)
+}
+
+func TestLoadFile_IncludeRemoteWrite(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"agent.yaml": `
+include: [conf.d]
+metrics:
+  wal_directory: /tmp/wal
+  global:
+    remote_write:
+      - name: cortex
+        url: http://cortex/api/prom/push
+      - url: http://backup/api/prom/push
+`,
+		"conf.d/remote_write.yaml": `
+metrics:
+  global:
+    remote_write:
+      - name: cortex
+        url: http://cortex:9009/api/prom/push
+      - url: http://other/api/prom/push
+`,
+	})
+
+	var c Config
+	require.NoError(t, LoadFile(filepath.Join(dir, "agent.yaml"), false, &c))
+
+	// Named remote writes are merged by name, and unnamed ones are appended.
+	var urls []string
+	for _, rw := range c.Metrics.Global.RemoteWrite {
+		urls = append(urls, rw.URL.String())
+	}
+	require.Equal(t, []string{
+		"http://cortex:9009/api/prom/push",
+		"http://backup/api/prom/push",
+		"http://other/api/prom/push",
+	}, urls)
+}
@@ -1,12 +1,14 @@
 package config
 
 import (
+	"flag"
 	"fmt"
 	"reflect"
 
 	"github.com/go-kit/log"
 	"github.com/gorilla/mux"
 	v1 "github.com/grafana/agent/pkg/integrations"
+	integrations_config "github.com/grafana/agent/pkg/integrations/config"
 	v2 "github.com/grafana/agent/pkg/integrations/v2"
 	"github.com/grafana/agent/pkg/metrics"
 	"github.com/grafana/agent/pkg/util"
@@ -34,6 +36,10 @@ type VersionedIntegrations struct {
 	version integrationsVersion
 	raw     util.RawYAML
 
+	// Flags holds the settings of integrations from command line flags. They
+	// are passed to the subsystem config of either version.
+	Flags integrations_config.Flags
+
 	configV1 *v1.ManagerConfig
 	configV2 *v2.SubsystemOptions
 }
@@ -75,6 +81,11 @@ func (c VersionedIntegrations) IsZero() bool {
 	}
 }
 
+// RegisterFlags registers the command line flags of integrations.
+func (c *VersionedIntegrations) RegisterFlags(f *flag.FlagSet) {
+	c.Flags.RegisterFlags(f)
+}
+
 // ApplyDefaults applies defaults to the subsystem based on globals.
 func (c *VersionedIntegrations) ApplyDefaults(scfg *server.Config, mcfg *metrics.Config) error {
 	if c.version != integrationsVersion2 {
@@ -92,16 +103,53 @@ func (c *VersionedIntegrations) setVersion(v integrationsVersion) error {
 	case integrationsVersion1:
 		cfg := v1.DefaultManagerConfig
 		c.configV1 = &cfg
-		return yaml.UnmarshalStrict(c.raw, c.configV1)
+		if err := yaml.UnmarshalStrict(c.raw, c.configV1); err != nil {
+			return err
+		}
+		c.configV1.Flags = c.Flags
+		return nil
 	case integrationsVersion2:
 		cfg := v2.DefaultSubsystemOptions
 		c.configV2 = &cfg
-		return yaml.UnmarshalStrict(c.raw, c.configV2)
+		if err := yaml.UnmarshalStrict(c.raw, c.configV2); err != nil {
+			return err
+		}
+		c.configV2.Flags = c.Flags
+		return nil
 	default:
 		panic(fmt.Sprintf("unknown integrations version %d", c.version))
 	}
 }
 
+// ConfigV1 returns the config of the original integrations subsystem. The
+// raw block is unmarshaled when the config wasn't loaded as version 1, so
+// callers which don't run Load can still read it.
+func (c *VersionedIntegrations) ConfigV1() (*v1.ManagerConfig, error) {
+	if c.configV1 != nil {
+		return c.configV1, nil
+	}
+	cfg := v1.DefaultManagerConfig
+	if err := yaml.UnmarshalStrict(c.raw, &cfg); err != nil {
+		return nil, err
+	}
+	cfg.Flags = c.Flags
+	return &cfg, nil
+}
+
+// ConfigV2 returns the config of the integrations-next subsystem. The raw
+// block is unmarshaled when the config wasn't loaded as version 2.
+func (c *VersionedIntegrations) ConfigV2() (*v2.SubsystemOptions, error) {
+	if c.configV2 != nil {
+		return c.configV2, nil
+	}
+	cfg := v2.DefaultSubsystemOptions
+	if err := yaml.UnmarshalStrict(c.raw, &cfg); err != nil {
+		return nil, err
+	}
+	cfg.Flags = c.Flags
+	return &cfg, nil
+}
+
 // IntegrationsGlobals is a global struct shared across integrations.
 type IntegrationsGlobals = v2.Globals
 
@@ -43,3 +43,21 @@ integrations:
 	require.NoError(t, err)
 	require.NotNil(t, c.Integrations.configV2)
 }
+
+func TestIntegrations_ConfigBeforeLoad(t *testing.T) {
+	cfg := `
+integrations:
+  agent:
+    enabled: true`
+
+	var c Config
+	require.NoError(t, LoadBytes([]byte(cfg), false, &c))
+
+	v1, err := c.Integrations.ConfigV1()
+	require.NoError(t, err)
+	require.Len(t, v1.Integrations, 1)
+	require.Equal(t, "agent", v1.Integrations[0].Name())
+
+	_, err = c.Integrations.ConfigV2()
+	require.Error(t, err, "v1 block must not unmarshal as integrations-next")
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/grafana/agent/pkg/integrations"
 	"github.com/grafana/agent/pkg/integrations/config"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -48,7 +49,7 @@ func New(c *Config) *Integration {
 
 // MetricsHandler satisfies Integration.RegisterRoutes.
 func (i *Integration) MetricsHandler() (http.Handler, error) {
-	return promhttp.Handler(), nil
+	return handler(), nil
 }
 
 // ScrapeConfigs satisfies Integration.ScrapeConfigs.
@@ -66,3 +67,12 @@ func (i *Integration) Run(ctx context.Context) error {
 	<-ctx.Done()
 	return ctx.Err()
 }
+
+// handler returns the handler for the Agent's own metrics. OpenMetrics is
+// enabled so exemplars are exposed to scrapers which request them.
+func handler() http.Handler {
+	return promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	)
+}
@@ -0,0 +1,93 @@
+// Package apache_http implements an integration which collects metrics from
+// the Apache HTTP Server mod_status module, modeled after
+// https://github.com/Lusitaniae/apache_exporter.
+package apache_http //nolint:golint
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+)
+
+// DefaultConfig holds the default settings for the apache_http integration.
+var DefaultConfig = Config{
+	ApacheAddr: "http://localhost/server-status?auto",
+	Timeout:    5 * time.Second,
+}
+
+// Config controls the apache_http integration.
+type Config struct {
+	// ApacheAddr is the URI of the mod_status page in machine readable
+	// ("?auto") format.
+	ApacheAddr string `yaml:"scrape_uri,omitempty"`
+	// ApacheHostOverride overrides the Host header sent in requests.
+	ApacheHostOverride string `yaml:"host_override,omitempty"`
+	// ApacheInsecure disables TLS certificate verification.
+	ApacheInsecure bool `yaml:"insecure,omitempty"`
+	// Timeout for requests to mod_status.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	return unmarshal((*plain)(c))
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "apache_http"
+}
+
+// InstanceKey returns the host:port of the Apache server being scraped.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	u, err := url.Parse(c.ApacheAddr)
+	if err != nil {
+		return "", fmt.Errorf("could not parse url: %w", err)
+	}
+	return u.Host, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
+
+// New creates a new apache_http integration. The integration scrapes metrics
+// from the mod_status page of an Apache HTTP Server.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	u, err := url.Parse(c.ApacheAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse scrape_uri: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("scrape_uri must use http or https, got %q", c.ApacheAddr)
+	}
+
+	client := &http.Client{
+		Timeout: c.Timeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: c.ApacheInsecure},
+		},
+	}
+
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(newCollector(logger, client, c.ApacheAddr, c.ApacheHostOverride)),
+	), nil
+}
@@ -0,0 +1,278 @@
+package apache_http //nolint:golint
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "apache"
+
+var (
+	upDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "up"),
+		"Could the apache server be reached.",
+		nil, nil,
+	)
+	infoDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "info"),
+		"Apache version and MPM information.",
+		[]string{"version", "mpm"}, nil,
+	)
+	accessesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "accesses_total"),
+		"Current total apache accesses.",
+		nil, nil,
+	)
+	kBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "sent_kilobytes_total"),
+		"Current total kbytes sent.",
+		nil, nil,
+	)
+	durationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "duration_ms_total"),
+		"Total duration of all registered requests in ms.",
+		nil, nil,
+	)
+	cpuLoadDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "cpuload"),
+		"The current percentage CPU used by each worker and in total by all workers combined.",
+		nil, nil,
+	)
+	uptimeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "uptime_seconds_total"),
+		"Current uptime in seconds.",
+		nil, nil,
+	)
+	reqPerSecDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "requests_per_second"),
+		"Average number of requests per second since the server was started.",
+		nil, nil,
+	)
+	workersDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "workers"),
+		"Apache worker statuses.",
+		[]string{"state"}, nil,
+	)
+	scoreboardDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "scoreboard"),
+		"Apache scoreboard statuses.",
+		[]string{"state"}, nil,
+	)
+	connectionsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "connections"),
+		"Apache connection statuses.",
+		[]string{"state"}, nil,
+	)
+)
+
+// scoreboardStates maps mod_status scoreboard keys to state label values.
+var scoreboardStates = []struct {
+	key   rune
+	state string
+}{
+	{'_', "idle"},
+	{'S', "startup"},
+	{'R', "read"},
+	{'W', "reply"},
+	{'K', "keepalive"},
+	{'D', "dns"},
+	{'C', "closing"},
+	{'L', "logging"},
+	{'G', "graceful_stop"},
+	{'I', "idle_cleanup"},
+	{'.', "open_slot"},
+}
+
+type collector struct {
+	log          log.Logger
+	client       *http.Client
+	scrapeURI    string
+	hostOverride string
+}
+
+func newCollector(l log.Logger, client *http.Client, scrapeURI, hostOverride string) *collector {
+	return &collector{
+		log:          l,
+		client:       client,
+		scrapeURI:    scrapeURI,
+		hostOverride: hostOverride,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- upDesc
+	ch <- infoDesc
+	ch <- accessesDesc
+	ch <- kBytesDesc
+	ch <- durationDesc
+	ch <- cpuLoadDesc
+	ch <- uptimeDesc
+	ch <- reqPerSecDesc
+	ch <- workersDesc
+	ch <- scoreboardDesc
+	ch <- connectionsDesc
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	status, err := c.fetchStatus()
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to scrape apache mod_status", "err", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
+	status.emit(ch)
+}
+
+func (c *collector) fetchStatus() (*serverStatus, error) {
+	req, err := http.NewRequest(http.MethodGet, c.scrapeURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.hostOverride != "" {
+		req.Host = c.hostOverride
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return parseStatus(resp.Body)
+}
+
+// serverStatus is the parsed output of mod_status in "?auto" mode. Pointer
+// fields are nil when the server didn't report the value; the set of
+// reported values depends on the Apache version, the MPM in use, and
+// whether ExtendedStatus is enabled.
+type serverStatus struct {
+	Version, MPM string
+
+	Accesses, KBytes, Duration *float64
+	CPULoad, Uptime, ReqPerSec *float64
+
+	BusyWorkers, IdleWorkers *float64
+
+	ConnsTotal, ConnsWriting, ConnsKeepAlive, ConnsClosing *float64
+
+	Scoreboard string
+}
+
+func parseStatus(r io.Reader) (*serverStatus, error) {
+	var (
+		s       serverStatus
+		scanner = bufio.NewScanner(r)
+		found   bool
+	)
+
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+		switch key {
+		case "ServerVersion":
+			s.Version = value
+			continue
+		case "ServerMPM":
+			s.MPM = value
+			continue
+		case "Scoreboard":
+			s.Scoreboard = value
+			found = true
+			continue
+		}
+
+		var field **float64
+		switch key {
+		case "Total Accesses":
+			field = &s.Accesses
+		case "Total kBytes":
+			field = &s.KBytes
+		case "Total Duration":
+			field = &s.Duration
+		case "CPULoad":
+			field = &s.CPULoad
+		case "Uptime":
+			field = &s.Uptime
+		case "ReqPerSec":
+			field = &s.ReqPerSec
+		case "BusyWorkers":
+			field = &s.BusyWorkers
+		case "IdleWorkers":
+			field = &s.IdleWorkers
+		case "ConnsTotal":
+			field = &s.ConnsTotal
+		case "ConnsAsyncWriting":
+			field = &s.ConnsWriting
+		case "ConnsAsyncKeepAlive":
+			field = &s.ConnsKeepAlive
+		case "ConnsAsyncClosing":
+			field = &s.ConnsClosing
+		default:
+			continue
+		}
+
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value %q for %s: %w", value, key, err)
+		}
+		*field = &v
+		found = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("response did not contain mod_status output; ensure scrape_uri ends in ?auto")
+	}
+	return &s, nil
+}
+
+func (s *serverStatus) emit(ch chan<- prometheus.Metric) {
+	emit := func(desc *prometheus.Desc, ty prometheus.ValueType, v *float64, labels ...string) {
+		if v != nil {
+			ch <- prometheus.MustNewConstMetric(desc, ty, *v, labels...)
+		}
+	}
+
+	if s.Version != "" || s.MPM != "" {
+		ch <- prometheus.MustNewConstMetric(infoDesc, prometheus.GaugeValue, 1, s.Version, s.MPM)
+	}
+
+	emit(accessesDesc, prometheus.CounterValue, s.Accesses)
+	emit(kBytesDesc, prometheus.CounterValue, s.KBytes)
+	emit(durationDesc, prometheus.CounterValue, s.Duration)
+	emit(cpuLoadDesc, prometheus.GaugeValue, s.CPULoad)
+	emit(uptimeDesc, prometheus.CounterValue, s.Uptime)
+	emit(reqPerSecDesc, prometheus.GaugeValue, s.ReqPerSec)
+	emit(workersDesc, prometheus.GaugeValue, s.BusyWorkers, "busy")
+	emit(workersDesc, prometheus.GaugeValue, s.IdleWorkers, "idle")
+	emit(connectionsDesc, prometheus.GaugeValue, s.ConnsTotal, "total")
+	emit(connectionsDesc, prometheus.GaugeValue, s.ConnsWriting, "writing")
+	emit(connectionsDesc, prometheus.GaugeValue, s.ConnsKeepAlive, "keepalive")
+	emit(connectionsDesc, prometheus.GaugeValue, s.ConnsClosing, "closing")
+
+	if s.Scoreboard != "" {
+		for _, st := range scoreboardStates {
+			count := float64(strings.Count(s.Scoreboard, string(st.key)))
+			ch <- prometheus.MustNewConstMetric(scoreboardDesc, prometheus.GaugeValue, count, st.state)
+		}
+	}
+}
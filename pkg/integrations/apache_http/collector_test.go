@@ -0,0 +1,102 @@
+package apache_http //nolint:golint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const statusPage = `localhost
+ServerVersion: Apache/2.4.41 (Ubuntu)
+ServerMPM: event
+Server Built: 2020-08-12T19:46:17
+CurrentTime: Monday, 17-Jan-2022 10:00:00 UTC
+Uptime: 3600
+Load1: 0.01
+Total Accesses: 131
+Total kBytes: 138
+Total Duration: 2350
+CPULoad: .00183
+ReqPerSec: .0363889
+BusyWorkers: 2
+IdleWorkers: 73
+ConnsTotal: 3
+ConnsAsyncWriting: 0
+ConnsAsyncKeepAlive: 1
+ConnsAsyncClosing: 0
+Scoreboard: _W_K_R__...
+`
+
+func TestCollector(t *testing.T) {
+	var gotHost string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHost = r.Host
+		_, _ = w.Write([]byte(statusPage))
+	}))
+	defer srv.Close()
+
+	c := newCollector(log.NewNopLogger(), srv.Client(), srv.URL+"/server-status?auto", "apache.example.com")
+
+	expect := `
+# HELP apache_accesses_total Current total apache accesses.
+# TYPE apache_accesses_total counter
+apache_accesses_total 131
+# HELP apache_connections Apache connection statuses.
+# TYPE apache_connections gauge
+apache_connections{state="closing"} 0
+apache_connections{state="keepalive"} 1
+apache_connections{state="total"} 3
+apache_connections{state="writing"} 0
+# HELP apache_info Apache version and MPM information.
+# TYPE apache_info gauge
+apache_info{mpm="event",version="Apache/2.4.41 (Ubuntu)"} 1
+# HELP apache_up Could the apache server be reached.
+# TYPE apache_up gauge
+apache_up 1
+# HELP apache_workers Apache worker statuses.
+# TYPE apache_workers gauge
+apache_workers{state="busy"} 2
+apache_workers{state="idle"} 73
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"apache_up", "apache_info", "apache_accesses_total", "apache_workers", "apache_connections")
+	require.NoError(t, err)
+	require.Equal(t, "apache.example.com", gotHost)
+}
+
+func TestParseStatus_Scoreboard(t *testing.T) {
+	s, err := parseStatus(strings.NewReader(statusPage))
+	require.NoError(t, err)
+	require.Equal(t, "_W_K_R__...", s.Scoreboard)
+	require.Equal(t, 2350.0, *s.Duration)
+
+	// Values not reported by the server should be left unset.
+	s, err = parseStatus(strings.NewReader("Total Accesses: 5\nScoreboard: __\n"))
+	require.NoError(t, err)
+	require.Nil(t, s.Duration)
+}
+
+func TestParseStatus_NotAuto(t *testing.T) {
+	_, err := parseStatus(strings.NewReader("<html><body>Apache Server Status</body></html>"))
+	require.Error(t, err)
+}
+
+func TestCollector_Down(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	c := newCollector(log.NewNopLogger(), srv.Client(), srv.URL, "")
+	expect := `
+# HELP apache_up Could the apache server be reached.
+# TYPE apache_up gauge
+apache_up 0
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect)))
+}
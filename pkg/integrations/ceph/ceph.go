@@ -0,0 +1,110 @@
+// Package ceph implements an integration which collects metrics from Ceph
+// clusters. Metrics from the ceph-mgr prometheus module are proxied, and
+// per-bucket and per-pool statistics are collected from the RADOS Gateway
+// admin API.
+package ceph
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig holds the default settings for the ceph integration.
+var DefaultConfig = Config{
+	MgrURL:  "http://localhost:9283/metrics",
+	Timeout: 10 * time.Second,
+}
+
+// Config controls the ceph integration.
+type Config struct {
+	// MgrURL is the URL of the metrics endpoint of the ceph-mgr prometheus
+	// module. Proxying mgr metrics is disabled when empty.
+	MgrURL string `yaml:"mgr_url,omitempty"`
+	// RGW configures collecting statistics from the RADOS Gateway admin API.
+	RGW *RGWConfig `yaml:"rgw,omitempty"`
+	// Timeout for requests to Ceph.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// TLSConfig configures TLS for requests to Ceph.
+	TLSConfig config_util.TLSConfig `yaml:"tls_config,omitempty"`
+}
+
+// RGWConfig configures the RADOS Gateway admin API client.
+type RGWConfig struct {
+	// Endpoint of the RADOS Gateway, e.g., http://localhost:7480.
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// AccessKey of a user with the buckets=read and usage=read caps.
+	AccessKey string `yaml:"access_key,omitempty"`
+	// SecretKey of the user.
+	SecretKey config_util.Secret `yaml:"secret_key,omitempty"`
+	// CollectUsage enables collecting per-user usage statistics. Requires
+	// the usage log to be enabled in the RADOS Gateway.
+	CollectUsage bool `yaml:"collect_usage,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for RGWConfig.
+func (c *RGWConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain RGWConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.Endpoint == "" {
+		return fmt.Errorf("rgw: endpoint must be set")
+	}
+	if c.AccessKey == "" || c.SecretKey == "" {
+		return fmt.Errorf("rgw: access_key and secret_key must be set")
+	}
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.MgrURL == "" && c.RGW == nil {
+		return fmt.Errorf("at least one of mgr_url and rgw must be set")
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "ceph"
+}
+
+// InstanceKey returns the host:port of the ceph-mgr, or of the RADOS Gateway
+// when mgr metrics aren't collected.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	uri := c.MgrURL
+	if uri == "" {
+		uri = c.RGW.Endpoint
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("could not parse url: %w", err)
+	}
+	return u.Host, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
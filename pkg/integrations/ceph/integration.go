@@ -0,0 +1,109 @@
+package ceph
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/expfmt"
+)
+
+// Integration is the ceph integration. Metrics from the ceph-mgr prometheus
+// module are merged with metrics collected from the RADOS Gateway.
+type Integration struct {
+	c         *Config
+	gatherers prometheus.Gatherers
+}
+
+// New creates a new ceph integration.
+func New(logger log.Logger, c *Config) (*Integration, error) {
+	tlsConfig, err := config_util.NewTLSConfig(&c.TLSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tls config: %w", err)
+	}
+	client := &http.Client{
+		Timeout: c.Timeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	i := &Integration{c: c}
+	if c.MgrURL != "" {
+		i.gatherers = append(i.gatherers, &mgrGatherer{client: client, url: c.MgrURL})
+	}
+	if c.RGW != nil {
+		r := prometheus.NewRegistry()
+		if err := r.Register(newRGWCollector(logger, client, c.RGW)); err != nil {
+			return nil, fmt.Errorf("couldn't register rgw collector: %w", err)
+		}
+		i.gatherers = append(i.gatherers, r)
+	}
+	return i, nil
+}
+
+// MetricsHandler satisfies Integration.RegisterRoutes.
+func (i *Integration) MetricsHandler() (http.Handler, error) {
+	return promhttp.HandlerFor(
+		i.gatherers,
+		promhttp.HandlerOpts{
+			ErrorHandling: promhttp.ContinueOnError,
+		},
+	), nil
+}
+
+// ScrapeConfigs satisfies Integration.ScrapeConfigs.
+func (i *Integration) ScrapeConfigs() []config.ScrapeConfig {
+	return []config.ScrapeConfig{{
+		JobName:     i.c.Name(),
+		MetricsPath: "/metrics",
+	}}
+}
+
+// Run satisfies Integration.Run.
+func (i *Integration) Run(ctx context.Context) error {
+	// We don't need to do anything here, so we can just wait for the context to
+	// finish.
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// mgrGatherer gathers metrics by scraping the ceph-mgr prometheus module.
+type mgrGatherer struct {
+	client *http.Client
+	url    string
+}
+
+// Gather implements prometheus.Gatherer.
+func (g *mgrGatherer) Gather() ([]*dto.MetricFamily, error) {
+	resp, err := g.client.Get(g.url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scrape ceph-mgr: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to scrape ceph-mgr: unexpected status code %d", resp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ceph-mgr metrics: %w", err)
+	}
+
+	res := make([]*dto.MetricFamily, 0, len(families))
+	for _, mf := range families {
+		res = append(res, mf)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].GetName() < res[j].GetName() })
+	return res, nil
+}
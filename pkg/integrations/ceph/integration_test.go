@@ -0,0 +1,109 @@
+package ceph
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+)
+
+const mgrMetrics = `# HELP ceph_health_status Cluster health status
+# TYPE ceph_health_status untyped
+ceph_health_status 0.0
+# HELP ceph_pool_stored DF pool stored
+# TYPE ceph_pool_stored untyped
+ceph_pool_stored{pool_id="1"} 1024.0
+`
+
+const rgwBuckets = `[
+	{
+		"bucket": "photos",
+		"owner": "alice",
+		"explicit_placement": {"data_pool": ""},
+		"placement_rule": "default-placement",
+		"usage": {"rgw.main": {"size": 2048, "size_actual": 8192, "num_objects": 2}},
+		"bucket_quota": {"enabled": true, "max_size": 1048576, "max_objects": 100}
+	},
+	{
+		"bucket": "logs",
+		"owner": "bob",
+		"pool": "default.rgw.buckets.data",
+		"usage": {"rgw.main": {"size": 100, "size_actual": 4096, "num_objects": 1}},
+		"bucket_quota": {"enabled": false}
+	}
+]`
+
+func TestIntegration(t *testing.T) {
+	mgr := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(mgrMetrics))
+	}))
+	defer mgr.Close()
+
+	rgw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS access:") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/admin/bucket":
+			_, _ = w.Write([]byte(rgwBuckets))
+		case "/admin/usage":
+			_, _ = w.Write([]byte(`{"summary":[{"user":"alice","total":{"bytes_sent":10,"bytes_received":20,"ops":3,"successful_ops":2}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer rgw.Close()
+
+	i, err := New(log.NewNopLogger(), &Config{
+		MgrURL:  mgr.URL,
+		Timeout: time.Second,
+		RGW: &RGWConfig{
+			Endpoint:     rgw.URL,
+			AccessKey:    "access",
+			SecretKey:    "secret",
+			CollectUsage: true,
+		},
+	})
+	require.NoError(t, err)
+
+	h, err := i.MetricsHandler()
+	require.NoError(t, err)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	bb, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	for _, expect := range []string{
+		`ceph_health_status 0`,
+		`ceph_pool_stored{pool_id="1"} 1024`,
+		`ceph_rgw_up 1`,
+		`ceph_rgw_bucket_size_bytes{bucket="photos",owner="alice",pool="default-placement"} 2048`,
+		`ceph_rgw_bucket_quota_max_objects{bucket="photos",owner="alice",pool="default-placement"} 100`,
+		`ceph_rgw_pool_objects{pool="default.rgw.buckets.data"} 1`,
+		`ceph_rgw_user_ops_total{user="alice"} 3`,
+	} {
+		require.Contains(t, string(bb), expect)
+	}
+	require.NotContains(t, string(bb), `ceph_rgw_bucket_quota_max_objects{bucket="logs"`)
+}
+
+func TestRGWCollector_Sign(t *testing.T) {
+	c := newRGWCollector(log.NewNopLogger(), http.DefaultClient, &RGWConfig{AccessKey: "access", SecretKey: "secret"})
+	c.now = func() time.Time { return time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/bucket?format=json&stats=true", nil)
+	c.sign(req, "/admin/bucket")
+
+	require.Equal(t, "Sat, 01 Jan 2022 00:00:00 GMT", req.Header.Get("Date"))
+	require.Equal(t, "AWS access:a59DqL/K0tvpol7duwowdiJ2iGg=", req.Header.Get("Authorization"))
+}
@@ -3,6 +3,7 @@
 package config
 
 import (
+	"net/url"
 	"time"
 
 	"github.com/prometheus/prometheus/pkg/relabel"
@@ -39,4 +40,8 @@ type ScrapeConfig struct {
 	// The path will be prepended by "/integrations/<integration name>" when read by
 	// the integrations manager.
 	MetricsPath string
+
+	// QueryParams are added to the URL of scrape requests. Integrations which
+	// probe multiple targets use them to select the target of each scrape.
+	QueryParams url.Values
 }
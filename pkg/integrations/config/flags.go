@@ -0,0 +1,54 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Flags holds settings of integrations which can only be set with command
+// line flags. Unlike the integrations config, which may be changed through
+// remote configs or the config management API, flags can only be set by
+// whoever starts the Agent.
+type Flags struct {
+	// ScriptExporterAllowedCommands holds the glob patterns of the commands
+	// script_exporter scripts may execute.
+	ScriptExporterAllowedCommands CommandPatterns
+}
+
+// RegisterFlags registers the flags of integrations to fs.
+func (f *Flags) RegisterFlags(fs *flag.FlagSet) {
+	fs.Var(&f.ScriptExporterAllowedCommands, "integrations.script-exporter.allowed-commands",
+		"Glob pattern of the commands script_exporter scripts may execute. May be given multiple times.")
+}
+
+// CommandPatterns is a flag.Value of glob patterns of commands.
+type CommandPatterns []string
+
+// String implements flag.Value.
+func (p *CommandPatterns) String() string {
+	if p == nil {
+		return ""
+	}
+	return strings.Join(*p, ",")
+}
+
+// Set implements flag.Value.
+func (p *CommandPatterns) Set(pattern string) error {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	*p = append(*p, pattern)
+	return nil
+}
+
+// Allowed returns whether command matches any of the patterns.
+func (p CommandPatterns) Allowed(command string) bool {
+	for _, pattern := range p {
+		if ok, _ := filepath.Match(pattern, command); ok {
+			return true
+		}
+	}
+	return false
+}
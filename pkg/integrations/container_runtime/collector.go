@@ -0,0 +1,205 @@
+package container_runtime //nolint:golint
+
+import (
+	"context"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "container_runtime"
+
+// containerStats holds the state and resource usage of a container.
+type containerStats struct {
+	// Namespace is the containerd namespace of the container. It is empty
+	// for Docker.
+	Namespace string
+	Name      string
+	Image     string
+	Running   bool
+
+	// Restarts is the number of restarts of the container, if known.
+	Restarts    *float64
+	HasUsage    bool
+	CPUSeconds  float64
+	MemoryUsage float64
+	// MemoryWorkingSet is the memory usage without inactive file cache.
+	MemoryWorkingSet float64
+	// MemoryLimit is the memory limit of the container, or 0 if unlimited.
+	MemoryLimit float64
+	Networks    map[string]networkStats
+}
+
+type networkStats struct {
+	RxBytes, RxPackets, RxErrors float64
+	TxBytes, TxPackets, TxErrors float64
+}
+
+// runtimeClient retrieves the stats of all containers of a runtime.
+type runtimeClient interface {
+	stats(ctx context.Context) ([]containerStats, error)
+}
+
+var (
+	containerLabels = []string{"namespace", "name", "image"}
+	networkLabels   = append(append([]string{}, containerLabels...), "interface")
+
+	upDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "up"),
+		"Whether the container runtime could be reached.",
+		nil, nil,
+	)
+	runningDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "container_running"),
+		"Whether the container is running.",
+		containerLabels, nil,
+	)
+	restartsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "container_restarts_total"),
+		"Number of times the container was restarted by the runtime.",
+		containerLabels, nil,
+	)
+	cpuDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "cpu_usage_seconds_total"),
+		"Cumulative CPU time consumed by the container.",
+		containerLabels, nil,
+	)
+	memoryUsageDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "memory_usage_bytes"),
+		"Memory usage of the container, including file cache.",
+		containerLabels, nil,
+	)
+	memoryWorkingSetDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "memory_working_set_bytes"),
+		"Memory usage of the container, excluding inactive file cache.",
+		containerLabels, nil,
+	)
+	memoryLimitDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "memory_limit_bytes"),
+		"Memory limit of the container.",
+		containerLabels, nil,
+	)
+	rxBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "network_receive_bytes_total"),
+		"Bytes received by the container.",
+		networkLabels, nil,
+	)
+	rxPacketsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "network_receive_packets_total"),
+		"Packets received by the container.",
+		networkLabels, nil,
+	)
+	rxErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "network_receive_errors_total"),
+		"Errors while receiving packets by the container.",
+		networkLabels, nil,
+	)
+	txBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "network_transmit_bytes_total"),
+		"Bytes transmitted by the container.",
+		networkLabels, nil,
+	)
+	txPacketsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "network_transmit_packets_total"),
+		"Packets transmitted by the container.",
+		networkLabels, nil,
+	)
+	txErrorsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "network_transmit_errors_total"),
+		"Errors while transmitting packets by the container.",
+		networkLabels, nil,
+	)
+)
+
+type collector struct {
+	log    log.Logger
+	cfg    *Config
+	client runtimeClient
+}
+
+func newCollector(l log.Logger, c *Config, client runtimeClient) *collector {
+	return &collector{log: l, cfg: c, client: client}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		upDesc, runningDesc, restartsDesc, cpuDesc,
+		memoryUsageDesc, memoryWorkingSetDesc, memoryLimitDesc,
+		rxBytesDesc, rxPacketsDesc, rxErrorsDesc,
+		txBytesDesc, txPacketsDesc, txErrorsDesc,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+
+	containers, err := c.client.stats(ctx)
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to collect container stats", "runtime", c.cfg.Runtime, "err", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
+
+	for _, s := range containers {
+		labels := []string{s.Namespace, s.Name, s.Image}
+		gauge := func(desc *prometheus.Desc, v float64, extra ...string) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, append(labels, extra...)...)
+		}
+		counter := func(desc *prometheus.Desc, v float64, extra ...string) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v, append(labels, extra...)...)
+		}
+
+		running := 0.0
+		if s.Running {
+			running = 1
+		}
+		gauge(runningDesc, running)
+		if s.Restarts != nil {
+			counter(restartsDesc, *s.Restarts)
+		}
+
+		if !s.HasUsage {
+			continue
+		}
+		counter(cpuDesc, s.CPUSeconds)
+		gauge(memoryUsageDesc, s.MemoryUsage)
+		gauge(memoryWorkingSetDesc, s.MemoryWorkingSet)
+		if s.MemoryLimit > 0 {
+			gauge(memoryLimitDesc, s.MemoryLimit)
+		}
+		for iface, n := range s.Networks {
+			counter(rxBytesDesc, n.RxBytes, iface)
+			counter(rxPacketsDesc, n.RxPackets, iface)
+			counter(rxErrorsDesc, n.RxErrors, iface)
+			counter(txBytesDesc, n.TxBytes, iface)
+			counter(txPacketsDesc, n.TxPackets, iface)
+			counter(txErrorsDesc, n.TxErrors, iface)
+		}
+	}
+}
+
+// workingSet returns the memory usage without inactive file cache.
+func workingSet(usage, inactiveFile uint64) float64 {
+	if inactiveFile > usage {
+		return 0
+	}
+	return float64(usage - inactiveFile)
+}
+
+// memoryLimit returns limit, or 0 if limit is so large that the container
+// has no limit.
+func memoryLimit(limit uint64) float64 {
+	// cgroups v1 reports an unlimited limit as the maximum int64 rounded
+	// down to a page, while cgroups v2 reports the maximum uint64.
+	if limit >= 1<<62 {
+		return 0
+	}
+	return float64(limit)
+}
@@ -0,0 +1,139 @@
+// Package container_runtime implements an integration which collects
+// resource usage of containers directly from the API of Docker or
+// containerd, for hosts which do not run cAdvisor or the kubelet.
+package container_runtime //nolint:golint
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+)
+
+// Supported container runtimes.
+const (
+	RuntimeDocker     = "docker"
+	RuntimeContainerd = "containerd"
+)
+
+// Default addresses of the container runtimes.
+const (
+	DefaultDockerAddress     = "unix:///var/run/docker.sock"
+	DefaultContainerdAddress = "/run/containerd/containerd.sock"
+)
+
+// DefaultConfig holds the default settings for the container_runtime
+// integration.
+var DefaultConfig = Config{
+	Runtime:    RuntimeDocker,
+	ProcfsPath: "/proc",
+	Timeout:    10 * time.Second,
+}
+
+// Config controls the container_runtime integration.
+type Config struct {
+	// Runtime is the container runtime to collect metrics from, either docker
+	// or containerd.
+	Runtime string `yaml:"runtime,omitempty"`
+	// Address of the API of the runtime. Defaults to the default socket of
+	// the runtime.
+	Address string `yaml:"address,omitempty"`
+	// ContainerdNamespaces are the containerd namespaces to collect metrics
+	// for. Metrics for all namespaces are collected when empty.
+	ContainerdNamespaces []string `yaml:"containerd_namespaces,omitempty"`
+	// ProcfsPath is the mount point of the proc filesystem of the host, used
+	// to read network statistics of containerd containers.
+	ProcfsPath string `yaml:"procfs_path,omitempty"`
+	// Timeout for collecting metrics.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	switch c.Runtime {
+	case RuntimeDocker:
+		if c.Address == "" {
+			c.Address = DefaultDockerAddress
+		}
+	case RuntimeContainerd:
+		if c.Address == "" {
+			c.Address = DefaultContainerdAddress
+		}
+	default:
+		return fmt.Errorf("unsupported runtime %q", c.Runtime)
+	}
+
+	if len(c.ContainerdNamespaces) > 0 && c.Runtime != RuntimeContainerd {
+		return fmt.Errorf("containerd_namespaces can only be set for the containerd runtime")
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "container_runtime"
+}
+
+// InstanceKey returns the agent key for local sockets, and the host:port of
+// the address otherwise.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	if strings.HasPrefix(c.Address, "/") || strings.HasPrefix(c.Address, "unix://") {
+		return agentKey, nil
+	}
+
+	u, err := url.Parse(c.Address)
+	if err != nil {
+		return "", fmt.Errorf("could not parse address: %w", err)
+	}
+	if u.Host == "" {
+		// Addresses without a scheme, e.g., localhost:2375.
+		return c.Address, nil
+	}
+	return u.Host, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
+
+// New creates a new container_runtime integration.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	var (
+		client runtimeClient
+		err    error
+	)
+	switch c.Runtime {
+	case RuntimeDocker:
+		client, err = newDockerClient(c)
+	case RuntimeContainerd:
+		client, err = newContainerdClient(logger, c)
+	default:
+		err = fmt.Errorf("unsupported runtime %q", c.Runtime)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(newCollector(logger, c, client)),
+	), nil
+}
@@ -0,0 +1,219 @@
+package container_runtime //nolint:golint
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	cgroupsv1 "github.com/containerd/cgroups/stats/v1"
+	cgroupsv2 "github.com/containerd/cgroups/v2/stats"
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	namespacesapi "github.com/containerd/containerd/api/services/namespaces/v1"
+	tasksapi "github.com/containerd/containerd/api/services/tasks/v1"
+	"github.com/containerd/containerd/api/types/task"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gogo/protobuf/types"
+	"github.com/prometheus/procfs"
+	"google.golang.org/grpc"
+)
+
+// Type URLs of the metrics of containerd tasks.
+const (
+	cgroupsV1MetricsType = "io.containerd.cgroups.v1.Metrics"
+	cgroupsV2MetricsType = "io.containerd.cgroups.v2.Metrics"
+)
+
+// Labels set on containers by containerd clients.
+const (
+	// restartCountLabel is set by the restart monitor of containerd.
+	restartCountLabel = "containerd.io/restart.count"
+	// nerdctlNameLabel holds the name of containers created by nerdctl.
+	nerdctlNameLabel = "nerdctl/name"
+)
+
+// containerdClient collects container stats from the containerd gRPC API.
+type containerdClient struct {
+	log        log.Logger
+	namespaces []string
+	procfsPath string
+
+	namespacesClient namespacesapi.NamespacesClient
+	containersClient containersapi.ContainersClient
+	tasksClient      tasksapi.TasksClient
+}
+
+func newContainerdClient(l log.Logger, c *Config) (*containerdClient, error) {
+	target := c.Address
+	if strings.HasPrefix(target, "/") {
+		target = "unix://" + target
+	}
+
+	// The connection is established lazily, so that the integration starts
+	// even if containerd is not running yet.
+	conn, err := grpc.Dial(target, grpc.WithInsecure())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create containerd client: %w", err)
+	}
+
+	return &containerdClient{
+		log:        l,
+		namespaces: c.ContainerdNamespaces,
+		procfsPath: c.ProcfsPath,
+
+		namespacesClient: namespacesapi.NewNamespacesClient(conn),
+		containersClient: containersapi.NewContainersClient(conn),
+		tasksClient:      tasksapi.NewTasksClient(conn),
+	}, nil
+}
+
+func (c *containerdClient) stats(ctx context.Context) ([]containerStats, error) {
+	nss := c.namespaces
+	if len(nss) == 0 {
+		resp, err := c.namespacesClient.List(ctx, &namespacesapi.ListNamespacesRequest{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list namespaces: %w", err)
+		}
+		for _, ns := range resp.Namespaces {
+			nss = append(nss, ns.Name)
+		}
+	}
+
+	var res []containerStats
+	for _, ns := range nss {
+		stats, err := c.namespaceStats(namespaces.WithNamespace(ctx, ns), ns)
+		if err != nil {
+			return nil, fmt.Errorf("namespace %s: %w", ns, err)
+		}
+		res = append(res, stats...)
+	}
+	return res, nil
+}
+
+func (c *containerdClient) namespaceStats(ctx context.Context, ns string) ([]containerStats, error) {
+	containers, err := c.containersClient.List(ctx, &containersapi.ListContainersRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	tasks, err := c.tasksClient.List(ctx, &tasksapi.ListTasksRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks: %w", err)
+	}
+	metrics, err := c.tasksClient.Metrics(ctx, &tasksapi.MetricsRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get task metrics: %w", err)
+	}
+
+	tasksByID := make(map[string]*task.Process, len(tasks.Tasks))
+	for _, t := range tasks.Tasks {
+		tasksByID[t.ID] = t
+	}
+	metricsByID := make(map[string]*types.Any, len(metrics.Metrics))
+	for _, m := range metrics.Metrics {
+		metricsByID[m.ID] = m.Data
+	}
+
+	res := make([]containerStats, 0, len(containers.Containers))
+	for _, ctr := range containers.Containers {
+		s := containerStats{
+			Namespace: ns,
+			Name:      ctr.ID,
+			Image:     ctr.Image,
+		}
+		if name := ctr.Labels[nerdctlNameLabel]; name != "" {
+			s.Name = name
+		}
+		if v, err := strconv.ParseFloat(ctr.Labels[restartCountLabel], 64); err == nil {
+			s.Restarts = &v
+		}
+
+		t, ok := tasksByID[ctr.ID]
+		s.Running = ok && t.Status == task.StatusRunning
+		if data := metricsByID[ctr.ID]; s.Running && data != nil {
+			if err := c.setUsage(&s, data); err != nil {
+				level.Debug(c.log).Log("msg", "failed to parse task metrics", "namespace", ns, "container", ctr.ID, "err", err)
+			}
+			s.Networks = c.networkStats(t.Pid)
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
+// setUsage sets the resource usage of s from the cgroup metrics of a task.
+func (c *containerdClient) setUsage(s *containerStats, data *types.Any) error {
+	switch data.TypeUrl {
+	case cgroupsV1MetricsType:
+		var m cgroupsv1.Metrics
+		if err := m.Unmarshal(data.Value); err != nil {
+			return err
+		}
+		if m.CPU != nil && m.CPU.Usage != nil {
+			s.CPUSeconds = float64(m.CPU.Usage.Total) / 1e9
+		}
+		if m.Memory != nil && m.Memory.Usage != nil {
+			s.MemoryUsage = float64(m.Memory.Usage.Usage)
+			s.MemoryWorkingSet = workingSet(m.Memory.Usage.Usage, m.Memory.TotalInactiveFile)
+			s.MemoryLimit = memoryLimit(m.Memory.Usage.Limit)
+		}
+
+	case cgroupsV2MetricsType:
+		var m cgroupsv2.Metrics
+		if err := m.Unmarshal(data.Value); err != nil {
+			return err
+		}
+		if m.CPU != nil {
+			s.CPUSeconds = float64(m.CPU.UsageUsec) / 1e6
+		}
+		if m.Memory != nil {
+			s.MemoryUsage = float64(m.Memory.Usage)
+			s.MemoryWorkingSet = workingSet(m.Memory.Usage, m.Memory.InactiveFile)
+			s.MemoryLimit = memoryLimit(m.Memory.UsageLimit)
+		}
+
+	default:
+		return fmt.Errorf("unsupported metrics type %q", data.TypeUrl)
+	}
+
+	s.HasUsage = true
+	return nil
+}
+
+// networkStats reads the network statistics of the network namespace of a
+// process. containerd does not report network statistics, so they are read
+// from the proc filesystem of the host.
+func (c *containerdClient) networkStats(pid uint32) map[string]networkStats {
+	fs, err := procfs.NewFS(c.procfsPath)
+	if err != nil {
+		level.Debug(c.log).Log("msg", "failed to open procfs", "err", err)
+		return nil
+	}
+	p, err := fs.Proc(int(pid))
+	if err != nil {
+		level.Debug(c.log).Log("msg", "failed to read process", "pid", pid, "err", err)
+		return nil
+	}
+	netDev, err := p.NetDev()
+	if err != nil {
+		level.Debug(c.log).Log("msg", "failed to read network statistics", "pid", pid, "err", err)
+		return nil
+	}
+
+	res := make(map[string]networkStats, len(netDev))
+	for iface, n := range netDev {
+		if iface == "lo" {
+			continue
+		}
+		res[iface] = networkStats{
+			RxBytes:   float64(n.RxBytes),
+			RxPackets: float64(n.RxPackets),
+			RxErrors:  float64(n.RxErrors),
+			TxBytes:   float64(n.TxBytes),
+			TxPackets: float64(n.TxPackets),
+			TxErrors:  float64(n.TxErrors),
+		}
+	}
+	return res
+}
@@ -0,0 +1,163 @@
+package container_runtime //nolint:golint
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cgroupsv2 "github.com/containerd/cgroups/v2/stats"
+	containersapi "github.com/containerd/containerd/api/services/containers/v1"
+	namespacesapi "github.com/containerd/containerd/api/services/namespaces/v1"
+	tasksapi "github.com/containerd/containerd/api/services/tasks/v1"
+	apitypes "github.com/containerd/containerd/api/types"
+	"github.com/containerd/containerd/api/types/task"
+	"github.com/containerd/containerd/namespaces"
+	"github.com/go-kit/log"
+	"github.com/gogo/protobuf/types"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"gopkg.in/yaml.v2"
+)
+
+type fakeNamespaces struct {
+	namespacesapi.UnimplementedNamespacesServer
+}
+
+func (fakeNamespaces) List(context.Context, *namespacesapi.ListNamespacesRequest) (*namespacesapi.ListNamespacesResponse, error) {
+	return &namespacesapi.ListNamespacesResponse{
+		Namespaces: []namespacesapi.Namespace{{Name: "default"}, {Name: "empty"}},
+	}, nil
+}
+
+type fakeContainers struct {
+	containersapi.UnimplementedContainersServer
+}
+
+func (fakeContainers) List(ctx context.Context, _ *containersapi.ListContainersRequest) (*containersapi.ListContainersResponse, error) {
+	if ns, _ := namespaces.Namespace(ctx); ns != "default" {
+		return &containersapi.ListContainersResponse{}, nil
+	}
+	return &containersapi.ListContainersResponse{
+		Containers: []containersapi.Container{
+			{ID: "c1", Image: "docker.io/library/redis:6", Labels: map[string]string{nerdctlNameLabel: "redis", restartCountLabel: "3"}},
+			{ID: "c2", Image: "docker.io/library/busybox:latest"},
+		},
+	}, nil
+}
+
+type fakeTasks struct {
+	tasksapi.UnimplementedTasksServer
+	t *testing.T
+}
+
+func (fakeTasks) List(ctx context.Context, _ *tasksapi.ListTasksRequest) (*tasksapi.ListTasksResponse, error) {
+	if ns, _ := namespaces.Namespace(ctx); ns != "default" {
+		return &tasksapi.ListTasksResponse{}, nil
+	}
+	return &tasksapi.ListTasksResponse{
+		Tasks: []*task.Process{
+			{ID: "c1", Pid: 1234, Status: task.StatusRunning},
+			{ID: "c2", Pid: 5678, Status: task.StatusStopped},
+		},
+	}, nil
+}
+
+func (f fakeTasks) Metrics(ctx context.Context, _ *tasksapi.MetricsRequest) (*tasksapi.MetricsResponse, error) {
+	if ns, _ := namespaces.Namespace(ctx); ns != "default" {
+		return &tasksapi.MetricsResponse{}, nil
+	}
+
+	m := cgroupsv2.Metrics{
+		CPU: &cgroupsv2.CPUStat{UsageUsec: 2500000},
+		Memory: &cgroupsv2.MemoryStat{
+			Usage:        52428800,
+			UsageLimit:   ^uint64(0),
+			InactiveFile: 2097152,
+		},
+	}
+	data, err := m.Marshal()
+	require.NoError(f.t, err)
+
+	return &tasksapi.MetricsResponse{
+		Metrics: []*apitypes.Metric{
+			{ID: "c1", Data: &types.Any{TypeUrl: cgroupsV2MetricsType, Value: data}},
+		},
+	}, nil
+}
+
+func TestContainerd(t *testing.T) {
+	procfsPath := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(procfsPath, "1234", "net"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(procfsPath, "1234", "net", "dev"), []byte(
+		`Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:     100       1    0    0    0     0          0         0      100       1    0    0    0     0       0          0
+  eth0:    4096      32    2    0    0     0          0         0     8192      64    0    0    0     0       0          0
+`), 0644))
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	namespacesapi.RegisterNamespacesServer(srv, &fakeNamespaces{})
+	containersapi.RegisterContainersServer(srv, &fakeContainers{})
+	tasksapi.RegisterTasksServer(srv, &fakeTasks{t: t})
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+runtime: containerd
+address: `+lis.Addr().String()+`
+procfs_path: `+procfsPath+`
+`), &cfg))
+
+	client, err := newContainerdClient(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+	col := newCollector(log.NewNopLogger(), &cfg, client)
+
+	expect := `
+# HELP container_runtime_container_restarts_total Number of times the container was restarted by the runtime.
+# TYPE container_runtime_container_restarts_total counter
+container_runtime_container_restarts_total{image="docker.io/library/redis:6",name="redis",namespace="default"} 3
+# HELP container_runtime_container_running Whether the container is running.
+# TYPE container_runtime_container_running gauge
+container_runtime_container_running{image="docker.io/library/busybox:latest",name="c2",namespace="default"} 0
+container_runtime_container_running{image="docker.io/library/redis:6",name="redis",namespace="default"} 1
+# HELP container_runtime_cpu_usage_seconds_total Cumulative CPU time consumed by the container.
+# TYPE container_runtime_cpu_usage_seconds_total counter
+container_runtime_cpu_usage_seconds_total{image="docker.io/library/redis:6",name="redis",namespace="default"} 2.5
+# HELP container_runtime_memory_usage_bytes Memory usage of the container, including file cache.
+# TYPE container_runtime_memory_usage_bytes gauge
+container_runtime_memory_usage_bytes{image="docker.io/library/redis:6",name="redis",namespace="default"} 5.24288e+07
+# HELP container_runtime_memory_working_set_bytes Memory usage of the container, excluding inactive file cache.
+# TYPE container_runtime_memory_working_set_bytes gauge
+container_runtime_memory_working_set_bytes{image="docker.io/library/redis:6",name="redis",namespace="default"} 5.0331648e+07
+# HELP container_runtime_network_receive_bytes_total Bytes received by the container.
+# TYPE container_runtime_network_receive_bytes_total counter
+container_runtime_network_receive_bytes_total{image="docker.io/library/redis:6",interface="eth0",name="redis",namespace="default"} 4096
+# HELP container_runtime_network_receive_errors_total Errors while receiving packets by the container.
+# TYPE container_runtime_network_receive_errors_total counter
+container_runtime_network_receive_errors_total{image="docker.io/library/redis:6",interface="eth0",name="redis",namespace="default"} 2
+# HELP container_runtime_network_receive_packets_total Packets received by the container.
+# TYPE container_runtime_network_receive_packets_total counter
+container_runtime_network_receive_packets_total{image="docker.io/library/redis:6",interface="eth0",name="redis",namespace="default"} 32
+# HELP container_runtime_network_transmit_bytes_total Bytes transmitted by the container.
+# TYPE container_runtime_network_transmit_bytes_total counter
+container_runtime_network_transmit_bytes_total{image="docker.io/library/redis:6",interface="eth0",name="redis",namespace="default"} 8192
+# HELP container_runtime_network_transmit_errors_total Errors while transmitting packets by the container.
+# TYPE container_runtime_network_transmit_errors_total counter
+container_runtime_network_transmit_errors_total{image="docker.io/library/redis:6",interface="eth0",name="redis",namespace="default"} 0
+# HELP container_runtime_network_transmit_packets_total Packets transmitted by the container.
+# TYPE container_runtime_network_transmit_packets_total counter
+container_runtime_network_transmit_packets_total{image="docker.io/library/redis:6",interface="eth0",name="redis",namespace="default"} 64
+# HELP container_runtime_up Whether the container runtime could be reached.
+# TYPE container_runtime_up gauge
+container_runtime_up 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+}
@@ -0,0 +1,132 @@
+package container_runtime //nolint:golint
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	docker "github.com/docker/docker/client"
+)
+
+// dockerClient collects container stats from the Docker Engine API.
+type dockerClient struct {
+	client *docker.Client
+}
+
+func newDockerClient(c *Config) (*dockerClient, error) {
+	client, err := docker.NewClientWithOpts(
+		docker.WithHost(c.Address),
+		docker.WithAPIVersionNegotiation(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker client: %w", err)
+	}
+	return &dockerClient{client: client}, nil
+}
+
+func (d *dockerClient) stats(ctx context.Context) ([]containerStats, error) {
+	containers, err := d.client.ContainerList(ctx, types.ContainerListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mut  sync.Mutex
+		errs []string
+		res  = make([]containerStats, 0, len(containers))
+	)
+	for _, c := range containers {
+		wg.Add(1)
+		go func(c types.Container) {
+			defer wg.Done()
+
+			s, err := d.containerStats(ctx, c)
+
+			mut.Lock()
+			defer mut.Unlock()
+			switch {
+			case docker.IsErrNotFound(err):
+				// The container was removed after listing it.
+			case err != nil:
+				errs = append(errs, fmt.Sprintf("%s: %s", c.ID, err))
+			default:
+				res = append(res, s)
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("failed to get container stats: %s", strings.Join(errs, "; "))
+	}
+	return res, nil
+}
+
+func (d *dockerClient) containerStats(ctx context.Context, c types.Container) (containerStats, error) {
+	s := containerStats{
+		Name:    containerName(c),
+		Image:   c.Image,
+		Running: c.State == "running",
+	}
+
+	inspect, err := d.client.ContainerInspect(ctx, c.ID)
+	if err != nil {
+		return s, err
+	}
+	if inspect.ContainerJSONBase != nil {
+		restarts := float64(inspect.RestartCount)
+		s.Restarts = &restarts
+	}
+
+	if !s.Running {
+		return s, nil
+	}
+
+	resp, err := d.client.ContainerStatsOneShot(ctx, c.ID)
+	if err != nil {
+		return s, err
+	}
+	defer resp.Body.Close()
+
+	var stats types.StatsJSON
+	if err := json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return s, fmt.Errorf("failed to parse stats: %w", err)
+	}
+
+	// The inactive file cache is reported as total_inactive_file for
+	// cgroups v1, and as inactive_file for cgroups v2.
+	inactiveFile, ok := stats.MemoryStats.Stats["total_inactive_file"]
+	if !ok {
+		inactiveFile = stats.MemoryStats.Stats["inactive_file"]
+	}
+
+	s.HasUsage = true
+	s.CPUSeconds = float64(stats.CPUStats.CPUUsage.TotalUsage) / 1e9
+	s.MemoryUsage = float64(stats.MemoryStats.Usage)
+	s.MemoryWorkingSet = workingSet(stats.MemoryStats.Usage, inactiveFile)
+	s.MemoryLimit = memoryLimit(stats.MemoryStats.Limit)
+	s.Networks = make(map[string]networkStats, len(stats.Networks))
+	for iface, n := range stats.Networks {
+		s.Networks[iface] = networkStats{
+			RxBytes:   float64(n.RxBytes),
+			RxPackets: float64(n.RxPackets),
+			RxErrors:  float64(n.RxErrors),
+			TxBytes:   float64(n.TxBytes),
+			TxPackets: float64(n.TxPackets),
+			TxErrors:  float64(n.TxErrors),
+		}
+	}
+	return s, nil
+}
+
+// containerName returns the name of a container without the leading slash.
+func containerName(c types.Container) string {
+	if len(c.Names) == 0 {
+		return c.ID
+	}
+	return strings.TrimPrefix(c.Names[0], "/")
+}
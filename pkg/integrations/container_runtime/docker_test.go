@@ -0,0 +1,164 @@
+package container_runtime //nolint:golint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestDocker(t *testing.T) {
+	responses := map[string]string{
+		"/containers/json": `[
+			{"Id": "abc", "Names": ["/web"], "Image": "nginx:1.21", "State": "running"},
+			{"Id": "def", "Names": ["/job"], "Image": "busybox", "State": "exited"},
+			{"Id": "gone", "Names": ["/gone"], "Image": "busybox", "State": "running"}
+		]`,
+		"/containers/abc/json": `{"Id": "abc", "RestartCount": 2}`,
+		"/containers/def/json": `{"Id": "def", "RestartCount": 0}`,
+		"/containers/abc/stats": `{
+			"cpu_stats": {"cpu_usage": {"total_usage": 1500000000}},
+			"memory_stats": {"usage": 104857600, "limit": 536870912, "stats": {"inactive_file": 4194304}},
+			"networks": {"eth0": {"rx_bytes": 1000, "rx_packets": 10, "rx_errors": 1, "tx_bytes": 2000, "tx_packets": 20, "tx_errors": 0}}
+		}`,
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Api-Version", "1.41")
+		w.Header().Set("Content-Type", "application/json")
+
+		path := r.URL.Path
+		if i := strings.Index(path[1:], "/"); strings.HasPrefix(path, "/v1.") && i > 0 {
+			path = path[i+1:]
+		}
+		if path == "/_ping" {
+			_, _ = w.Write([]byte("OK"))
+			return
+		}
+		if path == "/containers/abc/stats" {
+			require.Equal(t, "1", r.URL.Query().Get("one-shot"))
+		}
+
+		body, ok := responses[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message": "No such container"}`))
+			return
+		}
+		_, _ = w.Write([]byte(body))
+	}))
+	defer srv.Close()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte("address: "+strings.Replace(srv.URL, "http://", "tcp://", 1)), &cfg))
+
+	client, err := newDockerClient(&cfg)
+	require.NoError(t, err)
+	col := newCollector(log.NewNopLogger(), &cfg, client)
+
+	expect := `
+# HELP container_runtime_container_restarts_total Number of times the container was restarted by the runtime.
+# TYPE container_runtime_container_restarts_total counter
+container_runtime_container_restarts_total{image="busybox",name="job",namespace=""} 0
+container_runtime_container_restarts_total{image="nginx:1.21",name="web",namespace=""} 2
+# HELP container_runtime_container_running Whether the container is running.
+# TYPE container_runtime_container_running gauge
+container_runtime_container_running{image="busybox",name="job",namespace=""} 0
+container_runtime_container_running{image="nginx:1.21",name="web",namespace=""} 1
+# HELP container_runtime_cpu_usage_seconds_total Cumulative CPU time consumed by the container.
+# TYPE container_runtime_cpu_usage_seconds_total counter
+container_runtime_cpu_usage_seconds_total{image="nginx:1.21",name="web",namespace=""} 1.5
+# HELP container_runtime_memory_limit_bytes Memory limit of the container.
+# TYPE container_runtime_memory_limit_bytes gauge
+container_runtime_memory_limit_bytes{image="nginx:1.21",name="web",namespace=""} 5.36870912e+08
+# HELP container_runtime_memory_usage_bytes Memory usage of the container, including file cache.
+# TYPE container_runtime_memory_usage_bytes gauge
+container_runtime_memory_usage_bytes{image="nginx:1.21",name="web",namespace=""} 1.048576e+08
+# HELP container_runtime_memory_working_set_bytes Memory usage of the container, excluding inactive file cache.
+# TYPE container_runtime_memory_working_set_bytes gauge
+container_runtime_memory_working_set_bytes{image="nginx:1.21",name="web",namespace=""} 1.00663296e+08
+# HELP container_runtime_network_receive_bytes_total Bytes received by the container.
+# TYPE container_runtime_network_receive_bytes_total counter
+container_runtime_network_receive_bytes_total{image="nginx:1.21",interface="eth0",name="web",namespace=""} 1000
+# HELP container_runtime_network_receive_errors_total Errors while receiving packets by the container.
+# TYPE container_runtime_network_receive_errors_total counter
+container_runtime_network_receive_errors_total{image="nginx:1.21",interface="eth0",name="web",namespace=""} 1
+# HELP container_runtime_network_receive_packets_total Packets received by the container.
+# TYPE container_runtime_network_receive_packets_total counter
+container_runtime_network_receive_packets_total{image="nginx:1.21",interface="eth0",name="web",namespace=""} 10
+# HELP container_runtime_network_transmit_bytes_total Bytes transmitted by the container.
+# TYPE container_runtime_network_transmit_bytes_total counter
+container_runtime_network_transmit_bytes_total{image="nginx:1.21",interface="eth0",name="web",namespace=""} 2000
+# HELP container_runtime_network_transmit_errors_total Errors while transmitting packets by the container.
+# TYPE container_runtime_network_transmit_errors_total counter
+container_runtime_network_transmit_errors_total{image="nginx:1.21",interface="eth0",name="web",namespace=""} 0
+# HELP container_runtime_network_transmit_packets_total Packets transmitted by the container.
+# TYPE container_runtime_network_transmit_packets_total counter
+container_runtime_network_transmit_packets_total{image="nginx:1.21",interface="eth0",name="web",namespace=""} 20
+# HELP container_runtime_up Whether the container runtime could be reached.
+# TYPE container_runtime_up gauge
+container_runtime_up 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+}
+
+func TestConfig(t *testing.T) {
+	tt := []struct {
+		name    string
+		config  string
+		address string
+		key     string
+		err     string
+	}{
+		{
+			name:    "docker default",
+			config:  "runtime: docker",
+			address: DefaultDockerAddress,
+			key:     "agent",
+		},
+		{
+			name:    "containerd default",
+			config:  "runtime: containerd\ncontainerd_namespaces: [default]",
+			address: DefaultContainerdAddress,
+			key:     "agent",
+		},
+		{
+			name:    "remote docker",
+			config:  "address: tcp://docker-host:2375",
+			address: "tcp://docker-host:2375",
+			key:     "docker-host:2375",
+		},
+		{
+			name:   "unknown runtime",
+			config: "runtime: podman",
+			err:    `unsupported runtime "podman"`,
+		},
+		{
+			name:   "namespaces for docker",
+			config: "containerd_namespaces: [default]",
+			err:    "containerd_namespaces can only be set for the containerd runtime",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			err := yaml.Unmarshal([]byte(tc.config), &cfg)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.address, cfg.Address)
+
+			key, err := cfg.InstanceKey("agent")
+			require.NoError(t, err)
+			require.Equal(t, tc.key, key)
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package dns_server //nolint:golint
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// bindStatistics is the document served by /xml/v3/server of the BIND
+// statistics channel.
+type bindStatistics struct {
+	Server struct {
+		BootTime   time.Time      `xml:"boot-time"`
+		ConfigTime time.Time      `xml:"config-time"`
+		Version    string         `xml:"version"`
+		Counters   []bindCounters `xml:"counters"`
+	} `xml:"server"`
+	Views []struct {
+		Name     string         `xml:"name,attr"`
+		Counters []bindCounters `xml:"counters"`
+		Cache    []struct {
+			Name    string `xml:"name"`
+			Counter uint64 `xml:"counter"`
+		} `xml:"cache>rrset"`
+	} `xml:"views>view"`
+}
+
+type bindCounters struct {
+	Type     string `xml:"type,attr"`
+	Counters []struct {
+		Name  string `xml:"name,attr"`
+		Value uint64 `xml:",chardata"`
+	} `xml:"counter"`
+}
+
+// findBindCounters returns the counters of the given type as a map.
+func findBindCounters(cs []bindCounters, ty string) map[string]uint64 {
+	res := make(map[string]uint64)
+	for _, c := range cs {
+		if c.Type != ty {
+			continue
+		}
+		for _, counter := range c.Counters {
+			res[counter.Name] = counter.Value
+		}
+	}
+	return res
+}
+
+func bindDesc(subsystem, name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName("bind", subsystem, name), help, labels, nil)
+}
+
+var (
+	bindUpDesc                  = bindDesc("", "up", "Whether the last scrape of the BIND statistics channel was successful.")
+	bindInfoDesc                = bindDesc("", "info", "Information about the BIND server.", "version")
+	bindBootTimeDesc            = bindDesc("", "boot_time_seconds", "Start time of the BIND process since unix epoch in seconds.")
+	bindConfigTimeDesc          = bindDesc("", "config_time_seconds", "Time of the last configuration load since unix epoch in seconds.")
+	bindIncomingRequestsDesc    = bindDesc("", "incoming_requests_total", "Number of incoming DNS requests.", "opcode")
+	bindIncomingQueriesDesc     = bindDesc("", "incoming_queries_total", "Number of incoming DNS queries.", "type")
+	bindResponseRcodesDesc      = bindDesc("", "response_rcodes_total", "Number of responses sent per RCODE.", "rcode")
+	bindResponsesDesc           = bindDesc("", "responses_total", "Number of responses sent.", "result")
+	bindQueryRecursionsDesc     = bindDesc("", "query_recursions_total", "Number of queries causing recursion.")
+	bindZoneTransfersDesc       = bindDesc("", "zone_transfers_total", "Number of zone transfers.", "result")
+	bindResolverQueriesDesc     = bindDesc("resolver", "queries_total", "Number of outgoing DNS queries.", "view", "type")
+	bindResolverRespErrorsDesc  = bindDesc("resolver", "response_errors_total", "Number of resolver response errors received.", "view", "error")
+	bindResolverQueryErrorsDesc = bindDesc("resolver", "query_errors_total", "Number of failed resolver queries.", "view", "error")
+	bindResolverCacheHitsDesc   = bindDesc("resolver", "cache_hits_total", "Number of cache lookups resulting in a hit.", "view")
+	bindResolverCacheMissDesc   = bindDesc("resolver", "cache_misses_total", "Number of cache lookups resulting in a miss.", "view")
+	bindResolverCacheRRsetsDesc = bindDesc("resolver", "cache_rrsets", "Number of RRsets in the cache database.", "view", "type")
+)
+
+// Name server statistics exported as bind_responses_total, keyed by the
+// value of the result label.
+var bindResponseResults = map[string]string{
+	"QrySuccess":  "success",
+	"QryReferral": "referral",
+	"QryNxrrset":  "nxrrset",
+	"QrySERVFAIL": "servfail",
+	"QryFORMERR":  "formerr",
+	"QryNXDOMAIN": "nxdomain",
+	"QryDropped":  "dropped",
+	"QryFailure":  "failure",
+}
+
+// Resolver statistics exported as error counters, keyed by the value of the
+// error label.
+var (
+	bindResolverResponseErrors = map[string]string{
+		"NXDOMAIN":   "nxdomain",
+		"SERVFAIL":   "servfail",
+		"FORMERR":    "formerr",
+		"OtherError": "other",
+	}
+	bindResolverQueryErrors = map[string]string{
+		"QueryAbort":    "abort",
+		"QuerySockFail": "sockfail",
+		"QueryTimeout":  "timeout",
+	}
+)
+
+type bindCollector struct {
+	log    log.Logger
+	client *http.Client
+	url    string
+}
+
+func newBindCollector(l log.Logger, client *http.Client, baseURL string) *bindCollector {
+	return &bindCollector{
+		log:    l,
+		client: client,
+		url:    strings.TrimSuffix(baseURL, "/") + "/xml/v3/server",
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *bindCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		bindUpDesc, bindInfoDesc, bindBootTimeDesc, bindConfigTimeDesc,
+		bindIncomingRequestsDesc, bindIncomingQueriesDesc, bindResponseRcodesDesc,
+		bindResponsesDesc, bindQueryRecursionsDesc, bindZoneTransfersDesc,
+		bindResolverQueriesDesc, bindResolverRespErrorsDesc, bindResolverQueryErrorsDesc,
+		bindResolverCacheHitsDesc, bindResolverCacheMissDesc, bindResolverCacheRRsetsDesc,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *bindCollector) Collect(ch chan<- prometheus.Metric) {
+	gauge := func(desc *prometheus.Desc, v float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labels...)
+	}
+	counter := func(desc *prometheus.Desc, v uint64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, float64(v), labels...)
+	}
+
+	stats, err := c.fetch()
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to scrape bind statistics channel", "err", err)
+		gauge(bindUpDesc, 0)
+		return
+	}
+	gauge(bindUpDesc, 1)
+
+	srv := stats.Server
+	gauge(bindInfoDesc, 1, srv.Version)
+	if !srv.BootTime.IsZero() {
+		gauge(bindBootTimeDesc, float64(srv.BootTime.Unix()))
+	}
+	if !srv.ConfigTime.IsZero() {
+		gauge(bindConfigTimeDesc, float64(srv.ConfigTime.Unix()))
+	}
+
+	for opcode, v := range findBindCounters(srv.Counters, "opcode") {
+		counter(bindIncomingRequestsDesc, v, opcode)
+	}
+	for qtype, v := range findBindCounters(srv.Counters, "qtype") {
+		counter(bindIncomingQueriesDesc, v, qtype)
+	}
+	for rcode, v := range findBindCounters(srv.Counters, "rcode") {
+		counter(bindResponseRcodesDesc, v, rcode)
+	}
+
+	nsstats := findBindCounters(srv.Counters, "nsstat")
+	for name, result := range bindResponseResults {
+		if v, ok := nsstats[name]; ok {
+			counter(bindResponsesDesc, v, result)
+		}
+	}
+	if v, ok := nsstats["QryRecursion"]; ok {
+		counter(bindQueryRecursionsDesc, v)
+	}
+
+	zonestats := findBindCounters(srv.Counters, "zonestat")
+	if v, ok := zonestats["XfrSuccess"]; ok {
+		counter(bindZoneTransfersDesc, v, "success")
+	}
+	if v, ok := zonestats["XfrFail"]; ok {
+		counter(bindZoneTransfersDesc, v, "failure")
+	}
+
+	for _, view := range stats.Views {
+		for qtype, v := range findBindCounters(view.Counters, "resqtype") {
+			counter(bindResolverQueriesDesc, v, view.Name, qtype)
+		}
+
+		resstats := findBindCounters(view.Counters, "resstats")
+		for name, label := range bindResolverResponseErrors {
+			if v, ok := resstats[name]; ok {
+				counter(bindResolverRespErrorsDesc, v, view.Name, label)
+			}
+		}
+		for name, label := range bindResolverQueryErrors {
+			if v, ok := resstats[name]; ok {
+				counter(bindResolverQueryErrorsDesc, v, view.Name, label)
+			}
+		}
+
+		cachestats := findBindCounters(view.Counters, "cachestats")
+		if v, ok := cachestats["CacheHits"]; ok {
+			counter(bindResolverCacheHitsDesc, v, view.Name)
+		}
+		if v, ok := cachestats["CacheMisses"]; ok {
+			counter(bindResolverCacheMissDesc, v, view.Name)
+		}
+
+		for _, rrset := range view.Cache {
+			gauge(bindResolverCacheRRsetsDesc, float64(rrset.Counter), view.Name, rrset.Name)
+		}
+	}
+}
+
+func (c *bindCollector) fetch() (*bindStatistics, error) {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var stats bindStatistics
+	if err := xml.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		return nil, fmt.Errorf("failed to parse statistics: %w", err)
+	}
+	return &stats, nil
+}
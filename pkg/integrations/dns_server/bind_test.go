@@ -0,0 +1,113 @@
+package dns_server //nolint:golint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const testBindStatistics = `<?xml version="1.0" encoding="UTF-8"?>
+<statistics version="3.11">
+  <server>
+    <boot-time>2021-10-14T10:00:00.000Z</boot-time>
+    <config-time>2021-10-14T11:00:00.000Z</config-time>
+    <current-time>2021-10-14T12:00:00.000Z</current-time>
+    <version>9.16.21</version>
+    <counters type="opcode">
+      <counter name="QUERY">120</counter>
+      <counter name="NOTIFY">2</counter>
+    </counters>
+    <counters type="qtype">
+      <counter name="A">80</counter>
+      <counter name="AAAA">40</counter>
+    </counters>
+    <counters type="nsstat">
+      <counter name="Requestv4">120</counter>
+      <counter name="QrySuccess">100</counter>
+      <counter name="QryNXDOMAIN">15</counter>
+      <counter name="QryRecursion">30</counter>
+    </counters>
+    <counters type="zonestat">
+      <counter name="XfrSuccess">4</counter>
+      <counter name="XfrFail">1</counter>
+    </counters>
+  </server>
+  <views>
+    <view name="_default">
+      <counters type="resqtype">
+        <counter name="A">25</counter>
+      </counters>
+      <counters type="resstats">
+        <counter name="SERVFAIL">3</counter>
+        <counter name="QueryTimeout">2</counter>
+      </counters>
+      <cache name="_default">
+        <rrset>
+          <name>A</name>
+          <counter>12</counter>
+        </rrset>
+      </cache>
+      <counters type="cachestats">
+        <counter name="CacheHits">90</counter>
+        <counter name="CacheMisses">10</counter>
+      </counters>
+    </view>
+  </views>
+</statistics>
+`
+
+func TestBindCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/xml/v3/server", r.URL.Path)
+		_, _ = w.Write([]byte(testBindStatistics))
+	}))
+	defer srv.Close()
+
+	c := newBindCollector(log.NewNopLogger(), http.DefaultClient, srv.URL)
+
+	expect := `
+# HELP bind_boot_time_seconds Start time of the BIND process since unix epoch in seconds.
+# TYPE bind_boot_time_seconds gauge
+bind_boot_time_seconds 1.6342056e+09
+# HELP bind_incoming_requests_total Number of incoming DNS requests.
+# TYPE bind_incoming_requests_total counter
+bind_incoming_requests_total{opcode="NOTIFY"} 2
+bind_incoming_requests_total{opcode="QUERY"} 120
+# HELP bind_info Information about the BIND server.
+# TYPE bind_info gauge
+bind_info{version="9.16.21"} 1
+# HELP bind_resolver_cache_hits_total Number of cache lookups resulting in a hit.
+# TYPE bind_resolver_cache_hits_total counter
+bind_resolver_cache_hits_total{view="_default"} 90
+# HELP bind_resolver_cache_rrsets Number of RRsets in the cache database.
+# TYPE bind_resolver_cache_rrsets gauge
+bind_resolver_cache_rrsets{type="A",view="_default"} 12
+# HELP bind_resolver_query_errors_total Number of failed resolver queries.
+# TYPE bind_resolver_query_errors_total counter
+bind_resolver_query_errors_total{error="timeout",view="_default"} 2
+# HELP bind_resolver_response_errors_total Number of resolver response errors received.
+# TYPE bind_resolver_response_errors_total counter
+bind_resolver_response_errors_total{error="servfail",view="_default"} 3
+# HELP bind_responses_total Number of responses sent.
+# TYPE bind_responses_total counter
+bind_responses_total{result="nxdomain"} 15
+bind_responses_total{result="success"} 100
+# HELP bind_up Whether the last scrape of the BIND statistics channel was successful.
+# TYPE bind_up gauge
+bind_up 1
+# HELP bind_zone_transfers_total Number of zone transfers.
+# TYPE bind_zone_transfers_total counter
+bind_zone_transfers_total{result="failure"} 1
+bind_zone_transfers_total{result="success"} 4
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"bind_boot_time_seconds", "bind_incoming_requests_total", "bind_info", "bind_resolver_cache_hits_total",
+		"bind_resolver_cache_rrsets", "bind_resolver_query_errors_total", "bind_resolver_response_errors_total",
+		"bind_responses_total", "bind_up", "bind_zone_transfers_total")
+	require.NoError(t, err)
+}
@@ -0,0 +1,122 @@
+// Package dns_server implements an integration which collects metrics from
+// DNS servers. The BIND statistics channel and the PowerDNS Authoritative
+// Server and Recursor HTTP APIs are supported.
+package dns_server //nolint:golint
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	"github.com/prometheus/client_golang/prometheus"
+	config_util "github.com/prometheus/common/config"
+)
+
+// Supported server types.
+const (
+	ServerTypeBind     = "bind"
+	ServerTypePowerDNS = "powerdns"
+)
+
+// Default URLs used when url isn't set.
+const (
+	defaultBindURL     = "http://localhost:8053"
+	defaultPowerDNSURL = "http://localhost:8081"
+)
+
+// DefaultConfig holds the default settings for the dns_server integration.
+var DefaultConfig = Config{
+	ServerID: "localhost",
+	Timeout:  5 * time.Second,
+}
+
+// Config controls the dns_server integration.
+type Config struct {
+	// ServerType is the type of DNS server, either bind or powerdns.
+	ServerType string `yaml:"server_type,omitempty"`
+	// URL of the BIND statistics channel or the PowerDNS web server.
+	URL string `yaml:"url,omitempty"`
+	// APIKey used to authenticate against the PowerDNS API.
+	APIKey config_util.Secret `yaml:"api_key,omitempty"`
+	// ServerID of the PowerDNS server to collect statistics for.
+	ServerID string `yaml:"server_id,omitempty"`
+	// Timeout for requests to the DNS server.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	switch c.ServerType {
+	case ServerTypeBind:
+		if c.URL == "" {
+			c.URL = defaultBindURL
+		}
+	case ServerTypePowerDNS:
+		if c.URL == "" {
+			c.URL = defaultPowerDNSURL
+		}
+		if c.APIKey == "" {
+			return fmt.Errorf("api_key must be set for server_type %q", ServerTypePowerDNS)
+		}
+	default:
+		return fmt.Errorf("unsupported server_type %q, must be %q or %q", c.ServerType, ServerTypeBind, ServerTypePowerDNS)
+	}
+
+	if _, err := url.Parse(c.URL); err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "dns_server"
+}
+
+// InstanceKey returns the host:port of the DNS server being scraped.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return "", fmt.Errorf("could not parse url: %w", err)
+	}
+	return u.Host, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
+
+// New creates a new dns_server integration.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	client := &http.Client{Timeout: c.Timeout}
+
+	var col prometheus.Collector
+	switch c.ServerType {
+	case ServerTypeBind:
+		col = newBindCollector(logger, client, c.URL)
+	case ServerTypePowerDNS:
+		col = newPowerDNSCollector(logger, client, c.URL, string(c.APIKey), c.ServerID)
+	default:
+		return nil, fmt.Errorf("unsupported server_type %q", c.ServerType)
+	}
+
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
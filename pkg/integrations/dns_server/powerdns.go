@@ -0,0 +1,184 @@
+package dns_server //nolint:golint
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// powerDNSGauges are statistics of the PowerDNS Authoritative Server and
+// Recursor which describe a current value. All other statistics are
+// exported as counters.
+var powerDNSGauges = map[string]struct{}{
+	"cache-bytes":          {},
+	"cache-entries":        {},
+	"concurrent-queries":   {},
+	"failed-host-entries":  {},
+	"fd-usage":             {},
+	"key-cache-size":       {},
+	"latency":              {},
+	"malloc-bytes":         {},
+	"max-mthread-stack":    {},
+	"meta-cache-size":      {},
+	"negcache-entries":     {},
+	"nsspeeds-entries":     {},
+	"open-tcp-connections": {},
+	"packetcache-bytes":    {},
+	"packetcache-entries":  {},
+	"packetcache-size":     {},
+	"qa-latency":           {},
+	"qsize-q":              {},
+	"query-cache-size":     {},
+	"real-memory-usage":    {},
+	"security-status":      {},
+	"signature-cache-size": {},
+	"throttle-entries":     {},
+	"uptime":               {},
+}
+
+// powerDNSServer is the server object of the PowerDNS API.
+type powerDNSServer struct {
+	DaemonType string `json:"daemon_type"`
+	Version    string `json:"version"`
+}
+
+// powerDNSStatistic is an item of the statistics endpoint of the PowerDNS
+// API. Value is a string for a StatisticItem and a list of name/value pairs
+// for a MapStatisticItem.
+type powerDNSStatistic struct {
+	Name  string          `json:"name"`
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+type powerDNSMapEntry struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+var (
+	powerDNSUpDesc = prometheus.NewDesc(
+		"powerdns_up",
+		"Whether the last scrape of the PowerDNS API was successful.",
+		nil, nil,
+	)
+	powerDNSInfoDesc = prometheus.NewDesc(
+		"powerdns_info",
+		"Information about the PowerDNS server.",
+		[]string{"daemon_type", "version"}, nil,
+	)
+)
+
+// powerDNSCollector collects statistics from the PowerDNS API. Metric names
+// depend on the statistics reported by the server, so the collector is
+// unchecked.
+type powerDNSCollector struct {
+	log       log.Logger
+	client    *http.Client
+	serverURL string
+	apiKey    string
+}
+
+func newPowerDNSCollector(l log.Logger, client *http.Client, baseURL, apiKey, serverID string) *powerDNSCollector {
+	return &powerDNSCollector{
+		log:       l,
+		client:    client,
+		serverURL: strings.TrimSuffix(baseURL, "/") + "/api/v1/servers/" + url.PathEscape(serverID),
+		apiKey:    apiKey,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *powerDNSCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *powerDNSCollector) Collect(ch chan<- prometheus.Metric) {
+	var (
+		server powerDNSServer
+		stats  []powerDNSStatistic
+	)
+	err := c.get("", &server)
+	if err == nil {
+		err = c.get("/statistics", &stats)
+	}
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to scrape powerdns api", "err", err)
+		ch <- prometheus.MustNewConstMetric(powerDNSUpDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(powerDNSUpDesc, prometheus.GaugeValue, 1)
+	ch <- prometheus.MustNewConstMetric(powerDNSInfoDesc, prometheus.GaugeValue, 1, server.DaemonType, server.Version)
+
+	prefix := "powerdns_" + server.DaemonType + "_"
+	for _, s := range stats {
+		name := prefix + strings.NewReplacer("-", "_", ".", "_").Replace(s.Name)
+
+		switch s.Type {
+		case "StatisticItem":
+			var raw string
+			if err := json.Unmarshal(s.Value, &raw); err != nil {
+				continue
+			}
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				continue
+			}
+
+			valueType := prometheus.CounterValue
+			if _, ok := powerDNSGauges[s.Name]; ok {
+				valueType = prometheus.GaugeValue
+			} else {
+				name += "_total"
+			}
+			desc := prometheus.NewDesc(name, fmt.Sprintf("PowerDNS statistic %s.", s.Name), nil, nil)
+			ch <- prometheus.MustNewConstMetric(desc, valueType, v)
+
+		case "MapStatisticItem":
+			var entries []powerDNSMapEntry
+			if err := json.Unmarshal(s.Value, &entries); err != nil {
+				continue
+			}
+			desc := prometheus.NewDesc(name+"_total", fmt.Sprintf("PowerDNS statistic %s.", s.Name), []string{"key"}, nil)
+			for _, e := range entries {
+				v, err := strconv.ParseFloat(e.Value, 64)
+				if err != nil {
+					continue
+				}
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v, e.Name)
+			}
+		}
+		// RingStatisticItems hold the most frequent queries and remotes and
+		// are skipped to avoid unbounded label values.
+	}
+}
+
+// get requests path relative to the server object and decodes the response
+// into v.
+func (c *powerDNSCollector) get(path string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, c.serverURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-API-Key", c.apiKey)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d for %s", resp.StatusCode, req.URL.Path)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse response for %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package dns_server //nolint:golint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const testPowerDNSStatistics = `[
+  {"name": "udp-queries", "type": "StatisticItem", "value": "1200"},
+  {"name": "latency", "type": "StatisticItem", "value": "35"},
+  {"name": "response-by-rcode", "type": "MapStatisticItem", "value": [
+    {"name": "No Error", "value": "1100"},
+    {"name": "Name Error", "value": "100"}
+  ]},
+  {"name": "queries", "type": "RingStatisticItem", "size": "10000", "value": [
+    {"name": "example.com/A", "value": "5"}
+  ]}
+]`
+
+func newPowerDNSTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/servers/localhost":
+			_, _ = w.Write([]byte(`{"daemon_type": "authoritative", "version": "4.5.1", "id": "localhost"}`))
+		case "/api/v1/servers/localhost/statistics":
+			_, _ = w.Write([]byte(testPowerDNSStatistics))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPowerDNSCollector(t *testing.T) {
+	srv := newPowerDNSTestServer(t)
+	c := newPowerDNSCollector(log.NewNopLogger(), http.DefaultClient, srv.URL, "secret", "localhost")
+
+	expect := `
+# HELP powerdns_authoritative_latency PowerDNS statistic latency.
+# TYPE powerdns_authoritative_latency gauge
+powerdns_authoritative_latency 35
+# HELP powerdns_authoritative_response_by_rcode_total PowerDNS statistic response-by-rcode.
+# TYPE powerdns_authoritative_response_by_rcode_total counter
+powerdns_authoritative_response_by_rcode_total{key="Name Error"} 100
+powerdns_authoritative_response_by_rcode_total{key="No Error"} 1100
+# HELP powerdns_authoritative_udp_queries_total PowerDNS statistic udp-queries.
+# TYPE powerdns_authoritative_udp_queries_total counter
+powerdns_authoritative_udp_queries_total 1200
+# HELP powerdns_info Information about the PowerDNS server.
+# TYPE powerdns_info gauge
+powerdns_info{daemon_type="authoritative",version="4.5.1"} 1
+# HELP powerdns_up Whether the last scrape of the PowerDNS API was successful.
+# TYPE powerdns_up gauge
+powerdns_up 1
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect)))
+}
+
+func TestPowerDNSCollector_Unauthorized(t *testing.T) {
+	srv := newPowerDNSTestServer(t)
+	c := newPowerDNSCollector(log.NewNopLogger(), http.DefaultClient, srv.URL, "wrong", "localhost")
+
+	expect := `
+# HELP powerdns_up Whether the last scrape of the PowerDNS API was successful.
+# TYPE powerdns_up gauge
+powerdns_up 0
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect)))
+}
+
+func TestConfig(t *testing.T) {
+	tt := []struct {
+		name   string
+		cfg    string
+		expect string
+		err    string
+	}{
+		{name: "bind default url", cfg: `server_type: bind`, expect: defaultBindURL},
+		{name: "powerdns default url", cfg: `{server_type: powerdns, api_key: secret}`, expect: defaultPowerDNSURL},
+		{name: "powerdns without api key", cfg: `server_type: powerdns`, err: `api_key must be set for server_type "powerdns"`},
+		{name: "unknown type", cfg: `server_type: unbound`, err: `unsupported server_type "unbound", must be "bind" or "powerdns"`},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var c Config
+			err := yaml.Unmarshal([]byte(tc.cfg), &c)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, c.URL)
+		})
+	}
+}
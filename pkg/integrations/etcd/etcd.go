@@ -0,0 +1,79 @@
+// Package etcd implements an integration which proxies the metrics endpoint
+// of an etcd member. Client certificate authentication can be configured
+// inline, which is common for etcd clusters of self-hosted Kubernetes control
+// planes.
+package etcd
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+)
+
+// DefaultConfig holds the default settings for the etcd integration.
+var DefaultConfig = Config{
+	Endpoint:    "https://127.0.0.1:2379",
+	MetricsPath: "/metrics",
+	Timeout:     10 * time.Second,
+}
+
+// Config controls the etcd integration.
+type Config struct {
+	// Endpoint is the client URL of the etcd member, or the URL configured
+	// with --listen-metrics-urls.
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// MetricsPath is the path of the metrics endpoint.
+	MetricsPath string `yaml:"metrics_path,omitempty"`
+	// Timeout for requests to etcd.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// TLSConfig configures TLS and client certificate authentication.
+	TLSConfig TLSConfig `yaml:"tls_config,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("endpoint must use http or https, got %q", c.Endpoint)
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "etcd"
+}
+
+// InstanceKey returns the host:port of the etcd member being scraped.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	u, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return "", fmt.Errorf("could not parse url: %w", err)
+	}
+	return u.Host, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
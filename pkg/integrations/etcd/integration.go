@@ -0,0 +1,88 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/agent/pkg/integrations/config"
+)
+
+// Integration is the etcd integration. Scrapes of the integration are
+// proxied to the metrics endpoint of etcd.
+type Integration struct {
+	c      *Config
+	log    log.Logger
+	client *http.Client
+	url    string
+}
+
+// New creates a new etcd integration.
+func New(logger log.Logger, c *Config) (*Integration, error) {
+	tlsConfig, err := c.TLSConfig.build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tls config: %w", err)
+	}
+	client := &http.Client{
+		Timeout: c.Timeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	return &Integration{
+		c:      c,
+		log:    logger,
+		client: client,
+		url:    strings.TrimSuffix(c.Endpoint, "/") + c.MetricsPath,
+	}, nil
+}
+
+// MetricsHandler satisfies Integration.RegisterRoutes.
+func (i *Integration) MetricsHandler() (http.Handler, error) {
+	return http.HandlerFunc(i.serveMetrics), nil
+}
+
+func (i *Integration) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, i.url, nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if accept := r.Header.Get("Accept"); accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		level.Error(i.log).Log("msg", "failed to scrape etcd", "err", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// ScrapeConfigs satisfies Integration.ScrapeConfigs.
+func (i *Integration) ScrapeConfigs() []config.ScrapeConfig {
+	return []config.ScrapeConfig{{
+		JobName:     i.c.Name(),
+		MetricsPath: "/metrics",
+	}}
+}
+
+// Run satisfies Integration.Run.
+func (i *Integration) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
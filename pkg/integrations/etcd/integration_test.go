@@ -0,0 +1,137 @@
+package etcd
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+// testClientCert generates a CA and a client certificate signed by it. The
+// client certificate and key are returned PEM-encoded.
+func testClientCert(t *testing.T) (ca *x509.Certificate, certPEM, keyPEM string) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "etcd-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err = x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	clientTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "agent"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTemplate, ca, &clientKey.PublicKey, caKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(clientKey)
+	require.NoError(t, err)
+
+	certPEM = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientDER}))
+	keyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	return ca, certPEM, keyPEM
+}
+
+func TestIntegration_ClientCert(t *testing.T) {
+	ca, certPEM, keyPEM := testClientCert(t)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/metrics", r.URL.Path)
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write([]byte("etcd_server_has_leader 1\n"))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+
+	serverCA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+
+	cfg := map[string]interface{}{
+		"endpoint": srv.URL,
+		"tls_config": map[string]string{
+			"ca":   serverCA,
+			"cert": certPEM,
+			"key":  keyPEM,
+		},
+	}
+	bb, err := yaml.Marshal(cfg)
+	require.NoError(t, err)
+
+	var c Config
+	require.NoError(t, yaml.Unmarshal(bb, &c))
+	i, err := New(log.NewNopLogger(), &c)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	i.serveMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "text/plain; version=0.0.4", rec.Header().Get("Content-Type"))
+
+	body, err := ioutil.ReadAll(rec.Body)
+	require.NoError(t, err)
+	require.Equal(t, "etcd_server_has_leader 1\n", string(body))
+
+	// Without a client certificate the handshake fails.
+	c.TLSConfig.Cert, c.TLSConfig.Key = "", ""
+	i, err = New(log.NewNopLogger(), &c)
+	require.NoError(t, err)
+
+	rec = httptest.NewRecorder()
+	i.serveMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusBadGateway, rec.Code)
+}
+
+func TestTLSConfig_Validate(t *testing.T) {
+	tt := []struct {
+		cfg string
+		err string
+	}{
+		{`{cert: a, cert_file: b, key: c}`, "at most one of cert and cert_file must be set"},
+		{`{cert: a}`, "cert and key must be set together"},
+		{`{cert_file: a, key: b}`, "cert and key must be set together"},
+		{`{cert_file: a}`, "cert_file and key_file must be set together"},
+	}
+
+	for _, tc := range tt {
+		var c TLSConfig
+		err := yaml.Unmarshal([]byte(tc.cfg), &c)
+		require.EqualError(t, err, tc.err, tc.cfg)
+	}
+}
+
+func TestConfig_InvalidEndpoint(t *testing.T) {
+	var c Config
+	err := yaml.Unmarshal([]byte(`endpoint: 127.0.0.1:2379`), &c)
+	require.EqualError(t, err, `invalid endpoint: parse "127.0.0.1:2379": first path segment in URL cannot contain colon`)
+}
@@ -0,0 +1,88 @@
+package etcd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+
+	config_util "github.com/prometheus/common/config"
+)
+
+// TLSConfig configures TLS for requests to etcd. Certificates and keys can
+// either be read from files or be provided inline as PEM-encoded strings.
+type TLSConfig struct {
+	// CA is the PEM-encoded CA certificate used to verify etcd.
+	CA string `yaml:"ca,omitempty"`
+	// CAFile is the path to the CA certificate used to verify etcd.
+	CAFile string `yaml:"ca_file,omitempty"`
+	// Cert is the PEM-encoded client certificate.
+	Cert string `yaml:"cert,omitempty"`
+	// CertFile is the path to the client certificate.
+	CertFile string `yaml:"cert_file,omitempty"`
+	// Key is the PEM-encoded client key.
+	Key config_util.Secret `yaml:"key,omitempty"`
+	// KeyFile is the path to the client key.
+	KeyFile string `yaml:"key_file,omitempty"`
+	// ServerName is used to verify the hostname of etcd.
+	ServerName string `yaml:"server_name,omitempty"`
+	// InsecureSkipVerify disables verification of the server certificate.
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for TLSConfig.
+func (c *TLSConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain TLSConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.CA != "" && c.CAFile != "" {
+		return fmt.Errorf("at most one of ca and ca_file must be set")
+	}
+	if c.Cert != "" && c.CertFile != "" {
+		return fmt.Errorf("at most one of cert and cert_file must be set")
+	}
+	if c.Key != "" && c.KeyFile != "" {
+		return fmt.Errorf("at most one of key and key_file must be set")
+	}
+
+	if (c.Cert != "") != (c.Key != "") {
+		return fmt.Errorf("cert and key must be set together")
+	}
+	if (c.CertFile != "") != (c.KeyFile != "") {
+		return fmt.Errorf("cert_file and key_file must be set together")
+	}
+	return nil
+}
+
+// build creates a *tls.Config from c.
+func (c *TLSConfig) build() (*tls.Config, error) {
+	// Settings which are read from files are handled by config_util, which
+	// also reloads the client certificate when it is rotated on disk.
+	tlsConfig, err := config_util.NewTLSConfig(&config_util.TLSConfig{
+		CAFile:             c.CAFile,
+		CertFile:           c.CertFile,
+		KeyFile:            c.KeyFile,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if c.CA != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(c.CA)) {
+			return nil, fmt.Errorf("unable to use inline CA cert")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if c.Cert != "" {
+		cert, err := tls.X509KeyPair([]byte(c.Cert), []byte(c.Key))
+		if err != nil {
+			return nil, fmt.Errorf("unable to use inline client cert and key: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
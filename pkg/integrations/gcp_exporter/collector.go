@@ -0,0 +1,322 @@
+package gcp_exporter //nolint:golint
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/api/monitoring/v3"
+)
+
+const namespace = "stackdriver"
+
+// maxConcurrentRequests limits the number of metric types whose time series
+// are requested concurrently.
+const maxConcurrentRequests = 10
+
+var (
+	upDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "monitoring", "up"),
+		"Whether metrics of the project were collected successfully.",
+		[]string{"project_id"}, nil,
+	)
+
+	invalidLabelChars = regexp.MustCompile("[^a-zA-Z0-9_]")
+)
+
+// collector is an unchecked prometheus.Collector, as the metrics depend on
+// the metric types available in Cloud Monitoring.
+type collector struct {
+	log log.Logger
+	cfg *Config
+	svc *monitoring.Service
+
+	// now is used to determine the requested time window.
+	now func() time.Time
+}
+
+func newCollector(l log.Logger, c *Config, svc *monitoring.Service) *collector {
+	return &collector{log: l, cfg: c, svc: svc, now: time.Now}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx := context.Background()
+
+	for _, project := range c.cfg.ProjectIDs {
+		set := &metricSet{ch: ch, seen: make(map[string]struct{})}
+		if err := c.collectProject(ctx, project, set); err != nil {
+			level.Error(c.log).Log("msg", "failed to collect cloud monitoring metrics", "project_id", project, "err", err)
+			ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0, project)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1, project)
+	}
+}
+
+func (c *collector) collectProject(ctx context.Context, project string, set *metricSet) error {
+	descriptors, err := c.metricDescriptors(ctx, project)
+	if err != nil {
+		return err
+	}
+
+	var (
+		wg   sync.WaitGroup
+		sem  = make(chan struct{}, maxConcurrentRequests)
+		mut  sync.Mutex
+		errs []string
+	)
+	for _, d := range descriptors {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(d *monitoring.MetricDescriptor) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := c.collectTimeSeries(ctx, project, d, set); err != nil {
+				mut.Lock()
+				errs = append(errs, fmt.Sprintf("%s: %s", d.Type, err))
+				mut.Unlock()
+			}
+		}(d)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to collect time series: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// metricDescriptors returns the descriptors of all metric types matching the
+// configured prefixes.
+func (c *collector) metricDescriptors(ctx context.Context, project string) ([]*monitoring.MetricDescriptor, error) {
+	var (
+		descriptors []*monitoring.MetricDescriptor
+		seen        = make(map[string]struct{})
+	)
+	for _, prefix := range c.cfg.MetricPrefixes {
+		filter := fmt.Sprintf("metric.type = starts_with(%q)", prefix)
+		err := c.svc.Projects.MetricDescriptors.List("projects/"+project).Filter(filter).Pages(ctx, func(page *monitoring.ListMetricDescriptorsResponse) error {
+			for _, d := range page.MetricDescriptors {
+				// Prefixes may overlap.
+				if _, ok := seen[d.Type]; ok {
+					continue
+				}
+				seen[d.Type] = struct{}{}
+				descriptors = append(descriptors, d)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list metric descriptors for prefix %s: %w", prefix, err)
+		}
+	}
+	return descriptors, nil
+}
+
+func (c *collector) collectTimeSeries(ctx context.Context, project string, d *monitoring.MetricDescriptor, set *metricSet) error {
+	end := c.now().Add(-c.cfg.RequestOffset)
+	if c.cfg.IngestDelay && d.Metadata != nil && d.Metadata.IngestDelay != "" {
+		delay, err := time.ParseDuration(d.Metadata.IngestDelay)
+		if err != nil {
+			return fmt.Errorf("invalid ingest delay %q: %w", d.Metadata.IngestDelay, err)
+		}
+		end = end.Add(-delay)
+	}
+	start := end.Add(-c.cfg.RequestInterval)
+
+	filter := fmt.Sprintf("metric.type = %q", d.Type)
+	for _, f := range c.cfg.ExtraFilters {
+		if prefix, extra := splitExtraFilter(f); strings.HasPrefix(d.Type, prefix) {
+			filter += " AND " + extra
+		}
+	}
+
+	return c.svc.Projects.TimeSeries.List("projects/"+project).
+		Filter(filter).
+		IntervalStartTime(start.Format(time.RFC3339Nano)).
+		IntervalEndTime(end.Format(time.RFC3339Nano)).
+		View("FULL").
+		Pages(ctx, func(page *monitoring.ListTimeSeriesResponse) error {
+			for _, ts := range page.TimeSeries {
+				if err := set.add(project, d, ts); err != nil {
+					level.Debug(c.log).Log("msg", "skipping time series", "metric_type", d.Type, "err", err)
+				}
+			}
+			return nil
+		})
+}
+
+// metricSet sends the latest point of time series as metrics, dropping
+// duplicate series.
+type metricSet struct {
+	mut  sync.Mutex
+	ch   chan<- prometheus.Metric
+	seen map[string]struct{}
+}
+
+func (s *metricSet) add(project string, d *monitoring.MetricDescriptor, ts *monitoring.TimeSeries) error {
+	if len(ts.Points) == 0 || ts.Resource == nil || ts.Metric == nil {
+		return fmt.Errorf("incomplete time series")
+	}
+	// Points are returned in reverse time order.
+	point := ts.Points[0]
+	if point.Interval == nil || point.Value == nil {
+		return fmt.Errorf("point without value")
+	}
+	timestamp, err := time.Parse(time.RFC3339Nano, point.Interval.EndTime)
+	if err != nil {
+		return fmt.Errorf("invalid point end time: %w", err)
+	}
+
+	name := metricName(ts.Resource.Type, d.Type)
+	labelNames, labelValues := seriesLabels(project, ts)
+
+	key := name + "\xff" + strings.Join(labelNames, "\xff") + "\xff" + strings.Join(labelValues, "\xff")
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if _, ok := s.seen[key]; ok {
+		return nil
+	}
+	s.seen[key] = struct{}{}
+
+	help := d.Description
+	if help == "" {
+		help = fmt.Sprintf("Cloud Monitoring metric %s.", d.Type)
+	}
+	desc := prometheus.NewDesc(name, help, labelNames, nil)
+
+	var m prometheus.Metric
+	if dist := point.Value.DistributionValue; dist != nil {
+		m, err = histogram(desc, dist, labelValues)
+	} else {
+		m, err = sample(desc, ts.MetricKind, point.Value, labelValues)
+	}
+	if err != nil {
+		return err
+	}
+	s.ch <- prometheus.NewMetricWithTimestamp(timestamp, m)
+	return nil
+}
+
+// metricName builds the name of a metric from the type of the monitored
+// resource and the metric type, e.g.,
+// stackdriver_gce_instance_compute_googleapis_com_instance_cpu_utilization.
+func metricName(resourceType, metricType string) string {
+	return prometheus.BuildFQName(namespace, sanitize(resourceType), sanitize(metricType))
+}
+
+// seriesLabels returns the sorted label names and values of a time series,
+// composed of the metric labels, the resource labels, and the project ID.
+// Metric labels take precedence over resource labels of the same name.
+func seriesLabels(project string, ts *monitoring.TimeSeries) (names, values []string) {
+	labels := map[string]string{}
+	for k, v := range ts.Resource.Labels {
+		labels[sanitize(k)] = v
+	}
+	for k, v := range ts.Metric.Labels {
+		labels[sanitize(k)] = v
+	}
+	if _, ok := labels["project_id"]; !ok {
+		labels["project_id"] = project
+	}
+
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		values = append(values, labels[k])
+	}
+	return names, values
+}
+
+func sanitize(s string) string {
+	return invalidLabelChars.ReplaceAllString(s, "_")
+}
+
+// sample converts a scalar point into a metric. Cumulative metrics become
+// counters, while gauge and delta metrics become gauges.
+func sample(desc *prometheus.Desc, kind string, v *monitoring.TypedValue, labelValues []string) (prometheus.Metric, error) {
+	var value float64
+	switch {
+	case v.DoubleValue != nil:
+		value = *v.DoubleValue
+	case v.Int64Value != nil:
+		value = float64(*v.Int64Value)
+	case v.BoolValue != nil:
+		if *v.BoolValue {
+			value = 1
+		}
+	default:
+		return nil, fmt.Errorf("unsupported value type")
+	}
+
+	valueType := prometheus.GaugeValue
+	if kind == "CUMULATIVE" {
+		valueType = prometheus.CounterValue
+	}
+	return prometheus.NewConstMetric(desc, valueType, value, labelValues...)
+}
+
+// histogram converts a distribution point into a histogram.
+func histogram(desc *prometheus.Desc, dist *monitoring.Distribution, labelValues []string) (prometheus.Metric, error) {
+	bounds, err := bucketBounds(dist.BucketOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first bucket counts values below the first bound and the last
+	// bucket values above the last bound, which is covered by the count of
+	// the histogram. Trailing buckets without values may be omitted.
+	buckets := make(map[float64]uint64, len(bounds))
+	var cumulative uint64
+	for i, bound := range bounds {
+		if i < len(dist.BucketCounts) {
+			cumulative += uint64(dist.BucketCounts[i])
+		}
+		buckets[bound] = cumulative
+	}
+
+	count := uint64(dist.Count)
+	return prometheus.NewConstHistogram(desc, count, dist.Mean*float64(count), buckets, labelValues...)
+}
+
+// bucketBounds returns the upper bounds of the finite buckets of a
+// distribution.
+func bucketBounds(opts *monitoring.BucketOptions) ([]float64, error) {
+	switch {
+	case opts == nil:
+		return nil, nil
+	case opts.ExplicitBuckets != nil:
+		return opts.ExplicitBuckets.Bounds, nil
+	case opts.LinearBuckets != nil:
+		b := opts.LinearBuckets
+		bounds := make([]float64, 0, b.NumFiniteBuckets+1)
+		for i := int64(0); i <= b.NumFiniteBuckets; i++ {
+			bounds = append(bounds, b.Offset+b.Width*float64(i))
+		}
+		return bounds, nil
+	case opts.ExponentialBuckets != nil:
+		b := opts.ExponentialBuckets
+		bounds := make([]float64, 0, b.NumFiniteBuckets+1)
+		for i := int64(0); i <= b.NumFiniteBuckets; i++ {
+			bounds = append(bounds, b.Scale*math.Pow(b.GrowthFactor, float64(i)))
+		}
+		return bounds, nil
+	default:
+		return nil, fmt.Errorf("unsupported bucket options")
+	}
+}
@@ -0,0 +1,234 @@
+package gcp_exporter //nolint:golint
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/monitoring/v3"
+	"google.golang.org/api/option"
+	"gopkg.in/yaml.v2"
+)
+
+const testDescriptors = `{"metricDescriptors": [
+	{
+		"type": "compute.googleapis.com/instance/cpu/utilization",
+		"metricKind": "GAUGE",
+		"valueType": "DOUBLE",
+		"description": "Fractional utilization of allocated CPU on this instance.",
+		"metadata": {"ingestDelay": "240s"}
+	},
+	{
+		"type": "compute.googleapis.com/instance/network/received_bytes_count",
+		"metricKind": "CUMULATIVE",
+		"valueType": "INT64",
+		"description": "Count of bytes received from the network."
+	},
+	{
+		"type": "loadbalancing.googleapis.com/https/total_latencies",
+		"metricKind": "DELTA",
+		"valueType": "DISTRIBUTION",
+		"description": "Distribution of the latency."
+	}
+]}`
+
+var testTimeSeries = map[string]string{
+	"compute.googleapis.com/instance/cpu/utilization": `{"timeSeries": [{
+		"metric": {"type": "compute.googleapis.com/instance/cpu/utilization", "labels": {"instance_name": "vm-1"}},
+		"resource": {"type": "gce_instance", "labels": {"project_id": "my-project", "instance_id": "1234", "zone": "us-central1-a"}},
+		"metricKind": "GAUGE",
+		"valueType": "DOUBLE",
+		"points": [
+			{"interval": {"endTime": "2022-01-20T10:01:00Z"}, "value": {"doubleValue": 0.25}},
+			{"interval": {"endTime": "2022-01-20T10:00:00Z"}, "value": {"doubleValue": 0.5}}
+		]
+	}]}`,
+	"compute.googleapis.com/instance/network/received_bytes_count": `{"timeSeries": [{
+		"metric": {"type": "compute.googleapis.com/instance/network/received_bytes_count", "labels": {"instance_name": "vm-1", "loadbalanced": "false"}},
+		"resource": {"type": "gce_instance", "labels": {"project_id": "my-project", "instance_id": "1234", "zone": "us-central1-a"}},
+		"metricKind": "CUMULATIVE",
+		"valueType": "INT64",
+		"points": [{"interval": {"startTime": "2022-01-01T00:00:00Z", "endTime": "2022-01-20T10:01:00Z"}, "value": {"int64Value": "1024"}}]
+	}]}`,
+	"loadbalancing.googleapis.com/https/total_latencies": `{"timeSeries": [{
+		"metric": {"type": "loadbalancing.googleapis.com/https/total_latencies", "labels": {"response_code": "200"}},
+		"resource": {"type": "https_lb_rule", "labels": {"url_map_name": "web"}},
+		"metricKind": "DELTA",
+		"valueType": "DISTRIBUTION",
+		"points": [{"interval": {"endTime": "2022-01-20T10:01:00Z"}, "value": {"distributionValue": {
+			"count": "10",
+			"mean": 15,
+			"bucketOptions": {"exponentialBuckets": {"numFiniteBuckets": 2, "growthFactor": 2, "scale": 10}},
+			"bucketCounts": ["1", "6", "3"]
+		}}}]
+	}]}`,
+}
+
+func newTestService(t *testing.T, filters *[]string) *monitoring.Service {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v3/projects/my-project/metricDescriptors":
+			_, _ = w.Write([]byte(testDescriptors))
+		case "/v3/projects/my-project/timeSeries":
+			filter := r.URL.Query().Get("filter")
+			*filters = append(*filters, filter)
+			for metricType, body := range testTimeSeries {
+				if strings.HasPrefix(filter, `metric.type = "`+metricType+`"`) {
+					_, _ = w.Write([]byte(body))
+					return
+				}
+			}
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	svc, err := monitoring.NewService(context.Background(), option.WithEndpoint(srv.URL+"/"), option.WithoutAuthentication())
+	require.NoError(t, err)
+	return svc
+}
+
+func TestCollector(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+project_ids: [my-project]
+metrics_prefixes:
+  - compute.googleapis.com/instance
+  - loadbalancing.googleapis.com
+extra_filters:
+  - compute.googleapis.com/instance/cpu:resource.labels.zone = "us-central1-a"
+`), &cfg))
+
+	var filters []string
+	col := newCollector(log.NewNopLogger(), &cfg, newTestService(t, &filters))
+
+	expect := `
+# HELP stackdriver_gce_instance_compute_googleapis_com_instance_cpu_utilization Fractional utilization of allocated CPU on this instance.
+# TYPE stackdriver_gce_instance_compute_googleapis_com_instance_cpu_utilization gauge
+stackdriver_gce_instance_compute_googleapis_com_instance_cpu_utilization{instance_id="1234",instance_name="vm-1",project_id="my-project",zone="us-central1-a"} 0.25 1642672860000
+# HELP stackdriver_gce_instance_compute_googleapis_com_instance_network_received_bytes_count Count of bytes received from the network.
+# TYPE stackdriver_gce_instance_compute_googleapis_com_instance_network_received_bytes_count counter
+stackdriver_gce_instance_compute_googleapis_com_instance_network_received_bytes_count{instance_id="1234",instance_name="vm-1",loadbalanced="false",project_id="my-project",zone="us-central1-a"} 1024 1642672860000
+# HELP stackdriver_https_lb_rule_loadbalancing_googleapis_com_https_total_latencies Distribution of the latency.
+# TYPE stackdriver_https_lb_rule_loadbalancing_googleapis_com_https_total_latencies histogram
+stackdriver_https_lb_rule_loadbalancing_googleapis_com_https_total_latencies_bucket{project_id="my-project",response_code="200",url_map_name="web",le="10"} 1 1642672860000
+stackdriver_https_lb_rule_loadbalancing_googleapis_com_https_total_latencies_bucket{project_id="my-project",response_code="200",url_map_name="web",le="20"} 7 1642672860000
+stackdriver_https_lb_rule_loadbalancing_googleapis_com_https_total_latencies_bucket{project_id="my-project",response_code="200",url_map_name="web",le="40"} 10 1642672860000
+stackdriver_https_lb_rule_loadbalancing_googleapis_com_https_total_latencies_bucket{project_id="my-project",response_code="200",url_map_name="web",le="+Inf"} 10 1642672860000
+stackdriver_https_lb_rule_loadbalancing_googleapis_com_https_total_latencies_sum{project_id="my-project",response_code="200",url_map_name="web"} 150 1642672860000
+stackdriver_https_lb_rule_loadbalancing_googleapis_com_https_total_latencies_count{project_id="my-project",response_code="200",url_map_name="web"} 10 1642672860000
+# HELP stackdriver_monitoring_up Whether metrics of the project were collected successfully.
+# TYPE stackdriver_monitoring_up gauge
+stackdriver_monitoring_up{project_id="my-project"} 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+	require.Contains(t, filters, `metric.type = "compute.googleapis.com/instance/cpu/utilization" AND resource.labels.zone = "us-central1-a"`)
+	require.Contains(t, filters, `metric.type = "loadbalancing.googleapis.com/https/total_latencies"`)
+}
+
+func TestCollector_Error(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+project_ids: [other-project]
+metrics_prefixes: [compute.googleapis.com]
+`), &cfg))
+
+	var filters []string
+	col := newCollector(log.NewNopLogger(), &cfg, newTestService(t, &filters))
+
+	expect := `
+# HELP stackdriver_monitoring_up Whether metrics of the project were collected successfully.
+# TYPE stackdriver_monitoring_up gauge
+stackdriver_monitoring_up{project_id="other-project"} 0
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+}
+
+func TestCollector_IngestDelay(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+project_ids: [my-project]
+metrics_prefixes: [compute.googleapis.com]
+request_interval: 1m
+request_offset: 30s
+ingest_delay: true
+`), &cfg))
+
+	now := time.Date(2022, 1, 20, 10, 10, 0, 0, time.UTC)
+	col := newCollector(log.NewNopLogger(), &cfg, nil)
+	col.now = func() time.Time { return now }
+
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Query().Get("interval.startTime")+"/"+r.URL.Query().Get("interval.endTime"))
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	svc, err := monitoring.NewService(context.Background(), option.WithEndpoint(srv.URL+"/"), option.WithoutAuthentication())
+	require.NoError(t, err)
+	col.svc = svc
+
+	set := &metricSet{seen: make(map[string]struct{})}
+	d := &monitoring.MetricDescriptor{
+		Type:     "compute.googleapis.com/instance/cpu/utilization",
+		Metadata: &monitoring.MetricDescriptorMetadata{IngestDelay: "240s"},
+	}
+	require.NoError(t, col.collectTimeSeries(context.Background(), "my-project", d, set))
+	require.Equal(t, []string{"2022-01-20T10:04:30Z/2022-01-20T10:05:30Z"}, requests)
+}
+
+func TestConfig_Validation(t *testing.T) {
+	tt := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "missing project",
+			config: "metrics_prefixes: [compute.googleapis.com]",
+			err:    "at least one project_id must be set",
+		},
+		{
+			name:   "missing prefixes",
+			config: "project_ids: [my-project]",
+			err:    "at least one metrics_prefix must be set",
+		},
+		{
+			name:   "invalid extra filter",
+			config: "project_ids: [my-project]\nmetrics_prefixes: [compute.googleapis.com]\nextra_filters: [foo]",
+			err:    `invalid extra_filter "foo": must be in the form <metric prefix>:<filter>`,
+		},
+		{
+			name:   "valid",
+			config: "project_ids: [a, b]\nmetrics_prefixes: [compute.googleapis.com]",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			err := yaml.Unmarshal([]byte(tc.config), &cfg)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			key, err := cfg.InstanceKey("agent")
+			require.NoError(t, err)
+			require.Equal(t, "a,b", key)
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Package gcp_exporter implements an integration which pulls metrics from
+// Google Cloud Monitoring (formerly Stackdriver) and exposes them as
+// Prometheus metrics.
+package gcp_exporter //nolint:golint
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/monitoring/v3"
+	"google.golang.org/api/option"
+)
+
+// DefaultConfig holds the default settings for the gcp_exporter integration.
+var DefaultConfig = Config{
+	RequestInterval: 5 * time.Minute,
+	ClientTimeout:   15 * time.Second,
+}
+
+// Config controls the gcp_exporter integration.
+type Config struct {
+	// ProjectIDs are the Google Cloud projects to collect metrics from.
+	ProjectIDs []string `yaml:"project_ids,omitempty"`
+	// MetricPrefixes are the prefixes of the metric types to collect, e.g.,
+	// compute.googleapis.com/instance/cpu.
+	MetricPrefixes []string `yaml:"metrics_prefixes,omitempty"`
+	// ExtraFilters are additional time series filters in the form
+	// <metric prefix>:<filter>, applied to metrics matching the prefix.
+	ExtraFilters []string `yaml:"extra_filters,omitempty"`
+	// RequestInterval is the time window of the time series requested on
+	// each collection.
+	RequestInterval time.Duration `yaml:"request_interval,omitempty"`
+	// RequestOffset moves the requested time window into the past.
+	RequestOffset time.Duration `yaml:"request_offset,omitempty"`
+	// IngestDelay additionally moves the requested time window back by the
+	// ingest delay of each metric type.
+	IngestDelay bool `yaml:"ingest_delay,omitempty"`
+	// ClientTimeout is the timeout of requests to the Cloud Monitoring API.
+	ClientTimeout time.Duration `yaml:"gcp_client_timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if len(c.ProjectIDs) == 0 {
+		return fmt.Errorf("at least one project_id must be set")
+	}
+	if len(c.MetricPrefixes) == 0 {
+		return fmt.Errorf("at least one metrics_prefix must be set")
+	}
+	for _, f := range c.ExtraFilters {
+		if prefix, filter := splitExtraFilter(f); prefix == "" || filter == "" {
+			return fmt.Errorf("invalid extra_filter %q: must be in the form <metric prefix>:<filter>", f)
+		}
+	}
+	if c.RequestInterval <= 0 {
+		return fmt.Errorf("request_interval must be positive")
+	}
+	return nil
+}
+
+// splitExtraFilter splits an extra filter into its metric prefix and filter.
+// Metric prefixes never contain colons, so the first colon separates the two.
+func splitExtraFilter(f string) (prefix, filter string) {
+	i := strings.Index(f, ":")
+	if i < 0 {
+		return "", ""
+	}
+	return f[:i], f[i+1:]
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "gcp_exporter"
+}
+
+// InstanceKey returns the comma-separated list of project IDs.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	return strings.Join(c.ProjectIDs, ","), nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
+
+// New creates a new gcp_exporter integration. Credentials are found using
+// Google's Application Default Credentials, which supports workload
+// identity, the GOOGLE_APPLICATION_CREDENTIALS environment variable, and the
+// metadata server of Google Cloud.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	ctx := context.Background()
+
+	client, err := google.DefaultClient(ctx, monitoring.MonitoringReadScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create google cloud client: %w", err)
+	}
+	client.Timeout = c.ClientTimeout
+
+	svc, err := monitoring.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cloud monitoring service: %w", err)
+	}
+
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(newCollector(logger, c, svc)),
+	), nil
+}
@@ -0,0 +1,176 @@
+package gitlab
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// component describes a GitLab component exposing metrics and how its
+// address is configured in gitlab.rb.
+type component struct {
+	// service is the name of the settings of the component in gitlab.rb,
+	// e.g., gitlab_workhorse for gitlab_workhorse['...'].
+	service string
+	// addressKey is the setting holding the host:port of the metrics
+	// endpoint. If empty, the address is built from hostKey and portKey.
+	addressKey       string
+	hostKey, portKey string
+	// disableKeys are boolean settings which disable the metrics endpoint
+	// when set to false.
+	disableKeys []string
+
+	defaultPort int
+	metricsPath string
+}
+
+// components are the supported GitLab components, keyed by their name in the
+// configuration of the integration.
+var components = map[string]component{
+	"gitaly": {
+		service:     "gitaly",
+		addressKey:  "prometheus_listen_addr",
+		disableKeys: []string{"enable"},
+		defaultPort: 9236,
+		metricsPath: "/metrics",
+	},
+	"gitlab_exporter": {
+		service:     "gitlab_exporter",
+		hostKey:     "listen_address",
+		portKey:     "listen_port",
+		disableKeys: []string{"enable"},
+		defaultPort: 9168,
+		metricsPath: "/metrics",
+	},
+	"puma": {
+		service:     "puma",
+		hostKey:     "listen",
+		portKey:     "port",
+		disableKeys: []string{"enable"},
+		defaultPort: 8080,
+		metricsPath: "/-/metrics",
+	},
+	"sidekiq": {
+		service:     "sidekiq",
+		hostKey:     "listen_address",
+		portKey:     "listen_port",
+		disableKeys: []string{"enable", "metrics_enabled"},
+		defaultPort: 8082,
+		metricsPath: "/metrics",
+	},
+	"workhorse": {
+		service:     "gitlab_workhorse",
+		addressKey:  "prometheus_listen_addr",
+		disableKeys: []string{"enable"},
+		defaultPort: 9229,
+		metricsPath: "/metrics",
+	},
+}
+
+// settingRegexp matches settings of gitlab.rb in the form
+// service['key'] = value, where value is a string, number, or boolean.
+var settingRegexp = regexp.MustCompile(`^\s*(\w+)\[['"](\w+)['"]\]\s*=\s*('[^']*'|"[^"]*"|[\w.]+)\s*(?:#.*)?$`)
+
+// gitLabRB holds the settings of gitlab.rb, keyed by service and setting.
+type gitLabRB map[string]map[string]string
+
+// parseGitLabRB parses the settings of gitlab.rb which are relevant for
+// discovering components. Settings which are not simple values, such as
+// hashes and arrays, are ignored.
+func parseGitLabRB(r io.Reader) (gitLabRB, error) {
+	settings := gitLabRB{}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		m := settingRegexp.FindStringSubmatch(scanner.Text())
+		if m == nil {
+			continue
+		}
+		value := m[3]
+		if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') {
+			value = value[1 : len(value)-1]
+		}
+		if settings[m[1]] == nil {
+			settings[m[1]] = map[string]string{}
+		}
+		settings[m[1]][m[2]] = value
+	}
+	return settings, scanner.Err()
+}
+
+func readGitLabRB(path string) (gitLabRB, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseGitLabRB(f)
+}
+
+// endpoint is a discovered metrics endpoint of a component.
+type endpoint struct {
+	component string
+	url       string
+}
+
+// discover returns the metrics endpoints of the configured components.
+// Addresses are taken from the addresses of the config, then from settings,
+// and then from the default ports of the components. Components disabled in
+// settings are skipped unless their address is set in the config.
+func discover(c *Config, settings gitLabRB) []endpoint {
+	var res []endpoint
+	for _, name := range c.Components {
+		comp := components[name]
+
+		addr, ok := c.Addresses[name]
+		if !ok {
+			if comp.disabled(settings[comp.service]) {
+				continue
+			}
+			addr = comp.address(c.Host, settings[comp.service])
+		}
+		res = append(res, endpoint{
+			component: name,
+			url:       fmt.Sprintf("http://%s%s", addr, comp.metricsPath),
+		})
+	}
+	return res
+}
+
+func (comp component) disabled(settings map[string]string) bool {
+	for _, key := range comp.disableKeys {
+		if settings[key] == "false" {
+			return true
+		}
+	}
+	return false
+}
+
+// address returns the host:port of the metrics endpoint of the component.
+// Wildcard and missing hosts are replaced by host.
+func (comp component) address(host string, settings map[string]string) string {
+	var (
+		listenHost string
+		port       = strconv.Itoa(comp.defaultPort)
+	)
+	if comp.addressKey != "" {
+		if h, p, err := net.SplitHostPort(settings[comp.addressKey]); err == nil {
+			listenHost, port = h, p
+		}
+	} else {
+		listenHost = settings[comp.hostKey]
+		if p := settings[comp.portKey]; p != "" {
+			port = p
+		}
+	}
+
+	switch listenHost {
+	case "", "0.0.0.0", "::", "*":
+		listenHost = host
+	}
+	return net.JoinHostPort(listenHost, port)
+}
@@ -0,0 +1,75 @@
+package gitlab
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const testGitLabRB = `
+external_url 'https://gitlab.example.com'
+
+## Gitaly
+gitaly['prometheus_listen_addr'] = "0.0.0.0:9237"
+
+# gitlab_workhorse['prometheus_listen_addr'] = "localhost:9229"
+
+sidekiq['listen_address'] = '10.0.0.5' # Sidekiq metrics server
+sidekiq['listen_port'] = 8092
+
+gitlab_exporter['enable'] = false
+
+puma['port'] = 8081
+puma['worker_processes'] = 4
+gitlab_rails['env'] = {
+  'GITLAB_LOG_LEVEL' => 'info'
+}
+`
+
+func TestParseGitLabRB(t *testing.T) {
+	settings, err := parseGitLabRB(strings.NewReader(testGitLabRB))
+	require.NoError(t, err)
+
+	require.Equal(t, gitLabRB{
+		"gitaly":          {"prometheus_listen_addr": "0.0.0.0:9237"},
+		"sidekiq":         {"listen_address": "10.0.0.5", "listen_port": "8092"},
+		"gitlab_exporter": {"enable": "false"},
+		"puma":            {"port": "8081", "worker_processes": "4"},
+	}, settings)
+}
+
+func TestDiscover(t *testing.T) {
+	settings, err := parseGitLabRB(strings.NewReader(testGitLabRB))
+	require.NoError(t, err)
+
+	var c Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+host: gitlab.internal
+addresses:
+  workhorse: gitlab.internal:19229
+`), &c))
+
+	require.Equal(t, []endpoint{
+		{component: "gitaly", url: "http://gitlab.internal:9237/metrics"},
+		{component: "puma", url: "http://gitlab.internal:8081/-/metrics"},
+		{component: "sidekiq", url: "http://10.0.0.5:8092/metrics"},
+		{component: "workhorse", url: "http://gitlab.internal:19229/metrics"},
+	}, discover(&c, settings))
+}
+
+func TestDiscover_Defaults(t *testing.T) {
+	var c Config
+	require.NoError(t, yaml.Unmarshal([]byte(`components: [sidekiq, workhorse]`), &c))
+
+	require.Equal(t, []endpoint{
+		{component: "sidekiq", url: "http://localhost:8082/metrics"},
+		{component: "workhorse", url: "http://localhost:9229/metrics"},
+	}, discover(&c, nil))
+}
+
+func TestConfig_UnknownComponent(t *testing.T) {
+	var c Config
+	require.EqualError(t, yaml.Unmarshal([]byte(`components: [gitlab_pages]`), &c), `unknown component "gitlab_pages"`)
+}
@@ -0,0 +1,93 @@
+// Package gitlab implements an integration which collects metrics from the
+// exporters bundled with GitLab, such as Sidekiq, Gitaly, and Workhorse. The
+// addresses of the exporters are discovered from the Omnibus GitLab
+// configuration file.
+package gitlab
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+)
+
+// DefaultConfig holds the default settings for the gitlab integration.
+var DefaultConfig = Config{
+	Host:         "localhost",
+	GitLabRBPath: "/etc/gitlab/gitlab.rb",
+	Components:   []string{"gitaly", "gitlab_exporter", "puma", "sidekiq", "workhorse"},
+	Timeout:      10 * time.Second,
+}
+
+// Config controls the gitlab integration.
+type Config struct {
+	// Host is the host GitLab is running on. It is used for components
+	// listening on all interfaces or whose address is not configured in
+	// gitlab.rb.
+	Host string `yaml:"host,omitempty"`
+	// GitLabRBPath is the path of the Omnibus GitLab configuration file used
+	// to discover the addresses of components.
+	GitLabRBPath string `yaml:"gitlab_rb_path,omitempty"`
+	// Components to collect metrics from.
+	Components []string `yaml:"components,omitempty"`
+	// Addresses overrides the discovered host:port of components.
+	Addresses map[string]string `yaml:"addresses,omitempty"`
+	// Timeout for requests to components.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.Host == "" {
+		return fmt.Errorf("host must be set")
+	}
+	for _, name := range c.Components {
+		if _, ok := components[name]; !ok {
+			return fmt.Errorf("unknown component %q", name)
+		}
+	}
+	for name, addr := range c.Addresses {
+		if _, ok := components[name]; !ok {
+			return fmt.Errorf("unknown component %q in addresses", name)
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid address of component %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "gitlab"
+}
+
+// InstanceKey returns the host GitLab is running on. The hostname:port of the
+// agent process is used when GitLab runs on the same host as the agent.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	if ip := net.ParseIP(c.Host); c.Host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return agentKey, nil
+	}
+	return c.Host, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
@@ -0,0 +1,186 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/agent/pkg/integrations/config"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+const (
+	componentLabel         = "component"
+	exportedComponentLabel = "exported_component"
+
+	upName = "gitlab_component_up"
+	upHelp = "Whether the last scrape of the metrics endpoint of the GitLab component was successful."
+)
+
+// Integration is the gitlab integration. Metrics of all discovered
+// components are merged, with a component label added to every series.
+type Integration struct {
+	c        *Config
+	gatherer *gatherer
+}
+
+// New creates a new gitlab integration.
+func New(logger log.Logger, c *Config) (*Integration, error) {
+	settings, err := readGitLabRB(c.GitLabRBPath)
+	switch {
+	case os.IsNotExist(err):
+		level.Debug(logger).Log("msg", "gitlab.rb not found, using default component addresses", "path", c.GitLabRBPath)
+	case err != nil:
+		return nil, fmt.Errorf("failed to read gitlab.rb: %w", err)
+	}
+
+	endpoints := discover(c, settings)
+	for _, e := range endpoints {
+		level.Debug(logger).Log("msg", "discovered gitlab component", "component", e.component, "url", e.url)
+	}
+
+	return &Integration{
+		c: c,
+		gatherer: &gatherer{
+			log:       logger,
+			client:    &http.Client{Timeout: c.Timeout},
+			endpoints: endpoints,
+		},
+	}, nil
+}
+
+// MetricsHandler satisfies Integration.RegisterRoutes.
+func (i *Integration) MetricsHandler() (http.Handler, error) {
+	return promhttp.HandlerFor(
+		i.gatherer,
+		promhttp.HandlerOpts{
+			ErrorHandling: promhttp.ContinueOnError,
+		},
+	), nil
+}
+
+// ScrapeConfigs satisfies Integration.ScrapeConfigs.
+func (i *Integration) ScrapeConfigs() []config.ScrapeConfig {
+	return []config.ScrapeConfig{{
+		JobName:     i.c.Name(),
+		MetricsPath: "/metrics",
+	}}
+}
+
+// Run satisfies Integration.Run.
+func (i *Integration) Run(ctx context.Context) error {
+	// We don't need to do anything here, so we can just wait for the context to
+	// finish.
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// gatherer gathers metrics by scraping the metrics endpoints of components.
+type gatherer struct {
+	log       log.Logger
+	client    *http.Client
+	endpoints []endpoint
+}
+
+// Gather implements prometheus.Gatherer.
+func (g *gatherer) Gather() ([]*dto.MetricFamily, error) {
+	results := make([]map[string]*dto.MetricFamily, len(g.endpoints))
+
+	var wg sync.WaitGroup
+	for i, e := range g.endpoints {
+		wg.Add(1)
+		go func(i int, e endpoint) {
+			defer wg.Done()
+
+			families, err := g.scrape(e.url)
+			if err != nil {
+				level.Error(g.log).Log("msg", "failed to scrape gitlab component", "component", e.component, "err", err)
+				return
+			}
+			results[i] = families
+		}(i, e)
+	}
+	wg.Wait()
+
+	up := &dto.MetricFamily{
+		Name: strPtr(upName),
+		Help: strPtr(upHelp),
+		Type: dto.MetricType_GAUGE.Enum(),
+	}
+	merged := map[string]*dto.MetricFamily{upName: up}
+
+	for i, e := range g.endpoints {
+		var v float64
+		if results[i] != nil {
+			v = 1
+		}
+		up.Metric = append(up.Metric, &dto.Metric{
+			Label: []*dto.LabelPair{{Name: strPtr(componentLabel), Value: strPtr(e.component)}},
+			Gauge: &dto.Gauge{Value: &v},
+		})
+
+		for name, mf := range results[i] {
+			addComponentLabel(mf, e.component)
+
+			existing, ok := merged[name]
+			if !ok {
+				merged[name] = mf
+				continue
+			}
+			if existing.GetType() != mf.GetType() {
+				level.Warn(g.log).Log("msg", "dropping metric family with conflicting type", "component", e.component, "metric", name)
+				continue
+			}
+			existing.Metric = append(existing.Metric, mf.Metric...)
+		}
+	}
+
+	res := make([]*dto.MetricFamily, 0, len(merged))
+	for _, mf := range merged {
+		res = append(res, mf)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].GetName() < res[j].GetName() })
+	return res, nil
+}
+
+func (g *gatherer) scrape(url string) (map[string]*dto.MetricFamily, error) {
+	resp, err := g.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+	return families, nil
+}
+
+// addComponentLabel adds the component label to all metrics of mf. An
+// existing component label is renamed to exported_component.
+func addComponentLabel(mf *dto.MetricFamily, component string) {
+	for _, m := range mf.Metric {
+		for _, l := range m.Label {
+			if l.GetName() == componentLabel {
+				l.Name = strPtr(exportedComponentLabel)
+			}
+		}
+		m.Label = append(m.Label, &dto.LabelPair{Name: strPtr(componentLabel), Value: strPtr(component)})
+		sort.Slice(m.Label, func(i, j int) bool { return m.Label[i].GetName() < m.Label[j].GetName() })
+	}
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,80 @@
+package gitlab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func newMetricsServer(t *testing.T, path, metrics string) string {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(metrics))
+	}))
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestIntegration(t *testing.T) {
+	gitaly := newMetricsServer(t, "/metrics", `
+# HELP gitaly_authentications_total Counts of of Gitaly request authentication attempts
+# TYPE gitaly_authentications_total counter
+gitaly_authentications_total{enforced="true",status="ok"} 42
+# HELP process_open_fds Number of open file descriptors.
+# TYPE process_open_fds gauge
+process_open_fds 30
+`)
+	puma := newMetricsServer(t, "/-/metrics", `
+# HELP process_open_fds Number of open file descriptors.
+# TYPE process_open_fds gauge
+process_open_fds{component="rails"} 120
+# HELP puma_workers Total number of workers
+# TYPE puma_workers gauge
+puma_workers 4
+`)
+	workhorse := newMetricsServer(t, "/other", "")
+
+	var c Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+gitlab_rb_path: `+filepath.Join(t.TempDir(), "gitlab.rb")+`
+components: [gitaly, puma, workhorse]
+addresses:
+  gitaly: `+gitaly+`
+  puma: `+puma+`
+  workhorse: `+workhorse+`
+`), &c))
+
+	i, err := New(log.NewNopLogger(), &c)
+	require.NoError(t, err)
+
+	expect := `
+# HELP gitaly_authentications_total Counts of of Gitaly request authentication attempts
+# TYPE gitaly_authentications_total counter
+gitaly_authentications_total{component="gitaly",enforced="true",status="ok"} 42
+# HELP gitlab_component_up Whether the last scrape of the metrics endpoint of the GitLab component was successful.
+# TYPE gitlab_component_up gauge
+gitlab_component_up{component="gitaly"} 1
+gitlab_component_up{component="puma"} 1
+gitlab_component_up{component="workhorse"} 0
+# HELP process_open_fds Number of open file descriptors.
+# TYPE process_open_fds gauge
+process_open_fds{component="gitaly"} 30
+process_open_fds{component="puma",exported_component="rails"} 120
+# HELP puma_workers Total number of workers
+# TYPE puma_workers gauge
+puma_workers{component="puma"} 4
+`
+	require.NoError(t, testutil.GatherAndCompare(i.gatherer, strings.NewReader(expect)))
+}
@@ -0,0 +1,391 @@
+package haproxy_exporter //nolint:golint
+
+import (
+	"crypto/tls"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "haproxy"
+
+// Values of the "type" CSV field.
+const (
+	typeFrontend = "0"
+	typeBackend  = "1"
+	typeServer   = "2"
+)
+
+// fetcher returns a reader for the CSV statistics of HAProxy.
+type fetcher func() (io.ReadCloser, error)
+
+// newFetcher returns a fetcher for the given URI. URIs with a unix scheme
+// read from the HAProxy stats socket.
+func newFetcher(uri string, sslVerify bool, timeout time.Duration) (fetcher, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse scrape_uri: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http", "https", "file":
+		client := &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: !sslVerify},
+			},
+		}
+		return func() (io.ReadCloser, error) {
+			resp, err := client.Get(uri)
+			if err != nil {
+				return nil, err
+			}
+			if resp.StatusCode != http.StatusOK {
+				resp.Body.Close()
+				return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+			}
+			return resp.Body, nil
+		}, nil
+
+	case "unix":
+		return func() (io.ReadCloser, error) {
+			conn, err := net.DialTimeout("unix", u.Path, timeout)
+			if err != nil {
+				return nil, err
+			}
+			if err := conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			if _, err := io.WriteString(conn, "show stat\n"); err != nil {
+				conn.Close()
+				return nil, err
+			}
+			return conn, nil
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported scheme %q in scrape_uri", u.Scheme)
+	}
+}
+
+// metricInfo describes how a CSV field is converted into a metric.
+type metricInfo struct {
+	field string
+	name  string
+	help  string
+	ty    prometheus.ValueType
+}
+
+var (
+	frontendMetrics = []metricInfo{
+		{"scur", "current_sessions", "Current number of active sessions.", prometheus.GaugeValue},
+		{"smax", "max_sessions", "Maximum observed number of active sessions.", prometheus.GaugeValue},
+		{"slim", "limit_sessions", "Configured session limit.", prometheus.GaugeValue},
+		{"stot", "sessions_total", "Total number of sessions.", prometheus.CounterValue},
+		{"bin", "bytes_in_total", "Current total of incoming bytes.", prometheus.CounterValue},
+		{"bout", "bytes_out_total", "Current total of outgoing bytes.", prometheus.CounterValue},
+		{"dreq", "requests_denied_total", "Total of requests denied for security.", prometheus.CounterValue},
+		{"ereq", "request_errors_total", "Total of request errors.", prometheus.CounterValue},
+		{"rate", "current_session_rate", "Current number of sessions per second over last elapsed second.", prometheus.GaugeValue},
+		{"rate_max", "max_session_rate", "Maximum observed number of sessions per second.", prometheus.GaugeValue},
+		{"req_tot", "http_requests_total", "Total HTTP requests.", prometheus.CounterValue},
+		{"comp_in", "compressor_bytes_in_total", "Number of HTTP response bytes fed to the compressor.", prometheus.CounterValue},
+		{"comp_out", "compressor_bytes_out_total", "Number of HTTP response bytes emitted by the compressor.", prometheus.CounterValue},
+	}
+	backendMetrics = []metricInfo{
+		{"qcur", "current_queue", "Current number of queued requests not assigned to any server.", prometheus.GaugeValue},
+		{"qmax", "max_queue", "Maximum observed number of queued requests not assigned to any server.", prometheus.GaugeValue},
+		{"scur", "current_sessions", "Current number of active sessions.", prometheus.GaugeValue},
+		{"smax", "max_sessions", "Maximum observed number of active sessions.", prometheus.GaugeValue},
+		{"slim", "limit_sessions", "Configured session limit.", prometheus.GaugeValue},
+		{"stot", "sessions_total", "Total number of sessions.", prometheus.CounterValue},
+		{"bin", "bytes_in_total", "Current total of incoming bytes.", prometheus.CounterValue},
+		{"bout", "bytes_out_total", "Current total of outgoing bytes.", prometheus.CounterValue},
+		{"econ", "connection_errors_total", "Total of connection errors.", prometheus.CounterValue},
+		{"eresp", "response_errors_total", "Total of response errors.", prometheus.CounterValue},
+		{"wretr", "retry_warnings_total", "Total of retry warnings.", prometheus.CounterValue},
+		{"wredis", "redispatch_warnings_total", "Total of redispatch warnings.", prometheus.CounterValue},
+		{"status", "up", "Current health status of the backend (1 = UP, 0 = DOWN).", prometheus.GaugeValue},
+		{"weight", "weight", "Total weight of the servers in the backend.", prometheus.GaugeValue},
+		{"act", "current_server", "Current number of active servers.", prometheus.GaugeValue},
+		{"rate", "current_session_rate", "Current number of sessions per second over last elapsed second.", prometheus.GaugeValue},
+		{"rate_max", "max_session_rate", "Maximum number of sessions per second.", prometheus.GaugeValue},
+		{"qtime", "http_queue_time_average_seconds", "Avg. HTTP queue time for last 1024 successful connections.", prometheus.GaugeValue},
+		{"ctime", "http_connect_time_average_seconds", "Avg. HTTP connect time for last 1024 successful connections.", prometheus.GaugeValue},
+		{"rtime", "http_response_time_average_seconds", "Avg. HTTP response time for last 1024 successful connections.", prometheus.GaugeValue},
+		{"ttime", "http_total_time_average_seconds", "Avg. HTTP total time for last 1024 successful connections.", prometheus.GaugeValue},
+	}
+	serverMetrics = []metricInfo{
+		{"qcur", "current_queue", "Current number of queued requests assigned to this server.", prometheus.GaugeValue},
+		{"qmax", "max_queue", "Maximum observed number of queued requests assigned to this server.", prometheus.GaugeValue},
+		{"scur", "current_sessions", "Current number of active sessions.", prometheus.GaugeValue},
+		{"smax", "max_sessions", "Maximum observed number of active sessions.", prometheus.GaugeValue},
+		{"slim", "limit_sessions", "Configured session limit.", prometheus.GaugeValue},
+		{"stot", "sessions_total", "Total number of sessions.", prometheus.CounterValue},
+		{"bin", "bytes_in_total", "Current total of incoming bytes.", prometheus.CounterValue},
+		{"bout", "bytes_out_total", "Current total of outgoing bytes.", prometheus.CounterValue},
+		{"econ", "connection_errors_total", "Total of connection errors.", prometheus.CounterValue},
+		{"eresp", "response_errors_total", "Total of response errors.", prometheus.CounterValue},
+		{"wretr", "retry_warnings_total", "Total of retry warnings.", prometheus.CounterValue},
+		{"wredis", "redispatch_warnings_total", "Total of redispatch warnings.", prometheus.CounterValue},
+		{"status", "up", "Current health status of the server (1 = UP, 0 = DOWN).", prometheus.GaugeValue},
+		{"weight", "weight", "Current weight of the server.", prometheus.GaugeValue},
+		{"chkfail", "check_failures_total", "Total number of failed health checks.", prometheus.CounterValue},
+		{"downtime", "downtime_seconds_total", "Total downtime in seconds.", prometheus.CounterValue},
+		{"rate", "current_session_rate", "Current number of sessions per second over last elapsed second.", prometheus.GaugeValue},
+		{"rate_max", "max_session_rate", "Maximum observed number of sessions per second.", prometheus.GaugeValue},
+		{"check_duration", "check_duration_seconds", "Previously run health check duration, in seconds.", prometheus.GaugeValue},
+		{"qtime", "http_queue_time_average_seconds", "Avg. HTTP queue time for last 1024 successful connections.", prometheus.GaugeValue},
+		{"ctime", "http_connect_time_average_seconds", "Avg. HTTP connect time for last 1024 successful connections.", prometheus.GaugeValue},
+		{"rtime", "http_response_time_average_seconds", "Avg. HTTP response time for last 1024 successful connections.", prometheus.GaugeValue},
+		{"ttime", "http_total_time_average_seconds", "Avg. HTTP total time for last 1024 successful connections.", prometheus.GaugeValue},
+	}
+)
+
+// httpResponseCodes are the suffixes of the hrsp_* CSV fields.
+var httpResponseCodes = []string{"1xx", "2xx", "3xx", "4xx", "5xx", "other"}
+
+// millisecondFields are CSV fields reported in milliseconds which are
+// converted to seconds.
+var millisecondFields = map[string]bool{
+	"check_duration": true,
+	"qtime":          true,
+	"ctime":          true,
+	"rtime":          true,
+	"ttime":          true,
+}
+
+type proxyMetrics struct {
+	labels    []string
+	metrics   []metricInfo
+	descs     map[string]*prometheus.Desc
+	responses *prometheus.Desc
+}
+
+func newProxyMetrics(subsystem string, labels []string, metrics []metricInfo) *proxyMetrics {
+	pm := &proxyMetrics{
+		labels:  labels,
+		metrics: metrics,
+		descs:   make(map[string]*prometheus.Desc, len(metrics)),
+		responses: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, "http_responses_total"),
+			"Total of HTTP responses.",
+			append(append([]string{}, labels...), "code"), nil,
+		),
+	}
+	for _, m := range metrics {
+		pm.descs[m.field] = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, subsystem, m.name),
+			m.help,
+			labels, nil,
+		)
+	}
+	return pm
+}
+
+type collector struct {
+	log   log.Logger
+	fetch fetcher
+
+	up           *prometheus.Desc
+	scrapeErrors prometheus.Counter
+	csvErrors    prometheus.Counter
+
+	frontend, backend, server *proxyMetrics
+}
+
+func newCollector(l log.Logger, fetch fetcher, serverFields []string) (*collector, error) {
+	selected := serverMetrics
+	if len(serverFields) > 0 {
+		known := make(map[string]metricInfo, len(serverMetrics))
+		for _, m := range serverMetrics {
+			known[m.field] = m
+		}
+		selected = nil
+		for _, f := range serverFields {
+			m, ok := known[f]
+			if !ok {
+				return nil, fmt.Errorf("unknown server metric field %q", f)
+			}
+			selected = append(selected, m)
+		}
+	}
+
+	return &collector{
+		log:   l,
+		fetch: fetch,
+
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "up"),
+			"Was the last scrape of HAProxy successful.",
+			nil, nil,
+		),
+		scrapeErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "exporter_total_scrapes_failed",
+			Help:      "Number of errors while scraping HAProxy.",
+		}),
+		csvErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "exporter_csv_parse_failures_total",
+			Help:      "Number of errors while parsing CSV.",
+		}),
+
+		frontend: newProxyMetrics("frontend", []string{"frontend"}, frontendMetrics),
+		backend:  newProxyMetrics("backend", []string{"backend"}, backendMetrics),
+		server:   newProxyMetrics("server", []string{"backend", "server"}, selected),
+	}, nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.up
+	c.scrapeErrors.Describe(ch)
+	c.csvErrors.Describe(ch)
+	for _, pm := range []*proxyMetrics{c.frontend, c.backend, c.server} {
+		for _, d := range pm.descs {
+			ch <- d
+		}
+		ch <- pm.responses
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	up := 1.0
+	if err := c.scrape(ch); err != nil {
+		level.Error(c.log).Log("msg", "failed to scrape haproxy", "err", err)
+		c.scrapeErrors.Inc()
+		up = 0
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, up)
+	ch <- c.scrapeErrors
+	ch <- c.csvErrors
+}
+
+func (c *collector) scrape(ch chan<- prometheus.Metric) error {
+	body, err := c.fetch()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	// Read everything up front; the stats socket closes the connection once
+	// the output was written.
+	bb, err := ioutil.ReadAll(body)
+	if err != nil {
+		return err
+	}
+
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(string(bb), "# ")))
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read csv header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[name] = i
+	}
+	for _, required := range []string{"pxname", "svname", "type"} {
+		if _, ok := columns[required]; !ok {
+			return fmt.Errorf("csv header is missing field %q", required)
+		}
+	}
+
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			c.csvErrors.Inc()
+			return fmt.Errorf("failed to parse csv: %w", err)
+		}
+		if len(row) != len(header) {
+			c.csvErrors.Inc()
+			continue
+		}
+
+		get := func(field string) string {
+			if i, ok := columns[field]; ok {
+				return row[i]
+			}
+			return ""
+		}
+
+		pxname, svname := get("pxname"), get("svname")
+		switch get("type") {
+		case typeFrontend:
+			c.emit(ch, c.frontend, get, pxname)
+		case typeBackend:
+			c.emit(ch, c.backend, get, pxname)
+		case typeServer:
+			c.emit(ch, c.server, get, pxname, svname)
+		}
+	}
+	return nil
+}
+
+func (c *collector) emit(ch chan<- prometheus.Metric, pm *proxyMetrics, get func(string) string, labels ...string) {
+	for _, m := range pm.metrics {
+		raw := get(m.field)
+
+		var value float64
+		if m.field == "status" {
+			value = parseStatus(raw)
+		} else {
+			if raw == "" {
+				continue
+			}
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				c.csvErrors.Inc()
+				continue
+			}
+			value = v
+		}
+		if millisecondFields[m.field] {
+			value /= 1000
+		}
+		ch <- prometheus.MustNewConstMetric(pm.descs[m.field], m.ty, value, labels...)
+	}
+
+	for _, code := range httpResponseCodes {
+		raw := get("hrsp_" + code)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			c.csvErrors.Inc()
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(pm.responses, prometheus.CounterValue, v, append(labels, code)...)
+	}
+}
+
+// parseStatus converts the status field into 1 for healthy and 0 for
+// unhealthy.
+func parseStatus(status string) float64 {
+	switch {
+	case strings.HasPrefix(status, "UP"), status == "OPEN", status == "no check":
+		return 1
+	default:
+		return 0
+	}
+}
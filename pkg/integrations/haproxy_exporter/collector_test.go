@@ -0,0 +1,94 @@
+package haproxy_exporter //nolint:golint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const statsCSV = `# pxname,svname,qcur,qmax,scur,smax,slim,stot,bin,bout,dreq,dresp,ereq,econ,eresp,wretr,wredis,status,weight,act,bck,chkfail,chkdown,lastchg,downtime,qlimit,pid,iid,sid,throttle,lbtot,tracked,type,rate,rate_lim,rate_max,check_status,check_code,check_duration,hrsp_1xx,hrsp_2xx,hrsp_3xx,hrsp_4xx,hrsp_5xx,hrsp_other,hanafail,req_rate,req_rate_max,req_tot,cli_abrt,srv_abrt,comp_in,comp_out,comp_byp,comp_rsp,lastsess,last_chk,last_agt,qtime,ctime,rtime,ttime,
+http-in,FRONTEND,,,5,20,2000,1234,5000,10000,0,0,3,,,,,OPEN,,,,,,,,,1,2,0,,,,0,4,0,12,,,,0,1200,10,20,4,0,,4,12,1234,,,0,0,0,0,,,,,,,,
+app,web1,0,1,2,10,,600,2500,5000,,0,,1,0,0,0,UP,1,1,0,2,0,100,7,,1,3,1,,600,,2,1,,5,L7OK,200,3,0,590,5,4,1,0,0,,,,0,0,,,,,3,L7OK,,0,1,20,25,
+app,web2,0,0,0,8,,500,2000,4000,,0,,0,0,0,0,DOWN,1,1,0,5,1,50,60,,1,3,2,,500,,2,0,,4,L4CON,,0,0,495,3,2,0,0,0,,,,0,0,,,,,10,L4CON,,0,0,0,0,
+app,BACKEND,0,2,2,20,200,1100,4500,9000,0,0,,1,0,0,0,UP,1,1,0,,0,500,0,,1,3,0,,1100,,1,1,,9,,,,0,1085,8,6,1,0,,,,1100,0,0,0,0,0,0,3,,,0,1,20,25,
+`
+
+func TestCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(statsCSV))
+	}))
+	defer srv.Close()
+
+	fetch, err := newFetcher(srv.URL+"/;csv", true, time.Second)
+	require.NoError(t, err)
+	c, err := newCollector(log.NewNopLogger(), fetch, nil)
+	require.NoError(t, err)
+
+	expect := `
+# HELP haproxy_backend_current_sessions Current number of active sessions.
+# TYPE haproxy_backend_current_sessions gauge
+haproxy_backend_current_sessions{backend="app"} 2
+# HELP haproxy_frontend_http_responses_total Total of HTTP responses.
+# TYPE haproxy_frontend_http_responses_total counter
+haproxy_frontend_http_responses_total{code="1xx",frontend="http-in"} 0
+haproxy_frontend_http_responses_total{code="2xx",frontend="http-in"} 1200
+haproxy_frontend_http_responses_total{code="3xx",frontend="http-in"} 10
+haproxy_frontend_http_responses_total{code="4xx",frontend="http-in"} 20
+haproxy_frontend_http_responses_total{code="5xx",frontend="http-in"} 4
+haproxy_frontend_http_responses_total{code="other",frontend="http-in"} 0
+# HELP haproxy_frontend_sessions_total Total number of sessions.
+# TYPE haproxy_frontend_sessions_total counter
+haproxy_frontend_sessions_total{frontend="http-in"} 1234
+# HELP haproxy_server_check_duration_seconds Previously run health check duration, in seconds.
+# TYPE haproxy_server_check_duration_seconds gauge
+haproxy_server_check_duration_seconds{backend="app",server="web1"} 0.003
+haproxy_server_check_duration_seconds{backend="app",server="web2"} 0
+# HELP haproxy_server_up Current health status of the server (1 = UP, 0 = DOWN).
+# TYPE haproxy_server_up gauge
+haproxy_server_up{backend="app",server="web1"} 1
+haproxy_server_up{backend="app",server="web2"} 0
+# HELP haproxy_up Was the last scrape of HAProxy successful.
+# TYPE haproxy_up gauge
+haproxy_up 1
+`
+	err = testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"haproxy_up", "haproxy_backend_current_sessions", "haproxy_frontend_http_responses_total",
+		"haproxy_frontend_sessions_total", "haproxy_server_check_duration_seconds", "haproxy_server_up")
+	require.NoError(t, err)
+}
+
+func TestCollector_ServerMetricFields(t *testing.T) {
+	fetch, err := newFetcher("http://localhost/;csv", true, time.Second)
+	require.NoError(t, err)
+
+	_, err = newCollector(log.NewNopLogger(), fetch, []string{"scur", "status"})
+	require.NoError(t, err)
+
+	_, err = newCollector(log.NewNopLogger(), fetch, []string{"fake"})
+	require.EqualError(t, err, `unknown server metric field "fake"`)
+}
+
+func TestCollector_Down(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	fetch, err := newFetcher(srv.URL+"/;csv", true, time.Second)
+	require.NoError(t, err)
+	c, err := newCollector(log.NewNopLogger(), fetch, nil)
+	require.NoError(t, err)
+
+	expect := `
+# HELP haproxy_up Was the last scrape of HAProxy successful.
+# TYPE haproxy_up gauge
+haproxy_up 0
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect), "haproxy_up"))
+}
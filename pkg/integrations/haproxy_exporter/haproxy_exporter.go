@@ -0,0 +1,91 @@
+// Package haproxy_exporter implements an integration which collects metrics
+// from HAProxy's statistics, modeled after
+// https://github.com/prometheus/haproxy_exporter.
+package haproxy_exporter //nolint:golint
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+)
+
+// DefaultConfig holds the default settings for the haproxy_exporter
+// integration.
+var DefaultConfig = Config{
+	ScrapeURI: "http://localhost/;csv",
+	SSLVerify: true,
+	Timeout:   5 * time.Second,
+}
+
+// Config controls the haproxy_exporter integration.
+type Config struct {
+	// ScrapeURI is the URI of the HAProxy stats page in CSV format, or the
+	// path of the stats socket prefixed with unix:.
+	ScrapeURI string `yaml:"scrape_uri,omitempty"`
+	// SSLVerify enables TLS certificate verification when scraping over HTTPS.
+	SSLVerify bool `yaml:"ssl_verify,omitempty"`
+	// Timeout for requests to HAProxy.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// ServerMetricFields is a list of CSV fields to export metrics for at the
+	// server level. Defaults to all fields.
+	ServerMetricFields []string `yaml:"server_metric_fields,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	return unmarshal((*plain)(c))
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "haproxy_exporter"
+}
+
+// InstanceKey returns the host:port of the HAProxy stats page, or the path
+// to the stats socket.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	u, err := url.Parse(c.ScrapeURI)
+	if err != nil {
+		return "", fmt.Errorf("could not parse url: %w", err)
+	}
+	if u.Scheme == "unix" {
+		return u.Path, nil
+	}
+	return u.Host, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
+
+// New creates a new haproxy_exporter integration.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	fetch, err := newFetcher(c.ScrapeURI, c.SSLVerify, c.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	col, err := newCollector(logger, fetch, c.ServerMetricFields)
+	if err != nil {
+		return nil, err
+	}
+
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(col),
+	), nil
+}
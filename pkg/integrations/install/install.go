@@ -7,20 +7,45 @@ import (
 	//
 
 	_ "github.com/grafana/agent/pkg/integrations/agent"                  // register agent
+	_ "github.com/grafana/agent/pkg/integrations/apache_http"            // register apache_http
 	_ "github.com/grafana/agent/pkg/integrations/cadvisor"               // register cadvisor
+	_ "github.com/grafana/agent/pkg/integrations/ceph"                   // register ceph
 	_ "github.com/grafana/agent/pkg/integrations/consul_exporter"        // register consul_exporter
+	_ "github.com/grafana/agent/pkg/integrations/container_runtime"      // register container_runtime
+	_ "github.com/grafana/agent/pkg/integrations/dns_server"             // register dns_server
 	_ "github.com/grafana/agent/pkg/integrations/dnsmasq_exporter"       // register dnsmasq_exporter
 	_ "github.com/grafana/agent/pkg/integrations/elasticsearch_exporter" // register elasticsearch_exporter
+	_ "github.com/grafana/agent/pkg/integrations/etcd"                   // register etcd
+	_ "github.com/grafana/agent/pkg/integrations/gcp_exporter"           // register gcp_exporter
 	_ "github.com/grafana/agent/pkg/integrations/github_exporter"        // register github_exporter
+	_ "github.com/grafana/agent/pkg/integrations/gitlab"                 // register gitlab
+	_ "github.com/grafana/agent/pkg/integrations/haproxy_exporter"       // register haproxy_exporter
+	_ "github.com/grafana/agent/pkg/integrations/jmx_exporter"           // register jmx_exporter
+	_ "github.com/grafana/agent/pkg/integrations/json_exporter"          // register json_exporter
 	_ "github.com/grafana/agent/pkg/integrations/kafka_exporter"         // register kafka_exporter
+	_ "github.com/grafana/agent/pkg/integrations/keepalived"             // register keepalived
+	_ "github.com/grafana/agent/pkg/integrations/libvirt"                // register libvirt
 	_ "github.com/grafana/agent/pkg/integrations/memcached_exporter"     // register memcached_exporter
 	_ "github.com/grafana/agent/pkg/integrations/mongodb_exporter"       // register mongodb_exporter
+	_ "github.com/grafana/agent/pkg/integrations/mssql"                  // register mssql
 	_ "github.com/grafana/agent/pkg/integrations/mysqld_exporter"        // register mysqld_exporter
+	_ "github.com/grafana/agent/pkg/integrations/nginx_exporter"         // register nginx_exporter
 	_ "github.com/grafana/agent/pkg/integrations/node_exporter"          // register node_exporter
+	_ "github.com/grafana/agent/pkg/integrations/nvidia_gpu"             // register nvidia_gpu
+	_ "github.com/grafana/agent/pkg/integrations/openstack"              // register openstack
+	_ "github.com/grafana/agent/pkg/integrations/oracledb_exporter"      // register oracledb_exporter
 	_ "github.com/grafana/agent/pkg/integrations/postgres_exporter"      // register postgres_exporter
 	_ "github.com/grafana/agent/pkg/integrations/process_exporter"       // register process_exporter
 	_ "github.com/grafana/agent/pkg/integrations/redis_exporter"         // register redis_exporter
+	_ "github.com/grafana/agent/pkg/integrations/script_exporter"        // register script_exporter
+	_ "github.com/grafana/agent/pkg/integrations/smartctl"               // register smartctl
+	_ "github.com/grafana/agent/pkg/integrations/solr"                   // register solr
+	_ "github.com/grafana/agent/pkg/integrations/sql_exporter"           // register sql_exporter
+	_ "github.com/grafana/agent/pkg/integrations/squid_exporter"         // register squid_exporter
 	_ "github.com/grafana/agent/pkg/integrations/statsd_exporter"        // register statsd_exporter
+	_ "github.com/grafana/agent/pkg/integrations/tomcat"                 // register tomcat
+	_ "github.com/grafana/agent/pkg/integrations/vault"                  // register vault
+	_ "github.com/grafana/agent/pkg/integrations/vsphere"                // register vsphere
 	_ "github.com/grafana/agent/pkg/integrations/windows_exporter"       // register windows_exporter
 
 	//
@@ -28,5 +53,6 @@ import (
 	//
 
 	_ "github.com/grafana/agent/pkg/integrations/v2/agent" // register agent
+	_ "github.com/grafana/agent/pkg/integrations/v2/app_agent_receiver"
 	_ "github.com/grafana/agent/pkg/integrations/v2/eventhandler"
 )
@@ -29,6 +29,25 @@ type Config interface {
 	NewIntegration(l log.Logger) (Integration, error)
 }
 
+// FlagsConfig is implemented by configs whose integrations depend on
+// settings from command line flags.
+type FlagsConfig interface {
+	Config
+
+	// NewIntegrationWithFlags returns an integration for the config with the
+	// given logger and flags.
+	NewIntegrationWithFlags(l log.Logger, f config.Flags) (Integration, error)
+}
+
+// NewIntegration creates the integration of c. f is passed to configs which
+// implement FlagsConfig.
+func NewIntegration(c Config, l log.Logger, f config.Flags) (Integration, error) {
+	if fc, ok := c.(FlagsConfig); ok {
+		return fc.NewIntegrationWithFlags(l, f)
+	}
+	return c.NewIntegration(l)
+}
+
 // An Integration is a process that integrates with some external system and
 // pulls telemetry data.
 type Integration interface {
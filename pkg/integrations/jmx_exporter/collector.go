@@ -0,0 +1,349 @@
+package jmx_exporter //nolint:golint
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	scrapeDurationDesc = prometheus.NewDesc(
+		"jmx_scrape_duration_seconds",
+		"Time this JMX scrape took, in seconds.",
+		nil, nil,
+	)
+	scrapeErrorDesc = prometheus.NewDesc(
+		"jmx_scrape_error",
+		"Non-zero if this scrape failed.",
+		nil, nil,
+	)
+)
+
+// collector converts MBean attributes read through Jolokia into metrics. The
+// set of metrics depends on the MBeans of the target JVM, so the collector
+// is unchecked.
+type collector struct {
+	log    log.Logger
+	client *jolokiaClient
+
+	whitelist, blacklist []*objectName
+	rules                []*compiledRule
+
+	lowercaseName, lowercaseLabels bool
+}
+
+func newCollector(l log.Logger, client *jolokiaClient, c *Config) (*collector, error) {
+	col := &collector{
+		log:             l,
+		client:          client,
+		lowercaseName:   c.LowercaseOutputName,
+		lowercaseLabels: c.LowercaseOutputLabelNames,
+	}
+
+	whitelist := c.WhitelistObjectNames
+	if len(whitelist) == 0 {
+		whitelist = []string{"*:*"}
+	}
+	for _, name := range whitelist {
+		on, err := parseObjectName(name)
+		if err != nil {
+			return nil, err
+		}
+		col.whitelist = append(col.whitelist, on)
+	}
+	for _, name := range c.BlacklistObjectNames {
+		on, err := parseObjectName(name)
+		if err != nil {
+			return nil, err
+		}
+		col.blacklist = append(col.blacklist, on)
+	}
+
+	rules := c.Rules
+	if len(rules) == 0 {
+		rules = []Rule{DefaultRule}
+	}
+	for _, r := range rules {
+		cr, err := r.compile()
+		if err != nil {
+			return nil, err
+		}
+		col.rules = append(col.rules, cr)
+	}
+	return col, nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	start := time.Now()
+
+	scrapeError := 0.0
+	if err := c.scrape(ch); err != nil {
+		level.Error(c.log).Log("msg", "failed to scrape jolokia", "err", err)
+		scrapeError = 1
+	}
+
+	ch <- prometheus.MustNewConstMetric(scrapeDurationDesc, prometheus.GaugeValue, time.Since(start).Seconds())
+	ch <- prometheus.MustNewConstMetric(scrapeErrorDesc, prometheus.GaugeValue, scrapeError)
+}
+
+func (c *collector) scrape(ch chan<- prometheus.Metric) error {
+	beans, err := c.client.read(c.whitelist)
+	if err != nil {
+		return err
+	}
+
+	// Iterate in a stable order so that the first metric seen for a name,
+	// whose help and type are used for all metrics of that name, doesn't
+	// change between scrapes.
+	names := make([]string, 0, len(beans))
+	for name := range beans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s := newScrape(ch)
+	for _, name := range names {
+		on, err := parseObjectName(name)
+		if err != nil {
+			level.Debug(c.log).Log("msg", "ignoring mbean with unparseable name", "mbean", name, "err", err)
+			continue
+		}
+		if c.blacklisted(on) {
+			continue
+		}
+
+		attrs := beans[name]
+		attrNames := make([]string, 0, len(attrs))
+		for attr := range attrs {
+			attrNames = append(attrNames, attr)
+		}
+		sort.Strings(attrNames)
+
+		for _, attr := range attrNames {
+			c.processValue(s, on, nil, attr, attrs[attr])
+		}
+	}
+	return nil
+}
+
+func (c *collector) blacklisted(on *objectName) bool {
+	for _, b := range c.blacklist {
+		if b.matches(on) {
+			return true
+		}
+	}
+	return false
+}
+
+// processValue handles the value of an attribute. Composite values are
+// flattened, with their keys appended to attrKeys.
+func (c *collector) processValue(s *scrape, on *objectName, attrKeys []string, attr string, value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		nested := append(append([]string{}, attrKeys...), attr)
+		for _, k := range keys {
+			c.processValue(s, on, nested, k, v[k])
+		}
+	case float64, bool, string:
+		c.processAttribute(s, on, attrKeys, attr, v)
+	}
+}
+
+// processAttribute applies the first matching rule to a single attribute.
+func (c *collector) processAttribute(s *scrape, on *objectName, attrKeys []string, attr string, value interface{}) {
+	beanName := on.domain + "<" + on.propertyString() + "><" + strings.Join(attrKeys, ", ") + ">"
+
+	for _, r := range c.rules {
+		attrName := attr
+		if r.AttrNameSnakeCase {
+			attrName = toSnakeCase(attr)
+		}
+		matchStr := beanName + attrName + ": " + valueString(value)
+
+		match := r.match(matchStr)
+		if match == nil {
+			continue
+		}
+
+		var (
+			v  float64
+			ok bool
+		)
+		if r.Value != "" {
+			var err error
+			v, err = strconv.ParseFloat(r.expand(r.Value, matchStr, match), 64)
+			ok = err == nil
+		} else {
+			v, ok = numericValue(value)
+		}
+		if !ok {
+			// The rule matched but no number can be produced; stop processing
+			// the attribute like jmx_exporter does.
+			return
+		}
+		v *= r.ValueFactor
+
+		if r.Name == "" {
+			c.defaultExport(s, on, attrKeys, attrName, r, v)
+			return
+		}
+
+		name := safeName(r.expand(r.Name, matchStr, match))
+		if name == "" {
+			return
+		}
+		if c.lowercaseName {
+			name = strings.ToLower(name)
+		}
+
+		var labelNames, labelValues []string
+		for k, lv := range r.Labels {
+			labelName := safeName(r.expand(k, matchStr, match))
+			labelValue := r.expand(lv, matchStr, match)
+			if labelName == "" || labelValue == "" {
+				continue
+			}
+			if c.lowercaseLabels {
+				labelName = strings.ToLower(labelName)
+			}
+			labelNames = append(labelNames, labelName)
+			labelValues = append(labelValues, labelValue)
+		}
+
+		help := r.Help
+		if help == "" {
+			help = "Attribute exposed for management " + beanName + attrName
+		}
+		s.emit(name, help, r.valueType, v, labelNames, labelValues)
+		return
+	}
+}
+
+// defaultExport exports an attribute as
+// domain_beanPropertyValue1_key1_key2_attrName{beanPropertyName2="beanPropertyValue2", ...}.
+func (c *collector) defaultExport(s *scrape, on *objectName, attrKeys []string, attrName string, r *compiledRule, v float64) {
+	parts := []string{on.domain}
+	if len(on.props) > 0 {
+		parts = append(parts, on.props[0].value)
+	}
+	parts = append(parts, attrKeys...)
+	parts = append(parts, attrName)
+
+	name := safeName(strings.Join(parts, "_"))
+	if c.lowercaseName {
+		name = strings.ToLower(name)
+	}
+
+	var labelNames, labelValues []string
+	if len(on.props) > 1 {
+		for _, p := range on.props[1:] {
+			labelName := safeName(p.key)
+			if c.lowercaseLabels {
+				labelName = strings.ToLower(labelName)
+			}
+			labelNames = append(labelNames, labelName)
+			labelValues = append(labelValues, strings.Trim(p.value, `"`))
+		}
+	}
+
+	help := r.Help
+	if help == "" {
+		help = "Attribute exposed for management " + on.raw + " " + strings.Join(append(attrKeys, attrName), ".")
+	}
+	s.emit(name, help, r.valueType, v, labelNames, labelValues)
+}
+
+func valueString(v interface{}) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	default:
+		return ""
+	}
+}
+
+func numericValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+type familyInfo struct {
+	help string
+	ty   prometheus.ValueType
+}
+
+// scrape emits metrics for a single collection, making sure all metrics of
+// the same name share help and type and that no series is emitted twice.
+type scrape struct {
+	ch       chan<- prometheus.Metric
+	families map[string]familyInfo
+	seen     map[string]struct{}
+}
+
+func newScrape(ch chan<- prometheus.Metric) *scrape {
+	return &scrape{
+		ch:       ch,
+		families: make(map[string]familyInfo),
+		seen:     make(map[string]struct{}),
+	}
+}
+
+func (s *scrape) emit(name, help string, ty prometheus.ValueType, v float64, labelNames, labelValues []string) {
+	fi, ok := s.families[name]
+	if !ok {
+		fi = familyInfo{help: help, ty: ty}
+		s.families[name] = fi
+	}
+
+	// Sort labels so that the series key is independent of map order.
+	idx := make([]int, len(labelNames))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.Slice(idx, func(i, j int) bool { return labelNames[idx[i]] < labelNames[idx[j]] })
+
+	var key strings.Builder
+	key.WriteString(name)
+	for _, i := range idx {
+		key.WriteString("\xff" + labelNames[i] + "\xff" + labelValues[i])
+	}
+	if _, dup := s.seen[key.String()]; dup {
+		return
+	}
+	s.seen[key.String()] = struct{}{}
+
+	desc := prometheus.NewDesc(name, fi.help, labelNames, nil)
+	m, err := prometheus.NewConstMetric(desc, fi.ty, v, labelValues...)
+	if err != nil {
+		return
+	}
+	s.ch <- m
+}
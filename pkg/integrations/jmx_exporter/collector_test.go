@@ -0,0 +1,178 @@
+package jmx_exporter //nolint:golint
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const jolokiaBeans = `{
+	"java.lang:type=Memory": {
+		"HeapMemoryUsage": {"committed": 2048, "init": 1024, "max": 4096, "used": 1500},
+		"Verbose": false
+	},
+	"org.apache.cassandra.metrics:type=ClientRequest,scope=Read,name=Latency": {
+		"Count": 42,
+		"Mean": 250,
+		"LatencyUnit": "MICROSECONDS"
+	},
+	"org.apache.cassandra.metrics:type=ClientRequest,scope=Write,name=Latency": {
+		"Count": 7,
+		"Mean": 1000,
+		"LatencyUnit": "MICROSECONDS"
+	}
+}`
+
+// serveJolokia answers every read request of a Jolokia bulk request with
+// jolokiaBeans.
+func serveJolokia(w http.ResponseWriter, r *http.Request) {
+	var reqs []jolokiaRequest
+	if err := json.NewDecoder(r.Body).Decode(&reqs); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resps := make([]map[string]interface{}, 0, len(reqs))
+	for _, req := range reqs {
+		resps = append(resps, map[string]interface{}{
+			"request": req,
+			"value":   json.RawMessage(jolokiaBeans),
+			"status":  200,
+		})
+	}
+	_ = json.NewEncoder(w).Encode(resps)
+}
+
+func TestCollector_DefaultExport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(serveJolokia))
+	defer srv.Close()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(`lowercase_output_label_names: true`), &cfg))
+	c, err := newCollector(log.NewNopLogger(), newJolokiaClient(http.DefaultClient, srv.URL), &cfg)
+	require.NoError(t, err)
+
+	expect := `
+# HELP java_lang_Memory_HeapMemoryUsage_used Attribute exposed for management java.lang:type=Memory HeapMemoryUsage.used
+# TYPE java_lang_Memory_HeapMemoryUsage_used untyped
+java_lang_Memory_HeapMemoryUsage_used 1500
+# HELP java_lang_Memory_Verbose Attribute exposed for management java.lang:type=Memory Verbose
+# TYPE java_lang_Memory_Verbose untyped
+java_lang_Memory_Verbose 0
+# HELP jmx_scrape_error Non-zero if this scrape failed.
+# TYPE jmx_scrape_error gauge
+jmx_scrape_error 0
+# HELP org_apache_cassandra_metrics_ClientRequest_Count Attribute exposed for management org.apache.cassandra.metrics:type=ClientRequest,scope=Read,name=Latency Count
+# TYPE org_apache_cassandra_metrics_ClientRequest_Count untyped
+org_apache_cassandra_metrics_ClientRequest_Count{name="Latency",scope="Read"} 42
+org_apache_cassandra_metrics_ClientRequest_Count{name="Latency",scope="Write"} 7
+`
+	err = testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"java_lang_Memory_HeapMemoryUsage_used", "java_lang_Memory_Verbose",
+		"jmx_scrape_error", "org_apache_cassandra_metrics_ClientRequest_Count")
+	require.NoError(t, err)
+}
+
+func TestCollector_Rules(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(serveJolokia))
+	defer srv.Close()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+lowercase_output_name: true
+blacklist_object_names: ["java.lang:*"]
+rules:
+  - pattern: 'org.apache.cassandra.metrics<type=ClientRequest, scope=(\w+), name=Latency><>Count: .*'
+    name: cassandra_client_request_latency_count
+    type: COUNTER
+    help: Number of client requests.
+    labels:
+      operation: $1
+  - pattern: 'org.apache.cassandra.metrics<type=ClientRequest, scope=(\w+), name=Latency><>Mean: .*'
+    name: cassandra_client_request_latency_mean_milliseconds
+    type: GAUGE
+    value_factor: 0.001
+    labels:
+      operation: $1
+  - pattern: 'org.apache.cassandra.metrics<type=ClientRequest, scope=(\w+), name=Latency><>LatencyUnit: (\w+)'
+    name: cassandra_latency_unit_info
+    value: "1"
+    labels:
+      unit: $2
+`), &cfg))
+	c, err := newCollector(log.NewNopLogger(), newJolokiaClient(http.DefaultClient, srv.URL), &cfg)
+	require.NoError(t, err)
+
+	expect := `
+# HELP cassandra_client_request_latency_count Number of client requests.
+# TYPE cassandra_client_request_latency_count counter
+cassandra_client_request_latency_count{operation="Read"} 42
+cassandra_client_request_latency_count{operation="Write"} 7
+# HELP cassandra_client_request_latency_mean_milliseconds Attribute exposed for management org.apache.cassandra.metrics<type=ClientRequest, scope=Read, name=Latency><>Mean
+# TYPE cassandra_client_request_latency_mean_milliseconds gauge
+cassandra_client_request_latency_mean_milliseconds{operation="Read"} 0.25
+cassandra_client_request_latency_mean_milliseconds{operation="Write"} 1
+# HELP cassandra_latency_unit_info Attribute exposed for management org.apache.cassandra.metrics<type=ClientRequest, scope=Read, name=Latency><>LatencyUnit
+# TYPE cassandra_latency_unit_info untyped
+cassandra_latency_unit_info{unit="MICROSECONDS"} 1
+`
+	err = testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"cassandra_client_request_latency_count", "cassandra_client_request_latency_mean_milliseconds",
+		"cassandra_latency_unit_info", "java_lang_Memory_Verbose")
+	require.NoError(t, err)
+}
+
+func TestCollector_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	cfg := DefaultConfig
+	c, err := newCollector(log.NewNopLogger(), newJolokiaClient(http.DefaultClient, srv.URL), &cfg)
+	require.NoError(t, err)
+
+	expect := `
+# HELP jmx_scrape_error Non-zero if this scrape failed.
+# TYPE jmx_scrape_error gauge
+jmx_scrape_error 1
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect), "jmx_scrape_error"))
+}
+
+func TestObjectName(t *testing.T) {
+	tt := []struct {
+		pattern, name string
+		expect        bool
+	}{
+		{"*:*", "java.lang:type=Memory", true},
+		{"java.lang:type=Memory", "java.lang:type=Memory", true},
+		{"java.lang:type=Memory", "java.lang:type=Memory,name=foo", false},
+		{"java.lang:type=Memory,*", "java.lang:type=Memory,name=foo", true},
+		{"org.apache.cassandra.*:type=Table,*", "org.apache.cassandra.metrics:type=Table,keyspace=ks,name=ReadLatency", true},
+		{"org.apache.cassandra.metrics:type=Table,keyspace=system*,*", "org.apache.cassandra.metrics:type=Table,keyspace=ks,name=ReadLatency", false},
+	}
+
+	for _, tc := range tt {
+		pattern, err := parseObjectName(tc.pattern)
+		require.NoError(t, err)
+		name, err := parseObjectName(tc.name)
+		require.NoError(t, err)
+		require.Equal(t, tc.expect, pattern.matches(name), "%s matching %s", tc.pattern, tc.name)
+	}
+
+	_, err := parseObjectName("java.lang")
+	require.Error(t, err)
+}
+
+func TestToSnakeCase(t *testing.T) {
+	require.Equal(t, "heap_memory_usage", toSnakeCase("HeapMemoryUsage"))
+	require.Equal(t, "collection_count", toSnakeCase("CollectionCount"))
+}
@@ -0,0 +1,117 @@
+// Package jmx_exporter implements an integration which collects JMX metrics
+// from JVM services such as Cassandra through a Jolokia agent. Rules follow
+// the format of https://github.com/prometheus/jmx_exporter.
+package jmx_exporter //nolint:golint
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig holds the default settings for the jmx_exporter integration.
+var DefaultConfig = Config{
+	JolokiaURL:       "http://localhost:8778/jolokia",
+	Timeout:          10 * time.Second,
+	HTTPClientConfig: config_util.DefaultHTTPClientConfig,
+}
+
+// Config controls the jmx_exporter integration.
+type Config struct {
+	// JolokiaURL is the base URL of the Jolokia agent attached to the JVM.
+	JolokiaURL string `yaml:"jolokia_url,omitempty"`
+	// Timeout for requests to the Jolokia agent.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// WhitelistObjectNames is a list of ObjectName patterns of MBeans to
+	// query. Defaults to all MBeans.
+	WhitelistObjectNames []string `yaml:"whitelist_object_names,omitempty"`
+	// BlacklistObjectNames is a list of ObjectName patterns of MBeans to
+	// ignore. Takes precedence over WhitelistObjectNames.
+	BlacklistObjectNames []string `yaml:"blacklist_object_names,omitempty"`
+
+	// LowercaseOutputName lowercases the names of generated metrics.
+	LowercaseOutputName bool `yaml:"lowercase_output_name,omitempty"`
+	// LowercaseOutputLabelNames lowercases the label names of generated
+	// metrics.
+	LowercaseOutputLabelNames bool `yaml:"lowercase_output_label_names,omitempty"`
+
+	// Rules to apply in order to MBean attributes. When empty, all attributes
+	// are exported with a default format.
+	Rules []Rule `yaml:"rules,omitempty"`
+
+	// HTTPClientConfig configures TLS and authentication used for reaching
+	// the Jolokia agent.
+	HTTPClientConfig config_util.HTTPClientConfig `yaml:",inline"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	for _, name := range append(c.WhitelistObjectNames, c.BlacklistObjectNames...) {
+		if _, err := parseObjectName(name); err != nil {
+			return err
+		}
+	}
+	for i, r := range c.Rules {
+		if _, err := r.compile(); err != nil {
+			return fmt.Errorf("invalid rule %d: %w", i, err)
+		}
+	}
+	return c.HTTPClientConfig.Validate()
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "jmx_exporter"
+}
+
+// InstanceKey returns the host:port of the Jolokia agent.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	u, err := url.Parse(c.JolokiaURL)
+	if err != nil {
+		return "", fmt.Errorf("could not parse url: %w", err)
+	}
+	return u.Host, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
+
+// New creates a new jmx_exporter integration.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	client, err := config_util.NewClientFromConfig(c.HTTPClientConfig, c.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http client: %w", err)
+	}
+	client.Timeout = c.Timeout
+
+	col, err := newCollector(logger, newJolokiaClient(client, c.JolokiaURL), c)
+	if err != nil {
+		return nil, err
+	}
+
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(col),
+	), nil
+}
@@ -0,0 +1,102 @@
+package jmx_exporter //nolint:golint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// jolokiaClient reads MBean attributes through the Jolokia HTTP protocol.
+type jolokiaClient struct {
+	client *http.Client
+	url    string
+}
+
+func newJolokiaClient(client *http.Client, url string) *jolokiaClient {
+	return &jolokiaClient{client: client, url: url}
+}
+
+type jolokiaRequest struct {
+	Type   string                 `json:"type"`
+	MBean  string                 `json:"mbean"`
+	Config map[string]interface{} `json:"config,omitempty"`
+}
+
+type jolokiaResponse struct {
+	Value  json.RawMessage `json:"value"`
+	Status int             `json:"status"`
+	Error  string          `json:"error"`
+}
+
+// readConfig is passed along with every read request. Errors for individual
+// attributes are ignored so one unreadable attribute doesn't fail the whole
+// MBean, and key properties keep the order they were registered with.
+var readConfig = map[string]interface{}{
+	"ignoreErrors":       true,
+	"canonicalNaming":    false,
+	"serializeException": false,
+}
+
+// read reads all attributes of the MBeans matching the given ObjectNames
+// with a single bulk request. The result maps MBean names to attribute
+// values. ObjectNames which don't match any MBean are ignored.
+func (c *jolokiaClient) read(names []*objectName) (map[string]map[string]interface{}, error) {
+	reqs := make([]jolokiaRequest, 0, len(names))
+	for _, name := range names {
+		reqs = append(reqs, jolokiaRequest{Type: "read", MBean: name.raw, Config: readConfig})
+	}
+	body, err := json.Marshal(reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.client.Post(c.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var resps []jolokiaResponse
+	if err := json.NewDecoder(resp.Body).Decode(&resps); err != nil {
+		return nil, fmt.Errorf("failed to decode jolokia response: %w", err)
+	}
+	if len(resps) != len(reqs) {
+		return nil, fmt.Errorf("expected %d responses from jolokia, got %d", len(reqs), len(resps))
+	}
+
+	res := make(map[string]map[string]interface{})
+	for i, r := range resps {
+		switch r.Status {
+		case http.StatusOK:
+		case http.StatusNotFound:
+			continue
+		default:
+			return nil, fmt.Errorf("reading %s failed with status %d: %s", names[i].raw, r.Status, r.Error)
+		}
+
+		// Pattern reads return values keyed by MBean name, while reads of a
+		// single MBean return the attributes directly.
+		if names[i].isPattern() {
+			var beans map[string]map[string]interface{}
+			if err := json.Unmarshal(r.Value, &beans); err != nil {
+				return nil, fmt.Errorf("failed to decode value of %s: %w", names[i].raw, err)
+			}
+			for name, attrs := range beans {
+				res[name] = attrs
+			}
+			continue
+		}
+
+		var attrs map[string]interface{}
+		if err := json.Unmarshal(r.Value, &attrs); err != nil {
+			return nil, fmt.Errorf("failed to decode value of %s: %w", names[i].raw, err)
+		}
+		res[names[i].raw] = attrs
+	}
+	return res, nil
+}
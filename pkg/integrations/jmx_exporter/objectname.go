@@ -0,0 +1,143 @@
+package jmx_exporter //nolint:golint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// objectName is a parsed JMX ObjectName of the form
+// domain:key=value[,key=value...][,*]. Key properties retain the order in
+// which they were written.
+type objectName struct {
+	raw     string
+	domain  string
+	props   []property
+	anyProp bool // the property list ends with a ,* wildcard
+
+	domainRe *regexp.Regexp
+	valueRes []*regexp.Regexp
+}
+
+type property struct {
+	key, value string
+}
+
+// parseObjectName parses s as an ObjectName. Glob characters (* and ?) are
+// permitted in the domain and property values, which allows the result to be
+// used as a pattern.
+func parseObjectName(s string) (*objectName, error) {
+	idx := strings.Index(s, ":")
+	if idx < 0 {
+		return nil, fmt.Errorf("invalid object name %q: missing domain separator", s)
+	}
+
+	on := &objectName{
+		raw:      s,
+		domain:   s[:idx],
+		domainRe: globRegexp(s[:idx]),
+	}
+
+	for _, part := range splitProperties(s[idx+1:]) {
+		if part == "*" {
+			on.anyProp = true
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("invalid object name %q: malformed key property %q", s, part)
+		}
+		on.props = append(on.props, property{key: kv[0], value: kv[1]})
+		on.valueRes = append(on.valueRes, globRegexp(kv[1]))
+	}
+	if len(on.props) == 0 && !on.anyProp {
+		return nil, fmt.Errorf("invalid object name %q: no key properties", s)
+	}
+	return on, nil
+}
+
+// splitProperties splits a key property list on commas that are not part of
+// a quoted value.
+func splitProperties(s string) []string {
+	var (
+		parts   []string
+		start   int
+		inQuote bool
+	)
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if inQuote {
+				i++
+			}
+		case '"':
+			inQuote = !inQuote
+		case ',':
+			if !inQuote {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// globRegexp converts a glob containing * and ? into an anchored regexp.
+func globRegexp(glob string) *regexp.Regexp {
+	expr := regexp.QuoteMeta(glob)
+	expr = strings.ReplaceAll(expr, `\*`, ".*")
+	expr = strings.ReplaceAll(expr, `\?`, ".")
+	return regexp.MustCompile("^" + expr + "$")
+}
+
+// isPattern returns true if on matches more than one concrete ObjectName.
+func (on *objectName) isPattern() bool {
+	if on.anyProp || strings.ContainsAny(on.domain, "*?") {
+		return true
+	}
+	for _, p := range on.props {
+		if !strings.HasPrefix(p.value, `"`) && strings.ContainsAny(p.value, "*?") {
+			return true
+		}
+	}
+	return false
+}
+
+// matches returns true if the concrete ObjectName name matches the pattern
+// on.
+func (on *objectName) matches(name *objectName) bool {
+	if !on.domainRe.MatchString(name.domain) {
+		return false
+	}
+	if !on.anyProp && len(on.props) != len(name.props) {
+		return false
+	}
+
+	for i, p := range on.props {
+		value, ok := name.property(p.key)
+		if !ok || !on.valueRes[i].MatchString(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// property returns the value of the key property with the given key.
+func (on *objectName) property(key string) (string, bool) {
+	for _, p := range on.props {
+		if p.key == key {
+			return p.value, true
+		}
+	}
+	return "", false
+}
+
+// propertyString returns the key properties in the format used for rule
+// matching: k1=v1, k2=v2.
+func (on *objectName) propertyString() string {
+	parts := make([]string, 0, len(on.props))
+	for _, p := range on.props {
+		parts = append(parts, p.key+"="+p.value)
+	}
+	return strings.Join(parts, ", ")
+}
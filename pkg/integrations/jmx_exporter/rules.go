@@ -0,0 +1,137 @@
+package jmx_exporter //nolint:golint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// DefaultRule holds the default settings for a Rule.
+var DefaultRule = Rule{
+	ValueFactor: 1,
+}
+
+// Rule converts matching MBean attributes into metrics. Attributes are
+// matched against a string of the form:
+//
+//	domain<beanPropertyName1=beanPropertyValue1, ...><key1, key2, ...>attrName: value
+//
+// where the keys are set for attributes nested in composite data.
+type Rule struct {
+	// Pattern is a regular expression matched against the attribute. The
+	// expression is anchored on both ends. An empty pattern matches
+	// everything.
+	Pattern string `yaml:"pattern,omitempty"`
+	// Name of the metric to create. Capture groups from Pattern can be used
+	// (e.g., $1 or ${1}). If empty, the attribute is exported with the
+	// default format.
+	Name string `yaml:"name,omitempty"`
+	// Value of the metric. Static values and capture groups can be used. If
+	// empty, the value of the attribute is used.
+	Value string `yaml:"value,omitempty"`
+	// ValueFactor multiplies the value of the metric.
+	ValueFactor float64 `yaml:"value_factor,omitempty"`
+	// Help text for the metric.
+	Help string `yaml:"help,omitempty"`
+	// Type of the metric: GAUGE, COUNTER, or UNTYPED.
+	Type string `yaml:"type,omitempty"`
+	// Labels to set on the metric. Capture groups can be used in both names
+	// and values.
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// AttrNameSnakeCase converts the attribute name to snake case before
+	// matching.
+	AttrNameSnakeCase bool `yaml:"attr_name_snake_case,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Rule.
+func (r *Rule) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*r = DefaultRule
+
+	type plain Rule
+	return unmarshal((*plain)(r))
+}
+
+type compiledRule struct {
+	Rule
+	pattern   *regexp.Regexp
+	valueType prometheus.ValueType
+}
+
+func (r Rule) compile() (*compiledRule, error) {
+	cr := &compiledRule{Rule: r}
+
+	if r.Pattern != "" {
+		re, err := regexp.Compile("^(?:" + r.Pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %w", err)
+		}
+		cr.pattern = re
+	}
+
+	switch strings.ToUpper(r.Type) {
+	case "", "UNTYPED":
+		cr.valueType = prometheus.UntypedValue
+	case "GAUGE":
+		cr.valueType = prometheus.GaugeValue
+	case "COUNTER":
+		cr.valueType = prometheus.CounterValue
+	default:
+		return nil, fmt.Errorf("unsupported type %q", r.Type)
+	}
+
+	if r.Name == "" && (len(r.Labels) > 0 || r.Help != "") {
+		return nil, fmt.Errorf("name must be set when labels or help are set")
+	}
+	return cr, nil
+}
+
+// match returns the submatch indices of s, or nil if the rule doesn't match.
+func (r *compiledRule) match(s string) []int {
+	if r.pattern == nil {
+		return []int{0, len(s)}
+	}
+	return r.pattern.FindStringSubmatchIndex(s)
+}
+
+// expand replaces capture group references in template with the submatches
+// of s.
+func (r *compiledRule) expand(template, s string, match []int) string {
+	if r.pattern == nil {
+		return template
+	}
+	return string(r.pattern.ExpandString(nil, template, s, match))
+}
+
+var (
+	unsafeChars      = regexp.MustCompile(`[^a-zA-Z0-9:_]`)
+	multiUnderscores = regexp.MustCompile(`__+`)
+)
+
+// safeName converts s into a valid Prometheus metric or label name.
+func safeName(s string) string {
+	s = unsafeChars.ReplaceAllString(s, "_")
+	s = multiUnderscores.ReplaceAllString(s, "_")
+	if s != "" && s[0] >= '0' && s[0] <= '9' {
+		s = "_" + s
+	}
+	return s
+}
+
+// toSnakeCase converts a camel case attribute name to snake case.
+func toSnakeCase(s string) string {
+	var (
+		sb   strings.Builder
+		prev rune
+	)
+	for i, r := range s {
+		if unicode.IsUpper(r) && i > 0 && (unicode.IsLower(prev) || unicode.IsDigit(prev)) {
+			sb.WriteRune('_')
+		}
+		sb.WriteRune(unicode.ToLower(r))
+		prev = r
+	}
+	return sb.String()
+}
@@ -0,0 +1,257 @@
+package json_exporter //nolint:golint
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+var upDesc = prometheus.NewDesc(
+	"json_exporter_up",
+	"Whether the target could be reached and returned valid JSON.",
+	nil, nil,
+)
+
+// metric is a metric read from JSON responses.
+type metric struct {
+	cfg       MetricConfig
+	labels    []string
+	valueType prometheus.ValueType
+	// descs holds the Desc of each value of object metrics, or the Desc of
+	// value metrics keyed by an empty string.
+	descs map[string]*prometheus.Desc
+}
+
+func newMetric(mc MetricConfig) *metric {
+	m := &metric{
+		cfg:       mc,
+		valueType: prometheus.UntypedValue,
+		descs:     make(map[string]*prometheus.Desc),
+	}
+	for l := range mc.Labels {
+		m.labels = append(m.labels, l)
+	}
+	sort.Strings(m.labels)
+
+	switch mc.ValueType {
+	case "gauge":
+		m.valueType = prometheus.GaugeValue
+	case "counter":
+		m.valueType = prometheus.CounterValue
+	}
+
+	if mc.Type == "value" {
+		m.descs[""] = prometheus.NewDesc(mc.Name, mc.Help, m.labels, nil)
+		return m
+	}
+	for v := range mc.Values {
+		m.descs[v] = prometheus.NewDesc(mc.Name+"_"+v, mc.Help, m.labels, nil)
+	}
+	return m
+}
+
+type collector struct {
+	log    log.Logger
+	module *module
+	target string
+}
+
+func newCollector(l log.Logger, m *module, target string) *collector {
+	return &collector{
+		log:    log.With(l, "target", target),
+		module: m,
+		target: target,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- upDesc
+	for _, m := range c.module.metrics {
+		for _, desc := range m.descs {
+			ch <- desc
+		}
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	data, err := c.fetch()
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to probe target", "err", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
+
+	for _, m := range c.module.metrics {
+		if err := c.collectMetric(ch, m, data); err != nil {
+			level.Error(c.log).Log("msg", "failed to read metric", "metric", m.cfg.Name, "err", err)
+		}
+	}
+}
+
+// fetch requests the target and decodes its JSON response.
+func (c *collector) fetch() (interface{}, error) {
+	req, err := http.NewRequest(http.MethodGet, c.target, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range c.module.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := c.module.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var data interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return data, nil
+}
+
+func (c *collector) collectMetric(ch chan<- prometheus.Metric, m *metric, data interface{}) error {
+	results, err := find(m.cfg.Path, data)
+	if err != nil {
+		return err
+	}
+
+	if m.cfg.Type == "value" {
+		if len(results) == 0 {
+			return fmt.Errorf("no value found at %s", m.cfg.Path)
+		}
+		v, ok := floatValue(results[0])
+		if !ok {
+			return fmt.Errorf("value %v at %s is not a number", results[0], m.cfg.Path)
+		}
+		labels, err := m.labelValues(data)
+		if err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(m.descs[""], m.valueType, v, labels...)
+		return nil
+	}
+
+	for _, obj := range results {
+		labels, err := m.labelValues(obj)
+		if err != nil {
+			return err
+		}
+		for name, path := range m.cfg.Values {
+			values, err := find(path, obj)
+			if err != nil {
+				return err
+			}
+			// Values missing from an object are skipped.
+			if len(values) == 0 {
+				continue
+			}
+			v, ok := floatValue(values[0])
+			if !ok {
+				return fmt.Errorf("value %v at %s is not a number", values[0], path)
+			}
+			ch <- prometheus.MustNewConstMetric(m.descs[name], m.valueType, v, labels...)
+		}
+	}
+	return nil
+}
+
+// labelValues returns the values of the labels of m, read relative to data.
+// Missing values result in empty labels.
+func (m *metric) labelValues(data interface{}) ([]string, error) {
+	res := make([]string, 0, len(m.labels))
+	for _, l := range m.labels {
+		values, err := find(m.cfg.Labels[l], data)
+		if err != nil {
+			return nil, err
+		}
+		var v string
+		if len(values) > 0 {
+			v = stringValue(values[0])
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// find returns the values at path in data. Arrays found at path are
+// flattened into their elements. A new JSONPath is parsed for every call, as
+// JSONPaths aren't safe for concurrent use.
+func find(path string, data interface{}) ([]interface{}, error) {
+	j := jsonpath.New("").AllowMissingKeys(true)
+	if err := j.Parse(path); err != nil {
+		return nil, err
+	}
+	results, err := j.FindResults(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var res []interface{}
+	for _, r := range results {
+		for _, v := range r {
+			if !v.IsValid() || !v.CanInterface() {
+				continue
+			}
+			switch v := v.Interface().(type) {
+			case []interface{}:
+				res = append(res, v...)
+			default:
+				res = append(res, v)
+			}
+		}
+	}
+	return res, nil
+}
+
+// floatValue converts a JSON value into a float64. Numeric strings and
+// booleans are converted as well.
+func floatValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// stringValue converts a JSON value into a label value.
+func stringValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,104 @@
+package json_exporter //nolint:golint
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	config_util "github.com/prometheus/common/config"
+)
+
+// Integration is the json_exporter integration. Every scrape of its metrics
+// handler probes the target given by the target query parameter.
+type Integration struct {
+	log     log.Logger
+	c       *Config
+	modules map[string]*module
+}
+
+// module is a Module prepared for probing targets.
+type module struct {
+	client  *http.Client
+	headers map[string]string
+	metrics []*metric
+}
+
+// New creates a new json_exporter integration.
+func New(logger log.Logger, c *Config) (*Integration, error) {
+	modules := make(map[string]*module, len(c.Modules))
+	for name, m := range c.Modules {
+		client, err := config_util.NewClientFromConfig(m.HTTPClientConfig, c.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create http client for module %s: %w", name, err)
+		}
+		client.Timeout = c.Timeout
+
+		metrics := make([]*metric, 0, len(m.Metrics))
+		for _, mc := range m.Metrics {
+			metrics = append(metrics, newMetric(mc))
+		}
+		modules[name] = &module{client: client, headers: m.Headers, metrics: metrics}
+	}
+
+	return &Integration{log: logger, c: c, modules: modules}, nil
+}
+
+// MetricsHandler satisfies Integration.RegisterRoutes.
+func (i *Integration) MetricsHandler() (http.Handler, error) {
+	return http.HandlerFunc(i.handleProbe), nil
+}
+
+func (i *Integration) handleProbe(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	target := params.Get("target")
+	if target == "" {
+		http.Error(w, "target parameter is missing", http.StatusBadRequest)
+		return
+	}
+	name, err := i.c.moduleName(params.Get("module"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(newCollector(i.log, i.modules[name], target)); err != nil {
+		http.Error(w, fmt.Sprintf("couldn't register collector: %s", err), http.StatusInternalServerError)
+		return
+	}
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{
+		ErrorHandling: promhttp.ContinueOnError,
+	}).ServeHTTP(w, r)
+}
+
+// ScrapeConfigs satisfies Integration.ScrapeConfigs. A scrape config is
+// returned for every configured target.
+func (i *Integration) ScrapeConfigs() []config.ScrapeConfig {
+	res := make([]config.ScrapeConfig, 0, len(i.c.Targets))
+	for _, t := range i.c.Targets {
+		params := url.Values{"target": {t.URL}}
+		if t.Module != "" {
+			params.Set("module", t.Module)
+		}
+		res = append(res, config.ScrapeConfig{
+			JobName:     i.c.Name() + "/" + t.Name,
+			MetricsPath: "/metrics",
+			QueryParams: params,
+		})
+	}
+	return res
+}
+
+// Run satisfies Integration.Run.
+func (i *Integration) Run(ctx context.Context) error {
+	// We don't need to do anything here, so we can just wait for the context to
+	// finish.
+	<-ctx.Done()
+	return ctx.Err()
+}
@@ -0,0 +1,145 @@
+package json_exporter //nolint:golint
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations/config"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const testConfig = `
+modules:
+  default:
+    headers:
+      X-Token: secret
+    metrics:
+      - name: app_connections
+        help: Number of open connections.
+        path: '{.connections}'
+        value_type: gauge
+        labels:
+          version: '{.version}'
+      - name: app_queue
+        type: object
+        path: '{.queues[*]}'
+        labels:
+          queue: '{.name}'
+        values:
+          messages: '{.messages}'
+          consumers: '{.consumers}'
+      - name: app_healthy
+        path: '{.healthy}'
+  other:
+    metrics:
+      - name: other_value
+        path: '{.value}'
+targets:
+  - name: app
+    url: http://localhost:8080/status
+    module: default
+`
+
+const testResponse = `{
+	"version": "1.2.3",
+	"connections": 42,
+	"healthy": true,
+	"queues": [
+		{"name": "orders", "messages": 10, "consumers": "2"},
+		{"name": "emails", "messages": 0}
+	]
+}`
+
+func newTestIntegration(t *testing.T) *Integration {
+	t.Helper()
+
+	var c Config
+	require.NoError(t, yaml.Unmarshal([]byte(testConfig), &c))
+	i, err := New(log.NewNopLogger(), &c)
+	require.NoError(t, err)
+	return i
+}
+
+func probe(t *testing.T, i *Integration, params url.Values) (int, string) {
+	t.Helper()
+
+	h, err := i.MetricsHandler()
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics?"+params.Encode(), nil))
+	body, err := ioutil.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return rec.Code, string(body)
+}
+
+func TestIntegration_Probe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(testResponse))
+	}))
+	defer srv.Close()
+
+	i := newTestIntegration(t)
+	code, body := probe(t, i, url.Values{"target": {srv.URL}, "module": {"default"}})
+	require.Equal(t, http.StatusOK, code)
+
+	expect := []string{
+		`# TYPE app_connections gauge`,
+		`app_connections{version="1.2.3"} 42`,
+		`# HELP app_healthy Retrieved value.`,
+		`# TYPE app_healthy untyped`,
+		`app_healthy 1`,
+		`app_queue_consumers{queue="orders"} 2`,
+		`app_queue_messages{queue="emails"} 0`,
+		`app_queue_messages{queue="orders"} 10`,
+		`json_exporter_up 1`,
+	}
+	for _, e := range expect {
+		require.Contains(t, body, e)
+	}
+	require.NotContains(t, body, `app_queue_consumers{queue="emails"}`)
+
+	t.Run("other module", func(t *testing.T) {
+		code, body := probe(t, i, url.Values{"target": {srv.URL}, "module": {"other"}})
+		require.Equal(t, http.StatusOK, code)
+		require.Contains(t, body, "json_exporter_up 0")
+	})
+}
+
+func TestIntegration_ProbeErrors(t *testing.T) {
+	i := newTestIntegration(t)
+
+	code, body := probe(t, i, url.Values{})
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, "target parameter is missing", strings.TrimSpace(body))
+
+	code, body = probe(t, i, url.Values{"target": {"http://localhost"}})
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, "module must be set when multiple modules are defined", strings.TrimSpace(body))
+
+	code, body = probe(t, i, url.Values{"target": {"http://localhost"}, "module": {"missing"}})
+	require.Equal(t, http.StatusBadRequest, code)
+	require.Equal(t, `unknown module "missing"`, strings.TrimSpace(body))
+}
+
+func TestIntegration_ScrapeConfigs(t *testing.T) {
+	i := newTestIntegration(t)
+	require.Equal(t, []config.ScrapeConfig{{
+		JobName:     "json_exporter/app",
+		MetricsPath: "/metrics",
+		QueryParams: url.Values{
+			"target": {"http://localhost:8080/status"},
+			"module": {"default"},
+		},
+	}}, i.ScrapeConfigs())
+}
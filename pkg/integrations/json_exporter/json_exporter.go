@@ -0,0 +1,240 @@
+// Package json_exporter implements an integration which probes JSON HTTP APIs
+// and maps the values of the responses to metrics using JSONPath rules. Like
+// https://github.com/prometheus-community/json_exporter, it follows the
+// multi-target probe pattern: the target to probe is selected with the target
+// query parameter of the metrics endpoint.
+package json_exporter //nolint:golint
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// DefaultConfig holds the default settings for the json_exporter integration.
+var DefaultConfig = Config{
+	Timeout: 10 * time.Second,
+}
+
+// DefaultModule holds the default settings for a module.
+var DefaultModule = Module{
+	HTTPClientConfig: config_util.DefaultHTTPClientConfig,
+}
+
+// Config controls the json_exporter integration.
+type Config struct {
+	// Modules define how to map the responses of targets to metrics, keyed
+	// by name.
+	Modules map[string]Module `yaml:"modules,omitempty"`
+	// Targets to probe when scraping the integration.
+	Targets []Target `yaml:"targets,omitempty"`
+	// Timeout for requests to targets.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// Module defines how to request a target and map its response to metrics.
+type Module struct {
+	// Headers to add to requests.
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Metrics to read from the response.
+	Metrics []MetricConfig `yaml:"metrics,omitempty"`
+
+	// HTTPClientConfig configures TLS and authentication used for reaching
+	// targets.
+	HTTPClientConfig config_util.HTTPClientConfig `yaml:",inline"`
+}
+
+// MetricConfig defines a metric read from a JSON response. Paths use the
+// Kubernetes JSONPath syntax, e.g., {.status.connections}.
+type MetricConfig struct {
+	// Name of the metric. For object metrics, the name of each value is
+	// appended to the name.
+	Name string `yaml:"name"`
+	// Help text of the metric.
+	Help string `yaml:"help,omitempty"`
+	// Type of the metric, either value or object. Value metrics read a single
+	// value at Path. Object metrics read Values relative to each of the
+	// objects at Path.
+	Type string `yaml:"type,omitempty"`
+	// ValueType of the metric, either gauge, counter, or untyped.
+	ValueType string `yaml:"value_type,omitempty"`
+	// Path of the value or objects.
+	Path string `yaml:"path"`
+	// Labels maps label names to paths of their values. For object metrics,
+	// paths are relative to each object.
+	Labels map[string]string `yaml:"labels,omitempty"`
+	// Values maps value names to paths relative to each object. Only used by
+	// object metrics.
+	Values map[string]string `yaml:"values,omitempty"`
+}
+
+// Target is a target to probe.
+type Target struct {
+	// Name of the target, appended to the job name of its metrics.
+	Name string `yaml:"name"`
+	// URL of the target.
+	URL string `yaml:"url"`
+	// Module to use for the target. May be omitted when only a single
+	// module is defined.
+	Module string `yaml:"module,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if len(c.Modules) == 0 {
+		return fmt.Errorf("at least one module must be defined")
+	}
+
+	names := make(map[string]struct{}, len(c.Targets))
+	for _, t := range c.Targets {
+		if t.Name == "" || t.URL == "" {
+			return fmt.Errorf("targets must have a name and url")
+		}
+		if _, ok := names[t.Name]; ok {
+			return fmt.Errorf("duplicate target %q", t.Name)
+		}
+		names[t.Name] = struct{}{}
+
+		if _, err := c.moduleName(t.Module); err != nil {
+			return fmt.Errorf("target %s: %w", t.Name, err)
+		}
+	}
+	return nil
+}
+
+// moduleName resolves the module used for probes with the given module
+// parameter, which may be omitted when only a single module is defined.
+func (c *Config) moduleName(name string) (string, error) {
+	if name == "" {
+		if len(c.Modules) != 1 {
+			return "", fmt.Errorf("module must be set when multiple modules are defined")
+		}
+		for name := range c.Modules {
+			return name, nil
+		}
+	}
+	if _, ok := c.Modules[name]; !ok {
+		return "", fmt.Errorf("unknown module %q", name)
+	}
+	return name, nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Module.
+func (m *Module) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*m = DefaultModule
+
+	type plain Module
+	if err := unmarshal((*plain)(m)); err != nil {
+		return err
+	}
+
+	if len(m.Metrics) == 0 {
+		return fmt.Errorf("at least one metric must be defined")
+	}
+	for i := range m.Metrics {
+		if err := m.Metrics[i].validate(); err != nil {
+			return fmt.Errorf("metric %s: %w", m.Metrics[i].Name, err)
+		}
+	}
+	return m.HTTPClientConfig.Validate()
+}
+
+func (m *MetricConfig) validate() error {
+	switch m.Type {
+	case "":
+		m.Type = "value"
+	case "value", "object":
+	default:
+		return fmt.Errorf("invalid type %q, must be value or object", m.Type)
+	}
+	switch m.ValueType {
+	case "":
+		m.ValueType = "untyped"
+	case "gauge", "counter", "untyped":
+	default:
+		return fmt.Errorf("invalid value_type %q, must be gauge, counter, or untyped", m.ValueType)
+	}
+	if m.Help == "" {
+		m.Help = "Retrieved value."
+	}
+
+	if err := validatePath(m.Path); err != nil {
+		return err
+	}
+	for l, path := range m.Labels {
+		if !model.LabelName(l).IsValid() {
+			return fmt.Errorf("invalid label name %q", l)
+		}
+		if err := validatePath(path); err != nil {
+			return fmt.Errorf("label %s: %w", l, err)
+		}
+	}
+
+	if m.Type == "value" {
+		if len(m.Values) > 0 {
+			return fmt.Errorf("values can only be used by object metrics")
+		}
+		if !model.IsValidMetricName(model.LabelValue(m.Name)) {
+			return fmt.Errorf("invalid metric name %q", m.Name)
+		}
+		return nil
+	}
+
+	if len(m.Values) == 0 {
+		return fmt.Errorf("values must be set for object metrics")
+	}
+	for v, path := range m.Values {
+		if !model.IsValidMetricName(model.LabelValue(m.Name + "_" + v)) {
+			return fmt.Errorf("invalid metric name %q", m.Name+"_"+v)
+		}
+		if err := validatePath(path); err != nil {
+			return fmt.Errorf("value %s: %w", v, err)
+		}
+	}
+	return nil
+}
+
+func validatePath(path string) error {
+	if path == "" {
+		return fmt.Errorf("path must be set")
+	}
+	if err := jsonpath.New("").Parse(path); err != nil {
+		return fmt.Errorf("invalid path %q: %w", path, err)
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "json_exporter"
+}
+
+// InstanceKey returns the agentKey. Metrics of the targets are distinguished
+// by their job.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	return agentKey, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
@@ -0,0 +1,79 @@
+package json_exporter //nolint:golint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tt := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "no modules",
+			config: "timeout: 5s",
+			err:    "at least one module must be defined",
+		},
+		{
+			name: "invalid path",
+			config: `
+modules:
+  default:
+    metrics:
+      - {name: m, path: '{.foo'}`,
+			err: `metric m: invalid path "{.foo": unclosed action`,
+		},
+		{
+			name: "object without values",
+			config: `
+modules:
+  default:
+    metrics:
+      - {name: m, type: object, path: '{.items[*]}'}`,
+			err: "metric m: values must be set for object metrics",
+		},
+		{
+			name: "invalid value type",
+			config: `
+modules:
+  default:
+    metrics:
+      - {name: m, value_type: histogram, path: '{.foo}'}`,
+			err: `metric m: invalid value_type "histogram", must be gauge, counter, or untyped`,
+		},
+		{
+			name: "unknown module",
+			config: `
+modules:
+  default:
+    metrics:
+      - {name: m, path: '{.foo}'}
+targets:
+  - {name: t, url: 'http://localhost', module: other}`,
+			err: `target t: unknown module "other"`,
+		},
+		{
+			name: "duplicate target",
+			config: `
+modules:
+  default:
+    metrics:
+      - {name: m, path: '{.foo}'}
+targets:
+  - {name: t, url: 'http://localhost'}
+  - {name: t, url: 'http://localhost:8080'}`,
+			err: `duplicate target "t"`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var c Config
+			require.EqualError(t, yaml.Unmarshal([]byte(tc.config), &c), tc.err)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package keepalived
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "keepalived"
+
+// dumper retrieves the data and statistics of keepalived.
+type dumper interface {
+	dump() (data, stats []byte, err error)
+}
+
+var instanceLabels = []string{"name", "interface", "vrid"}
+
+func instanceDesc(name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "vrrp", name), help, append(instanceLabels, labels...), nil)
+}
+
+var (
+	upDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "up"),
+		"Whether the data and statistics of keepalived could be read.",
+		nil, nil,
+	)
+
+	stateDesc             = instanceDesc("state", "State of the VRRP instance.", "state")
+	lastTransitionDesc    = instanceDesc("last_transition_timestamp_seconds", "Time of the last state transition of the VRRP instance.")
+	priorityDesc          = instanceDesc("priority", "Configured priority of the VRRP instance.")
+	effectivePriorityDesc = instanceDesc("effective_priority", "Effective priority of the VRRP instance, including the weights of tracked scripts and interfaces.")
+	scriptStatusDesc      = prometheus.NewDesc(prometheus.BuildFQName(namespace, "vrrp", "script_status"), "Status of the VRRP check script.", []string{"name", "status"}, nil)
+	scriptWeightDesc      = prometheus.NewDesc(prometheus.BuildFQName(namespace, "vrrp", "script_weight"), "Weight of the VRRP check script.", []string{"name"}, nil)
+
+	// statDescs maps statistics of instances to metrics.
+	statDescs = map[string]*prometheus.Desc{
+		"advertisements.received": instanceDesc("advertisements_received_total", "Advertisements received by the VRRP instance."),
+		"advertisements.sent":     instanceDesc("advertisements_sent_total", "Advertisements sent by the VRRP instance."),
+		"became master":           instanceDesc("became_master_total", "Number of times the VRRP instance transitioned to master."),
+		"released master":         instanceDesc("released_master_total", "Number of times the VRRP instance released master."),
+		"priority zero.received":  instanceDesc("priority_zero_received_total", "Advertisements with priority zero received by the VRRP instance."),
+		"priority zero.sent":      instanceDesc("priority_zero_sent_total", "Advertisements with priority zero sent by the VRRP instance."),
+	}
+
+	// groupDescs maps groups of statistics of instances to metrics with a
+	// type label holding the name of the statistic.
+	groupDescs = map[string]*prometheus.Desc{
+		"packet errors":         instanceDesc("packet_errors_total", "Invalid packets received by the VRRP instance.", "type"),
+		"authentication errors": instanceDesc("authentication_errors_total", "Authentication errors of the VRRP instance.", "type"),
+	}
+)
+
+type collector struct {
+	log    log.Logger
+	dumper dumper
+
+	// mtx serializes collections, as keepalived can only write one dump at
+	// a time.
+	mtx sync.Mutex
+}
+
+func newCollector(l log.Logger, d dumper) *collector {
+	return &collector{log: l, dumper: d}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		upDesc, stateDesc, lastTransitionDesc, priorityDesc,
+		effectivePriorityDesc, scriptStatusDesc, scriptWeightDesc,
+	} {
+		ch <- d
+	}
+	for _, d := range statDescs {
+		ch <- d
+	}
+	for _, d := range groupDescs {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	instances, scripts, err := c.read()
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to read keepalived data", "err", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
+
+	for _, i := range instances {
+		labels := []string{i.Name, i.Interface, i.VRID}
+		gauge := func(desc *prometheus.Desc, v float64, extra ...string) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, append(labels, extra...)...)
+		}
+
+		gauge(stateDesc, 1, strings.ToLower(i.State))
+		gauge(priorityDesc, i.Priority)
+		gauge(effectivePriorityDesc, i.EffectivePriority)
+		if i.LastTransition > 0 {
+			gauge(lastTransitionDesc, i.LastTransition)
+		}
+
+		for name, v := range i.Stats {
+			if desc, ok := statDescs[name]; ok {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v, labels...)
+				continue
+			}
+			parts := strings.SplitN(name, ".", 2)
+			if desc, ok := groupDescs[parts[0]]; ok && len(parts) == 2 {
+				typ := strings.ReplaceAll(parts[1], " ", "_")
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v, append(labels, typ)...)
+			}
+		}
+	}
+
+	for _, s := range scripts {
+		ch <- prometheus.MustNewConstMetric(scriptStatusDesc, prometheus.GaugeValue, 1, s.Name, strings.ToLower(s.Status))
+		ch <- prometheus.MustNewConstMetric(scriptWeightDesc, prometheus.GaugeValue, s.Weight, s.Name)
+	}
+}
+
+func (c *collector) read() ([]*vrrpInstance, []*vrrpScript, error) {
+	data, stats, err := c.dumper.dump()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	instances, scripts, err := parseData(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse data: %w", err)
+	}
+	if err := parseStats(bytes.NewReader(stats), instances); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse stats: %w", err)
+	}
+	return instances, scripts, nil
+}
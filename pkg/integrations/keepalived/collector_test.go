@@ -0,0 +1,171 @@
+package keepalived
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const testData = `------< Global definitions >------
+ Network namespace = (default)
+ Router ID = lb-1
+------< VRRP Topology >------
+ VRRP Instance = VI_1
+   VRRP Version = 2
+   State = MASTER
+   Flags: none
+   Wantstate = MASTER
+   Last transition = 1619081547.564877 (Thu Apr 22 08:52:27.564877 2021)
+   Interface = eth0
+   Using src_ip = 10.0.0.11
+   Virtual Router ID = 51
+   Priority = 100
+   Effective priority = 102
+   Advert interval = 1 sec
+   Virtual IP (1):
+     10.0.0.100/32 dev eth0 scope global
+   Tracked scripts :
+     chk_haproxy weight 2
+ VRRP Instance = VI_2
+   State = BACKUP
+   Interface = eth1
+   Virtual Router ID = 52
+   Priority = 90
+   Effective priority = 90
+------< VRRP Scripts >------
+ VRRP Script = chk_haproxy
+   Command = /usr/bin/killall -0 haproxy
+   Interval = 2 sec
+   Timeout = 0 sec
+   Weight = 2
+   Rise = 2
+   Fall = 2
+   Status = GOOD
+   State = idle
+`
+
+const testStats = `VRRP Instance: VI_1
+  Advertisements:
+    Received: 5
+    Sent: 2156
+  Became master: 2
+  Released master: 1
+  Packet Errors:
+    Length: 0
+    TTL: 1
+    Invalid Type: 0
+  Authentication Errors:
+    Invalid Type: 0
+    Type Mismatch: 3
+  Priority Zero:
+    Received: 0
+    Sent: 1
+VRRP Instance: VI_2
+  Advertisements:
+    Received: 2100
+    Sent: 0
+  Became master: 0
+  Released master: 0
+`
+
+type fakeDumper struct {
+	data, stats string
+	err         error
+}
+
+func (d fakeDumper) dump() ([]byte, []byte, error) {
+	return []byte(d.data), []byte(d.stats), d.err
+}
+
+func TestCollector(t *testing.T) {
+	c := newCollector(log.NewNopLogger(), fakeDumper{data: testData, stats: testStats})
+
+	expect := `
+# HELP keepalived_up Whether the data and statistics of keepalived could be read.
+# TYPE keepalived_up gauge
+keepalived_up 1
+# HELP keepalived_vrrp_advertisements_received_total Advertisements received by the VRRP instance.
+# TYPE keepalived_vrrp_advertisements_received_total counter
+keepalived_vrrp_advertisements_received_total{interface="eth0",name="VI_1",vrid="51"} 5
+keepalived_vrrp_advertisements_received_total{interface="eth1",name="VI_2",vrid="52"} 2100
+# HELP keepalived_vrrp_authentication_errors_total Authentication errors of the VRRP instance.
+# TYPE keepalived_vrrp_authentication_errors_total counter
+keepalived_vrrp_authentication_errors_total{interface="eth0",name="VI_1",type="invalid_type",vrid="51"} 0
+keepalived_vrrp_authentication_errors_total{interface="eth0",name="VI_1",type="type_mismatch",vrid="51"} 3
+# HELP keepalived_vrrp_became_master_total Number of times the VRRP instance transitioned to master.
+# TYPE keepalived_vrrp_became_master_total counter
+keepalived_vrrp_became_master_total{interface="eth0",name="VI_1",vrid="51"} 2
+keepalived_vrrp_became_master_total{interface="eth1",name="VI_2",vrid="52"} 0
+# HELP keepalived_vrrp_effective_priority Effective priority of the VRRP instance, including the weights of tracked scripts and interfaces.
+# TYPE keepalived_vrrp_effective_priority gauge
+keepalived_vrrp_effective_priority{interface="eth0",name="VI_1",vrid="51"} 102
+keepalived_vrrp_effective_priority{interface="eth1",name="VI_2",vrid="52"} 90
+# HELP keepalived_vrrp_last_transition_timestamp_seconds Time of the last state transition of the VRRP instance.
+# TYPE keepalived_vrrp_last_transition_timestamp_seconds gauge
+keepalived_vrrp_last_transition_timestamp_seconds{interface="eth0",name="VI_1",vrid="51"} 1.619081547564877e+09
+# HELP keepalived_vrrp_packet_errors_total Invalid packets received by the VRRP instance.
+# TYPE keepalived_vrrp_packet_errors_total counter
+keepalived_vrrp_packet_errors_total{interface="eth0",name="VI_1",type="invalid_type",vrid="51"} 0
+keepalived_vrrp_packet_errors_total{interface="eth0",name="VI_1",type="length",vrid="51"} 0
+keepalived_vrrp_packet_errors_total{interface="eth0",name="VI_1",type="ttl",vrid="51"} 1
+# HELP keepalived_vrrp_priority Configured priority of the VRRP instance.
+# TYPE keepalived_vrrp_priority gauge
+keepalived_vrrp_priority{interface="eth0",name="VI_1",vrid="51"} 100
+keepalived_vrrp_priority{interface="eth1",name="VI_2",vrid="52"} 90
+# HELP keepalived_vrrp_priority_zero_sent_total Advertisements with priority zero sent by the VRRP instance.
+# TYPE keepalived_vrrp_priority_zero_sent_total counter
+keepalived_vrrp_priority_zero_sent_total{interface="eth0",name="VI_1",vrid="51"} 1
+# HELP keepalived_vrrp_released_master_total Number of times the VRRP instance released master.
+# TYPE keepalived_vrrp_released_master_total counter
+keepalived_vrrp_released_master_total{interface="eth0",name="VI_1",vrid="51"} 1
+keepalived_vrrp_released_master_total{interface="eth1",name="VI_2",vrid="52"} 0
+# HELP keepalived_vrrp_script_status Status of the VRRP check script.
+# TYPE keepalived_vrrp_script_status gauge
+keepalived_vrrp_script_status{name="chk_haproxy",status="good"} 1
+# HELP keepalived_vrrp_script_weight Weight of the VRRP check script.
+# TYPE keepalived_vrrp_script_weight gauge
+keepalived_vrrp_script_weight{name="chk_haproxy"} 2
+# HELP keepalived_vrrp_state State of the VRRP instance.
+# TYPE keepalived_vrrp_state gauge
+keepalived_vrrp_state{interface="eth0",name="VI_1",state="master",vrid="51"} 1
+keepalived_vrrp_state{interface="eth1",name="VI_2",state="backup",vrid="52"} 1
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"keepalived_up",
+		"keepalived_vrrp_advertisements_received_total",
+		"keepalived_vrrp_authentication_errors_total",
+		"keepalived_vrrp_became_master_total",
+		"keepalived_vrrp_effective_priority",
+		"keepalived_vrrp_last_transition_timestamp_seconds",
+		"keepalived_vrrp_packet_errors_total",
+		"keepalived_vrrp_priority",
+		"keepalived_vrrp_priority_zero_sent_total",
+		"keepalived_vrrp_released_master_total",
+		"keepalived_vrrp_script_status",
+		"keepalived_vrrp_script_weight",
+		"keepalived_vrrp_state",
+	))
+}
+
+func TestCollector_Error(t *testing.T) {
+	c := newCollector(log.NewNopLogger(), fakeDumper{err: errors.New("keepalived is not running")})
+
+	expect := `
+# HELP keepalived_up Whether the data and statistics of keepalived could be read.
+# TYPE keepalived_up gauge
+keepalived_up 0
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect)))
+}
+
+func TestParseData_InvalidNumber(t *testing.T) {
+	_, _, err := parseData(strings.NewReader(`------< VRRP Topology >------
+ VRRP Instance = VI_1
+   Priority = high
+`))
+	require.EqualError(t, err, `instance VI_1: invalid value "high" of Priority`)
+}
@@ -0,0 +1,63 @@
+// Package keepalived implements an integration which collects the state,
+// priority, and statistics of VRRP instances and the status of check scripts
+// from keepalived.
+package keepalived
+
+import (
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+)
+
+// DefaultConfig holds the default settings for the keepalived integration.
+var DefaultConfig = Config{
+	PIDPath:   "/var/run/keepalived.pid",
+	DataPath:  "/tmp/keepalived.data",
+	StatsPath: "/tmp/keepalived.stats",
+	Timeout:   5 * time.Second,
+}
+
+// Config controls the keepalived integration.
+type Config struct {
+	// PIDPath is the path of the PID file of the keepalived parent process.
+	PIDPath string `yaml:"pid_path,omitempty"`
+	// DataPath is the path of the file keepalived writes its data to when
+	// receiving SIGUSR1.
+	DataPath string `yaml:"data_path,omitempty"`
+	// StatsPath is the path of the file keepalived writes its statistics to
+	// when receiving SIGUSR2.
+	StatsPath string `yaml:"stats_path,omitempty"`
+	// Timeout for keepalived to write its data and statistics.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	return unmarshal((*plain)(c))
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "keepalived"
+}
+
+// InstanceKey returns the hostname:port of the agent process.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	return agentKey, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeSingleton, metricsutils.CreateShim)
+}
@@ -0,0 +1,102 @@
+//go:build linux
+// +build linux
+
+package keepalived
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+)
+
+// pollInterval is the interval to check whether keepalived finished writing
+// a dump.
+const pollInterval = 50 * time.Millisecond
+
+// New creates a new keepalived integration. The agent must be allowed to
+// send signals to keepalived, which usually requires running as root.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	d := &signalDumper{
+		pidPath:   c.PIDPath,
+		dataPath:  c.DataPath,
+		statsPath: c.StatsPath,
+		timeout:   c.Timeout,
+	}
+
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(newCollector(logger, d)),
+	), nil
+}
+
+// signalDumper implements dumper by signaling keepalived to write its data
+// and statistics to files.
+type signalDumper struct {
+	pidPath, dataPath, statsPath string
+	timeout                      time.Duration
+}
+
+func (d *signalDumper) dump() (data, stats []byte, err error) {
+	pid, err := readPID(d.pidPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if data, err = d.signalAndRead(pid, syscall.SIGUSR1, d.dataPath); err != nil {
+		return nil, nil, fmt.Errorf("failed to dump data: %w", err)
+	}
+	if stats, err = d.signalAndRead(pid, syscall.SIGUSR2, d.statsPath); err != nil {
+		return nil, nil, fmt.Errorf("failed to dump stats: %w", err)
+	}
+	return data, stats, nil
+}
+
+// signalAndRead sends sig to keepalived and reads path once keepalived
+// finished writing it. keepalived does not write dumps atomically, so the
+// dump is considered finished when the file was modified after sending sig
+// and has not changed for one poll interval.
+func (d *signalDumper) signalAndRead(pid int, sig syscall.Signal, path string) ([]byte, error) {
+	prev, _ := os.Stat(path)
+
+	if err := syscall.Kill(pid, sig); err != nil {
+		return nil, fmt.Errorf("failed to signal keepalived: %w", err)
+	}
+
+	deadline := time.Now().Add(d.timeout)
+	var last os.FileInfo
+	for time.Now().Before(deadline) {
+		time.Sleep(pollInterval)
+
+		fi, err := os.Stat(path)
+		if err != nil || (prev != nil && sameFileInfo(prev, fi)) {
+			continue
+		}
+		if last != nil && sameFileInfo(last, fi) {
+			return os.ReadFile(path)
+		}
+		last = fi
+	}
+	return nil, fmt.Errorf("timed out waiting for keepalived to write %s", path)
+}
+
+func sameFileInfo(a, b os.FileInfo) bool {
+	return a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+}
+
+func readPID(path string) (int, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read pid file: %w", err)
+	}
+	pid, err := strconv.Atoi(string(bytes.TrimSpace(buf)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid pid file %s: %w", path, err)
+	}
+	return pid, nil
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package keepalived
+
+import (
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/agent/pkg/integrations"
+)
+
+// New creates a new keepalived integration.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	level.Warn(logger).Log("msg", "the keepalived integration only works on linux; enabling it on other platforms will do nothing")
+	return &integrations.StubIntegration{}, nil
+}
@@ -0,0 +1,64 @@
+//go:build linux
+// +build linux
+
+package keepalived
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestSignalDumper signals the test process, which writes the dumps like
+// keepalived would.
+func TestSignalDumper(t *testing.T) {
+	dir := t.TempDir()
+	d := &signalDumper{
+		pidPath:   filepath.Join(dir, "keepalived.pid"),
+		dataPath:  filepath.Join(dir, "keepalived.data"),
+		statsPath: filepath.Join(dir, "keepalived.stats"),
+		timeout:   5 * time.Second,
+	}
+	require.NoError(t, os.WriteFile(d.pidPath, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644))
+	// Stale dumps of a previous collection must not be returned.
+	require.NoError(t, os.WriteFile(d.dataPath, []byte("stale"), 0644))
+
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2)
+	defer signal.Stop(sigs)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case sig := <-sigs:
+				path, content := d.dataPath, testData
+				if sig == syscall.SIGUSR2 {
+					path, content = d.statsPath, testStats
+				}
+				_ = os.WriteFile(path, []byte(content), 0644)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	data, stats, err := d.dump()
+	require.NoError(t, err)
+	require.Equal(t, testData, string(data))
+	require.Equal(t, testStats, string(stats))
+}
+
+func TestSignalDumper_NoPIDFile(t *testing.T) {
+	d := &signalDumper{pidPath: filepath.Join(t.TempDir(), "keepalived.pid")}
+	_, _, err := d.dump()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to read pid file")
+}
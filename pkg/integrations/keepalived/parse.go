@@ -0,0 +1,196 @@
+package keepalived
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// vrrpInstance is a VRRP instance read from the data and statistics of
+// keepalived.
+type vrrpInstance struct {
+	Name      string
+	Interface string
+	VRID      string
+	State     string
+
+	Priority          float64
+	EffectivePriority float64
+	// LastTransition is the Unix time of the last state transition.
+	LastTransition float64
+
+	// Stats holds the statistics of the instance, keyed by the lowercase
+	// group and name of the statistic, e.g., "advertisements.received" or
+	// "became master".
+	Stats map[string]float64
+}
+
+// vrrpScript is a VRRP check script read from the data of keepalived.
+type vrrpScript struct {
+	Name   string
+	Status string
+	Weight float64
+}
+
+// parseData parses the data keepalived writes when receiving SIGUSR1.
+// Instances are listed in the VRRP Topology section and scripts in the VRRP
+// Scripts section, with one property per line:
+//
+//   ------< VRRP Topology >------
+//    VRRP Instance = VI_1
+//      State = MASTER
+//      Priority = 100
+//
+// Properties with a deeper indentation, such as virtual IP addresses, are
+// ignored.
+func parseData(r io.Reader) ([]*vrrpInstance, []*vrrpScript, error) {
+	var (
+		instances []*vrrpInstance
+		scripts   []*vrrpScript
+
+		section  string
+		instance *vrrpInstance
+		script   *vrrpScript
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "------<") {
+			section = strings.TrimSpace(strings.Trim(line, "-<>"))
+			instance, script = nil, nil
+			continue
+		}
+
+		key, value, ok := splitProperty(line, " = ")
+		if !ok {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		switch {
+		case section == "VRRP Topology" && key == "VRRP Instance":
+			instance = &vrrpInstance{Name: value, Stats: map[string]float64{}}
+			instances = append(instances, instance)
+		case section == "VRRP Scripts" && key == "VRRP Script":
+			script = &vrrpScript{Name: value}
+			scripts = append(scripts, script)
+		case instance != nil && indent == 3:
+			if err := instance.set(key, value); err != nil {
+				return nil, nil, fmt.Errorf("instance %s: %w", instance.Name, err)
+			}
+		case script != nil && indent == 3:
+			if err := script.set(key, value); err != nil {
+				return nil, nil, fmt.Errorf("script %s: %w", script.Name, err)
+			}
+		}
+	}
+	return instances, scripts, scanner.Err()
+}
+
+func (i *vrrpInstance) set(key, value string) (err error) {
+	switch key {
+	case "State":
+		i.State = value
+	case "Interface":
+		i.Interface = value
+	case "Virtual Router ID":
+		i.VRID = value
+	case "Priority":
+		i.Priority, err = parseNumber(key, value)
+	case "Effective priority":
+		i.EffectivePriority, err = parseNumber(key, value)
+	case "Last transition":
+		// The Unix time is followed by the formatted time, e.g.,
+		// 1619081547.564877 (Thu Apr 22 08:52:27.564877 2021).
+		if fields := strings.Fields(value); len(fields) > 0 {
+			i.LastTransition, err = parseNumber(key, fields[0])
+		}
+	}
+	return err
+}
+
+func (s *vrrpScript) set(key, value string) (err error) {
+	switch key {
+	case "Status":
+		s.Status = value
+	case "Weight":
+		s.Weight, err = parseNumber(key, value)
+	}
+	return err
+}
+
+// parseStats parses the statistics keepalived writes when receiving SIGUSR2
+// and adds them to the matching instances. Statistics are either listed
+// directly below an instance or grouped:
+//
+//   VRRP Instance: VI_1
+//     Advertisements:
+//       Received: 0
+//       Sent: 2156
+//     Became master: 1
+func parseStats(r io.Reader, instances []*vrrpInstance) error {
+	byName := make(map[string]*vrrpInstance, len(instances))
+	for _, i := range instances {
+		byName[i.Name] = i
+	}
+
+	var (
+		instance *vrrpInstance
+		group    string
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		key, value, ok := splitProperty(line, ":")
+		if !ok {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		switch {
+		case indent == 0:
+			instance, group = nil, ""
+			if key == "VRRP Instance" {
+				instance = byName[value]
+			}
+		case instance == nil:
+		case value == "":
+			group = strings.ToLower(key)
+		default:
+			name := strings.ToLower(key)
+			if indent > 2 && group != "" {
+				name = group + "." + name
+			}
+			v, err := parseNumber(key, value)
+			if err != nil {
+				return fmt.Errorf("instance %s: %w", instance.Name, err)
+			}
+			instance.Stats[name] = v
+		}
+	}
+	return scanner.Err()
+}
+
+// splitProperty splits line into a key and value separated by sep. The value
+// is empty for group headers such as "Advertisements:".
+func splitProperty(line, sep string) (key, value string, ok bool) {
+	idx := strings.Index(line, sep)
+	if idx < 0 {
+		return "", "", false
+	}
+	key = strings.TrimSpace(line[:idx])
+	value = strings.TrimSpace(line[idx+len(sep):])
+	return key, value, key != ""
+}
+
+func parseNumber(key, value string) (float64, error) {
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q of %s", value, key)
+	}
+	return v, nil
+}
@@ -0,0 +1,213 @@
+package libvirt
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "libvirt"
+
+// Statistics requested from libvirt, from virDomainStatsTypes.
+const (
+	statsState     = 1 << 0
+	statsCPUTotal  = 1 << 1
+	statsBalloon   = 1 << 2
+	statsVCPU      = 1 << 3
+	statsInterface = 1 << 4
+	statsBlock     = 1 << 5
+
+	statsAll = statsState | statsCPUTotal | statsBalloon | statsVCPU | statsInterface | statsBlock
+)
+
+// domainStates are the names of virDomainState values.
+var domainStates = []string{"nostate", "running", "blocked", "paused", "shutdown", "shutoff", "crashed", "pmsuspended"}
+
+const (
+	nsPerSecond   = 1e9
+	bytesPerKiB   = 1024
+	noScaleFactor = 1
+)
+
+// stat maps a parameter of the domain statistics to a metric.
+type stat struct {
+	param     string
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	// scale converts the value of the parameter to the unit of the metric.
+	scale float64
+}
+
+func newStat(param, name, help string, valueType prometheus.ValueType, scale float64, labels ...string) stat {
+	return stat{
+		param:     param,
+		desc:      prometheus.NewDesc(prometheus.BuildFQName(namespace, "domain", name), help, append([]string{"domain"}, labels...), nil),
+		valueType: valueType,
+		scale:     scale,
+	}
+}
+
+var (
+	upDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "up"),
+		"Whether libvirt could be reached.",
+		nil, nil,
+	)
+	stateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "domain", "state"),
+		"State of the domain.",
+		[]string{"domain", "state"}, nil,
+	)
+
+	// domainStatsList are statistics of the domain as a whole.
+	domainStatsList = []stat{
+		newStat("cpu.time", "cpu_time_seconds_total", "CPU time consumed by the domain.", prometheus.CounterValue, 1/nsPerSecond),
+		newStat("cpu.user", "cpu_user_seconds_total", "User CPU time consumed by the domain.", prometheus.CounterValue, 1/nsPerSecond),
+		newStat("cpu.system", "cpu_system_seconds_total", "System CPU time consumed by the domain.", prometheus.CounterValue, 1/nsPerSecond),
+		newStat("vcpu.current", "vcpus_current", "Number of online vCPUs of the domain.", prometheus.GaugeValue, noScaleFactor),
+		newStat("vcpu.maximum", "vcpus_maximum", "Maximum number of vCPUs of the domain.", prometheus.GaugeValue, noScaleFactor),
+		newStat("balloon.current", "memory_balloon_current_bytes", "Current memory of the domain as set by the balloon driver.", prometheus.GaugeValue, bytesPerKiB),
+		newStat("balloon.maximum", "memory_balloon_maximum_bytes", "Maximum memory of the domain.", prometheus.GaugeValue, bytesPerKiB),
+		newStat("balloon.unused", "memory_unused_bytes", "Memory unused by the guest.", prometheus.GaugeValue, bytesPerKiB),
+		newStat("balloon.available", "memory_available_bytes", "Memory available to the guest.", prometheus.GaugeValue, bytesPerKiB),
+		newStat("balloon.usable", "memory_usable_bytes", "Memory usable by the guest without swapping.", prometheus.GaugeValue, bytesPerKiB),
+		newStat("balloon.disk_caches", "memory_disk_caches_bytes", "Memory of the guest used for disk caches.", prometheus.GaugeValue, bytesPerKiB),
+		newStat("balloon.rss", "memory_rss_bytes", "Resident set size of the domain process on the host.", prometheus.GaugeValue, bytesPerKiB),
+		newStat("balloon.swap_in", "memory_swap_in_bytes_total", "Memory swapped in by the guest.", prometheus.CounterValue, bytesPerKiB),
+		newStat("balloon.swap_out", "memory_swap_out_bytes_total", "Memory swapped out by the guest.", prometheus.CounterValue, bytesPerKiB),
+		newStat("balloon.major_fault", "memory_major_faults_total", "Major page faults of the guest.", prometheus.CounterValue, noScaleFactor),
+		newStat("balloon.minor_fault", "memory_minor_faults_total", "Minor page faults of the guest.", prometheus.CounterValue, noScaleFactor),
+	}
+
+	// vcpuStatsList are statistics of each vCPU, with parameters prefixed by
+	// vcpu.<num>.
+	vcpuStatsList = []stat{
+		newStat("time", "vcpu_time_seconds_total", "CPU time consumed by the vCPU.", prometheus.CounterValue, 1/nsPerSecond, "vcpu"),
+		newStat("wait", "vcpu_wait_seconds_total", "Time the vCPU wanted to run but was not scheduled.", prometheus.CounterValue, 1/nsPerSecond, "vcpu"),
+	}
+
+	// interfaceStatsList are statistics of each network interface, with
+	// parameters prefixed by net.<num>.
+	interfaceStatsList = []stat{
+		newStat("rx.bytes", "interface_receive_bytes_total", "Bytes received by the network interface.", prometheus.CounterValue, noScaleFactor, "interface"),
+		newStat("rx.pkts", "interface_receive_packets_total", "Packets received by the network interface.", prometheus.CounterValue, noScaleFactor, "interface"),
+		newStat("rx.errs", "interface_receive_errors_total", "Receive errors of the network interface.", prometheus.CounterValue, noScaleFactor, "interface"),
+		newStat("rx.drop", "interface_receive_drops_total", "Received packets dropped by the network interface.", prometheus.CounterValue, noScaleFactor, "interface"),
+		newStat("tx.bytes", "interface_transmit_bytes_total", "Bytes transmitted by the network interface.", prometheus.CounterValue, noScaleFactor, "interface"),
+		newStat("tx.pkts", "interface_transmit_packets_total", "Packets transmitted by the network interface.", prometheus.CounterValue, noScaleFactor, "interface"),
+		newStat("tx.errs", "interface_transmit_errors_total", "Transmit errors of the network interface.", prometheus.CounterValue, noScaleFactor, "interface"),
+		newStat("tx.drop", "interface_transmit_drops_total", "Transmitted packets dropped by the network interface.", prometheus.CounterValue, noScaleFactor, "interface"),
+	}
+
+	// blockStatsList are statistics of each block device, with parameters
+	// prefixed by block.<num>.
+	blockStatsList = []stat{
+		newStat("rd.reqs", "block_read_requests_total", "Read requests of the block device.", prometheus.CounterValue, noScaleFactor, "device", "path"),
+		newStat("rd.bytes", "block_read_bytes_total", "Bytes read from the block device.", prometheus.CounterValue, noScaleFactor, "device", "path"),
+		newStat("rd.times", "block_read_seconds_total", "Time spent reading from the block device.", prometheus.CounterValue, 1/nsPerSecond, "device", "path"),
+		newStat("wr.reqs", "block_write_requests_total", "Write requests of the block device.", prometheus.CounterValue, noScaleFactor, "device", "path"),
+		newStat("wr.bytes", "block_write_bytes_total", "Bytes written to the block device.", prometheus.CounterValue, noScaleFactor, "device", "path"),
+		newStat("wr.times", "block_write_seconds_total", "Time spent writing to the block device.", prometheus.CounterValue, 1/nsPerSecond, "device", "path"),
+		newStat("fl.reqs", "block_flush_requests_total", "Flush requests of the block device.", prometheus.CounterValue, noScaleFactor, "device", "path"),
+		newStat("fl.times", "block_flush_seconds_total", "Time spent flushing the block device.", prometheus.CounterValue, 1/nsPerSecond, "device", "path"),
+		newStat("allocation", "block_allocation_bytes", "Highest allocated offset of the block device.", prometheus.GaugeValue, noScaleFactor, "device", "path"),
+		newStat("capacity", "block_capacity_bytes", "Logical size of the block device.", prometheus.GaugeValue, noScaleFactor, "device", "path"),
+		newStat("physical", "block_physical_bytes", "Physical size of the block device on the host.", prometheus.GaugeValue, noScaleFactor, "device", "path"),
+	}
+)
+
+type collector struct {
+	log log.Logger
+	cfg *Config
+}
+
+func newCollector(l log.Logger, c *Config) *collector {
+	return &collector{log: l, cfg: c}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- upDesc
+	ch <- stateDesc
+	for _, list := range [][]stat{domainStatsList, vcpuStatsList, interfaceStatsList, blockStatsList} {
+		for _, s := range list {
+			ch <- s.desc
+		}
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	domains, err := c.domainStats()
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to collect libvirt domain stats", "err", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
+
+	for _, d := range domains {
+		collectDomain(ch, d)
+	}
+}
+
+func (c *collector) domainStats() ([]domainStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+
+	client, err := dial(ctx, "unix", c.cfg.SocketPath, c.cfg.URI)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	return client.allDomainStats(statsAll)
+}
+
+func collectDomain(ch chan<- prometheus.Metric, d domainStats) {
+	emit := func(s stat, prefix string, labels ...string) {
+		v, ok := d.Values[prefix+s.param]
+		if !ok {
+			return
+		}
+		ch <- prometheus.MustNewConstMetric(s.desc, s.valueType, v*s.scale, append([]string{d.Name}, labels...)...)
+	}
+
+	if state, ok := d.Values["state.state"]; ok {
+		name := "unknown"
+		if i := int(state); i >= 0 && i < len(domainStates) {
+			name = domainStates[i]
+		}
+		ch <- prometheus.MustNewConstMetric(stateDesc, prometheus.GaugeValue, 1, d.Name, name)
+	}
+
+	for _, s := range domainStatsList {
+		emit(s, "")
+	}
+
+	for i := 0; i < int(d.Values["vcpu.maximum"]); i++ {
+		prefix := fmt.Sprintf("vcpu.%d.", i)
+		for _, s := range vcpuStatsList {
+			emit(s, prefix, fmt.Sprint(i))
+		}
+	}
+
+	for i := 0; i < int(d.Values["net.count"]); i++ {
+		prefix := fmt.Sprintf("net.%d.", i)
+		iface := d.Strings[prefix+"name"]
+		for _, s := range interfaceStatsList {
+			emit(s, prefix, iface)
+		}
+	}
+
+	for i := 0; i < int(d.Values["block.count"]); i++ {
+		prefix := fmt.Sprintf("block.%d.", i)
+		device, path := d.Strings[prefix+"name"], d.Strings[prefix+"path"]
+		for _, s := range blockStatsList {
+			emit(s, prefix, device, path)
+		}
+	}
+}
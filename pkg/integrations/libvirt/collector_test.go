@@ -0,0 +1,266 @@
+package libvirt
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// typedParam is a typed parameter sent by the fake libvirtd.
+type typedParam struct {
+	field string
+	typ   int32
+	value interface{}
+}
+
+func (p typedParam) encode(w *xdrWriter) {
+	w.string(p.field)
+	w.int32(p.typ)
+	switch v := p.value.(type) {
+	case int32:
+		w.int32(v)
+	case uint32:
+		w.uint32(v)
+	case uint64:
+		w.uint64(v)
+	case float64:
+		w.uint64(math.Float64bits(v))
+	case string:
+		w.string(v)
+	}
+}
+
+var testDomains = map[string][]typedParam{
+	"web": {
+		{"state.state", typedParamInt, int32(1)},
+		{"state.reason", typedParamInt, int32(1)},
+		{"cpu.time", typedParamULLong, uint64(12500000000)},
+		{"cpu.user", typedParamULLong, uint64(10000000000)},
+		{"cpu.system", typedParamULLong, uint64(2500000000)},
+		{"balloon.current", typedParamULLong, uint64(2097152)},
+		{"balloon.maximum", typedParamULLong, uint64(4194304)},
+		{"balloon.rss", typedParamULLong, uint64(1048576)},
+		{"vcpu.current", typedParamUInt, uint32(2)},
+		{"vcpu.maximum", typedParamUInt, uint32(2)},
+		{"vcpu.0.state", typedParamInt, int32(1)},
+		{"vcpu.0.time", typedParamULLong, uint64(5000000000)},
+		{"vcpu.0.wait", typedParamULLong, uint64(1000000)},
+		{"vcpu.1.state", typedParamInt, int32(1)},
+		{"vcpu.1.time", typedParamULLong, uint64(6000000000)},
+		{"net.count", typedParamUInt, uint32(1)},
+		{"net.0.name", typedParamString, "vnet0"},
+		{"net.0.rx.bytes", typedParamULLong, uint64(1000)},
+		{"net.0.tx.bytes", typedParamULLong, uint64(2000)},
+		{"block.count", typedParamUInt, uint32(1)},
+		{"block.0.name", typedParamString, "vda"},
+		{"block.0.path", typedParamString, "/var/lib/libvirt/images/web.qcow2"},
+		{"block.0.rd.reqs", typedParamULLong, uint64(100)},
+		{"block.0.rd.bytes", typedParamULLong, uint64(409600)},
+		{"block.0.wr.times", typedParamULLong, uint64(1500000000)},
+		{"block.0.capacity", typedParamULLong, uint64(10737418240)},
+	},
+	"db": {
+		{"state.state", typedParamInt, int32(5)},
+		{"state.reason", typedParamInt, int32(1)},
+		{"balloon.maximum", typedParamULLong, uint64(1048576)},
+		{"vcpu.current", typedParamUInt, uint32(1)},
+		{"vcpu.maximum", typedParamUInt, uint32(1)},
+	},
+}
+
+// serveLibvirt serves the calls of the libvirt RPC protocol used by the
+// integration on conn.
+func serveLibvirt(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		var lenBuf [4]byte
+		if _, err := io.ReadFull(conn, lenBuf[:]); err != nil {
+			return
+		}
+		buf := make([]byte, binary.BigEndian.Uint32(lenBuf[:])-4)
+		_, err := io.ReadFull(conn, buf)
+		require.NoError(t, err)
+
+		r := &xdrReader{buf: buf}
+		require.Equal(t, uint32(remoteProgram), r.uint32())
+		require.Equal(t, uint32(remoteProtocolVersion), r.uint32())
+		proc := r.int32()
+		require.Equal(t, int32(messageTypeCall), r.int32())
+		serial := r.uint32()
+		r.int32()
+
+		var (
+			body   xdrWriter
+			status int32 = messageStatusOK
+		)
+		switch proc {
+		case procConnectOpen:
+			uri, _ := r.optionalString()
+			if uri != "qemu:///system" {
+				status = messageStatusError
+				body.int32(38)
+				body.int32(0)
+				body.optionalString("no connection driver available for " + uri)
+			}
+		case procConnectClose:
+		case procConnectGetAllDomainStats:
+			require.Equal(t, uint32(0), r.uint32())
+			require.Equal(t, uint32(statsAll), r.uint32())
+			body.uint32(uint32(len(testDomains)))
+			for name, params := range testDomains {
+				body.string(name)
+				body.Write(make([]byte, 16))
+				body.int32(-1)
+				body.uint32(uint32(len(params)))
+				for _, p := range params {
+					p.encode(&body)
+				}
+			}
+		default:
+			t.Errorf("unexpected procedure %d", proc)
+			return
+		}
+
+		// Send an event first, which must be skipped by the client.
+		var event xdrWriter
+		event.uint32(28)
+		event.uint32(remoteProgram)
+		event.uint32(remoteProtocolVersion)
+		event.int32(procConnectGetAllDomainStats)
+		event.int32(2)
+		event.uint32(0)
+		event.int32(messageStatusOK)
+
+		var reply xdrWriter
+		reply.uint32(uint32(28 + body.Len()))
+		reply.uint32(remoteProgram)
+		reply.uint32(remoteProtocolVersion)
+		reply.int32(proc)
+		reply.int32(messageTypeReply)
+		reply.uint32(serial)
+		reply.int32(status)
+		reply.Write(body.Bytes())
+
+		_, err = conn.Write(append(event.Bytes(), reply.Bytes()...))
+		require.NoError(t, err)
+	}
+}
+
+func newTestSocket(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "libvirt-sock")
+	lis, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	t.Cleanup(func() { lis.Close() })
+
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go serveLibvirt(t, conn)
+		}
+	}()
+	return path
+}
+
+func TestCollector(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.SocketPath = newTestSocket(t)
+	col := newCollector(log.NewNopLogger(), &cfg)
+
+	expect := `
+# HELP libvirt_domain_block_capacity_bytes Logical size of the block device.
+# TYPE libvirt_domain_block_capacity_bytes gauge
+libvirt_domain_block_capacity_bytes{device="vda",domain="web",path="/var/lib/libvirt/images/web.qcow2"} 1.073741824e+10
+# HELP libvirt_domain_block_read_bytes_total Bytes read from the block device.
+# TYPE libvirt_domain_block_read_bytes_total counter
+libvirt_domain_block_read_bytes_total{device="vda",domain="web",path="/var/lib/libvirt/images/web.qcow2"} 409600
+# HELP libvirt_domain_block_read_requests_total Read requests of the block device.
+# TYPE libvirt_domain_block_read_requests_total counter
+libvirt_domain_block_read_requests_total{device="vda",domain="web",path="/var/lib/libvirt/images/web.qcow2"} 100
+# HELP libvirt_domain_block_write_seconds_total Time spent writing to the block device.
+# TYPE libvirt_domain_block_write_seconds_total counter
+libvirt_domain_block_write_seconds_total{device="vda",domain="web",path="/var/lib/libvirt/images/web.qcow2"} 1.5
+# HELP libvirt_domain_cpu_system_seconds_total System CPU time consumed by the domain.
+# TYPE libvirt_domain_cpu_system_seconds_total counter
+libvirt_domain_cpu_system_seconds_total{domain="web"} 2.5
+# HELP libvirt_domain_cpu_time_seconds_total CPU time consumed by the domain.
+# TYPE libvirt_domain_cpu_time_seconds_total counter
+libvirt_domain_cpu_time_seconds_total{domain="web"} 12.5
+# HELP libvirt_domain_cpu_user_seconds_total User CPU time consumed by the domain.
+# TYPE libvirt_domain_cpu_user_seconds_total counter
+libvirt_domain_cpu_user_seconds_total{domain="web"} 10
+# HELP libvirt_domain_interface_receive_bytes_total Bytes received by the network interface.
+# TYPE libvirt_domain_interface_receive_bytes_total counter
+libvirt_domain_interface_receive_bytes_total{domain="web",interface="vnet0"} 1000
+# HELP libvirt_domain_interface_transmit_bytes_total Bytes transmitted by the network interface.
+# TYPE libvirt_domain_interface_transmit_bytes_total counter
+libvirt_domain_interface_transmit_bytes_total{domain="web",interface="vnet0"} 2000
+# HELP libvirt_domain_memory_balloon_current_bytes Current memory of the domain as set by the balloon driver.
+# TYPE libvirt_domain_memory_balloon_current_bytes gauge
+libvirt_domain_memory_balloon_current_bytes{domain="web"} 2.147483648e+09
+# HELP libvirt_domain_memory_balloon_maximum_bytes Maximum memory of the domain.
+# TYPE libvirt_domain_memory_balloon_maximum_bytes gauge
+libvirt_domain_memory_balloon_maximum_bytes{domain="db"} 1.073741824e+09
+libvirt_domain_memory_balloon_maximum_bytes{domain="web"} 4.294967296e+09
+# HELP libvirt_domain_memory_rss_bytes Resident set size of the domain process on the host.
+# TYPE libvirt_domain_memory_rss_bytes gauge
+libvirt_domain_memory_rss_bytes{domain="web"} 1.073741824e+09
+# HELP libvirt_domain_state State of the domain.
+# TYPE libvirt_domain_state gauge
+libvirt_domain_state{domain="db",state="shutoff"} 1
+libvirt_domain_state{domain="web",state="running"} 1
+# HELP libvirt_domain_vcpu_time_seconds_total CPU time consumed by the vCPU.
+# TYPE libvirt_domain_vcpu_time_seconds_total counter
+libvirt_domain_vcpu_time_seconds_total{domain="web",vcpu="0"} 5
+libvirt_domain_vcpu_time_seconds_total{domain="web",vcpu="1"} 6
+# HELP libvirt_domain_vcpu_wait_seconds_total Time the vCPU wanted to run but was not scheduled.
+# TYPE libvirt_domain_vcpu_wait_seconds_total counter
+libvirt_domain_vcpu_wait_seconds_total{domain="web",vcpu="0"} 0.001
+# HELP libvirt_domain_vcpus_current Number of online vCPUs of the domain.
+# TYPE libvirt_domain_vcpus_current gauge
+libvirt_domain_vcpus_current{domain="db"} 1
+libvirt_domain_vcpus_current{domain="web"} 2
+# HELP libvirt_domain_vcpus_maximum Maximum number of vCPUs of the domain.
+# TYPE libvirt_domain_vcpus_maximum gauge
+libvirt_domain_vcpus_maximum{domain="db"} 1
+libvirt_domain_vcpus_maximum{domain="web"} 2
+# HELP libvirt_up Whether libvirt could be reached.
+# TYPE libvirt_up gauge
+libvirt_up 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+}
+
+func TestCollector_Error(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.SocketPath = newTestSocket(t)
+	cfg.URI = "xen:///system"
+	cfg.Timeout = time.Second
+	col := newCollector(log.NewNopLogger(), &cfg)
+
+	client, err := dial(context.Background(), "unix", cfg.SocketPath, cfg.URI)
+	require.Nil(t, client)
+	require.EqualError(t, err, "failed to open connection to xen:///system: libvirt error 38: no connection driver available for xen:///system")
+
+	expect := `
+# HELP libvirt_up Whether libvirt could be reached.
+# TYPE libvirt_up gauge
+libvirt_up 0
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+}
@@ -0,0 +1,66 @@
+// Package libvirt implements an integration which collects vCPU, memory
+// balloon, block device, and network interface statistics of the domains of
+// a libvirt host.
+package libvirt
+
+import (
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+)
+
+// DefaultConfig holds the default settings for the libvirt integration.
+var DefaultConfig = Config{
+	SocketPath: "/var/run/libvirt/libvirt-sock",
+	URI:        "qemu:///system",
+	Timeout:    10 * time.Second,
+}
+
+// Config controls the libvirt integration.
+type Config struct {
+	// SocketPath is the path of the socket of libvirtd.
+	SocketPath string `yaml:"socket_path,omitempty"`
+	// URI of the hypervisor to collect metrics from.
+	URI string `yaml:"uri,omitempty"`
+	// Timeout for collecting metrics.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	return unmarshal((*plain)(c))
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "libvirt"
+}
+
+// InstanceKey returns the hostname:port of the agent process.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	return agentKey, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeSingleton, metricsutils.CreateShim)
+}
+
+// New creates a new libvirt integration.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(newCollector(logger, c)),
+	), nil
+}
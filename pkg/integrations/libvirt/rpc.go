@@ -0,0 +1,335 @@
+package libvirt
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+)
+
+// Constants of the libvirt RPC protocol, as defined in remote_protocol.x and
+// virnetprotocol.x of libvirt.
+const (
+	remoteProgram         = 0x20008086
+	remoteProtocolVersion = 1
+
+	procConnectOpen              = 1
+	procConnectClose             = 2
+	procConnectGetAllDomainStats = 344
+
+	messageTypeCall  = 0
+	messageTypeReply = 1
+
+	messageStatusOK    = 0
+	messageStatusError = 1
+
+	// maxMessageSize is the maximum size of a message accepted by libvirt.
+	maxMessageSize = 32 << 20
+)
+
+// Types of typed parameters.
+const (
+	typedParamInt = iota + 1
+	typedParamUInt
+	typedParamLLong
+	typedParamULLong
+	typedParamDouble
+	typedParamBoolean
+	typedParamString
+)
+
+// libvirtError is an error returned by libvirt.
+type libvirtError struct {
+	Code    int32
+	Message string
+}
+
+func (e *libvirtError) Error() string {
+	return fmt.Sprintf("libvirt error %d: %s", e.Code, e.Message)
+}
+
+// domainStats holds the statistics of a domain returned by
+// virConnectGetAllDomainStats.
+type domainStats struct {
+	Name string
+	// Values holds all numeric parameters.
+	Values map[string]float64
+	// Strings holds all string parameters, e.g., names of devices.
+	Strings map[string]string
+}
+
+// rpcClient is a minimal client of the libvirt RPC protocol, which only
+// implements the calls needed to collect domain statistics.
+type rpcClient struct {
+	conn   net.Conn
+	serial uint32
+}
+
+// dial connects to libvirtd and opens a connection to the hypervisor
+// identified by uri.
+func dial(ctx context.Context, network, address, uri string) (*rpcClient, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c := &rpcClient{conn: conn}
+
+	var args xdrWriter
+	args.optionalString(uri)
+	args.uint32(0) // flags
+	if _, err := c.call(procConnectOpen, args.Bytes()); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open connection to %s: %w", uri, err)
+	}
+	return c, nil
+}
+
+// Close closes the connection to the hypervisor and libvirtd.
+func (c *rpcClient) Close() error {
+	_, err := c.call(procConnectClose, nil)
+	if cerr := c.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// allDomainStats returns the statistics of all domains. stats is a bitmask
+// of the virDomainStatsTypes to return.
+func (c *rpcClient) allDomainStats(stats uint32) ([]domainStats, error) {
+	var args xdrWriter
+	args.uint32(0) // No domains, to return statistics of all domains.
+	args.uint32(stats)
+	args.uint32(0) // flags
+
+	body, err := c.call(procConnectGetAllDomainStats, args.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	r := &xdrReader{buf: body}
+	n := r.uint32()
+	if r.err == nil && int(n) > len(body) {
+		return nil, fmt.Errorf("invalid number of domains %d", n)
+	}
+
+	res := make([]domainStats, 0, n)
+	for i := uint32(0); i < n && r.err == nil; i++ {
+		d := domainStats{
+			Name:    r.string(),
+			Values:  map[string]float64{},
+			Strings: map[string]string{},
+		}
+		r.opaque(16) // UUID
+		r.int32()    // ID
+
+		params := r.uint32()
+		for j := uint32(0); j < params && r.err == nil; j++ {
+			field := r.string()
+			switch typ := r.int32(); typ {
+			case typedParamInt, typedParamBoolean:
+				d.Values[field] = float64(r.int32())
+			case typedParamUInt:
+				d.Values[field] = float64(r.uint32())
+			case typedParamLLong:
+				d.Values[field] = float64(r.int64())
+			case typedParamULLong:
+				d.Values[field] = float64(r.uint64())
+			case typedParamDouble:
+				d.Values[field] = r.float64()
+			case typedParamString:
+				d.Strings[field] = r.string()
+			default:
+				return nil, fmt.Errorf("unknown type %d of typed parameter %s", typ, field)
+			}
+		}
+		res = append(res, d)
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to decode domain stats: %w", r.err)
+	}
+	return res, nil
+}
+
+// call performs a remote procedure call and returns the body of the reply.
+func (c *rpcClient) call(proc int32, args []byte) ([]byte, error) {
+	c.serial++
+	serial := c.serial
+
+	var msg xdrWriter
+	msg.uint32(uint32(4 + 24 + len(args)))
+	msg.uint32(remoteProgram)
+	msg.uint32(remoteProtocolVersion)
+	msg.int32(proc)
+	msg.int32(messageTypeCall)
+	msg.uint32(serial)
+	msg.int32(messageStatusOK)
+	msg.Write(args)
+	if _, err := c.conn.Write(msg.Bytes()); err != nil {
+		return nil, err
+	}
+
+	for {
+		var lenBuf [4]byte
+		if _, err := io.ReadFull(c.conn, lenBuf[:]); err != nil {
+			return nil, err
+		}
+		size := binary.BigEndian.Uint32(lenBuf[:])
+		if size < 28 || size > maxMessageSize {
+			return nil, fmt.Errorf("invalid message size %d", size)
+		}
+		buf := make([]byte, size-4)
+		if _, err := io.ReadFull(c.conn, buf); err != nil {
+			return nil, err
+		}
+
+		r := &xdrReader{buf: buf}
+		var (
+			prog      = r.uint32()
+			_         = r.uint32() // version
+			replyProc = r.int32()
+			typ       = r.int32()
+			replySer  = r.uint32()
+			status    = r.int32()
+		)
+		// Skip events and other messages which are not the reply to this
+		// call.
+		if prog != remoteProgram || typ != messageTypeReply || replyProc != proc || replySer != serial {
+			continue
+		}
+
+		body := buf[24:]
+		if status == messageStatusError {
+			return nil, decodeError(body)
+		}
+		return body, nil
+	}
+}
+
+// decodeError decodes a remote_error.
+func decodeError(body []byte) error {
+	r := &xdrReader{buf: body}
+	e := &libvirtError{Code: r.int32()}
+	r.int32() // domain
+	if msg, ok := r.optionalString(); ok {
+		e.Message = msg
+	}
+	if r.err != nil {
+		return fmt.Errorf("failed to decode libvirt error: %w", r.err)
+	}
+	return e
+}
+
+// xdrWriter encodes values using XDR (RFC 4506).
+type xdrWriter struct {
+	bytes.Buffer
+}
+
+func (w *xdrWriter) uint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	w.Write(b[:])
+}
+
+func (w *xdrWriter) int32(v int32) { w.uint32(uint32(v)) }
+
+func (w *xdrWriter) uint64(v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	w.Write(b[:])
+}
+
+func (w *xdrWriter) string(s string) {
+	w.uint32(uint32(len(s)))
+	w.WriteString(s)
+	w.Write(make([]byte, padding(len(s))))
+}
+
+// optionalString encodes s as an optional string, which is absent if s is
+// empty.
+func (w *xdrWriter) optionalString(s string) {
+	if s == "" {
+		w.uint32(0)
+		return
+	}
+	w.uint32(1)
+	w.string(s)
+}
+
+// xdrReader decodes values encoded using XDR. Errors are sticky, so that
+// only the error of the last read needs to be checked.
+type xdrReader struct {
+	buf []byte
+	err error
+}
+
+var errShortBuffer = errors.New("unexpected end of message")
+
+func (r *xdrReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.buf) {
+		r.err = errShortBuffer
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *xdrReader) uint32() uint32 {
+	b := r.next(4)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint32(b)
+}
+
+func (r *xdrReader) int32() int32 { return int32(r.uint32()) }
+
+func (r *xdrReader) uint64() uint64 {
+	b := r.next(8)
+	if b == nil {
+		return 0
+	}
+	return binary.BigEndian.Uint64(b)
+}
+
+func (r *xdrReader) int64() int64 { return int64(r.uint64()) }
+
+func (r *xdrReader) float64() float64 { return math.Float64frombits(r.uint64()) }
+
+func (r *xdrReader) opaque(n int) []byte {
+	return r.next(n + padding(n))
+}
+
+func (r *xdrReader) string() string {
+	n := int(r.uint32())
+	b := r.opaque(n)
+	if b == nil {
+		return ""
+	}
+	return string(b[:n])
+}
+
+func (r *xdrReader) optionalString() (string, bool) {
+	if r.uint32() == 0 {
+		return "", false
+	}
+	return r.string(), r.err == nil
+}
+
+// padding returns the number of bytes needed to pad n bytes to a multiple
+// of four.
+func padding(n int) int {
+	return (4 - n%4) % 4
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/gorilla/mux"
+	"github.com/grafana/agent/pkg/integrations/config"
 	"github.com/grafana/agent/pkg/metrics"
 	"github.com/grafana/agent/pkg/metrics/instance"
 	"github.com/grafana/agent/pkg/metrics/instance/configstore"
@@ -61,6 +62,9 @@ type ManagerConfig struct {
 
 	IntegrationRestartBackoff time.Duration `yaml:"integration_restart_backoff,omitempty"`
 
+	// Flags holds the settings of integrations from command line flags.
+	Flags config.Flags `yaml:"-"`
+
 	// ListenPort tells the integration Manager which port the Agent is
 	// listening on for generating Prometheus instance configs.
 	ListenPort int `yaml:"-"`
@@ -146,6 +150,36 @@ func (c *ManagerConfig) ApplyDefaults(scfg *server.Config, mcfg *metrics.Config)
 	return nil
 }
 
+// WithInstanceKeys returns a copy of c where the instance key of every
+// enabled integration is set to the instance label the Manager uses for it
+// when running on the host hostname. Integrations whose instance key can't
+// be determined are left unchanged.
+func (c ManagerConfig) WithInstanceKeys(hostname string) ManagerConfig {
+	agentKey := fmt.Sprintf("%s:%d", hostname, c.ListenPort)
+
+	integrations := make(Configs, 0, len(c.Integrations))
+	for _, ic := range c.Integrations {
+		if ic.Common.Enabled {
+			if key, err := integrationInstanceKey(ic, agentKey); err == nil {
+				ic.Common.InstanceKey = &key
+			}
+		}
+		integrations = append(integrations, ic)
+	}
+	c.Integrations = integrations
+	return c
+}
+
+// integrationInstanceKey returns the instance label used for the integration
+// ic. agentKey is the host:port of the Agent.
+func integrationInstanceKey(ic UnmarshaledConfig, agentKey string) (string, error) {
+	if kp := ic.Common.InstanceKey; kp != nil {
+		// Common config takes precedence.
+		return strings.TrimSpace(*kp), nil
+	}
+	return ic.InstanceKey(agentKey)
+}
+
 // Manager manages a set of integrations and runs them.
 type Manager struct {
 	logger log.Logger
@@ -243,7 +277,7 @@ func (m *Manager) ApplyConfig(cfg ManagerConfig) error {
 		}
 
 		l := log.With(m.logger, "integration", ic.Name())
-		i, err := ic.NewIntegration(l)
+		i, err := NewIntegration(ic.Config, l, cfg.Flags)
 		if err != nil {
 			level.Error(m.logger).Log("msg", "failed to initialize integration. it will not run or be scraped", "integration", ic.Name(), "err", err)
 			failed = true
@@ -255,21 +289,15 @@ func (m *Manager) ApplyConfig(cfg ManagerConfig) error {
 		}
 
 		// Find what instance label should be used to represent this integration.
-		var instanceKey string
-		if kp := ic.Common.InstanceKey; kp != nil {
-			// Common config takes precedence.
-			instanceKey = strings.TrimSpace(*kp)
-		} else {
-			instanceKey, err = ic.InstanceKey(fmt.Sprintf("%s:%d", m.hostname, cfg.ListenPort))
-			if err != nil {
-				level.Error(m.logger).Log("msg", "failed to get instance key for integration. it will not run or be scraped", "integration", ic.Name(), "err", err)
-				failed = true
+		instanceKey, err := integrationInstanceKey(ic, fmt.Sprintf("%s:%d", m.hostname, cfg.ListenPort))
+		if err != nil {
+			level.Error(m.logger).Log("msg", "failed to get instance key for integration. it will not run or be scraped", "integration", ic.Name(), "err", err)
+			failed = true
 
-				// If this integration was running before, its instance won't be cleaned
-				// up since it's now removed from the map. We need to clean it up here.
-				_ = m.im.DeleteConfig(key)
-				continue
-			}
+			// If this integration was running before, its instance won't be cleaned
+			// up since it's now removed from the map. We need to clean it up here.
+			_ = m.im.DeleteConfig(key)
+			continue
 		}
 
 		// Create, start, and register the new integration.
@@ -414,6 +442,7 @@ func (m *Manager) instanceConfigForIntegration(p *integrationProcess, cfg Manage
 		sc := &promConfig.ScrapeConfig{
 			JobName:                 fmt.Sprintf("integrations/%s", isc.JobName),
 			MetricsPath:             path.Join("/integrations", p.cfg.Name(), isc.MetricsPath),
+			Params:                  isc.QueryParams,
 			Scheme:                  schema,
 			HonorLabels:             false,
 			HonorTimestamps:         true,
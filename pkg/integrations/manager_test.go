@@ -346,6 +346,26 @@ func TestManager_PromConfigChangeReloads(t *testing.T) {
 	}
 }
 
+func TestManagerConfig_WithInstanceKeys(t *testing.T) {
+	custom := " custom-instance "
+
+	cfg := mockManagerConfig()
+	cfg.ListenPort = 12345
+	cfg.Integrations = Configs{
+		makeUnmarshaledConfig(mockConfig{}, true),
+		makeUnmarshaledConfig(mockConfig{}, false),
+		{Config: mockConfig{}, Common: config.Common{Enabled: true, InstanceKey: &custom}},
+	}
+
+	resolved := cfg.WithInstanceKeys("agent-host")
+	require.Equal(t, "agent-host:12345", *resolved.Integrations[0].Common.InstanceKey)
+	require.Nil(t, resolved.Integrations[1].Common.InstanceKey)
+	require.Equal(t, "custom-instance", *resolved.Integrations[2].Common.InstanceKey)
+
+	// The original config must not be modified.
+	require.Nil(t, cfg.Integrations[0].Common.InstanceKey)
+}
+
 func generateMockConfigWithEnabledFlag(enabled bool) ManagerConfig {
 	enabledMock := newMockIntegration()
 	enabledConfig := mockConfig{Integration: enabledMock}
@@ -0,0 +1,375 @@
+package mssql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "mssql"
+
+// Scraper collects a set of metrics from SQL Server.
+type Scraper interface {
+	// Name of the scraper, used in enable_collectors and disable_collectors.
+	Name() string
+	// EnabledByDefault returns whether the scraper runs when not explicitly
+	// enabled.
+	EnabledByDefault() bool
+	// Scrape collects metrics from db and sends them to ch.
+	Scrape(ctx context.Context, db *sql.DB, ch chan<- prometheus.Metric) error
+}
+
+var allScrapers = []Scraper{
+	scrapeConnections{},
+	scrapeDatabaseState{},
+	scrapeIOStall{},
+	scrapePerfCounters{},
+	scrapeAvailabilityGroups{},
+}
+
+// collector runs every enabled Scraper on each scrape.
+type collector struct {
+	log      log.Logger
+	db       *sql.DB
+	scrapers []Scraper
+	timeout  time.Duration
+
+	up                *prometheus.Desc
+	collectorSuccess  *prometheus.Desc
+	collectorDuration *prometheus.Desc
+}
+
+func newCollector(l log.Logger, db *sql.DB, scrapers []Scraper, timeout time.Duration) *collector {
+	return &collector{
+		log:      l,
+		db:       db,
+		scrapers: scrapers,
+		timeout:  timeout,
+
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "up"),
+			"Whether the SQL Server could be reached.",
+			nil, nil,
+		),
+		collectorSuccess: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "collector_success"),
+			"Whether a collector succeeded.",
+			[]string{"collector"}, nil,
+		),
+		collectorDuration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "collector_duration_seconds"),
+			"Collector time duration.",
+			[]string{"collector"}, nil,
+		),
+	}
+}
+
+// Describe implements prometheus.Collector. Scrapers produce dynamic
+// descriptors, so the collector is unchecked.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	if err := c.db.PingContext(ctx); err != nil {
+		level.Error(c.log).Log("msg", "failed to ping sql server", "err", err)
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+
+	var wg sync.WaitGroup
+	for _, s := range c.scrapers {
+		wg.Add(1)
+		go func(s Scraper) {
+			defer wg.Done()
+
+			start := time.Now()
+			success := 1.0
+			if err := s.Scrape(ctx, c.db, ch); err != nil {
+				level.Error(c.log).Log("msg", "collector failed", "collector", s.Name(), "err", err)
+				success = 0
+			}
+			ch <- prometheus.MustNewConstMetric(c.collectorDuration, prometheus.GaugeValue, time.Since(start).Seconds(), s.Name())
+			ch <- prometheus.MustNewConstMetric(c.collectorSuccess, prometheus.GaugeValue, success, s.Name())
+		}(s)
+	}
+	wg.Wait()
+}
+
+//
+// connections
+//
+
+var connectionsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "connections"),
+	"Number of active connections per database.",
+	[]string{"database"}, nil,
+)
+
+type scrapeConnections struct{}
+
+func (scrapeConnections) Name() string           { return "connections" }
+func (scrapeConnections) EnabledByDefault() bool { return true }
+
+func (scrapeConnections) Scrape(ctx context.Context, db *sql.DB, ch chan<- prometheus.Metric) error {
+	const query = `SELECT DB_NAME(sp.dbid), COUNT(sp.spid) FROM sys.sysprocesses sp WHERE sp.dbid > 0 GROUP BY sp.dbid`
+	return queryRows(ctx, db, query, func(scan func(...interface{}) error) error {
+		var (
+			database string
+			count    float64
+		)
+		if err := scan(&database, &count); err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(connectionsDesc, prometheus.GaugeValue, count, database)
+		return nil
+	})
+}
+
+//
+// database_state
+//
+
+var databaseStateDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "database_state"),
+	"State of each database (0=online 1=restoring 2=recovering 3=recovery pending 4=suspect 5=emergency 6=offline).",
+	[]string{"database"}, nil,
+)
+
+type scrapeDatabaseState struct{}
+
+func (scrapeDatabaseState) Name() string           { return "database_state" }
+func (scrapeDatabaseState) EnabledByDefault() bool { return true }
+
+func (scrapeDatabaseState) Scrape(ctx context.Context, db *sql.DB, ch chan<- prometheus.Metric) error {
+	const query = `SELECT name, state FROM sys.databases`
+	return queryRows(ctx, db, query, func(scan func(...interface{}) error) error {
+		var (
+			database string
+			state    float64
+		)
+		if err := scan(&database, &state); err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(databaseStateDesc, prometheus.GaugeValue, state, database)
+		return nil
+	})
+}
+
+//
+// io_stall
+//
+
+var (
+	ioStallReadDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "io", "stall_read_seconds_total"),
+		"Total time users waited for reads issued on database files.",
+		[]string{"database"}, nil,
+	)
+	ioStallWriteDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "io", "stall_write_seconds_total"),
+		"Total time users waited for writes issued on database files.",
+		[]string{"database"}, nil,
+	)
+	ioReadsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "io", "reads_total"),
+		"Number of reads issued on database files.",
+		[]string{"database"}, nil,
+	)
+	ioWritesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "io", "writes_total"),
+		"Number of writes issued on database files.",
+		[]string{"database"}, nil,
+	)
+)
+
+type scrapeIOStall struct{}
+
+func (scrapeIOStall) Name() string           { return "io_stall" }
+func (scrapeIOStall) EnabledByDefault() bool { return true }
+
+func (scrapeIOStall) Scrape(ctx context.Context, db *sql.DB, ch chan<- prometheus.Metric) error {
+	const query = `SELECT DB_NAME(database_id), SUM(io_stall_read_ms), SUM(io_stall_write_ms), SUM(num_of_reads), SUM(num_of_writes)
+FROM sys.dm_io_virtual_file_stats(NULL, NULL) GROUP BY database_id`
+	return queryRows(ctx, db, query, func(scan func(...interface{}) error) error {
+		var (
+			database                                 string
+			stallReadMs, stallWriteMs, reads, writes float64
+		)
+		if err := scan(&database, &stallReadMs, &stallWriteMs, &reads, &writes); err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(ioStallReadDesc, prometheus.CounterValue, stallReadMs/1000, database)
+		ch <- prometheus.MustNewConstMetric(ioStallWriteDesc, prometheus.CounterValue, stallWriteMs/1000, database)
+		ch <- prometheus.MustNewConstMetric(ioReadsDesc, prometheus.CounterValue, reads, database)
+		ch <- prometheus.MustNewConstMetric(ioWritesDesc, prometheus.CounterValue, writes, database)
+		return nil
+	})
+}
+
+//
+// perf_counters
+//
+
+var perfCounterDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "os_performance_counter"),
+	"Raw value of a counter from sys.dm_os_performance_counters. Per-second counters are cumulative.",
+	[]string{"object", "counter", "instance"}, nil,
+)
+
+// perfCounterNames is the set of counters collected from
+// sys.dm_os_performance_counters.
+var perfCounterNames = []string{
+	"Batch Requests/sec",
+	"SQL Compilations/sec",
+	"SQL Re-Compilations/sec",
+	"User Connections",
+	"Processes blocked",
+	"Page life expectancy",
+	"Buffer cache hit ratio",
+	"Page reads/sec",
+	"Page writes/sec",
+	"Lock Waits/sec",
+	"Number of Deadlocks/sec",
+	"Transactions/sec",
+	"Log Flushes/sec",
+	"Memory Grants Pending",
+	"Target Server Memory (KB)",
+	"Total Server Memory (KB)",
+}
+
+type scrapePerfCounters struct{}
+
+func (scrapePerfCounters) Name() string           { return "perf_counters" }
+func (scrapePerfCounters) EnabledByDefault() bool { return true }
+
+func (scrapePerfCounters) Scrape(ctx context.Context, db *sql.DB, ch chan<- prometheus.Metric) error {
+	quoted := make([]string, 0, len(perfCounterNames))
+	for _, n := range perfCounterNames {
+		quoted = append(quoted, "'"+n+"'")
+	}
+	query := `SELECT RTRIM(object_name), RTRIM(counter_name), RTRIM(instance_name), cntr_value
+FROM sys.dm_os_performance_counters WHERE counter_name IN (` + strings.Join(quoted, ", ") + `)`
+
+	return queryRows(ctx, db, query, func(scan func(...interface{}) error) error {
+		var (
+			object, counter, instance string
+			value                     float64
+		)
+		if err := scan(&object, &counter, &instance, &value); err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(perfCounterDesc, prometheus.GaugeValue, value, object, counter, instance)
+		return nil
+	})
+}
+
+//
+// availability_groups
+//
+
+var (
+	agReplicaRoleDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "availability_replica", "role"),
+		"Current role of an availability replica (0=resolving 1=primary 2=secondary).",
+		[]string{"group", "replica"}, nil,
+	)
+	agReplicaHealthDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "availability_replica", "synchronization_health"),
+		"Synchronization health of an availability replica (0=not healthy 1=partially healthy 2=healthy).",
+		[]string{"group", "replica"}, nil,
+	)
+	agReplicaConnectedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "availability_replica", "connected"),
+		"Whether a secondary replica is connected to the primary replica.",
+		[]string{"group", "replica"}, nil,
+	)
+	agDatabaseStateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "availability_database", "synchronization_state"),
+		"Synchronization state of a local availability database (0=not synchronizing 1=synchronizing 2=synchronized 3=reverting 4=initializing).",
+		[]string{"group", "database"}, nil,
+	)
+	agLogSendQueueDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "availability_database", "log_send_queue_bytes"),
+		"Amount of log records of the primary database that have not been sent to the secondary databases.",
+		[]string{"group", "database"}, nil,
+	)
+	agRedoQueueDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "availability_database", "redo_queue_bytes"),
+		"Amount of log records in the log files of the secondary replica that have not yet been redone.",
+		[]string{"group", "database"}, nil,
+	)
+)
+
+type scrapeAvailabilityGroups struct{}
+
+func (scrapeAvailabilityGroups) Name() string           { return "availability_groups" }
+func (scrapeAvailabilityGroups) EnabledByDefault() bool { return false }
+
+func (scrapeAvailabilityGroups) Scrape(ctx context.Context, db *sql.DB, ch chan<- prometheus.Metric) error {
+	const replicasQuery = `SELECT ag.name, ar.replica_server_name, ISNULL(ars.role, 0), ISNULL(ars.synchronization_health, 0), ISNULL(ars.connected_state, 0)
+FROM sys.dm_hadr_availability_replica_states ars
+JOIN sys.availability_replicas ar ON ars.replica_id = ar.replica_id
+JOIN sys.availability_groups ag ON ars.group_id = ag.group_id`
+
+	err := queryRows(ctx, db, replicasQuery, func(scan func(...interface{}) error) error {
+		var (
+			group, replica          string
+			role, health, connected float64
+		)
+		if err := scan(&group, &replica, &role, &health, &connected); err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(agReplicaRoleDesc, prometheus.GaugeValue, role, group, replica)
+		ch <- prometheus.MustNewConstMetric(agReplicaHealthDesc, prometheus.GaugeValue, health, group, replica)
+		ch <- prometheus.MustNewConstMetric(agReplicaConnectedDesc, prometheus.GaugeValue, connected, group, replica)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	const databasesQuery = `SELECT ag.name, DB_NAME(drs.database_id), drs.synchronization_state, ISNULL(drs.log_send_queue_size, 0), ISNULL(drs.redo_queue_size, 0)
+FROM sys.dm_hadr_database_replica_states drs
+JOIN sys.availability_groups ag ON drs.group_id = ag.group_id
+WHERE drs.is_local = 1`
+
+	return queryRows(ctx, db, databasesQuery, func(scan func(...interface{}) error) error {
+		var (
+			group, database                 string
+			state, sendQueueKB, redoQueueKB float64
+		)
+		if err := scan(&group, &database, &state, &sendQueueKB, &redoQueueKB); err != nil {
+			return err
+		}
+		ch <- prometheus.MustNewConstMetric(agDatabaseStateDesc, prometheus.GaugeValue, state, group, database)
+		ch <- prometheus.MustNewConstMetric(agLogSendQueueDesc, prometheus.GaugeValue, sendQueueKB*1024, group, database)
+		ch <- prometheus.MustNewConstMetric(agRedoQueueDesc, prometheus.GaugeValue, redoQueueKB*1024, group, database)
+		return nil
+	})
+}
+
+// queryRows runs query and invokes fn for each returned row.
+func queryRows(ctx context.Context, db *sql.DB, query string, fn func(scan func(...interface{}) error) error) error {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := fn(rows.Scan); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
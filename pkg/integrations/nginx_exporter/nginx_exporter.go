@@ -0,0 +1,107 @@
+// Package nginx_exporter implements an integration which collects metrics
+// from nginx's stub_status module or the NGINX Plus API, modeled after
+// https://github.com/nginxinc/nginx-prometheus-exporter.
+package nginx_exporter //nolint:golint
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	"github.com/prometheus/client_golang/prometheus"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig holds the default settings for the nginx_exporter integration.
+var DefaultConfig = Config{
+	ScrapeURI:        "http://127.0.0.1:8080/stub_status",
+	Timeout:          5 * time.Second,
+	PlusAPIVersion:   6,
+	HTTPClientConfig: config_util.DefaultHTTPClientConfig,
+}
+
+// Config controls the nginx_exporter integration.
+type Config struct {
+	// ScrapeURI is the URI of the stub_status page, or the base URI of the
+	// NGINX Plus API when NginxPlus is true.
+	ScrapeURI string `yaml:"scrape_uri,omitempty"`
+	// NginxPlus enables collecting metrics from the NGINX Plus API instead of
+	// stub_status.
+	NginxPlus bool `yaml:"nginx_plus,omitempty"`
+	// PlusAPIVersion is the version of the NGINX Plus API to use.
+	PlusAPIVersion int `yaml:"nginx_plus_api_version,omitempty"`
+	// Timeout for requests to nginx.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// HTTPClientConfig configures TLS and authentication used for reaching
+	// the status endpoint.
+	HTTPClientConfig config_util.HTTPClientConfig `yaml:",inline"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	return c.HTTPClientConfig.Validate()
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "nginx_exporter"
+}
+
+// InstanceKey returns the host:port of the nginx server being scraped.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	u, err := url.Parse(c.ScrapeURI)
+	if err != nil {
+		return "", fmt.Errorf("could not parse url: %w", err)
+	}
+	return u.Host, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
+
+// New creates a new nginx_exporter integration.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	u, err := url.Parse(c.ScrapeURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse scrape_uri: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("scrape_uri must use http or https, got %q", c.ScrapeURI)
+	}
+
+	client, err := config_util.NewClientFromConfig(c.HTTPClientConfig, c.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create http client: %w", err)
+	}
+	client.Timeout = c.Timeout
+
+	var col prometheus.Collector
+	if c.NginxPlus {
+		col = newPlusCollector(logger, client, c.ScrapeURI, c.PlusAPIVersion)
+	} else {
+		col = newStubStatusCollector(logger, client, c.ScrapeURI)
+	}
+
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(col),
+	), nil
+}
@@ -0,0 +1,97 @@
+package nginx_exporter //nolint:golint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const stubStatusPage = `Active connections: 291
+server accepts handled requests
+ 16630948 16630948 31070465
+Reading: 6 Writing: 179 Waiting: 106
+`
+
+func TestStubStatusCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(stubStatusPage))
+	}))
+	defer srv.Close()
+
+	c := newStubStatusCollector(log.NewNopLogger(), srv.Client(), srv.URL)
+
+	expect := `
+# HELP nginx_connections_active Active client connections.
+# TYPE nginx_connections_active gauge
+nginx_connections_active 291
+# HELP nginx_connections_waiting Idle client connections.
+# TYPE nginx_connections_waiting gauge
+nginx_connections_waiting 106
+# HELP nginx_http_requests_total Total http requests.
+# TYPE nginx_http_requests_total counter
+nginx_http_requests_total 3.1070465e+07
+# HELP nginx_up Status of the last metric scrape.
+# TYPE nginx_up gauge
+nginx_up 1
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"nginx_up", "nginx_connections_active", "nginx_connections_waiting", "nginx_http_requests_total")
+	require.NoError(t, err)
+}
+
+func TestParseStubStatus_Invalid(t *testing.T) {
+	_, err := parseStubStatus(strings.NewReader("<html>not found</html>"))
+	require.Error(t, err)
+}
+
+func TestPlusCollector(t *testing.T) {
+	responses := map[string]string{
+		"/api/6/connections":       `{"accepted":4968119,"dropped":0,"active":5,"idle":117}`,
+		"/api/6/http/requests":     `{"total":10624511,"current":4}`,
+		"/api/6/ssl":               `{"handshakes":79572,"handshakes_failed":21025,"session_reuses":15762}`,
+		"/api/6/http/server_zones": `{"hg.nginx.org":{"processing":0,"requests":175276,"responses":{"1xx":0,"2xx":162948,"3xx":10117,"4xx":1374,"5xx":836,"total":175275},"discarded":1,"received":47217410,"sent":5668662093}}`,
+		"/api/6/http/upstreams":    `{"trac-backend":{"peers":[{"id":0,"server":"10.0.0.1:8080","state":"up","active":1,"requests":1234,"responses":{"1xx":0,"2xx":1200,"3xx":0,"4xx":30,"5xx":4,"total":1234},"sent":100,"received":200,"fails":2,"unavail":0}],"keepalive":3,"zombies":0}}`,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		resp, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(resp))
+	}))
+	defer srv.Close()
+
+	c := newPlusCollector(log.NewNopLogger(), srv.Client(), srv.URL+"/api/", 6)
+
+	expect := `
+# HELP nginxplus_connections_active Active client connections.
+# TYPE nginxplus_connections_active gauge
+nginxplus_connections_active 5
+# HELP nginxplus_server_zone_requests Total client requests.
+# TYPE nginxplus_server_zone_requests counter
+nginxplus_server_zone_requests{server_zone="hg.nginx.org"} 175276
+# HELP nginxplus_up Status of the last metric scrape.
+# TYPE nginxplus_up gauge
+nginxplus_up 1
+# HELP nginxplus_upstream_server_responses Total responses sent to clients.
+# TYPE nginxplus_upstream_server_responses counter
+nginxplus_upstream_server_responses{code="1xx",server="10.0.0.1:8080",upstream="trac-backend"} 0
+nginxplus_upstream_server_responses{code="2xx",server="10.0.0.1:8080",upstream="trac-backend"} 1200
+nginxplus_upstream_server_responses{code="3xx",server="10.0.0.1:8080",upstream="trac-backend"} 0
+nginxplus_upstream_server_responses{code="4xx",server="10.0.0.1:8080",upstream="trac-backend"} 30
+nginxplus_upstream_server_responses{code="5xx",server="10.0.0.1:8080",upstream="trac-backend"} 4
+# HELP nginxplus_upstream_server_state Current state (1=up, 2=draining, 3=down, 4=unavail, 5=checking, 6=unhealthy).
+# TYPE nginxplus_upstream_server_state gauge
+nginxplus_upstream_server_state{server="10.0.0.1:8080",upstream="trac-backend"} 1
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"nginxplus_up", "nginxplus_connections_active", "nginxplus_server_zone_requests",
+		"nginxplus_upstream_server_responses", "nginxplus_upstream_server_state")
+	require.NoError(t, err)
+}
@@ -0,0 +1,263 @@
+package nginx_exporter //nolint:golint
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const plusNamespace = "nginxplus"
+
+func plusDesc(subsystem, name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(plusNamespace, subsystem, name), help, labels, nil)
+}
+
+var (
+	plusUpDesc = plusDesc("", "up", "Status of the last metric scrape.")
+
+	plusConnectionsAcceptedDesc = plusDesc("connections", "accepted", "Accepted client connections.")
+	plusConnectionsDroppedDesc  = plusDesc("connections", "dropped", "Dropped client connections.")
+	plusConnectionsActiveDesc   = plusDesc("connections", "active", "Active client connections.")
+	plusConnectionsIdleDesc     = plusDesc("connections", "idle", "Idle client connections.")
+
+	plusHTTPRequestsTotalDesc   = plusDesc("http", "requests_total", "Total http requests.")
+	plusHTTPRequestsCurrentDesc = plusDesc("http", "requests_current", "Current http requests.")
+
+	plusSSLHandshakesDesc       = plusDesc("ssl", "handshakes", "Successful SSL handshakes.")
+	plusSSLHandshakesFailedDesc = plusDesc("ssl", "handshakes_failed", "Failed SSL handshakes.")
+	plusSSLSessionReusesDesc    = plusDesc("ssl", "session_reuses", "Session reuses during SSL handshake.")
+
+	plusServerZoneProcessingDesc = plusDesc("server_zone", "processing", "Client requests that are currently being processed.", "server_zone")
+	plusServerZoneRequestsDesc   = plusDesc("server_zone", "requests", "Total client requests.", "server_zone")
+	plusServerZoneResponsesDesc  = plusDesc("server_zone", "responses", "Total responses sent to clients.", "server_zone", "code")
+	plusServerZoneDiscardedDesc  = plusDesc("server_zone", "discarded", "Requests completed without sending a response.", "server_zone")
+	plusServerZoneReceivedDesc   = plusDesc("server_zone", "received", "Bytes received from clients.", "server_zone")
+	plusServerZoneSentDesc       = plusDesc("server_zone", "sent", "Bytes sent to clients.", "server_zone")
+
+	plusUpstreamServerStateDesc     = plusDesc("upstream_server", "state", "Current state (1=up, 2=draining, 3=down, 4=unavail, 5=checking, 6=unhealthy).", "upstream", "server")
+	plusUpstreamServerActiveDesc    = plusDesc("upstream_server", "active", "Active connections.", "upstream", "server")
+	plusUpstreamServerRequestsDesc  = plusDesc("upstream_server", "requests", "Total client requests.", "upstream", "server")
+	plusUpstreamServerResponsesDesc = plusDesc("upstream_server", "responses", "Total responses sent to clients.", "upstream", "server", "code")
+	plusUpstreamServerSentDesc      = plusDesc("upstream_server", "sent", "Bytes sent to this server.", "upstream", "server")
+	plusUpstreamServerReceivedDesc  = plusDesc("upstream_server", "received", "Bytes received from this server.", "upstream", "server")
+	plusUpstreamServerFailsDesc     = plusDesc("upstream_server", "fails", "Total number of unsuccessful attempts to communicate with the server.", "upstream", "server")
+	plusUpstreamServerUnavailDesc   = plusDesc("upstream_server", "unavail", "How many times the server became unavailable for client requests.", "upstream", "server")
+	plusUpstreamKeepalivesDesc      = plusDesc("upstream", "keepalives", "Idle keepalive connections.", "upstream")
+	plusUpstreamZombiesDesc         = plusDesc("upstream", "zombies", "Servers removed from the group but still processing active client requests.", "upstream")
+)
+
+var upstreamServerStates = map[string]float64{
+	"up":        1,
+	"draining":  2,
+	"down":      3,
+	"unavail":   4,
+	"checking":  5,
+	"unhealthy": 6,
+}
+
+type plusResponses struct {
+	Responses1xx float64 `json:"1xx"`
+	Responses2xx float64 `json:"2xx"`
+	Responses3xx float64 `json:"3xx"`
+	Responses4xx float64 `json:"4xx"`
+	Responses5xx float64 `json:"5xx"`
+}
+
+func (r plusResponses) byCode() map[string]float64 {
+	return map[string]float64{
+		"1xx": r.Responses1xx,
+		"2xx": r.Responses2xx,
+		"3xx": r.Responses3xx,
+		"4xx": r.Responses4xx,
+		"5xx": r.Responses5xx,
+	}
+}
+
+type plusConnections struct {
+	Accepted float64 `json:"accepted"`
+	Dropped  float64 `json:"dropped"`
+	Active   float64 `json:"active"`
+	Idle     float64 `json:"idle"`
+}
+
+type plusHTTPRequests struct {
+	Total   float64 `json:"total"`
+	Current float64 `json:"current"`
+}
+
+type plusSSL struct {
+	Handshakes       float64 `json:"handshakes"`
+	HandshakesFailed float64 `json:"handshakes_failed"`
+	SessionReuses    float64 `json:"session_reuses"`
+}
+
+type plusServerZone struct {
+	Processing float64       `json:"processing"`
+	Requests   float64       `json:"requests"`
+	Responses  plusResponses `json:"responses"`
+	Discarded  float64       `json:"discarded"`
+	Received   float64       `json:"received"`
+	Sent       float64       `json:"sent"`
+}
+
+type plusUpstream struct {
+	Peers []struct {
+		Server    string        `json:"server"`
+		State     string        `json:"state"`
+		Active    float64       `json:"active"`
+		Requests  float64       `json:"requests"`
+		Responses plusResponses `json:"responses"`
+		Sent      float64       `json:"sent"`
+		Received  float64       `json:"received"`
+		Fails     float64       `json:"fails"`
+		Unavail   float64       `json:"unavail"`
+	} `json:"peers"`
+	Keepalives float64 `json:"keepalive"`
+	Zombies    float64 `json:"zombies"`
+}
+
+// plusCollector collects metrics from the NGINX Plus API.
+type plusCollector struct {
+	log     log.Logger
+	client  *http.Client
+	baseURI string
+	version int
+}
+
+func newPlusCollector(l log.Logger, client *http.Client, baseURI string, version int) *plusCollector {
+	return &plusCollector{
+		log:     l,
+		client:  client,
+		baseURI: strings.TrimSuffix(baseURI, "/"),
+		version: version,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *plusCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		plusUpDesc,
+		plusConnectionsAcceptedDesc, plusConnectionsDroppedDesc, plusConnectionsActiveDesc, plusConnectionsIdleDesc,
+		plusHTTPRequestsTotalDesc, plusHTTPRequestsCurrentDesc,
+		plusSSLHandshakesDesc, plusSSLHandshakesFailedDesc, plusSSLSessionReusesDesc,
+		plusServerZoneProcessingDesc, plusServerZoneRequestsDesc, plusServerZoneResponsesDesc,
+		plusServerZoneDiscardedDesc, plusServerZoneReceivedDesc, plusServerZoneSentDesc,
+		plusUpstreamServerStateDesc, plusUpstreamServerActiveDesc, plusUpstreamServerRequestsDesc,
+		plusUpstreamServerResponsesDesc, plusUpstreamServerSentDesc, plusUpstreamServerReceivedDesc,
+		plusUpstreamServerFailsDesc, plusUpstreamServerUnavailDesc,
+		plusUpstreamKeepalivesDesc, plusUpstreamZombiesDesc,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *plusCollector) Collect(ch chan<- prometheus.Metric) {
+	var (
+		connections plusConnections
+		requests    plusHTTPRequests
+		ssl         plusSSL
+		serverZones map[string]plusServerZone
+		upstreams   map[string]plusUpstream
+	)
+
+	var (
+		wg   sync.WaitGroup
+		mut  sync.Mutex
+		errs []error
+	)
+	for path, out := range map[string]interface{}{
+		"connections":       &connections,
+		"http/requests":     &requests,
+		"ssl":               &ssl,
+		"http/server_zones": &serverZones,
+		"http/upstreams":    &upstreams,
+	} {
+		wg.Add(1)
+		go func(path string, out interface{}) {
+			defer wg.Done()
+			if err := c.get(path, out); err != nil {
+				mut.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", path, err))
+				mut.Unlock()
+			}
+		}(path, out)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		for _, err := range errs {
+			level.Error(c.log).Log("msg", "failed to scrape nginx plus api", "err", err)
+		}
+		ch <- prometheus.MustNewConstMetric(plusUpDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(plusUpDesc, prometheus.GaugeValue, 1)
+
+	counter := func(desc *prometheus.Desc, v float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v, labels...)
+	}
+	gauge := func(desc *prometheus.Desc, v float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labels...)
+	}
+
+	counter(plusConnectionsAcceptedDesc, connections.Accepted)
+	counter(plusConnectionsDroppedDesc, connections.Dropped)
+	gauge(plusConnectionsActiveDesc, connections.Active)
+	gauge(plusConnectionsIdleDesc, connections.Idle)
+
+	counter(plusHTTPRequestsTotalDesc, requests.Total)
+	gauge(plusHTTPRequestsCurrentDesc, requests.Current)
+
+	counter(plusSSLHandshakesDesc, ssl.Handshakes)
+	counter(plusSSLHandshakesFailedDesc, ssl.HandshakesFailed)
+	counter(plusSSLSessionReusesDesc, ssl.SessionReuses)
+
+	for name, zone := range serverZones {
+		gauge(plusServerZoneProcessingDesc, zone.Processing, name)
+		counter(plusServerZoneRequestsDesc, zone.Requests, name)
+		for code, v := range zone.Responses.byCode() {
+			counter(plusServerZoneResponsesDesc, v, name, code)
+		}
+		counter(plusServerZoneDiscardedDesc, zone.Discarded, name)
+		counter(plusServerZoneReceivedDesc, zone.Received, name)
+		counter(plusServerZoneSentDesc, zone.Sent, name)
+	}
+
+	for name, upstream := range upstreams {
+		for _, peer := range upstream.Peers {
+			gauge(plusUpstreamServerStateDesc, upstreamServerStates[peer.State], name, peer.Server)
+			gauge(plusUpstreamServerActiveDesc, peer.Active, name, peer.Server)
+			counter(plusUpstreamServerRequestsDesc, peer.Requests, name, peer.Server)
+			for code, v := range peer.Responses.byCode() {
+				counter(plusUpstreamServerResponsesDesc, v, name, peer.Server, code)
+			}
+			counter(plusUpstreamServerSentDesc, peer.Sent, name, peer.Server)
+			counter(plusUpstreamServerReceivedDesc, peer.Received, name, peer.Server)
+			counter(plusUpstreamServerFailsDesc, peer.Fails, name, peer.Server)
+			counter(plusUpstreamServerUnavailDesc, peer.Unavail, name, peer.Server)
+		}
+		gauge(plusUpstreamKeepalivesDesc, upstream.Keepalives, name)
+		gauge(plusUpstreamZombiesDesc, upstream.Zombies, name)
+	}
+}
+
+// get retrieves an endpoint from the NGINX Plus API and decodes it into out.
+func (c *plusCollector) get(path string, out interface{}) error {
+	resp, err := c.client.Get(fmt.Sprintf("%s/%d/%s", c.baseURI, c.version, path))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
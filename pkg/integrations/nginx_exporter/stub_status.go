@@ -0,0 +1,148 @@
+package nginx_exporter //nolint:golint
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	stubUpDesc = prometheus.NewDesc(
+		"nginx_up",
+		"Status of the last metric scrape.",
+		nil, nil,
+	)
+	stubConnectionsDesc = map[string]*prometheus.Desc{}
+	stubRequestsDesc    = prometheus.NewDesc(
+		"nginx_http_requests_total",
+		"Total http requests.",
+		nil, nil,
+	)
+)
+
+func init() {
+	for name, help := range map[string]string{
+		"accepted": "Accepted client connections.",
+		"handled":  "Handled client connections.",
+		"active":   "Active client connections.",
+		"reading":  "Connections where NGINX is reading the request header.",
+		"writing":  "Connections where NGINX is writing the response back to the client.",
+		"waiting":  "Idle client connections.",
+	} {
+		stubConnectionsDesc[name] = prometheus.NewDesc("nginx_connections_"+name, help, nil, nil)
+	}
+}
+
+// stubStatus is the parsed output of the stub_status module.
+type stubStatus struct {
+	Active, Accepted, Handled, Requests float64
+	Reading, Writing, Waiting           float64
+}
+
+// stubStatusCollector collects metrics from the stub_status module.
+type stubStatusCollector struct {
+	log       log.Logger
+	client    *http.Client
+	scrapeURI string
+}
+
+func newStubStatusCollector(l log.Logger, client *http.Client, scrapeURI string) *stubStatusCollector {
+	return &stubStatusCollector{log: l, client: client, scrapeURI: scrapeURI}
+}
+
+// Describe implements prometheus.Collector.
+func (c *stubStatusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- stubUpDesc
+	ch <- stubRequestsDesc
+	for _, d := range stubConnectionsDesc {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *stubStatusCollector) Collect(ch chan<- prometheus.Metric) {
+	s, err := c.fetch()
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to scrape nginx stub_status", "err", err)
+		ch <- prometheus.MustNewConstMetric(stubUpDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(stubUpDesc, prometheus.GaugeValue, 1)
+
+	ch <- prometheus.MustNewConstMetric(stubRequestsDesc, prometheus.CounterValue, s.Requests)
+	ch <- prometheus.MustNewConstMetric(stubConnectionsDesc["accepted"], prometheus.CounterValue, s.Accepted)
+	ch <- prometheus.MustNewConstMetric(stubConnectionsDesc["handled"], prometheus.CounterValue, s.Handled)
+	ch <- prometheus.MustNewConstMetric(stubConnectionsDesc["active"], prometheus.GaugeValue, s.Active)
+	ch <- prometheus.MustNewConstMetric(stubConnectionsDesc["reading"], prometheus.GaugeValue, s.Reading)
+	ch <- prometheus.MustNewConstMetric(stubConnectionsDesc["writing"], prometheus.GaugeValue, s.Writing)
+	ch <- prometheus.MustNewConstMetric(stubConnectionsDesc["waiting"], prometheus.GaugeValue, s.Waiting)
+}
+
+func (c *stubStatusCollector) fetch() (*stubStatus, error) {
+	resp, err := c.client.Get(c.scrapeURI)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return parseStubStatus(resp.Body)
+}
+
+// parseStubStatus parses stub_status output, which looks like:
+//
+//	Active connections: 291
+//	server accepts handled requests
+//	 16630948 16630948 31070465
+//	Reading: 6 Writing: 179 Waiting: 106
+func parseStubStatus(r io.Reader) (*stubStatus, error) {
+	bb, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(strings.TrimSpace(string(bb)), "\n")
+	if len(lines) != 4 {
+		return nil, fmt.Errorf("invalid stub_status output: expected 4 lines, got %d", len(lines))
+	}
+
+	var s stubStatus
+
+	activeParts := strings.Fields(lines[0])
+	if len(activeParts) != 3 {
+		return nil, fmt.Errorf("invalid stub_status active connections line: %q", lines[0])
+	}
+	if s.Active, err = strconv.ParseFloat(activeParts[2], 64); err != nil {
+		return nil, fmt.Errorf("invalid active connections: %w", err)
+	}
+
+	counters := strings.Fields(lines[2])
+	if len(counters) != 3 {
+		return nil, fmt.Errorf("invalid stub_status counters line: %q", lines[2])
+	}
+	for i, dst := range []*float64{&s.Accepted, &s.Handled, &s.Requests} {
+		if *dst, err = strconv.ParseFloat(counters[i], 64); err != nil {
+			return nil, fmt.Errorf("invalid counter %q: %w", counters[i], err)
+		}
+	}
+
+	states := strings.Fields(lines[3])
+	if len(states) != 6 {
+		return nil, fmt.Errorf("invalid stub_status connection states line: %q", lines[3])
+	}
+	for i, dst := range []*float64{&s.Reading, &s.Writing, &s.Waiting} {
+		if *dst, err = strconv.ParseFloat(states[i*2+1], 64); err != nil {
+			return nil, fmt.Errorf("invalid connection state %q: %w", states[i*2+1], err)
+		}
+	}
+
+	return &s, nil
+}
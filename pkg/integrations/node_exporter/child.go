@@ -0,0 +1,246 @@
+package node_exporter //nolint:golint
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/agent/pkg/integrations/config"
+)
+
+// childEnv is set in the environment of node_exporter child processes.
+const childEnv = "AGENT_NODE_EXPORTER_CHILD"
+
+// ChildProcessConfig configures running the node_exporter collectors in a
+// separate child process. The child process is the current executable
+// re-executed, and serves metrics to the agent over a local unix socket.
+//
+// Running collectors in a child process allows granting privileges required
+// by some collectors to the child process only.
+type ChildProcessConfig struct {
+	Enabled bool `yaml:"enabled,omitempty"`
+
+	// User and group IDs to run the child process as. Defaults to the IDs of
+	// the agent.
+	UID *uint32 `yaml:"uid,omitempty"`
+	GID *uint32 `yaml:"gid,omitempty"`
+
+	// Capabilities to grant to the child process, such as CAP_SYS_TIME. The
+	// agent must hold these capabilities in its permitted and inheritable
+	// sets.
+	Capabilities []string `yaml:"capabilities,omitempty"`
+}
+
+// MaybeRunChild runs the node_exporter collectors and exits if the current
+// process is a node_exporter child process. Otherwise, MaybeRunChild returns
+// immediately.
+//
+// MaybeRunChild must be called at the start of main by binaries which include
+// the node_exporter integration.
+func MaybeRunChild() {
+	if os.Getenv(childEnv) == "" {
+		return
+	}
+
+	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
+	logger = level.NewFilter(logger, level.AllowInfo())
+	logger = log.With(logger, "ts", log.DefaultTimestampUTC, "integration", "node_exporter", "pid", os.Getpid())
+
+	l, err := net.FileListener(os.NewFile(3, "listener"))
+	if err != nil {
+		level.Error(logger).Log("msg", "failed to open listener", "err", err)
+		os.Exit(1)
+	}
+	if err := runChild(logger, os.Stdin, l); err != nil {
+		level.Error(logger).Log("msg", "node_exporter child process failed", "err", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// runChild reads a Config from stdin and serves the metrics of the
+// node_exporter collectors on l until stdin is closed.
+func runChild(logger log.Logger, stdin io.Reader, l net.Listener) error {
+	var c Config
+	dec := json.NewDecoder(stdin)
+	if err := dec.Decode(&c); err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	c.ChildProcess = ChildProcessConfig{}
+
+	i, err := New(logger, &c)
+	if err != nil {
+		return err
+	}
+	h, err := i.MetricsHandler()
+	if err != nil {
+		return err
+	}
+
+	// The parent keeps stdin open for as long as it wants the child to run,
+	// and it's closed automatically if the parent exits.
+	srv := &http.Server{Handler: h}
+	go func() {
+		_, _ = io.Copy(ioutil.Discard, io.MultiReader(dec.Buffered(), stdin))
+		_ = srv.Close()
+	}()
+
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// childIntegration is the node_exporter integration running its collectors
+// in a child process.
+type childIntegration struct {
+	c      *Config
+	logger log.Logger
+	proxy  http.Handler
+
+	mut    sync.Mutex
+	socket string // Path of the socket of the running child process.
+}
+
+func newChildIntegration(l log.Logger, c *Config) (*childIntegration, error) {
+	// Validate the child process settings before the child is started.
+	if _, err := childSysProcAttr(c.ChildProcess); err != nil {
+		return nil, err
+	}
+
+	i := &childIntegration{c: c, logger: l}
+
+	proxy := httputil.NewSingleHostReverseProxy(&url.URL{Scheme: "http", Host: "node_exporter"})
+	proxy.Transport = &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			i.mut.Lock()
+			socket := i.socket
+			i.mut.Unlock()
+
+			if socket == "" {
+				return nil, fmt.Errorf("node_exporter child process is not running")
+			}
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}
+	proxy.ErrorHandler = func(rw http.ResponseWriter, _ *http.Request, err error) {
+		http.Error(rw, err.Error(), http.StatusServiceUnavailable)
+	}
+	i.proxy = proxy
+
+	return i, nil
+}
+
+// MetricsHandler implements Integration.
+func (i *childIntegration) MetricsHandler() (http.Handler, error) {
+	return i.proxy, nil
+}
+
+// ScrapeConfigs satisfies Integration.ScrapeConfigs.
+func (i *childIntegration) ScrapeConfigs() []config.ScrapeConfig {
+	return []config.ScrapeConfig{{
+		JobName:     i.c.Name(),
+		MetricsPath: "/metrics",
+	}}
+}
+
+// Run satisfies Integration.Run. Run starts the child process and blocks
+// until ctx is canceled or the child process exits.
+func (i *childIntegration) Run(ctx context.Context) error {
+	cfg := *i.c
+	cfg.ChildProcess = ChildProcessConfig{}
+	cfgBytes, err := json.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to serialize config: %w", err)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find executable: %w", err)
+	}
+	attr, err := childSysProcAttr(i.c.ChildProcess)
+	if err != nil {
+		return err
+	}
+
+	dir, err := ioutil.TempDir("", "agent-node-exporter")
+	if err != nil {
+		return fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "node_exporter.sock")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Net: "unix", Name: socket})
+	if err != nil {
+		return fmt.Errorf("failed to create socket: %w", err)
+	}
+	// The listener is handed over to the child process; the socket file must
+	// remain after closing our copy of it.
+	l.SetUnlinkOnClose(false)
+	lf, err := l.File()
+	_ = l.Close()
+	if err != nil {
+		return fmt.Errorf("failed to create socket: %w", err)
+	}
+	defer lf.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, exe)
+	cmd.Env = append(os.Environ(), childEnv+"=1")
+	cmd.ExtraFiles = []*os.File{lf}
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = attr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start node_exporter child process: %w", err)
+	}
+	level.Info(i.logger).Log("msg", "started node_exporter child process", "pid", cmd.Process.Pid)
+
+	// stdin is kept open until Run exits. Closing it stops the child process.
+	defer stdin.Close()
+	if _, err := stdin.Write(cfgBytes); err != nil {
+		cancel()
+		_ = cmd.Wait()
+		return fmt.Errorf("failed to send config to node_exporter child process: %w", err)
+	}
+
+	i.mut.Lock()
+	i.socket = socket
+	i.mut.Unlock()
+	defer func() {
+		i.mut.Lock()
+		i.socket = ""
+		i.mut.Unlock()
+	}()
+
+	err = cmd.Wait()
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case err != nil:
+		return fmt.Errorf("node_exporter child process exited: %w", err)
+	default:
+		return fmt.Errorf("node_exporter child process exited unexpectedly")
+	}
+}
@@ -0,0 +1,64 @@
+package node_exporter //nolint:golint
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/pkg/util"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMain allows the test binary to be re-executed as a node_exporter child
+// process.
+func TestMain(m *testing.M) {
+	MaybeRunChild()
+	os.Exit(m.Run())
+}
+
+func TestChildIntegration(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.SetCollectors = []string{CollectorLoadAvg}
+	cfg.ChildProcess.Enabled = true
+
+	i, err := cfg.NewIntegration(util.TestLogger(t))
+	require.NoError(t, err)
+	require.IsType(t, &childIntegration{}, i)
+
+	h, err := i.MetricsHandler()
+	require.NoError(t, err)
+
+	// Scraping fails until the child process is running.
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	exited := make(chan error, 1)
+	go func() { exited <- i.Run(ctx) }()
+
+	require.Eventually(t, func() bool {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		return rec.Code == http.StatusOK && strings.Contains(rec.Body.String(), "node_load1 ")
+	}, 10*time.Second, 50*time.Millisecond)
+
+	cancel()
+	require.ErrorIs(t, <-exited, context.Canceled)
+}
+
+func TestChildSysProcAttr(t *testing.T) {
+	uid := uint32(65534)
+	attr, err := childSysProcAttr(ChildProcessConfig{UID: &uid, Capabilities: []string{"cap_sys_time"}})
+	require.NoError(t, err)
+	require.Equal(t, uid, attr.Credential.Uid)
+	require.Equal(t, uint32(os.Getgid()), attr.Credential.Gid)
+	require.Len(t, attr.AmbientCaps, 1)
+
+	_, err = childSysProcAttr(ChildProcessConfig{Capabilities: []string{"CAP_FLY"}})
+	require.EqualError(t, err, `unknown capability "CAP_FLY"`)
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package node_exporter //nolint:golint
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// childSysProcAttr returns an error, as node_exporter child processes are
+// only supported on Linux.
+func childSysProcAttr(ChildProcessConfig) (*syscall.SysProcAttr, error) {
+	return nil, fmt.Errorf("child_process is only supported on Linux")
+}
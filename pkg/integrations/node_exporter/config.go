@@ -77,6 +77,9 @@ func init() {
 type Config struct {
 	IncludeExporterMetrics bool `yaml:"include_exporter_metrics,omitempty"`
 
+	// Run collectors in a separate child process.
+	ChildProcess ChildProcessConfig `yaml:"child_process,omitempty"`
+
 	ProcFSPath string `yaml:"procfs_path,omitempty"`
 	SysFSPath  string `yaml:"sysfs_path,omitempty"`
 	RootFSPath string `yaml:"rootfs_path,omitempty"`
@@ -236,6 +239,9 @@ func (c *Config) InstanceKey(agentKey string) (string, error) {
 
 // NewIntegration converts this config into an instance of an integration.
 func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	if c.ChildProcess.Enabled {
+		return newChildIntegration(l, c)
+	}
 	return New(l, c)
 }
 
@@ -0,0 +1,285 @@
+package nvidia_gpu //nolint:golint
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "nvidia_gpu"
+
+func gpuDesc(name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, append([]string{"gpu", "uuid"}, labels...), nil)
+}
+
+var (
+	upDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "up"),
+		"Whether NVML could be queried for GPU information.",
+		nil, nil,
+	)
+	numDevicesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "num_devices"),
+		"Number of GPU devices.",
+		nil, nil,
+	)
+
+	infoDesc                  = gpuDesc("info", "Information about the GPU.", "name", "minor_number", "driver_version")
+	memoryTotalDesc           = gpuDesc("memory_total_bytes", "Total memory of the GPU.")
+	memoryUsedDesc            = gpuDesc("memory_used_bytes", "Memory of the GPU in use.")
+	utilizationDesc           = gpuDesc("utilization_percent", "Percent of time over the past sample period during which kernels were executing on the GPU.")
+	memoryUtilizationDesc     = gpuDesc("memory_utilization_percent", "Percent of time over the past sample period during which memory was being read or written.")
+	encoderUtilizationDesc    = gpuDesc("encoder_utilization_percent", "Utilization of the video encoder.")
+	decoderUtilizationDesc    = gpuDesc("decoder_utilization_percent", "Utilization of the video decoder.")
+	powerUsageDesc            = gpuDesc("power_usage_watts", "Power usage of the GPU and its associated circuitry.")
+	temperatureDesc           = gpuDesc("temperature_celsius", "Temperature of the GPU.")
+	fanSpeedDesc              = gpuDesc("fan_speed_percent", "Intended fan speed as a percent of the maximum.")
+	processMemoryUsedDesc     = gpuDesc("process_memory_used_bytes", "Memory of the GPU used by the process.", "pid", "process_name")
+	processScrapeDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "process_scrape_duration_seconds"),
+		"Time it took to list GPU processes with nvidia-smi.",
+		nil, nil,
+	)
+)
+
+// nvml is the subset of NVML used by the collector.
+type nvml interface {
+	DriverVersion() (string, error)
+	DeviceCount() (uint, error)
+	Device(idx uint) (device, error)
+}
+
+// device is a single GPU as returned by NVML.
+type device interface {
+	UUID() (string, error)
+	Name() (string, error)
+	MinorNumber() (uint, error)
+	// MemoryInfo returns the total and used memory in bytes.
+	MemoryInfo() (uint64, uint64, error)
+	// UtilizationRates returns the GPU and memory utilization in percent.
+	UtilizationRates() (uint, uint, error)
+	// EncoderUtilization returns the utilization in percent and the sampling
+	// period in microseconds.
+	EncoderUtilization() (uint, uint, error)
+	// DecoderUtilization returns the utilization in percent and the sampling
+	// period in microseconds.
+	DecoderUtilization() (uint, uint, error)
+	// PowerUsage returns the power usage in milliwatts.
+	PowerUsage() (uint, error)
+	Temperature() (uint, error)
+	FanSpeed() (uint, error)
+}
+
+// gpuProcess is a process using a GPU.
+type gpuProcess struct {
+	GPUUUID    string
+	PID        string
+	Name       string
+	UsedMemory float64 // bytes
+}
+
+// processLister lists processes using GPUs.
+type processLister func(ctx context.Context) ([]gpuProcess, error)
+
+type collector struct {
+	log       log.Logger
+	nvml      nvml
+	processes processLister
+}
+
+func newCollector(l log.Logger, n nvml, processes processLister) *collector {
+	return &collector{log: l, nvml: n, processes: processes}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		upDesc, numDevicesDesc, infoDesc, memoryTotalDesc, memoryUsedDesc,
+		utilizationDesc, memoryUtilizationDesc, encoderUtilizationDesc,
+		decoderUtilizationDesc, powerUsageDesc, temperatureDesc, fanSpeedDesc,
+	} {
+		ch <- d
+	}
+	if c.processes != nil {
+		ch <- processMemoryUsedDesc
+		ch <- processScrapeDurationDesc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	count, err := c.nvml.DeviceCount()
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to get gpu device count", "err", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
+	ch <- prometheus.MustNewConstMetric(numDevicesDesc, prometheus.GaugeValue, float64(count))
+
+	driverVersion, err := c.nvml.DriverVersion()
+	if err != nil {
+		level.Debug(c.log).Log("msg", "failed to get driver version", "err", err)
+	}
+
+	uuidToIndex := make(map[string]string, count)
+	for i := uint(0); i < count; i++ {
+		index := strconv.FormatUint(uint64(i), 10)
+
+		d, err := c.nvml.Device(i)
+		if err != nil {
+			level.Error(c.log).Log("msg", "failed to get gpu device", "gpu", index, "err", err)
+			continue
+		}
+		uuid, err := d.UUID()
+		if err != nil {
+			level.Error(c.log).Log("msg", "failed to get gpu uuid", "gpu", index, "err", err)
+			continue
+		}
+		uuidToIndex[uuid] = index
+
+		c.collectDevice(ch, d, index, uuid, driverVersion)
+	}
+
+	if c.processes != nil {
+		c.collectProcesses(ch, uuidToIndex)
+	}
+}
+
+func (c *collector) collectDevice(ch chan<- prometheus.Metric, d device, index, uuid, driverVersion string) {
+	// Not all metrics are supported by every GPU; failures for individual
+	// metrics only cause those metrics to be omitted.
+	logErr := func(metric string, err error) {
+		level.Debug(c.log).Log("msg", "failed to get gpu metric", "gpu", index, "metric", metric, "err", err)
+	}
+	gauge := func(desc *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, index, uuid)
+	}
+
+	name, err := d.Name()
+	if err != nil {
+		logErr("name", err)
+	}
+	minor, err := d.MinorNumber()
+	if err != nil {
+		logErr("minor_number", err)
+	}
+	ch <- prometheus.MustNewConstMetric(infoDesc, prometheus.GaugeValue, 1, index, uuid, name, strconv.FormatUint(uint64(minor), 10), driverVersion)
+
+	if total, used, err := d.MemoryInfo(); err != nil {
+		logErr("memory", err)
+	} else {
+		gauge(memoryTotalDesc, float64(total))
+		gauge(memoryUsedDesc, float64(used))
+	}
+
+	if gpu, memory, err := d.UtilizationRates(); err != nil {
+		logErr("utilization", err)
+	} else {
+		gauge(utilizationDesc, float64(gpu))
+		gauge(memoryUtilizationDesc, float64(memory))
+	}
+
+	if util, _, err := d.EncoderUtilization(); err != nil {
+		logErr("encoder_utilization", err)
+	} else {
+		gauge(encoderUtilizationDesc, float64(util))
+	}
+
+	if util, _, err := d.DecoderUtilization(); err != nil {
+		logErr("decoder_utilization", err)
+	} else {
+		gauge(decoderUtilizationDesc, float64(util))
+	}
+
+	if milliwatts, err := d.PowerUsage(); err != nil {
+		logErr("power_usage", err)
+	} else {
+		gauge(powerUsageDesc, float64(milliwatts)/1000)
+	}
+
+	if temp, err := d.Temperature(); err != nil {
+		logErr("temperature", err)
+	} else {
+		gauge(temperatureDesc, float64(temp))
+	}
+
+	if speed, err := d.FanSpeed(); err != nil {
+		logErr("fan_speed", err)
+	} else {
+		gauge(fanSpeedDesc, float64(speed))
+	}
+}
+
+func (c *collector) collectProcesses(ch chan<- prometheus.Metric, uuidToIndex map[string]string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	procs, err := c.processes(ctx)
+	ch <- prometheus.MustNewConstMetric(processScrapeDurationDesc, prometheus.GaugeValue, time.Since(start).Seconds())
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to list gpu processes", "err", err)
+		return
+	}
+
+	for _, p := range procs {
+		index, ok := uuidToIndex[p.GPUUUID]
+		if !ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(processMemoryUsedDesc, prometheus.GaugeValue, p.UsedMemory, index, p.GPUUUID, p.PID, p.Name)
+	}
+}
+
+// nvidiaSMIProcessLister returns a processLister which invokes nvidia-smi.
+func nvidiaSMIProcessLister(path string) processLister {
+	return func(ctx context.Context) ([]gpuProcess, error) {
+		var stdout, stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, path, "--query-compute-apps=gpu_uuid,pid,process_name,used_memory", "--format=csv,noheader,nounits")
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("running nvidia-smi failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return parseComputeApps(&stdout)
+	}
+}
+
+// parseComputeApps parses the output of nvidia-smi --query-compute-apps in
+// CSV format without header and units. Used memory is reported in MiB.
+func parseComputeApps(r io.Reader) ([]gpuProcess, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = 4
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse nvidia-smi output: %w", err)
+	}
+
+	procs := make([]gpuProcess, 0, len(records))
+	for _, rec := range records {
+		usedMiB, err := strconv.ParseFloat(rec[3], 64)
+		if err != nil {
+			// nvidia-smi reports [N/A] when memory usage isn't available.
+			usedMiB = 0
+		}
+		procs = append(procs, gpuProcess{
+			GPUUUID:    rec[0],
+			PID:        rec[1],
+			Name:       rec[2],
+			UsedMemory: usedMiB * 1024 * 1024,
+		})
+	}
+	return procs, nil
+}
@@ -0,0 +1,92 @@
+package nvidia_gpu //nolint:golint
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+var errNotSupported = errors.New("Not Supported")
+
+type fakeNVML struct {
+	devices []fakeDevice
+}
+
+func (f *fakeNVML) DriverVersion() (string, error) { return "470.82.01", nil }
+func (f *fakeNVML) DeviceCount() (uint, error)     { return uint(len(f.devices)), nil }
+func (f *fakeNVML) Device(idx uint) (device, error) {
+	return f.devices[idx], nil
+}
+
+type fakeDevice struct {
+	uuid string
+}
+
+func (d fakeDevice) UUID() (string, error)                   { return d.uuid, nil }
+func (d fakeDevice) Name() (string, error)                   { return "Tesla T4", nil }
+func (d fakeDevice) MinorNumber() (uint, error)              { return 0, nil }
+func (d fakeDevice) MemoryInfo() (uint64, uint64, error)     { return 16 << 30, 1 << 30, nil }
+func (d fakeDevice) UtilizationRates() (uint, uint, error)   { return 87, 12, nil }
+func (d fakeDevice) EncoderUtilization() (uint, uint, error) { return 0, 167000, nil }
+func (d fakeDevice) DecoderUtilization() (uint, uint, error) { return 0, 167000, nil }
+func (d fakeDevice) PowerUsage() (uint, error)               { return 70500, nil }
+func (d fakeDevice) Temperature() (uint, error)              { return 64, nil }
+func (d fakeDevice) FanSpeed() (uint, error)                 { return 0, errNotSupported }
+
+func TestCollector(t *testing.T) {
+	n := &fakeNVML{devices: []fakeDevice{{uuid: "GPU-aaaa"}}}
+	processes := func(ctx context.Context) ([]gpuProcess, error) {
+		return []gpuProcess{
+			{GPUUUID: "GPU-aaaa", PID: "1234", Name: "python", UsedMemory: 512 * 1024 * 1024},
+			{GPUUUID: "GPU-unknown", PID: "5678", Name: "other", UsedMemory: 1},
+		}, nil
+	}
+	c := newCollector(log.NewNopLogger(), n, processes)
+
+	expect := `
+# HELP nvidia_gpu_fan_speed_percent Intended fan speed as a percent of the maximum.
+# TYPE nvidia_gpu_fan_speed_percent gauge
+# HELP nvidia_gpu_info Information about the GPU.
+# TYPE nvidia_gpu_info gauge
+nvidia_gpu_info{driver_version="470.82.01",gpu="0",minor_number="0",name="Tesla T4",uuid="GPU-aaaa"} 1
+# HELP nvidia_gpu_memory_used_bytes Memory of the GPU in use.
+# TYPE nvidia_gpu_memory_used_bytes gauge
+nvidia_gpu_memory_used_bytes{gpu="0",uuid="GPU-aaaa"} 1.073741824e+09
+# HELP nvidia_gpu_power_usage_watts Power usage of the GPU and its associated circuitry.
+# TYPE nvidia_gpu_power_usage_watts gauge
+nvidia_gpu_power_usage_watts{gpu="0",uuid="GPU-aaaa"} 70.5
+# HELP nvidia_gpu_process_memory_used_bytes Memory of the GPU used by the process.
+# TYPE nvidia_gpu_process_memory_used_bytes gauge
+nvidia_gpu_process_memory_used_bytes{gpu="0",pid="1234",process_name="python",uuid="GPU-aaaa"} 5.36870912e+08
+# HELP nvidia_gpu_up Whether NVML could be queried for GPU information.
+# TYPE nvidia_gpu_up gauge
+nvidia_gpu_up 1
+# HELP nvidia_gpu_utilization_percent Percent of time over the past sample period during which kernels were executing on the GPU.
+# TYPE nvidia_gpu_utilization_percent gauge
+nvidia_gpu_utilization_percent{gpu="0",uuid="GPU-aaaa"} 87
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"nvidia_gpu_up", "nvidia_gpu_info", "nvidia_gpu_memory_used_bytes", "nvidia_gpu_power_usage_watts",
+		"nvidia_gpu_utilization_percent", "nvidia_gpu_fan_speed_percent", "nvidia_gpu_process_memory_used_bytes")
+	require.NoError(t, err)
+}
+
+func TestParseComputeApps(t *testing.T) {
+	out := `GPU-aaaa, 1234, /usr/bin/python3, 512
+GPU-bbbb, 5678, ./train, [N/A]
+`
+	procs, err := parseComputeApps(strings.NewReader(out))
+	require.NoError(t, err)
+	require.Equal(t, []gpuProcess{
+		{GPUUUID: "GPU-aaaa", PID: "1234", Name: "/usr/bin/python3", UsedMemory: 512 * 1024 * 1024},
+		{GPUUUID: "GPU-bbbb", PID: "5678", Name: "./train", UsedMemory: 0},
+	}, procs)
+
+	_, err = parseComputeApps(strings.NewReader("garbage"))
+	require.Error(t, err)
+}
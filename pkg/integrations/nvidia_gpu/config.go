@@ -0,0 +1,50 @@
+// Package nvidia_gpu implements an integration which collects metrics from
+// NVIDIA GPUs through NVML.
+package nvidia_gpu //nolint:golint
+
+import (
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+)
+
+// DefaultConfig holds the default settings for the nvidia_gpu integration.
+var DefaultConfig = Config{
+	CollectProcesses: true,
+	NvidiaSMIPath:    "nvidia-smi",
+}
+
+// Config controls the nvidia_gpu integration.
+type Config struct {
+	// CollectProcesses enables collecting GPU memory used per process. The
+	// per-process information is retrieved by invoking nvidia-smi.
+	CollectProcesses bool `yaml:"collect_processes,omitempty"`
+	// NvidiaSMIPath is the path to the nvidia-smi binary.
+	NvidiaSMIPath string `yaml:"nvidia_smi_path,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	return unmarshal((*plain)(c))
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "nvidia_gpu"
+}
+
+// InstanceKey returns the hostname:port of the agent process.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	return agentKey, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
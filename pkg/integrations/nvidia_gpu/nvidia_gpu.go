@@ -0,0 +1,49 @@
+//go:build linux
+// +build linux
+
+package nvidia_gpu //nolint:golint
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/mindprince/gonvml"
+)
+
+// New creates a new nvidia_gpu integration. NVML is loaded dynamically, so
+// the NVIDIA driver must be installed on the host.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	if err := gonvml.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to initialize NVML: %w", err)
+	}
+
+	var processes processLister
+	if c.CollectProcesses {
+		processes = nvidiaSMIProcessLister(c.NvidiaSMIPath)
+	}
+
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(newCollector(logger, gonvmlLibrary{}, processes)),
+		integrations.WithRunner(func(ctx context.Context) error {
+			<-ctx.Done()
+			if err := gonvml.Shutdown(); err != nil {
+				level.Warn(logger).Log("msg", "failed to shut down NVML", "err", err)
+			}
+			return ctx.Err()
+		}),
+	), nil
+}
+
+// gonvmlLibrary implements nvml using gonvml.
+type gonvmlLibrary struct{}
+
+func (gonvmlLibrary) DriverVersion() (string, error) { return gonvml.SystemDriverVersion() }
+func (gonvmlLibrary) DeviceCount() (uint, error)     { return gonvml.DeviceCount() }
+
+func (gonvmlLibrary) Device(idx uint) (device, error) {
+	return gonvml.DeviceHandleByIndex(idx)
+}
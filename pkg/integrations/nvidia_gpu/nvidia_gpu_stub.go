@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package nvidia_gpu //nolint:golint
+
+import (
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/agent/pkg/integrations"
+)
+
+// New creates a new nvidia_gpu integration.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	level.Warn(logger).Log("msg", "the nvidia_gpu integration only works on linux; enabling it on other platforms will do nothing")
+	return &integrations.StubIntegration{}, nil
+}
@@ -0,0 +1,349 @@
+package openstack
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	config_util "github.com/prometheus/common/config"
+)
+
+const namespace = "openstack"
+
+const bytesPerMiB, bytesPerGiB = 1 << 20, 1 << 30
+
+func desc(subsystem, name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, subsystem, name), help, labels, nil)
+}
+
+var (
+	serviceUpDesc = desc("", "service_up", "Whether metrics of the OpenStack service were collected successfully.", "service")
+
+	serviceLabels           = []string{"binary", "host", "zone"}
+	novaServiceUpDesc       = desc("nova", "service_up", "Whether the Nova service is up.", serviceLabels...)
+	novaServiceEnabledDesc  = desc("nova", "service_enabled", "Whether the Nova service is enabled.", serviceLabels...)
+	novaHypervisorUpDesc    = desc("nova", "hypervisor_up", "Whether the hypervisor is up.", "hypervisor")
+	novaVCPUsDesc           = desc("nova", "hypervisor_vcpus", "Number of vCPUs of the hypervisor.", "hypervisor")
+	novaVCPUsUsedDesc       = desc("nova", "hypervisor_vcpus_used", "Number of vCPUs of the hypervisor in use.", "hypervisor")
+	novaMemoryDesc          = desc("nova", "hypervisor_memory_bytes", "Memory of the hypervisor.", "hypervisor")
+	novaMemoryUsedDesc      = desc("nova", "hypervisor_memory_used_bytes", "Memory of the hypervisor in use.", "hypervisor")
+	novaLocalStorageDesc    = desc("nova", "hypervisor_local_storage_bytes", "Local storage of the hypervisor.", "hypervisor")
+	novaLocalStorageUsed    = desc("nova", "hypervisor_local_storage_used_bytes", "Local storage of the hypervisor in use.", "hypervisor")
+	novaRunningVMsDesc      = desc("nova", "hypervisor_running_vms", "Number of VMs running on the hypervisor.", "hypervisor")
+	novaServersDesc         = desc("nova", "servers", "Number of servers.", "status")
+	neutronAgentUpDesc      = desc("neutron", "agent_up", "Whether the Neutron agent is alive.", "agent_type", "host")
+	neutronAgentEnabledDesc = desc("neutron", "agent_enabled", "Whether the Neutron agent is administratively up.", "agent_type", "host")
+	neutronNetworksDesc     = desc("neutron", "networks", "Number of networks.")
+	neutronRoutersDesc      = desc("neutron", "routers", "Number of routers.", "status")
+	neutronFloatingIPsDesc  = desc("neutron", "floating_ips", "Number of floating IPs.", "associated")
+	cinderServiceUpDesc     = desc("cinder", "service_up", "Whether the Cinder service is up.", serviceLabels...)
+	cinderServiceEnabled    = desc("cinder", "service_enabled", "Whether the Cinder service is enabled.", serviceLabels...)
+	cinderVolumesDesc       = desc("cinder", "volumes", "Number of volumes.", "status")
+	cinderVolumesSizeDesc   = desc("cinder", "volumes_size_bytes", "Total size of volumes.", "status")
+	cinderPoolCapacityDesc  = desc("cinder", "pool_capacity_bytes", "Capacity of the storage pool.", "pool")
+	cinderPoolFreeDesc      = desc("cinder", "pool_free_bytes", "Free capacity of the storage pool.", "pool")
+)
+
+// link is a pagination link of the Nova and Cinder APIs.
+type link struct {
+	Href string `json:"href"`
+	Rel  string `json:"rel"`
+}
+
+func nextLink(links []link) string {
+	for _, l := range links {
+		if l.Rel == "next" {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+// computeService is a service returned by os-services of Nova and Cinder.
+type computeService struct {
+	Binary string `json:"binary"`
+	Host   string `json:"host"`
+	Zone   string `json:"zone"`
+	Status string `json:"status"`
+	State  string `json:"state"`
+}
+
+type collector struct {
+	log      log.Logger
+	cfg      *Config
+	keystone *keystoneClient
+}
+
+func newCollector(l log.Logger, c *Config) (*collector, error) {
+	tlsConfig, err := config_util.NewTLSConfig(&c.TLSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tls config: %w", err)
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	return &collector{
+		log:      l,
+		cfg:      c,
+		keystone: &keystoneClient{client: client, cfg: c},
+	}, nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		serviceUpDesc, novaServiceUpDesc, novaServiceEnabledDesc, novaHypervisorUpDesc,
+		novaVCPUsDesc, novaVCPUsUsedDesc, novaMemoryDesc, novaMemoryUsedDesc,
+		novaLocalStorageDesc, novaLocalStorageUsed, novaRunningVMsDesc, novaServersDesc,
+		neutronAgentUpDesc, neutronAgentEnabledDesc, neutronNetworksDesc, neutronRoutersDesc,
+		neutronFloatingIPsDesc, cinderServiceUpDesc, cinderServiceEnabled, cinderVolumesDesc,
+		cinderVolumesSizeDesc, cinderPoolCapacityDesc, cinderPoolFreeDesc,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+
+	gauge := func(desc *prometheus.Desc, v float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labels...)
+	}
+
+	for _, service := range c.cfg.Services {
+		var err error
+		switch service {
+		case ServiceNova:
+			err = c.collectNova(ctx, gauge)
+		case ServiceNeutron:
+			err = c.collectNeutron(ctx, gauge)
+		case ServiceCinder:
+			err = c.collectCinder(ctx, gauge)
+		}
+
+		if err != nil {
+			level.Error(c.log).Log("msg", "failed to collect openstack metrics", "service", service, "err", err)
+			gauge(serviceUpDesc, 0, service)
+			continue
+		}
+		gauge(serviceUpDesc, 1, service)
+	}
+}
+
+type gaugeFunc func(desc *prometheus.Desc, v float64, labels ...string)
+
+func (c *collector) collectServices(ctx context.Context, gauge gaugeFunc, serviceType string, upDesc, enabledDesc *prometheus.Desc) error {
+	var res struct {
+		Services []computeService `json:"services"`
+	}
+	if err := c.keystone.get(ctx, serviceType, "/os-services", &res); err != nil {
+		return err
+	}
+	for _, s := range res.Services {
+		gauge(upDesc, boolValue(s.State == "up"), s.Binary, s.Host, s.Zone)
+		gauge(enabledDesc, boolValue(s.Status == "enabled"), s.Binary, s.Host, s.Zone)
+	}
+	return nil
+}
+
+func (c *collector) collectNova(ctx context.Context, gauge gaugeFunc) error {
+	if err := c.collectServices(ctx, gauge, serviceTypeCompute, novaServiceUpDesc, novaServiceEnabledDesc); err != nil {
+		return err
+	}
+
+	var hypervisors struct {
+		Hypervisors []struct {
+			Hostname    string  `json:"hypervisor_hostname"`
+			State       string  `json:"state"`
+			VCPUs       float64 `json:"vcpus"`
+			VCPUsUsed   float64 `json:"vcpus_used"`
+			MemoryMB    float64 `json:"memory_mb"`
+			MemoryMBUse float64 `json:"memory_mb_used"`
+			LocalGB     float64 `json:"local_gb"`
+			LocalGBUsed float64 `json:"local_gb_used"`
+			RunningVMs  float64 `json:"running_vms"`
+		} `json:"hypervisors"`
+	}
+	if err := c.keystone.get(ctx, serviceTypeCompute, "/os-hypervisors/detail", &hypervisors); err != nil {
+		return err
+	}
+	for _, h := range hypervisors.Hypervisors {
+		gauge(novaHypervisorUpDesc, boolValue(h.State == "up"), h.Hostname)
+		gauge(novaVCPUsDesc, h.VCPUs, h.Hostname)
+		gauge(novaVCPUsUsedDesc, h.VCPUsUsed, h.Hostname)
+		gauge(novaMemoryDesc, h.MemoryMB*bytesPerMiB, h.Hostname)
+		gauge(novaMemoryUsedDesc, h.MemoryMBUse*bytesPerMiB, h.Hostname)
+		gauge(novaLocalStorageDesc, h.LocalGB*bytesPerGiB, h.Hostname)
+		gauge(novaLocalStorageUsed, h.LocalGBUsed*bytesPerGiB, h.Hostname)
+		gauge(novaRunningVMsDesc, h.RunningVMs, h.Hostname)
+	}
+
+	servers := make(map[string]float64)
+	for path := "/servers/detail?all_tenants=1"; path != ""; {
+		var res struct {
+			Servers []struct {
+				Status string `json:"status"`
+			} `json:"servers"`
+			Links []link `json:"servers_links"`
+		}
+		if err := c.keystone.get(ctx, serviceTypeCompute, path, &res); err != nil {
+			return err
+		}
+		for _, s := range res.Servers {
+			servers[s.Status]++
+		}
+		path = nextLink(res.Links)
+	}
+	for status, n := range servers {
+		gauge(novaServersDesc, n, status)
+	}
+	return nil
+}
+
+func (c *collector) collectNeutron(ctx context.Context, gauge gaugeFunc) error {
+	var agents struct {
+		Agents []struct {
+			AgentType    string `json:"agent_type"`
+			Host         string `json:"host"`
+			Alive        bool   `json:"alive"`
+			AdminStateUp bool   `json:"admin_state_up"`
+		} `json:"agents"`
+	}
+	if err := c.keystone.get(ctx, serviceTypeNetwork, "/v2.0/agents", &agents); err != nil {
+		return err
+	}
+	for _, a := range agents.Agents {
+		gauge(neutronAgentUpDesc, boolValue(a.Alive), a.AgentType, a.Host)
+		gauge(neutronAgentEnabledDesc, boolValue(a.AdminStateUp), a.AgentType, a.Host)
+	}
+
+	var networks struct {
+		Networks []struct{} `json:"networks"`
+	}
+	if err := c.keystone.get(ctx, serviceTypeNetwork, "/v2.0/networks?fields=id", &networks); err != nil {
+		return err
+	}
+	gauge(neutronNetworksDesc, float64(len(networks.Networks)))
+
+	var routers struct {
+		Routers []struct {
+			Status string `json:"status"`
+		} `json:"routers"`
+	}
+	if err := c.keystone.get(ctx, serviceTypeNetwork, "/v2.0/routers?fields=status", &routers); err != nil {
+		return err
+	}
+	routersByStatus := make(map[string]float64)
+	for _, r := range routers.Routers {
+		routersByStatus[r.Status]++
+	}
+	for status, n := range routersByStatus {
+		gauge(neutronRoutersDesc, n, status)
+	}
+
+	var floatingIPs struct {
+		FloatingIPs []struct {
+			PortID *string `json:"port_id"`
+		} `json:"floatingips"`
+	}
+	if err := c.keystone.get(ctx, serviceTypeNetwork, "/v2.0/floatingips?fields=port_id", &floatingIPs); err != nil {
+		return err
+	}
+	var associated, unassociated float64
+	for _, ip := range floatingIPs.FloatingIPs {
+		if ip.PortID != nil && *ip.PortID != "" {
+			associated++
+		} else {
+			unassociated++
+		}
+	}
+	gauge(neutronFloatingIPsDesc, associated, "true")
+	gauge(neutronFloatingIPsDesc, unassociated, "false")
+	return nil
+}
+
+func (c *collector) collectCinder(ctx context.Context, gauge gaugeFunc) error {
+	if err := c.collectServices(ctx, gauge, serviceTypeVolume, cinderServiceUpDesc, cinderServiceEnabled); err != nil {
+		return err
+	}
+
+	var (
+		volumes = make(map[string]float64)
+		sizes   = make(map[string]float64)
+	)
+	for path := "/volumes/detail?all_tenants=1"; path != ""; {
+		var res struct {
+			Volumes []struct {
+				Status string  `json:"status"`
+				Size   float64 `json:"size"`
+			} `json:"volumes"`
+			Links []link `json:"volumes_links"`
+		}
+		if err := c.keystone.get(ctx, serviceTypeVolume, path, &res); err != nil {
+			return err
+		}
+		for _, v := range res.Volumes {
+			volumes[v.Status]++
+			sizes[v.Status] += v.Size * bytesPerGiB
+		}
+		path = nextLink(res.Links)
+	}
+	for status, n := range volumes {
+		gauge(cinderVolumesDesc, n, status)
+		gauge(cinderVolumesSizeDesc, sizes[status], status)
+	}
+
+	var pools struct {
+		Pools []struct {
+			Name         string `json:"name"`
+			Capabilities struct {
+				// Capacities are numbers, or strings such as "infinite"
+				// or "unknown" for some backends.
+				TotalCapacityGB interface{} `json:"total_capacity_gb"`
+				FreeCapacityGB  interface{} `json:"free_capacity_gb"`
+			} `json:"capabilities"`
+		} `json:"pools"`
+	}
+	if err := c.keystone.get(ctx, serviceTypeVolume, "/scheduler-stats/get_pools?detail=true", &pools); err != nil {
+		return err
+	}
+	for _, p := range pools.Pools {
+		if v, ok := parseCapacity(p.Capabilities.TotalCapacityGB); ok {
+			gauge(cinderPoolCapacityDesc, v*bytesPerGiB, p.Name)
+		}
+		if v, ok := parseCapacity(p.Capabilities.FreeCapacityGB); ok {
+			gauge(cinderPoolFreeDesc, v*bytesPerGiB, p.Name)
+		}
+	}
+	return nil
+}
+
+// parseCapacity parses a capacity reported by a Cinder pool.
+func parseCapacity(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
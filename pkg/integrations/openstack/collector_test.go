@@ -0,0 +1,282 @@
+package openstack
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestCollector(t *testing.T) {
+	var srv *httptest.Server
+	mux := http.NewServeMux()
+
+	reply := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-Auth-Token") != "test-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(strings.ReplaceAll(body, "SERVER", srv.URL)))
+		}
+	}
+
+	mux.HandleFunc("/identity/v3/auth/tokens", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Auth struct {
+				Identity struct {
+					Password struct {
+						User struct {
+							Name     string `json:"name"`
+							Password string `json:"password"`
+						} `json:"user"`
+					} `json:"password"`
+				} `json:"identity"`
+			} `json:"auth"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Auth.Identity.Password.User.Name != "admin" || req.Auth.Identity.Password.User.Password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("X-Subject-Token", "test-token")
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"token": map[string]interface{}{
+				"expires_at": time.Now().Add(time.Hour).Format(time.RFC3339),
+				"catalog": []map[string]interface{}{
+					{"type": "compute", "endpoints": []map[string]string{
+						{"interface": "internal", "region": "RegionOne", "url": srv.URL + "/internal"},
+						{"interface": "public", "region": "RegionOne", "url": srv.URL + "/compute/v2.1"},
+					}},
+					{"type": "network", "endpoints": []map[string]string{
+						{"interface": "public", "region": "RegionOne", "url": srv.URL + "/network/v2.0/"},
+					}},
+					{"type": "volumev3", "endpoints": []map[string]string{
+						{"interface": "public", "region": "RegionOne", "url": srv.URL + "/volume/v3/project"},
+					}},
+				},
+			},
+		})
+	})
+
+	mux.HandleFunc("/compute/v2.1/os-services", reply(`{"services": [
+		{"binary": "nova-compute", "host": "compute-1", "zone": "nova", "status": "enabled", "state": "up"},
+		{"binary": "nova-compute", "host": "compute-2", "zone": "nova", "status": "disabled", "state": "down"}
+	]}`))
+	mux.HandleFunc("/compute/v2.1/os-hypervisors/detail", reply(`{"hypervisors": [
+		{"hypervisor_hostname": "compute-1", "state": "up", "vcpus": 16, "vcpus_used": 4,
+		 "memory_mb": 1024, "memory_mb_used": 512, "local_gb": 100, "local_gb_used": 10, "running_vms": 2}
+	]}`))
+	mux.HandleFunc("/compute/v2.1/servers/detail", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("marker") == "" {
+			reply(`{"servers": [{"status": "ACTIVE"}, {"status": "ACTIVE"}],
+				"servers_links": [{"rel": "next", "href": "SERVER/compute/v2.1/servers/detail?all_tenants=1&marker=2"}]}`)(w, r)
+			return
+		}
+		reply(`{"servers": [{"status": "SHUTOFF"}]}`)(w, r)
+	})
+
+	mux.HandleFunc("/network/v2.0/agents", reply(`{"agents": [
+		{"agent_type": "Open vSwitch agent", "host": "network-1", "alive": true, "admin_state_up": true},
+		{"agent_type": "DHCP agent", "host": "network-1", "alive": false, "admin_state_up": true}
+	]}`))
+	mux.HandleFunc("/network/v2.0/networks", reply(`{"networks": [{"id": "a"}, {"id": "b"}, {"id": "c"}]}`))
+	mux.HandleFunc("/network/v2.0/routers", reply(`{"routers": [{"status": "ACTIVE"}]}`))
+	mux.HandleFunc("/network/v2.0/floatingips", reply(`{"floatingips": [{"port_id": "p1"}, {"port_id": null}, {"port_id": null}]}`))
+
+	mux.HandleFunc("/volume/v3/project/os-services", reply(`{"services": [
+		{"binary": "cinder-volume", "host": "storage-1@lvm", "zone": "nova", "status": "enabled", "state": "up"}
+	]}`))
+	mux.HandleFunc("/volume/v3/project/volumes/detail", reply(`{"volumes": [
+		{"status": "in-use", "size": 10}, {"status": "available", "size": 1}, {"status": "in-use", "size": 5}
+	]}`))
+	mux.HandleFunc("/volume/v3/project/scheduler-stats/get_pools", reply(`{"pools": [
+		{"name": "storage-1@lvm#lvm", "capabilities": {"total_capacity_gb": 200, "free_capacity_gb": "150"}},
+		{"name": "storage-2@ceph#ceph", "capabilities": {"total_capacity_gb": "infinite", "free_capacity_gb": "unknown"}}
+	]}`))
+
+	srv = httptest.NewServer(mux)
+	defer srv.Close()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+auth_url: `+srv.URL+`/identity/v3
+username: admin
+password: secret
+project_name: admin
+`), &cfg))
+
+	col, err := newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+
+	expect := `
+# HELP openstack_cinder_pool_capacity_bytes Capacity of the storage pool.
+# TYPE openstack_cinder_pool_capacity_bytes gauge
+openstack_cinder_pool_capacity_bytes{pool="storage-1@lvm#lvm"} 2.147483648e+11
+# HELP openstack_cinder_pool_free_bytes Free capacity of the storage pool.
+# TYPE openstack_cinder_pool_free_bytes gauge
+openstack_cinder_pool_free_bytes{pool="storage-1@lvm#lvm"} 1.6106127360e+11
+# HELP openstack_cinder_service_enabled Whether the Cinder service is enabled.
+# TYPE openstack_cinder_service_enabled gauge
+openstack_cinder_service_enabled{binary="cinder-volume",host="storage-1@lvm",zone="nova"} 1
+# HELP openstack_cinder_service_up Whether the Cinder service is up.
+# TYPE openstack_cinder_service_up gauge
+openstack_cinder_service_up{binary="cinder-volume",host="storage-1@lvm",zone="nova"} 1
+# HELP openstack_cinder_volumes Number of volumes.
+# TYPE openstack_cinder_volumes gauge
+openstack_cinder_volumes{status="available"} 1
+openstack_cinder_volumes{status="in-use"} 2
+# HELP openstack_cinder_volumes_size_bytes Total size of volumes.
+# TYPE openstack_cinder_volumes_size_bytes gauge
+openstack_cinder_volumes_size_bytes{status="available"} 1.073741824e+09
+openstack_cinder_volumes_size_bytes{status="in-use"} 1.610612736e+10
+# HELP openstack_neutron_agent_enabled Whether the Neutron agent is administratively up.
+# TYPE openstack_neutron_agent_enabled gauge
+openstack_neutron_agent_enabled{agent_type="DHCP agent",host="network-1"} 1
+openstack_neutron_agent_enabled{agent_type="Open vSwitch agent",host="network-1"} 1
+# HELP openstack_neutron_agent_up Whether the Neutron agent is alive.
+# TYPE openstack_neutron_agent_up gauge
+openstack_neutron_agent_up{agent_type="DHCP agent",host="network-1"} 0
+openstack_neutron_agent_up{agent_type="Open vSwitch agent",host="network-1"} 1
+# HELP openstack_neutron_floating_ips Number of floating IPs.
+# TYPE openstack_neutron_floating_ips gauge
+openstack_neutron_floating_ips{associated="false"} 2
+openstack_neutron_floating_ips{associated="true"} 1
+# HELP openstack_neutron_networks Number of networks.
+# TYPE openstack_neutron_networks gauge
+openstack_neutron_networks 3
+# HELP openstack_neutron_routers Number of routers.
+# TYPE openstack_neutron_routers gauge
+openstack_neutron_routers{status="ACTIVE"} 1
+# HELP openstack_nova_hypervisor_local_storage_bytes Local storage of the hypervisor.
+# TYPE openstack_nova_hypervisor_local_storage_bytes gauge
+openstack_nova_hypervisor_local_storage_bytes{hypervisor="compute-1"} 1.073741824e+11
+# HELP openstack_nova_hypervisor_local_storage_used_bytes Local storage of the hypervisor in use.
+# TYPE openstack_nova_hypervisor_local_storage_used_bytes gauge
+openstack_nova_hypervisor_local_storage_used_bytes{hypervisor="compute-1"} 1.073741824e+10
+# HELP openstack_nova_hypervisor_memory_bytes Memory of the hypervisor.
+# TYPE openstack_nova_hypervisor_memory_bytes gauge
+openstack_nova_hypervisor_memory_bytes{hypervisor="compute-1"} 1.073741824e+09
+# HELP openstack_nova_hypervisor_memory_used_bytes Memory of the hypervisor in use.
+# TYPE openstack_nova_hypervisor_memory_used_bytes gauge
+openstack_nova_hypervisor_memory_used_bytes{hypervisor="compute-1"} 5.36870912e+08
+# HELP openstack_nova_hypervisor_running_vms Number of VMs running on the hypervisor.
+# TYPE openstack_nova_hypervisor_running_vms gauge
+openstack_nova_hypervisor_running_vms{hypervisor="compute-1"} 2
+# HELP openstack_nova_hypervisor_up Whether the hypervisor is up.
+# TYPE openstack_nova_hypervisor_up gauge
+openstack_nova_hypervisor_up{hypervisor="compute-1"} 1
+# HELP openstack_nova_hypervisor_vcpus Number of vCPUs of the hypervisor.
+# TYPE openstack_nova_hypervisor_vcpus gauge
+openstack_nova_hypervisor_vcpus{hypervisor="compute-1"} 16
+# HELP openstack_nova_hypervisor_vcpus_used Number of vCPUs of the hypervisor in use.
+# TYPE openstack_nova_hypervisor_vcpus_used gauge
+openstack_nova_hypervisor_vcpus_used{hypervisor="compute-1"} 4
+# HELP openstack_nova_servers Number of servers.
+# TYPE openstack_nova_servers gauge
+openstack_nova_servers{status="ACTIVE"} 2
+openstack_nova_servers{status="SHUTOFF"} 1
+# HELP openstack_nova_service_enabled Whether the Nova service is enabled.
+# TYPE openstack_nova_service_enabled gauge
+openstack_nova_service_enabled{binary="nova-compute",host="compute-1",zone="nova"} 1
+openstack_nova_service_enabled{binary="nova-compute",host="compute-2",zone="nova"} 0
+# HELP openstack_nova_service_up Whether the Nova service is up.
+# TYPE openstack_nova_service_up gauge
+openstack_nova_service_up{binary="nova-compute",host="compute-1",zone="nova"} 1
+openstack_nova_service_up{binary="nova-compute",host="compute-2",zone="nova"} 0
+# HELP openstack_service_up Whether metrics of the OpenStack service were collected successfully.
+# TYPE openstack_service_up gauge
+openstack_service_up{service="cinder"} 1
+openstack_service_up{service="neutron"} 1
+openstack_service_up{service="nova"} 1
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+}
+
+func TestCollector_AuthFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+auth_url: `+srv.URL+`/identity/v3
+username: admin
+password: wrong
+project_name: admin
+services: [nova]
+`), &cfg))
+
+	col, err := newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+
+	expect := `
+# HELP openstack_service_up Whether metrics of the OpenStack service were collected successfully.
+# TYPE openstack_service_up gauge
+openstack_service_up{service="nova"} 0
+`
+	require.NoError(t, testutil.CollectAndCompare(col, strings.NewReader(expect)))
+}
+
+func TestConfig_Validation(t *testing.T) {
+	tt := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "missing auth_url",
+			config: "username: admin\nproject_name: admin",
+			err:    "auth_url must be set",
+		},
+		{
+			name:   "both credentials",
+			config: "auth_url: http://keystone:5000/v3\nusername: admin\napplication_credential_id: abc",
+			err:    "at most one of application_credential_id and username must be set",
+		},
+		{
+			name:   "missing project",
+			config: "auth_url: http://keystone:5000/v3\nusername: admin",
+			err:    "project_name must be set for password authentication",
+		},
+		{
+			name:   "unknown service",
+			config: "auth_url: http://keystone:5000/v3\napplication_credential_id: abc\nservices: [swift]",
+			err:    `unsupported service "swift"`,
+		},
+		{
+			name:   "application credential",
+			config: "auth_url: http://keystone:5000/v3\napplication_credential_id: abc\nregion: RegionOne",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			err := yaml.Unmarshal([]byte(tc.config), &cfg)
+			if tc.err != "" {
+				require.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			key, err := cfg.InstanceKey("agent")
+			require.NoError(t, err)
+			require.Equal(t, "keystone:5000/RegionOne", key)
+		})
+	}
+}
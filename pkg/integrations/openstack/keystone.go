@@ -0,0 +1,204 @@
+package openstack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Service types of the Keystone catalog used by the integration.
+const (
+	serviceTypeCompute = "compute"
+	serviceTypeNetwork = "network"
+	serviceTypeVolume  = "volumev3"
+)
+
+// tokenExpiryMargin is how long before its expiry a token is replaced.
+const tokenExpiryMargin = time.Minute
+
+type catalogEntry struct {
+	Type      string `json:"type"`
+	Endpoints []struct {
+		Interface string `json:"interface"`
+		Region    string `json:"region"`
+		URL       string `json:"url"`
+	} `json:"endpoints"`
+}
+
+// keystoneClient authenticates against Keystone and performs requests
+// against the endpoints of the service catalog.
+type keystoneClient struct {
+	client *http.Client
+	cfg    *Config
+
+	mut     sync.Mutex
+	token   string
+	expires time.Time
+	catalog []catalogEntry
+}
+
+// authenticate requests a new token from Keystone.
+func (k *keystoneClient) authenticate(ctx context.Context) error {
+	var identity map[string]interface{}
+	if k.cfg.ApplicationCredentialID != "" {
+		identity = map[string]interface{}{
+			"methods": []string{"application_credential"},
+			"application_credential": map[string]string{
+				"id":     k.cfg.ApplicationCredentialID,
+				"secret": string(k.cfg.ApplicationCredentialSecret),
+			},
+		}
+	} else {
+		identity = map[string]interface{}{
+			"methods": []string{"password"},
+			"password": map[string]interface{}{
+				"user": map[string]interface{}{
+					"name":     k.cfg.Username,
+					"domain":   map[string]string{"name": k.cfg.UserDomainName},
+					"password": string(k.cfg.Password),
+				},
+			},
+		}
+	}
+	auth := map[string]interface{}{"identity": identity}
+	if k.cfg.ApplicationCredentialID == "" {
+		// Application credentials are always scoped to their project.
+		auth["scope"] = map[string]interface{}{
+			"project": map[string]interface{}{
+				"name":   k.cfg.ProjectName,
+				"domain": map[string]string{"name": k.cfg.ProjectDomainName},
+			},
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"auth": auth})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(k.cfg.AuthURL, "/")+"/auth/tokens", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("keystone authentication failed with status code %d", resp.StatusCode)
+	}
+
+	var res struct {
+		Token struct {
+			ExpiresAt time.Time      `json:"expires_at"`
+			Catalog   []catalogEntry `json:"catalog"`
+		} `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("failed to parse keystone response: %w", err)
+	}
+
+	k.token = resp.Header.Get("X-Subject-Token")
+	k.expires = res.Token.ExpiresAt
+	k.catalog = res.Token.Catalog
+	return nil
+}
+
+// session returns a valid token and the current catalog, authenticating if
+// needed.
+func (k *keystoneClient) session(ctx context.Context) (string, []catalogEntry, error) {
+	k.mut.Lock()
+	defer k.mut.Unlock()
+
+	if k.token == "" || time.Now().Add(tokenExpiryMargin).After(k.expires) {
+		if err := k.authenticate(ctx); err != nil {
+			return "", nil, err
+		}
+	}
+	return k.token, k.catalog, nil
+}
+
+// invalidate discards the current token.
+func (k *keystoneClient) invalidate() {
+	k.mut.Lock()
+	defer k.mut.Unlock()
+	k.token = ""
+}
+
+// endpoint returns the URL of serviceType from catalog.
+func (k *keystoneClient) endpoint(catalog []catalogEntry, serviceType string) (string, error) {
+	for _, e := range catalog {
+		if e.Type != serviceType {
+			continue
+		}
+		for _, ep := range e.Endpoints {
+			if ep.Interface != k.cfg.Interface {
+				continue
+			}
+			if k.cfg.Region != "" && ep.Region != k.cfg.Region {
+				continue
+			}
+			u := strings.TrimSuffix(ep.URL, "/")
+			if serviceType == serviceTypeNetwork {
+				// Neutron endpoints may or may not include the API version,
+				// which is always part of the requested paths.
+				u = strings.TrimSuffix(u, "/v2.0")
+			}
+			return u, nil
+		}
+	}
+	return "", fmt.Errorf("no %s endpoint for service %s found in catalog", k.cfg.Interface, serviceType)
+}
+
+// get requests path from the endpoint of serviceType and decodes the JSON
+// response into v. path may also be an absolute URL, as returned in links
+// for pagination.
+func (k *keystoneClient) get(ctx context.Context, serviceType, path string, v interface{}) error {
+	token, catalog, err := k.session(ctx)
+	if err != nil {
+		return err
+	}
+
+	uri := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		base, err := k.endpoint(catalog, serviceType)
+		if err != nil {
+			return err
+		}
+		uri = base + path
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Auth-Token", token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		// The token may have been revoked; authenticate again on the next
+		// request.
+		k.invalidate()
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d for %s", resp.StatusCode, req.URL.Path)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse response for %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
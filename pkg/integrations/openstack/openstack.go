@@ -0,0 +1,147 @@
+// Package openstack implements an integration which collects metrics about
+// the state of an OpenStack cloud from the Nova, Neutron, and Cinder APIs,
+// using Keystone for authentication and service discovery.
+package openstack
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/common"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+)
+
+// Services which metrics can be collected for.
+const (
+	ServiceNova    = "nova"
+	ServiceNeutron = "neutron"
+	ServiceCinder  = "cinder"
+)
+
+// DefaultConfig holds the default settings for the openstack integration.
+var DefaultConfig = Config{
+	UserDomainName:    "Default",
+	ProjectDomainName: "Default",
+	Interface:         "public",
+	Services:          []string{ServiceNova, ServiceNeutron, ServiceCinder},
+	Timeout:           30 * time.Second,
+}
+
+// Config controls the openstack integration.
+type Config struct {
+	// AuthURL is the URL of the Keystone v3 API.
+	AuthURL string `yaml:"auth_url,omitempty"`
+
+	// Username, password, and project used for password authentication.
+	Username          string             `yaml:"username,omitempty"`
+	UserDomainName    string             `yaml:"user_domain_name,omitempty"`
+	Password          config_util.Secret `yaml:"password,omitempty"`
+	ProjectName       string             `yaml:"project_name,omitempty"`
+	ProjectDomainName string             `yaml:"project_domain_name,omitempty"`
+
+	// Application credential used instead of password authentication.
+	ApplicationCredentialID     string             `yaml:"application_credential_id,omitempty"`
+	ApplicationCredentialSecret config_util.Secret `yaml:"application_credential_secret,omitempty"`
+
+	// Region of the endpoints to use. The first endpoint found is used if
+	// empty.
+	Region string `yaml:"region,omitempty"`
+	// Interface of the endpoints to use: public, internal, or admin.
+	Interface string `yaml:"interface,omitempty"`
+	// Services to collect metrics for.
+	Services []string `yaml:"services,omitempty"`
+	// Timeout for collecting metrics.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// TLSConfig configures TLS for requests to OpenStack.
+	TLSConfig config_util.TLSConfig `yaml:"tls_config,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.AuthURL == "" {
+		return fmt.Errorf("auth_url must be set")
+	}
+	if _, err := url.Parse(c.AuthURL); err != nil {
+		return fmt.Errorf("invalid auth_url: %w", err)
+	}
+
+	switch {
+	case c.ApplicationCredentialID != "" && c.Username != "":
+		return fmt.Errorf("at most one of application_credential_id and username must be set")
+	case c.ApplicationCredentialID == "" && c.Username == "":
+		return fmt.Errorf("one of application_credential_id and username must be set")
+	case c.Username != "" && c.ProjectName == "":
+		return fmt.Errorf("project_name must be set for password authentication")
+	}
+
+	switch c.Interface {
+	case "public", "internal", "admin":
+	default:
+		return fmt.Errorf("unsupported interface %q", c.Interface)
+	}
+
+	for _, s := range c.Services {
+		switch s {
+		case ServiceNova, ServiceNeutron, ServiceCinder:
+		default:
+			return fmt.Errorf("unsupported service %q", s)
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "openstack"
+}
+
+// InstanceKey returns the host:port of Keystone, followed by the region if
+// one is configured.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	u, err := url.Parse(c.AuthURL)
+	if err != nil {
+		return "", fmt.Errorf("could not parse url: %w", err)
+	}
+	if c.Region != "" {
+		return u.Host + "/" + c.Region, nil
+	}
+	return u.Host, nil
+}
+
+// ScrapeLimits returns the limits of autoscrape settings for the integration.
+// Collecting metrics may take up to timeout.
+func (c *Config) ScrapeLimits() common.ScrapeLimits {
+	return common.ScrapeLimits{MinScrapeTimeout: model.Duration(c.Timeout)}
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
+
+// New creates a new openstack integration.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	col, err := newCollector(logger, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(c.Name(), integrations.WithCollectors(col)), nil
+}
@@ -0,0 +1,202 @@
+package oracledb_exporter //nolint:golint
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "oracledb"
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// collector runs the configured metric queries against the database on every
+// scrape.
+type collector struct {
+	log     log.Logger
+	db      *sql.DB
+	metrics []Metric
+	timeout time.Duration
+
+	up             *prometheus.Desc
+	scrapeDuration *prometheus.Desc
+	scrapeError    *prometheus.Desc
+}
+
+func newCollector(l log.Logger, db *sql.DB, metrics []Metric, timeout time.Duration) *collector {
+	return &collector{
+		log:     l,
+		db:      db,
+		metrics: metrics,
+		timeout: timeout,
+
+		up: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "up"),
+			"Whether the Oracle database server is up.",
+			nil, nil,
+		),
+		scrapeDuration: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "last_scrape_duration_seconds"),
+			"Duration of the last scrape of metrics from Oracle DB.",
+			nil, nil,
+		),
+		scrapeError: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "exporter", "last_scrape_error"),
+			"Whether the last scrape of metrics from Oracle DB resulted in an error (1 for error, 0 for success).",
+			nil, nil,
+		),
+	}
+}
+
+// run waits for ctx to be canceled and closes the database afterwards.
+func (c *collector) run(ctx context.Context) error {
+	<-ctx.Done()
+	return c.db.Close()
+}
+
+// Describe implements prometheus.Collector. The set of metrics is dynamic, so
+// the collector is unchecked and no descriptors are sent.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	start := time.Now()
+
+	var scrapeError float64
+	defer func() {
+		ch <- prometheus.MustNewConstMetric(c.scrapeDuration, prometheus.GaugeValue, time.Since(start).Seconds())
+		ch <- prometheus.MustNewConstMetric(c.scrapeError, prometheus.GaugeValue, scrapeError)
+	}()
+
+	pingCtx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	if err := c.db.PingContext(pingCtx); err != nil {
+		level.Error(c.log).Log("msg", "failed to ping oracle database", "err", err)
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		scrapeError = 1
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+
+	for _, m := range c.metrics {
+		if err := c.collectMetric(ch, m); err != nil {
+			level.Error(c.log).Log("msg", "failed to collect metric", "context", m.Context, "err", err)
+			scrapeError = 1
+		}
+	}
+}
+
+func (c *collector) collectMetric(ch chan<- prometheus.Metric, m Metric) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx, m.Request)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	var found bool
+	for rows.Next() {
+		values := make([]sql.NullString, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+
+		row := make(map[string]string, len(cols))
+		for i, col := range cols {
+			row[strings.ToLower(col)] = values[i].String
+		}
+
+		metrics, err := rowMetrics(m, row)
+		if err != nil {
+			return err
+		}
+		for _, metric := range metrics {
+			ch <- metric
+		}
+		found = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if !found && !m.IgnoreZeroResult {
+		return fmt.Errorf("query returned no rows")
+	}
+	return nil
+}
+
+// rowMetrics converts a single result row into metrics based on m. Column
+// names in row must be lowercase.
+func rowMetrics(m Metric, row map[string]string) ([]prometheus.Metric, error) {
+	var (
+		labelNames  = make([]string, 0, len(m.Labels))
+		labelValues = make([]string, 0, len(m.Labels))
+	)
+	for _, l := range m.Labels {
+		l = strings.ToLower(l)
+		labelNames = append(labelNames, l)
+		labelValues = append(labelValues, row[l])
+	}
+
+	res := make([]prometheus.Metric, 0, len(m.MetricsDesc))
+	for col, help := range m.MetricsDesc {
+		raw, ok := row[strings.ToLower(col)]
+		if !ok {
+			return nil, fmt.Errorf("column %q not found in query result", col)
+		}
+		value, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return nil, fmt.Errorf("column %q has non-numeric value %q", col, raw)
+		}
+
+		name := col
+		if m.FieldToAppend != "" {
+			name = row[strings.ToLower(m.FieldToAppend)]
+		}
+		name = cleanName(name)
+
+		valueType := prometheus.GaugeValue
+		if strings.EqualFold(m.MetricsType[col], "counter") {
+			valueType = prometheus.CounterValue
+		}
+
+		desc := prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, cleanName(m.Context), name),
+			help,
+			labelNames, nil,
+		)
+		metric, err := prometheus.NewConstMetric(desc, valueType, value, labelValues...)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, metric)
+	}
+	return res, nil
+}
+
+// cleanName converts s into a valid Prometheus metric name component.
+func cleanName(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.ReplaceAll(s, " ", "_")
+	s = invalidNameChars.ReplaceAllString(s, "")
+	return strings.ToLower(s)
+}
@@ -0,0 +1,122 @@
+package oracledb_exporter //nolint:golint
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pelletier/go-toml"
+)
+
+// Metric describes a query that is run against the database and how to
+// convert its result rows into Prometheus metrics. Each column named in
+// MetricsDesc becomes a metric named oracledb_<context>_<column>; columns
+// named in Labels become labels.
+type Metric struct {
+	Context          string            `toml:"context"`
+	Labels           []string          `toml:"labels"`
+	MetricsDesc      map[string]string `toml:"metricsdesc"`
+	MetricsType      map[string]string `toml:"metricstype"`
+	FieldToAppend    string            `toml:"fieldtoappend"`
+	Request          string            `toml:"request"`
+	IgnoreZeroResult bool              `toml:"ignorezeroresult"`
+}
+
+// Validate ensures that m can be collected.
+func (m *Metric) Validate() error {
+	if m.Context == "" {
+		return fmt.Errorf("metric is missing a context")
+	}
+	if m.Request == "" {
+		return fmt.Errorf("metric %q is missing a request", m.Context)
+	}
+	if len(m.MetricsDesc) == 0 {
+		return fmt.Errorf("metric %q has no metricsdesc", m.Context)
+	}
+	for col, ty := range m.MetricsType {
+		if _, ok := m.MetricsDesc[col]; !ok {
+			return fmt.Errorf("metric %q has a metricstype for unknown column %q", m.Context, col)
+		}
+		switch strings.ToLower(ty) {
+		case "gauge", "counter":
+		default:
+			return fmt.Errorf("metric %q has unsupported metricstype %q for column %q", m.Context, ty, col)
+		}
+	}
+	return nil
+}
+
+type metricsFile struct {
+	Metric []Metric `toml:"metric"`
+}
+
+// LoadMetricsFile loads a set of metrics from a TOML file at path.
+func LoadMetricsFile(path string) ([]Metric, error) {
+	bb, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMetrics(bb)
+}
+
+// ParseMetrics parses a set of metrics from TOML.
+func ParseMetrics(bb []byte) ([]Metric, error) {
+	var f metricsFile
+	if err := toml.Unmarshal(bb, &f); err != nil {
+		return nil, err
+	}
+	for i := range f.Metric {
+		if err := f.Metric[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return f.Metric, nil
+}
+
+// DefaultMetrics are collected unless disable_default_metrics is set.
+var DefaultMetrics = []Metric{
+	{
+		Context:     "sessions",
+		Labels:      []string{"status", "type"},
+		MetricsDesc: map[string]string{"value": "Gauge metric with count of sessions by status and type."},
+		Request:     "SELECT status, type, COUNT(*) as value FROM v$session GROUP BY status, type",
+	},
+	{
+		Context: "resource",
+		Labels:  []string{"resource_name"},
+		MetricsDesc: map[string]string{
+			"current_utilization": "Generic counter metric from v$resource_limit view in Oracle (current value).",
+			"limit_value":         "Generic counter metric from v$resource_limit view in Oracle (UNLIMITED: -1).",
+		},
+		Request: "SELECT resource_name, current_utilization, CASE WHEN TRIM(limit_value) LIKE 'UNLIMITED' THEN '-1' ELSE TRIM(limit_value) END as limit_value FROM v$resource_limit",
+	},
+	{
+		Context: "activity",
+		MetricsDesc: map[string]string{
+			"value": "Generic counter metric from v$sysstat view in Oracle.",
+		},
+		FieldToAppend: "name",
+		Request:       "SELECT name, value FROM v$sysstat WHERE name IN ('parse count (total)', 'execute count', 'user commits', 'user rollbacks')",
+	},
+	{
+		Context:     "process",
+		MetricsDesc: map[string]string{"count": "Gauge metric with count of processes."},
+		Request:     "SELECT COUNT(*) as count FROM v$process",
+	},
+	{
+		Context:       "wait_time",
+		MetricsDesc:   map[string]string{"value": "Generic counter metric from v$waitclassmetric view in Oracle."},
+		FieldToAppend: "wait_class",
+		Request:       "SELECT n.wait_class as wait_class, round(m.time_waited/m.intsize_csec, 3) as value FROM v$waitclassmetric m, v$system_wait_class n WHERE m.wait_class_id = n.wait_class_id AND n.wait_class != 'Idle'",
+	},
+	{
+		Context: "tablespace",
+		Labels:  []string{"tablespace", "type"},
+		MetricsDesc: map[string]string{
+			"bytes":     "Generic counter metric of tablespaces bytes in Oracle.",
+			"max_bytes": "Generic counter metric of tablespaces max bytes in Oracle.",
+			"free":      "Generic counter metric of tablespaces free bytes in Oracle.",
+		},
+		Request: "SELECT dt.tablespace_name as tablespace, dt.contents as type, dt.block_size * dtum.used_space as bytes, dt.block_size * dtum.tablespace_size as max_bytes, dt.block_size * (dtum.tablespace_size - dtum.used_space) as free FROM dba_tablespace_usage_metrics dtum, dba_tablespaces dt WHERE dtum.tablespace_name = dt.tablespace_name",
+	},
+}
@@ -0,0 +1,91 @@
+package process_exporter //nolint:golint
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	common "github.com/ncabatoff/process-exporter"
+)
+
+// containerIDRegexp matches the container ID in the last element of a cgroup
+// path, such as "cri-containerd-<id>.scope", "docker-<id>.scope" or "<id>".
+var containerIDRegexp = regexp.MustCompile(`(?:^|[-:])([0-9a-f]{64})(?:\.scope)?$`)
+
+// cgroupNamer wraps a MatchNamer and appends the cgroup of matched processes
+// to their group name, so processes matched by the same rule are grouped
+// separately per cgroup or container.
+type cgroupNamer struct {
+	common.MatchNamer
+
+	procFSPath string
+	groupBy    string
+}
+
+// MatchAndName implements common.MatchNamer. Processes whose cgroup can't be
+// determined keep the name given by the wrapped MatchNamer.
+func (n *cgroupNamer) MatchAndName(attrs common.ProcAttributes) (bool, string) {
+	ok, name := n.MatchNamer.MatchAndName(attrs)
+	if !ok {
+		return ok, name
+	}
+
+	// The process may have exited since it was listed; ignore errors.
+	path, err := readCgroupPath(filepath.Join(n.procFSPath, strconv.Itoa(attrs.PID), "cgroup"))
+	if err != nil {
+		return ok, name
+	}
+
+	var group string
+	switch n.groupBy {
+	case GroupByCgroupPath:
+		group = path
+	case GroupByCgroupContainerID:
+		group = containerID(path)
+	}
+	if group == "" {
+		return ok, name
+	}
+	return ok, name + ":" + group
+}
+
+// readCgroupPath returns the cgroup path from a /proc/<pid>/cgroup file. The
+// path of the cgroup v2 unified hierarchy is preferred, falling back to the
+// first cgroup v1 hierarchy on systems without cgroup v2.
+func readCgroupPath(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var fallback string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Lines are formatted as hierarchy-ID:controller-list:cgroup-path.
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			return parts[2], nil
+		}
+		if fallback == "" {
+			fallback = parts[2]
+		}
+	}
+	return fallback, scanner.Err()
+}
+
+// containerID returns the ID of the container from a cgroup path. An empty
+// string is returned for processes which aren't running in a container.
+func containerID(path string) string {
+	m := containerIDRegexp.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
@@ -0,0 +1,68 @@
+package process_exporter //nolint:golint
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	common "github.com/ncabatoff/process-exporter"
+	"github.com/stretchr/testify/require"
+)
+
+const testContainerID = "0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func TestContainerID(t *testing.T) {
+	tt := []struct {
+		path   string
+		expect string
+	}{
+		{"/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod1234.slice/cri-containerd-" + testContainerID + ".scope", testContainerID},
+		{"/system.slice/docker-" + testContainerID + ".scope", testContainerID},
+		{"/kubepods/besteffort/pod1234/" + testContainerID, testContainerID},
+		{"/kubepods-besteffort-pod1234.slice:cri-containerd:" + testContainerID, testContainerID},
+		{"/system.slice/sshd.service", ""},
+		{"/", ""},
+	}
+	for _, tc := range tt {
+		require.Equal(t, tc.expect, containerID(tc.path), tc.path)
+	}
+}
+
+func TestCgroupNamer(t *testing.T) {
+	procFS := t.TempDir()
+	writeCgroup := func(pid int, contents string) {
+		dir := filepath.Join(procFS, strconv.Itoa(pid))
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "cgroup"), []byte(contents), 0644))
+	}
+
+	containerPath := "/kubepods.slice/kubepods-pod1234.slice/cri-containerd-" + testContainerID + ".scope"
+	writeCgroup(1, "0::"+containerPath+"\n")
+	writeCgroup(2, "12:pids:/system.slice/sshd.service\n1:name=systemd:/system.slice/sshd.service\n")
+
+	tt := []struct {
+		groupBy string
+		pid     int
+		expect  string
+	}{
+		{GroupByCgroupPath, 1, "nginx:" + containerPath},
+		{GroupByCgroupContainerID, 1, "nginx:" + testContainerID},
+		{GroupByCgroupPath, 2, "nginx:/system.slice/sshd.service"},
+		{GroupByCgroupContainerID, 2, "nginx"},
+		{GroupByCgroupPath, 3, "nginx"},
+	}
+	for _, tc := range tt {
+		n := &cgroupNamer{MatchNamer: staticNamer("nginx"), procFSPath: procFS, groupBy: tc.groupBy}
+		ok, name := n.MatchAndName(common.ProcAttributes{PID: tc.pid})
+		require.True(t, ok)
+		require.Equal(t, tc.expect, name, "group_by_cgroup %s, pid %d", tc.groupBy, tc.pid)
+	}
+}
+
+// staticNamer is a common.MatchNamer which matches every process.
+type staticNamer string
+
+func (n staticNamer) MatchAndName(common.ProcAttributes) (bool, string) { return true, string(n) }
+func (n staticNamer) String() string                                     { return string(n) }
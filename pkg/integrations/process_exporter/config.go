@@ -2,8 +2,12 @@
 package process_exporter //nolint:golint
 
 import (
+	"fmt"
+
 	"github.com/go-kit/log"
 	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
 
 	exporter_config "github.com/ncabatoff/process-exporter/config"
 )
@@ -17,6 +21,15 @@ var DefaultConfig = Config{
 	Recheck:    false,
 }
 
+// Supported values for Config.GroupByCgroup.
+const (
+	// GroupByCgroupPath groups processes by their cgroup v2 path.
+	GroupByCgroupPath = "path"
+	// GroupByCgroupContainerID groups processes by the ID of the container they
+	// run in.
+	GroupByCgroupContainerID = "container_id"
+)
+
 // Config controls the process_exporter integration.
 type Config struct {
 	ProcessExporter exporter_config.MatcherRules `yaml:"process_names,omitempty"`
@@ -26,6 +39,8 @@ type Config struct {
 	Threads    bool   `yaml:"track_threads,omitempty"`
 	SMaps      bool   `yaml:"gather_smaps,omitempty"`
 	Recheck    bool   `yaml:"recheck_on_scrape,omitempty"`
+
+	GroupByCgroup string `yaml:"group_by_cgroup,omitempty"`
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
@@ -33,7 +48,16 @@ func (c *Config) UnmarshalYAML(unmarshal func(v interface{}) error) error {
 	*c = DefaultConfig
 
 	type plain Config
-	return unmarshal((*plain)(c))
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	switch c.GroupByCgroup {
+	case "", GroupByCgroupPath, GroupByCgroupContainerID:
+	default:
+		return fmt.Errorf("unsupported group_by_cgroup %q, must be one of %q or %q", c.GroupByCgroup, GroupByCgroupPath, GroupByCgroupContainerID)
+	}
+	return nil
 }
 
 // Name returns the name of the integration that this config represents.
@@ -53,4 +77,5 @@ func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error)
 
 func init() {
 	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeEither, metricsutils.CreateShim)
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/version"
 
+	common "github.com/ncabatoff/process-exporter"
 	"github.com/ncabatoff/process-exporter/collector"
 )
 
@@ -30,12 +31,17 @@ func New(logger log.Logger, c *Config) (*Integration, error) {
 		return nil, fmt.Errorf("process_names is invalid: %w", err)
 	}
 
+	var namer common.MatchNamer = cfg.MatchNamers
+	if c.GroupByCgroup != "" {
+		namer = &cgroupNamer{MatchNamer: namer, procFSPath: c.ProcFSPath, groupBy: c.GroupByCgroup}
+	}
+
 	pc, err := collector.NewProcessCollector(collector.ProcessCollectorOption{
 		ProcFSPath:  c.ProcFSPath,
 		Children:    c.Children,
 		Threads:     c.Threads,
 		GatherSMaps: c.SMaps,
-		Namer:       cfg.MatchNamers,
+		Namer:       namer,
 		Recheck:     c.Recheck,
 		Debug:       false,
 	})
@@ -0,0 +1,73 @@
+package script_exporter //nolint:golint
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Integration is the script_exporter integration. Metrics of scripts and
+// textfiles are merged with metrics reporting the executions of scripts.
+type Integration struct {
+	c         *Config
+	scripts   *scripts
+	gatherers prometheus.Gatherers
+}
+
+// New creates a new script_exporter integration. Scripts may only execute
+// commands matching allowedCommands.
+func New(logger log.Logger, c *Config, allowedCommands config.CommandPatterns) (*Integration, error) {
+	for _, sc := range c.Scripts {
+		if !allowedCommands.Allowed(sc.Command) {
+			return nil, fmt.Errorf("script %s: command %q is not allowed by -integrations.script-exporter.allowed-commands", sc.Name, sc.Command)
+		}
+	}
+
+	s := newScripts(logger, c.Scripts)
+
+	r := prometheus.NewRegistry()
+	if err := r.Register(s); err != nil {
+		return nil, fmt.Errorf("couldn't register scripts collector: %w", err)
+	}
+
+	return &Integration{
+		c:       c,
+		scripts: s,
+		gatherers: prometheus.Gatherers{
+			r,
+			s,
+			&textfiles{log: logger, directories: c.TextfileDirectories},
+		},
+	}, nil
+}
+
+// MetricsHandler satisfies Integration.RegisterRoutes.
+func (i *Integration) MetricsHandler() (http.Handler, error) {
+	return promhttp.HandlerFor(
+		i.gatherers,
+		promhttp.HandlerOpts{
+			ErrorHandling: promhttp.ContinueOnError,
+		},
+	), nil
+}
+
+// ScrapeConfigs satisfies Integration.ScrapeConfigs.
+func (i *Integration) ScrapeConfigs() []config.ScrapeConfig {
+	return []config.ScrapeConfig{{
+		JobName:     i.c.Name(),
+		MetricsPath: "/metrics",
+	}}
+}
+
+// Run satisfies Integration.Run. Scripts are executed on their interval until
+// ctx is canceled.
+func (i *Integration) Run(ctx context.Context) error {
+	i.scripts.run(ctx)
+	<-ctx.Done()
+	return ctx.Err()
+}
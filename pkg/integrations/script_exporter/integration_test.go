@@ -0,0 +1,116 @@
+package script_exporter //nolint:golint
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations/config"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, path, content string, perm os.FileMode) {
+	t.Helper()
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), perm))
+}
+
+func scrape(t *testing.T, i *Integration) string {
+	t.Helper()
+
+	h, err := i.MetricsHandler()
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := ioutil.ReadAll(rec.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestIntegration(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test scripts require a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	ok := filepath.Join(dir, "ok.sh")
+	writeFile(t, ok, `#!/bin/sh
+echo '# HELP backup_size_bytes Size of the last backup.'
+echo '# TYPE backup_size_bytes gauge'
+echo "backup_size_bytes{target=\"$1\"} 1024"
+`, 0755)
+	fail := filepath.Join(dir, "fail.sh")
+	writeFile(t, fail, "#!/bin/sh\necho 'disk not mounted' >&2\nexit 1\n", 0755)
+	slow := filepath.Join(dir, "slow.sh")
+	writeFile(t, slow, "#!/bin/sh\nexec sleep 10\n", 0755)
+
+	textfiles := filepath.Join(dir, "textfiles")
+	require.NoError(t, os.Mkdir(textfiles, 0755))
+	writeFile(t, filepath.Join(textfiles, "cron.prom"), "cron_last_success_timestamp_seconds 1640995200\n", 0644)
+	writeFile(t, filepath.Join(textfiles, "ignored.txt"), "ignored 1\n", 0644)
+
+	c := &Config{
+		Scripts: []ScriptConfig{
+			{Name: "ok", Command: ok, Args: []string{"db"}, Interval: time.Minute, Timeout: time.Second},
+			{Name: "fail", Command: fail, Interval: time.Minute, Timeout: time.Second},
+			{Name: "slow", Command: slow, Interval: time.Minute, Timeout: 100 * time.Millisecond},
+		},
+		TextfileDirectories: []string{textfiles},
+	}
+	i, err := New(log.NewNopLogger(), c, config.CommandPatterns{filepath.Join(dir, "*")})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = i.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	require.Eventually(t, func() bool {
+		return strings.Contains(scrape(t, i), `script_exporter_script_success{script="slow"}`)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	body := scrape(t, i)
+	expect := []string{
+		`backup_size_bytes{target="db"} 1024`,
+		`cron_last_success_timestamp_seconds 1.6409952e+09`,
+		`script_exporter_script_success{script="fail"} 0`,
+		`script_exporter_script_success{script="ok"} 1`,
+		`script_exporter_script_success{script="slow"} 0`,
+		`script_exporter_textfile_scrape_error 0`,
+		`script_exporter_textfile_mtime_seconds{file="` + filepath.Join(textfiles, "cron.prom") + `"}`,
+	}
+	for _, e := range expect {
+		require.Contains(t, body, e)
+	}
+	require.NotContains(t, body, "ignored")
+}
+
+func TestTextfiles_Errors(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.prom"), "a_total 1\n", 0644)
+	writeFile(t, filepath.Join(dir, "b.prom"), "b_total{ 1\n", 0644)
+	writeFile(t, filepath.Join(dir, "c.prom"), "c_total 1 1640995200000\n", 0644)
+
+	i, err := New(log.NewNopLogger(), &Config{TextfileDirectories: []string{dir}}, nil)
+	require.NoError(t, err)
+
+	body := scrape(t, i)
+	require.Contains(t, body, "a_total 1")
+	require.NotContains(t, body, "b_total")
+	require.NotContains(t, body, "c_total")
+	require.Contains(t, body, "script_exporter_textfile_scrape_error 1")
+}
@@ -0,0 +1,131 @@
+// Package script_exporter implements an integration which collects metrics
+// from user-defined scripts executed on a schedule and from node_exporter
+// style textfile directories. Scripts and textfiles must output metrics in
+// the Prometheus text exposition format.
+package script_exporter //nolint:golint
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+)
+
+// DefaultConfig holds the default settings for the script_exporter
+// integration.
+var DefaultConfig = Config{}
+
+// DefaultScriptConfig holds the default settings for a script.
+var DefaultScriptConfig = ScriptConfig{
+	Interval: time.Minute,
+}
+
+// Config controls the script_exporter integration.
+type Config struct {
+	// Scripts to execute.
+	Scripts []ScriptConfig `yaml:"scripts,omitempty"`
+	// TextfileDirectories are directories to read *.prom files from.
+	TextfileDirectories []string `yaml:"textfile_directories,omitempty"`
+}
+
+// ScriptConfig configures a script executed on a schedule.
+type ScriptConfig struct {
+	// Name of the script, used as the script label of the metrics reporting
+	// its executions.
+	Name string `yaml:"name"`
+	// Command is the absolute path of the executable to run.
+	Command string `yaml:"command"`
+	// Args are passed to the command.
+	Args []string `yaml:"args,omitempty"`
+	// Interval between executions of the script.
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// Timeout after which the script is killed. Defaults to Interval.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if len(c.Scripts) == 0 && len(c.TextfileDirectories) == 0 {
+		return fmt.Errorf("at least one of scripts and textfile_directories must be set")
+	}
+
+	names := make(map[string]struct{}, len(c.Scripts))
+	for _, s := range c.Scripts {
+		if _, ok := names[s.Name]; ok {
+			return fmt.Errorf("duplicate script %q", s.Name)
+		}
+		names[s.Name] = struct{}{}
+	}
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for ScriptConfig.
+func (c *ScriptConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultScriptConfig
+
+	type plain ScriptConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.Name == "" {
+		return fmt.Errorf("scripts must have a name")
+	}
+	if !filepath.IsAbs(c.Command) {
+		return fmt.Errorf("script %s: command must be an absolute path", c.Name)
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("script %s: interval must be greater than zero", c.Name)
+	}
+	if c.Timeout == 0 {
+		c.Timeout = c.Interval
+	}
+	if c.Timeout < 0 || c.Timeout > c.Interval {
+		return fmt.Errorf("script %s: timeout must be greater than zero and not exceed interval", c.Name)
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "script_exporter"
+}
+
+// InstanceKey returns the agentKey, as scripts and textfiles are read from
+// the machine the agent is running on.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	return agentKey, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+// No commands are allowed, so it fails if any scripts are configured.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c, nil)
+}
+
+// NewIntegrationWithFlags converts this config into an instance of an
+// integration. Scripts may only execute the commands allowed by
+// -integrations.script-exporter.allowed-commands. The allowed commands are
+// set with a command line flag rather than the config so that whoever can
+// change the config, e.g. through remote configs or the config management
+// API, can't run arbitrary commands.
+func (c *Config) NewIntegrationWithFlags(l log.Logger, f config.Flags) (integrations.Integration, error) {
+	return New(l, c, f.ScriptExporterAllowedCommands)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeSingleton, metricsutils.CreateShim)
+}
@@ -0,0 +1,119 @@
+package script_exporter //nolint:golint
+
+import (
+	"flag"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	"github.com/grafana/agent/pkg/integrations/config"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	cfg := `
+scripts:
+  - name: backup
+    command: /opt/scripts/backup.sh
+    interval: 5m
+`
+	var c Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(cfg), &c))
+	require.Equal(t, []ScriptConfig{{
+		Name:     "backup",
+		Command:  "/opt/scripts/backup.sh",
+		Interval: 5 * time.Minute,
+		Timeout:  5 * time.Minute,
+	}}, c.Scripts)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tt := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "nothing to collect",
+			config: "{}",
+			err:    "at least one of scripts and textfile_directories must be set",
+		},
+		{
+			name: "relative command",
+			config: `
+scripts:
+  - {name: s, command: s.sh}`,
+			err: "script s: command must be an absolute path",
+		},
+		{
+			name: "timeout exceeds interval",
+			config: `
+scripts:
+  - {name: s, command: /opt/scripts/s.sh, interval: 10s, timeout: 1m}`,
+			err: "script s: timeout must be greater than zero and not exceed interval",
+		},
+		{
+			name: "duplicate script",
+			config: `
+scripts:
+  - {name: s, command: /opt/scripts/a.sh}
+  - {name: s, command: /opt/scripts/b.sh}`,
+			err: `duplicate script "s"`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var c Config
+			require.EqualError(t, yaml.Unmarshal([]byte(tc.config), &c), tc.err)
+		})
+	}
+}
+
+// TestConfig_AllowedCommandsFromConfig ensures the config can't allow
+// commands.
+func TestConfig_AllowedCommandsFromConfig(t *testing.T) {
+	cfg := `
+allowed_commands: ['*', /usr/bin/*]
+scripts:
+  - {name: s, command: /usr/bin/curl}`
+
+	var c Config
+	require.Error(t, yaml.UnmarshalStrict([]byte(cfg), &c))
+}
+
+func TestConfig_NewIntegrationWithFlags(t *testing.T) {
+	cfg := `
+scripts:
+  - {name: s, command: /usr/bin/curl}`
+
+	var c Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(cfg), &c))
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var f config.Flags
+	f.RegisterFlags(fs)
+	require.NoError(t, fs.Parse([]string{"-integrations.script-exporter.allowed-commands", "/opt/scripts/*"}))
+
+	_, err := integrations.NewIntegration(&c, log.NewNopLogger(), f)
+	require.EqualError(t, err, `script s: command "/usr/bin/curl" is not allowed by -integrations.script-exporter.allowed-commands`)
+
+	_, err = c.NewIntegration(log.NewNopLogger())
+	require.EqualError(t, err, `script s: command "/usr/bin/curl" is not allowed by -integrations.script-exporter.allowed-commands`)
+
+	require.NoError(t, fs.Parse([]string{"-integrations.script-exporter.allowed-commands", "/usr/bin/*"}))
+	_, err = integrations.NewIntegration(&c, log.NewNopLogger(), f)
+	require.NoError(t, err)
+}
+
+func TestFlags_InvalidPattern(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var f config.Flags
+	f.RegisterFlags(fs)
+
+	err := fs.Parse([]string{"-integrations.script-exporter.allowed-commands", "/opt/[scripts"})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid pattern "/opt/[scripts"`)
+}
@@ -0,0 +1,190 @@
+package script_exporter //nolint:golint
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+var (
+	scriptSuccessDesc = prometheus.NewDesc(
+		"script_exporter_script_success",
+		"Whether the last execution of the script was successful.",
+		[]string{"script"}, nil,
+	)
+	scriptDurationDesc = prometheus.NewDesc(
+		"script_exporter_script_duration_seconds",
+		"Duration of the last execution of the script.",
+		[]string{"script"}, nil,
+	)
+	scriptLastRunDesc = prometheus.NewDesc(
+		"script_exporter_script_last_run_timestamp_seconds",
+		"Timestamp of the last execution of the script.",
+		[]string{"script"}, nil,
+	)
+)
+
+// scriptResult holds the result of the last execution of a script.
+type scriptResult struct {
+	families []*dto.MetricFamily
+	success  bool
+	duration time.Duration
+	lastRun  time.Time
+}
+
+// scripts executes scripts on a schedule and caches the metrics of their last
+// successful execution.
+type scripts struct {
+	log     log.Logger
+	configs []ScriptConfig
+
+	mut     sync.RWMutex
+	results map[string]scriptResult
+}
+
+func newScripts(l log.Logger, configs []ScriptConfig) *scripts {
+	return &scripts{
+		log:     l,
+		configs: configs,
+		results: make(map[string]scriptResult, len(configs)),
+	}
+}
+
+// run executes every script on its interval until ctx is canceled.
+func (s *scripts) run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, sc := range s.configs {
+		wg.Add(1)
+		go func(sc ScriptConfig) {
+			defer wg.Done()
+
+			t := time.NewTicker(sc.Interval)
+			defer t.Stop()
+			for {
+				s.execute(ctx, sc)
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+				}
+			}
+		}(sc)
+	}
+	wg.Wait()
+}
+
+// execute runs sc once and stores its result.
+func (s *scripts) execute(ctx context.Context, sc ScriptConfig) {
+	start := time.Now()
+	families, err := runScript(ctx, sc)
+	res := scriptResult{
+		families: families,
+		success:  err == nil,
+		duration: time.Since(start),
+		lastRun:  start,
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		level.Error(s.log).Log("msg", "failed to execute script", "script", sc.Name, "err", err)
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.results[sc.Name] = res
+}
+
+// runScript runs sc and parses its output.
+func runScript(ctx context.Context, sc ScriptConfig) ([]*dto.MetricFamily, error) {
+	ctx, cancel := context.WithTimeout(ctx, sc.Timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, sc.Command, sc.Args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("script timed out after %s", sc.Timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+	return parseMetrics(&stdout)
+}
+
+// parseMetrics parses metrics in the text exposition format. Metrics with
+// explicit timestamps are rejected.
+func parseMetrics(r io.Reader) ([]*dto.MetricFamily, error) {
+	var parser expfmt.TextParser
+	parsed, err := parser.TextToMetricFamilies(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+
+	families := make([]*dto.MetricFamily, 0, len(parsed))
+	for _, mf := range parsed {
+		for _, m := range mf.Metric {
+			if m.TimestampMs != nil {
+				return nil, fmt.Errorf("metric %s has an explicit timestamp, which is not supported", mf.GetName())
+			}
+		}
+		families = append(families, mf)
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].GetName() < families[j].GetName() })
+	return families, nil
+}
+
+// Gather implements prometheus.Gatherer, returning the metrics of the last
+// execution of every script.
+func (s *scripts) Gather() ([]*dto.MetricFamily, error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	var res []*dto.MetricFamily
+	for _, sc := range s.configs {
+		res = append(res, s.results[sc.Name].families...)
+	}
+	return res, nil
+}
+
+// Describe implements prometheus.Collector.
+func (s *scripts) Describe(ch chan<- *prometheus.Desc) {
+	ch <- scriptSuccessDesc
+	ch <- scriptDurationDesc
+	ch <- scriptLastRunDesc
+}
+
+// Collect implements prometheus.Collector, reporting the executions of
+// scripts.
+func (s *scripts) Collect(ch chan<- prometheus.Metric) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	for name, res := range s.results {
+		success := 0.0
+		if res.success {
+			success = 1
+		}
+		ch <- prometheus.MustNewConstMetric(scriptSuccessDesc, prometheus.GaugeValue, success, name)
+		ch <- prometheus.MustNewConstMetric(scriptDurationDesc, prometheus.GaugeValue, res.duration.Seconds(), name)
+		ch <- prometheus.MustNewConstMetric(scriptLastRunDesc, prometheus.GaugeValue, float64(res.lastRun.UnixNano())/1e9, name)
+	}
+}
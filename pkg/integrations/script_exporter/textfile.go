@@ -0,0 +1,102 @@
+package script_exporter //nolint:golint
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	dto "github.com/prometheus/client_model/go"
+)
+
+const (
+	textfileMtimeName = "script_exporter_textfile_mtime_seconds"
+	textfileMtimeHelp = "Unix time of the last modification of the textfile."
+
+	textfileErrorName = "script_exporter_textfile_scrape_error"
+	textfileErrorHelp = "Whether an error occurred reading or parsing a textfile."
+)
+
+// textfiles reads metrics from *.prom files in directories when gathered,
+// like the textfile collector of node_exporter.
+type textfiles struct {
+	log         log.Logger
+	directories []string
+}
+
+// Gather implements prometheus.Gatherer.
+func (t *textfiles) Gather() ([]*dto.MetricFamily, error) {
+	var (
+		res     []*dto.MetricFamily
+		mtimes  []*dto.Metric
+		errored bool
+	)
+
+	for _, dir := range t.directories {
+		paths, err := filepath.Glob(filepath.Join(dir, "*.prom"))
+		if err != nil {
+			level.Error(t.log).Log("msg", "failed to list textfiles", "directory", dir, "err", err)
+			errored = true
+			continue
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			families, info, err := readTextfile(path)
+			if err != nil {
+				level.Error(t.log).Log("msg", "failed to read textfile", "file", path, "err", err)
+				errored = true
+				continue
+			}
+			res = append(res, families...)
+			mtime := float64(info.ModTime().UnixNano()) / 1e9
+			mtimes = append(mtimes, &dto.Metric{
+				Label: []*dto.LabelPair{{Name: strPtr("file"), Value: strPtr(path)}},
+				Gauge: &dto.Gauge{Value: &mtime},
+			})
+		}
+	}
+
+	if len(mtimes) > 0 {
+		res = append(res, gaugeFamily(textfileMtimeName, textfileMtimeHelp, mtimes...))
+	}
+	errorValue := 0.0
+	if errored {
+		errorValue = 1
+	}
+	res = append(res, gaugeFamily(textfileErrorName, textfileErrorHelp, &dto.Metric{
+		Gauge: &dto.Gauge{Value: &errorValue},
+	}))
+	return res, nil
+}
+
+// readTextfile parses the metrics of the textfile at path.
+func readTextfile(path string) ([]*dto.MetricFamily, os.FileInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	families, err := parseMetrics(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return families, info, nil
+}
+
+func gaugeFamily(name, help string, metrics ...*dto.Metric) *dto.MetricFamily {
+	return &dto.MetricFamily{
+		Name:   strPtr(name),
+		Help:   strPtr(help),
+		Type:   dto.MetricType_GAUGE.Enum(),
+		Metric: metrics,
+	}
+}
+
+func strPtr(s string) *string { return &s }
@@ -0,0 +1,141 @@
+package smartctl
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "smartctl"
+
+func deviceDesc(name, help string, labels ...string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "device", name), help, append([]string{"device"}, labels...), nil)
+}
+
+var (
+	devicesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "devices"),
+		"Number of monitored devices.",
+		nil, nil,
+	)
+
+	infoDesc                 = deviceDesc("info", "Information about the device.", "type", "protocol", "model_family", "model_name", "serial_number", "firmware_version")
+	collectSuccessDesc       = deviceDesc("collect_success", "Whether the last attempt to read SMART data of the device succeeded.")
+	lastCollectDesc          = deviceDesc("last_collect_timestamp_seconds", "Timestamp of the last successful read of SMART data of the device.")
+	exitStatusDesc           = deviceDesc("exit_status", "Exit status of smartctl for the device.")
+	capacityDesc             = deviceDesc("capacity_bytes", "Capacity of the device.")
+	smartStatusDesc          = deviceDesc("smart_status_passed", "Whether the SMART overall-health self-assessment test passed.")
+	temperatureDesc          = deviceDesc("temperature_celsius", "Current temperature of the device.")
+	powerOnDesc              = deviceDesc("power_on_seconds", "Time the device has been powered on.")
+	powerCycleDesc           = deviceDesc("power_cycle_count", "Number of power cycles of the device.")
+	selfTestPassedDesc       = deviceDesc("self_test_passed", "Whether the most recent self-test passed.")
+	reallocatedSectorsDesc   = deviceDesc("reallocated_sectors", "Number of reallocated sectors.")
+	pendingSectorsDesc       = deviceDesc("pending_sectors", "Number of sectors waiting to be remapped.")
+	offlineUncorrectableDesc = deviceDesc("offline_uncorrectable_sectors", "Number of uncorrectable errors found during offline scans.")
+	attributeDesc            = deviceDesc("attribute", "Value of a SMART attribute.", "attribute_id", "attribute_name", "attribute_value_type")
+	nvmeCriticalWarningDesc  = deviceDesc("nvme_critical_warning", "Critical warning bitmask of the NVMe device.")
+	nvmeAvailableSpareDesc   = deviceDesc("nvme_available_spare_percent", "Remaining spare capacity of the NVMe device.")
+	nvmePercentageUsedDesc   = deviceDesc("nvme_percentage_used", "Vendor specific estimate of the used life of the NVMe device.")
+	nvmeMediaErrorsDesc      = deviceDesc("nvme_media_errors_total", "Number of unrecovered data integrity errors of the NVMe device.")
+	nvmeUnsafeShutdownsDesc  = deviceDesc("nvme_unsafe_shutdowns_total", "Number of unsafe shutdowns of the NVMe device.")
+)
+
+// Describe implements prometheus.Collector.
+func (m *monitor) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		devicesDesc, infoDesc, collectSuccessDesc, lastCollectDesc, exitStatusDesc,
+		capacityDesc, smartStatusDesc, temperatureDesc, powerOnDesc, powerCycleDesc,
+		selfTestPassedDesc, reallocatedSectorsDesc, pendingSectorsDesc,
+		offlineUncorrectableDesc, attributeDesc, nvmeCriticalWarningDesc,
+		nvmeAvailableSpareDesc, nvmePercentageUsedDesc, nvmeMediaErrorsDesc,
+		nvmeUnsafeShutdownsDesc,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector. Metrics are generated from the
+// data most recently read by the monitor; smartctl isn't invoked during
+// collection.
+func (m *monitor) Collect(ch chan<- prometheus.Metric) {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+
+	ch <- prometheus.MustNewConstMetric(devicesDesc, prometheus.GaugeValue, float64(len(m.devices)))
+
+	for _, d := range m.devices {
+		state, ok := m.states[d.Name]
+		if !ok {
+			continue
+		}
+
+		gauge := func(desc *prometheus.Desc, v float64, labels ...string) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, append([]string{d.Name}, labels...)...)
+		}
+		counter := func(desc *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v, d.Name)
+		}
+
+		gauge(collectSuccessDesc, boolValue(state.success))
+		if state.output == nil {
+			continue
+		}
+		out := state.output
+
+		gauge(lastCollectDesc, float64(state.lastCollect.UnixNano())/1e9)
+		gauge(exitStatusDesc, float64(out.Smartctl.ExitStatus))
+		gauge(infoDesc, 1, out.Device.Type, out.Device.Protocol, out.ModelFamily, out.ModelName, out.SerialNumber, out.FirmwareVersion)
+
+		if out.UserCapacity.Bytes > 0 {
+			gauge(capacityDesc, out.UserCapacity.Bytes)
+		}
+		if out.SmartStatus != nil {
+			gauge(smartStatusDesc, boolValue(out.SmartStatus.Passed))
+		}
+		if out.Temperature.Current != nil {
+			gauge(temperatureDesc, *out.Temperature.Current)
+		}
+		if out.PowerOnTime.Hours != nil {
+			counter(powerOnDesc, *out.PowerOnTime.Hours*3600)
+		}
+		if out.PowerCycleCount != nil {
+			counter(powerCycleDesc, *out.PowerCycleCount)
+		}
+		if passed, ok := out.selfTestPassed(); ok {
+			gauge(selfTestPassedDesc, boolValue(passed))
+		}
+
+		for id, desc := range map[int]*prometheus.Desc{
+			attrReallocatedSectors: reallocatedSectorsDesc,
+			attrPendingSectors:     pendingSectorsDesc,
+			attrOfflineUncorrect:   offlineUncorrectableDesc,
+		} {
+			if a, ok := out.attribute(id); ok {
+				gauge(desc, a.Raw.Value)
+			}
+		}
+
+		for _, a := range out.ATASmartAttributes.Table {
+			id := strconv.Itoa(a.ID)
+			gauge(attributeDesc, a.Value, id, a.Name, "value")
+			gauge(attributeDesc, a.Worst, id, a.Name, "worst")
+			gauge(attributeDesc, a.Thresh, id, a.Name, "thresh")
+			gauge(attributeDesc, a.Raw.Value, id, a.Name, "raw")
+		}
+
+		if nvme := out.NVMeSmartHealthInformationLog; nvme != nil {
+			gauge(nvmeCriticalWarningDesc, nvme.CriticalWarning)
+			gauge(nvmeAvailableSpareDesc, nvme.AvailableSpare)
+			gauge(nvmePercentageUsedDesc, nvme.PercentageUsed)
+			counter(nvmeMediaErrorsDesc, nvme.MediaErrors)
+			counter(nvmeUnsafeShutdownsDesc, nvme.UnsafeShutdowns)
+		}
+	}
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,227 @@
+package smartctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// runFunc invokes smartctl with the given arguments and returns its output.
+type runFunc func(ctx context.Context, args ...string) ([]byte, error)
+
+// execSmartctl returns a runFunc which executes the smartctl binary at path.
+func execSmartctl(path string) runFunc {
+	return func(ctx context.Context, args ...string) ([]byte, error) {
+		out, err := exec.CommandContext(ctx, path, args...).Output()
+
+		// smartctl uses its exit status as a bitmask describing the state of
+		// the device. The JSON output is still valid when it is non-zero and
+		// contains the exit status itself.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(out) > 0 {
+			return out, nil
+		}
+		return out, err
+	}
+}
+
+// scanDevice is a device found by smartctl --scan-open.
+type scanDevice struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+type scanOutput struct {
+	Devices []scanDevice `json:"devices"`
+}
+
+// deviceState is the latest information read for a device.
+type deviceState struct {
+	output      *smartctlOutput
+	success     bool
+	lastCollect time.Time
+}
+
+// monitor periodically reads SMART data for all monitored devices and
+// exposes the most recent data as metrics.
+type monitor struct {
+	log log.Logger
+	cfg *Config
+	run runFunc
+
+	include, exclude *regexp.Regexp
+
+	mut      sync.RWMutex
+	devices  []scanDevice
+	states   map[string]*deviceState
+	lastScan time.Time
+}
+
+func newMonitor(l log.Logger, c *Config, run runFunc) (*monitor, error) {
+	m := &monitor{
+		log:    l,
+		cfg:    c,
+		run:    run,
+		states: make(map[string]*deviceState),
+	}
+
+	var err error
+	if c.DeviceInclude != "" {
+		if m.include, err = regexp.Compile(c.DeviceInclude); err != nil {
+			return nil, fmt.Errorf("invalid device_include: %w", err)
+		}
+	}
+	if c.DeviceExclude != "" {
+		if m.exclude, err = regexp.Compile(c.DeviceExclude); err != nil {
+			return nil, fmt.Errorf("invalid device_exclude: %w", err)
+		}
+	}
+
+	for _, name := range c.Devices {
+		m.devices = append(m.devices, scanDevice{Name: name, Type: c.DeviceTypeHints[name]})
+	}
+	return m, nil
+}
+
+// Run reads SMART data every interval until ctx is canceled.
+func (m *monitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		m.refresh(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// refresh discovers devices if needed and reads SMART data of every device.
+func (m *monitor) refresh(ctx context.Context) {
+	if len(m.cfg.Devices) == 0 && time.Since(m.lastScan) >= m.cfg.RescanInterval {
+		devices, err := m.scan(ctx)
+		if err != nil {
+			level.Error(m.log).Log("msg", "failed to discover devices", "err", err)
+		} else {
+			m.setDevices(devices)
+			m.lastScan = time.Now()
+		}
+	}
+
+	m.mut.RLock()
+	devices := m.devices
+	m.mut.RUnlock()
+
+	for _, d := range devices {
+		if ctx.Err() != nil {
+			return
+		}
+		m.readDevice(ctx, d)
+	}
+}
+
+// scan discovers devices with smartctl --scan-open.
+func (m *monitor) scan(ctx context.Context) ([]scanDevice, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+
+	out, err := m.run(ctx, "--json", "--scan-open")
+	if err != nil {
+		return nil, err
+	}
+
+	var scan scanOutput
+	if err := json.Unmarshal(out, &scan); err != nil {
+		return nil, fmt.Errorf("failed to parse smartctl scan output: %w", err)
+	}
+
+	devices := make([]scanDevice, 0, len(scan.Devices))
+	for _, d := range scan.Devices {
+		if m.include != nil && !m.include.MatchString(d.Name) {
+			continue
+		}
+		if m.exclude != nil && m.exclude.MatchString(d.Name) {
+			continue
+		}
+		if hint, ok := m.cfg.DeviceTypeHints[d.Name]; ok {
+			d.Type = hint
+		}
+		devices = append(devices, d)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].Name < devices[j].Name })
+	return devices, nil
+}
+
+// setDevices replaces the set of monitored devices, dropping the state of
+// devices which disappeared.
+func (m *monitor) setDevices(devices []scanDevice) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.devices = devices
+
+	keep := make(map[string]struct{}, len(devices))
+	for _, d := range devices {
+		keep[d.Name] = struct{}{}
+	}
+	for name := range m.states {
+		if _, ok := keep[name]; !ok {
+			delete(m.states, name)
+		}
+	}
+}
+
+// readDevice reads the SMART data of a single device. Data previously read
+// is kept when the device is in standby or couldn't be read.
+func (m *monitor) readDevice(ctx context.Context, d scanDevice) {
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+
+	args := []string{"--json", "--info", "--health", "--attributes", "--log=selftest", "--tolerance=verypermissive"}
+	if !m.cfg.IncludeStandby {
+		args = append(args, "--nocheck=standby")
+	}
+	if d.Type != "" {
+		args = append(args, "--device="+d.Type)
+	}
+	args = append(args, d.Name)
+
+	out, err := m.run(ctx, args...)
+
+	var parsed smartctlOutput
+	if err == nil {
+		err = json.Unmarshal(out, &parsed)
+	}
+	if err == nil && parsed.SmartStatus == nil && parsed.Smartctl.ExitStatus&exitDeviceOpenFailed != 0 {
+		err = fmt.Errorf("device could not be opened or is in standby: %s", parsed.Smartctl.message())
+	}
+
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	state, ok := m.states[d.Name]
+	if !ok {
+		state = &deviceState{}
+		m.states[d.Name] = state
+	}
+	if err != nil {
+		level.Warn(m.log).Log("msg", "failed to read SMART data", "device", d.Name, "err", err)
+		state.success = false
+		return
+	}
+	state.output = &parsed
+	state.success = true
+	state.lastCollect = time.Now()
+}
@@ -0,0 +1,133 @@
+package smartctl
+
+import "strings"
+
+// Bits of the smartctl exit status.
+const (
+	exitDeviceOpenFailed = 1 << 1
+)
+
+// smartctlOutput is the subset of the JSON output of smartctl used by the
+// integration.
+type smartctlOutput struct {
+	Smartctl smartctlInfo `json:"smartctl"`
+
+	Device struct {
+		Name     string `json:"name"`
+		Type     string `json:"type"`
+		Protocol string `json:"protocol"`
+	} `json:"device"`
+	ModelFamily     string `json:"model_family"`
+	ModelName       string `json:"model_name"`
+	SerialNumber    string `json:"serial_number"`
+	FirmwareVersion string `json:"firmware_version"`
+	UserCapacity    struct {
+		Bytes float64 `json:"bytes"`
+	} `json:"user_capacity"`
+
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature struct {
+		Current *float64 `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime struct {
+		Hours *float64 `json:"hours"`
+	} `json:"power_on_time"`
+	PowerCycleCount *float64 `json:"power_cycle_count"`
+
+	ATASmartAttributes struct {
+		Table []ataAttribute `json:"table"`
+	} `json:"ata_smart_attributes"`
+	ATASmartData struct {
+		SelfTest struct {
+			Status struct {
+				Passed *bool `json:"passed"`
+			} `json:"status"`
+		} `json:"self_test"`
+	} `json:"ata_smart_data"`
+	ATASmartSelfTestLog struct {
+		Standard struct {
+			Table []struct {
+				Status struct {
+					Passed *bool `json:"passed"`
+				} `json:"status"`
+			} `json:"table"`
+		} `json:"standard"`
+	} `json:"ata_smart_self_test_log"`
+
+	NVMeSmartHealthInformationLog *struct {
+		CriticalWarning float64 `json:"critical_warning"`
+		AvailableSpare  float64 `json:"available_spare"`
+		PercentageUsed  float64 `json:"percentage_used"`
+		MediaErrors     float64 `json:"media_errors"`
+		UnsafeShutdowns float64 `json:"unsafe_shutdowns"`
+	} `json:"nvme_smart_health_information_log"`
+	NVMeSelfTestLog struct {
+		Table []struct {
+			SelfTestResult struct {
+				Value int `json:"value"`
+			} `json:"self_test_result"`
+		} `json:"table"`
+	} `json:"nvme_self_test_log"`
+}
+
+type smartctlInfo struct {
+	ExitStatus int `json:"exit_status"`
+	Messages   []struct {
+		String string `json:"string"`
+	} `json:"messages"`
+}
+
+// message returns all messages reported by smartctl.
+func (i smartctlInfo) message() string {
+	msgs := make([]string, 0, len(i.Messages))
+	for _, m := range i.Messages {
+		msgs = append(msgs, m.String)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+type ataAttribute struct {
+	ID     int     `json:"id"`
+	Name   string  `json:"name"`
+	Value  float64 `json:"value"`
+	Worst  float64 `json:"worst"`
+	Thresh float64 `json:"thresh"`
+	Raw    struct {
+		Value float64 `json:"value"`
+	} `json:"raw"`
+}
+
+// ATA attribute IDs with dedicated metrics.
+const (
+	attrReallocatedSectors = 5
+	attrPendingSectors     = 197
+	attrOfflineUncorrect   = 198
+)
+
+// attribute returns the ATA attribute with the given ID.
+func (o *smartctlOutput) attribute(id int) (ataAttribute, bool) {
+	for _, a := range o.ATASmartAttributes.Table {
+		if a.ID == id {
+			return a, true
+		}
+	}
+	return ataAttribute{}, false
+}
+
+// selfTestPassed returns whether the most recent self-test passed. ok is
+// false if no self-test result is available.
+func (o *smartctlOutput) selfTestPassed() (passed, ok bool) {
+	if table := o.ATASmartSelfTestLog.Standard.Table; len(table) > 0 && table[0].Status.Passed != nil {
+		return *table[0].Status.Passed, true
+	}
+	if p := o.ATASmartData.SelfTest.Status.Passed; p != nil {
+		return *p, true
+	}
+	if table := o.NVMeSelfTestLog.Table; len(table) > 0 {
+		// A result of 0 means the operation completed without error.
+		return table[0].SelfTestResult.Value == 0, true
+	}
+	return false, false
+}
@@ -0,0 +1,104 @@
+// Package smartctl implements an integration which collects SMART disk
+// health metrics by periodically invoking smartctl.
+package smartctl
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+)
+
+// DefaultConfig holds the default settings for the smartctl integration.
+var DefaultConfig = Config{
+	SmartctlPath:   "smartctl",
+	Interval:       60 * time.Second,
+	Timeout:        30 * time.Second,
+	RescanInterval: 10 * time.Minute,
+}
+
+// Config controls the smartctl integration.
+type Config struct {
+	// SmartctlPath is the path to the smartctl binary. smartctl 7.0 or newer
+	// is required for JSON output.
+	SmartctlPath string `yaml:"smartctl_path,omitempty"`
+	// Interval between invocations of smartctl for each device.
+	Interval time.Duration `yaml:"interval,omitempty"`
+	// Timeout for a single invocation of smartctl.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// Devices is a static list of devices to monitor. Devices are discovered
+	// with smartctl --scan-open when empty.
+	Devices []string `yaml:"devices,omitempty"`
+	// DeviceTypeHints maps devices to the device type passed to smartctl
+	// with -d, e.g., sat or megaraid,0.
+	DeviceTypeHints map[string]string `yaml:"device_type_hints,omitempty"`
+	// RescanInterval is how often devices are discovered again.
+	RescanInterval time.Duration `yaml:"rescan_interval,omitempty"`
+	// DeviceInclude is a regular expression of discovered devices to monitor.
+	DeviceInclude string `yaml:"device_include,omitempty"`
+	// DeviceExclude is a regular expression of discovered devices to ignore.
+	DeviceExclude string `yaml:"device_exclude,omitempty"`
+
+	// IncludeStandby wakes up devices in standby to read their data. When
+	// false, devices in standby are skipped and keep their last values.
+	IncludeStandby bool `yaml:"include_standby,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.DeviceInclude != "" && c.DeviceExclude != "" {
+		return fmt.Errorf("at most one of device_include and device_exclude must be set")
+	}
+	for _, expr := range []string{c.DeviceInclude, c.DeviceExclude} {
+		if _, err := regexp.Compile(expr); err != nil {
+			return fmt.Errorf("invalid device filter %q: %w", expr, err)
+		}
+	}
+	if c.Interval <= 0 {
+		return fmt.Errorf("interval must be greater than 0")
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "smartctl"
+}
+
+// InstanceKey returns the hostname:port of the agent process.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	return agentKey, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+}
+
+// New creates a new smartctl integration.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	m, err := newMonitor(logger, c, execSmartctl(c.SmartctlPath))
+	if err != nil {
+		return nil, err
+	}
+
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(m),
+		integrations.WithRunner(m.Run),
+	), nil
+}
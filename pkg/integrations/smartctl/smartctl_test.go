@@ -0,0 +1,157 @@
+package smartctl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+var smartctlOutputs = map[string]string{
+	"scan": `{"devices": [
+		{"name": "/dev/sda", "type": "sat", "protocol": "ATA"},
+		{"name": "/dev/sdb", "type": "sat", "protocol": "ATA"},
+		{"name": "/dev/nvme0", "type": "nvme", "protocol": "NVMe"}
+	]}`,
+	"/dev/sda": `{
+		"smartctl": {"exit_status": 0},
+		"device": {"name": "/dev/sda", "type": "sat", "protocol": "ATA"},
+		"model_family": "Seagate IronWolf",
+		"model_name": "ST4000VN008-2DR166",
+		"serial_number": "ZDH1234",
+		"firmware_version": "SC60",
+		"user_capacity": {"bytes": 4000787030016},
+		"smart_status": {"passed": true},
+		"temperature": {"current": 34},
+		"power_on_time": {"hours": 100},
+		"power_cycle_count": 12,
+		"ata_smart_attributes": {"table": [
+			{"id": 5, "name": "Reallocated_Sector_Ct", "value": 100, "worst": 100, "thresh": 10, "raw": {"value": 8}},
+			{"id": 197, "name": "Current_Pending_Sector", "value": 100, "worst": 100, "thresh": 0, "raw": {"value": 1}}
+		]},
+		"ata_smart_self_test_log": {"standard": {"table": [{"status": {"passed": false}}]}}
+	}`,
+	"/dev/sdb": `{
+		"smartctl": {"exit_status": 2, "messages": [{"string": "Device is in STANDBY mode, exit(2)"}]},
+		"device": {"name": "/dev/sdb", "type": "sat", "protocol": "ATA"}
+	}`,
+	"/dev/nvme0": `{
+		"smartctl": {"exit_status": 0},
+		"device": {"name": "/dev/nvme0", "type": "nvme", "protocol": "NVMe"},
+		"model_name": "Samsung SSD 970 EVO 1TB",
+		"smart_status": {"passed": true},
+		"temperature": {"current": 41},
+		"nvme_smart_health_information_log": {"critical_warning": 0, "available_spare": 100, "percentage_used": 3, "media_errors": 0, "unsafe_shutdowns": 7},
+		"nvme_self_test_log": {"table": [{"self_test_result": {"value": 0}}]}
+	}`,
+}
+
+func fakeSmartctl(calls *[][]string) runFunc {
+	return func(ctx context.Context, args ...string) ([]byte, error) {
+		*calls = append(*calls, args)
+
+		key := args[len(args)-1]
+		if key == "--scan-open" {
+			key = "scan"
+		}
+		out, ok := smartctlOutputs[key]
+		if !ok {
+			return nil, fmt.Errorf("unknown device %s", key)
+		}
+		return []byte(out), nil
+	}
+}
+
+func newTestMonitor(t *testing.T, cfg string, calls *[][]string) *monitor {
+	t.Helper()
+
+	var c Config
+	require.NoError(t, yaml.Unmarshal([]byte(cfg), &c))
+	m, err := newMonitor(log.NewNopLogger(), &c, fakeSmartctl(calls))
+	require.NoError(t, err)
+	return m
+}
+
+func TestMonitor(t *testing.T) {
+	var calls [][]string
+	m := newTestMonitor(t, `device_exclude: ^/dev/sdc$`, &calls)
+	m.refresh(context.Background())
+
+	require.Equal(t, []string{"--json", "--scan-open"}, calls[0])
+	require.Equal(t, []string{
+		"--json", "--info", "--health", "--attributes", "--log=selftest", "--tolerance=verypermissive",
+		"--nocheck=standby", "--device=nvme", "/dev/nvme0",
+	}, calls[1])
+
+	expect := `
+# HELP smartctl_device_collect_success Whether the last attempt to read SMART data of the device succeeded.
+# TYPE smartctl_device_collect_success gauge
+smartctl_device_collect_success{device="/dev/nvme0"} 1
+smartctl_device_collect_success{device="/dev/sda"} 1
+smartctl_device_collect_success{device="/dev/sdb"} 0
+# HELP smartctl_device_nvme_unsafe_shutdowns_total Number of unsafe shutdowns of the NVMe device.
+# TYPE smartctl_device_nvme_unsafe_shutdowns_total counter
+smartctl_device_nvme_unsafe_shutdowns_total{device="/dev/nvme0"} 7
+# HELP smartctl_device_pending_sectors Number of sectors waiting to be remapped.
+# TYPE smartctl_device_pending_sectors gauge
+smartctl_device_pending_sectors{device="/dev/sda"} 1
+# HELP smartctl_device_power_on_seconds Time the device has been powered on.
+# TYPE smartctl_device_power_on_seconds counter
+smartctl_device_power_on_seconds{device="/dev/sda"} 360000
+# HELP smartctl_device_reallocated_sectors Number of reallocated sectors.
+# TYPE smartctl_device_reallocated_sectors gauge
+smartctl_device_reallocated_sectors{device="/dev/sda"} 8
+# HELP smartctl_device_self_test_passed Whether the most recent self-test passed.
+# TYPE smartctl_device_self_test_passed gauge
+smartctl_device_self_test_passed{device="/dev/nvme0"} 1
+smartctl_device_self_test_passed{device="/dev/sda"} 0
+# HELP smartctl_device_temperature_celsius Current temperature of the device.
+# TYPE smartctl_device_temperature_celsius gauge
+smartctl_device_temperature_celsius{device="/dev/nvme0"} 41
+smartctl_device_temperature_celsius{device="/dev/sda"} 34
+# HELP smartctl_devices Number of monitored devices.
+# TYPE smartctl_devices gauge
+smartctl_devices 3
+`
+	err := testutil.CollectAndCompare(m, strings.NewReader(expect),
+		"smartctl_devices", "smartctl_device_collect_success", "smartctl_device_temperature_celsius",
+		"smartctl_device_reallocated_sectors", "smartctl_device_pending_sectors", "smartctl_device_self_test_passed",
+		"smartctl_device_power_on_seconds", "smartctl_device_nvme_unsafe_shutdowns_total")
+	require.NoError(t, err)
+}
+
+func TestMonitor_StaticDevices(t *testing.T) {
+	var calls [][]string
+	m := newTestMonitor(t, `
+devices: [/dev/sda]
+device_type_hints:
+  /dev/sda: sat
+include_standby: true
+`, &calls)
+	m.refresh(context.Background())
+
+	require.Equal(t, [][]string{{
+		"--json", "--info", "--health", "--attributes", "--log=selftest", "--tolerance=verypermissive",
+		"--device=sat", "/dev/sda",
+	}}, calls)
+}
+
+func TestMonitor_DeviceInclude(t *testing.T) {
+	var calls [][]string
+	m := newTestMonitor(t, `device_include: ^/dev/nvme`, &calls)
+
+	devices, err := m.scan(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []scanDevice{{Name: "/dev/nvme0", Type: "nvme"}}, devices)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	var c Config
+	err := yaml.Unmarshal([]byte(`{device_include: a, device_exclude: b}`), &c)
+	require.EqualError(t, err, "at most one of device_include and device_exclude must be set")
+}
@@ -0,0 +1,294 @@
+package solr
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "solr"
+
+// metricPrefixes limits the metrics returned by the metrics API to the ones
+// used by the collector.
+var metricPrefixes = []string{"CORE.", "INDEX.sizeInBytes", "SEARCHER.searcher.", "QUERY.", "UPDATE."}
+
+// metricsResponse is the response of the metrics API. Metrics are grouped by
+// registry, which is named solr.core.<core> for cores.
+type metricsResponse struct {
+	Metrics map[string]map[string]json.RawMessage `json:"metrics"`
+}
+
+// filter matches names against anchored include and exclude regular
+// expressions.
+type filter struct {
+	include, exclude *regexp.Regexp
+}
+
+func newFilter(name, include, exclude string) (filter, error) {
+	var (
+		f   filter
+		err error
+	)
+	if include != "" {
+		if f.include, err = regexp.Compile("^(?:" + include + ")$"); err != nil {
+			return f, fmt.Errorf("invalid %s_include: %w", name, err)
+		}
+	}
+	if exclude != "" {
+		if f.exclude, err = regexp.Compile("^(?:" + exclude + ")$"); err != nil {
+			return f, fmt.Errorf("invalid %s_exclude: %w", name, err)
+		}
+	}
+	return f, nil
+}
+
+func (f filter) matches(s string) bool {
+	if f.include != nil && !f.include.MatchString(s) {
+		return false
+	}
+	return f.exclude == nil || !f.exclude.MatchString(s)
+}
+
+type filters struct {
+	collections, cores, handlers filter
+}
+
+var coreLabels = []string{"core", "collection", "shard", "replica"}
+
+func coreDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "core", name), help, coreLabels, nil)
+}
+
+func handlerDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "core", name), help, append(coreLabels, "category", "handler"), nil)
+}
+
+// coreMetric maps a metric of a core to a metric of the collector.
+type coreMetric struct {
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+	// scale converts the value of the metric to the unit of the collector
+	// metric.
+	scale float64
+}
+
+var (
+	upDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "up"),
+		"Whether the last scrape of the Solr metrics API was successful.",
+		nil, nil,
+	)
+
+	coreMetrics = map[string]coreMetric{
+		"INDEX.sizeInBytes":                             {coreDesc("index_size_bytes", "Size of the index of the core."), prometheus.GaugeValue, 1},
+		"SEARCHER.searcher.numDocs":                     {coreDesc("documents", "Number of documents in the index of the core."), prometheus.GaugeValue, 1},
+		"SEARCHER.searcher.maxDoc":                      {coreDesc("max_documents", "Number of documents in the index of the core, including deleted documents."), prometheus.GaugeValue, 1},
+		"SEARCHER.searcher.deletedDocs":                 {coreDesc("deleted_documents", "Number of deleted documents in the index of the core."), prometheus.GaugeValue, 1},
+		"SEARCHER.searcher.warmupTime":                  {coreDesc("searcher_warmup_seconds", "Time spent warming up the current searcher."), prometheus.GaugeValue, 1e-3},
+		"UPDATE.updateHandler.docsPending":              {coreDesc("update_pending_documents", "Number of documents pending commit."), prometheus.GaugeValue, 1},
+		"UPDATE.updateHandler.cumulativeAdds":           {coreDesc("update_adds_total", "Number of documents added."), prometheus.CounterValue, 1},
+		"UPDATE.updateHandler.cumulativeDeletesById":    {coreDesc("update_deletes_by_id_total", "Number of delete by ID commands."), prometheus.CounterValue, 1},
+		"UPDATE.updateHandler.cumulativeDeletesByQuery": {coreDesc("update_deletes_by_query_total", "Number of delete by query commands."), prometheus.CounterValue, 1},
+		"UPDATE.updateHandler.commits":                  {coreDesc("update_commits_total", "Number of commits."), prometheus.CounterValue, 1},
+	}
+
+	// handlerMetrics are metrics of request handlers, named
+	// <category>.<handler>.<metric>.
+	handlerMetrics = map[string]coreMetric{
+		"requests":  {handlerDesc("handler_requests_total", "Number of requests processed by the request handler."), prometheus.CounterValue, 1},
+		"errors":    {handlerDesc("handler_errors_total", "Number of requests processed by the request handler resulting in errors."), prometheus.CounterValue, 1},
+		"timeouts":  {handlerDesc("handler_timeouts_total", "Number of requests processed by the request handler which timed out."), prometheus.CounterValue, 1},
+		"totalTime": {handlerDesc("handler_request_seconds_total", "Total time spent processing requests by the request handler."), prometheus.CounterValue, 1e-9},
+	}
+)
+
+type collector struct {
+	log      log.Logger
+	client   *http.Client
+	url      string
+	username string
+	password string
+	filters  *filters
+}
+
+func newCollector(l log.Logger, client *http.Client, c *Config) (*collector, error) {
+	f, err := c.filters()
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Set("group", "core")
+	q.Set("prefix", strings.Join(metricPrefixes, ","))
+	q.Set("wt", "json")
+
+	return &collector{
+		log:      l,
+		client:   client,
+		url:      strings.TrimSuffix(c.URL, "/") + "/admin/metrics?" + q.Encode(),
+		username: c.Username,
+		password: string(c.Password),
+		filters:  f,
+	}, nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- upDesc
+	for _, m := range coreMetrics {
+		ch <- m.desc
+	}
+	for _, m := range handlerMetrics {
+		ch <- m.desc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	resp, err := c.fetchMetrics()
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to scrape solr metrics", "err", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
+
+	for registry, metrics := range resp.Metrics {
+		if !strings.HasPrefix(registry, "solr.core.") {
+			continue
+		}
+		c.collectCore(ch, strings.TrimPrefix(registry, "solr.core."), metrics)
+	}
+}
+
+func (c *collector) collectCore(ch chan<- prometheus.Metric, core string, metrics map[string]json.RawMessage) {
+	// Cores of SolrCloud collections report the collection, shard, and
+	// replica they belong to.
+	if name, ok := stringValue(metrics["CORE.coreName"]); ok {
+		core = name
+	}
+	collection, _ := stringValue(metrics["CORE.collection"])
+	shard, _ := stringValue(metrics["CORE.shard"])
+	replica, _ := stringValue(metrics["CORE.replicaName"])
+
+	if !c.filters.cores.matches(core) {
+		return
+	}
+	if collection != "" && !c.filters.collections.matches(collection) {
+		return
+	}
+	labels := []string{core, collection, shard, replica}
+
+	for name, raw := range metrics {
+		if m, ok := coreMetrics[name]; ok {
+			if v, ok := numericValue(raw); ok {
+				ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, v*m.scale, labels...)
+			}
+			continue
+		}
+
+		category, handler, metric, ok := splitHandlerMetric(name)
+		if !ok || !c.filters.handlers.matches(handler) {
+			continue
+		}
+		m, ok := handlerMetrics[metric]
+		if !ok {
+			continue
+		}
+		if v, ok := numericValue(raw); ok {
+			ch <- prometheus.MustNewConstMetric(m.desc, m.valueType, v*m.scale, append(labels, category, handler)...)
+		}
+	}
+}
+
+func (c *collector) fetchMetrics() (*metricsResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	var m metricsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse metrics: %w", err)
+	}
+	return &m, nil
+}
+
+// splitHandlerMetric splits the name of a metric of a request handler, e.g.,
+// QUERY./select.requests, into its category, handler, and metric. Only
+// handlers registered at a path are considered.
+func splitHandlerMetric(name string) (category, handler, metric string, ok bool) {
+	first, last := strings.Index(name, "."), strings.LastIndex(name, ".")
+	if first < 0 || last <= first+1 {
+		return "", "", "", false
+	}
+	category, handler, metric = name[:first], name[first+1:last], name[last+1:]
+	if category != "QUERY" && category != "UPDATE" || !strings.HasPrefix(handler, "/") {
+		return "", "", "", false
+	}
+	return category, handler, metric, true
+}
+
+// numericValue decodes the value of a metric. Counters and gauges are
+// reported as plain numbers in the compact format, and as objects with a
+// count or value field otherwise. Meters and timers are always reported as
+// objects with a count field.
+func numericValue(raw json.RawMessage) (float64, bool) {
+	var v float64
+	if err := json.Unmarshal(raw, &v); err == nil {
+		return v, true
+	}
+
+	var obj struct {
+		Count *float64 `json:"count"`
+		Value *float64 `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return 0, false
+	}
+	switch {
+	case obj.Count != nil:
+		return *obj.Count, true
+	case obj.Value != nil:
+		return *obj.Value, true
+	}
+	return 0, false
+}
+
+// stringValue decodes the value of a string gauge.
+func stringValue(raw json.RawMessage) (string, bool) {
+	if raw == nil {
+		return "", false
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, true
+	}
+	var obj struct {
+		Value *string `json:"value"`
+	}
+	if err := json.Unmarshal(raw, &obj); err != nil || obj.Value == nil {
+		return "", false
+	}
+	return *obj.Value, true
+}
@@ -0,0 +1,178 @@
+package solr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const testMetrics = `{
+  "responseHeader": {"status": 0, "QTime": 3},
+  "metrics": {
+    "solr.core.products.shard1.replica_n1": {
+      "CORE.coreName": "products_shard1_replica_n1",
+      "CORE.collection": "products",
+      "CORE.shard": "shard1",
+      "CORE.replicaName": "core_node2",
+      "INDEX.sizeInBytes": 52428800,
+      "QUERY./select.errors": {"count": 2, "meanRate": 0.01},
+      "QUERY./select.requests": 150,
+      "QUERY./select.timeouts": {"count": 0, "meanRate": 0},
+      "QUERY./select.totalTime": 4500000000,
+      "QUERY./select.requestTimes": {"count": 150, "p95_ms": 12.5},
+      "QUERY./export.requests": 5,
+      "SEARCHER.searcher.deletedDocs": 10,
+      "SEARCHER.searcher.maxDoc": 1010,
+      "SEARCHER.searcher.numDocs": 1000,
+      "SEARCHER.searcher.warmupTime": 250,
+      "UPDATE./update.requests": 20,
+      "UPDATE.updateHandler.commits": {"count": 4, "meanRate": 0.001},
+      "UPDATE.updateHandler.cumulativeAdds": {"count": 1010, "meanRate": 0.1},
+      "UPDATE.updateHandler.docsPending": 3,
+      "UPDATE.updateHandler.errors": 1
+    },
+    "solr.core.logs.shard1.replica_n1": {
+      "CORE.coreName": "logs_shard1_replica_n1",
+      "CORE.collection": "logs",
+      "CORE.shard": "shard1",
+      "CORE.replicaName": "core_node3",
+      "INDEX.sizeInBytes": 1048576,
+      "QUERY./select.requests": 7
+    },
+    "solr.core.legacy": {
+      "CORE.coreName": {"value": "legacy"},
+      "INDEX.sizeInBytes": {"value": 4096},
+      "QUERY./select.requests": {"count": 1}
+    }
+  }
+}`
+
+func TestCollector(t *testing.T) {
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "agent" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/solr/admin/metrics" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		query = r.URL.Query()
+		_, _ = w.Write([]byte(testMetrics))
+	}))
+	defer srv.Close()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte("{url: '"+srv.URL+"/solr/', username: agent, password: secret}"), &cfg))
+	c, err := newCollector(log.NewNopLogger(), http.DefaultClient, &cfg)
+	require.NoError(t, err)
+
+	expect := `
+# HELP solr_core_deleted_documents Number of deleted documents in the index of the core.
+# TYPE solr_core_deleted_documents gauge
+solr_core_deleted_documents{collection="products",core="products_shard1_replica_n1",replica="core_node2",shard="shard1"} 10
+# HELP solr_core_documents Number of documents in the index of the core.
+# TYPE solr_core_documents gauge
+solr_core_documents{collection="products",core="products_shard1_replica_n1",replica="core_node2",shard="shard1"} 1000
+# HELP solr_core_handler_errors_total Number of requests processed by the request handler resulting in errors.
+# TYPE solr_core_handler_errors_total counter
+solr_core_handler_errors_total{category="QUERY",collection="products",core="products_shard1_replica_n1",handler="/select",replica="core_node2",shard="shard1"} 2
+# HELP solr_core_handler_request_seconds_total Total time spent processing requests by the request handler.
+# TYPE solr_core_handler_request_seconds_total counter
+solr_core_handler_request_seconds_total{category="QUERY",collection="products",core="products_shard1_replica_n1",handler="/select",replica="core_node2",shard="shard1"} 4.5
+# HELP solr_core_handler_requests_total Number of requests processed by the request handler.
+# TYPE solr_core_handler_requests_total counter
+solr_core_handler_requests_total{category="QUERY",collection="",core="legacy",handler="/select",replica="",shard=""} 1
+solr_core_handler_requests_total{category="QUERY",collection="logs",core="logs_shard1_replica_n1",handler="/select",replica="core_node3",shard="shard1"} 7
+solr_core_handler_requests_total{category="QUERY",collection="products",core="products_shard1_replica_n1",handler="/select",replica="core_node2",shard="shard1"} 150
+solr_core_handler_requests_total{category="UPDATE",collection="products",core="products_shard1_replica_n1",handler="/update",replica="core_node2",shard="shard1"} 20
+# HELP solr_core_index_size_bytes Size of the index of the core.
+# TYPE solr_core_index_size_bytes gauge
+solr_core_index_size_bytes{collection="",core="legacy",replica="",shard=""} 4096
+solr_core_index_size_bytes{collection="logs",core="logs_shard1_replica_n1",replica="core_node3",shard="shard1"} 1.048576e+06
+solr_core_index_size_bytes{collection="products",core="products_shard1_replica_n1",replica="core_node2",shard="shard1"} 5.24288e+07
+# HELP solr_core_searcher_warmup_seconds Time spent warming up the current searcher.
+# TYPE solr_core_searcher_warmup_seconds gauge
+solr_core_searcher_warmup_seconds{collection="products",core="products_shard1_replica_n1",replica="core_node2",shard="shard1"} 0.25
+# HELP solr_core_update_adds_total Number of documents added.
+# TYPE solr_core_update_adds_total counter
+solr_core_update_adds_total{collection="products",core="products_shard1_replica_n1",replica="core_node2",shard="shard1"} 1010
+# HELP solr_core_update_commits_total Number of commits.
+# TYPE solr_core_update_commits_total counter
+solr_core_update_commits_total{collection="products",core="products_shard1_replica_n1",replica="core_node2",shard="shard1"} 4
+# HELP solr_up Whether the last scrape of the Solr metrics API was successful.
+# TYPE solr_up gauge
+solr_up 1
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"solr_up", "solr_core_documents", "solr_core_deleted_documents", "solr_core_index_size_bytes",
+		"solr_core_searcher_warmup_seconds", "solr_core_update_adds_total", "solr_core_update_commits_total",
+		"solr_core_handler_requests_total", "solr_core_handler_errors_total", "solr_core_handler_request_seconds_total",
+	))
+	require.Equal(t, "core", query.Get("group"))
+	require.Equal(t, "CORE.,INDEX.sizeInBytes,SEARCHER.searcher.,QUERY.,UPDATE.", query.Get("prefix"))
+}
+
+func TestCollector_Filters(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(testMetrics))
+	}))
+	defer srv.Close()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+url: `+srv.URL+`/solr
+collection_exclude: logs
+core_exclude: legacy
+handler_include: /select|/export
+`), &cfg))
+	c, err := newCollector(log.NewNopLogger(), http.DefaultClient, &cfg)
+	require.NoError(t, err)
+
+	expect := `
+# HELP solr_core_handler_requests_total Number of requests processed by the request handler.
+# TYPE solr_core_handler_requests_total counter
+solr_core_handler_requests_total{category="QUERY",collection="products",core="products_shard1_replica_n1",handler="/export",replica="core_node2",shard="shard1"} 5
+solr_core_handler_requests_total{category="QUERY",collection="products",core="products_shard1_replica_n1",handler="/select",replica="core_node2",shard="shard1"} 150
+# HELP solr_core_index_size_bytes Size of the index of the core.
+# TYPE solr_core_index_size_bytes gauge
+solr_core_index_size_bytes{collection="products",core="products_shard1_replica_n1",replica="core_node2",shard="shard1"} 5.24288e+07
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"solr_core_handler_requests_total", "solr_core_index_size_bytes",
+	))
+}
+
+func TestCollector_Unauthorized(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte("url: "+srv.URL+"/solr"), &cfg))
+	c, err := newCollector(log.NewNopLogger(), http.DefaultClient, &cfg)
+	require.NoError(t, err)
+
+	expect := `
+# HELP solr_up Whether the last scrape of the Solr metrics API was successful.
+# TYPE solr_up gauge
+solr_up 0
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect)))
+}
+
+func TestConfig_InvalidFilter(t *testing.T) {
+	var c Config
+	err := yaml.Unmarshal([]byte("core_include: '('"), &c)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid core_include")
+}
@@ -0,0 +1,138 @@
+// Package solr implements an integration which collects per-core metrics of
+// Apache Solr from its metrics API.
+package solr
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig holds the default settings for the solr integration.
+var DefaultConfig = Config{
+	URL:            "http://localhost:8983/solr",
+	HandlerInclude: "/select|/update",
+	Timeout:        10 * time.Second,
+}
+
+// Config controls the solr integration.
+type Config struct {
+	// URL is the base URL of Solr.
+	URL string `yaml:"url,omitempty"`
+	// Username for basic authentication.
+	Username string `yaml:"username,omitempty"`
+	// Password for basic authentication.
+	Password config_util.Secret `yaml:"password,omitempty"`
+
+	// CollectionInclude and CollectionExclude are regular expressions of the
+	// SolrCloud collections to collect metrics from.
+	CollectionInclude string `yaml:"collection_include,omitempty"`
+	CollectionExclude string `yaml:"collection_exclude,omitempty"`
+	// CoreInclude and CoreExclude are regular expressions of the cores to
+	// collect metrics from.
+	CoreInclude string `yaml:"core_include,omitempty"`
+	CoreExclude string `yaml:"core_exclude,omitempty"`
+	// HandlerInclude and HandlerExclude are regular expressions of the
+	// request handlers to collect metrics from.
+	HandlerInclude string `yaml:"handler_include,omitempty"`
+	HandlerExclude string `yaml:"handler_exclude,omitempty"`
+
+	// Timeout for requests to Solr.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// TLSConfig configures TLS for requests to Solr.
+	TLSConfig config_util.TLSConfig `yaml:"tls_config,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url must use http or https, got %q", c.URL)
+	}
+
+	_, err = c.filters()
+	return err
+}
+
+// filters compiles the filters of cores and handlers.
+func (c *Config) filters() (*filters, error) {
+	var (
+		f   filters
+		err error
+	)
+	if f.collections, err = newFilter("collection", c.CollectionInclude, c.CollectionExclude); err != nil {
+		return nil, err
+	}
+	if f.cores, err = newFilter("core", c.CoreInclude, c.CoreExclude); err != nil {
+		return nil, err
+	}
+	if f.handlers, err = newFilter("handler", c.HandlerInclude, c.HandlerExclude); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "solr"
+}
+
+// InstanceKey returns the host:port of the Solr node being scraped.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return "", fmt.Errorf("could not parse url: %w", err)
+	}
+	return u.Host, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
+
+// New creates a new solr integration.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	tlsConfig, err := config_util.NewTLSConfig(&c.TLSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tls config: %w", err)
+	}
+	client := &http.Client{
+		Timeout: c.Timeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	col, err := newCollector(logger, client, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(col),
+	), nil
+}
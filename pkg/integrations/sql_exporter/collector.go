@@ -0,0 +1,299 @@
+package sql_exporter //nolint:golint
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "sql_exporter"
+
+var (
+	upDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "up"),
+		"Whether the database could be reached.",
+		nil, nil,
+	)
+	querySuccessDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "query", "success"),
+		"Whether the last execution of the query was successful.",
+		[]string{"query"}, nil,
+	)
+	queryDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "query", "duration_seconds"),
+		"Duration of the last execution of the query.",
+		[]string{"query"}, nil,
+	)
+)
+
+// metric is a metric read from the rows returned by a query.
+type metric struct {
+	cfg       MetricConfig
+	desc      *prometheus.Desc
+	valueType prometheus.ValueType
+}
+
+// query is a query executed once per collection, providing the rows for one
+// or more metrics.
+type query struct {
+	name    string
+	sql     string
+	metrics []*metric
+}
+
+type collector struct {
+	log     log.Logger
+	db      *sql.DB
+	timeout time.Duration
+	queries []*query
+}
+
+func newCollector(l log.Logger, db *sql.DB, c *Config) *collector {
+	var (
+		queries []*query
+		named   = make(map[string]*query, len(c.Queries))
+	)
+	for _, q := range c.Queries {
+		named[q.Name] = &query{name: q.Name, sql: q.Query}
+	}
+
+	for _, mc := range c.Metrics {
+		labels := append([]string{}, mc.KeyLabels...)
+		if mc.ValueLabel != "" {
+			labels = append(labels, mc.ValueLabel)
+		}
+		m := &metric{
+			cfg:       mc,
+			desc:      prometheus.NewDesc(mc.Name, mc.Help, labels, mc.StaticLabels),
+			valueType: prometheus.GaugeValue,
+		}
+		if mc.Type == "counter" {
+			m.valueType = prometheus.CounterValue
+		}
+
+		// Metrics with an inline query get a query named after the metric.
+		if mc.QueryRef == "" {
+			queries = append(queries, &query{name: mc.Name, sql: mc.Query, metrics: []*metric{m}})
+			continue
+		}
+		named[mc.QueryRef].metrics = append(named[mc.QueryRef].metrics, m)
+	}
+
+	// Only execute named queries used by metrics.
+	for _, q := range c.Queries {
+		if len(named[q.Name].metrics) > 0 {
+			queries = append(queries, named[q.Name])
+		}
+	}
+
+	return &collector{
+		log:     l,
+		db:      db,
+		timeout: c.QueryTimeout,
+		queries: queries,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- upDesc
+	ch <- querySuccessDesc
+	ch <- queryDurationDesc
+	for _, q := range c.queries {
+		for _, m := range q.metrics {
+			ch <- m.desc
+		}
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	err := c.db.PingContext(ctx)
+	cancel()
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to connect to database", "err", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
+
+	var wg sync.WaitGroup
+	for _, q := range c.queries {
+		wg.Add(1)
+		go func(q *query) {
+			defer wg.Done()
+
+			start := time.Now()
+			err := c.runQuery(ch, q)
+			ch <- prometheus.MustNewConstMetric(queryDurationDesc, prometheus.GaugeValue, time.Since(start).Seconds(), q.name)
+
+			success := 1.0
+			if err != nil {
+				level.Error(c.log).Log("msg", "failed to execute query", "query", q.name, "err", err)
+				success = 0
+			}
+			ch <- prometheus.MustNewConstMetric(querySuccessDesc, prometheus.GaugeValue, success, q.name)
+		}(q)
+	}
+	wg.Wait()
+}
+
+// runQuery executes q and sends the metrics read from the returned rows to
+// ch.
+func (c *collector) runQuery(ch chan<- prometheus.Metric, q *query) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	rows, err := c.db.QueryContext(ctx, q.sql)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	// Columns are matched case-insensitively, as some databases such as
+	// Snowflake return upper case column names.
+	columnIdx := make(map[string]int, len(columns))
+	for i, col := range columns {
+		columnIdx[strings.ToLower(col)] = i
+	}
+
+	readers := make([]*metricReader, 0, len(q.metrics))
+	for _, m := range q.metrics {
+		r, err := newMetricReader(m, columnIdx)
+		if err != nil {
+			return fmt.Errorf("metric %s: %w", m.cfg.Name, err)
+		}
+		readers = append(readers, r)
+	}
+
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		for _, r := range readers {
+			r.read(ch, values)
+		}
+	}
+	return rows.Err()
+}
+
+// metricReader reads the series of a metric from rows.
+type metricReader struct {
+	m        *metric
+	keyIdx   []int
+	valueIdx []int
+	// seen holds the label values of the series already read.
+	seen map[string]struct{}
+}
+
+func newMetricReader(m *metric, columnIdx map[string]int) (*metricReader, error) {
+	lookup := func(col string) (int, error) {
+		idx, ok := columnIdx[strings.ToLower(col)]
+		if !ok {
+			return 0, fmt.Errorf("column %q not returned by query", col)
+		}
+		return idx, nil
+	}
+
+	r := &metricReader{m: m, seen: map[string]struct{}{}}
+	for _, col := range m.cfg.KeyLabels {
+		idx, err := lookup(col)
+		if err != nil {
+			return nil, err
+		}
+		r.keyIdx = append(r.keyIdx, idx)
+	}
+	for _, col := range m.cfg.Values {
+		idx, err := lookup(col)
+		if err != nil {
+			return nil, err
+		}
+		r.valueIdx = append(r.valueIdx, idx)
+	}
+	return r, nil
+}
+
+// read sends a series for every value column of row to ch. NULL values and
+// series which were already read from a previous row are skipped.
+func (r *metricReader) read(ch chan<- prometheus.Metric, row []interface{}) {
+	labels := make([]string, 0, len(r.keyIdx)+1)
+	for _, idx := range r.keyIdx {
+		labels = append(labels, stringValue(row[idx]))
+	}
+
+	for i, idx := range r.valueIdx {
+		v, ok := floatValue(row[idx])
+		if !ok {
+			continue
+		}
+
+		series := labels
+		if r.m.cfg.ValueLabel != "" {
+			series = append(labels, r.m.cfg.Values[i])
+		}
+		key := strings.Join(series, "\xff")
+		if _, ok := r.seen[key]; ok {
+			continue
+		}
+		r.seen[key] = struct{}{}
+
+		ch <- prometheus.MustNewConstMetric(r.m.desc, r.m.valueType, v, series...)
+	}
+}
+
+// floatValue converts a value scanned from a row into a float64.
+func floatValue(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case time.Time:
+		return float64(v.UnixNano()) / 1e9, true
+	case []byte:
+		f, err := strconv.ParseFloat(string(v), 64)
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// stringValue converts a value scanned from a row into a label value.
+func stringValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,142 @@
+package sql_exporter //nolint:golint
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const testConfig = `
+driver: snowflake
+data_source_name: user:password@account/db/schema
+queries:
+  - name: orders
+    query: SELECT region, status, count, amount FROM orders_summary
+  - name: unused
+    query: SELECT 1
+metrics:
+  - metric_name: shop_orders
+    help: Number of orders.
+    query_ref: orders
+    key_labels: [region, status]
+    values: [count]
+  - metric_name: shop_order_amount_total
+    type: counter
+    help: Total amount of orders.
+    query_ref: orders
+    key_labels: [region]
+    static_labels:
+      currency: EUR
+    values: [amount]
+  - metric_name: shop_stock
+    help: Items in stock.
+    query: SELECT warehouse, available, reserved FROM stock
+    key_labels: [warehouse]
+    values: [available, reserved]
+    value_label: kind
+`
+
+func TestCollector(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(testConfig), &cfg))
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true), sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+	c := newCollector(log.NewNopLogger(), db, &cfg)
+
+	mock.ExpectPing()
+	// Snowflake returns upper case column names.
+	mock.ExpectQuery("SELECT region, status, count, amount FROM orders_summary").WillReturnRows(
+		sqlmock.NewRows([]string{"REGION", "STATUS", "COUNT", "AMOUNT"}).
+			AddRow("eu", "open", int64(3), "120.5").
+			AddRow("eu", "shipped", int64(10), []byte("1000")).
+			AddRow("us", "open", nil, float64(42)),
+	)
+	mock.ExpectQuery("SELECT warehouse, available, reserved FROM stock").WillReturnRows(
+		sqlmock.NewRows([]string{"warehouse", "available", "reserved"}).
+			AddRow("berlin", int64(100), int64(5)).
+			AddRow("berlin", int64(99), int64(4)),
+	)
+
+	expect := `
+# HELP shop_order_amount_total Total amount of orders.
+# TYPE shop_order_amount_total counter
+shop_order_amount_total{currency="EUR",region="eu"} 120.5
+shop_order_amount_total{currency="EUR",region="us"} 42
+# HELP shop_orders Number of orders.
+# TYPE shop_orders gauge
+shop_orders{region="eu",status="open"} 3
+shop_orders{region="eu",status="shipped"} 10
+# HELP shop_stock Items in stock.
+# TYPE shop_stock gauge
+shop_stock{kind="available",warehouse="berlin"} 100
+shop_stock{kind="reserved",warehouse="berlin"} 5
+# HELP sql_exporter_query_success Whether the last execution of the query was successful.
+# TYPE sql_exporter_query_success gauge
+sql_exporter_query_success{query="orders"} 1
+sql_exporter_query_success{query="shop_stock"} 1
+# HELP sql_exporter_up Whether the database could be reached.
+# TYPE sql_exporter_up gauge
+sql_exporter_up 1
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"shop_order_amount_total", "shop_orders", "shop_stock", "sql_exporter_query_success", "sql_exporter_up",
+	))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCollector_QueryErrors(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(testConfig), &cfg))
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true), sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+	c := newCollector(log.NewNopLogger(), db, &cfg)
+
+	mock.ExpectPing()
+	mock.ExpectQuery("SELECT region, status, count, amount FROM orders_summary").
+		WillReturnError(errors.New("table does not exist"))
+	mock.ExpectQuery("SELECT warehouse, available, reserved FROM stock").WillReturnRows(
+		sqlmock.NewRows([]string{"warehouse", "available"}).AddRow("berlin", int64(100)),
+	)
+
+	expect := `
+# HELP sql_exporter_query_success Whether the last execution of the query was successful.
+# TYPE sql_exporter_query_success gauge
+sql_exporter_query_success{query="orders"} 0
+sql_exporter_query_success{query="shop_stock"} 0
+# HELP sql_exporter_up Whether the database could be reached.
+# TYPE sql_exporter_up gauge
+sql_exporter_up 1
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"shop_order_amount_total", "shop_orders", "shop_stock", "sql_exporter_query_success", "sql_exporter_up",
+	))
+}
+
+func TestCollector_Down(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte(testConfig), &cfg))
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true), sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.MatchExpectationsInOrder(false)
+	c := newCollector(log.NewNopLogger(), db, &cfg)
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	expect := `
+# HELP sql_exporter_up Whether the database could be reached.
+# TYPE sql_exporter_up gauge
+sql_exporter_up 0
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect)))
+}
@@ -0,0 +1,9 @@
+package sql_exporter //nolint:golint
+
+import (
+	// Register the supported database/sql drivers. The mysql driver is
+	// registered by the import in sql_exporter.go.
+	_ "github.com/denisenkom/go-mssqldb"
+	_ "github.com/lib/pq"
+	_ "github.com/snowflakedb/gosnowflake"
+)
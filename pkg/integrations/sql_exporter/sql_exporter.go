@@ -0,0 +1,262 @@
+// Package sql_exporter implements an integration which collects metrics from
+// user-defined SQL queries. Queries and the columns used for the values and
+// labels of metrics are defined in the config, which allows collecting
+// arbitrary metrics from PostgreSQL, MySQL, SQL Server, and Snowflake without
+// a custom exporter.
+package sql_exporter //nolint:golint
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/go-sql-driver/mysql"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+)
+
+// DefaultConfig holds the default settings for the sql_exporter integration.
+var DefaultConfig = Config{
+	MaxOpenConnections: 3,
+	QueryTimeout:       10 * time.Second,
+}
+
+// drivers are the supported database/sql drivers.
+var drivers = map[string]struct{}{
+	"postgres":  {},
+	"mysql":     {},
+	"sqlserver": {},
+	"snowflake": {},
+}
+
+// Config controls the sql_exporter integration.
+type Config struct {
+	// Driver is the name of the database/sql driver.
+	Driver string `yaml:"driver,omitempty"`
+	// DataSourceName is the driver-specific data source name.
+	DataSourceName config_util.Secret `yaml:"data_source_name,omitempty"`
+	// MaxOpenConnections is the maximum number of open connections to the
+	// database, which limits the number of queries executed concurrently.
+	MaxOpenConnections int `yaml:"max_open_connections,omitempty"`
+	// QueryTimeout is the timeout of each query.
+	QueryTimeout time.Duration `yaml:"query_timeout,omitempty"`
+	// Queries are named queries which can be shared by multiple metrics.
+	Queries []QueryConfig `yaml:"queries,omitempty"`
+	// Metrics to collect.
+	Metrics []MetricConfig `yaml:"metrics,omitempty"`
+}
+
+// QueryConfig is a named query.
+type QueryConfig struct {
+	Name  string `yaml:"name"`
+	Query string `yaml:"query"`
+}
+
+// MetricConfig defines a metric read from the rows returned by a query.
+type MetricConfig struct {
+	// Name of the metric.
+	Name string `yaml:"metric_name"`
+	// Type of the metric, either gauge or counter.
+	Type string `yaml:"type,omitempty"`
+	// Help text of the metric.
+	Help string `yaml:"help"`
+	// Query to execute. Mutually exclusive with QueryRef.
+	Query string `yaml:"query,omitempty"`
+	// QueryRef is the name of a query of the queries of the config.
+	QueryRef string `yaml:"query_ref,omitempty"`
+	// KeyLabels are the columns used as labels.
+	KeyLabels []string `yaml:"key_labels,omitempty"`
+	// StaticLabels are labels with fixed values added to the metric.
+	StaticLabels map[string]string `yaml:"static_labels,omitempty"`
+	// Values are the columns used as values. A series is created for every
+	// value column.
+	Values []string `yaml:"values"`
+	// ValueLabel is the label holding the name of the value column. Required
+	// when more than one value column is used.
+	ValueLabel string `yaml:"value_label,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+	return c.validate()
+}
+
+func (c *Config) validate() error {
+	if _, ok := drivers[c.Driver]; !ok {
+		return fmt.Errorf("unsupported driver %q, must be one of postgres, mysql, sqlserver, or snowflake", c.Driver)
+	}
+	if c.DataSourceName == "" {
+		return fmt.Errorf("data_source_name must be set")
+	}
+	if len(c.Metrics) == 0 {
+		return fmt.Errorf("at least one metric must be defined")
+	}
+
+	queries := make(map[string]struct{}, len(c.Queries))
+	for _, q := range c.Queries {
+		if q.Name == "" || q.Query == "" {
+			return fmt.Errorf("queries must have a name and query")
+		}
+		if _, ok := queries[q.Name]; ok {
+			return fmt.Errorf("duplicate query %q", q.Name)
+		}
+		queries[q.Name] = struct{}{}
+	}
+
+	names := make(map[string]struct{}, len(c.Metrics))
+	for i := range c.Metrics {
+		m := &c.Metrics[i]
+		if !model.IsValidMetricName(model.LabelValue(m.Name)) {
+			return fmt.Errorf("invalid metric name %q", m.Name)
+		}
+		if _, ok := names[m.Name]; ok {
+			return fmt.Errorf("duplicate metric %q", m.Name)
+		}
+		names[m.Name] = struct{}{}
+
+		if err := m.validate(queries); err != nil {
+			return fmt.Errorf("metric %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+func (m *MetricConfig) validate(queries map[string]struct{}) error {
+	switch m.Type {
+	case "":
+		m.Type = "gauge"
+	case "gauge", "counter":
+	default:
+		return fmt.Errorf("invalid type %q, must be gauge or counter", m.Type)
+	}
+	if m.Help == "" {
+		return fmt.Errorf("help must be set")
+	}
+
+	switch {
+	case m.Query == "" && m.QueryRef == "":
+		return fmt.Errorf("either query or query_ref must be set")
+	case m.Query != "" && m.QueryRef != "":
+		return fmt.Errorf("query and query_ref are mutually exclusive")
+	case m.QueryRef != "":
+		if _, ok := queries[m.QueryRef]; !ok {
+			return fmt.Errorf("unknown query %q", m.QueryRef)
+		}
+	default:
+		// Inline queries are named after their metric.
+		if _, ok := queries[m.Name]; ok {
+			return fmt.Errorf("name conflicts with query %q", m.Name)
+		}
+	}
+
+	if len(m.Values) == 0 {
+		return fmt.Errorf("values must be set")
+	}
+	if len(m.Values) > 1 && m.ValueLabel == "" {
+		return fmt.Errorf("value_label must be set when using multiple values")
+	}
+
+	labels := make(map[string]struct{})
+	for _, l := range m.labelNames() {
+		if !model.LabelName(l).IsValid() {
+			return fmt.Errorf("invalid label name %q", l)
+		}
+		if _, ok := labels[l]; ok {
+			return fmt.Errorf("duplicate label %q", l)
+		}
+		labels[l] = struct{}{}
+	}
+	return nil
+}
+
+// labelNames returns the names of all labels of the metric.
+func (m *MetricConfig) labelNames() []string {
+	res := append([]string{}, m.KeyLabels...)
+	if m.ValueLabel != "" {
+		res = append(res, m.ValueLabel)
+	}
+	for l := range m.StaticLabels {
+		res = append(res, l)
+	}
+	return res
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "sql_exporter"
+}
+
+// InstanceKey returns the address and database of the data source name.
+func (c *Config) InstanceKey(_ string) (string, error) {
+	dsn := string(c.DataSourceName)
+
+	switch c.Driver {
+	case "mysql":
+		m, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse DSN: %w", err)
+		}
+		if m.Addr == "" {
+			m.Addr = "localhost:3306"
+		}
+		if m.Net == "" {
+			m.Net = "tcp"
+		}
+		return fmt.Sprintf("%s(%s)/%s", m.Net, m.Addr, m.DBName), nil
+	case "snowflake":
+		// Snowflake DSNs have the form user:password@account/database/schema.
+		dsn = "snowflake://" + dsn
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("can't automatically determine a value for `instance` from the DSN. manually assign a value for `instance` in the integration config")
+	}
+	return u.Host + u.Path, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
+
+// New creates a new sql_exporter integration. The connection to the
+// database is established lazily on the first scrape.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	db, err := sql.Open(c.Driver, string(c.DataSourceName))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(c.MaxOpenConnections)
+	db.SetMaxIdleConns(c.MaxOpenConnections)
+
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(newCollector(logger, db, c)),
+		integrations.WithRunner(func(ctx context.Context) error {
+			<-ctx.Done()
+			if err := db.Close(); err != nil {
+				level.Warn(logger).Log("msg", "failed to close database", "err", err)
+			}
+			return ctx.Err()
+		}),
+	), nil
+}
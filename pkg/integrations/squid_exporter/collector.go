@@ -0,0 +1,299 @@
+package squid_exporter //nolint:golint
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "squid"
+
+// counter is a value of the cache manager counters page exported as a
+// Prometheus counter.
+type counter struct {
+	key    string
+	name   string
+	help   string
+	factor float64
+}
+
+var counters = []counter{
+	{"client_http.requests", "client_http_requests_total", "Number of HTTP requests received from clients.", 1},
+	{"client_http.hits", "client_http_hits_total", "Number of HTTP requests served from the cache.", 1},
+	{"client_http.errors", "client_http_errors_total", "Number of HTTP requests from clients resulting in errors.", 1},
+	{"client_http.kbytes_in", "client_http_received_bytes_total", "Bytes received from clients.", 1024},
+	{"client_http.kbytes_out", "client_http_sent_bytes_total", "Bytes sent to clients.", 1024},
+	{"client_http.hit_kbytes_out", "client_http_hit_sent_bytes_total", "Bytes sent to clients for requests served from the cache.", 1024},
+	{"server.all.requests", "server_all_requests_total", "Number of requests sent to origin servers.", 1},
+	{"server.all.errors", "server_all_errors_total", "Number of requests to origin servers resulting in errors.", 1},
+	{"server.all.kbytes_in", "server_all_received_bytes_total", "Bytes received from origin servers.", 1024},
+	{"server.all.kbytes_out", "server_all_sent_bytes_total", "Bytes sent to origin servers.", 1024},
+	{"server.http.requests", "server_http_requests_total", "Number of HTTP requests sent to origin servers.", 1},
+	{"server.http.errors", "server_http_errors_total", "Number of HTTP requests to origin servers resulting in errors.", 1},
+	{"server.ftp.requests", "server_ftp_requests_total", "Number of FTP requests sent to origin servers.", 1},
+	{"server.other.requests", "server_other_requests_total", "Number of other requests sent to origin servers.", 1},
+	{"swap.outs", "swap_outs_total", "Number of objects saved to disk.", 1},
+	{"swap.ins", "swap_ins_total", "Number of objects read from disk.", 1},
+	{"swap.files_cleaned", "swap_files_cleaned_total", "Number of orphaned cache files removed.", 1},
+	{"aborted_requests", "aborted_requests_total", "Number of requests aborted by clients.", 1},
+	{"cpu_time", "cpu_seconds_total", "CPU time used by Squid.", 1},
+}
+
+var (
+	infoVersionRe = regexp.MustCompile(`Squid Object Cache: Version (\S+)`)
+	// Matches values like "5min: 12.3%, 60min: 10.0%".
+	infoRatioRe = regexp.MustCompile(`5min: ([0-9.]+)%, 60min: ([0-9.]+)%`)
+	// Matches values like "1.2% used, 98.8% free".
+	infoCapacityRe = regexp.MustCompile(`([0-9.]+)% used`)
+)
+
+// hitRatios maps lines of the info page to the hit ratio type they describe.
+// Squid 2 uses the "as %" wording.
+var hitRatios = map[string]string{
+	"Request Hit Ratios":               "requests",
+	"Byte Hit Ratios":                  "bytes",
+	"Request Memory Hit Ratios":        "memory",
+	"Request Disk Hit Ratios":          "disk",
+	"Hits as % of all requests":        "requests",
+	"Hits as % of bytes sent":          "bytes",
+	"Memory hits as % of hit requests": "memory",
+	"Disk hits as % of hit requests":   "disk",
+}
+
+type collector struct {
+	log      log.Logger
+	client   *http.Client
+	baseURI  string
+	username string
+	password string
+
+	up                *prometheus.Desc
+	info              *prometheus.Desc
+	counters          map[string]*prometheus.Desc
+	hitRatio          *prometheus.Desc
+	clients           *prometheus.Desc
+	uptime            *prometheus.Desc
+	storageSwapSize   *prometheus.Desc
+	storageMemSize    *prometheus.Desc
+	storageSwapUsed   *prometheus.Desc
+	storageMemUsed    *prometheus.Desc
+	meanObjectSize    *prometheus.Desc
+	fileDescsInUse    *prometheus.Desc
+	fileDescsMax      *prometheus.Desc
+	storeEntries      *prometheus.Desc
+	storeEntriesInMem *prometheus.Desc
+}
+
+func newCollector(l log.Logger, c *Config) *collector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "", name), help, labels, nil)
+	}
+
+	col := &collector{
+		log:      l,
+		client:   &http.Client{Timeout: c.Timeout},
+		baseURI:  "http://" + c.Address + "/squid-internal-mgr/",
+		username: c.Username,
+		password: string(c.Password),
+
+		up:                desc("up", "Whether the last scrape of the Squid cache manager was successful."),
+		info:              desc("info", "Information about the Squid server.", "version"),
+		counters:          make(map[string]*prometheus.Desc, len(counters)),
+		hitRatio:          desc("hit_ratio", "Cache hit ratio over the given window.", "type", "window"),
+		clients:           desc("clients", "Number of clients accessing the cache."),
+		uptime:            desc("uptime_seconds", "Time Squid has been running."),
+		storageSwapSize:   desc("storage_swap_size_bytes", "Size of the objects stored on disk."),
+		storageMemSize:    desc("storage_mem_size_bytes", "Size of the objects stored in memory."),
+		storageSwapUsed:   desc("storage_swap_used_ratio", "Ratio of the disk cache capacity in use."),
+		storageMemUsed:    desc("storage_mem_used_ratio", "Ratio of the memory cache capacity in use."),
+		meanObjectSize:    desc("mean_object_size_bytes", "Mean size of cached objects."),
+		fileDescsInUse:    desc("file_descriptors_in_use", "Number of file descriptors currently in use."),
+		fileDescsMax:      desc("file_descriptors_max", "Maximum number of file descriptors."),
+		storeEntries:      desc("store_entries", "Number of entries in the store."),
+		storeEntriesInMem: desc("store_entries_in_memory", "Number of entries of the store held in memory."),
+	}
+	for _, c := range counters {
+		col.counters[c.key] = desc(c.name, c.help)
+	}
+	return col
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.up, c.info, c.hitRatio, c.clients, c.uptime, c.storageSwapSize,
+		c.storageMemSize, c.storageSwapUsed, c.storageMemUsed, c.meanObjectSize,
+		c.fileDescsInUse, c.fileDescsMax, c.storeEntries, c.storeEntriesInMem,
+	} {
+		ch <- d
+	}
+	for _, d := range c.counters {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	counterValues, err := c.fetchCounters()
+	if err == nil {
+		err = c.collectInfo(ch)
+	}
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to scrape squid cache manager", "err", err)
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+
+	for _, ctr := range counters {
+		v, ok := counterValues[ctr.key]
+		if !ok {
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(c.counters[ctr.key], prometheus.CounterValue, v*ctr.factor)
+	}
+}
+
+// get retrieves a page of the cache manager.
+func (c *collector) get(page string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURI+page, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code %d for %s", resp.StatusCode, page)
+	}
+	return resp.Body, nil
+}
+
+// fetchCounters retrieves the counters page, which consists of lines of the
+// form "key = value".
+func (c *collector) fetchCounters() (map[string]float64, error) {
+	body, err := c.get("counters")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	res := make(map[string]float64)
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+		if err != nil {
+			continue
+		}
+		res[strings.TrimSpace(parts[0])] = v
+	}
+	return res, scanner.Err()
+}
+
+// collectInfo retrieves the info page, which consists of lines of the form
+// "Description:\tvalue".
+func (c *collector) collectInfo(ch chan<- prometheus.Metric) error {
+	body, err := c.get("info")
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	gauge := func(desc *prometheus.Desc, v float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labels...)
+	}
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := infoVersionRe.FindStringSubmatch(line); m != nil {
+			gauge(c.info, 1, m[1])
+			continue
+		}
+
+		// Store entry lines have the form "1234 StoreEntries".
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[1] == "StoreEntries" {
+			switch strings.Join(fields[2:], " ") {
+			case "":
+				gauge(c.storeEntries, parseLeadingFloat(line))
+			case "with MemObjects":
+				gauge(c.storeEntriesInMem, parseLeadingFloat(line))
+			}
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+		if ty, ok := hitRatios[key]; ok {
+			if m := infoRatioRe.FindStringSubmatch(value); m != nil {
+				gauge(c.hitRatio, parsePercent(m[1]), ty, "5min")
+				gauge(c.hitRatio, parsePercent(m[2]), ty, "60min")
+			}
+			continue
+		}
+
+		switch key {
+		case "Number of clients accessing cache":
+			gauge(c.clients, parseLeadingFloat(value))
+		case "UP Time":
+			gauge(c.uptime, parseLeadingFloat(value))
+		case "Storage Swap size":
+			gauge(c.storageSwapSize, parseLeadingFloat(value)*1024)
+		case "Storage Mem size":
+			gauge(c.storageMemSize, parseLeadingFloat(value)*1024)
+		case "Storage Swap capacity":
+			if m := infoCapacityRe.FindStringSubmatch(value); m != nil {
+				gauge(c.storageSwapUsed, parsePercent(m[1]))
+			}
+		case "Storage Mem capacity":
+			if m := infoCapacityRe.FindStringSubmatch(value); m != nil {
+				gauge(c.storageMemUsed, parsePercent(m[1]))
+			}
+		case "Mean Object Size":
+			gauge(c.meanObjectSize, parseLeadingFloat(value)*1024)
+		case "Number of file desc currently in use":
+			gauge(c.fileDescsInUse, parseLeadingFloat(value))
+		case "Maximum number of file descriptors":
+			gauge(c.fileDescsMax, parseLeadingFloat(value))
+		}
+	}
+	return scanner.Err()
+}
+
+// parseLeadingFloat parses the number at the start of s, ignoring units that
+// follow it.
+func parseLeadingFloat(s string) float64 {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return 0
+	}
+	v, _ := strconv.ParseFloat(fields[0], 64)
+	return v
+}
+
+func parsePercent(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v / 100
+}
@@ -0,0 +1,143 @@
+package squid_exporter //nolint:golint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const testCounters = `sample_time = 1634215080.385129 (Thu, 14 Oct 2021 12:38:00 GMT)
+client_http.requests = 120
+client_http.hits = 30
+client_http.errors = 2
+client_http.kbytes_in = 10
+client_http.kbytes_out = 200
+client_http.hit_kbytes_out = 50
+server.all.requests = 90
+server.all.errors = 1
+server.all.kbytes_in = 180
+server.all.kbytes_out = 8
+cpu_time = 1.5
+`
+
+const testInfo = `Squid Object Cache: Version 4.13
+Build Info: Ubuntu linux
+Connection information for squid:
+	Number of clients accessing cache:	3
+	Request Hit Ratios:	5min: 25.0%, 60min: 12.5%
+	Byte Hit Ratios:	5min: 20.0%, 60min: 10.0%
+Cache information for squid:
+	Storage Swap size:	2048 KB
+	Storage Swap capacity:	 1.5% used, 98.5% free
+	Storage Mem size:	512 KB
+	Storage Mem capacity:	50.0% used, 50.0% free
+	Mean Object Size:	4.00 KB
+Resource usage for squid:
+	UP Time:	3600.123 seconds
+File descriptor usage for squid:
+	Maximum number of file descriptors:   1024
+	Number of file desc currently in use:   18
+Internal Data Structures:
+	   120 StoreEntries
+	    40 StoreEntries with MemObjects
+`
+
+func TestCollector(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pass, _ := r.BasicAuth(); pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/squid-internal-mgr/counters":
+			_, _ = w.Write([]byte(testCounters))
+		case "/squid-internal-mgr/info":
+			_, _ = w.Write([]byte(testInfo))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte("address: "+strings.TrimPrefix(srv.URL, "http://")+"\npassword: secret"), &cfg))
+	c := newCollector(log.NewNopLogger(), &cfg)
+
+	expect := `
+# HELP squid_client_http_requests_total Number of HTTP requests received from clients.
+# TYPE squid_client_http_requests_total counter
+squid_client_http_requests_total 120
+# HELP squid_client_http_sent_bytes_total Bytes sent to clients.
+# TYPE squid_client_http_sent_bytes_total counter
+squid_client_http_sent_bytes_total 204800
+# HELP squid_cpu_seconds_total CPU time used by Squid.
+# TYPE squid_cpu_seconds_total counter
+squid_cpu_seconds_total 1.5
+# HELP squid_hit_ratio Cache hit ratio over the given window.
+# TYPE squid_hit_ratio gauge
+squid_hit_ratio{type="bytes",window="5min"} 0.2
+squid_hit_ratio{type="bytes",window="60min"} 0.1
+squid_hit_ratio{type="requests",window="5min"} 0.25
+squid_hit_ratio{type="requests",window="60min"} 0.125
+# HELP squid_info Information about the Squid server.
+# TYPE squid_info gauge
+squid_info{version="4.13"} 1
+# HELP squid_storage_swap_size_bytes Size of the objects stored on disk.
+# TYPE squid_storage_swap_size_bytes gauge
+squid_storage_swap_size_bytes 2.097152e+06
+# HELP squid_storage_mem_used_ratio Ratio of the memory cache capacity in use.
+# TYPE squid_storage_mem_used_ratio gauge
+squid_storage_mem_used_ratio 0.5
+# HELP squid_file_descriptors_in_use Number of file descriptors currently in use.
+# TYPE squid_file_descriptors_in_use gauge
+squid_file_descriptors_in_use 18
+# HELP squid_store_entries Number of entries in the store.
+# TYPE squid_store_entries gauge
+squid_store_entries 120
+# HELP squid_store_entries_in_memory Number of entries of the store held in memory.
+# TYPE squid_store_entries_in_memory gauge
+squid_store_entries_in_memory 40
+# HELP squid_up Whether the last scrape of the Squid cache manager was successful.
+# TYPE squid_up gauge
+squid_up 1
+# HELP squid_uptime_seconds Time Squid has been running.
+# TYPE squid_uptime_seconds gauge
+squid_uptime_seconds 3600.123
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"squid_client_http_requests_total", "squid_client_http_sent_bytes_total", "squid_cpu_seconds_total",
+		"squid_hit_ratio", "squid_info", "squid_storage_swap_size_bytes", "squid_storage_mem_used_ratio",
+		"squid_file_descriptors_in_use", "squid_store_entries", "squid_store_entries_in_memory",
+		"squid_up", "squid_uptime_seconds")
+	require.NoError(t, err)
+}
+
+func TestCollector_Unauthorized(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte("address: "+strings.TrimPrefix(srv.URL, "http://")), &cfg))
+	c := newCollector(log.NewNopLogger(), &cfg)
+
+	expect := `
+# HELP squid_up Whether the last scrape of the Squid cache manager was successful.
+# TYPE squid_up gauge
+squid_up 0
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect)))
+}
+
+func TestConfig_InvalidAddress(t *testing.T) {
+	var c Config
+	err := yaml.Unmarshal([]byte(`address: localhost`), &c)
+	require.EqualError(t, err, `invalid address "localhost": address localhost: missing port in address`)
+}
@@ -0,0 +1,80 @@
+// Package squid_exporter implements an integration which collects metrics
+// from the Squid cache manager interface, modeled after
+// https://github.com/boynux/squid-exporter.
+package squid_exporter //nolint:golint
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig holds the default settings for the squid_exporter
+// integration.
+var DefaultConfig = Config{
+	Address: "localhost:3128",
+	Timeout: 5 * time.Second,
+}
+
+// Config controls the squid_exporter integration.
+type Config struct {
+	// Address is the host:port of the Squid HTTP port serving the cache
+	// manager.
+	Address string `yaml:"address,omitempty"`
+	// Username sent along with Password. Squid ignores the username for
+	// cache manager requests, but it is required for basic authentication.
+	Username string `yaml:"username,omitempty"`
+	// Password is the cachemgr_passwd configured in Squid.
+	Password config_util.Secret `yaml:"password,omitempty"`
+	// Timeout for requests to Squid.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if _, _, err := net.SplitHostPort(c.Address); err != nil {
+		return fmt.Errorf("invalid address %q: %w", c.Address, err)
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "squid_exporter"
+}
+
+// InstanceKey returns the address of the Squid server being scraped.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	return c.Address, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
+
+// New creates a new squid_exporter integration.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(newCollector(logger, c)),
+	), nil
+}
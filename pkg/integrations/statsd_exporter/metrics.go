@@ -24,6 +24,7 @@ type Metrics struct {
 	ErrorEventStats       *prometheus.CounterVec
 	EventsActions         *prometheus.CounterVec
 	MetricsCount          *prometheus.GaugeVec
+	ConfigLoads           *prometheus.CounterVec
 }
 
 // NewMetrics initializes Metrics and registers them to the given Registerer.
@@ -102,6 +103,10 @@ func NewMetrics(r prometheus.Registerer) (*Metrics, error) {
 		Name: "statsd_exporter_metrics_total",
 		Help: "The total number of metrics.",
 	}, []string{"type"})
+	m.ConfigLoads = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "statsd_exporter_config_reloads_total",
+		Help: "The number of mapping config reloads.",
+	}, []string{"outcome"})
 
 	cs := []prometheus.Collector{
 		m.EventStats,
@@ -122,6 +127,7 @@ func NewMetrics(r prometheus.Registerer) (*Metrics, error) {
 		m.ErrorEventStats,
 		m.EventsActions,
 		m.MetricsCount,
+		m.ConfigLoads,
 	}
 	if r != nil {
 		for _, c := range cs {
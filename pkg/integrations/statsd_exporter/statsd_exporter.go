@@ -2,8 +2,10 @@
 package statsd_exporter //nolint:golint
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
@@ -14,6 +16,8 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/grafana/agent/pkg/integrations"
 	"github.com/grafana/agent/pkg/integrations/config"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/version"
@@ -34,6 +38,8 @@ var DefaultConfig = Config{
 	ListenTCP:      ":9125",
 	UnixSocketMode: "755",
 
+	MappingConfigReloadInterval: 30 * time.Second,
+
 	CacheSize:           1000,
 	CacheType:           "lru",
 	EventQueueSize:      10000,
@@ -54,6 +60,9 @@ type Config struct {
 	UnixSocketMode string               `yaml:"unix_socket_mode,omitempty"`
 	MappingConfig  *mapper.MetricMapper `yaml:"mapping_config,omitempty"`
 
+	MappingConfigFile           string        `yaml:"mapping_config_file,omitempty"`
+	MappingConfigReloadInterval time.Duration `yaml:"mapping_config_reload_interval,omitempty"`
+
 	ReadBuffer          int           `yaml:"read_buffer,omitempty"`
 	CacheSize           int           `yaml:"cache_size,omitempty"`
 	CacheType           string        `yaml:"cache_type,omitempty"`
@@ -92,6 +101,7 @@ func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error)
 
 func init() {
 	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeEither, metricsutils.CreateShim)
 }
 
 // Exporter defines the statsd_exporter integration.
@@ -100,7 +110,11 @@ type Exporter struct {
 	reg      *prometheus.Registry
 	metrics  *Metrics
 	exporter *exporter.Exporter
+	mapper   *mapper.MetricMapper
 	log      log.Logger
+
+	// Contents of the last successfully loaded mapping_config_file.
+	mappingConfig []byte
 }
 
 // New creates a new statsd_exporter integration. The integration scrapes
@@ -122,7 +136,11 @@ func New(log log.Logger, c *Config) (integrations.Integration, error) {
 		Logger:        log,
 	}
 
-	if c.MappingConfig != nil {
+	var mappingConfig []byte
+	switch {
+	case c.MappingConfig != nil && c.MappingConfigFile != "":
+		return nil, fmt.Errorf("at most one of mapping_config and mapping_config_file may be set")
+	case c.MappingConfig != nil:
 		cfgBytes, err := yaml.Marshal(c.MappingConfig)
 		if err != nil {
 			return nil, fmt.Errorf("failed to serialize mapping config: %w", err)
@@ -132,6 +150,16 @@ func New(log log.Logger, c *Config) (integrations.Integration, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to load mapping config: %w", err)
 		}
+	case c.MappingConfigFile != "":
+		mappingConfig, err = ioutil.ReadFile(c.MappingConfigFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mapping config file: %w", err)
+		}
+
+		err = statsdMapper.InitFromYAMLString(string(mappingConfig))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load mapping config file %s: %w", c.MappingConfigFile, err)
+		}
 	}
 
 	var cache mapper.MetricMapperCache
@@ -162,8 +190,11 @@ func New(log log.Logger, c *Config) (integrations.Integration, error) {
 		cfg:      c,
 		metrics:  m,
 		exporter: e,
+		mapper:   statsdMapper,
 		reg:      reg,
 		log:      log,
+
+		mappingConfig: mappingConfig,
 	}, nil
 }
 
@@ -336,6 +367,52 @@ func (e *Exporter) Run(ctx context.Context) error {
 
 	go e.exporter.Listen(events)
 
+	if e.cfg.MappingConfigFile != "" && e.cfg.MappingConfigReloadInterval > 0 {
+		go e.watchMappingConfig(ctx)
+	}
+
 	<-ctx.Done()
 	return nil
 }
+
+// watchMappingConfig periodically reloads the mapping config file until ctx
+// is canceled. New mappings are applied without restarting the listeners.
+func (e *Exporter) watchMappingConfig(ctx context.Context) {
+	ticker := time.NewTicker(e.cfg.MappingConfigReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.reloadMappingConfig(); err != nil {
+				level.Error(e.log).Log("msg", "failed to reload mapping config, keeping previous mappings", "file", e.cfg.MappingConfigFile, "err", err)
+			}
+		}
+	}
+}
+
+// reloadMappingConfig applies the mapping config file if its contents changed
+// since it was last loaded. The previous mappings are kept if the file can't
+// be loaded.
+func (e *Exporter) reloadMappingConfig() error {
+	bb, err := ioutil.ReadFile(e.cfg.MappingConfigFile)
+	if err != nil {
+		e.metrics.ConfigLoads.WithLabelValues("failure").Inc()
+		return err
+	}
+	if bytes.Equal(bb, e.mappingConfig) {
+		return nil
+	}
+
+	if err := e.mapper.InitFromYAMLString(string(bb)); err != nil {
+		e.metrics.ConfigLoads.WithLabelValues("failure").Inc()
+		return err
+	}
+	e.mappingConfig = bb
+	e.metrics.ConfigLoads.WithLabelValues("success").Inc()
+
+	level.Info(e.log).Log("msg", "reloaded mapping config", "file", e.cfg.MappingConfigFile)
+	return nil
+}
@@ -0,0 +1,54 @@
+package statsd_exporter //nolint:golint
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/statsd_exporter/pkg/mapper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExporter_ReloadMappingConfig(t *testing.T) {
+	mappingFile := filepath.Join(t.TempDir(), "mapping.yml")
+	writeMapping := func(name string) {
+		cfg := "mappings:\n- match: test.*.requests\n  name: " + name + "\n  labels:\n    service: $1\n"
+		require.NoError(t, ioutil.WriteFile(mappingFile, []byte(cfg), 0600))
+	}
+	mappedName := func(e *Exporter) string {
+		m, _, ok := e.mapper.GetMapping("test.api.requests", mapper.MetricTypeCounter)
+		require.True(t, ok)
+		return m.Name
+	}
+
+	writeMapping("requests_total")
+
+	cfg := DefaultConfig
+	cfg.MappingConfigFile = mappingFile
+	i, err := New(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+	e := i.(*Exporter)
+	require.Equal(t, "requests_total", mappedName(e))
+
+	// Unchanged files are not reloaded.
+	require.NoError(t, e.reloadMappingConfig())
+
+	writeMapping("service_requests_total")
+	require.NoError(t, e.reloadMappingConfig())
+	require.Equal(t, "service_requests_total", mappedName(e))
+
+	// Invalid mapping configs keep the previous mappings.
+	require.NoError(t, ioutil.WriteFile(mappingFile, []byte("mappings: {"), 0600))
+	require.Error(t, e.reloadMappingConfig())
+	require.Equal(t, "service_requests_total", mappedName(e))
+}
+
+func TestNew_MappingConfigConflict(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.MappingConfig = &mapper.MetricMapper{}
+	cfg.MappingConfigFile = "mapping.yml"
+
+	_, err := New(log.NewNopLogger(), &cfg)
+	require.EqualError(t, err, "at most one of mapping_config and mapping_config_file may be set")
+}
@@ -0,0 +1,266 @@
+package tomcat
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "tomcat"
+
+// status is the XML document returned by the manager status page.
+type status struct {
+	JVM struct {
+		Memory struct {
+			Free  float64 `xml:"free,attr"`
+			Total float64 `xml:"total,attr"`
+			Max   float64 `xml:"max,attr"`
+		} `xml:"memory"`
+		MemoryPools []struct {
+			Name      string  `xml:"name,attr"`
+			Type      string  `xml:"type,attr"`
+			Committed float64 `xml:"usageCommitted,attr"`
+			Max       float64 `xml:"usageMax,attr"`
+			Used      float64 `xml:"usageUsed,attr"`
+		} `xml:"memorypool"`
+	} `xml:"jvm"`
+	Connectors []struct {
+		Name       string `xml:"name,attr"`
+		ThreadInfo struct {
+			MaxThreads         float64 `xml:"maxThreads,attr"`
+			CurrentThreadCount float64 `xml:"currentThreadCount,attr"`
+			CurrentThreadsBusy float64 `xml:"currentThreadsBusy,attr"`
+		} `xml:"threadInfo"`
+		RequestInfo struct {
+			MaxTime        float64 `xml:"maxTime,attr"`
+			ProcessingTime float64 `xml:"processingTime,attr"`
+			RequestCount   float64 `xml:"requestCount,attr"`
+			ErrorCount     float64 `xml:"errorCount,attr"`
+			BytesReceived  float64 `xml:"bytesReceived,attr"`
+			BytesSent      float64 `xml:"bytesSent,attr"`
+		} `xml:"requestInfo"`
+	} `xml:"connector"`
+}
+
+// webapp is an application listed by the manager text interface.
+type webapp struct {
+	Path     string
+	Running  bool
+	Sessions float64
+}
+
+func connectorDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "connector", name), help, []string{"connector"}, nil)
+}
+
+var (
+	upDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "up"),
+		"Whether the last scrape of the Tomcat manager was successful.",
+		nil, nil,
+	)
+
+	jvmMemoryFreeDesc  = prometheus.NewDesc(prometheus.BuildFQName(namespace, "jvm", "memory_free_bytes"), "Free memory of the JVM.", nil, nil)
+	jvmMemoryTotalDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "jvm", "memory_total_bytes"), "Total memory of the JVM.", nil, nil)
+	jvmMemoryMaxDesc   = prometheus.NewDesc(prometheus.BuildFQName(namespace, "jvm", "memory_max_bytes"), "Maximum memory the JVM will attempt to use.", nil, nil)
+
+	memoryPoolLabels        = []string{"pool", "type"}
+	memoryPoolUsedDesc      = prometheus.NewDesc(prometheus.BuildFQName(namespace, "jvm", "memory_pool_used_bytes"), "Used memory of the JVM memory pool.", memoryPoolLabels, nil)
+	memoryPoolCommittedDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "jvm", "memory_pool_committed_bytes"), "Committed memory of the JVM memory pool.", memoryPoolLabels, nil)
+	memoryPoolMaxDesc       = prometheus.NewDesc(prometheus.BuildFQName(namespace, "jvm", "memory_pool_max_bytes"), "Maximum memory of the JVM memory pool.", memoryPoolLabels, nil)
+
+	threadsMaxDesc           = connectorDesc("threads_max", "Maximum number of threads of the connector.")
+	threadsCurrentDesc       = connectorDesc("threads_current", "Current number of threads of the connector.")
+	threadsBusyDesc          = connectorDesc("threads_busy", "Current number of busy threads of the connector.")
+	requestsDesc             = connectorDesc("requests_total", "Number of requests processed by the connector.")
+	errorsDesc               = connectorDesc("errors_total", "Number of requests processed by the connector resulting in errors.")
+	processingTimeDesc       = connectorDesc("processing_seconds_total", "Total time spent processing requests.")
+	maxTimeDesc              = connectorDesc("request_max_seconds", "Longest time spent processing a single request.")
+	bytesReceivedDesc        = connectorDesc("received_bytes_total", "Bytes received by the connector.")
+	bytesSentDesc            = connectorDesc("sent_bytes_total", "Bytes sent by the connector.")
+	webappRunningDesc        = prometheus.NewDesc(prometheus.BuildFQName(namespace, "webapp", "running"), "Whether the web application is running.", []string{"path"}, nil)
+	webappSessionsDesc       = prometheus.NewDesc(prometheus.BuildFQName(namespace, "webapp", "sessions_active"), "Number of active sessions of the web application.", []string{"path"}, nil)
+	webappCollectSuccessDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "webapp", "collect_success"), "Whether the last attempt to list web applications succeeded.", nil, nil)
+)
+
+type collector struct {
+	log      log.Logger
+	client   *http.Client
+	baseURL  string
+	username string
+	password string
+	webapps  bool
+}
+
+func newCollector(l log.Logger, client *http.Client, c *Config) *collector {
+	return &collector{
+		log:      l,
+		client:   client,
+		baseURL:  strings.TrimSuffix(c.ManagerURL, "/"),
+		username: c.Username,
+		password: string(c.Password),
+		webapps:  c.CollectWebapps,
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		upDesc, jvmMemoryFreeDesc, jvmMemoryTotalDesc, jvmMemoryMaxDesc,
+		memoryPoolUsedDesc, memoryPoolCommittedDesc, memoryPoolMaxDesc,
+		threadsMaxDesc, threadsCurrentDesc, threadsBusyDesc, requestsDesc,
+		errorsDesc, processingTimeDesc, maxTimeDesc, bytesReceivedDesc,
+		bytesSentDesc, webappRunningDesc, webappSessionsDesc, webappCollectSuccessDesc,
+	} {
+		ch <- d
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	gauge := func(desc *prometheus.Desc, v float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labels...)
+	}
+	counter := func(desc *prometheus.Desc, v float64, labels ...string) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v, labels...)
+	}
+
+	s, err := c.fetchStatus()
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to scrape tomcat manager status", "err", err)
+		gauge(upDesc, 0)
+		return
+	}
+	gauge(upDesc, 1)
+
+	gauge(jvmMemoryFreeDesc, s.JVM.Memory.Free)
+	gauge(jvmMemoryTotalDesc, s.JVM.Memory.Total)
+	gauge(jvmMemoryMaxDesc, s.JVM.Memory.Max)
+	for _, p := range s.JVM.MemoryPools {
+		gauge(memoryPoolUsedDesc, p.Used, p.Name, p.Type)
+		gauge(memoryPoolCommittedDesc, p.Committed, p.Name, p.Type)
+		// A maximum of -1 means the pool size is undefined.
+		if p.Max >= 0 {
+			gauge(memoryPoolMaxDesc, p.Max, p.Name, p.Type)
+		}
+	}
+
+	for _, conn := range s.Connectors {
+		// Connector names are quoted, e.g., "http-nio-8080".
+		name := strings.Trim(conn.Name, `"`)
+
+		gauge(threadsMaxDesc, conn.ThreadInfo.MaxThreads, name)
+		gauge(threadsCurrentDesc, conn.ThreadInfo.CurrentThreadCount, name)
+		gauge(threadsBusyDesc, conn.ThreadInfo.CurrentThreadsBusy, name)
+
+		// Times are reported in milliseconds.
+		counter(requestsDesc, conn.RequestInfo.RequestCount, name)
+		counter(errorsDesc, conn.RequestInfo.ErrorCount, name)
+		counter(processingTimeDesc, conn.RequestInfo.ProcessingTime/1000, name)
+		gauge(maxTimeDesc, conn.RequestInfo.MaxTime/1000, name)
+		counter(bytesReceivedDesc, conn.RequestInfo.BytesReceived, name)
+		counter(bytesSentDesc, conn.RequestInfo.BytesSent, name)
+	}
+
+	if !c.webapps {
+		return
+	}
+	apps, err := c.fetchWebapps()
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to list tomcat web applications", "err", err)
+		gauge(webappCollectSuccessDesc, 0)
+		return
+	}
+	gauge(webappCollectSuccessDesc, 1)
+	for _, app := range apps {
+		gauge(webappRunningDesc, boolValue(app.Running), app.Path)
+		gauge(webappSessionsDesc, app.Sessions, app.Path)
+	}
+}
+
+// get performs a request against the manager application.
+func (c *collector) get(path string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("unexpected status code %d for %s", resp.StatusCode, path)
+	}
+	return resp.Body, nil
+}
+
+func (c *collector) fetchStatus() (*status, error) {
+	body, err := c.get("/status?XML=true")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var s status
+	if err := xml.NewDecoder(body).Decode(&s); err != nil {
+		return nil, fmt.Errorf("failed to parse status: %w", err)
+	}
+	return &s, nil
+}
+
+// fetchWebapps lists the deployed web applications using the text
+// interface. The response starts with a status line followed by one line per
+// application in the form "path:state:sessions:name".
+func (c *collector) fetchWebapps() ([]webapp, error) {
+	body, err := c.get("/text/list")
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("empty response")
+	}
+	if line := scanner.Text(); !strings.HasPrefix(line, "OK") {
+		return nil, fmt.Errorf("unexpected response: %s", line)
+	}
+
+	var apps []webapp
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 4)
+		if len(parts) != 4 {
+			continue
+		}
+		sessions, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil {
+			continue
+		}
+		apps = append(apps, webapp{
+			Path:     parts[0],
+			Running:  parts[1] == "running",
+			Sessions: sessions,
+		})
+	}
+	return apps, scanner.Err()
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
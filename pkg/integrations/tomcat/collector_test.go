@@ -0,0 +1,119 @@
+package tomcat
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const testStatus = `<?xml version="1.0" encoding="utf-8"?><?xml-stylesheet type="text/xsl" href="/manager/xform.xsl" ?>
+<status><jvm><memory free='1000' total='4000' max='8000'/><memorypool name='G1 Eden Space' type='Heap memory' usageInit='100' usageCommitted='300' usageMax='-1' usageUsed='200'/><memorypool name='Metaspace' type='Non-heap memory' usageInit='0' usageCommitted='60' usageMax='500' usageUsed='50'/></jvm><connector name='"http-nio-8080"'><threadInfo  maxThreads="200" currentThreadCount="10" currentThreadsBusy="2" /><requestInfo  maxTime="1500" processingTime="42000" requestCount="120" errorCount="3" bytesReceived="1024" bytesSent="4096" /><workers></workers></connector></status>`
+
+const testList = `OK - Listed applications for virtual host [localhost]
+/:running:0:ROOT
+/examples:stopped:0:examples
+/shop:running:7:shop##v2
+`
+
+func TestCollector(t *testing.T) {
+	var statusQuery url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, _ := r.BasicAuth(); user != "agent" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/manager/status":
+			statusQuery = r.URL.Query()
+			_, _ = w.Write([]byte(testStatus))
+		case "/manager/text/list":
+			_, _ = w.Write([]byte(testList))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte("{manager_url: '"+srv.URL+"/manager/', username: agent, password: secret}"), &cfg))
+	c := newCollector(log.NewNopLogger(), http.DefaultClient, &cfg)
+
+	expect := `
+# HELP tomcat_connector_processing_seconds_total Total time spent processing requests.
+# TYPE tomcat_connector_processing_seconds_total counter
+tomcat_connector_processing_seconds_total{connector="http-nio-8080"} 42
+# HELP tomcat_connector_request_max_seconds Longest time spent processing a single request.
+# TYPE tomcat_connector_request_max_seconds gauge
+tomcat_connector_request_max_seconds{connector="http-nio-8080"} 1.5
+# HELP tomcat_connector_requests_total Number of requests processed by the connector.
+# TYPE tomcat_connector_requests_total counter
+tomcat_connector_requests_total{connector="http-nio-8080"} 120
+# HELP tomcat_connector_threads_busy Current number of busy threads of the connector.
+# TYPE tomcat_connector_threads_busy gauge
+tomcat_connector_threads_busy{connector="http-nio-8080"} 2
+# HELP tomcat_jvm_memory_free_bytes Free memory of the JVM.
+# TYPE tomcat_jvm_memory_free_bytes gauge
+tomcat_jvm_memory_free_bytes 1000
+# HELP tomcat_jvm_memory_pool_max_bytes Maximum memory of the JVM memory pool.
+# TYPE tomcat_jvm_memory_pool_max_bytes gauge
+tomcat_jvm_memory_pool_max_bytes{pool="Metaspace",type="Non-heap memory"} 500
+# HELP tomcat_jvm_memory_pool_used_bytes Used memory of the JVM memory pool.
+# TYPE tomcat_jvm_memory_pool_used_bytes gauge
+tomcat_jvm_memory_pool_used_bytes{pool="G1 Eden Space",type="Heap memory"} 200
+tomcat_jvm_memory_pool_used_bytes{pool="Metaspace",type="Non-heap memory"} 50
+# HELP tomcat_up Whether the last scrape of the Tomcat manager was successful.
+# TYPE tomcat_up gauge
+tomcat_up 1
+# HELP tomcat_webapp_collect_success Whether the last attempt to list web applications succeeded.
+# TYPE tomcat_webapp_collect_success gauge
+tomcat_webapp_collect_success 1
+# HELP tomcat_webapp_running Whether the web application is running.
+# TYPE tomcat_webapp_running gauge
+tomcat_webapp_running{path="/"} 1
+tomcat_webapp_running{path="/examples"} 0
+tomcat_webapp_running{path="/shop"} 1
+# HELP tomcat_webapp_sessions_active Number of active sessions of the web application.
+# TYPE tomcat_webapp_sessions_active gauge
+tomcat_webapp_sessions_active{path="/"} 0
+tomcat_webapp_sessions_active{path="/examples"} 0
+tomcat_webapp_sessions_active{path="/shop"} 7
+`
+	err := testutil.CollectAndCompare(c, strings.NewReader(expect),
+		"tomcat_connector_processing_seconds_total", "tomcat_connector_request_max_seconds",
+		"tomcat_connector_requests_total", "tomcat_connector_threads_busy", "tomcat_jvm_memory_free_bytes",
+		"tomcat_jvm_memory_pool_max_bytes", "tomcat_jvm_memory_pool_used_bytes", "tomcat_up",
+		"tomcat_webapp_collect_success", "tomcat_webapp_running", "tomcat_webapp_sessions_active")
+	require.NoError(t, err)
+	require.Equal(t, "true", statusQuery.Get("XML"))
+}
+
+func TestCollector_Unauthorized(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte("manager_url: "+srv.URL+"/manager"), &cfg))
+	c := newCollector(log.NewNopLogger(), http.DefaultClient, &cfg)
+
+	expect := `
+# HELP tomcat_up Whether the last scrape of the Tomcat manager was successful.
+# TYPE tomcat_up gauge
+tomcat_up 0
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect)))
+}
+
+func TestConfig_InvalidURL(t *testing.T) {
+	var c Config
+	err := yaml.Unmarshal([]byte(`manager_url: localhost:8080/manager`), &c)
+	require.EqualError(t, err, `manager_url must use http or https, got "localhost:8080/manager"`)
+}
@@ -0,0 +1,103 @@
+// Package tomcat implements an integration which collects metrics from the
+// status page of the Apache Tomcat manager application.
+package tomcat
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig holds the default settings for the tomcat integration.
+var DefaultConfig = Config{
+	ManagerURL:     "http://localhost:8080/manager",
+	CollectWebapps: true,
+	Timeout:        5 * time.Second,
+}
+
+// Config controls the tomcat integration.
+type Config struct {
+	// ManagerURL is the base URL of the Tomcat manager application.
+	ManagerURL string `yaml:"manager_url,omitempty"`
+	// Username of a Tomcat user with the manager-script role.
+	Username string `yaml:"username,omitempty"`
+	// Password of the user.
+	Password config_util.Secret `yaml:"password,omitempty"`
+	// CollectWebapps enables collecting the state and active sessions of
+	// deployed web applications.
+	CollectWebapps bool `yaml:"collect_webapps"`
+	// Timeout for requests to Tomcat.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// TLSConfig configures TLS for requests to Tomcat.
+	TLSConfig config_util.TLSConfig `yaml:"tls_config,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	u, err := url.Parse(c.ManagerURL)
+	if err != nil {
+		return fmt.Errorf("invalid manager_url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("manager_url must use http or https, got %q", c.ManagerURL)
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "tomcat"
+}
+
+// InstanceKey returns the host:port of the Tomcat server being scraped.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	u, err := url.Parse(c.ManagerURL)
+	if err != nil {
+		return "", fmt.Errorf("could not parse url: %w", err)
+	}
+	return u.Host, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
+
+// New creates a new tomcat integration.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	tlsConfig, err := config_util.NewTLSConfig(&c.TLSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tls config: %w", err)
+	}
+	client := &http.Client{
+		Timeout: c.Timeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(newCollector(logger, client, c)),
+	), nil
+}
@@ -4,10 +4,13 @@
 package agent
 
 import (
+	"net/http"
+
 	"github.com/go-kit/log"
 	"github.com/grafana/agent/pkg/integrations/v2"
 	"github.com/grafana/agent/pkg/integrations/v2/common"
 	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -38,9 +41,18 @@ func (c *Config) Identifier(globals integrations.Globals) (string, error) {
 
 // NewIntegration converts this config into an instance of an integration.
 func (c *Config) NewIntegration(l log.Logger, globals integrations.Globals) (integrations.Integration, error) {
-	return metricsutils.NewMetricsHandlerIntegration(l, c, c.Common, globals, promhttp.Handler())
+	return metricsutils.NewMetricsHandlerIntegration(l, c, c.Common, globals, handler())
 }
 
 func init() {
 	integrations.Register(&Config{}, integrations.TypeSingleton)
 }
+
+// handler returns the handler for the Agent's own metrics. OpenMetrics is
+// enabled so exemplars are exposed to scrapers which request them.
+func handler() http.Handler {
+	return promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	)
+}
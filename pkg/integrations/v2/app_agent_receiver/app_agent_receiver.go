@@ -0,0 +1,119 @@
+// Package app_agent_receiver implements an integration which receives
+// telemetry from frontend app agents, such as Grafana Faro in browsers, and
+// sends it to the logs and traces subsystems of the agent.
+package app_agent_receiver //nolint:golint
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gorilla/mux"
+	"github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/collector/component"
+)
+
+// collectPath is the path app agents send payloads to.
+const collectPath = "/collect"
+
+// metricsHTTPIntegration exposes the metrics of the integration.
+type metricsHTTPIntegration interface {
+	integrations.MetricsIntegration
+	Handler(prefix string) (http.Handler, error)
+}
+
+type appAgentReceiverIntegration struct {
+	metricsHTTPIntegration
+
+	log     log.Logger
+	cfg     *Config
+	handler http.Handler
+}
+
+// Static typecheck tests
+var (
+	_ integrations.Integration        = (*appAgentReceiverIntegration)(nil)
+	_ integrations.HTTPIntegration    = (*appAgentReceiverIntegration)(nil)
+	_ integrations.MetricsIntegration = (*appAgentReceiverIntegration)(nil)
+)
+
+func newIntegration(l log.Logger, c *Config, globals integrations.Globals) (integrations.Integration, error) {
+	reg := prometheus.NewRegistry()
+	exporters := []exporter{newMetricsExporter(reg)}
+
+	if c.LogsInstance != "" {
+		if globals.Logs == nil {
+			return nil, fmt.Errorf("logs_instance %s is set but the logs subsystem isn't running", c.LogsInstance)
+		}
+		getInstance := func() logsSender {
+			// Avoid returning a typed nil.
+			if inst := globals.Logs.Instance(c.LogsInstance); inst != nil {
+				return inst
+			}
+			return nil
+		}
+		exporters = append(exporters, newLogsExporter(getInstance, c.LogsLabels, c.LogsSendTimeout))
+	}
+
+	if c.TracesInstance != "" {
+		if globals.Tracing == nil {
+			return nil, fmt.Errorf("traces_instance %s is set but the traces subsystem isn't running", c.TracesInstance)
+		}
+		getExporters := func() []component.TracesExporter {
+			if inst := globals.Tracing.Instance(c.TracesInstance); inst != nil {
+				return inst.TracesExporters()
+			}
+			return nil
+		}
+		exporters = append(exporters, &tracesExporter{getExporters: getExporters})
+	}
+
+	mi, err := metricsutils.NewMetricsHandlerIntegration(l, c, c.Common, globals, promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	if err != nil {
+		return nil, err
+	}
+	mhi, ok := mi.(metricsHTTPIntegration)
+	if !ok {
+		return nil, fmt.Errorf("metrics integration doesn't expose an HTTP handler")
+	}
+
+	r := mux.NewRouter()
+	r.Handle(collectPath, newHandler(l, c.Server, exporters))
+
+	return &appAgentReceiverIntegration{
+		metricsHTTPIntegration: mhi,
+		log:                    l,
+		cfg:                    c,
+		handler:                r,
+	}, nil
+}
+
+// RunIntegration implements Integration. It serves the collect endpoint
+// until ctx is canceled.
+func (i *appAgentReceiverIntegration) RunIntegration(ctx context.Context) error {
+	addr := net.JoinHostPort(i.cfg.Server.Host, strconv.Itoa(i.cfg.Server.Port))
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	srv := &http.Server{Handler: i.handler}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+
+	level.Info(i.log).Log("msg", "receiving app agent payloads", "addr", lis.Addr().String()+collectPath)
+	if err := srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package app_agent_receiver //nolint:golint
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/common"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig holds the default settings for the app_agent_receiver
+// integration.
+var DefaultConfig = Config{
+	Server: ServerConfig{
+		Host:                  "127.0.0.1",
+		Port:                  12347,
+		MaxAllowedPayloadSize: 5e6,
+		RateLimiting: RateLimitingConfig{
+			Enabled:    true,
+			RPS:        100,
+			Burstiness: 50,
+		},
+	},
+	LogsSendTimeout: 2 * time.Second,
+}
+
+// Config controls the app_agent_receiver integration.
+type Config struct {
+	Common common.MetricsConfig `yaml:",inline"`
+
+	// Server configures the HTTP server receiving payloads from app agents.
+	Server ServerConfig `yaml:"server,omitempty"`
+
+	// LogsInstance is the logs instance exceptions, logs and measurements are
+	// sent to. They're dropped when empty.
+	LogsInstance string `yaml:"logs_instance,omitempty"`
+	// LogsLabels are static labels added to every log entry.
+	LogsLabels map[string]string `yaml:"logs_labels,omitempty"`
+	// LogsSendTimeout is how long to wait for the logs instance to accept an
+	// entry.
+	LogsSendTimeout time.Duration `yaml:"logs_send_timeout,omitempty"`
+
+	// TracesInstance is the traces instance spans are exported with. They're
+	// dropped when empty.
+	TracesInstance string `yaml:"traces_instance,omitempty"`
+}
+
+// ServerConfig configures the HTTP server of the integration.
+type ServerConfig struct {
+	Host string `yaml:"host,omitempty"`
+	Port int    `yaml:"port,omitempty"`
+
+	// CORSAllowedOrigins are the origins browsers may send payloads from.
+	// "*" allows every origin.
+	CORSAllowedOrigins []string `yaml:"cors_allowed_origins,omitempty"`
+	// APIKey, when set, must be sent by app agents in the x-api-key header.
+	APIKey config_util.Secret `yaml:"api_key,omitempty"`
+	// MaxAllowedPayloadSize is the maximum size of a payload in bytes.
+	MaxAllowedPayloadSize int64 `yaml:"max_allowed_payload_size,omitempty"`
+
+	RateLimiting RateLimitingConfig `yaml:"rate_limiting,omitempty"`
+}
+
+// RateLimitingConfig limits the rate of payloads accepted by the server.
+type RateLimitingConfig struct {
+	Enabled    bool    `yaml:"enabled,omitempty"`
+	RPS        float64 `yaml:"rps,omitempty"`
+	Burstiness int     `yaml:"burstiness,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.Server.RateLimiting.Enabled && c.Server.RateLimiting.RPS <= 0 {
+		return fmt.Errorf("server.rate_limiting.rps must be greater than 0")
+	}
+	if c.Server.MaxAllowedPayloadSize <= 0 {
+		return fmt.Errorf("server.max_allowed_payload_size must be greater than 0")
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string { return "app_agent_receiver" }
+
+// ApplyDefaults applies runtime-specific defaults to c.
+func (c *Config) ApplyDefaults(globals integrations.Globals) error {
+	c.Common.ApplyDefaults(globals.SubsystemOpts.Metrics.Autoscrape)
+	if id, err := c.Identifier(globals); err == nil {
+		c.Common.InstanceKey = &id
+	}
+	return nil
+}
+
+// Identifier uniquely identifies this instance of Config.
+func (c *Config) Identifier(globals integrations.Globals) (string, error) {
+	if c.Common.InstanceKey != nil {
+		return *c.Common.InstanceKey, nil
+	}
+	return fmt.Sprintf("%s:%d", c.Server.Host, c.Server.Port), nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger, globals integrations.Globals) (integrations.Integration, error) {
+	return newIntegration(l, c, globals)
+}
+
+func init() {
+	integrations.Register(&Config{}, integrations.TypeMultiplex)
+}
@@ -0,0 +1,166 @@
+package app_agent_receiver //nolint:golint
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/go-logfmt/logfmt"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"go.opentelemetry.io/collector/component"
+	"go.uber.org/multierr"
+)
+
+const (
+	kindException   = "exception"
+	kindLog         = "log"
+	kindMeasurement = "measurement"
+)
+
+// exporter sends the telemetry of a payload to a subsystem of the agent.
+type exporter interface {
+	Export(ctx context.Context, p Payload) error
+}
+
+// logsSender sends entries to a logs instance.
+type logsSender interface {
+	SendEntry(entry api.Entry, dur time.Duration) bool
+}
+
+// logsExporter sends exceptions, logs and measurements to a logs instance as
+// logfmt lines.
+type logsExporter struct {
+	getInstance func() logsSender
+	labels      model.LabelSet
+	timeout     time.Duration
+}
+
+func newLogsExporter(getInstance func() logsSender, labels map[string]string, timeout time.Duration) *logsExporter {
+	ls := make(model.LabelSet, len(labels))
+	for k, v := range labels {
+		ls[model.LabelName(k)] = model.LabelValue(v)
+	}
+	return &logsExporter{getInstance: getInstance, labels: ls, timeout: timeout}
+}
+
+// Export implements exporter.
+func (e *logsExporter) Export(_ context.Context, p Payload) error {
+	inst := e.getInstance()
+	if inst == nil {
+		return fmt.Errorf("logs instance not found")
+	}
+
+	var errs error
+	send := func(kind string, ts time.Time, keyvals []interface{}) {
+		line, err := logfmt.MarshalKeyvals(keyvals...)
+		if err != nil {
+			errs = multierr.Append(errs, err)
+			return
+		}
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+
+		labels := e.labels.Clone()
+		labels["kind"] = model.LabelValue(kind)
+		if p.Meta.App.Name != "" {
+			labels["app"] = model.LabelValue(p.Meta.App.Name)
+		}
+
+		sent := inst.SendEntry(api.Entry{
+			Labels: labels,
+			Entry:  logproto.Entry{Timestamp: ts, Line: string(line)},
+		}, e.timeout)
+		if !sent {
+			errs = multierr.Append(errs, fmt.Errorf("failed to send %s to logs instance", kind))
+		}
+	}
+
+	for _, exc := range p.Exceptions {
+		send(kindException, exc.Timestamp, exc.KeyVals(p.Meta))
+	}
+	for _, l := range p.Logs {
+		send(kindLog, l.Timestamp, l.KeyVals(p.Meta))
+	}
+	for _, m := range p.Measurements {
+		send(kindMeasurement, m.Timestamp, m.KeyVals(p.Meta))
+	}
+	return errs
+}
+
+// tracesExporter sends spans to the exporters of a traces instance.
+type tracesExporter struct {
+	getExporters func() []component.TracesExporter
+}
+
+// Export implements exporter.
+func (e *tracesExporter) Export(ctx context.Context, p Payload) error {
+	if p.Traces == nil || p.Traces.SpanCount() == 0 {
+		return nil
+	}
+
+	exporters := e.getExporters()
+	if len(exporters) == 0 {
+		return fmt.Errorf("traces instance not found")
+	}
+	var errs error
+	for _, exp := range exporters {
+		errs = multierr.Append(errs, exp.ConsumeTraces(ctx, p.Traces.Clone()))
+	}
+	return errs
+}
+
+// metricsExporter records the number of events received and the values of
+// measurements as metrics of the integration.
+type metricsExporter struct {
+	events       *prometheus.CounterVec
+	measurements *prometheus.SummaryVec
+}
+
+func newMetricsExporter(reg prometheus.Registerer) *metricsExporter {
+	e := &metricsExporter{
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "app_agent_receiver_events_total",
+			Help: "Total number of exceptions, logs, measurements and spans received from apps.",
+		}, []string{"app", "kind"}),
+		measurements: prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Name:       "app_agent_receiver_measurements",
+			Help:       "Values of the measurements received from apps.",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+		}, []string{"app", "type", "name"}),
+	}
+	reg.MustRegister(e.events, e.measurements)
+	return e
+}
+
+// Export implements exporter.
+func (e *metricsExporter) Export(_ context.Context, p Payload) error {
+	app := p.Meta.App.Name
+	e.events.WithLabelValues(app, kindException).Add(float64(len(p.Exceptions)))
+	e.events.WithLabelValues(app, kindLog).Add(float64(len(p.Logs)))
+	e.events.WithLabelValues(app, kindMeasurement).Add(float64(len(p.Measurements)))
+	if p.Traces != nil {
+		e.events.WithLabelValues(app, "span").Add(float64(p.Traces.SpanCount()))
+	}
+
+	for _, m := range p.Measurements {
+		for name, v := range m.Values {
+			e.measurements.WithLabelValues(app, m.Type, name).Observe(v)
+		}
+	}
+	return nil
+}
+
+// exportAll runs every exporter, logging failures.
+func exportAll(ctx context.Context, l log.Logger, exporters []exporter, p Payload) {
+	for _, exp := range exporters {
+		if err := exp.Export(ctx, p); err != nil {
+			level.Warn(l).Log("msg", "failed to export app agent payload", "err", err)
+		}
+	}
+}
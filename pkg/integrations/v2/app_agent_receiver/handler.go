@@ -0,0 +1,104 @@
+package app_agent_receiver //nolint:golint
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-kit/log"
+	"golang.org/x/time/rate"
+)
+
+const apiKeyHeader = "x-api-key"
+
+// handler receives payloads from app agents and exports them.
+type handler struct {
+	log       log.Logger
+	cfg       ServerConfig
+	limiter   *rate.Limiter
+	exporters []exporter
+}
+
+func newHandler(l log.Logger, cfg ServerConfig, exporters []exporter) *handler {
+	h := &handler{log: l, cfg: cfg, exporters: exporters}
+	if cfg.RateLimiting.Enabled {
+		h.limiter = rate.NewLimiter(rate.Limit(cfg.RateLimiting.RPS), cfg.RateLimiting.Burstiness)
+	}
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *handler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if !h.allowCORS(rw, r) {
+		http.Error(rw, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	if r.Method == http.MethodOptions {
+		rw.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if h.cfg.APIKey != "" {
+		key := r.Header.Get(apiKeyHeader)
+		if subtle.ConstantTimeCompare([]byte(key), []byte(h.cfg.APIKey)) != 1 {
+			http.Error(rw, "api key not provided or incorrect", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if h.limiter != nil && !h.limiter.Allow() {
+		http.Error(rw, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
+
+	var p Payload
+	body := http.MaxBytesReader(rw, r.Body, h.cfg.MaxAllowedPayloadSize)
+	if err := json.NewDecoder(body).Decode(&p); err != nil {
+		status := http.StatusBadRequest
+		if err.Error() == errTooLarge {
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(rw, err.Error(), status)
+		return
+	}
+
+	exportAll(r.Context(), h.log, h.exporters, p)
+
+	rw.WriteHeader(http.StatusAccepted)
+}
+
+// errTooLarge is the message of the error returned by http.MaxBytesReader,
+// which isn't exported.
+const errTooLarge = "http: request body too large"
+
+// allowCORS sets the CORS headers of the response and returns false if the
+// origin of a cross-origin request isn't allowed.
+func (h *handler) allowCORS(rw http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	allowed := false
+	for _, o := range h.cfg.CORSAllowedOrigins {
+		if o == "*" || o == origin {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return false
+	}
+
+	rw.Header().Set("Access-Control-Allow-Origin", origin)
+	rw.Header().Add("Vary", "Origin")
+	if r.Method == http.MethodOptions {
+		rw.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+		rw.Header().Set("Access-Control-Allow-Headers", "Content-Type, "+apiKeyHeader)
+	}
+	return true
+}
@@ -0,0 +1,175 @@
+package app_agent_receiver //nolint:golint
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+)
+
+const testPayload = `{
+  "exceptions": [{
+    "type": "TypeError",
+    "value": "x is undefined",
+    "timestamp": "2022-01-02T03:04:05Z",
+    "stacktrace": {"frames": [{"function": "render", "filename": "app.js", "lineno": 10, "colno": 5}]}
+  }],
+  "logs": [{"message": "loaded", "level": "info", "context": {"route": "/cart"}, "timestamp": "2022-01-02T03:04:06Z"}],
+  "measurements": [{"type": "web-vitals", "values": {"fcp": 120.5}, "timestamp": "2022-01-02T03:04:07Z"}],
+  "meta": {"app": {"name": "shop", "version": "1.0.0"}, "session": {"id": "abc"}},
+  "traces": {"resourceSpans": [{"instrumentationLibrarySpans": [{"spans": [{
+    "traceId": "0102030405060708090a0b0c0d0e0f10",
+    "spanId": "0102030405060708",
+    "name": "fetch"
+  }]}]}]}
+}`
+
+func TestHandler(t *testing.T) {
+	logs := &mockLogsSender{}
+	sink := new(consumertest.TracesSink)
+	reg := prometheus.NewRegistry()
+
+	exporters := []exporter{
+		newMetricsExporter(reg),
+		newLogsExporter(func() logsSender { return logs }, map[string]string{"job": "frontend"}, time.Second),
+		&tracesExporter{getExporters: func() []component.TracesExporter { return []component.TracesExporter{newTracesSink(t, sink)} }},
+	}
+	h := newHandler(log.NewNopLogger(), DefaultConfig.Server, exporters)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, collectPath, strings.NewReader(testPayload)))
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	require.Len(t, logs.entries, 3)
+	require.Equal(t, model.LabelSet{"job": "frontend", "app": "shop", "kind": "exception"}, logs.entries[0].Labels)
+	require.Equal(t,
+		`timestamp=2022-01-02T03:04:05Z kind=exception type=TypeError value="x is undefined" stacktrace="  at render (app.js:10:5)" app_name=shop app_version=1.0.0 session_id=abc`,
+		logs.entries[0].Line)
+	require.Equal(t,
+		`timestamp=2022-01-02T03:04:06Z kind=log message=loaded level=info context_route=/cart app_name=shop app_version=1.0.0 session_id=abc`,
+		logs.entries[1].Line)
+	require.Equal(t,
+		`timestamp=2022-01-02T03:04:07Z kind=measurement type=web-vitals fcp=120.5 app_name=shop app_version=1.0.0 session_id=abc`,
+		logs.entries[2].Line)
+	require.Equal(t, time.Date(2022, 1, 2, 3, 4, 7, 0, time.UTC), logs.entries[2].Timestamp)
+
+	require.Equal(t, 1, sink.SpanCount())
+
+	require.Equal(t, 1.0, testutil.ToFloat64(exporters[0].(*metricsExporter).events.WithLabelValues("shop", "span")))
+	count, err := testutil.GatherAndCount(reg, "app_agent_receiver_measurements")
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}
+
+func TestHandler_Rejected(t *testing.T) {
+	cfg := DefaultConfig.Server
+	cfg.APIKey = "secret"
+	cfg.MaxAllowedPayloadSize = 20
+	cfg.CORSAllowedOrigins = []string{"https://shop.example.com"}
+	cfg.RateLimiting.RPS = 1
+	cfg.RateLimiting.Burstiness = 1
+
+	tt := []struct {
+		name         string
+		method       string
+		body         string
+		header       map[string]string
+		expectStatus int
+	}{
+		{
+			name:         "accepted",
+			method:       http.MethodPost,
+			body:         `{}`,
+			header:       map[string]string{apiKeyHeader: "secret", "Origin": "https://shop.example.com"},
+			expectStatus: http.StatusAccepted,
+		},
+		{
+			name:         "rate limited",
+			method:       http.MethodPost,
+			body:         `{}`,
+			header:       map[string]string{apiKeyHeader: "secret"},
+			expectStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:         "wrong api key",
+			method:       http.MethodPost,
+			body:         `{}`,
+			header:       map[string]string{apiKeyHeader: "wrong"},
+			expectStatus: http.StatusUnauthorized,
+		},
+		{
+			name:         "origin not allowed",
+			method:       http.MethodPost,
+			body:         `{}`,
+			header:       map[string]string{apiKeyHeader: "secret", "Origin": "https://evil.example.com"},
+			expectStatus: http.StatusForbidden,
+		},
+		{
+			name:         "preflight",
+			method:       http.MethodOptions,
+			header:       map[string]string{"Origin": "https://shop.example.com"},
+			expectStatus: http.StatusNoContent,
+		},
+		{
+			name:         "method not allowed",
+			method:       http.MethodGet,
+			expectStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	h := newHandler(log.NewNopLogger(), cfg, nil)
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, collectPath, strings.NewReader(tc.body))
+			for k, v := range tc.header {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			require.Equal(t, tc.expectStatus, rec.Code)
+		})
+	}
+
+	t.Run("payload too large", func(t *testing.T) {
+		cfg.RateLimiting.Enabled = false
+		h := newHandler(log.NewNopLogger(), cfg, nil)
+		req := httptest.NewRequest(http.MethodPost, collectPath, strings.NewReader(testPayload))
+		req.Header.Set(apiKeyHeader, "secret")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	})
+}
+
+type mockLogsSender struct {
+	entries []api.Entry
+}
+
+func (s *mockLogsSender) SendEntry(entry api.Entry, _ time.Duration) bool {
+	s.entries = append(s.entries, entry)
+	return true
+}
+
+func newTracesSink(t *testing.T, sink *consumertest.TracesSink) component.TracesExporter {
+	t.Helper()
+
+	cfg := config.NewExporterSettings(config.NewComponentID("sink"))
+	exp, err := exporterhelper.NewTracesExporter(&cfg, componenttest.NewNopExporterCreateSettings(), sink.ConsumeTraces)
+	require.NoError(t, err)
+	require.NoError(t, exp.Start(context.Background(), componenttest.NewNopHost()))
+	return exp
+}
@@ -0,0 +1,224 @@
+package app_agent_receiver //nolint:golint
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/model/otlp"
+	"go.opentelemetry.io/collector/model/pdata"
+)
+
+// Payload is the telemetry sent by app agents in a single request.
+type Payload struct {
+	Exceptions   []Exception   `json:"exceptions,omitempty"`
+	Logs         []Log         `json:"logs,omitempty"`
+	Measurements []Measurement `json:"measurements,omitempty"`
+	Meta         Meta          `json:"meta"`
+	Traces       *Traces       `json:"traces,omitempty"`
+}
+
+// Exception is an error thrown by the app.
+type Exception struct {
+	Type       string      `json:"type"`
+	Value      string      `json:"value"`
+	Stacktrace *Stacktrace `json:"stacktrace,omitempty"`
+	Timestamp  time.Time   `json:"timestamp"`
+}
+
+// Stacktrace is the stack of an exception.
+type Stacktrace struct {
+	Frames []Frame `json:"frames"`
+}
+
+// Frame is a single frame of a stacktrace.
+type Frame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+	Colno    int    `json:"colno"`
+}
+
+// Log is a log message of the app.
+type Log struct {
+	Message   string            `json:"message"`
+	Level     string            `json:"level"`
+	Context   map[string]string `json:"context,omitempty"`
+	Timestamp time.Time         `json:"timestamp"`
+}
+
+// Measurement is a set of values measured by the app, e.g. web vitals.
+type Measurement struct {
+	Type      string             `json:"type"`
+	Values    map[string]float64 `json:"values"`
+	Timestamp time.Time          `json:"timestamp"`
+}
+
+// Meta describes the app and the context telemetry was collected in.
+type Meta struct {
+	SDK     SDK     `json:"sdk,omitempty"`
+	App     App     `json:"app,omitempty"`
+	Session Session `json:"session,omitempty"`
+	User    User    `json:"user,omitempty"`
+	Page    Page    `json:"page,omitempty"`
+	Browser Browser `json:"browser,omitempty"`
+}
+
+// SDK is the app agent which collected the telemetry.
+type SDK struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+}
+
+// App identifies the app.
+type App struct {
+	Name        string `json:"name,omitempty"`
+	Release     string `json:"release,omitempty"`
+	Version     string `json:"version,omitempty"`
+	Environment string `json:"environment,omitempty"`
+}
+
+// Session identifies the session of the user.
+type Session struct {
+	ID string `json:"id,omitempty"`
+}
+
+// User identifies the user of the app.
+type User struct {
+	ID       string `json:"id,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// Page is the page of the app the telemetry was collected on.
+type Page struct {
+	URL string `json:"url,omitempty"`
+}
+
+// Browser describes the browser or device running the app.
+type Browser struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	OS      string `json:"os,omitempty"`
+	Mobile  bool   `json:"mobile,omitempty"`
+}
+
+// Traces are spans encoded as OTLP/JSON.
+type Traces struct {
+	pdata.Traces
+}
+
+// UnmarshalJSON implements json.Unmarshaler for Traces.
+func (t *Traces) UnmarshalJSON(b []byte) error {
+	td, err := otlp.NewJSONTracesUnmarshaler().UnmarshalTraces(b)
+	if err != nil {
+		return fmt.Errorf("invalid traces: %w", err)
+	}
+	*t = Traces{td}
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler for Traces.
+func (t Traces) MarshalJSON() ([]byte, error) {
+	return otlp.NewJSONTracesMarshaler().MarshalTraces(t.Traces)
+}
+
+// KeyVals returns the keyvals describing e, followed by meta.
+func (e Exception) KeyVals(meta Meta) []interface{} {
+	kv := []interface{}{
+		"timestamp", e.Timestamp.UTC().Format(time.RFC3339Nano),
+		"kind", kindException,
+		"type", e.Type,
+		"value", e.Value,
+	}
+	if e.Stacktrace != nil {
+		kv = append(kv, "stacktrace", e.Stacktrace.String())
+	}
+	return append(kv, meta.KeyVals()...)
+}
+
+// String formats the stacktrace like browsers do.
+func (s Stacktrace) String() string {
+	var sb strings.Builder
+	for i, f := range s.Frames {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		fmt.Fprintf(&sb, "  at %s (%s:%d:%d)", f.Function, f.Filename, f.Lineno, f.Colno)
+	}
+	return sb.String()
+}
+
+// KeyVals returns the keyvals describing l, followed by meta.
+func (l Log) KeyVals(meta Meta) []interface{} {
+	kv := []interface{}{
+		"timestamp", l.Timestamp.UTC().Format(time.RFC3339Nano),
+		"kind", kindLog,
+		"message", l.Message,
+		"level", l.Level,
+	}
+	for _, k := range sortedKeys(l.Context) {
+		kv = append(kv, "context_"+k, l.Context[k])
+	}
+	return append(kv, meta.KeyVals()...)
+}
+
+// KeyVals returns the keyvals describing m, followed by meta.
+func (m Measurement) KeyVals(meta Meta) []interface{} {
+	kv := []interface{}{
+		"timestamp", m.Timestamp.UTC().Format(time.RFC3339Nano),
+		"kind", kindMeasurement,
+		"type", m.Type,
+	}
+	names := make([]string, 0, len(m.Values))
+	for name := range m.Values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		kv = append(kv, name, strconv.FormatFloat(m.Values[name], 'f', -1, 64))
+	}
+	return append(kv, meta.KeyVals()...)
+}
+
+// KeyVals returns the non-empty fields of m as keyvals.
+func (m Meta) KeyVals() []interface{} {
+	fields := []struct{ key, value string }{
+		{"sdk_name", m.SDK.Name},
+		{"sdk_version", m.SDK.Version},
+		{"app_name", m.App.Name},
+		{"app_release", m.App.Release},
+		{"app_version", m.App.Version},
+		{"app_environment", m.App.Environment},
+		{"session_id", m.Session.ID},
+		{"user_id", m.User.ID},
+		{"user_email", m.User.Email},
+		{"user_username", m.User.Username},
+		{"page_url", m.Page.URL},
+		{"browser_name", m.Browser.Name},
+		{"browser_version", m.Browser.Version},
+		{"browser_os", m.Browser.OS},
+	}
+
+	var kv []interface{}
+	for _, f := range fields {
+		if f.value != "" {
+			kv = append(kv, f.key, f.value)
+		}
+	}
+	if m.Browser.Mobile {
+		kv = append(kv, "browser_mobile", "true")
+	}
+	return kv
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
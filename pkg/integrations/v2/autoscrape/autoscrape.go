@@ -3,6 +3,7 @@ package autoscrape
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
 	"github.com/go-kit/log"
@@ -10,6 +11,7 @@ import (
 	"github.com/grafana/agent/pkg/metrics"
 	"github.com/grafana/agent/pkg/metrics/instance"
 	"github.com/oklog/run"
+	"github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
 	prom_config "github.com/prometheus/prometheus/config"
 	"github.com/prometheus/prometheus/discovery"
@@ -49,6 +51,35 @@ type Config struct {
 
 	RelabelConfigs       []*relabel.Config `yaml:"relabel_configs,omitempty"`        // Relabel the autoscrape job
 	MetricRelabelConfigs []*relabel.Config `yaml:"metric_relabel_configs,omitempty"` // Relabel individual autoscrape metrics
+
+	ExtraTargets *ExtraTargetsConfig `yaml:"extra_targets,omitempty"` // Discover targets for a separate autoscrape job
+}
+
+// ExtraTargetsConfig discovers targets which are scraped by a separate
+// autoscrape job of an integration, sharing the relabel rules of the
+// integration's job.
+type ExtraTargetsConfig struct {
+	// HTTPClientConfig is used to scrape the extra targets. The credentials
+	// of the agent and endpoint_auth are never sent to extra targets.
+	HTTPClientConfig config.HTTPClientConfig `yaml:"http_client_config,omitempty"`
+
+	// ServiceDiscoveryConfigs are the Prometheus service discovery configs
+	// used to discover targets, e.g., http_sd_configs or file_sd_configs.
+	ServiceDiscoveryConfigs discovery.Configs `yaml:"-"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for ExtraTargetsConfig.
+func (c *ExtraTargetsConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = ExtraTargetsConfig{HTTPClientConfig: config.DefaultHTTPClientConfig}
+	if err := discovery.UnmarshalYAMLWithInlineConfigs(c, unmarshal); err != nil {
+		return err
+	}
+	return c.HTTPClientConfig.Validate()
+}
+
+// MarshalYAML implements yaml.Marshaler for ExtraTargetsConfig.
+func (c *ExtraTargetsConfig) MarshalYAML() (interface{}, error) {
+	return discovery.MarshalYAMLWithInlineConfigs(c)
 }
 
 // InstanceStore is used to find instances to send metrics to. It is a subset
@@ -56,6 +87,12 @@ type Config struct {
 type InstanceStore interface {
 	// GetInstance retrieves a ManagedInstance by name.
 	GetInstance(name string) (instance.ManagedInstance, error)
+
+	// ListConfigs, ApplyConfig, and DeleteConfig are used to manage the
+	// instances which send metrics to a specific tenant.
+	ListConfigs() map[string]instance.Config
+	ApplyConfig(instance.Config) error
+	DeleteConfig(name string) error
 }
 
 // ScrapeConfig bind a Prometheus scrape config with an instance to send
@@ -63,6 +100,10 @@ type InstanceStore interface {
 type ScrapeConfig struct {
 	Instance string
 	Config   prom_config.ScrapeConfig
+
+	// TenantID, when set, sends the scraped metrics to the remote_write
+	// endpoints of Instance with the X-Scope-OrgID header set to TenantID.
+	TenantID string
 }
 
 // Scraper is a metrics autoscraper.
@@ -79,8 +120,9 @@ type Scraper struct {
 	// all of our integrations, and we instead need to launch a pair of each for
 	// every instance we're writing to.
 
-	iscrapersMut sync.RWMutex
-	iscrapers    map[string]*instanceScraper
+	iscrapersMut    sync.RWMutex
+	iscrapers       map[string]*instanceScraper
+	tenantInstances map[string]struct{} // Instances created for tenants.
 }
 
 // NewScraper creates a new autoscraper. Scraper will run until Stop is called.
@@ -115,16 +157,32 @@ func (s *Scraper) ApplyConfig(jobs []*ScrapeConfig) error {
 	}
 
 	// Shard our jobs by target instance.
-	shardedJobs := map[string][]*prom_config.ScrapeConfig{}
+	var (
+		shardedJobs     = map[string][]*prom_config.ScrapeConfig{}
+		tenantInstances = map[string]struct{}{}
+	)
 	for _, j := range jobs {
-		_, err := s.is.GetInstance(j.Instance)
+		instanceName := j.Instance
+		if j.TenantID != "" {
+			instanceName = tenantInstanceName(j.Instance, j.TenantID)
+			if _, applied := tenantInstances[instanceName]; !applied {
+				if err := s.applyTenantInstance(j.Instance, j.TenantID); err != nil {
+					level.Error(s.log).Log("msg", "cannot autoscrape integration", "name", j.Config.JobName, "err", err)
+					saveError(err)
+					continue
+				}
+				tenantInstances[instanceName] = struct{}{}
+			}
+		}
+
+		_, err := s.is.GetInstance(instanceName)
 		if err != nil {
 			level.Error(s.log).Log("msg", "cannot autoscrape integration", "name", j.Config.JobName, "err", err)
 			saveError(err)
 			continue
 		}
 
-		shardedJobs[j.Instance] = append(shardedJobs[j.Instance], &j.Config)
+		shardedJobs[instanceName] = append(shardedJobs[instanceName], &j.Config)
 	}
 
 	// Then pass the jobs to instanceScraper, creating them if we need to.
@@ -150,9 +208,60 @@ func (s *Scraper) ApplyConfig(jobs []*ScrapeConfig) error {
 		}
 	}
 
+	// Delete the instances of tenants which are no longer used.
+	for name := range s.tenantInstances {
+		if _, current := tenantInstances[name]; !current {
+			s.deleteTenantInstance(name)
+		}
+	}
+	s.tenantInstances = tenantInstances
+
 	return firstError
 }
 
+// tenantInstanceName returns the name of the instance which sends metrics to
+// the remote_write endpoints of instanceName for tenant.
+func tenantInstanceName(instanceName, tenant string) string {
+	return fmt.Sprintf("%s-tenant-%s", instanceName, tenant)
+}
+
+// applyTenantInstance creates or updates the instance which sends metrics to
+// the remote_write endpoints of instanceName for tenant. The instance is a
+// copy of instanceName without scrape configs, and with the X-Scope-OrgID
+// header of its remote_write configs set to tenant.
+func (s *Scraper) applyTenantInstance(instanceName, tenant string) error {
+	base, ok := s.is.ListConfigs()[instanceName]
+	if !ok {
+		return fmt.Errorf("instance %s not found", instanceName)
+	}
+	cfg, err := base.Clone()
+	if err != nil {
+		return fmt.Errorf("failed to copy instance %s: %w", instanceName, err)
+	}
+
+	cfg.Name = tenantInstanceName(instanceName, tenant)
+	cfg.ScrapeConfigs = nil
+	for _, rw := range cfg.RemoteWrite {
+		// Names of remote_write configs must be unique across instances.
+		rw.Name = fmt.Sprintf("%s-tenant-%s", rw.Name, tenant)
+		if rw.Headers == nil {
+			rw.Headers = map[string]string{}
+		}
+		rw.Headers["X-Scope-OrgID"] = tenant
+	}
+
+	if err := s.is.ApplyConfig(cfg); err != nil {
+		return fmt.Errorf("failed to apply instance for tenant %s: %w", tenant, err)
+	}
+	return nil
+}
+
+func (s *Scraper) deleteTenantInstance(name string) {
+	if err := s.is.DeleteConfig(name); err != nil {
+		level.Warn(s.log).Log("msg", "failed to delete tenant instance", "instance", name, "err", err)
+	}
+}
+
 // TargetsActive returns the set of active scrape targets for all target
 // instances.
 func (s *Scraper) TargetsActive() map[string]metrics.TargetSet {
@@ -175,6 +284,10 @@ func (s *Scraper) Stop() {
 		is.Stop()
 		delete(s.iscrapers, instance)
 	}
+	for name := range s.tenantInstances {
+		s.deleteTenantInstance(name)
+	}
+	s.tenantInstances = nil
 
 	s.cancel()
 }
@@ -3,23 +3,57 @@ package autoscrape
 import (
 	"context"
 	"net/http/httptest"
+	"net/url"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/grafana/agent/pkg/metrics/instance"
 	"github.com/grafana/agent/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	config_util "github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
 	prom_config "github.com/prometheus/prometheus/config"
 	"github.com/prometheus/prometheus/discovery"
+	_ "github.com/prometheus/prometheus/discovery/file" // Register file_sd_configs
+	_ "github.com/prometheus/prometheus/discovery/http" // Register http_sd_configs
 	"github.com/prometheus/prometheus/pkg/exemplar"
 	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
 )
 
+func TestConfig_ExtraTargets(t *testing.T) {
+	in := `
+extra_targets:
+  http_client_config:
+    authorization:
+      credentials_file: /etc/targets/token
+  file_sd_configs:
+  - files: [/etc/targets/*.json]
+  http_sd_configs:
+  - url: http://localhost:8080/targets
+`
+
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(in), &cfg))
+	require.NotNil(t, cfg.ExtraTargets)
+	require.Len(t, cfg.ExtraTargets.ServiceDiscoveryConfigs, 2)
+
+	require.Equal(t, "file", cfg.ExtraTargets.ServiceDiscoveryConfigs[0].Name())
+	require.Equal(t, "http", cfg.ExtraTargets.ServiceDiscoveryConfigs[1].Name())
+	require.Equal(t, "/etc/targets/token", cfg.ExtraTargets.HTTPClientConfig.Authorization.CredentialsFile)
+
+	out, err := yaml.Marshal(&cfg)
+	require.NoError(t, err)
+	var roundTrip Config
+	require.NoError(t, yaml.UnmarshalStrict(out, &roundTrip))
+	require.Equal(t, cfg, roundTrip)
+}
+
 // TestAutoscrape is a basic end-to-end test of the autoscraper.
 func TestAutoscrape(t *testing.T) {
 	srv := httptest.NewServer(promhttp.Handler())
@@ -68,6 +102,125 @@ func TestAutoscrape(t *testing.T) {
 	require.NoError(t, wt.Wait(5*time.Second), "timed out waiting for scrape")
 }
 
+// TestAutoscrape_Exemplars ensures that exemplars exposed by integrations over
+// OpenMetrics are appended to instances.
+func TestAutoscrape_Exemplars(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	c := prometheus.NewCounter(prometheus.CounterOpts{Name: "requests_total", Help: "requests_total"})
+	reg.MustRegister(c)
+	c.(prometheus.ExemplarAdder).AddWithExemplar(1, prometheus.Labels{"traceID": "abc123"})
+
+	srv := httptest.NewServer(promhttp.HandlerFor(reg, promhttp.HandlerOpts{EnableOpenMetrics: true}))
+	defer srv.Close()
+
+	wt := util.NewWaitTrigger()
+
+	app := noOpAppender
+	app.AppendExemplarFunc = func(ref uint64, l labels.Labels, e exemplar.Exemplar) (uint64, error) {
+		if e.Labels.Get("traceID") == "abc123" {
+			wt.Trigger()
+		}
+		return noOpAppender.AppendExemplarFunc(ref, l, e)
+	}
+
+	im := instance.MockManager{
+		GetInstanceFunc: func(name string) (instance.ManagedInstance, error) {
+			return &mockInstance{app: &app}, nil
+		},
+	}
+	as := NewScraper(util.TestLogger(t), im)
+	defer as.Stop()
+
+	err := as.ApplyConfig([]*ScrapeConfig{{
+		Instance: t.Name(),
+		Config: func() prom_config.ScrapeConfig {
+			cfg := prom_config.DefaultScrapeConfig
+			cfg.JobName = t.Name()
+			cfg.ScrapeInterval = model.Duration(time.Second)
+			cfg.ScrapeTimeout = model.Duration(time.Second)
+			cfg.ServiceDiscoveryConfigs = discovery.Configs{
+				discovery.StaticConfig{{
+					Targets: []model.LabelSet{{
+						model.AddressLabel: model.LabelValue(srv.Listener.Addr().String()),
+					}},
+					Source: t.Name(),
+				}},
+			}
+			return cfg
+		}(),
+	}})
+	require.NoError(t, err, "failed to apply configs")
+
+	// SD won't start sending targets until after 5 seconds.
+	time.Sleep(5 * time.Second)
+
+	require.NoError(t, wt.Wait(5*time.Second), "timed out waiting for exemplar")
+}
+
+func TestAutoscrape_TenantInstances(t *testing.T) {
+	rwURL, err := url.Parse("http://localhost:9009/api/prom/push")
+	require.NoError(t, err)
+	rw := &prom_config.RemoteWriteConfig{
+		URL:     &config_util.URL{URL: rwURL},
+		Name:    "default-abcdef",
+		Headers: map[string]string{"X-Custom": "value"},
+	}
+	base := instance.DefaultConfig
+	base.Name = "default"
+	base.ScrapeConfigs = []*prom_config.ScrapeConfig{{JobName: "job"}}
+	base.RemoteWrite = []*prom_config.RemoteWriteConfig{rw}
+
+	var (
+		applied = map[string]instance.Config{}
+		deleted []string
+	)
+	im := instance.MockManager{
+		GetInstanceFunc: func(name string) (instance.ManagedInstance, error) {
+			return &mockInstance{app: &noOpAppender}, nil
+		},
+		ListConfigsFunc: func() map[string]instance.Config {
+			return map[string]instance.Config{"default": base}
+		},
+		ApplyConfigFunc: func(c instance.Config) error {
+			applied[c.Name] = c
+			return nil
+		},
+		DeleteConfigFunc: func(name string) error {
+			deleted = append(deleted, name)
+			return nil
+		},
+	}
+	as := NewScraper(util.TestLogger(t), im)
+	defer as.Stop()
+
+	job := func(name, tenant string) *ScrapeConfig {
+		cfg := prom_config.DefaultScrapeConfig
+		cfg.JobName = name
+		return &ScrapeConfig{Instance: "default", Config: cfg, TenantID: tenant}
+	}
+
+	err = as.ApplyConfig([]*ScrapeConfig{job("a", "team-a"), job("b", "team-b"), job("c", "")})
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	require.Contains(t, as.TargetsActive(), "default-tenant-team-a")
+	require.Contains(t, as.TargetsActive(), "default")
+
+	tenantCfg := applied["default-tenant-team-a"]
+	require.Empty(t, tenantCfg.ScrapeConfigs)
+	require.Len(t, tenantCfg.RemoteWrite, 1)
+	require.Equal(t, "default-abcdef-tenant-team-a", tenantCfg.RemoteWrite[0].Name)
+	require.Equal(t, map[string]string{"X-Custom": "value", "X-Scope-OrgID": "team-a"}, tenantCfg.RemoteWrite[0].Headers)
+
+	// The base instance config must not be modified.
+	require.Equal(t, "default-abcdef", rw.Name)
+	require.Equal(t, map[string]string{"X-Custom": "value"}, rw.Headers)
+
+	// Instances of tenants which are no longer used are deleted.
+	err = as.ApplyConfig([]*ScrapeConfig{job("a", "team-a")})
+	require.NoError(t, err)
+	require.Equal(t, []string{"default-tenant-team-b"}, deleted)
+}
+
 var globalRef uint64
 var noOpAppender = mockAppender{
 	AppendFunc: func(ref uint64, l labels.Labels, t int64, v float64) (uint64, error) {
@@ -0,0 +1,177 @@
+package common
+
+import (
+	"crypto/subtle"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	config_util "github.com/prometheus/common/config"
+)
+
+// EndpointAuth configures authentication for the HTTP endpoints of
+// integrations. Requests must satisfy every configured method.
+type EndpointAuth struct {
+	// BasicAuth requires requests to use HTTP basic authentication.
+	BasicAuth *EndpointBasicAuth `yaml:"basic_auth,omitempty"`
+	// ClientCAFile requires requests to present a TLS client certificate
+	// signed by one of the CAs in the file. The HTTP server of the agent must
+	// be configured to request client certificates.
+	ClientCAFile string `yaml:"client_ca_file,omitempty"`
+	// ClientCertFile and ClientKeyFile hold the TLS client certificate which
+	// autoscrape presents when ClientCAFile is set.
+	ClientCertFile string `yaml:"client_cert_file,omitempty"`
+	ClientKeyFile  string `yaml:"client_key_file,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for EndpointAuth.
+func (a *EndpointAuth) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain EndpointAuth
+	if err := unmarshal((*plain)(a)); err != nil {
+		return err
+	}
+
+	if (a.ClientCertFile == "") != (a.ClientKeyFile == "") {
+		return fmt.Errorf("client_cert_file and client_key_file must be set together")
+	}
+	if a.ClientCertFile != "" && a.ClientCAFile == "" {
+		return fmt.Errorf("client_cert_file requires client_ca_file to be set")
+	}
+	return nil
+}
+
+// ApplyClientConfig sets the credentials scrapers must use in cfg.
+func (a *EndpointAuth) ApplyClientConfig(cfg *config_util.HTTPClientConfig) {
+	if a == nil {
+		return
+	}
+	if a.BasicAuth != nil {
+		cfg.BasicAuth = a.BasicAuth.ClientConfig()
+	}
+	if a.ClientCertFile != "" {
+		cfg.TLSConfig.CertFile = a.ClientCertFile
+		cfg.TLSConfig.KeyFile = a.ClientKeyFile
+	}
+}
+
+// EndpointBasicAuth holds the credentials requests must use for HTTP basic
+// authentication.
+type EndpointBasicAuth struct {
+	Username     string             `yaml:"username"`
+	Password     config_util.Secret `yaml:"password,omitempty"`
+	PasswordFile string             `yaml:"password_file,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for EndpointBasicAuth.
+func (a *EndpointBasicAuth) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	type plain EndpointBasicAuth
+	if err := unmarshal((*plain)(a)); err != nil {
+		return err
+	}
+
+	if a.Username == "" {
+		return fmt.Errorf("basic_auth username must be set")
+	}
+	if (a.Password == "") == (a.PasswordFile == "") {
+		return fmt.Errorf("exactly one of basic_auth password and password_file must be set")
+	}
+	return nil
+}
+
+// ClientConfig returns the basic auth settings scrapers must use.
+func (a *EndpointBasicAuth) ClientConfig() *config_util.BasicAuth {
+	return &config_util.BasicAuth{
+		Username:     a.Username,
+		Password:     a.Password,
+		PasswordFile: a.PasswordFile,
+	}
+}
+
+func (a *EndpointBasicAuth) password() (string, error) {
+	if a.PasswordFile == "" {
+		return string(a.Password), nil
+	}
+	bb, err := ioutil.ReadFile(a.PasswordFile)
+	if err != nil {
+		return "", fmt.Errorf("unable to read basic_auth password file %s: %w", a.PasswordFile, err)
+	}
+	return strings.TrimSpace(string(bb)), nil
+}
+
+// Handler wraps next with a handler which rejects requests that don't
+// satisfy a. next is returned unmodified when a is nil. The basic auth
+// password file is read for every request, so the password can be rotated
+// without restarting the integration.
+func (a *EndpointAuth) Handler(next http.Handler) (http.Handler, error) {
+	if a == nil {
+		return next, nil
+	}
+
+	if a.BasicAuth != nil {
+		// Fail early if the password file can't be read.
+		if _, err := a.BasicAuth.password(); err != nil {
+			return nil, err
+		}
+		next = basicAuthHandler(a.BasicAuth, next)
+	}
+
+	if a.ClientCAFile != "" {
+		bb, err := ioutil.ReadFile(a.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read client CA file %s: %w", a.ClientCAFile, err)
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(bb) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", a.ClientCAFile)
+		}
+		next = clientCertHandler(roots, next)
+	}
+
+	return next, nil
+}
+
+func basicAuthHandler(a *EndpointBasicAuth, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		password, err := a.password()
+		if err != nil {
+			http.Error(rw, "unable to read basic_auth password", http.StatusInternalServerError)
+			return
+		}
+
+		u, p, ok := r.BasicAuth()
+		// Both credentials are always compared to not leak which one is wrong.
+		validUser := subtle.ConstantTimeCompare([]byte(u), []byte(a.Username)) == 1
+		validPassword := subtle.ConstantTimeCompare([]byte(p), []byte(password)) == 1
+		if !ok || !validUser || !validPassword {
+			rw.Header().Set("WWW-Authenticate", `Basic realm="integrations"`)
+			http.Error(rw, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+func clientCertHandler(roots *x509.CertPool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			http.Error(rw, "client certificate required", http.StatusForbidden)
+			return
+		}
+
+		opts := x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: x509.NewCertPool(),
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+		for _, cert := range r.TLS.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		if _, err := r.TLS.PeerCertificates[0].Verify(opts); err != nil {
+			http.Error(rw, "invalid client certificate", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
@@ -0,0 +1,165 @@
+package common
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+var okHandler = http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+	rw.WriteHeader(http.StatusOK)
+})
+
+func TestEndpointAuth_Unmarshal(t *testing.T) {
+	tt := []struct {
+		name string
+		in   string
+		err  string
+	}{
+		{name: "valid", in: "basic_auth: {username: admin, password: secret}"},
+		{name: "no username", in: "basic_auth: {password: secret}", err: "basic_auth username must be set"},
+		{name: "no password", in: "basic_auth: {username: admin}", err: "exactly one of basic_auth password and password_file must be set"},
+		{
+			name: "password and password_file",
+			in:   "basic_auth: {username: admin, password: secret, password_file: /etc/password}",
+			err:  "exactly one of basic_auth password and password_file must be set",
+		},
+		{
+			name: "client certificate",
+			in:   "{client_ca_file: /etc/ca.pem, client_cert_file: /etc/cert.pem, client_key_file: /etc/key.pem}",
+		},
+		{
+			name: "client certificate without key",
+			in:   "{client_ca_file: /etc/ca.pem, client_cert_file: /etc/cert.pem}",
+			err:  "client_cert_file and client_key_file must be set together",
+		},
+		{
+			name: "client certificate without CA",
+			in:   "{client_cert_file: /etc/cert.pem, client_key_file: /etc/key.pem}",
+			err:  "client_cert_file requires client_ca_file to be set",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var a EndpointAuth
+			err := yaml.UnmarshalStrict([]byte(tc.in), &a)
+			if tc.err == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestEndpointAuth_Handler(t *testing.T) {
+	t.Run("no auth", func(t *testing.T) {
+		var a *EndpointAuth
+		h, err := a.Handler(okHandler)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, serve(h, httptest.NewRequest(http.MethodGet, "/metrics", nil)))
+	})
+
+	t.Run("basic auth", func(t *testing.T) {
+		passwordFile := filepath.Join(t.TempDir(), "password")
+		require.NoError(t, ioutil.WriteFile(passwordFile, []byte("secret\n"), 0600))
+
+		a := &EndpointAuth{BasicAuth: &EndpointBasicAuth{Username: "admin", PasswordFile: passwordFile}}
+		h, err := a.Handler(okHandler)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		require.Equal(t, http.StatusUnauthorized, serve(h, req))
+
+		req.SetBasicAuth("admin", "wrong")
+		require.Equal(t, http.StatusUnauthorized, serve(h, req))
+
+		req.SetBasicAuth("admin", "secret")
+		require.Equal(t, http.StatusOK, serve(h, req))
+
+		// The password file is read for every request.
+		require.NoError(t, ioutil.WriteFile(passwordFile, []byte("rotated\n"), 0600))
+		require.Equal(t, http.StatusUnauthorized, serve(h, req))
+		req.SetBasicAuth("admin", "rotated")
+		require.Equal(t, http.StatusOK, serve(h, req))
+	})
+
+	t.Run("client certificates", func(t *testing.T) {
+		caCert, caKey := newTestCert(t, nil, nil)
+		clientCert, _ := newTestCert(t, caCert, caKey)
+		otherCert, _ := newTestCert(t, nil, nil)
+
+		caFile := filepath.Join(t.TempDir(), "ca.pem")
+		caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw})
+		require.NoError(t, ioutil.WriteFile(caFile, caPEM, 0600))
+
+		a := &EndpointAuth{ClientCAFile: caFile}
+		h, err := a.Handler(okHandler)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		require.Equal(t, http.StatusForbidden, serve(h, req))
+
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{otherCert}}
+		require.Equal(t, http.StatusForbidden, serve(h, req))
+
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{clientCert}}
+		require.Equal(t, http.StatusOK, serve(h, req))
+	})
+
+	t.Run("missing client CA file", func(t *testing.T) {
+		a := &EndpointAuth{ClientCAFile: filepath.Join(t.TempDir(), "missing.pem")}
+		_, err := a.Handler(okHandler)
+		require.Error(t, err)
+	})
+}
+
+func serve(h http.Handler, req *http.Request) int {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+// newTestCert creates a certificate signed by parent. A self-signed CA
+// certificate is created when parent is nil.
+func newTestCert(t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if parent == nil {
+		tmpl.Subject.CommonName = "ca"
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
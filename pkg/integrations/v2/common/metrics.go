@@ -1,8 +1,12 @@
 package common
 
 import (
+	"fmt"
+
 	"github.com/grafana/agent/pkg/integrations/v2/autoscrape"
+	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
 )
 
 // MetricsConfig is a set of common options shared by metrics integrations. It
@@ -16,6 +20,21 @@ type MetricsConfig struct {
 	Autoscrape  autoscrape.Config `yaml:"autoscrape,omitempty"`
 	InstanceKey *string           `yaml:"instance,omitempty"`
 	ExtraLabels labels.Labels     `yaml:"extra_labels,omitempty"`
+
+	// MetricAllow and MetricDeny filter the metrics exposed by the integration
+	// by name. When MetricAllow is set, only metrics matching one of its
+	// regular expressions are exposed. Metrics matching one of the regular
+	// expressions of MetricDeny are never exposed.
+	MetricAllow []relabel.Regexp `yaml:"metric_allow,omitempty"`
+	MetricDeny  []relabel.Regexp `yaml:"metric_deny,omitempty"`
+
+	// EndpointAuth overrides the authentication of the integration's HTTP
+	// endpoints. Defaults to integrations.metrics.endpoint_auth.
+	EndpointAuth *EndpointAuth `yaml:"endpoint_auth,omitempty"`
+
+	// TenantID sets the X-Scope-OrgID header when writing autoscraped metrics
+	// to the remote_write endpoints of the autoscrape metrics instance.
+	TenantID string `yaml:"tenant_id,omitempty"`
 }
 
 // ApplyDefaults applies defaults to mc.
@@ -34,3 +53,66 @@ func (mc *MetricsConfig) ApplyDefaults(g autoscrape.Global) {
 		mc.Autoscrape.ScrapeTimeout = g.ScrapeTimeout
 	}
 }
+
+// ScrapeLimits are the limits of the autoscrape settings an integration can
+// be scraped with. Scrapes of the integration are likely to fail when its
+// settings don't satisfy the limits.
+type ScrapeLimits struct {
+	// MinScrapeInterval is the shortest scrape_interval supported by the
+	// integration.
+	MinScrapeInterval model.Duration
+	// MinScrapeTimeout is the shortest scrape_timeout in which the integration
+	// is able to respond to a scrape.
+	MinScrapeTimeout model.Duration
+}
+
+// ScrapeLimitsProvider is implemented by integration configs which declare
+// ScrapeLimits.
+type ScrapeLimitsProvider interface {
+	// ScrapeLimits returns the ScrapeLimits of the integration.
+	ScrapeLimits() ScrapeLimits
+}
+
+// ApplyDefaultsWithLimits applies defaults to mc and validates its autoscrape
+// settings against limits. Settings inherited from g are adjusted to satisfy
+// limits, while an error is returned for settings of mc which don't satisfy
+// them.
+func (mc *MetricsConfig) ApplyDefaultsWithLimits(g autoscrape.Global, limits ScrapeLimits) error {
+	var (
+		as = &mc.Autoscrape
+
+		inheritedInterval = as.ScrapeInterval == 0
+		inheritedTimeout  = as.ScrapeTimeout == 0
+	)
+	mc.ApplyDefaults(g)
+
+	if inheritedTimeout && as.ScrapeTimeout < limits.MinScrapeTimeout {
+		as.ScrapeTimeout = limits.MinScrapeTimeout
+	}
+	if inheritedInterval {
+		if as.ScrapeInterval < limits.MinScrapeInterval {
+			as.ScrapeInterval = limits.MinScrapeInterval
+		}
+		if as.ScrapeInterval < as.ScrapeTimeout {
+			as.ScrapeInterval = as.ScrapeTimeout
+		}
+	}
+	// Like Prometheus, an inherited timeout is capped to the interval.
+	if inheritedTimeout && as.ScrapeTimeout > as.ScrapeInterval {
+		as.ScrapeTimeout = as.ScrapeInterval
+	}
+
+	// Settings only need to be valid when the integration is autoscraped.
+	if !*as.Enable {
+		return nil
+	}
+	switch {
+	case as.ScrapeInterval < limits.MinScrapeInterval:
+		return fmt.Errorf("autoscrape scrape_interval %s is shorter than the minimum of %s supported by the integration", as.ScrapeInterval, limits.MinScrapeInterval)
+	case as.ScrapeTimeout < limits.MinScrapeTimeout:
+		return fmt.Errorf("autoscrape scrape_timeout %s is shorter than the minimum of %s needed by the integration", as.ScrapeTimeout, limits.MinScrapeTimeout)
+	case as.ScrapeTimeout > as.ScrapeInterval:
+		return fmt.Errorf("autoscrape scrape_timeout %s is greater than scrape_interval %s", as.ScrapeTimeout, as.ScrapeInterval)
+	}
+	return nil
+}
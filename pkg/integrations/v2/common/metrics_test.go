@@ -0,0 +1,116 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/pkg/integrations/v2/autoscrape"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsConfig_ApplyDefaultsWithLimits(t *testing.T) {
+	global := autoscrape.Global{
+		Enable:          true,
+		MetricsInstance: "default",
+		ScrapeInterval:  model.Duration(15 * time.Second),
+		ScrapeTimeout:   model.Duration(10 * time.Second),
+	}
+	limits := ScrapeLimits{
+		MinScrapeInterval: model.Duration(20 * time.Second),
+		MinScrapeTimeout:  model.Duration(30 * time.Second),
+	}
+
+	tt := []struct {
+		name           string
+		interval       time.Duration
+		timeout        time.Duration
+		disabled       bool
+		limits         ScrapeLimits
+		expectInterval time.Duration
+		expectTimeout  time.Duration
+		expectError    string
+	}{
+		{
+			name:           "no limits",
+			expectInterval: 15 * time.Second,
+			expectTimeout:  10 * time.Second,
+		},
+		{
+			name:           "inherited settings are raised",
+			limits:         limits,
+			expectInterval: 30 * time.Second,
+			expectTimeout:  30 * time.Second,
+		},
+		{
+			name:           "inherited interval is raised to explicit timeout",
+			timeout:        45 * time.Second,
+			limits:         limits,
+			expectInterval: 45 * time.Second,
+			expectTimeout:  45 * time.Second,
+		},
+		{
+			name:           "inherited timeout is capped to interval",
+			interval:       5 * time.Second,
+			expectInterval: 5 * time.Second,
+			expectTimeout:  5 * time.Second,
+		},
+		{
+			name:           "explicit settings satisfy limits",
+			interval:       time.Minute,
+			timeout:        40 * time.Second,
+			limits:         limits,
+			expectInterval: time.Minute,
+			expectTimeout:  40 * time.Second,
+		},
+		{
+			name:        "explicit interval too short",
+			interval:    10 * time.Second,
+			timeout:     10 * time.Second,
+			limits:      ScrapeLimits{MinScrapeInterval: limits.MinScrapeInterval},
+			expectError: "autoscrape scrape_interval 10s is shorter than the minimum of 20s supported by the integration",
+		},
+		{
+			name:        "explicit timeout too short",
+			timeout:     10 * time.Second,
+			limits:      limits,
+			expectError: "autoscrape scrape_timeout 10s is shorter than the minimum of 30s needed by the integration",
+		},
+		{
+			name:        "explicit timeout greater than interval",
+			interval:    10 * time.Second,
+			timeout:     20 * time.Second,
+			expectError: "autoscrape scrape_timeout 20s is greater than scrape_interval 10s",
+		},
+		{
+			name:           "autoscrape disabled",
+			interval:       10 * time.Second,
+			timeout:        10 * time.Second,
+			disabled:       true,
+			limits:         limits,
+			expectInterval: 10 * time.Second,
+			expectTimeout:  10 * time.Second,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var mc MetricsConfig
+			mc.Autoscrape.ScrapeInterval = model.Duration(tc.interval)
+			mc.Autoscrape.ScrapeTimeout = model.Duration(tc.timeout)
+			if tc.disabled {
+				enable := false
+				mc.Autoscrape.Enable = &enable
+			}
+
+			err := mc.ApplyDefaultsWithLimits(global, tc.limits)
+			if tc.expectError != "" {
+				require.EqualError(t, err, tc.expectError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, model.Duration(tc.expectInterval), mc.Autoscrape.ScrapeInterval)
+			require.Equal(t, model.Duration(tc.expectTimeout), mc.Autoscrape.ScrapeTimeout)
+		})
+	}
+}
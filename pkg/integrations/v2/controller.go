@@ -175,6 +175,26 @@ NextConfig:
 	return nil
 }
 
+// activeConfig is the config of an integration managed by the controller.
+type activeConfig struct {
+	id      integrationID
+	c       Config
+	running bool
+}
+
+// Configs returns the configs of the integrations managed by the controller.
+// Defaults have already been applied to the returned configs.
+func (c *controller) Configs() []activeConfig {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	res := make([]activeConfig, 0, len(c.integrations))
+	for _, ci := range c.integrations {
+		res = append(res, activeConfig{id: ci.id, c: ci.c, running: ci.Running()})
+	}
+	return res
+}
+
 // Handler returns an HTTP handler for the controller and its integrations.
 // Handler will pass through requests to other running integrations. Handler
 // always returns an http.Handler regardless of error.
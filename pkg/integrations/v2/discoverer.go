@@ -0,0 +1,157 @@
+package integrations
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/prometheus/discovery"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// discoverer discovers the targets of Templates and expands them into the
+// configs of integration instances.
+type discoverer struct {
+	log log.Logger
+	mgr *discovery.Manager
+
+	mut       sync.Mutex
+	templates map[string]*Template            // Templates by discovery set name.
+	groups    map[string][]*targetgroup.Group // Discovered groups by discovery set name.
+}
+
+// newDiscoverer creates a new discoverer. Discovery stops when ctx is
+// canceled.
+func newDiscoverer(ctx context.Context, l log.Logger) *discoverer {
+	l = log.With(l, "component", "integrations discovery")
+	return &discoverer{
+		log: l,
+		mgr: discovery.NewManager(ctx, l, discovery.Name("integrations")),
+	}
+}
+
+// ApplyConfig updates the Templates to discover targets for.
+func (d *discoverer) ApplyConfig(tt Templates) error {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	var (
+		templates = make(map[string]*Template, len(tt))
+		sdConfigs = make(map[string]discovery.Configs, len(tt))
+	)
+	for i, t := range tt {
+		setName := fmt.Sprintf("%s/%d", t.Name(), i)
+		templates[setName] = t
+		sdConfigs[setName] = t.Discovery.ServiceDiscoveryConfigs
+	}
+	if err := d.mgr.ApplyConfig(sdConfigs); err != nil {
+		return err
+	}
+
+	d.templates = templates
+	return nil
+}
+
+// run runs service discovery until ctx is canceled. onUpdate is invoked
+// whenever the discovered targets change.
+func (d *discoverer) run(ctx context.Context, onUpdate func()) {
+	go func() {
+		if err := d.mgr.Run(); err != nil {
+			level.Error(d.log).Log("msg", "integrations service discovery exited with error", "err", err)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case groups := <-d.mgr.SyncCh():
+			d.mut.Lock()
+			d.groups = groups
+			d.mut.Unlock()
+
+			onUpdate()
+		}
+	}
+}
+
+// Configs returns the configs of integration instances for the currently
+// discovered targets. Targets whose config can't be created and targets
+// which would duplicate an instance, including instances of the static
+// configs, are skipped.
+func (d *discoverer) Configs(globals Globals, static Configs) Configs {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+
+	ids := make(map[integrationID]struct{}, len(static))
+	for _, c := range static {
+		// Errors are ignored here and reported when the static configs are
+		// applied.
+		if identifier, err := c.Identifier(globals); err == nil {
+			ids[integrationID{Name: c.Name(), Identifier: identifier}] = struct{}{}
+		}
+	}
+
+	setNames := make([]string, 0, len(d.templates))
+	for setName := range d.templates {
+		setNames = append(setNames, setName)
+	}
+	sort.Strings(setNames)
+
+	var res Configs
+	for _, setName := range setNames {
+		t := d.templates[setName]
+
+		for _, lset := range discoveredTargets(d.groups[setName], t.Discovery.RelabelConfigs) {
+			c, err := t.Expand(lset)
+			if err != nil {
+				level.Warn(d.log).Log("msg", "failed to create config for discovered target", "integration", t.Name(), "target", lset, "err", err)
+				continue
+			}
+			identifier, err := c.Identifier(globals)
+			if err != nil {
+				level.Warn(d.log).Log("msg", "failed to build identifier for discovered target", "integration", t.Name(), "target", lset, "err", err)
+				continue
+			}
+
+			id := integrationID{Name: c.Name(), Identifier: identifier}
+			if _, exist := ids[id]; exist {
+				level.Debug(d.log).Log("msg", "skipping discovered target for existing instance", "integration", t.Name(), "instance", identifier, "target", lset)
+				continue
+			}
+			ids[id] = struct{}{}
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// discoveredTargets returns the labels of the targets of groups after
+// applying relabelConfigs. Dropped targets are omitted.
+func discoveredTargets(groups []*targetgroup.Group, relabelConfigs []*relabel.Config) []labels.Labels {
+	var res []labels.Labels
+	for _, group := range groups {
+		for _, target := range group.Targets {
+			lset := make(map[string]string, len(group.Labels)+len(target))
+			for name, value := range group.Labels {
+				lset[string(name)] = string(value)
+			}
+			// Labels of the target take precedence over labels of the group.
+			for name, value := range target {
+				lset[string(name)] = string(value)
+			}
+
+			processed := relabel.Process(labels.FromMap(lset), relabelConfigs...)
+			if processed == nil {
+				continue
+			}
+			res = append(res, processed)
+		}
+	}
+	return res
+}
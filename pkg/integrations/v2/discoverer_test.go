@@ -0,0 +1,86 @@
+package integrations
+
+import (
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestDiscoverer_Configs(t *testing.T) {
+	setRegistered(t, map[Config]Type{
+		&testDiscoveredIntegration{}: TypeMultiplex,
+	})
+
+	var cfgToParse = `
+discovered_configs:
+  - address: static:80
+  - address: '{{ .__address__ }}'
+    role: '{{ .role }}'
+    discovery:
+      static_configs:
+        - targets: [static:80, a:80, b:80, c:80]
+      relabel_configs:
+        - source_labels: [__address__]
+          regex: c:80
+          action: drop`
+
+	var fullCfg testFullConfig
+	require.NoError(t, yaml.UnmarshalStrict([]byte(cfgToParse), &fullCfg))
+	require.Len(t, fullCfg.Configs, 1)
+	require.Len(t, fullCfg.Templates, 1)
+
+	d := &discoverer{log: log.NewNopLogger()}
+	d.templates = map[string]*Template{"discovered/0": fullCfg.Templates[0]}
+	d.groups = map[string][]*targetgroup.Group{
+		"discovered/0": {{
+			Targets: []model.LabelSet{
+				{model.AddressLabel: "static:80"},
+				{model.AddressLabel: "a:80"},
+				{model.AddressLabel: "b:80", "role": "replica"},
+				{model.AddressLabel: "c:80"},
+			},
+			Labels: model.LabelSet{"role": "primary"},
+		}},
+	}
+
+	// static:80 duplicates the static config and c:80 is dropped by
+	// relabeling.
+	expect := Configs{
+		&testDiscoveredIntegration{Address: "a:80", Role: "primary"},
+		&testDiscoveredIntegration{Address: "b:80", Role: "replica"},
+	}
+	require.Equal(t, expect, d.Configs(Globals{}, fullCfg.Configs))
+}
+
+func TestDiscoveredTargets(t *testing.T) {
+	groups := []*targetgroup.Group{{
+		Targets: []model.LabelSet{
+			{model.AddressLabel: "a:80"},
+			{model.AddressLabel: "b:80", "env": "dev"},
+		},
+		Labels: model.LabelSet{"env": "prod"},
+	}}
+
+	expect := []labels.Labels{
+		labels.FromStrings(model.AddressLabel, "a:80", "env", "prod"),
+		labels.FromStrings(model.AddressLabel, "b:80", "env", "dev"),
+	}
+	require.Equal(t, expect, discoveredTargets(groups, nil))
+}
+
+type testDiscoveredIntegration struct {
+	Address string `yaml:"address"`
+	Role    string `yaml:"role,omitempty"`
+}
+
+func (i *testDiscoveredIntegration) Name() string                       { return "discovered" }
+func (i *testDiscoveredIntegration) ApplyDefaults(Globals) error        { return nil }
+func (i *testDiscoveredIntegration) Identifier(Globals) (string, error) { return i.Address, nil }
+func (i *testDiscoveredIntegration) NewIntegration(log.Logger, Globals) (Integration, error) {
+	return NoOpIntegration, nil
+}
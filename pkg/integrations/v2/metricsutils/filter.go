@@ -0,0 +1,119 @@
+package metricsutils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// newMetricFilter wraps next, an http.Handler which exposes metrics, with a
+// handler which only exposes the metric families whose name matches allow
+// and doesn't match deny. An empty allow list allows all metric families.
+// next is returned unmodified when both lists are empty.
+func newMetricFilter(allow, deny []relabel.Regexp, next http.Handler) http.Handler {
+	if len(allow) == 0 && len(deny) == 0 {
+		return next
+	}
+	return &metricFilter{allow: allow, deny: deny, next: next}
+}
+
+type metricFilter struct {
+	allow, deny []relabel.Regexp
+	next        http.Handler
+}
+
+func (f *metricFilter) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	// Request an uncompressed format which can always be decoded, regardless
+	// of the format requested by the scraper. The protobuf format retains
+	// exemplars, which are re-encoded when the scraper accepts OpenMetrics.
+	req := r.Clone(r.Context())
+	req.Header.Set("Accept", string(expfmt.FmtProtoDelim))
+	req.Header.Del("Accept-Encoding")
+
+	resp := &bufferedResponse{header: make(http.Header), code: http.StatusOK}
+	f.next.ServeHTTP(resp, req)
+
+	if resp.code != http.StatusOK {
+		resp.copyTo(rw)
+		return
+	}
+
+	var (
+		dec      = expfmt.NewDecoder(&resp.body, expfmt.ResponseFormat(resp.header))
+		families []*dto.MetricFamily
+	)
+	for {
+		var mf dto.MetricFamily
+		if err := dec.Decode(&mf); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			http.Error(rw, fmt.Sprintf("failed to decode metrics: %s", err), http.StatusInternalServerError)
+			return
+		}
+		if f.keep(mf.GetName()) {
+			families = append(families, &mf)
+		}
+	}
+
+	var (
+		format = expfmt.NegotiateIncludingOpenMetrics(r.Header)
+		buf    bytes.Buffer
+		enc    = expfmt.NewEncoder(&buf, format)
+	)
+	for _, mf := range families {
+		if err := enc.Encode(mf); err != nil {
+			http.Error(rw, fmt.Sprintf("failed to encode metrics: %s", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	if closer, ok := enc.(expfmt.Closer); ok {
+		if err := closer.Close(); err != nil {
+			http.Error(rw, fmt.Sprintf("failed to encode metrics: %s", err), http.StatusInternalServerError)
+			return
+		}
+	}
+	rw.Header().Set("Content-Type", string(format))
+	_, _ = buf.WriteTo(rw)
+}
+
+func (f *metricFilter) keep(name string) bool {
+	if len(f.allow) > 0 && !matchesAny(f.allow, name) {
+		return false
+	}
+	return !matchesAny(f.deny, name)
+}
+
+func matchesAny(res []relabel.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedResponse is an http.ResponseWriter which buffers the response.
+type bufferedResponse struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (r *bufferedResponse) Header() http.Header         { return r.header }
+func (r *bufferedResponse) Write(b []byte) (int, error) { return r.body.Write(b) }
+func (r *bufferedResponse) WriteHeader(code int)        { r.code = code }
+
+// copyTo writes the buffered response to rw.
+func (r *bufferedResponse) copyTo(rw http.ResponseWriter) {
+	for k, v := range r.header {
+		rw.Header()[k] = v
+	}
+	rw.WriteHeader(r.code)
+	_, _ = r.body.WriteTo(rw)
+}
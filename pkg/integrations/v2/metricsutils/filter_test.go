@@ -0,0 +1,134 @@
+package metricsutils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricFilter(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	for _, name := range []string{"node_cpu_seconds_total", "node_disk_io_time_seconds_total", "node_memory_MemFree_bytes", "node_scrape_collector_success"} {
+		c := prometheus.NewCounter(prometheus.CounterOpts{Name: name, Help: name})
+		reg.MustRegister(c)
+	}
+	next := promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+
+	tt := []struct {
+		name        string
+		allow, deny []string
+		expect      []string
+	}{
+		{
+			name:   "no filters",
+			expect: []string{"node_cpu_seconds_total", "node_disk_io_time_seconds_total", "node_memory_MemFree_bytes", "node_scrape_collector_success"},
+		},
+		{
+			name:   "allow",
+			allow:  []string{"node_cpu_.*", "node_memory_.*"},
+			expect: []string{"node_cpu_seconds_total", "node_memory_MemFree_bytes"},
+		},
+		{
+			name:   "deny",
+			deny:   []string{"node_disk_.*", "node_scrape_.*"},
+			expect: []string{"node_cpu_seconds_total", "node_memory_MemFree_bytes"},
+		},
+		{
+			name:   "deny takes precedence",
+			allow:  []string{"node_.*"},
+			deny:   []string{"node_memory_.*"},
+			expect: []string{"node_cpu_seconds_total", "node_disk_io_time_seconds_total", "node_scrape_collector_success"},
+		},
+		{
+			name:   "regexes are anchored",
+			allow:  []string{"cpu"},
+			expect: nil,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newMetricFilter(regexps(t, tc.allow), regexps(t, tc.deny), next)
+
+			for _, accept := range []expfmt.Format{expfmt.FmtText, expfmt.FmtProtoDelim} {
+				req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+				req.Header.Set("Accept", string(accept))
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, req)
+				require.Equal(t, http.StatusOK, rec.Code)
+
+				format := expfmt.ResponseFormat(rec.Header())
+				require.Equal(t, accept, format)
+
+				var (
+					dec   = expfmt.NewDecoder(rec.Body, format)
+					names []string
+				)
+				for {
+					var mf dto.MetricFamily
+					if err := dec.Decode(&mf); err != nil {
+						break
+					}
+					names = append(names, mf.GetName())
+				}
+				// The text decoder doesn't preserve the order of families.
+				sort.Strings(names)
+				require.Equal(t, tc.expect, names)
+			}
+		})
+	}
+}
+
+func TestMetricFilter_Exemplars(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	c := prometheus.NewCounter(prometheus.CounterOpts{Name: "requests_total", Help: "requests_total"})
+	reg.MustRegister(c)
+	c.(prometheus.ExemplarAdder).AddWithExemplar(1, prometheus.Labels{"traceID": "abc123"})
+
+	next := promhttp.HandlerFor(reg, promhttp.HandlerOpts{EnableOpenMetrics: true})
+	h := newMetricFilter(regexps(t, []string{"requests_.*"}), nil, next)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Accept", string(expfmt.FmtOpenMetrics))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, string(expfmt.FmtOpenMetrics), rec.Header().Get("Content-Type"))
+
+	body := rec.Body.String()
+	require.Contains(t, body, `requests_total 1.0 # {traceID="abc123"} 1.0`)
+	require.True(t, strings.HasSuffix(body, "# EOF\n"))
+}
+
+func TestMetricFilter_Error(t *testing.T) {
+	next := http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		http.Error(rw, "collector failed", http.StatusInternalServerError)
+	})
+	h := newMetricFilter(nil, regexps(t, []string{"node_.*"}), next)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.True(t, strings.HasPrefix(rec.Body.String(), "collector failed"))
+}
+
+func regexps(t *testing.T, exprs []string) []relabel.Regexp {
+	t.Helper()
+
+	res := make([]relabel.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := relabel.NewRegexp(expr)
+		require.NoError(t, err)
+		res = append(res, re)
+	}
+	return res
+}
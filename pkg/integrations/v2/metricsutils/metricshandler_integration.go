@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/url"
 	"path"
 
 	"github.com/go-kit/log"
@@ -30,7 +31,7 @@ func NewMetricsHandlerIntegration(
 	if err != nil {
 		return nil, err
 	}
-	return &metricsHandlerIntegration{
+	i := &metricsHandlerIntegration{
 		integrationName: c.Name(),
 		instanceID:      id,
 
@@ -39,7 +40,11 @@ func NewMetricsHandlerIntegration(
 		handler: h,
 
 		targets: []handlerTarget{{MetricsPath: "metrics"}},
-	}, nil
+	}
+	if err := i.checkAutoscrapeAuth(); err != nil {
+		return nil, err
+	}
+	return i, nil
 }
 
 type metricsHandlerIntegration struct {
@@ -56,6 +61,8 @@ type metricsHandlerIntegration struct {
 type handlerTarget struct {
 	// Path relative to Handler prefix where metrics are available.
 	MetricsPath string
+	// Query parameters to add to scrape requests.
+	QueryParams url.Values
 	// Extra labels to inject into the target. Labels here that take precedence
 	// over labels with the same name from the generated target group.
 	Labels model.LabelSet
@@ -82,11 +89,42 @@ func (i *metricsHandlerIntegration) RunIntegration(ctx context.Context) error {
 
 // Handler implements HTTPIntegration.
 func (i *metricsHandlerIntegration) Handler(prefix string) (http.Handler, error) {
+	handler := newMetricFilter(i.common.MetricAllow, i.common.MetricDeny, i.handler)
+	handler, err := i.endpointAuth().Handler(handler)
+	if err != nil {
+		return nil, fmt.Errorf("configuring endpoint_auth: %w", err)
+	}
+
 	r := mux.NewRouter()
-	r.Handle(path.Join(prefix, "metrics"), i.handler)
+	r.Handle(path.Join(prefix, "metrics"), handler)
 	return r, nil
 }
 
+// endpointAuth returns the authentication settings for the endpoints of the
+// integration, falling back to the settings of the subsystem.
+func (i *metricsHandlerIntegration) endpointAuth() *common.EndpointAuth {
+	if i.common.EndpointAuth != nil {
+		return i.common.EndpointAuth
+	}
+	return i.globals.SubsystemOpts.Metrics.EndpointAuth
+}
+
+// checkAutoscrapeAuth returns an error when autoscrape would be rejected by
+// the endpoint auth of the integration.
+func (i *metricsHandlerIntegration) checkAutoscrapeAuth() error {
+	if i.common.Autoscrape.Enable == nil || !*i.common.Autoscrape.Enable {
+		return nil
+	}
+	auth := i.endpointAuth()
+	if auth == nil || auth.ClientCAFile == "" {
+		return nil
+	}
+	if auth.ClientCertFile == "" && i.globals.SubsystemOpts.ClientConfig.TLSConfig.CertFile == "" {
+		return fmt.Errorf("endpoint_auth: client_cert_file must be set for autoscrape when client_ca_file is set")
+	}
+	return nil
+}
+
 // Targets implements MetricsIntegration.
 func (i *metricsHandlerIntegration) Targets(ep integrations.Endpoint) []*targetgroup.Group {
 	integrationNameValue := model.LabelValue("integrations/" + i.integrationName)
@@ -110,10 +148,16 @@ func (i *metricsHandlerIntegration) Targets(ep integrations.Endpoint) []*targetg
 	}
 
 	for _, t := range i.targets {
-		group.Targets = append(group.Targets, model.LabelSet{
+		target := model.LabelSet{
 			model.AddressLabel:     model.LabelValue(ep.Host),
 			model.MetricsPathLabel: model.LabelValue(path.Join(ep.Prefix, t.MetricsPath)),
-		}.Merge(t.Labels))
+		}
+		for k, v := range t.QueryParams {
+			if len(v) > 0 {
+				target[model.LabelName(model.ParamLabelPrefix+k)] = model.LabelValue(v[0])
+			}
+		}
+		group.Targets = append(group.Targets, target.Merge(t.Labels))
 	}
 
 	return []*targetgroup.Group{group}
@@ -138,14 +182,36 @@ func (i *metricsHandlerIntegration) ScrapeConfigs(sd discovery.Configs) []*autos
 	cfg.JobName = fmt.Sprintf("%s/%s", i.integrationName, i.instanceID)
 	cfg.Scheme = i.globals.AgentBaseURL.Scheme
 	cfg.HTTPClientConfig = i.globals.SubsystemOpts.ClientConfig
+	i.endpointAuth().ApplyClientConfig(&cfg.HTTPClientConfig)
 	cfg.ServiceDiscoveryConfigs = sd
 	cfg.ScrapeInterval = i.common.Autoscrape.ScrapeInterval
 	cfg.ScrapeTimeout = i.common.Autoscrape.ScrapeTimeout
 	cfg.RelabelConfigs = i.common.Autoscrape.RelabelConfigs
 	cfg.MetricRelabelConfigs = i.common.Autoscrape.MetricRelabelConfigs
 
-	return []*autoscrape.ScrapeConfig{{
+	res := []*autoscrape.ScrapeConfig{{
 		Instance: i.common.Autoscrape.MetricsInstance,
 		Config:   cfg,
+		TenantID: i.common.TenantID,
 	}}
+
+	// Extra targets are scraped by their own job so the credentials used to
+	// scrape the agent are never sent to them.
+	if extra := i.common.Autoscrape.ExtraTargets; extra != nil && len(extra.ServiceDiscoveryConfigs) > 0 {
+		extraCfg := config.DefaultScrapeConfig
+		extraCfg.JobName = fmt.Sprintf("%s/%s/extra_targets", i.integrationName, i.instanceID)
+		extraCfg.HTTPClientConfig = extra.HTTPClientConfig
+		extraCfg.ServiceDiscoveryConfigs = extra.ServiceDiscoveryConfigs
+		extraCfg.ScrapeInterval = cfg.ScrapeInterval
+		extraCfg.ScrapeTimeout = cfg.ScrapeTimeout
+		extraCfg.RelabelConfigs = cfg.RelabelConfigs
+		extraCfg.MetricRelabelConfigs = cfg.MetricRelabelConfigs
+
+		res = append(res, &autoscrape.ScrapeConfig{
+			Instance: i.common.Autoscrape.MetricsInstance,
+			Config:   extraCfg,
+			TenantID: i.common.TenantID,
+		})
+	}
+	return res
 }
@@ -3,13 +3,16 @@ package metricsutils
 import (
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
 
 	"github.com/go-kit/log"
 	"github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/autoscrape"
 	"github.com/grafana/agent/pkg/integrations/v2/common"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery"
 	"github.com/prometheus/prometheus/discovery/targetgroup"
 	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/stretchr/testify/require"
@@ -69,9 +72,140 @@ func TestMetricsHandlerIntegration_Targets(t *testing.T) {
 				require.Equal(t, lbl.Value, string(val), "extra label %s does not match expectation", lbl.Name)
 			}
 		})
+
+		t.Run("Query params", func(t *testing.T) {
+			i, err := NewMetricsHandlerIntegration(nil, fakeConfig{}, cfg, globals, http.NotFoundHandler())
+			require.NoError(t, err)
+			i.(*metricsHandlerIntegration).targets = []handlerTarget{{
+				MetricsPath: "metrics",
+				QueryParams: url.Values{"target": {"http://example.com"}},
+			}}
+
+			actual := i.Targets(integrations.Endpoint{Host: "test", Prefix: "/test/"})
+			require.Len(t, actual, 1)
+			require.Equal(t, []model.LabelSet{{
+				"__address__":      "test",
+				"__metrics_path__": "/test/metrics",
+				"__param_target":   "http://example.com",
+			}}, actual[0].Targets)
+		})
 	})
 }
 
+func TestMetricsHandlerIntegration_EndpointAuth(t *testing.T) {
+	globals := integrations.Globals{
+		AgentIdentifier: "testagent",
+		AgentBaseURL: func() *url.URL {
+			u, err := url.Parse("http://testagent/")
+			require.NoError(t, err)
+			return u
+		}(),
+		SubsystemOpts: integrations.DefaultSubsystemOptions,
+	}
+	globals.SubsystemOpts.Metrics.EndpointAuth = &common.EndpointAuth{
+		BasicAuth: &common.EndpointBasicAuth{Username: "admin", Password: "secret"},
+	}
+
+	ok := http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	serve := func(t *testing.T, i integrations.MetricsIntegration, username, password string) int {
+		t.Helper()
+
+		h, err := i.(integrations.HTTPIntegration).Handler("/test/")
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodGet, "/test/metrics", nil)
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("Inherited", func(t *testing.T) {
+		var cfg common.MetricsConfig
+		cfg.ApplyDefaults(globals.SubsystemOpts.Metrics.Autoscrape)
+
+		i, err := NewMetricsHandlerIntegration(nil, fakeConfig{}, cfg, globals, ok)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusUnauthorized, serve(t, i, "", ""))
+		require.Equal(t, http.StatusOK, serve(t, i, "admin", "secret"))
+
+		scrapeConfigs := i.ScrapeConfigs(nil)
+		require.Len(t, scrapeConfigs, 1)
+		require.Equal(t, "admin", scrapeConfigs[0].Config.HTTPClientConfig.BasicAuth.Username)
+	})
+
+	t.Run("Overridden", func(t *testing.T) {
+		cfg := common.MetricsConfig{EndpointAuth: &common.EndpointAuth{}}
+		cfg.ApplyDefaults(globals.SubsystemOpts.Metrics.Autoscrape)
+
+		i, err := NewMetricsHandlerIntegration(nil, fakeConfig{}, cfg, globals, ok)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, serve(t, i, "", ""))
+
+		scrapeConfigs := i.ScrapeConfigs(nil)
+		require.Len(t, scrapeConfigs, 1)
+		require.Nil(t, scrapeConfigs[0].Config.HTTPClientConfig.BasicAuth)
+	})
+
+	t.Run("Client certificate", func(t *testing.T) {
+		cfg := common.MetricsConfig{EndpointAuth: &common.EndpointAuth{ClientCAFile: "/etc/ca.pem"}}
+		cfg.ApplyDefaults(globals.SubsystemOpts.Metrics.Autoscrape)
+
+		_, err := NewMetricsHandlerIntegration(nil, fakeConfig{}, cfg, globals, ok)
+		require.EqualError(t, err, "endpoint_auth: client_cert_file must be set for autoscrape when client_ca_file is set")
+
+		cfg.EndpointAuth.ClientCertFile = "/etc/cert.pem"
+		cfg.EndpointAuth.ClientKeyFile = "/etc/key.pem"
+		i, err := NewMetricsHandlerIntegration(nil, fakeConfig{}, cfg, globals, ok)
+		require.NoError(t, err)
+
+		scrapeConfigs := i.ScrapeConfigs(nil)
+		require.Len(t, scrapeConfigs, 1)
+		require.Equal(t, "/etc/cert.pem", scrapeConfigs[0].Config.HTTPClientConfig.TLSConfig.CertFile)
+		require.Equal(t, "/etc/key.pem", scrapeConfigs[0].Config.HTTPClientConfig.TLSConfig.KeyFile)
+	})
+}
+
+func TestMetricsHandlerIntegration_ExtraTargets(t *testing.T) {
+	globals := integrations.Globals{
+		AgentIdentifier: "testagent",
+		AgentBaseURL: func() *url.URL {
+			u, err := url.Parse("http://testagent/")
+			require.NoError(t, err)
+			return u
+		}(),
+		SubsystemOpts: integrations.DefaultSubsystemOptions,
+	}
+
+	extraSD := discovery.StaticConfig{{
+		Targets: []model.LabelSet{{model.AddressLabel: "localhost:9100"}},
+	}}
+
+	var cfg common.MetricsConfig
+	cfg.Autoscrape.ExtraTargets = &autoscrape.ExtraTargetsConfig{
+		ServiceDiscoveryConfigs: discovery.Configs{extraSD},
+	}
+	cfg.ApplyDefaults(globals.SubsystemOpts.Metrics.Autoscrape)
+
+	i, err := NewMetricsHandlerIntegration(nil, fakeConfig{}, cfg, globals, http.NotFoundHandler())
+	require.NoError(t, err)
+
+	integrationSD := discovery.StaticConfig{{Source: "integration"}}
+	scrapeConfigs := i.ScrapeConfigs(discovery.Configs{integrationSD})
+	require.Len(t, scrapeConfigs, 2)
+	require.Equal(t, discovery.Configs{integrationSD}, scrapeConfigs[0].Config.ServiceDiscoveryConfigs)
+
+	extra := scrapeConfigs[1].Config
+	require.Equal(t, "fake/testagent/extra_targets", extra.JobName)
+	require.Equal(t, discovery.Configs{extraSD}, extra.ServiceDiscoveryConfigs)
+	require.Nil(t, extra.HTTPClientConfig.BasicAuth)
+}
+
 type fakeConfig struct{}
 
 func (fakeConfig) Name() string                                      { return "fake" }
@@ -37,7 +37,13 @@ func (s *configShim) LegacyConfig() (v1.Config, common.MetricsConfig) { return s
 func (s *configShim) Name() string { return s.orig.Name() }
 
 func (s *configShim) ApplyDefaults(g v2.Globals) error {
-	s.common.ApplyDefaults(g.SubsystemOpts.Metrics.Autoscrape)
+	var limits common.ScrapeLimits
+	if p, ok := s.orig.(common.ScrapeLimitsProvider); ok {
+		limits = p.ScrapeLimits()
+	}
+	if err := s.common.ApplyDefaultsWithLimits(g.SubsystemOpts.Metrics.Autoscrape, limits); err != nil {
+		return err
+	}
 	if id, err := s.Identifier(g); err == nil {
 		s.common.InstanceKey = &id
 	}
@@ -60,7 +66,7 @@ func (s *configShim) Identifier(g v2.Globals) (string, error) {
 }
 
 func (s *configShim) NewIntegration(l log.Logger, g v2.Globals) (v2.Integration, error) {
-	v1Integration, err := s.orig.NewIntegration(l)
+	v1Integration, err := v1.NewIntegration(s.orig, l, g.SubsystemOpts.Flags)
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +98,7 @@ func (s *configShim) NewIntegration(l log.Logger, g v2.Globals) (v2.Integration,
 	for _, sc := range v1ScrapeConfigs {
 		targets = append(targets, handlerTarget{
 			MetricsPath: sc.MetricsPath,
+			QueryParams: sc.QueryParams,
 			Labels: model.LabelSet{
 				model.JobLabel: model.LabelValue("integrations/" + sc.JobName),
 			},
@@ -115,7 +122,7 @@ func (s *configShim) NewIntegration(l log.Logger, g v2.Globals) (v2.Integration,
 	}
 
 	// Aggregate our converted settings into a v2 integration.
-	return &metricsHandlerIntegration{
+	i := &metricsHandlerIntegration{
 		integrationName: s.orig.Name(),
 		instanceID:      id,
 
@@ -125,5 +132,9 @@ func (s *configShim) NewIntegration(l log.Logger, g v2.Globals) (v2.Integration,
 		targets: targets,
 
 		runFunc: runFunc,
-	}, nil
+	}
+	if err := i.checkAutoscrapeAuth(); err != nil {
+		return nil, err
+	}
+	return i, nil
 }
@@ -25,6 +25,7 @@ var (
 
 	emptyStructType = reflect.TypeOf(struct{}{})
 	configsType     = reflect.TypeOf(Configs{})
+	templatesType   = reflect.TypeOf(Templates{})
 )
 
 // Register dynamically registers a new integration. The Config
@@ -172,7 +173,10 @@ func MarshalYAML(v interface{}) (interface{}, error) {
 	//
 	// The ordering of fields in inVal and cfgVal match identically up until the
 	// extra fields appended to the end of cfgVal.
-	var configs Configs
+	var (
+		configs   Configs
+		templates Templates
+	)
 	for i, n := 0, inType.NumField(); i < n; i++ {
 		if inType.Field(i).Type == configsType {
 			configs = inVal.Field(i).Interface().(Configs)
@@ -180,6 +184,9 @@ func MarshalYAML(v interface{}) (interface{}, error) {
 				configs = Configs{}
 			}
 		}
+		if inType.Field(i).Type == templatesType {
+			templates = inVal.Field(i).Interface().(Templates)
+		}
 		if outType.Field(i).PkgPath != "" {
 			continue // Field is unexported: ignore.
 		}
@@ -203,17 +210,7 @@ func MarshalYAML(v interface{}) (interface{}, error) {
 		}
 
 		// Generate the *util.RawYAML to marshal out with.
-		var (
-			bb  []byte
-			err error
-		)
-		switch v := c.(type) {
-		case UpgradedConfig:
-			inner, common := v.LegacyConfig()
-			bb, err = util.MarshalYAMLMerged(common, inner)
-		default:
-			bb, err = yaml.Marshal(v)
-		}
+		bb, err := marshalConfig(c)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal integration %q: %w", fieldName, err)
 		}
@@ -229,11 +226,34 @@ func MarshalYAML(v interface{}) (interface{}, error) {
 		}
 	}
 
+	for _, t := range templates {
+		bb, err := t.marshalYAML()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal integration %q: %w", t.Name(), err)
+		}
+		raw := util.RawYAML(bb)
+
+		field := outVal.FieldByName("XXX_Configs_" + t.Name())
+		field.Set(reflect.Append(field, reflect.ValueOf(&raw)))
+	}
+
 	return outPointer.Interface(), nil
 }
 
+// marshalConfig marshals c to YAML. Upgraded configs are marshaled as their
+// legacy config merged with the common config.
+func marshalConfig(c Config) ([]byte, error) {
+	if uc, ok := c.(UpgradedConfig); ok {
+		inner, common := uc.LegacyConfig()
+		return util.MarshalYAMLMerged(common, inner)
+	}
+	return yaml.Marshal(c)
+}
+
 // UnmarshalYAML helps implement yaml.Unmarshaller for structs that have a
-// Configs field that should be inlined in the YAML string. Code adapted from
+// Configs field that should be inlined in the YAML string. Configs of
+// multiplexed integrations which define a discovery block are unmarshaled
+// into an optional Templates field. Code adapted from
 // Prometheus:
 //
 //   https://github.com/prometheus/prometheus/blob/511511324adfc4f4178f064cc104c2deac3335de/discovery/registry.go#L111
@@ -258,7 +278,10 @@ func UnmarshalYAML(out interface{}, unmarshal func(interface{}) error) error {
 	//
 	// The ordering of fields in outVal and cfgVal match identically up until the
 	// extra fields appended to the end of cfgVal.
-	var configs *Configs
+	var (
+		configs   *Configs
+		templates *Templates
+	)
 	for i := 0; i < outVal.NumField(); i++ {
 		if outType.Field(i).Type == configsType {
 			if configs != nil {
@@ -267,6 +290,13 @@ func UnmarshalYAML(out interface{}, unmarshal func(interface{}) error) error {
 			configs = outVal.Field(i).Addr().Interface().(*Configs)
 			continue
 		}
+		if outType.Field(i).Type == templatesType {
+			if templates != nil {
+				return fmt.Errorf("integrations: Multiple Templates fields found in %T", out)
+			}
+			templates = outVal.Field(i).Addr().Interface().(*Templates)
+			continue
+		}
 		if cfgType.Field(i).PkgPath != "" {
 			// Ignore unexported fields
 			continue
@@ -315,6 +345,20 @@ func UnmarshalYAML(out interface{}, unmarshal func(interface{}) error) error {
 					continue
 				}
 				raw := field.Index(i).Interface().(*util.RawYAML)
+
+				// Configs with a discovery block are templates for instances
+				// created from discovered targets.
+				t, ok, err := parseTemplate(*raw, configName, configReference)
+				if err != nil {
+					return err
+				} else if ok {
+					if templates == nil {
+						return fmt.Errorf("integration %q: discovery is not supported", configName)
+					}
+					*templates = append(*templates, t)
+					continue
+				}
+
 				c, err := deferredConfigUnmarshal(*raw, configReference)
 				if err != nil {
 					return err
@@ -372,7 +416,7 @@ func getConfigTypeForIntegrations(out reflect.Type) reflect.Type {
 	var fields []reflect.StructField
 	for i, n := 0, out.NumField(); i < n; i++ {
 		switch field := out.Field(i); {
-		case field.PkgPath == "" && field.Type != configsType:
+		case field.PkgPath == "" && field.Type != configsType && field.Type != templatesType:
 			fields = append(fields, field)
 		default:
 			fields = append(fields, reflect.StructField{
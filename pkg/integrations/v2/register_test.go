@@ -7,6 +7,8 @@ import (
 	"github.com/go-kit/log"
 	v1 "github.com/grafana/agent/pkg/integrations"
 	"github.com/grafana/agent/pkg/integrations/v2/common"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v2"
 )
@@ -76,6 +78,65 @@ test_configs:
 	require.Equal(t, expect, fullCfg)
 }
 
+func TestIntegrationRegistration_Templates(t *testing.T) {
+	setRegistered(t, map[Config]Type{
+		&testIntegrationA{}: TypeMultiplex,
+	})
+
+	var cfgToParse = `
+name: John Doe
+test_configs:
+  - text: Hello, world!
+  - text: 'Hello, {{ .__address__ }}!'
+    discovery:
+      static_configs:
+        - targets: [localhost:1234]
+      relabel_configs:
+        - source_labels: [__address__]
+          target_label: instance`
+
+	var fullCfg testFullConfig
+	err := yaml.UnmarshalStrict([]byte(cfgToParse), &fullCfg)
+	require.NoError(t, err)
+
+	require.Equal(t, Configs{
+		&testIntegrationA{Text: "Hello, world!", Truth: true},
+	}, fullCfg.Configs)
+
+	require.Len(t, fullCfg.Templates, 1)
+	tmpl := fullCfg.Templates[0]
+	require.Equal(t, "test", tmpl.Name())
+	require.Len(t, tmpl.Discovery.ServiceDiscoveryConfigs, 1)
+	require.Len(t, tmpl.Discovery.RelabelConfigs, 1)
+
+	c, err := tmpl.Expand(labels.FromStrings(model.AddressLabel, "localhost:1234"))
+	require.NoError(t, err)
+	require.Equal(t, &testIntegrationA{Text: "Hello, localhost:1234!", Truth: true}, c)
+
+	t.Run("Marshal", func(t *testing.T) {
+		bb, err := yaml.Marshal(fullCfg)
+		require.NoError(t, err)
+
+		var roundTrip testFullConfig
+		require.NoError(t, yaml.UnmarshalStrict(bb, &roundTrip))
+		require.Equal(t, fullCfg.Configs, roundTrip.Configs)
+		require.Len(t, roundTrip.Templates, 1)
+		require.Equal(t, tmpl.raw, roundTrip.Templates[0].raw)
+	})
+
+	t.Run("Invalid template", func(t *testing.T) {
+		var fullCfg testFullConfig
+		err := yaml.UnmarshalStrict([]byte(`
+test_configs:
+  - text: 'Hello, {{ .__address__ !'
+    discovery:
+      static_configs:
+        - targets: [localhost:1234]`), &fullCfg)
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "invalid template for test")
+	})
+}
+
 func TestIntegrationRegistration_Legacy(t *testing.T) {
 	setRegistered(t, nil)
 
@@ -134,6 +195,41 @@ legacy_configs:
 	require.Equal(t, "world", shim.Data.(*legacyConfig).Text)
 }
 
+func TestIntegrationRegistration_Legacy_Either(t *testing.T) {
+	setRegistered(t, nil)
+
+	RegisterLegacy(&legacyConfig{}, TypeEither, func(in v1.Config, mc common.MetricsConfig) UpgradedConfig {
+		return &legacyShim{Data: in, Common: mc}
+	})
+
+	var cfgToParse = `
+name: John Doe
+duration: 500ms
+legacy:
+  text: hello
+legacy_configs:
+  - text: world
+  - text: again`
+
+	var fullCfg testFullConfig
+	err := yaml.UnmarshalStrict([]byte(cfgToParse), &fullCfg)
+	require.NoError(t, err)
+
+	var texts []string
+	for _, c := range fullCfg.Configs {
+		require.IsType(t, &legacyShim{}, c)
+		texts = append(texts, c.(*legacyShim).Data.(*legacyConfig).Text)
+	}
+	require.ElementsMatch(t, []string{"hello", "world", "again"}, texts)
+
+	// Configs of integrations supporting multiple instances are always
+	// marshaled as a list.
+	bb, err := yaml.Marshal(fullCfg)
+	require.NoError(t, err)
+	require.Contains(t, string(bb), "legacy_configs:")
+	require.NotContains(t, string(bb), "\nlegacy:")
+}
+
 type legacyConfig struct {
 	Text string `yaml:"text"`
 }
@@ -193,7 +289,12 @@ type testFullConfig struct {
 	Duration time.Duration `yaml:"duration"`
 	Default  int           `yaml:"default"`
 
-	Configs Configs `yaml:"-"`
+	Configs   Configs   `yaml:"-"`
+	Templates Templates `yaml:"-"`
+}
+
+func (c testFullConfig) MarshalYAML() (interface{}, error) {
+	return MarshalYAML(c)
 }
 
 func (c *testFullConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
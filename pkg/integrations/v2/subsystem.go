@@ -5,16 +5,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
 	"github.com/gorilla/mux"
+	"github.com/grafana/agent/pkg/integrations/config"
 	"github.com/grafana/agent/pkg/integrations/v2/autoscrape"
+	"github.com/grafana/agent/pkg/integrations/v2/common"
 	"github.com/grafana/agent/pkg/metrics"
+	"github.com/grafana/agent/pkg/util"
 	common_config "github.com/prometheus/common/config"
 	"github.com/prometheus/common/model"
 	http_sd "github.com/prometheus/prometheus/discovery/http"
+	"gopkg.in/yaml.v2"
 )
 
 const (
@@ -26,6 +32,10 @@ const (
 	// IntegrationsAutoscrapeTargetsEndpoint is the API endpoint where autoscrape
 	// integrations targets are exposed.
 	IntegrationsAutoscrapeTargetsEndpoint = "/agent/api/v1/metrics/integrations/targets"
+
+	// IntegrationsConfigsEndpoint is the API endpoint where the configs of
+	// active integrations are exposed after defaults have been applied.
+	IntegrationsConfigsEndpoint = "/agent/api/v1/integrations/configs"
 )
 
 // DefaultSubsystemOptions holds the default settings for a Controller.
@@ -47,13 +57,24 @@ type SubsystemOptions struct {
 	// the custom UnmarshalYAML method of Controller.
 	Configs Configs `yaml:"-"`
 
+	// Templates are configurations of integrations whose instances are created
+	// from discovered targets. Unmarshaled alongside Configs.
+	Templates Templates `yaml:"-"`
+
 	// Override settings to self-communicate with agent.
 	ClientConfig common_config.HTTPClientConfig `yaml:"client_config,omitempty"`
+
+	// Flags holds the settings of integrations from command line flags.
+	Flags config.Flags `yaml:"-"`
 }
 
 // MetricsSubsystemOptions controls how metrics integrations behave.
 type MetricsSubsystemOptions struct {
 	Autoscrape autoscrape.Global `yaml:"autoscrape,omitempty"`
+
+	// EndpointAuth configures authentication for the HTTP endpoints of metrics
+	// integrations. Integrations may override it.
+	EndpointAuth *common.EndpointAuth `yaml:"endpoint_auth,omitempty"`
 }
 
 // ApplyDefaults will apply defaults to o.
@@ -93,6 +114,10 @@ type Subsystem struct {
 	ctrl             *controller
 	stopController   context.CancelFunc
 	controllerExited chan struct{}
+
+	discoverer       *discoverer
+	stopDiscoverer   context.CancelFunc
+	discovererExited chan struct{}
 }
 
 // NewSubsystem creates and starts a new integrations Subsystem. Every field in
@@ -116,6 +141,10 @@ func NewSubsystem(l log.Logger, globals Globals) (*Subsystem, error) {
 		close(ctrlExited)
 	}()
 
+	// The discoverer is stopped separately from the controller, as it applies
+	// discovered integrations to the controller.
+	discoveryCtx, discoveryCancel := context.WithCancel(context.Background())
+
 	s := &Subsystem{
 		logger: l,
 
@@ -125,23 +154,55 @@ func NewSubsystem(l log.Logger, globals Globals) (*Subsystem, error) {
 		ctrl:             ctrl,
 		stopController:   cancel,
 		controllerExited: ctrlExited,
+
+		discoverer:       newDiscoverer(discoveryCtx, l),
+		stopDiscoverer:   discoveryCancel,
+		discovererExited: make(chan struct{}),
 	}
 	if err := s.ApplyConfig(globals); err != nil {
+		discoveryCancel()
 		cancel()
 		autoscraper.Stop()
 		return nil, err
 	}
+
+	go func() {
+		s.discoverer.run(discoveryCtx, s.applyDiscovered)
+		close(s.discovererExited)
+	}()
 	return s, nil
 }
 
 // ApplyConfig updates the configuration of the integrations subsystem.
 func (s *Subsystem) ApplyConfig(globals Globals) error {
-	const prefix = "/integrations/"
+	s.mut.Lock()
+	defer s.mut.Unlock()
 
+	if err := s.discoverer.ApplyConfig(globals.SubsystemOpts.Templates); err != nil {
+		return fmt.Errorf("error applying integrations discovery: %w", err)
+	}
+	return s.applyConfig(globals)
+}
+
+// applyDiscovered applies the integrations for the currently discovered
+// targets.
+func (s *Subsystem) applyDiscovered() {
 	s.mut.Lock()
 	defer s.mut.Unlock()
 
-	if err := s.ctrl.UpdateController(controllerConfig(globals.SubsystemOpts.Configs), globals); err != nil {
+	if err := s.applyConfig(s.globals); err != nil {
+		level.Error(s.logger).Log("msg", "failed to apply discovered integrations", "err", err)
+	}
+}
+
+// applyConfig applies the static and discovered integrations to the
+// controller. s.mut must be held when calling applyConfig.
+func (s *Subsystem) applyConfig(globals Globals) error {
+	const prefix = "/integrations/"
+
+	static := globals.SubsystemOpts.Configs
+	configs := append(controllerConfig(static), s.discoverer.Configs(globals, static)...)
+	if err := s.ctrl.UpdateController(configs, globals); err != nil {
 		return fmt.Errorf("error applying integrations: %w", err)
 	}
 
@@ -241,11 +302,58 @@ func (s *Subsystem) WireAPI(r *mux.Router) {
 		allTargets := s.autoscraper.TargetsActive()
 		metrics.ListTargetsHandler(allTargets).ServeHTTP(rw, r)
 	})
+
+	r.HandleFunc(IntegrationsConfigsEndpoint, s.configsHandler)
+}
+
+// integrationConfig is an active integration config returned by the
+// integrations configs API.
+type integrationConfig struct {
+	Name     string       `yaml:"name"`
+	Instance string       `yaml:"instance"`
+	Running  bool         `yaml:"running"`
+	Config   util.RawYAML `yaml:"config"`
+}
+
+// configsHandler writes the configs of all active integrations as YAML.
+// Secrets are redacted when marshaling.
+func (s *Subsystem) configsHandler(rw http.ResponseWriter, _ *http.Request) {
+	active := s.ctrl.Configs()
+	sort.Slice(active, func(i, j int) bool {
+		if active[i].id.Name != active[j].id.Name {
+			return active[i].id.Name < active[j].id.Name
+		}
+		return active[i].id.Identifier < active[j].id.Identifier
+	})
+
+	resp := make([]integrationConfig, 0, len(active))
+	for _, ac := range active {
+		bb, err := marshalConfig(ac.c)
+		if err != nil {
+			http.Error(rw, fmt.Sprintf("failed to marshal config for %s: %s", ac.id, err), http.StatusInternalServerError)
+			return
+		}
+		resp = append(resp, integrationConfig{
+			Name:     ac.id.Name,
+			Instance: ac.id.Identifier,
+			Running:  ac.running,
+			Config:   util.RawYAML(bb),
+		})
+	}
+
+	bb, err := yaml.Marshal(resp)
+	if err != nil {
+		http.Error(rw, fmt.Sprintf("failed to marshal configs: %s", err), http.StatusInternalServerError)
+		return
+	}
+	_, _ = rw.Write(bb)
 }
 
 // Stop stops the manager and all running integrations. Blocks until all
 // running integrations exit.
 func (s *Subsystem) Stop() {
+	s.stopDiscoverer()
+	<-s.discovererExited
 	s.autoscraper.Stop()
 	s.stopController()
 	<-s.controllerExited
@@ -1,10 +1,13 @@
 package integrations
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	v1 "github.com/grafana/agent/pkg/integrations"
 	"github.com/grafana/agent/pkg/integrations/v2/common"
+	"github.com/grafana/agent/pkg/util"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v2"
 )
@@ -63,3 +66,49 @@ func TestSubsystemOptions_Unmarshal(t *testing.T) {
 		})
 	}
 }
+
+func TestSubsystem_ConfigsHandler(t *testing.T) {
+	globals := Globals{
+		AgentIdentifier: "localhost:12345",
+		SubsystemOpts:   DefaultSubsystemOptions,
+	}
+	cfg := controllerConfig{
+		&testIntegrationA{Text: "Hello, world!"},
+		&legacyShim{
+			Data: &legacyConfig{Text: "hello"},
+			Common: common.MetricsConfig{
+				EndpointAuth: &common.EndpointAuth{
+					BasicAuth: &common.EndpointBasicAuth{Username: "admin", Password: "hunter2"},
+				},
+			},
+		},
+	}
+
+	ctrl, err := newController(util.TestLogger(t), cfg, globals)
+	require.NoError(t, err)
+	sc := newSyncController(t, ctrl)
+	defer sc.Stop()
+
+	s := &Subsystem{ctrl: ctrl}
+	rec := httptest.NewRecorder()
+	s.configsHandler(rec, httptest.NewRequest(http.MethodGet, IntegrationsConfigsEndpoint, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var resp []struct {
+		Name     string                 `yaml:"name"`
+		Instance string                 `yaml:"instance"`
+		Config   map[string]interface{} `yaml:"config"`
+	}
+	require.NoError(t, yaml.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp, 2)
+
+	require.Equal(t, "legacy", resp[0].Name)
+	require.Equal(t, "localhost:12345", resp[0].Instance)
+	require.Equal(t, "hello", resp[0].Config["text"])
+	require.Contains(t, resp[0].Config, "autoscrape")
+	require.NotContains(t, rec.Body.String(), "hunter2")
+
+	require.Equal(t, "test", resp[1].Name)
+	require.Equal(t, "integrationA", resp[1].Instance)
+	require.Equal(t, "Hello, world!", resp[1].Config["text"])
+}
@@ -0,0 +1,182 @@
+package integrations
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/grafana/agent/pkg/util"
+	"github.com/prometheus/prometheus/discovery"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"gopkg.in/yaml.v2"
+)
+
+// discoveryKey is the key of the block which turns a multiplexed integration
+// config into a Template.
+const discoveryKey = "discovery"
+
+// Templates is a list of integration templates. Like Configs, Templates does
+// not implement yaml.Unmarshaler or yaml.Marshaler. Templates are unmarshaled
+// by UnmarshalYAML from the configs of multiplexed integrations which define a
+// discovery block.
+type Templates []*Template
+
+// Template is the config of a multiplexed integration whose instances are
+// discovered using Prometheus service discovery. An instance of the
+// integration is created for every discovered target, with its config
+// templated from the labels of the target.
+//
+// Every string in the config may use Go templating, with the labels of the
+// target as data:
+//
+//   redis_exporter_configs:
+//   - redis_addr: 'redis://{{ .__address__ }}'
+//     discovery:
+//       kubernetes_sd_configs:
+//       - role: pod
+type Template struct {
+	// Discovery configures how targets are discovered.
+	Discovery DiscoveryConfig
+
+	name string        // Name of the integration.
+	ref  interface{}   // Registered Config or v1.Config of the integration.
+	raw  yaml.MapSlice // Config of the integration without the discovery block.
+}
+
+// DiscoveryConfig configures discovering the targets of a Template.
+type DiscoveryConfig struct {
+	// ServiceDiscoveryConfigs are the Prometheus service discovery configs
+	// used to discover targets, e.g., kubernetes_sd_configs.
+	ServiceDiscoveryConfigs discovery.Configs `yaml:"-"`
+	// RelabelConfigs are applied to discovered targets. Targets may be dropped
+	// and labels may be added before templating.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for DiscoveryConfig.
+func (c *DiscoveryConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DiscoveryConfig{}
+	return discovery.UnmarshalYAMLWithInlineConfigs(c, unmarshal)
+}
+
+// MarshalYAML implements yaml.Marshaler for DiscoveryConfig.
+func (c *DiscoveryConfig) MarshalYAML() (interface{}, error) {
+	return discovery.MarshalYAMLWithInlineConfigs(c)
+}
+
+// Name returns the name of the integration the Template creates instances
+// of.
+func (t *Template) Name() string { return t.name }
+
+// Expand returns the config of the integration instance for a target with
+// the labels lset.
+func (t *Template) Expand(lset labels.Labels) (Config, error) {
+	expanded, err := walkTemplates(t.raw, func(tmpl *template.Template) (string, error) {
+		var sb strings.Builder
+		err := tmpl.Execute(&sb, lset.Map())
+		return sb.String(), err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bb, err := yaml.Marshal(expanded)
+	if err != nil {
+		return nil, err
+	}
+	return deferredConfigUnmarshal(util.RawYAML(bb), t.ref)
+}
+
+// marshalYAML returns the config of the Template including the discovery
+// block.
+func (t *Template) marshalYAML() ([]byte, error) {
+	ms := append(yaml.MapSlice{}, t.raw...)
+	ms = append(ms, yaml.MapItem{Key: discoveryKey, Value: &t.Discovery})
+	return yaml.Marshal(ms)
+}
+
+// parseTemplate parses raw into a Template for the integration name when raw
+// defines a discovery block. ok will be false when raw doesn't define a
+// discovery block.
+func parseTemplate(raw util.RawYAML, name string, ref interface{}) (t *Template, ok bool, err error) {
+	ms, err := raw.Map()
+	if err != nil {
+		return nil, false, err
+	}
+
+	t = &Template{name: name, ref: ref}
+	for _, item := range ms {
+		if item.Key != discoveryKey {
+			t.raw = append(t.raw, item)
+			continue
+		}
+		ok = true
+
+		bb, err := yaml.Marshal(item.Value)
+		if err != nil {
+			return nil, false, err
+		}
+		if err := yaml.UnmarshalStrict(bb, &t.Discovery); err != nil {
+			return nil, false, fmt.Errorf("invalid discovery block for %s: %w", name, err)
+		}
+	}
+	if !ok {
+		return nil, false, nil
+	}
+
+	// Validate the templates of the config.
+	_, err = walkTemplates(t.raw, func(*template.Template) (string, error) { return "", nil })
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid template for %s: %w", name, err)
+	}
+	return t, true, nil
+}
+
+// walkTemplates returns a copy of v where every string containing a template
+// is replaced by the result of f.
+func walkTemplates(v interface{}, f func(*template.Template) (string, error)) (interface{}, error) {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		res := make(yaml.MapSlice, 0, len(v))
+		for _, item := range v {
+			val, err := walkTemplates(item.Value, f)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, yaml.MapItem{Key: item.Key, Value: val})
+		}
+		return res, nil
+	case map[interface{}]interface{}:
+		res := make(map[interface{}]interface{}, len(v))
+		for k, elem := range v {
+			val, err := walkTemplates(elem, f)
+			if err != nil {
+				return nil, err
+			}
+			res[k] = val
+		}
+		return res, nil
+	case []interface{}:
+		res := make([]interface{}, 0, len(v))
+		for _, elem := range v {
+			val, err := walkTemplates(elem, f)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, val)
+		}
+		return res, nil
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		tmpl, err := template.New("").Option("missingkey=zero").Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		return f(tmpl)
+	default:
+		return v, nil
+	}
+}
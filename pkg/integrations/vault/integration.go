@@ -0,0 +1,102 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/agent/pkg/integrations/config"
+	config_util "github.com/prometheus/common/config"
+)
+
+// Integration is the vault integration. Scrapes of the integration are
+// proxied to Vault's telemetry endpoint using a token managed by the
+// integration.
+type Integration struct {
+	c      *Config
+	log    log.Logger
+	client *http.Client
+	tokens *tokenManager
+}
+
+// New creates a new vault integration.
+func New(logger log.Logger, c *Config) (*Integration, error) {
+	tlsConfig, err := config_util.NewTLSConfig(&c.TLSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tls config: %w", err)
+	}
+	client := &http.Client{
+		Timeout: c.Timeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	return &Integration{
+		c:      c,
+		log:    logger,
+		client: client,
+		tokens: newTokenManager(logger, client, c),
+	}, nil
+}
+
+// MetricsHandler satisfies Integration.RegisterRoutes.
+func (i *Integration) MetricsHandler() (http.Handler, error) {
+	return http.HandlerFunc(i.serveMetrics), nil
+}
+
+func (i *Integration) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, strings.TrimSuffix(i.c.Address, "/")+"/v1/sys/metrics?format=prometheus", nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if i.tokens.enabled() {
+		token := i.tokens.Token()
+		if token == "" {
+			http.Error(w, "no vault token available", http.StatusServiceUnavailable)
+			return
+		}
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if i.c.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", i.c.Namespace)
+	}
+
+	resp, err := i.client.Do(req)
+	if err != nil {
+		level.Error(i.log).Log("msg", "failed to scrape vault", "err", err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusForbidden {
+		// The token may have been revoked; get a new one for the next scrape.
+		i.tokens.invalidate()
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body)
+}
+
+// ScrapeConfigs satisfies Integration.ScrapeConfigs.
+func (i *Integration) ScrapeConfigs() []config.ScrapeConfig {
+	return []config.ScrapeConfig{{
+		JobName:     i.c.Name(),
+		MetricsPath: "/metrics",
+	}}
+}
+
+// Run satisfies Integration.Run.
+func (i *Integration) Run(ctx context.Context) error {
+	return i.tokens.run(ctx)
+}
@@ -0,0 +1,158 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+type fakeVault struct {
+	logins, renewals int32
+}
+
+func (v *fakeVault) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("X-Vault-Token")
+
+	switch r.URL.Path {
+	case "/v1/auth/approle/login":
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req["role_id"] != "role" || req["secret_id"] != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"errors":["invalid role or secret ID"]}`))
+			return
+		}
+		atomic.AddInt32(&v.logins, 1)
+		_, _ = w.Write([]byte(`{"auth":{"client_token":"approle-token","lease_duration":3600,"renewable":true}}`))
+
+	case "/v1/auth/token/renew-self":
+		if token != "approle-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		atomic.AddInt32(&v.renewals, 1)
+		_, _ = w.Write([]byte(`{"auth":{"client_token":"approle-token","lease_duration":3600,"renewable":true}}`))
+
+	case "/v1/auth/token/lookup-self":
+		if token != "static-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"ttl":0,"renewable":false}}`))
+
+	case "/v1/sys/metrics":
+		if token != "approle-token" && token != "static-token" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write([]byte("vault_core_unsealed 1\n"))
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestIntegration(t *testing.T, cfg string) *Integration {
+	t.Helper()
+
+	var c Config
+	require.NoError(t, yaml.Unmarshal([]byte(cfg), &c))
+	i, err := New(log.NewNopLogger(), &c)
+	require.NoError(t, err)
+	return i
+}
+
+func TestIntegration_AppRole(t *testing.T) {
+	fv := &fakeVault{}
+	srv := httptest.NewServer(fv)
+	defer srv.Close()
+
+	i := newTestIntegration(t, `
+address: `+srv.URL+`
+approle:
+  role_id: role
+  secret_id: secret
+`)
+
+	// Scrapes fail until a token has been obtained.
+	h, err := i.MetricsHandler()
+	require.NoError(t, err)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	wait, err := i.tokens.refresh(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 40*time.Minute, wait)
+	require.Equal(t, "approle-token", i.tokens.Token())
+
+	// Refreshing a renewable token renews it rather than logging in again.
+	_, err = i.tokens.refresh(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&fv.logins))
+	require.Equal(t, int32(1), atomic.LoadInt32(&fv.renewals))
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "vault_core_unsealed 1\n", rec.Body.String())
+}
+
+func TestIntegration_StaticToken(t *testing.T) {
+	srv := httptest.NewServer(&fakeVault{})
+	defer srv.Close()
+
+	i := newTestIntegration(t, `
+address: `+srv.URL+`
+token: static-token
+`)
+
+	wait, err := i.tokens.refresh(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, nonExpiringInterval, wait)
+	require.Equal(t, "static-token", i.tokens.Token())
+}
+
+func TestIntegration_LoginError(t *testing.T) {
+	srv := httptest.NewServer(&fakeVault{})
+	defer srv.Close()
+
+	i := newTestIntegration(t, `
+address: `+srv.URL+`
+approle:
+  role_id: role
+  secret_id: wrong
+`)
+
+	_, err := i.tokens.refresh(context.Background())
+	require.EqualError(t, err, "approle login failed: unexpected status code 400: invalid role or secret ID")
+	require.Equal(t, "", i.tokens.Token())
+}
+
+func TestConfig_Validate(t *testing.T) {
+	var c Config
+	err := yaml.Unmarshal([]byte(`
+token: foo
+approle:
+  role_id: role
+`), &c)
+	require.EqualError(t, err, "at most one of token, token_file, and approle must be set")
+
+	err = yaml.Unmarshal([]byte(`
+approle:
+  secret_id: secret
+`), &c)
+	require.EqualError(t, err, "approle: role_id must be set")
+}
@@ -0,0 +1,266 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	config_util "github.com/prometheus/common/config"
+)
+
+const (
+	// minRefreshInterval bounds how often tokens are renewed. Tokens with a
+	// remaining TTL shorter than twice this interval are replaced rather than
+	// renewed, since they've likely reached their maximum TTL.
+	minRefreshInterval = 5 * time.Second
+	// retryInterval is how long to wait after a failed refresh.
+	retryInterval = 10 * time.Second
+	// nonExpiringInterval is how often tokens without a TTL are obtained
+	// again, which picks up changes to token_file.
+	nonExpiringInterval = 5 * time.Minute
+)
+
+// tokenManager obtains and renews the token used to scrape Vault.
+type tokenManager struct {
+	log       log.Logger
+	client    *http.Client
+	address   string
+	namespace string
+
+	token     config_util.Secret
+	tokenFile string
+	appRole   *AppRoleConfig
+
+	refreshCh chan struct{}
+
+	mut       sync.RWMutex
+	current   string
+	renewable bool
+}
+
+func newTokenManager(l log.Logger, client *http.Client, c *Config) *tokenManager {
+	return &tokenManager{
+		log:       l,
+		client:    client,
+		address:   strings.TrimSuffix(c.Address, "/"),
+		namespace: c.Namespace,
+
+		token:     c.Token,
+		tokenFile: c.TokenFile,
+		appRole:   c.AppRole,
+
+		refreshCh: make(chan struct{}, 1),
+	}
+}
+
+// enabled returns true if an auth method is configured.
+func (m *tokenManager) enabled() bool {
+	return m.token != "" || m.tokenFile != "" || m.appRole != nil
+}
+
+// Token returns the current token. Returns an empty string if no token has
+// been obtained yet.
+func (m *tokenManager) Token() string {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+	return m.current
+}
+
+// invalidate discards the current token and triggers obtaining a new one.
+func (m *tokenManager) invalidate() {
+	m.mut.Lock()
+	m.current, m.renewable = "", false
+	m.mut.Unlock()
+
+	select {
+	case m.refreshCh <- struct{}{}:
+	default:
+	}
+}
+
+// run keeps the token refreshed until ctx is canceled.
+func (m *tokenManager) run(ctx context.Context) error {
+	if !m.enabled() {
+		<-ctx.Done()
+		return nil
+	}
+
+	for {
+		wait, err := m.refresh(ctx)
+		if err != nil {
+			level.Error(m.log).Log("msg", "failed to refresh vault token", "err", err)
+			wait = retryInterval
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-m.refreshCh:
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// refresh renews the current token if possible, otherwise obtaining a new
+// one. Returns how long to wait until the next refresh.
+func (m *tokenManager) refresh(ctx context.Context) (time.Duration, error) {
+	m.mut.RLock()
+	token, renewable := m.current, m.renewable
+	m.mut.RUnlock()
+
+	if token != "" && renewable {
+		var resp authResponse
+		err := m.do(ctx, http.MethodPost, "/v1/auth/token/renew-self", token, struct{}{}, &resp)
+		switch {
+		case err != nil:
+			level.Warn(m.log).Log("msg", "failed to renew vault token, obtaining a new one", "err", err)
+		case resp.Auth.LeaseDuration < int64(2*minRefreshInterval/time.Second):
+			level.Debug(m.log).Log("msg", "vault token is about to expire, obtaining a new one")
+		default:
+			m.set(token, resp.Auth.Renewable)
+			return refreshInterval(resp.Auth.LeaseDuration), nil
+		}
+	}
+
+	token, ttl, renewable, err := m.login(ctx)
+	if err != nil {
+		return 0, err
+	}
+	m.set(token, renewable)
+	return refreshInterval(ttl), nil
+}
+
+func (m *tokenManager) set(token string, renewable bool) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.current, m.renewable = token, renewable
+}
+
+// login obtains a new token, returning the token, its TTL in seconds, and
+// whether it is renewable.
+func (m *tokenManager) login(ctx context.Context) (token string, ttl int64, renewable bool, err error) {
+	if m.appRole != nil {
+		secretID := string(m.appRole.SecretID)
+		if m.appRole.SecretIDFile != "" {
+			if secretID, err = readFile(m.appRole.SecretIDFile); err != nil {
+				return "", 0, false, err
+			}
+		}
+
+		req := map[string]string{"role_id": m.appRole.RoleID}
+		if secretID != "" {
+			req["secret_id"] = secretID
+		}
+
+		var resp authResponse
+		path := fmt.Sprintf("/v1/auth/%s/login", strings.Trim(m.appRole.MountPath, "/"))
+		if err := m.do(ctx, http.MethodPost, path, "", req, &resp); err != nil {
+			return "", 0, false, fmt.Errorf("approle login failed: %w", err)
+		}
+		return resp.Auth.ClientToken, resp.Auth.LeaseDuration, resp.Auth.Renewable, nil
+	}
+
+	token = string(m.token)
+	if m.tokenFile != "" {
+		if token, err = readFile(m.tokenFile); err != nil {
+			return "", 0, false, err
+		}
+	}
+
+	var resp lookupResponse
+	if err := m.do(ctx, http.MethodGet, "/v1/auth/token/lookup-self", token, nil, &resp); err != nil {
+		return "", 0, false, fmt.Errorf("token lookup failed: %w", err)
+	}
+	return token, resp.Data.TTL, resp.Data.Renewable, nil
+}
+
+// refreshInterval returns how long to wait before refreshing a token with
+// the given TTL in seconds.
+func refreshInterval(ttl int64) time.Duration {
+	if ttl <= 0 {
+		return nonExpiringInterval
+	}
+	interval := time.Duration(ttl) * time.Second * 2 / 3
+	if interval < minRefreshInterval {
+		interval = minRefreshInterval
+	}
+	return interval
+}
+
+type authResponse struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int64  `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+}
+
+type lookupResponse struct {
+	Data struct {
+		TTL       int64 `json:"ttl"`
+		Renewable bool  `json:"renewable"`
+	} `json:"data"`
+}
+
+type errorResponse struct {
+	Errors []string `json:"errors"`
+}
+
+// do performs a request against the Vault API, decoding the JSON response
+// into out.
+func (m *tokenManager) do(ctx context.Context, method, path, token string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		bb, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(bb)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, m.address+path, body)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if m.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", m.namespace)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp errorResponse
+		if json.NewDecoder(resp.Body).Decode(&errResp) == nil && len(errResp.Errors) > 0 {
+			return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.Join(errResp.Errors, "; "))
+		}
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func readFile(path string) (string, error) {
+	bb, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return strings.TrimSpace(string(bb)), nil
+}
@@ -0,0 +1,130 @@
+// Package vault implements an integration which proxies HashiCorp Vault's
+// telemetry endpoint. The integration manages the Vault token used for
+// scraping, renewing it automatically so that long-lived tokens don't need to
+// be distributed.
+package vault
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	config_util "github.com/prometheus/common/config"
+)
+
+// DefaultConfig holds the default settings for the vault integration.
+var DefaultConfig = Config{
+	Address: "http://127.0.0.1:8200",
+	Timeout: 10 * time.Second,
+}
+
+// DefaultAppRoleConfig holds the default settings for AppRole
+// authentication.
+var DefaultAppRoleConfig = AppRoleConfig{
+	MountPath: "approle",
+}
+
+// Config controls the vault integration.
+type Config struct {
+	// Address of the Vault server.
+	Address string `yaml:"address,omitempty"`
+	// Namespace to use for requests. Only supported by Vault Enterprise.
+	Namespace string `yaml:"namespace,omitempty"`
+	// Timeout for requests to Vault.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// Token to authenticate with. Renewed automatically when renewable.
+	Token config_util.Secret `yaml:"token,omitempty"`
+	// TokenFile is a file to read the token from. The file is read again
+	// whenever the token can no longer be renewed.
+	TokenFile string `yaml:"token_file,omitempty"`
+	// AppRole authenticates with the AppRole auth method and logs in again
+	// whenever the token can no longer be renewed.
+	AppRole *AppRoleConfig `yaml:"approle,omitempty"`
+
+	// TLSConfig configures TLS for requests to Vault.
+	TLSConfig config_util.TLSConfig `yaml:"tls_config,omitempty"`
+}
+
+// AppRoleConfig configures the AppRole auth method.
+type AppRoleConfig struct {
+	// MountPath of the AppRole auth method.
+	MountPath string `yaml:"mount_path,omitempty"`
+	// RoleID to log in with.
+	RoleID string `yaml:"role_id,omitempty"`
+	// SecretID to log in with.
+	SecretID config_util.Secret `yaml:"secret_id,omitempty"`
+	// SecretIDFile is a file to read the SecretID from.
+	SecretIDFile string `yaml:"secret_id_file,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for AppRoleConfig.
+func (c *AppRoleConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultAppRoleConfig
+
+	type plain AppRoleConfig
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.RoleID == "" {
+		return fmt.Errorf("approle: role_id must be set")
+	}
+	if c.SecretID != "" && c.SecretIDFile != "" {
+		return fmt.Errorf("approle: at most one of secret_id and secret_id_file must be set")
+	}
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	var methods int
+	for _, set := range []bool{c.Token != "", c.TokenFile != "", c.AppRole != nil} {
+		if set {
+			methods++
+		}
+	}
+	if methods > 1 {
+		return fmt.Errorf("at most one of token, token_file, and approle must be set")
+	}
+
+	if _, err := url.Parse(c.Address); err != nil {
+		return fmt.Errorf("invalid address: %w", err)
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "vault"
+}
+
+// InstanceKey returns the host:port of the Vault server.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	u, err := url.Parse(c.Address)
+	if err != nil {
+		return "", fmt.Errorf("could not parse url: %w", err)
+	}
+	return u.Host, nil
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
@@ -0,0 +1,423 @@
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	config_util "github.com/prometheus/common/config"
+)
+
+const namespace = "vsphere"
+
+// realtimeInterval is the interval of the real-time statistics of ESXi
+// hosts, which are not limited by the statistics level of vCenter.
+const realtimeInterval = 20
+
+// maxQueryMetrics is the default limit of vCenter for the number of metrics
+// of a single query for historical statistics.
+const maxQueryMetrics = 64
+
+var (
+	upDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "up"),
+		"Whether the last scrape of the vSphere API was successful.",
+		nil, nil,
+	)
+	datastoreCapacityDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "datastore", "capacity_bytes"),
+		"Capacity of the datastore.",
+		[]string{"datastore"}, nil,
+	)
+	datastoreFreeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "datastore", "free_bytes"),
+		"Free space of the datastore.",
+		[]string{"datastore"}, nil,
+	)
+	datastoreAccessibleDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "datastore", "accessible"),
+		"Whether the datastore is accessible.",
+		[]string{"datastore"}, nil,
+	)
+)
+
+// objectFilter selects managed objects by name.
+type objectFilter struct {
+	include, exclude *regexp.Regexp
+}
+
+func newObjectFilter(c ObjectConfig) (objectFilter, error) {
+	var (
+		f   objectFilter
+		err error
+	)
+	if c.Include != "" {
+		if f.include, err = regexp.Compile(c.Include); err != nil {
+			return f, err
+		}
+	}
+	if c.Exclude != "" {
+		if f.exclude, err = regexp.Compile(c.Exclude); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+func (f objectFilter) match(name string) bool {
+	if f.include != nil && !f.include.MatchString(name) {
+		return false
+	}
+	return f.exclude == nil || !f.exclude.MatchString(name)
+}
+
+// collector collects performance counters from the vSphere API. Metric names
+// depend on the configured counters, so the collector is unchecked.
+type collector struct {
+	log    log.Logger
+	cfg    *Config
+	client *soapClient
+
+	hostFilter, vmFilter, datastoreFilter objectFilter
+
+	mut      sync.Mutex
+	content  *serviceContent
+	counters map[string]perfCounterInfo
+	views    map[string]mor
+}
+
+func newCollector(l log.Logger, c *Config) (*collector, error) {
+	tlsConfig, err := config_util.NewTLSConfig(&c.TLSConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tls config: %w", err)
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	col := &collector{
+		log: l,
+		cfg: c,
+		client: &soapClient{
+			client: &http.Client{
+				Jar: jar,
+				Transport: &http.Transport{
+					Proxy:           http.ProxyFromEnvironment,
+					TLSClientConfig: tlsConfig,
+				},
+			},
+			url: c.URL,
+		},
+	}
+
+	for _, f := range []struct {
+		cfg ObjectConfig
+		out *objectFilter
+	}{
+		{c.Hosts, &col.hostFilter},
+		{c.VMs, &col.vmFilter},
+		{c.Datastores, &col.datastoreFilter},
+	} {
+		if *f.out, err = newObjectFilter(f.cfg); err != nil {
+			return nil, err
+		}
+	}
+	return col, nil
+}
+
+// run waits for ctx to be canceled and logs out of the vSphere API.
+func (c *collector) run(ctx context.Context) error {
+	<-ctx.Done()
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.content != nil {
+		logoutCtx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+		defer cancel()
+		_ = c.client.logout(logoutCtx, c.content.SessionManager)
+	}
+	return nil
+}
+
+// Describe implements prometheus.Collector.
+func (c *collector) Describe(ch chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (c *collector) Collect(ch chan<- prometheus.Metric) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.Timeout)
+	defer cancel()
+
+	metrics, err := c.collect(ctx)
+
+	// Log in again if the session expired.
+	var fault *soapFault
+	if errors.As(err, &fault) && fault.notAuthenticated() {
+		c.content = nil
+		metrics, err = c.collect(ctx)
+	}
+
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to collect vsphere metrics", "err", err)
+		ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 0)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(upDesc, prometheus.GaugeValue, 1)
+	for _, m := range metrics {
+		ch <- m
+	}
+}
+
+// metricSet accumulates metrics of a scrape. Metrics with label values
+// collected before, e.g., for objects with the same name in different
+// datacenters, are dropped.
+type metricSet struct {
+	metrics []prometheus.Metric
+	seen    map[string]struct{}
+}
+
+func (s *metricSet) add(desc *prometheus.Desc, v float64, labels ...string) {
+	key := desc.String() + "\xff" + strings.Join(labels, "\xff")
+	if _, ok := s.seen[key]; ok {
+		return
+	}
+	s.seen[key] = struct{}{}
+	s.metrics = append(s.metrics, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, labels...))
+}
+
+func (c *collector) collect(ctx context.Context) ([]prometheus.Metric, error) {
+	if err := c.session(ctx); err != nil {
+		return nil, err
+	}
+	set := &metricSet{seen: make(map[string]struct{})}
+
+	var hostNames map[string]string
+	if c.cfg.Hosts.Enabled || c.cfg.VMs.Enabled {
+		hosts, err := c.objects(ctx, "HostSystem", "name", "runtime.connectionState")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list hosts: %w", err)
+		}
+
+		hostNames = make(map[string]string, len(hosts))
+		entities := make(map[mor][]string)
+		for _, h := range hosts {
+			name, _ := h.prop("name")
+			hostNames[h.Obj.Value] = name.Text
+
+			state, _ := h.prop("runtime.connectionState")
+			if state.Text == "connected" && c.hostFilter.match(name.Text) {
+				entities[h.Obj] = []string{name.Text}
+			}
+		}
+
+		if c.cfg.Hosts.Enabled {
+			if err := c.collectPerf(ctx, set, "host", []string{"host"}, entities, c.cfg.Hosts.Counters, c.cfg.SamplingInterval); err != nil {
+				return nil, fmt.Errorf("failed to query host performance: %w", err)
+			}
+		}
+	}
+
+	if c.cfg.VMs.Enabled {
+		vms, err := c.objects(ctx, "VirtualMachine", "name", "runtime.host", "runtime.powerState")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list vms: %w", err)
+		}
+
+		entities := make(map[mor][]string)
+		for _, vm := range vms {
+			name, _ := vm.prop("name")
+			state, _ := vm.prop("runtime.powerState")
+			if state.Text != "poweredOn" || !c.vmFilter.match(name.Text) {
+				continue
+			}
+			host, _ := vm.prop("runtime.host")
+			entities[vm.Obj] = []string{name.Text, hostNames[host.Text]}
+		}
+
+		if err := c.collectPerf(ctx, set, "vm", []string{"vm", "host"}, entities, c.cfg.VMs.Counters, c.cfg.SamplingInterval); err != nil {
+			return nil, fmt.Errorf("failed to query vm performance: %w", err)
+		}
+	}
+
+	if c.cfg.Datastores.Enabled {
+		datastores, err := c.objects(ctx, "Datastore", "name", "summary.capacity", "summary.freeSpace", "summary.accessible")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list datastores: %w", err)
+		}
+
+		entities := make(map[mor][]string)
+		for _, ds := range datastores {
+			name, _ := ds.prop("name")
+			if !c.datastoreFilter.match(name.Text) {
+				continue
+			}
+			entities[ds.Obj] = []string{name.Text}
+
+			if v, ok := ds.prop("summary.capacity"); ok {
+				set.add(datastoreCapacityDesc, parseFloat(v.Text), name.Text)
+			}
+			if v, ok := ds.prop("summary.freeSpace"); ok {
+				set.add(datastoreFreeDesc, parseFloat(v.Text), name.Text)
+			}
+			if v, ok := ds.prop("summary.accessible"); ok {
+				accessible := 0.0
+				if v.Text == "true" {
+					accessible = 1
+				}
+				set.add(datastoreAccessibleDesc, accessible, name.Text)
+			}
+		}
+
+		if err := c.collectPerf(ctx, set, "datastore", []string{"datastore"}, entities, c.cfg.Datastores.Counters, c.cfg.DatastoreSamplingInterval); err != nil {
+			return nil, fmt.Errorf("failed to query datastore performance: %w", err)
+		}
+	}
+
+	return set.metrics, nil
+}
+
+// session logs in and retrieves the performance counters if there's no
+// active session.
+func (c *collector) session(ctx context.Context) error {
+	if c.content != nil {
+		return nil
+	}
+
+	content, err := c.client.retrieveServiceContent(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve service content: %w", err)
+	}
+	if err := c.client.login(ctx, content.SessionManager, c.cfg.Username, string(c.cfg.Password)); err != nil {
+		return fmt.Errorf("failed to log in: %w", err)
+	}
+
+	objs, err := c.client.retrieveProperties(ctx, content.PropertyCollector, content.PerfManager, "PerformanceManager", []string{"perfCounter"})
+	if err != nil {
+		return fmt.Errorf("failed to retrieve performance counters: %w", err)
+	}
+	counters := make(map[string]perfCounterInfo)
+	for _, o := range objs {
+		v, _ := o.prop("perfCounter")
+		for _, info := range v.PerfCounters {
+			counters[info.name()] = info
+		}
+	}
+
+	c.content = content
+	c.counters = counters
+	c.views = make(map[string]mor)
+	return nil
+}
+
+// objects retrieves properties of all managed objects of the given type.
+func (c *collector) objects(ctx context.Context, objectType string, paths ...string) ([]objectContent, error) {
+	view, ok := c.views[objectType]
+	if !ok {
+		var err error
+		view, err = c.client.createContainerView(ctx, c.content.ViewManager, c.content.RootFolder, objectType)
+		if err != nil {
+			return nil, err
+		}
+		c.views[objectType] = view
+	}
+	return c.client.retrieveProperties(ctx, c.content.PropertyCollector, view, objectType, paths)
+}
+
+// collectPerf queries the latest sample of counters for entities and adds
+// them to set as metrics named vsphere_<kind>_<counter>. entities maps each
+// entity to its label values.
+func (c *collector) collectPerf(ctx context.Context, set *metricSet, kind string, labels []string, entities map[mor][]string, counters []string, interval time.Duration) error {
+	var (
+		ids   []perfMetricID
+		descs = make(map[int32]*prometheus.Desc)
+		units = make(map[int32]string)
+	)
+	for _, name := range counters {
+		info, ok := c.counters[name]
+		if !ok {
+			level.Debug(c.log).Log("msg", "performance counter not found", "counter", name)
+			continue
+		}
+		ids = append(ids, perfMetricID{CounterID: info.Key})
+		descs[info.Key] = prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, kind, strings.ReplaceAll(name, ".", "_")),
+			fmt.Sprintf("vSphere performance counter %s.", name),
+			labels, nil,
+		)
+		units[info.Key] = info.UnitInfo.Key
+	}
+	if len(ids) == 0 || len(entities) == 0 {
+		return nil
+	}
+
+	intervalID := int(interval.Seconds())
+	specs := make([]perfQuerySpec, 0, len(entities))
+	for entity := range entities {
+		specs = append(specs, perfQuerySpec{Entity: entity, MaxSample: 1, MetricID: ids, IntervalID: intervalID})
+	}
+
+	// Queries for historical statistics are limited in size.
+	batch := len(specs)
+	if intervalID != realtimeInterval {
+		batch = maxQueryMetrics / len(ids)
+		if batch < 1 {
+			batch = 1
+		}
+	}
+
+	for len(specs) > 0 {
+		n := batch
+		if n > len(specs) {
+			n = len(specs)
+		}
+		results, err := c.client.queryPerf(ctx, c.content.PerfManager, specs[:n])
+		if err != nil {
+			return err
+		}
+		specs = specs[n:]
+
+		for _, r := range results {
+			labelValues, ok := entities[r.Entity]
+			if !ok {
+				continue
+			}
+			for _, series := range r.Value {
+				desc, ok := descs[series.ID.CounterID]
+				if !ok || len(series.Value) == 0 {
+					continue
+				}
+				v := series.Value[len(series.Value)-1]
+				if v < 0 {
+					// -1 is reported when no data is available.
+					continue
+				}
+
+				value := float64(v)
+				if units[series.ID.CounterID] == "percent" {
+					// Percentages are reported in hundredths of a percent.
+					value /= 100
+				}
+				set.add(desc, value, labelValues...)
+			}
+		}
+	}
+	return nil
+}
+
+func parseFloat(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
@@ -0,0 +1,230 @@
+package vsphere
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+const testPerfCounters = `
+<PerfCounterInfo><key>2</key><nameInfo><key>usage</key></nameInfo><groupInfo><key>cpu</key></groupInfo><unitInfo><key>percent</key></unitInfo><rollupType>average</rollupType></PerfCounterInfo>
+<PerfCounterInfo><key>24</key><nameInfo><key>usage</key></nameInfo><groupInfo><key>mem</key></groupInfo><unitInfo><key>percent</key></unitInfo><rollupType>average</rollupType></PerfCounterInfo>
+<PerfCounterInfo><key>240</key><nameInfo><key>used</key></nameInfo><groupInfo><key>disk</key></groupInfo><unitInfo><key>kiloBytes</key></unitInfo><rollupType>latest</rollupType></PerfCounterInfo>`
+
+// fakeVSphere implements the subset of the vSphere SOAP API used by the
+// integration. Requests which don't match what the integration is expected
+// to send are recorded in errs, to be checked by the test.
+type fakeVSphere struct {
+	mut     sync.Mutex
+	session string
+	logins  int
+	queries []string
+	errs    []string
+}
+
+// expect records an error unless body contains substr.
+func (f *fakeVSphere) expect(body, substr string) {
+	if !strings.Contains(body, substr) {
+		f.errs = append(f.errs, fmt.Sprintf("request doesn't contain %q: %s", substr, body))
+	}
+}
+
+func (f *fakeVSphere) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+
+	bb, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	body := string(bb)
+
+	writeBody := func(s string) {
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"><soapenv:Body>%s</soapenv:Body></soapenv:Envelope>`, s)
+	}
+
+	switch {
+	case strings.Contains(body, "<RetrieveServiceContent>"):
+		writeBody(`<RetrieveServiceContentResponse xmlns="urn:vim25"><returnval>
+			<rootFolder type="Folder">group-d1</rootFolder>
+			<propertyCollector type="PropertyCollector">propertyCollector</propertyCollector>
+			<viewManager type="ViewManager">ViewManager</viewManager>
+			<sessionManager type="SessionManager">SessionManager</sessionManager>
+			<perfManager type="PerformanceManager">PerfMgr</perfManager>
+		</returnval></RetrieveServiceContentResponse>`)
+		return
+
+	case strings.Contains(body, "<Login>"):
+		f.expect(body, "<userName>agent</userName><password>secret</password>")
+		f.logins++
+		f.session = fmt.Sprintf("session-%d", f.logins)
+		http.SetCookie(w, &http.Cookie{Name: "vmware_soap_session", Value: f.session})
+		writeBody(`<LoginResponse xmlns="urn:vim25"><returnval><key>abc</key></returnval></LoginResponse>`)
+		return
+	}
+
+	if cookie, err := r.Cookie("vmware_soap_session"); err != nil || cookie.Value != f.session {
+		w.WriteHeader(http.StatusInternalServerError)
+		writeBody(`<soapenv:Fault><faultcode>ServerFaultCode</faultcode><faultstring>The session is not authenticated.</faultstring><detail><NotAuthenticatedFault xmlns="urn:vim25" xsi:type="NotAuthenticated"></NotAuthenticatedFault></detail></soapenv:Fault>`)
+		return
+	}
+
+	switch {
+	case strings.Contains(body, "<CreateContainerView>"):
+		f.expect(body, `<container type="Folder">group-d1</container>`)
+		writeBody(`<CreateContainerViewResponse xmlns="urn:vim25"><returnval type="ContainerView">view-1</returnval></CreateContainerViewResponse>`)
+
+	case strings.Contains(body, "<type>PerformanceManager</type>"):
+		writeBody(`<RetrievePropertiesExResponse xmlns="urn:vim25"><returnval><objects><obj type="PerformanceManager">PerfMgr</obj>
+			<propSet><name>perfCounter</name><val xsi:type="ArrayOfPerfCounterInfo">` + testPerfCounters + `</val></propSet>
+		</objects></returnval></RetrievePropertiesExResponse>`)
+
+	case strings.Contains(body, "<type>HostSystem</type>"):
+		f.expect(body, `<selectSet xsi:type="TraversalSpec"><name>traverseEntities</name><type>ContainerView</type><path>view</path>`)
+		// The second host is returned on a second page.
+		writeBody(`<RetrievePropertiesExResponse xmlns="urn:vim25"><returnval><token>page-2</token><objects><obj type="HostSystem">host-1</obj>
+			<propSet><name>name</name><val xsi:type="xsd:string">esx1</val></propSet>
+			<propSet><name>runtime.connectionState</name><val xsi:type="HostSystemConnectionState">connected</val></propSet>
+		</objects></returnval></RetrievePropertiesExResponse>`)
+
+	case strings.Contains(body, "<ContinueRetrievePropertiesEx>"):
+		f.expect(body, "<token>page-2</token>")
+		writeBody(`<ContinueRetrievePropertiesExResponse xmlns="urn:vim25"><returnval><objects><obj type="HostSystem">host-2</obj>
+			<propSet><name>name</name><val xsi:type="xsd:string">esx2</val></propSet>
+			<propSet><name>runtime.connectionState</name><val xsi:type="HostSystemConnectionState">disconnected</val></propSet>
+		</objects></returnval></ContinueRetrievePropertiesExResponse>`)
+
+	case strings.Contains(body, "<type>VirtualMachine</type>"):
+		writeBody(`<RetrievePropertiesExResponse xmlns="urn:vim25"><returnval>
+			<objects><obj type="VirtualMachine">vm-1</obj>
+				<propSet><name>name</name><val xsi:type="xsd:string">web</val></propSet>
+				<propSet><name>runtime.host</name><val type="HostSystem" xsi:type="ManagedObjectReference">host-1</val></propSet>
+				<propSet><name>runtime.powerState</name><val xsi:type="VirtualMachinePowerState">poweredOn</val></propSet>
+			</objects>
+			<objects><obj type="VirtualMachine">vm-2</obj>
+				<propSet><name>name</name><val xsi:type="xsd:string">db</val></propSet>
+				<propSet><name>runtime.host</name><val type="HostSystem" xsi:type="ManagedObjectReference">host-1</val></propSet>
+				<propSet><name>runtime.powerState</name><val xsi:type="VirtualMachinePowerState">poweredOff</val></propSet>
+			</objects>
+		</returnval></RetrievePropertiesExResponse>`)
+
+	case strings.Contains(body, "<type>Datastore</type>"):
+		writeBody(`<RetrievePropertiesExResponse xmlns="urn:vim25"><returnval><objects><obj type="Datastore">datastore-1</obj>
+			<propSet><name>name</name><val xsi:type="xsd:string">ds1</val></propSet>
+			<propSet><name>summary.accessible</name><val xsi:type="xsd:boolean">true</val></propSet>
+			<propSet><name>summary.capacity</name><val xsi:type="xsd:long">1000</val></propSet>
+			<propSet><name>summary.freeSpace</name><val xsi:type="xsd:long">400</val></propSet>
+		</objects></returnval></RetrievePropertiesExResponse>`)
+
+	case strings.Contains(body, "<QueryPerf>"):
+		f.queries = append(f.queries, body)
+
+		var res strings.Builder
+		res.WriteString(`<QueryPerfResponse xmlns="urn:vim25">`)
+		if strings.Contains(body, `<entity type="HostSystem">host-1</entity>`) {
+			f.expect(body, "<intervalId>20</intervalId>")
+			res.WriteString(`<returnval xsi:type="PerfEntityMetric"><entity type="HostSystem">host-1</entity>
+				<value xsi:type="PerfMetricIntSeries"><id><counterId>2</counterId><instance></instance></id><value>2550</value></value>
+			</returnval>`)
+		}
+		if strings.Contains(body, `<entity type="VirtualMachine">vm-1</entity>`) {
+			res.WriteString(`<returnval xsi:type="PerfEntityMetric"><entity type="VirtualMachine">vm-1</entity>
+				<value xsi:type="PerfMetricIntSeries"><id><counterId>2</counterId><instance></instance></id><value>1000</value></value>
+				<value xsi:type="PerfMetricIntSeries"><id><counterId>24</counterId><instance></instance></id><value>-1</value></value>
+			</returnval>`)
+		}
+		if strings.Contains(body, `<entity type="Datastore">datastore-1</entity>`) {
+			f.expect(body, "<intervalId>300</intervalId>")
+			res.WriteString(`<returnval xsi:type="PerfEntityMetric"><entity type="Datastore">datastore-1</entity>
+				<value xsi:type="PerfMetricIntSeries"><id><counterId>240</counterId><instance></instance></id><value>100</value><value>123</value></value>
+			</returnval>`)
+		}
+		res.WriteString(`</QueryPerfResponse>`)
+		writeBody(res.String())
+
+	default:
+		f.errs = append(f.errs, "unexpected request: "+body)
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+const expectAll = `
+# HELP vsphere_datastore_accessible Whether the datastore is accessible.
+# TYPE vsphere_datastore_accessible gauge
+vsphere_datastore_accessible{datastore="ds1"} 1
+# HELP vsphere_datastore_capacity_bytes Capacity of the datastore.
+# TYPE vsphere_datastore_capacity_bytes gauge
+vsphere_datastore_capacity_bytes{datastore="ds1"} 1000
+# HELP vsphere_datastore_disk_used_latest vSphere performance counter disk.used.latest.
+# TYPE vsphere_datastore_disk_used_latest gauge
+vsphere_datastore_disk_used_latest{datastore="ds1"} 123
+# HELP vsphere_datastore_free_bytes Free space of the datastore.
+# TYPE vsphere_datastore_free_bytes gauge
+vsphere_datastore_free_bytes{datastore="ds1"} 400
+# HELP vsphere_host_cpu_usage_average vSphere performance counter cpu.usage.average.
+# TYPE vsphere_host_cpu_usage_average gauge
+vsphere_host_cpu_usage_average{host="esx1"} 25.5
+# HELP vsphere_up Whether the last scrape of the vSphere API was successful.
+# TYPE vsphere_up gauge
+vsphere_up 1
+# HELP vsphere_vm_cpu_usage_average vSphere performance counter cpu.usage.average.
+# TYPE vsphere_vm_cpu_usage_average gauge
+vsphere_vm_cpu_usage_average{host="esx1",vm="web"} 10
+`
+
+func TestCollector(t *testing.T) {
+	fake := &fakeVSphere{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte("{vsphere_url: "+srv.URL+"/sdk, username: agent, password: secret}"), &cfg))
+	c, err := newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expectAll)))
+	require.Equal(t, 1, fake.logins)
+
+	// Expire the session; the collector should log in again.
+	fake.mut.Lock()
+	fake.session = "expired"
+	fake.mut.Unlock()
+
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expectAll)))
+	require.Equal(t, 2, fake.logins)
+	require.Empty(t, fake.errs)
+}
+
+func TestCollector_Filters(t *testing.T) {
+	fake := &fakeVSphere{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	var cfg Config
+	require.NoError(t, yaml.Unmarshal([]byte("{vsphere_url: "+srv.URL+"/sdk, username: agent, password: secret, hosts: {exclude: ^esx1$}, vms: {enabled: false}, datastores: {counters: []}}"), &cfg))
+	c, err := newCollector(log.NewNopLogger(), &cfg)
+	require.NoError(t, err)
+
+	expect := `
+# HELP vsphere_datastore_capacity_bytes Capacity of the datastore.
+# TYPE vsphere_datastore_capacity_bytes gauge
+vsphere_datastore_capacity_bytes{datastore="ds1"} 1000
+# HELP vsphere_up Whether the last scrape of the vSphere API was successful.
+# TYPE vsphere_up gauge
+vsphere_up 1
+`
+	require.NoError(t, testutil.CollectAndCompare(c, strings.NewReader(expect), "vsphere_up", "vsphere_datastore_capacity_bytes", "vsphere_host_cpu_usage_average", "vsphere_vm_cpu_usage_average"))
+
+	// No entities are left to query.
+	require.Empty(t, fake.queries)
+	require.Empty(t, fake.errs)
+}
@@ -0,0 +1,323 @@
+package vsphere
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// soapAction is sent with every request. vCenter also serves clients of
+// newer API versions using this namespace.
+const soapAction = "urn:vim25/6.0"
+
+// mor is a managed object reference.
+type mor struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// soapFault is a fault returned by the vSphere API.
+type soapFault struct {
+	Code   string `xml:"faultcode"`
+	String string `xml:"faultstring"`
+	Detail struct {
+		Inner []struct {
+			XMLName xml.Name
+		} `xml:",any"`
+	} `xml:"detail"`
+}
+
+func (f *soapFault) Error() string {
+	return fmt.Sprintf("vsphere fault: %s", f.String)
+}
+
+// notAuthenticated returns true if the fault was caused by an invalid or
+// expired session.
+func (f *soapFault) notAuthenticated() bool {
+	for _, d := range f.Detail.Inner {
+		if strings.HasPrefix(d.XMLName.Local, "NotAuthenticated") {
+			return true
+		}
+	}
+	return false
+}
+
+// soapClient performs requests against the vim25 SOAP API. The session is
+// tracked by the cookie jar of the underlying http.Client.
+type soapClient struct {
+	client *http.Client
+	url    string
+}
+
+// call sends req as the body of a SOAP envelope and decodes the body of the
+// response into resp.
+func (c *soapClient) call(ctx context.Context, req, resp interface{}) error {
+	var buf bytes.Buffer
+	buf.WriteString(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns="urn:vim25"><soapenv:Body>`)
+	if err := xml.NewEncoder(&buf).Encode(req); err != nil {
+		return err
+	}
+	buf.WriteString(`</soapenv:Body></soapenv:Envelope>`)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, &buf)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	httpReq.Header.Set("SOAPAction", soapAction)
+
+	httpResp, err := c.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	var env struct {
+		Body struct {
+			Fault *soapFault `xml:"Fault"`
+			Inner []byte     `xml:",innerxml"`
+		} `xml:"Body"`
+	}
+	body, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(body, &env); err != nil {
+		return fmt.Errorf("failed to parse response with status code %d: %w", httpResp.StatusCode, err)
+	}
+	if env.Body.Fault != nil {
+		return env.Body.Fault
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", httpResp.StatusCode)
+	}
+	return xml.Unmarshal(env.Body.Inner, resp)
+}
+
+// serviceContent holds references to the managed objects used by the
+// integration.
+type serviceContent struct {
+	RootFolder        mor `xml:"rootFolder"`
+	PropertyCollector mor `xml:"propertyCollector"`
+	ViewManager       mor `xml:"viewManager"`
+	SessionManager    mor `xml:"sessionManager"`
+	PerfManager       mor `xml:"perfManager"`
+}
+
+func (c *soapClient) retrieveServiceContent(ctx context.Context) (*serviceContent, error) {
+	req := struct {
+		XMLName xml.Name `xml:"RetrieveServiceContent"`
+		This    mor      `xml:"_this"`
+	}{This: mor{Type: "ServiceInstance", Value: "ServiceInstance"}}
+
+	var resp struct {
+		Returnval serviceContent `xml:"returnval"`
+	}
+	if err := c.call(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp.Returnval, nil
+}
+
+func (c *soapClient) login(ctx context.Context, sessionManager mor, username, password string) error {
+	req := struct {
+		XMLName  xml.Name `xml:"Login"`
+		This     mor      `xml:"_this"`
+		UserName string   `xml:"userName"`
+		Password string   `xml:"password"`
+	}{This: sessionManager, UserName: username, Password: password}
+
+	var resp struct{}
+	return c.call(ctx, req, &resp)
+}
+
+func (c *soapClient) logout(ctx context.Context, sessionManager mor) error {
+	req := struct {
+		XMLName xml.Name `xml:"Logout"`
+		This    mor      `xml:"_this"`
+	}{This: sessionManager}
+
+	var resp struct{}
+	return c.call(ctx, req, &resp)
+}
+
+func (c *soapClient) createContainerView(ctx context.Context, viewManager, container mor, objectType string) (mor, error) {
+	req := struct {
+		XMLName   xml.Name `xml:"CreateContainerView"`
+		This      mor      `xml:"_this"`
+		Container mor      `xml:"container"`
+		Type      string   `xml:"type"`
+		Recursive bool     `xml:"recursive"`
+	}{This: viewManager, Container: container, Type: objectType, Recursive: true}
+
+	var resp struct {
+		Returnval mor `xml:"returnval"`
+	}
+	if err := c.call(ctx, req, &resp); err != nil {
+		return mor{}, err
+	}
+	return resp.Returnval, nil
+}
+
+// objectContent holds the retrieved properties of a managed object.
+type objectContent struct {
+	Obj     mor `xml:"obj"`
+	PropSet []struct {
+		Name string        `xml:"name"`
+		Val  propertyValue `xml:"val"`
+	} `xml:"propSet"`
+}
+
+// propertyValue is the value of a property. Only the value types used by
+// the integration are decoded.
+type propertyValue struct {
+	Text         string            `xml:",chardata"`
+	PerfCounters []perfCounterInfo `xml:"PerfCounterInfo"`
+}
+
+// prop returns the value of the property with the given name.
+func (o *objectContent) prop(name string) (propertyValue, bool) {
+	for _, p := range o.PropSet {
+		if p.Name == name {
+			return p.Val, true
+		}
+	}
+	return propertyValue{}, false
+}
+
+type propertySpec struct {
+	Type    string   `xml:"type"`
+	PathSet []string `xml:"pathSet"`
+}
+
+type traversalSpec struct {
+	XSIType string `xml:"xsi:type,attr"`
+	Name    string `xml:"name"`
+	Type    string `xml:"type"`
+	Path    string `xml:"path"`
+	Skip    bool   `xml:"skip"`
+}
+
+type objectSpec struct {
+	Obj       mor            `xml:"obj"`
+	Skip      bool           `xml:"skip"`
+	SelectSet *traversalSpec `xml:"selectSet,omitempty"`
+}
+
+// retrieveProperties retrieves the properties in paths of obj. If obj is a
+// ContainerView, the properties of all objects of the view are retrieved.
+func (c *soapClient) retrieveProperties(ctx context.Context, propertyCollector, obj mor, objectType string, paths []string) ([]objectContent, error) {
+	spec := objectSpec{Obj: obj}
+	if obj.Type == "ContainerView" {
+		spec.Skip = true
+		spec.SelectSet = &traversalSpec{
+			XSIType: "TraversalSpec",
+			Name:    "traverseEntities",
+			Type:    "ContainerView",
+			Path:    "view",
+		}
+	}
+
+	req := struct {
+		XMLName xml.Name `xml:"RetrievePropertiesEx"`
+		This    mor      `xml:"_this"`
+		SpecSet struct {
+			PropSet   propertySpec `xml:"propSet"`
+			ObjectSet objectSpec   `xml:"objectSet"`
+		} `xml:"specSet"`
+		Options struct{} `xml:"options"`
+	}{This: propertyCollector}
+	req.SpecSet.PropSet = propertySpec{Type: objectType, PathSet: paths}
+	req.SpecSet.ObjectSet = spec
+
+	type result struct {
+		Returnval struct {
+			Token   string          `xml:"token"`
+			Objects []objectContent `xml:"objects"`
+		} `xml:"returnval"`
+	}
+
+	var resp result
+	if err := c.call(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+	objects := resp.Returnval.Objects
+
+	for token := resp.Returnval.Token; token != ""; {
+		req := struct {
+			XMLName xml.Name `xml:"ContinueRetrievePropertiesEx"`
+			This    mor      `xml:"_this"`
+			Token   string   `xml:"token"`
+		}{This: propertyCollector, Token: token}
+
+		var resp result
+		if err := c.call(ctx, req, &resp); err != nil {
+			return nil, err
+		}
+		objects = append(objects, resp.Returnval.Objects...)
+		token = resp.Returnval.Token
+	}
+	return objects, nil
+}
+
+// elementDescription describes the name, group, or unit of a performance
+// counter.
+type elementDescription struct {
+	Key string `xml:"key"`
+}
+
+// perfCounterInfo describes a performance counter.
+type perfCounterInfo struct {
+	Key       int32              `xml:"key"`
+	NameInfo  elementDescription `xml:"nameInfo"`
+	GroupInfo elementDescription `xml:"groupInfo"`
+	UnitInfo  elementDescription `xml:"unitInfo"`
+	Rollup    string             `xml:"rollupType"`
+}
+
+// name returns the name of the counter in the form group.name.rollup, e.g.,
+// cpu.usage.average.
+func (i perfCounterInfo) name() string {
+	return i.GroupInfo.Key + "." + i.NameInfo.Key + "." + i.Rollup
+}
+
+type perfMetricID struct {
+	CounterID int32  `xml:"counterId"`
+	Instance  string `xml:"instance"`
+}
+
+type perfQuerySpec struct {
+	Entity     mor            `xml:"entity"`
+	MaxSample  int            `xml:"maxSample"`
+	MetricID   []perfMetricID `xml:"metricId"`
+	IntervalID int            `xml:"intervalId"`
+}
+
+// perfEntityMetric holds the samples of an entity returned by QueryPerf.
+type perfEntityMetric struct {
+	Entity mor `xml:"entity"`
+	Value  []struct {
+		ID    perfMetricID `xml:"id"`
+		Value []int64      `xml:"value"`
+	} `xml:"value"`
+}
+
+func (c *soapClient) queryPerf(ctx context.Context, perfManager mor, specs []perfQuerySpec) ([]perfEntityMetric, error) {
+	req := struct {
+		XMLName   xml.Name        `xml:"QueryPerf"`
+		This      mor             `xml:"_this"`
+		QuerySpec []perfQuerySpec `xml:"querySpec"`
+	}{This: perfManager, QuerySpec: specs}
+
+	var resp struct {
+		Returnval []perfEntityMetric `xml:"returnval"`
+	}
+	if err := c.call(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Returnval, nil
+}
@@ -0,0 +1,177 @@
+// Package vsphere implements an integration which collects performance
+// counters of hosts, virtual machines, and datastores from VMware vCenter or
+// ESXi using the vSphere API.
+package vsphere
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/integrations"
+	integrations_v2 "github.com/grafana/agent/pkg/integrations/v2"
+	"github.com/grafana/agent/pkg/integrations/v2/common"
+	"github.com/grafana/agent/pkg/integrations/v2/metricsutils"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+)
+
+// DefaultConfig holds the default settings for the vsphere integration.
+var DefaultConfig = Config{
+	Timeout:                   30 * time.Second,
+	SamplingInterval:          20 * time.Second,
+	DatastoreSamplingInterval: 5 * time.Minute,
+
+	Hosts: ObjectConfig{
+		Enabled: true,
+		Counters: []string{
+			"cpu.usage.average",
+			"cpu.usagemhz.average",
+			"mem.usage.average",
+			"mem.consumed.average",
+			"disk.read.average",
+			"disk.write.average",
+			"net.received.average",
+			"net.transmitted.average",
+		},
+	},
+	VMs: ObjectConfig{
+		Enabled: true,
+		Counters: []string{
+			"cpu.usage.average",
+			"cpu.ready.summation",
+			"mem.usage.average",
+			"mem.active.average",
+			"disk.read.average",
+			"disk.write.average",
+			"net.received.average",
+			"net.transmitted.average",
+		},
+	},
+	Datastores: ObjectConfig{
+		Enabled: true,
+		Counters: []string{
+			"disk.capacity.latest",
+			"disk.provisioned.latest",
+			"disk.used.latest",
+		},
+	},
+}
+
+// Config controls the vsphere integration.
+type Config struct {
+	// URL of the vSphere API, e.g., https://vcenter.example.com/sdk.
+	URL string `yaml:"vsphere_url,omitempty"`
+	// Username to log in with.
+	Username string `yaml:"username,omitempty"`
+	// Password to log in with.
+	Password config_util.Secret `yaml:"password,omitempty"`
+	// Timeout for collecting metrics.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// TLSConfig configures TLS for requests to the vSphere API.
+	TLSConfig config_util.TLSConfig `yaml:"tls_config,omitempty"`
+
+	// SamplingInterval is the interval of the performance counters of hosts
+	// and VMs. 20s selects the real-time statistics of ESXi; longer
+	// intervals must match a historical interval configured in vCenter.
+	SamplingInterval time.Duration `yaml:"sampling_interval,omitempty"`
+	// DatastoreSamplingInterval is the interval of the performance counters
+	// of datastores, which only have historical statistics.
+	DatastoreSamplingInterval time.Duration `yaml:"datastore_sampling_interval,omitempty"`
+
+	Hosts      ObjectConfig `yaml:"hosts,omitempty"`
+	VMs        ObjectConfig `yaml:"vms,omitempty"`
+	Datastores ObjectConfig `yaml:"datastores,omitempty"`
+}
+
+// ObjectConfig controls collection of a type of managed object.
+type ObjectConfig struct {
+	// Enabled enables collection for the object type.
+	Enabled bool `yaml:"enabled"`
+	// Include is a regex of object names to collect. All objects are
+	// collected if empty.
+	Include string `yaml:"include,omitempty"`
+	// Exclude is a regex of object names not to collect.
+	Exclude string `yaml:"exclude,omitempty"`
+	// Counters are the names of the performance counters to collect, in the
+	// form group.name.rollup.
+	Counters []string `yaml:"counters,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler for Config.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type plain Config
+	if err := unmarshal((*plain)(c)); err != nil {
+		return err
+	}
+
+	if c.URL == "" {
+		return fmt.Errorf("vsphere_url must be set")
+	}
+	if _, err := url.Parse(c.URL); err != nil {
+		return fmt.Errorf("invalid vsphere_url: %w", err)
+	}
+	if c.SamplingInterval < time.Second || c.DatastoreSamplingInterval < time.Second {
+		return fmt.Errorf("sampling intervals must be at least 1s")
+	}
+
+	for name, oc := range map[string]ObjectConfig{"hosts": c.Hosts, "vms": c.VMs, "datastores": c.Datastores} {
+		for _, re := range []string{oc.Include, oc.Exclude} {
+			if _, err := regexp.Compile(re); err != nil {
+				return fmt.Errorf("%s: invalid filter %q: %w", name, re, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Name returns the name of the integration that this config represents.
+func (c *Config) Name() string {
+	return "vsphere"
+}
+
+// InstanceKey returns the host:port of the vSphere API.
+func (c *Config) InstanceKey(agentKey string) (string, error) {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return "", fmt.Errorf("could not parse url: %w", err)
+	}
+	return u.Host, nil
+}
+
+// ScrapeLimits returns the limits of autoscrape settings for the integration.
+// Collecting metrics may take up to timeout, and performance counters only
+// change once per sampling_interval.
+func (c *Config) ScrapeLimits() common.ScrapeLimits {
+	return common.ScrapeLimits{
+		MinScrapeInterval: model.Duration(c.SamplingInterval),
+		MinScrapeTimeout:  model.Duration(c.Timeout),
+	}
+}
+
+// NewIntegration converts this config into an instance of an integration.
+func (c *Config) NewIntegration(l log.Logger) (integrations.Integration, error) {
+	return New(l, c)
+}
+
+func init() {
+	integrations.RegisterIntegration(&Config{})
+	integrations_v2.RegisterLegacy(&Config{}, integrations_v2.TypeMultiplex, metricsutils.CreateShim)
+}
+
+// New creates a new vsphere integration.
+func New(logger log.Logger, c *Config) (integrations.Integration, error) {
+	col, err := newCollector(logger, c)
+	if err != nil {
+		return nil, err
+	}
+	return integrations.NewCollectorIntegration(
+		c.Name(),
+		integrations.WithCollectors(col),
+		integrations.WithRunner(col.run),
+	), nil
+}
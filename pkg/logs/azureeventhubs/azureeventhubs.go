@@ -0,0 +1,193 @@
+// Package azureeventhubs implements a log source which consumes logs streamed
+// to Azure Event Hubs, such as Azure diagnostic logs. Event Hubs are consumed
+// through their Kafka-compatible endpoint.
+package azureeventhubs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/grafana/agent/pkg/logs/kafka"
+	"github.com/grafana/agent/pkg/logs/stages"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// kafkaPort is the port of the Kafka endpoint of an Event Hubs namespace.
+const kafkaPort = "9093"
+
+// Labels discovered for every record which can be used in relabel_configs.
+const (
+	metaLabelPrefix    = model.MetaLabelPrefix + "azure_event_hubs_"
+	categoryLabel      = metaLabelPrefix + "category"
+	resourceIDLabel    = metaLabelPrefix + "resource_id"
+	operationNameLabel = metaLabelPrefix + "operation_name"
+	levelLabel         = metaLabelPrefix + "level"
+)
+
+// DefaultConfig holds the default settings for an Azure Event Hubs scrape
+// config.
+var DefaultConfig = Config{
+	GroupID: "grafana-agent",
+}
+
+// Config configures a job which consumes logs from Azure Event Hubs.
+type Config struct {
+	// JobName identifies the job in metrics and pipeline stages. Required.
+	JobName string `yaml:"job_name"`
+
+	// FullyQualifiedNamespace is the host of the Event Hubs namespace, such
+	// as my-namespace.servicebus.windows.net. Required.
+	FullyQualifiedNamespace string `yaml:"fully_qualified_namespace"`
+
+	// EventHubs to consume from. Required.
+	EventHubs []string `yaml:"event_hubs"`
+
+	// ConnectionString of the namespace or of a shared access policy with
+	// Listen permissions. Required.
+	ConnectionString config_util.Secret `yaml:"connection_string"`
+
+	// GroupID is the consumer group to join.
+	GroupID string `yaml:"group_id,omitempty"`
+
+	// DisallowCustomMessages drops messages which aren't Azure resource log
+	// envelopes instead of forwarding them as is.
+	DisallowCustomMessages bool `yaml:"disallow_custom_messages,omitempty"`
+
+	// Labels to add to every log line.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+
+	// UseIncomingTimestamp uses the time of the records instead of the time
+	// they were consumed.
+	UseIncomingTimestamp bool `yaml:"use_incoming_timestamp,omitempty"`
+
+	// RelabelConfigs are applied to the __meta_azure_event_hubs_* and
+	// __meta_kafka_* labels of each record.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
+
+	// PipelineStages process log lines before they're sent to Loki.
+	PipelineStages stages.PipelineStages `yaml:"pipeline_stages,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type config Config
+	if err := unmarshal((*config)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate returns an error if c is not valid.
+func (c *Config) Validate() error {
+	switch {
+	case c.JobName == "":
+		return fmt.Errorf("azure_event_hubs config must have a job_name")
+	case c.FullyQualifiedNamespace == "":
+		return fmt.Errorf("azure_event_hubs config %s must have a fully_qualified_namespace", c.JobName)
+	case len(c.EventHubs) == 0:
+		return fmt.Errorf("azure_event_hubs config %s must have at least one event hub", c.JobName)
+	case c.ConnectionString == "":
+		return fmt.Errorf("azure_event_hubs config %s must have a connection_string", c.JobName)
+	case c.GroupID == "":
+		return fmt.Errorf("azure_event_hubs config %s must have a group_id", c.JobName)
+	}
+	return nil
+}
+
+// KafkaConfig returns the config of the Kafka job which consumes the Event
+// Hubs of c.
+func (c *Config) KafkaConfig() kafka.Config {
+	broker := c.FullyQualifiedNamespace
+	if _, _, err := net.SplitHostPort(broker); err != nil {
+		broker = net.JoinHostPort(broker, kafkaPort)
+	}
+
+	return kafka.Config{
+		JobName: c.JobName,
+		Brokers: []string{broker},
+		Topics:  c.EventHubs,
+		GroupID: c.GroupID,
+		Version: sarama.V1_0_0_0.String(),
+		Format:  kafka.FormatRaw,
+		Authentication: kafka.Authentication{
+			Type: kafka.AuthenticationTypeSASL,
+			SASLConfig: kafka.SASLConfig{
+				Mechanism: sarama.SASLTypePlaintext,
+				User:      "$ConnectionString",
+				Password:  c.ConnectionString,
+				UseTLS:    true,
+			},
+		},
+		Labels:               c.Labels,
+		UseIncomingTimestamp: c.UseIncomingTimestamp,
+		RelabelConfigs:       c.RelabelConfigs,
+		PipelineStages:       c.PipelineStages,
+		Decoder:              c.decode,
+	}
+}
+
+// envelope is the body of messages holding Azure resource logs.
+type envelope struct {
+	Records []json.RawMessage `json:"records"`
+}
+
+// record holds the fields of an Azure resource log record which are exposed
+// as labels.
+type record struct {
+	Time          string `json:"time"`
+	ResourceID    string `json:"resourceId"`
+	Category      string `json:"category"`
+	OperationName string `json:"operationName"`
+	Level         string `json:"level"`
+}
+
+// decode splits an envelope of Azure resource logs into one record per log.
+// Messages which aren't envelopes are forwarded as is unless
+// DisallowCustomMessages is set.
+func (c *Config) decode(msg *sarama.ConsumerMessage) ([]kafka.Record, error) {
+	var env envelope
+	if err := json.Unmarshal(msg.Value, &env); err != nil || len(env.Records) == 0 {
+		if c.DisallowCustomMessages {
+			return nil, fmt.Errorf("message is not an azure resource log envelope")
+		}
+		return []kafka.Record{{Line: string(msg.Value)}}, nil
+	}
+
+	records := make([]kafka.Record, 0, len(env.Records))
+	for _, raw := range env.Records {
+		var r record
+		if err := json.Unmarshal(raw, &r); err != nil {
+			return nil, fmt.Errorf("invalid record: %w", err)
+		}
+
+		lset := model.LabelSet{}
+		for name, value := range map[model.LabelName]string{
+			categoryLabel:      r.Category,
+			resourceIDLabel:    r.ResourceID,
+			operationNameLabel: r.OperationName,
+			levelLabel:         r.Level,
+		} {
+			if value != "" {
+				lset[name] = model.LabelValue(value)
+			}
+		}
+
+		// Records which don't have a valid time fall back to the time of
+		// the message.
+		ts, _ := time.Parse(time.RFC3339Nano, r.Time)
+
+		records = append(records, kafka.Record{
+			Line:      string(raw),
+			Timestamp: ts,
+			Labels:    lset,
+		})
+	}
+	return records, nil
+}
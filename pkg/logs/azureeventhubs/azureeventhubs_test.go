@@ -0,0 +1,89 @@
+package azureeventhubs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/grafana/agent/pkg/logs/kafka"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig_KafkaConfig(t *testing.T) {
+	in := `
+job_name: azure
+fully_qualified_namespace: my-namespace.servicebus.windows.net
+event_hubs: [insights-logs]
+connection_string: Endpoint=sb://my-namespace.servicebus.windows.net/;SharedAccessKeyName=agent;SharedAccessKey=secret
+labels:
+  job: azure
+`
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(in), &cfg))
+
+	kc := cfg.KafkaConfig()
+	require.NoError(t, kc.Validate())
+	require.Equal(t, []string{"my-namespace.servicebus.windows.net:9093"}, kc.Brokers)
+	require.Equal(t, []string{"insights-logs"}, kc.Topics)
+	require.Equal(t, "grafana-agent", kc.GroupID)
+	require.Equal(t, kafka.AuthenticationTypeSASL, kc.Authentication.Type)
+	require.Equal(t, "$ConnectionString", kc.Authentication.SASLConfig.User)
+	require.Equal(t, cfg.ConnectionString, kc.Authentication.SASLConfig.Password)
+	require.True(t, kc.Authentication.SASLConfig.UseTLS)
+	require.Equal(t, model.LabelSet{"job": "azure"}, kc.Labels)
+	require.NotNil(t, kc.Decoder)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	in := `
+job_name: azure
+fully_qualified_namespace: my-namespace.servicebus.windows.net
+event_hubs: [insights-logs]
+`
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(in), &cfg)
+	require.EqualError(t, err, "azure_event_hubs config azure must have a connection_string")
+}
+
+func TestConfig_decode(t *testing.T) {
+	envelope := `{"records": [
+		{"time": "2022-01-01T00:00:00.5Z", "resourceId": "/SUBSCRIPTIONS/1/RESOURCEGROUPS/RG/PROVIDERS/MICROSOFT.WEB/SITES/APP", "category": "AppServiceHTTPLogs", "operationName": "Microsoft.Web/sites/log"},
+		{"time": "invalid", "category": "AppServiceConsoleLogs", "level": "Informational"}
+	]}`
+
+	t.Run("envelope", func(t *testing.T) {
+		cfg := Config{}
+		records, err := cfg.decode(&sarama.ConsumerMessage{Value: []byte(envelope)})
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+
+		require.Equal(t, time.Date(2022, 1, 1, 0, 0, 0, 500*int(time.Millisecond), time.UTC), records[0].Timestamp)
+		require.Equal(t, model.LabelSet{
+			categoryLabel:      "AppServiceHTTPLogs",
+			resourceIDLabel:    "/SUBSCRIPTIONS/1/RESOURCEGROUPS/RG/PROVIDERS/MICROSOFT.WEB/SITES/APP",
+			operationNameLabel: "Microsoft.Web/sites/log",
+		}, records[0].Labels)
+		require.Contains(t, records[0].Line, `"category": "AppServiceHTTPLogs"`)
+
+		require.True(t, records[1].Timestamp.IsZero())
+		require.Equal(t, model.LabelSet{
+			categoryLabel: "AppServiceConsoleLogs",
+			levelLabel:    "Informational",
+		}, records[1].Labels)
+	})
+
+	t.Run("custom message", func(t *testing.T) {
+		cfg := Config{}
+		records, err := cfg.decode(&sarama.ConsumerMessage{Value: []byte("hello")})
+		require.NoError(t, err)
+		require.Equal(t, []kafka.Record{{Line: "hello"}}, records)
+	})
+
+	t.Run("disallowed custom message", func(t *testing.T) {
+		cfg := Config{DisallowCustomMessages: true}
+		_, err := cfg.decode(&sarama.ConsumerMessage{Value: []byte(`{"message": "hello"}`)})
+		require.Error(t, err)
+	})
+}
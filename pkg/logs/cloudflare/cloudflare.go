@@ -0,0 +1,53 @@
+// Package cloudflare implements a log source which pulls HTTP request logs of
+// Cloudflare zones from the Logpull API.
+package cloudflare
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/logs/stages"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TargetManager runs a Target for each Cloudflare config.
+type TargetManager struct {
+	targets map[string]*Target
+}
+
+// NewTargetManager creates a TargetManager which starts pulling for every
+// config in cfgs. Entries are processed by the pipeline stages of their
+// config and then sent to client. The position of each target is stored in a
+// file named by appending the job name to positionsPrefix.
+func NewTargetManager(reg prometheus.Registerer, l log.Logger, client api.EntryHandler, positionsPrefix string, cfgs []Config) (*TargetManager, error) {
+	tm := &TargetManager{
+		targets: make(map[string]*Target, len(cfgs)),
+	}
+	if len(cfgs) == 0 {
+		return tm, nil
+	}
+
+	metrics := NewMetrics(reg)
+	for i := range cfgs {
+		cfg := &cfgs[i]
+		jobName := cfg.JobName
+		pipeline, err := stages.NewPipeline(log.With(l, "component", "cloudflare_pipeline"), cfg.PipelineStages, &jobName, reg)
+		if err != nil {
+			tm.Stop()
+			return nil, fmt.Errorf("failed to create pipeline for cloudflare config %s: %w", cfg.JobName, err)
+		}
+
+		positionsPath := positionsPrefix + url.PathEscape(cfg.JobName) + ".json"
+		tm.targets[cfg.JobName] = NewTarget(l, metrics, pipeline.Wrap(client), positionsPath, cfg)
+	}
+	return tm, nil
+}
+
+// Stop stops all targets.
+func (tm *TargetManager) Stop() {
+	for _, t := range tm.targets {
+		t.Stop()
+	}
+}
@@ -0,0 +1,128 @@
+package cloudflare
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		in := `
+job_name: cloudflare
+api_token: secret
+zone_id: zone
+additional_fields: [ClientCountry, RayID]
+`
+		var cfg Config
+		require.NoError(t, yaml.UnmarshalStrict([]byte(in), &cfg))
+		require.Equal(t, model.Duration(time.Minute), cfg.PullRange)
+		require.Equal(t, 10, cfg.RequestsPerMinute)
+		require.Equal(t, append(defaultFields, "ClientCountry"), cfg.Fields())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		in := `
+job_name: cloudflare
+api_token: secret
+zone_id: zone
+pull_range: 2h
+`
+		var cfg Config
+		err := yaml.UnmarshalStrict([]byte(in), &cfg)
+		require.EqualError(t, err, "cloudflare config cloudflare must have a pull_range between 0 and 1h0m0s")
+	})
+
+	t.Run("fields types extend each other", func(t *testing.T) {
+		require.Subset(t, minimalFields, defaultFields)
+		require.Subset(t, extendedFields, minimalFields)
+		require.Len(t, defaultFields, 10)
+	})
+}
+
+func TestTarget_pullUntilCurrent(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/zones/zone/logs/received", r.URL.Path)
+		require.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		require.Equal(t, "rfc3339", r.URL.Query().Get("timestamps"))
+		require.Equal(t, strings.Join(defaultFields, ","), r.URL.Query().Get("fields"))
+
+		start := r.URL.Query().Get("start")
+		requests = append(requests, start)
+		fmt.Fprintf(w, "{\"EdgeStartTimestamp\":%q,\"RayID\":\"1\"}\n\n{\"RayID\":\"2\"}\n", start)
+	}))
+	defer srv.Close()
+
+	cfg := &Config{
+		JobName:           "cloudflare",
+		APIToken:          "secret",
+		ZoneID:            "zone",
+		PullRange:         model.Duration(time.Minute),
+		FieldsType:        FieldsTypeDefault,
+		RequestsPerMinute: 6000,
+		Labels:            model.LabelSet{"job": "cloudflare"},
+	}
+
+	entries := make(chan api.Entry, 10)
+	positionsPath := filepath.Join(t.TempDir(), "cloudflare.json")
+	target := newTarget(log.NewNopLogger(), NewMetrics(nil), api.NewEntryHandler(entries, func() {}), positionsPath, cfg, srv.URL)
+
+	// Two full ranges are available before the Logpull delay.
+	from := time.Now().Add(-logpullDelay - 2*time.Minute - 30*time.Second).Truncate(time.Second)
+	next := target.pullUntilCurrent(from)
+	require.Equal(t, from.Add(2*time.Minute), next)
+	require.Equal(t, []string{
+		from.UTC().Format(time.RFC3339),
+		from.Add(time.Minute).UTC().Format(time.RFC3339),
+	}, requests)
+
+	close(entries)
+	var lines []api.Entry
+	for e := range entries {
+		lines = append(lines, e)
+	}
+	require.Len(t, lines, 4)
+	require.Equal(t, model.LabelSet{"job": "cloudflare"}, lines[0].Labels)
+	require.True(t, from.Equal(lines[0].Timestamp), "timestamp should be read from EdgeStartTimestamp")
+	require.Equal(t, `{"RayID":"2"}`, lines[1].Line)
+
+	position, err := target.readPosition()
+	require.NoError(t, err)
+	require.True(t, next.Equal(position))
+}
+
+func TestTarget_pullError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer srv.Close()
+
+	cfg := &Config{
+		JobName:           "cloudflare",
+		APIToken:          "secret",
+		ZoneID:            "zone",
+		PullRange:         model.Duration(time.Minute),
+		FieldsType:        FieldsTypeDefault,
+		RequestsPerMinute: 6000,
+	}
+	positionsPath := filepath.Join(t.TempDir(), "cloudflare.json")
+	target := newTarget(log.NewNopLogger(), NewMetrics(nil), api.NewEntryHandler(make(chan api.Entry), func() {}), positionsPath, cfg, srv.URL)
+
+	from := time.Now().Add(-logpullDelay - 5*time.Minute)
+	require.Equal(t, from, target.pullUntilCurrent(from), "failed ranges should be retried")
+
+	position, err := target.readPosition()
+	require.NoError(t, err)
+	require.True(t, position.IsZero())
+}
@@ -0,0 +1,142 @@
+package cloudflare
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/agent/pkg/logs/stages"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+)
+
+// maxPullRange is the longest time range the Logpull API returns logs for in
+// a single request.
+const maxPullRange = time.Hour
+
+// DefaultConfig holds the default settings for a Cloudflare scrape config.
+var DefaultConfig = Config{
+	PullRange:         model.Duration(time.Minute),
+	FieldsType:        FieldsTypeDefault,
+	RequestsPerMinute: 10,
+}
+
+// Config configures a job which pulls HTTP request logs of a Cloudflare zone
+// from the Logpull API.
+type Config struct {
+	// JobName identifies the job in metrics and pipeline stages. Required.
+	JobName string `yaml:"job_name"`
+
+	// APIToken is a Cloudflare API token with the Zone Logs Read permission.
+	// Required.
+	APIToken config_util.Secret `yaml:"api_token"`
+
+	// ZoneID is the ID of the zone to pull logs for. Required.
+	ZoneID string `yaml:"zone_id"`
+
+	// PullRange is both the interval between pulls and the time range of logs
+	// requested by each pull.
+	PullRange model.Duration `yaml:"pull_range,omitempty"`
+
+	// FieldsType selects a predefined set of log fields to request.
+	FieldsType FieldsType `yaml:"fields_type,omitempty"`
+
+	// AdditionalFields are requested in addition to the fields of FieldsType.
+	AdditionalFields []string `yaml:"additional_fields,omitempty"`
+
+	// RequestsPerMinute limits the number of requests sent to the Logpull
+	// API, which matters when catching up after the agent was down.
+	RequestsPerMinute int `yaml:"requests_per_minute,omitempty"`
+
+	// Labels to add to every log line.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+
+	// PipelineStages process log lines before they're sent to Loki.
+	PipelineStages stages.PipelineStages `yaml:"pipeline_stages,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type config Config
+	if err := unmarshal((*config)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate returns an error if c is not valid.
+func (c *Config) Validate() error {
+	switch {
+	case c.JobName == "":
+		return fmt.Errorf("cloudflare config must have a job_name")
+	case c.APIToken == "":
+		return fmt.Errorf("cloudflare config %s must have an api_token", c.JobName)
+	case c.ZoneID == "":
+		return fmt.Errorf("cloudflare config %s must have a zone_id", c.JobName)
+	case c.PullRange <= 0 || time.Duration(c.PullRange) > maxPullRange:
+		return fmt.Errorf("cloudflare config %s must have a pull_range between 0 and %s", c.JobName, maxPullRange)
+	case c.RequestsPerMinute <= 0:
+		return fmt.Errorf("cloudflare config %s must have a positive requests_per_minute", c.JobName)
+	}
+	if _, ok := fieldsByType[c.FieldsType]; !ok {
+		return fmt.Errorf("cloudflare config %s has unknown fields_type %q", c.JobName, c.FieldsType)
+	}
+	return nil
+}
+
+// Fields returns the log fields to request.
+func (c *Config) Fields() []string {
+	base := fieldsByType[c.FieldsType]
+
+	fields := make([]string, 0, len(base)+len(c.AdditionalFields))
+	seen := make(map[string]struct{}, cap(fields))
+	for _, list := range [][]string{base, c.AdditionalFields} {
+		for _, f := range list {
+			if _, ok := seen[f]; ok {
+				continue
+			}
+			seen[f] = struct{}{}
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// FieldsType is a predefined set of log fields.
+type FieldsType string
+
+// Supported fields types.
+const (
+	FieldsTypeDefault  FieldsType = "default"
+	FieldsTypeMinimal  FieldsType = "minimal"
+	FieldsTypeExtended FieldsType = "extended"
+)
+
+var (
+	defaultFields = []string{
+		"ClientIP", "ClientRequestHost", "ClientRequestMethod", "ClientRequestURI",
+		"EdgeEndTimestamp", "EdgeResponseBytes", "EdgeRequestHost",
+		"EdgeResponseStatus", "EdgeStartTimestamp", "RayID",
+	}
+	minimalFields = append(defaultFields[:len(defaultFields):len(defaultFields)],
+		"ZoneID", "ClientSSLProtocol", "ClientRequestProtocol", "ClientRequestPath",
+		"ClientRequestUserAgent", "ClientRequestReferer", "EdgeColoCode",
+		"ClientCountry", "CacheCacheStatus", "CacheResponseStatus",
+		"EdgeResponseContentType",
+	)
+	extendedFields = append(minimalFields[:len(minimalFields):len(minimalFields)],
+		"ClientSSLCipher", "ClientASN", "ClientIPClass", "CacheResponseBytes",
+		"EdgePathingOp", "EdgePathingSrc", "EdgePathingStatus", "ParentRayID",
+		"WorkerCPUTime", "WorkerStatus", "WorkerSubrequest",
+		"WorkerSubrequestCount", "OriginIP", "OriginResponseStatus",
+		"OriginSSLProtocol", "OriginResponseHTTPExpires",
+		"OriginResponseHTTPLastModified",
+	)
+
+	fieldsByType = map[FieldsType][]string{
+		FieldsTypeDefault:  defaultFields,
+		FieldsTypeMinimal:  minimalFields,
+		FieldsTypeExtended: extendedFields,
+	}
+)
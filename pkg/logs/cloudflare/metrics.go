@@ -0,0 +1,38 @@
+package cloudflare
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics holds metrics for Cloudflare targets.
+type Metrics struct {
+	entries *prometheus.CounterVec
+	errors  *prometheus.CounterVec
+	lastEnd *prometheus.GaugeVec
+}
+
+// NewMetrics creates a new set of metrics. Metrics will be registered to reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{}
+
+	m.entries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "cloudflare_target_entries_total",
+		Help:      "Total number of log lines pulled from Cloudflare.",
+	}, []string{"job"})
+
+	m.errors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "cloudflare_target_pull_errors_total",
+		Help:      "Total number of failed pulls from the Cloudflare Logpull API.",
+	}, []string{"job"})
+
+	m.lastEnd = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "promtail",
+		Name:      "cloudflare_target_last_pulled_end_timestamp_seconds",
+		Help:      "End of the last time range pulled from the Cloudflare Logpull API.",
+	}, []string{"job"})
+
+	if reg != nil {
+		reg.MustRegister(m.entries, m.errors, m.lastEnd)
+	}
+	return m
+}
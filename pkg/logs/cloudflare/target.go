@@ -0,0 +1,247 @@
+package cloudflare
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultBaseURL = "https://api.cloudflare.com/client/v4"
+
+	// logpullDelay is how far in the past the end of a pulled time range must
+	// be. Logs become available in the Logpull API after a delay.
+	logpullDelay = time.Minute
+
+	// logpullRetention is how long the Logpull API keeps logs for.
+	logpullRetention = 7 * 24 * time.Hour
+
+	// maxLineSize is the largest log line which can be read from a response.
+	maxLineSize = 1024 * 1024
+)
+
+// Target pulls logs of a zone from the Logpull API on an interval.
+type Target struct {
+	log           log.Logger
+	metrics       *Metrics
+	cfg           *Config
+	handler       api.EntryHandler
+	positionsPath string
+
+	client  *http.Client
+	baseURL string
+	limiter *rate.Limiter
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewTarget creates a new Target and starts pulling. Entries are sent to
+// handler, which is stopped when the Target is stopped. The end of the last
+// pulled time range is stored at positionsPath, so pulls continue where they
+// left off after a restart.
+func NewTarget(l log.Logger, m *Metrics, handler api.EntryHandler, positionsPath string, cfg *Config) *Target {
+	t := newTarget(l, m, handler, positionsPath, cfg, defaultBaseURL)
+	t.wg.Add(1)
+	go t.run()
+	return t
+}
+
+func newTarget(l log.Logger, m *Metrics, handler api.EntryHandler, positionsPath string, cfg *Config, baseURL string) *Target {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Target{
+		log:           log.With(l, "job", cfg.JobName),
+		metrics:       m,
+		cfg:           cfg,
+		handler:       handler,
+		positionsPath: positionsPath,
+
+		client:  &http.Client{Timeout: time.Minute},
+		baseURL: baseURL,
+		limiter: rate.NewLimiter(rate.Every(time.Minute/time.Duration(cfg.RequestsPerMinute)), 1),
+
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func (t *Target) run() {
+	defer t.wg.Done()
+
+	pullRange := time.Duration(t.cfg.PullRange)
+	from, err := t.readPosition()
+	if err != nil {
+		level.Warn(t.log).Log("msg", "failed to read position, pulling from the current time", "path", t.positionsPath, "err", err)
+	}
+	if from.IsZero() {
+		from = time.Now().Add(-logpullDelay - pullRange)
+	}
+
+	ticker := time.NewTicker(pullRange)
+	defer ticker.Stop()
+
+	for {
+		from = t.pullUntilCurrent(from)
+
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pullUntilCurrent pulls consecutive time ranges starting at from until the
+// next range would end too recently to be available. Returns the start of the
+// next range to pull. A failed pull is retried on the next call.
+func (t *Target) pullUntilCurrent(from time.Time) time.Time {
+	pullRange := time.Duration(t.cfg.PullRange)
+
+	if oldest := time.Now().Add(-logpullRetention + logpullDelay); from.Before(oldest) {
+		level.Warn(t.log).Log("msg", "skipping logs which are no longer retained by cloudflare", "from", from, "to", oldest)
+		from = oldest
+	}
+
+	for {
+		end := from.Add(pullRange)
+		if end.After(time.Now().Add(-logpullDelay)) {
+			return from
+		}
+		if err := t.limiter.Wait(t.ctx); err != nil {
+			return from
+		}
+		if err := t.pull(from, end); err != nil {
+			if t.ctx.Err() == nil {
+				level.Error(t.log).Log("msg", "failed to pull logs", "start", from, "end", end, "err", err)
+				t.metrics.errors.WithLabelValues(t.cfg.JobName).Inc()
+			}
+			return from
+		}
+
+		from = end
+		t.metrics.lastEnd.WithLabelValues(t.cfg.JobName).Set(float64(end.Unix()))
+		if err := t.writePosition(end); err != nil {
+			level.Warn(t.log).Log("msg", "failed to write position", "path", t.positionsPath, "err", err)
+		}
+	}
+}
+
+// pull requests the logs between start and end and sends them to the
+// handler.
+func (t *Target) pull(start, end time.Time) error {
+	query := url.Values{
+		"start":      {start.UTC().Format(time.RFC3339)},
+		"end":        {end.UTC().Format(time.RFC3339)},
+		"fields":     {strings.Join(t.cfg.Fields(), ",")},
+		"timestamps": {"rfc3339"},
+	}
+	u := fmt.Sprintf("%s/zones/%s/logs/received?%s", t.baseURL, url.PathEscape(t.cfg.ZoneID), query.Encode())
+
+	req, err := http.NewRequestWithContext(t.ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+string(t.cfg.APIToken))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		entry := api.Entry{
+			Labels: t.cfg.Labels.Clone(),
+			Entry:  logproto.Entry{Timestamp: lineTimestamp(line), Line: line},
+		}
+		select {
+		case t.handler.Chan() <- entry:
+		case <-t.ctx.Done():
+			return t.ctx.Err()
+		}
+		t.metrics.entries.WithLabelValues(t.cfg.JobName).Inc()
+	}
+	return scanner.Err()
+}
+
+// lineTimestamp returns the EdgeStartTimestamp of a log line, or the current
+// time if the line doesn't have one.
+func lineTimestamp(line string) time.Time {
+	var fields struct {
+		EdgeStartTimestamp time.Time `json:"EdgeStartTimestamp"`
+	}
+	if err := json.Unmarshal([]byte(line), &fields); err != nil || fields.EdgeStartTimestamp.IsZero() {
+		return time.Now()
+	}
+	return fields.EdgeStartTimestamp
+}
+
+// position is the content of the positions file of a Target.
+type position struct {
+	End time.Time `json:"end"`
+}
+
+// readPosition returns the end of the last pulled time range, or the zero
+// time if nothing was pulled yet.
+func (t *Target) readPosition() (time.Time, error) {
+	buf, err := ioutil.ReadFile(t.positionsPath)
+	if os.IsNotExist(err) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+
+	var p position
+	if err := json.Unmarshal(buf, &p); err != nil {
+		return time.Time{}, err
+	}
+	return p.End, nil
+}
+
+func (t *Target) writePosition(end time.Time) error {
+	buf, err := json.Marshal(position{End: end})
+	if err != nil {
+		return err
+	}
+
+	temp := t.positionsPath + "-new"
+	if err := ioutil.WriteFile(temp, buf, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, t.positionsPath)
+}
+
+// Stop stops pulling and stops the handler of the Target.
+func (t *Target) Stop() {
+	t.cancel()
+	t.wg.Wait()
+	t.handler.Stop()
+}
@@ -1,10 +1,23 @@
 package logs
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"path/filepath"
 
+	"github.com/grafana/agent/pkg/logs/azureeventhubs"
+	"github.com/grafana/agent/pkg/logs/cloudflare"
+	"github.com/grafana/agent/pkg/logs/docker"
+	"github.com/grafana/agent/pkg/logs/fluentforward"
+	"github.com/grafana/agent/pkg/logs/gelf"
+	"github.com/grafana/agent/pkg/logs/heroku"
+	"github.com/grafana/agent/pkg/logs/kafka"
+	"github.com/grafana/agent/pkg/logs/limit"
+	"github.com/grafana/agent/pkg/logs/output"
+	"github.com/grafana/agent/pkg/logs/stages"
+	"github.com/grafana/agent/pkg/logs/syslog"
+	"github.com/grafana/agent/pkg/logs/wal"
 	"github.com/grafana/loki/clients/pkg/promtail/client"
 	"github.com/grafana/loki/clients/pkg/promtail/positions"
 	"github.com/grafana/loki/clients/pkg/promtail/scrapeconfig"
@@ -37,15 +50,21 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 //   3. No InstanceConfig may have an empty name.
 //   4. If InstanceConfig positions path is empty, shared PositionsDirectory
 //      must not be empty.
+//   5. No two jobs of an InstanceConfig which aren't scrape_configs may have
+//      the same job name.
+//   6. No two InstanceConfigs may have the same WAL directory.
 //
 // Defaults:
 //
 //   1. If a positions config is empty, it will be generated based on
 //      the InstanceConfig name and Config.PositionsDirectory.
+//   2. If the WAL directory of an InstanceConfig with the WAL enabled is
+//      empty, it will be generated next to the positions file.
 func (c *Config) ApplyDefaults() error {
 	var (
 		names     = map[string]struct{}{}
 		positions = map[string]string{} // positions file name -> config using it
+		wals      = map[string]string{} // wal directory -> config using it
 	)
 
 	for idx, ic := range c.Configs {
@@ -67,6 +86,24 @@ func (c *Config) ApplyDefaults() error {
 			return fmt.Errorf("Loki configs %s and %s must have different positions file paths", orig, ic.Name)
 		}
 		positions[ic.PositionsConfig.PositionsFile] = ic.Name
+
+		if ic.WAL.Enabled {
+			if ic.WAL.Dir == "" {
+				ic.WAL.Dir = filepath.Join(filepath.Dir(ic.PositionsConfig.PositionsFile), ic.Name+"-wal")
+			}
+			if orig, ok := wals[ic.WAL.Dir]; ok {
+				return fmt.Errorf("Loki configs %s and %s must have different wal directories", orig, ic.Name)
+			}
+			wals[ic.WAL.Dir] = ic.Name
+		}
+
+		jobs := map[string]struct{}{}
+		for _, name := range ic.jobNames() {
+			if _, ok := jobs[name]; ok {
+				return fmt.Errorf("Loki config %s has multiple jobs named %s", ic.Name, name)
+			}
+			jobs[name] = struct{}{}
+		}
 	}
 
 	return nil
@@ -80,6 +117,134 @@ type InstanceConfig struct {
 	PositionsConfig positions.Config      `yaml:"positions,omitempty"`
 	ScrapeConfig    []scrapeconfig.Config `yaml:"scrape_configs,omitempty"`
 	TargetConfig    file.Config           `yaml:"target_config,omitempty"`
+
+	// KafkaConfigs consume log lines from Kafka topics. They're separate
+	// from ScrapeConfig since Promtail doesn't support Kafka.
+	KafkaConfigs []kafka.Config `yaml:"kafka_configs,omitempty"`
+
+	// AzureEventHubsConfigs consume logs from Azure Event Hubs through their
+	// Kafka endpoint.
+	AzureEventHubsConfigs []azureeventhubs.Config `yaml:"azure_event_hubs_configs,omitempty"`
+
+	// CloudflareConfigs pull HTTP request logs from the Cloudflare Logpull
+	// API.
+	CloudflareConfigs []cloudflare.Config `yaml:"cloudflare_configs,omitempty"`
+
+	// HerokuDrainConfigs receive logs from Heroku HTTPS drains.
+	HerokuDrainConfigs []heroku.Config `yaml:"heroku_drain_configs,omitempty"`
+
+	// FluentForwardConfigs receive logs from Fluentd and Fluent Bit over the
+	// forward protocol.
+	FluentForwardConfigs []fluentforward.Config `yaml:"fluent_forward_configs,omitempty"`
+
+	// GELFConfigs receive logs in the Graylog Extended Log Format.
+	GELFConfigs []gelf.Config `yaml:"gelf_configs,omitempty"`
+
+	// DockerConfigs read the logs of containers discovered on Docker hosts.
+	DockerConfigs []docker.Config `yaml:"docker_configs,omitempty"`
+
+	// SyslogConfigs receive RFC5424 and RFC3164 syslog messages. Unlike the
+	// syslog target of ScrapeConfig, they accept RFC3164 messages and UDP.
+	SyslogConfigs []syslog.Config `yaml:"syslog_configs,omitempty"`
+
+	// Outputs receive log entries in addition to the clients.
+	Outputs output.Config `yaml:"outputs,omitempty"`
+
+	// WAL buffers entries on disk until they're sent to the clients.
+	WAL wal.Config `yaml:"wal,omitempty"`
+
+	// Limits drop entries over rate limits before they're sent to the
+	// outputs and the clients.
+	Limits limit.Config `yaml:"limits,omitempty"`
+}
+
+// jobNames returns the job names of the log sources of c which aren't
+// handled by Promtail.
+func (c *InstanceConfig) jobNames() []string {
+	var names []string
+	for _, kc := range c.kafkaConfigs() {
+		names = append(names, kc.JobName)
+	}
+	for _, cc := range c.CloudflareConfigs {
+		names = append(names, cc.JobName)
+	}
+	for _, hc := range c.HerokuDrainConfigs {
+		names = append(names, hc.JobName)
+	}
+	for _, fc := range c.FluentForwardConfigs {
+		names = append(names, fc.JobName)
+	}
+	for _, gc := range c.GELFConfigs {
+		names = append(names, gc.JobName)
+	}
+	for _, dc := range c.DockerConfigs {
+		names = append(names, dc.JobName)
+	}
+	for _, sc := range c.SyslogConfigs {
+		names = append(names, sc.JobName)
+	}
+	return names
+}
+
+// ErrJobNotFound is returned by InstanceConfig.PipelineStages when there's no
+// job with the given name.
+var ErrJobNotFound = errors.New("job not found")
+
+// PipelineStages returns the pipeline stages of the job of c called jobName.
+func (c *InstanceConfig) PipelineStages(jobName string) (stages.PipelineStages, error) {
+	for _, sc := range c.ScrapeConfig {
+		if sc.JobName == jobName {
+			return sc.PipelineStages, nil
+		}
+	}
+
+	for _, kc := range c.kafkaConfigs() {
+		if kc.JobName == jobName {
+			return kc.PipelineStages, nil
+		}
+	}
+	for _, cc := range c.CloudflareConfigs {
+		if cc.JobName == jobName {
+			return cc.PipelineStages, nil
+		}
+	}
+	for _, hc := range c.HerokuDrainConfigs {
+		if hc.JobName == jobName {
+			return hc.PipelineStages, nil
+		}
+	}
+	for _, fc := range c.FluentForwardConfigs {
+		if fc.JobName == jobName {
+			return fc.PipelineStages, nil
+		}
+	}
+	for _, gc := range c.GELFConfigs {
+		if gc.JobName == jobName {
+			return gc.PipelineStages, nil
+		}
+	}
+	for _, dc := range c.DockerConfigs {
+		if dc.JobName == jobName {
+			return dc.PipelineStages, nil
+		}
+	}
+	for _, sc := range c.SyslogConfigs {
+		if sc.JobName == jobName {
+			return sc.PipelineStages, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrJobNotFound, jobName)
+}
+
+// kafkaConfigs returns all Kafka jobs of c, including the ones which consume
+// from Azure Event Hubs.
+func (c *InstanceConfig) kafkaConfigs() []kafka.Config {
+	cfgs := make([]kafka.Config, 0, len(c.KafkaConfigs)+len(c.AzureEventHubsConfigs))
+	cfgs = append(cfgs, c.KafkaConfigs...)
+	for i := range c.AzureEventHubsConfigs {
+		cfgs = append(cfgs, c.AzureEventHubsConfigs[i].KafkaConfig())
+	}
+	return cfgs
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
@@ -88,6 +88,89 @@ func TestConfig_ApplyDefaults_Validations(t *testing.T) {
 				- name: config-b
 		  `),
 		},
+		{
+			name: "kafka configs with same job name",
+			err:  fmt.Errorf("Loki config config-a has multiple jobs named kafka"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  kafka_configs:
+				  - job_name: kafka
+				    brokers: [localhost:9092]
+				    topics: [logs]
+				  - job_name: kafka
+				    brokers: [localhost:9092]
+				    topics: [other-logs]
+		  `),
+		},
+		{
+			name: "kafka and cloudflare configs with same job name",
+			err:  fmt.Errorf("Loki config config-a has multiple jobs named logs"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  kafka_configs:
+				  - job_name: logs
+				    brokers: [localhost:9092]
+				    topics: [logs]
+				  cloudflare_configs:
+				  - job_name: logs
+				    api_token: secret
+				    zone_id: zone
+		  `),
+		},
+		{
+			name: "cloudflare and heroku drain configs with same job name",
+			err:  fmt.Errorf("Loki config config-a has multiple jobs named logs"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  cloudflare_configs:
+				  - job_name: logs
+				    api_token: secret
+				    zone_id: zone
+				  heroku_drain_configs:
+				  - job_name: logs
+				    listen_address: 0.0.0.0:8080
+		  `),
+		},
+		{
+			name: "kafka and azure event hubs configs with same job name",
+			err:  fmt.Errorf("Loki config config-a has multiple jobs named logs"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  kafka_configs:
+				  - job_name: logs
+				    brokers: [localhost:9092]
+				    topics: [logs]
+				  azure_event_hubs_configs:
+				  - job_name: logs
+				    fully_qualified_namespace: my-namespace.servicebus.windows.net
+				    event_hubs: [insights-logs]
+				    connection_string: secret
+		  `),
+		},
+		{
+			name: "re-used wal directory",
+			err:  fmt.Errorf("Loki configs config-a and config-b must have different wal directories"),
+			cfg: untab(`
+				positions_directory: /tmp
+				configs:
+				- name: config-a
+				  wal:
+				    enabled: true
+				    dir: /tmp/wal
+				- name: config-b
+				  wal:
+				    enabled: true
+				    dir: /tmp/wal
+		  `),
+		},
 	}
 
 	for _, tc := range tt {
@@ -111,6 +194,8 @@ func TestConfig_ApplyDefaults_Defaults(t *testing.T) {
 			positions:
 				filename: /config-a.yml
 		- name: config-b
+			wal:
+				enabled: true
 	`)
 	var cfg Config
 	err := yaml.UnmarshalStrict([]byte(cfgText), &cfg)
@@ -123,6 +208,81 @@ func TestConfig_ApplyDefaults_Defaults(t *testing.T) {
 
 	require.Equal(t, "/config-a.yml", pathA)
 	require.Equal(t, filepath.Join("/tmp", "config-b.yml"), pathB)
+
+	require.False(t, cfg.Configs[0].WAL.Enabled)
+	require.Equal(t, filepath.Join("/tmp", "config-b-wal"), cfg.Configs[1].WAL.Dir)
+	require.Equal(t, 1024, cfg.Configs[1].WAL.MaxSizeMB)
+}
+
+func TestConfig_GcplogScrapeConfig(t *testing.T) {
+	cfgText := untab(`
+		positions_directory: /tmp
+		configs:
+		- name: gcp
+			scrape_configs:
+			- job_name: gcplog
+				gcplog:
+					project_id: my-project
+					subscription: agent-logs
+					labels:
+						job: gcplog
+				relabel_configs:
+				- source_labels: [__project_id]
+					target_label: project
+	`)
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(cfgText), &cfg)
+	require.NoError(t, err)
+
+	sc := cfg.Configs[0].ScrapeConfig[0]
+	require.NotNil(t, sc.GcplogConfig)
+	require.Equal(t, "my-project", sc.GcplogConfig.ProjectID)
+	require.Equal(t, "agent-logs", sc.GcplogConfig.Subscription)
+	require.Len(t, sc.RelabelConfigs, 1)
+}
+
+func TestInstanceConfig_PipelineStages(t *testing.T) {
+	cfgText := untab(`
+		positions_directory: /tmp
+		configs:
+		- name: default
+			scrape_configs:
+			- job_name: varlogs
+				static_configs:
+				- targets: [localhost]
+				pipeline_stages:
+				- regex:
+						expression: "^(?P<level>\\w+)"
+			- job_name: colored
+				static_configs:
+				- targets: [localhost]
+				pipeline_stages:
+				- decolorize:
+			gelf_configs:
+			- job_name: gelf
+				listen_address: 127.0.0.1:12201
+				pipeline_stages:
+				- decolorize:
+	`)
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(cfgText), &cfg)
+	require.NoError(t, err)
+	ic := cfg.Configs[0]
+
+	stgs, err := ic.PipelineStages("varlogs")
+	require.NoError(t, err)
+	require.Len(t, stgs, 1)
+
+	stgs, err = ic.PipelineStages("colored")
+	require.NoError(t, err)
+	require.Len(t, stgs, 1)
+
+	stgs, err = ic.PipelineStages("gelf")
+	require.NoError(t, err)
+	require.Len(t, stgs, 1)
+
+	_, err = ic.PipelineStages("missing")
+	require.ErrorIs(t, err, ErrJobNotFound)
 }
 
 // untab is a utility function to make it easier to write YAML tests, where some editors
@@ -0,0 +1,58 @@
+package docker
+
+import (
+	"fmt"
+
+	"github.com/grafana/agent/pkg/logs/stages"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/moby"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// Config configures a job which tails the logs of containers discovered on
+// Docker hosts.
+type Config struct {
+	// JobName identifies the job in metrics and pipeline stages. Required.
+	JobName string `yaml:"job_name"`
+
+	// DockerSDConfigs discover the containers to tail. At least one is
+	// required.
+	DockerSDConfigs []*moby.DockerSDConfig `yaml:"docker_sd_configs"`
+
+	// Labels to add to every log line.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+
+	// RelabelConfigs are applied to the __meta_docker_* labels of each
+	// container and stream.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
+
+	// PipelineStages process log lines before they're sent to Loki.
+	PipelineStages stages.PipelineStages `yaml:"pipeline_stages,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = Config{}
+
+	type config Config
+	if err := unmarshal((*config)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate returns an error if c is not valid.
+func (c *Config) Validate() error {
+	switch {
+	case c.JobName == "":
+		return fmt.Errorf("docker config must have a job_name")
+	case len(c.DockerSDConfigs) == 0:
+		return fmt.Errorf("docker config %s must have at least one docker_sd_config", c.JobName)
+	}
+	for _, sd := range c.DockerSDConfigs {
+		if sd == nil {
+			return fmt.Errorf("docker config %s has an empty docker_sd_config", c.JobName)
+		}
+	}
+	return nil
+}
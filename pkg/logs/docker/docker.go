@@ -0,0 +1,62 @@
+// Package docker implements a log source which discovers containers on
+// Docker hosts and reads their logs through the Docker API.
+package docker
+
+import (
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/agent/pkg/logs/stages"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TargetManager runs a Target for each Docker config.
+type TargetManager struct {
+	log     log.Logger
+	targets map[string]*Target
+}
+
+// NewTargetManager creates a TargetManager which starts discovering
+// containers for every config in cfgs. Entries are processed by the pipeline
+// stages of their config and then sent to client.
+func NewTargetManager(reg prometheus.Registerer, l log.Logger, client api.EntryHandler, cfgs []Config) (*TargetManager, error) {
+	tm := &TargetManager{
+		log:     l,
+		targets: make(map[string]*Target, len(cfgs)),
+	}
+	if len(cfgs) == 0 {
+		return tm, nil
+	}
+
+	metrics := NewMetrics(reg)
+	for i := range cfgs {
+		cfg := &cfgs[i]
+		jobName := cfg.JobName
+		pipeline, err := stages.NewPipeline(log.With(l, "component", "docker_pipeline"), cfg.PipelineStages, &jobName, reg)
+		if err != nil {
+			tm.Stop()
+			return nil, fmt.Errorf("failed to create pipeline for docker config %s: %w", cfg.JobName, err)
+		}
+
+		handler := pipeline.Wrap(client)
+		t, err := NewTarget(l, metrics, handler, cfg)
+		if err != nil {
+			handler.Stop()
+			tm.Stop()
+			return nil, fmt.Errorf("failed to create docker target %s: %w", cfg.JobName, err)
+		}
+		tm.targets[cfg.JobName] = t
+	}
+	return tm, nil
+}
+
+// Stop stops all targets.
+func (tm *TargetManager) Stop() {
+	for name, t := range tm.targets {
+		if err := t.Stop(); err != nil {
+			level.Error(tm.log).Log("msg", "failed to stop docker target", "job", name, "err", err)
+		}
+	}
+}
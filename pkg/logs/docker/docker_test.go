@@ -0,0 +1,147 @@
+package docker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/moby"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+job_name: docker
+docker_sd_configs:
+  - host: unix:///var/run/docker.sock
+`), &cfg)
+	require.NoError(t, err)
+	require.Len(t, cfg.DockerSDConfigs, 1)
+	require.Equal(t, moby.DefaultDockerSDConfig.RefreshInterval, cfg.DockerSDConfigs[0].RefreshInterval)
+
+	err = yaml.UnmarshalStrict([]byte("job_name: docker"), &cfg)
+	require.EqualError(t, err, "docker config docker must have at least one docker_sd_config")
+}
+
+func TestParseLine(t *testing.T) {
+	ts, text := parseLine("2022-01-01T00:00:00.123456789Z hello world\n")
+	require.True(t, time.Date(2022, 1, 1, 0, 0, 0, 123456789, time.UTC).Equal(ts))
+	require.Equal(t, "hello world", text)
+
+	_, text = parseLine("no timestamp\r\n")
+	require.Equal(t, "no timestamp", text)
+}
+
+// apiVersionPath matches the version prefix of Docker API paths.
+var apiVersionPath = regexp.MustCompile(`^/v[0-9.]+`)
+
+// fakeDocker returns a Docker API server with a single running container
+// called web, which writes a line to stdout and one to stderr.
+func fakeDocker(t *testing.T) *httptest.Server {
+	writeJSON := func(w http.ResponseWriter, v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(v))
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch apiVersionPath.ReplaceAllString(r.URL.Path, "") {
+		case "/_ping":
+			w.Header().Set("API-Version", "1.41")
+			_, _ = w.Write([]byte("OK"))
+		case "/containers/json":
+			writeJSON(w, []interface{}{map[string]interface{}{
+				"Id":         "abc",
+				"Names":      []string{"/web"},
+				"Labels":     map[string]string{"com.example.team": "a"},
+				"HostConfig": map[string]string{"NetworkMode": "bridge"},
+				"NetworkSettings": map[string]interface{}{
+					"Networks": map[string]interface{}{
+						"bridge": map[string]string{"NetworkID": "net", "IPAddress": "172.17.0.2"},
+					},
+				},
+			}})
+		case "/networks":
+			writeJSON(w, []interface{}{})
+		case "/containers/abc/json":
+			writeJSON(w, map[string]interface{}{"Id": "abc", "Config": map[string]bool{"Tty": false}})
+		case "/containers/abc/logs":
+			w.Header().Set("Content-Type", "application/vnd.docker.raw-stream")
+			_, _ = stdcopy.NewStdWriter(w, stdcopy.Stdout).Write([]byte("2022-01-01T00:00:00Z hello\n"))
+			_, _ = stdcopy.NewStdWriter(w, stdcopy.Stderr).Write([]byte("2022-01-01T00:00:01Z oops\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestTarget(t *testing.T) {
+	srv := fakeDocker(t)
+	defer srv.Close()
+
+	sd := moby.DefaultDockerSDConfig
+	sd.Host = srv.URL
+	sd.RefreshInterval = model.Duration(time.Hour)
+
+	cfg := &Config{
+		JobName:         "docker",
+		DockerSDConfigs: []*moby.DockerSDConfig{&sd},
+		Labels:          model.LabelSet{"job": "docker"},
+		RelabelConfigs: []*relabel.Config{
+			{
+				SourceLabels: model.LabelNames{"__meta_docker_container_name"},
+				Regex:        relabel.MustNewRegexp("/(.*)"),
+				Replacement:  "$1",
+				TargetLabel:  "container",
+				Action:       relabel.Replace,
+			},
+			{
+				SourceLabels: model.LabelNames{"__meta_docker_container_label_com_example_team"},
+				Regex:        relabel.MustNewRegexp("(.*)"),
+				Replacement:  "$1",
+				TargetLabel:  "team",
+				Action:       relabel.Replace,
+			},
+			{
+				SourceLabels: model.LabelNames{logStreamLabel},
+				Regex:        relabel.MustNewRegexp("(.*)"),
+				Replacement:  "$1",
+				TargetLabel:  "stream",
+				Action:       relabel.Replace,
+			},
+		},
+	}
+
+	entries := make(chan api.Entry, 10)
+	target, err := NewTarget(log.NewNopLogger(), NewMetrics(nil), api.NewEntryHandler(entries, func() {}), cfg)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, target.Stop()) }()
+
+	var received []api.Entry
+	for len(received) < 2 {
+		select {
+		case e := <-entries:
+			received = append(received, e)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for entries, got %d", len(received))
+		}
+	}
+	sort.Slice(received, func(i, j int) bool { return received[i].Line < received[j].Line })
+
+	require.Equal(t, "hello", received[0].Line)
+	require.Equal(t, model.LabelSet{"job": "docker", "container": "web", "team": "a", "stream": "stdout"}, received[0].Labels)
+	require.True(t, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).Equal(received[0].Timestamp))
+
+	require.Equal(t, "oops", received[1].Line)
+	require.Equal(t, model.LabelSet{"job": "docker", "container": "web", "team": "a", "stream": "stderr"}, received[1].Labels)
+}
@@ -0,0 +1,38 @@
+package docker
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics holds metrics for Docker targets.
+type Metrics struct {
+	entries    *prometheus.CounterVec
+	errors     *prometheus.CounterVec
+	containers *prometheus.GaugeVec
+}
+
+// NewMetrics creates a new set of metrics. Metrics will be registered to reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{}
+
+	m.entries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "docker_target_entries_total",
+		Help:      "Total number of log lines read from Docker containers.",
+	}, []string{"job"})
+
+	m.errors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "docker_target_errors_total",
+		Help:      "Total number of errors reading the logs of Docker containers.",
+	}, []string{"job"})
+
+	m.containers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "promtail",
+		Name:      "docker_target_containers",
+		Help:      "Number of Docker containers whose logs are being read.",
+	}, []string{"job"})
+
+	if reg != nil {
+		reg.MustRegister(m.entries, m.errors, m.containers)
+	}
+	return m
+}
@@ -0,0 +1,371 @@
+package docker
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/moby"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// Labels discovered for every container and stream which can be used in
+// relabel_configs, in addition to the __meta_docker_* labels of Docker
+// service discovery.
+const (
+	containerIDLabel = model.MetaLabelPrefix + "docker_container_id"
+	logStreamLabel   = model.MetaLabelPrefix + "docker_container_log_stream"
+)
+
+// Target discovers containers with the docker_sd_configs of its config and
+// reads their logs. Each line is sent as an entry with the timestamp Docker
+// recorded for it.
+//
+// Only lines written after the Target was created are read. Lines written
+// by a container while it's not discovered, like after it stopped and before
+// it's discovered again, are read once it's discovered.
+type Target struct {
+	log     log.Logger
+	metrics *Metrics
+	cfg     *Config
+	handler api.EntryHandler
+	start   time.Time
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mut        sync.Mutex
+	containers map[string]context.CancelFunc // Containers being read by key.
+	last       map[string]time.Time          // Timestamp of the last line read by key.
+}
+
+// NewTarget creates a new Target which discovers containers with the
+// docker_sd_configs of cfg. Entries are sent to handler, which is stopped when
+// the Target is stopped.
+func NewTarget(l log.Logger, m *Metrics, handler api.EntryHandler, cfg *Config) (*Target, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &Target{
+		log:        log.With(l, "job", cfg.JobName),
+		metrics:    m,
+		cfg:        cfg,
+		handler:    handler,
+		start:      time.Now(),
+		ctx:        ctx,
+		cancel:     cancel,
+		containers: map[string]context.CancelFunc{},
+		last:       map[string]time.Time{},
+	}
+
+	for i, sd := range cfg.DockerSDConfigs {
+		cli, err := newClient(sd)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		disc, err := moby.NewDockerDiscovery(sd, log.With(t.log, "discovery", "docker"))
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		ch := make(chan []*targetgroup.Group)
+		t.wg.Add(2)
+		go func() {
+			defer t.wg.Done()
+			disc.Run(ctx, ch)
+		}()
+		go func(i int) {
+			defer t.wg.Done()
+			t.watch(cli, i, ch)
+		}(i)
+	}
+	return t, nil
+}
+
+// newClient creates a client for the Docker host of cfg, with the same
+// options Docker service discovery uses.
+func newClient(cfg *moby.DockerSDConfig) (*client.Client, error) {
+	hostURL, err := url.Parse(cfg.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []client.Opt{
+		client.WithHost(cfg.Host),
+		client.WithAPIVersionNegotiation(),
+	}
+
+	// Only HTTP hosts use the HTTP client config, like in service discovery.
+	// No timeout is set, as reading logs is a long-running request.
+	if hostURL.Scheme == "http" || hostURL.Scheme == "https" {
+		rt, err := config.NewRoundTripperFromConfig(cfg.HTTPClientConfig, "docker_logs")
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts,
+			client.WithHTTPClient(&http.Client{Transport: rt}),
+			client.WithScheme(hostURL.Scheme),
+		)
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error setting up docker client: %w", err)
+	}
+	return cli, nil
+}
+
+// watch starts and stops reading containers as they're discovered by the
+// discovery of the i-th docker_sd_config.
+func (t *Target) watch(cli *client.Client, i int, ch <-chan []*targetgroup.Group) {
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case groups := <-ch:
+			t.sync(cli, i, discoveredContainers(groups))
+		}
+	}
+}
+
+// discoveredContainers returns the labels of each container in groups by
+// container ID. Docker service discovery returns a target per port and
+// network of a container; the labels of the first target are used.
+func discoveredContainers(groups []*targetgroup.Group) map[string]model.LabelSet {
+	res := map[string]model.LabelSet{}
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		for _, target := range group.Targets {
+			id := string(target[containerIDLabel])
+			if _, ok := res[id]; ok || id == "" {
+				continue
+			}
+			res[id] = group.Labels.Merge(target)
+		}
+	}
+	return res
+}
+
+func (t *Target) sync(cli *client.Client, i int, discovered map[string]model.LabelSet) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	prefix := fmt.Sprintf("%d/", i)
+	for key, cancel := range t.containers {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if _, ok := discovered[strings.TrimPrefix(key, prefix)]; !ok {
+			cancel()
+			delete(t.containers, key)
+		}
+	}
+
+	for id, lset := range discovered {
+		key := prefix + id
+		if _, ok := t.containers[key]; ok || t.ctx.Err() != nil {
+			continue
+		}
+
+		ctx, cancel := context.WithCancel(t.ctx)
+		t.containers[key] = cancel
+		t.wg.Add(1)
+		go func(id string, lset model.LabelSet) {
+			defer t.wg.Done()
+			defer cancel()
+			t.read(ctx, cli, key, id, lset)
+
+			// Containers are read again when they're discovered again, like
+			// after they're restarted.
+			t.mut.Lock()
+			defer t.mut.Unlock()
+			if _, ok := t.containers[key]; ok && ctx.Err() == nil {
+				delete(t.containers, key)
+			}
+			t.metrics.containers.WithLabelValues(t.cfg.JobName).Set(float64(len(t.containers)))
+		}(id, lset)
+	}
+	t.metrics.containers.WithLabelValues(t.cfg.JobName).Set(float64(len(t.containers)))
+}
+
+// read follows the logs of a container until they end or ctx is canceled.
+func (t *Target) read(ctx context.Context, cli *client.Client, key, id string, discovered model.LabelSet) {
+	l := log.With(t.log, "container", id)
+
+	t.mut.Lock()
+	since := t.start
+	if last, ok := t.last[key]; ok && last.After(since) {
+		// Docker includes lines at the since timestamp.
+		since = last.Add(time.Nanosecond)
+	}
+	t.mut.Unlock()
+
+	info, err := cli.ContainerInspect(ctx, id)
+	if err != nil {
+		t.readError(ctx, l, "failed to inspect container", err)
+		return
+	}
+
+	logs, err := cli.ContainerLogs(ctx, id, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+		Timestamps: true,
+		Since:      fmt.Sprintf("%d.%09d", since.Unix(), since.Nanosecond()),
+	})
+	if err != nil {
+		t.readError(ctx, l, "failed to read container logs", err)
+		return
+	}
+	defer logs.Close()
+
+	// Containers with a TTY write a single stream. The streams of other
+	// containers are multiplexed.
+	if info.Config != nil && info.Config.Tty {
+		t.readStream(ctx, key, discovered, "stdout", logs)
+		return
+	}
+
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(stdoutWriter, stderrWriter, logs)
+		stdoutWriter.CloseWithError(err)
+		stderrWriter.CloseWithError(err)
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		t.readStream(ctx, key, discovered, "stdout", stdoutReader)
+	}()
+	go func() {
+		defer wg.Done()
+		t.readStream(ctx, key, discovered, "stderr", stderrReader)
+	}()
+	wg.Wait()
+}
+
+func (t *Target) readError(ctx context.Context, l log.Logger, msg string, err error) {
+	if ctx.Err() != nil {
+		return
+	}
+	level.Error(l).Log("msg", msg, "err", err)
+	t.metrics.errors.WithLabelValues(t.cfg.JobName).Inc()
+}
+
+// readStream sends each line of r as an entry. Lines of streams dropped by
+// relabeling are read and discarded.
+func (t *Target) readStream(ctx context.Context, key string, discovered model.LabelSet, stream string, r io.ReadCloser) {
+	defer r.Close()
+
+	lset := t.labels(discovered, stream)
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" && lset != nil {
+			ts, text := parseLine(line)
+			entry := api.Entry{
+				Labels: lset.Clone(),
+				Entry:  logproto.Entry{Timestamp: ts, Line: text},
+			}
+			select {
+			case t.handler.Chan() <- entry:
+			case <-ctx.Done():
+				return
+			}
+			t.metrics.entries.WithLabelValues(t.cfg.JobName).Inc()
+
+			t.mut.Lock()
+			if ts.After(t.last[key]) {
+				t.last[key] = ts
+			}
+			t.mut.Unlock()
+		}
+
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				t.readError(ctx, log.With(t.log, "stream", stream), "failed to read container logs", err)
+			}
+			return
+		}
+	}
+}
+
+// parseLine splits a line with a timestamp written by Docker into the
+// timestamp and the text of the line. Lines without a valid timestamp are
+// read at the current time.
+func parseLine(line string) (time.Time, string) {
+	line = strings.TrimRight(line, "\r\n")
+	idx := strings.IndexByte(line, ' ')
+	if idx < 0 {
+		return time.Now(), line
+	}
+	ts, err := time.Parse(time.RFC3339Nano, line[:idx])
+	if err != nil {
+		return time.Now(), line
+	}
+	return ts, line[idx+1:]
+}
+
+// labels returns the labels of the entries of stream after relabeling.
+// Returns nil if the stream was dropped by relabeling.
+func (t *Target) labels(discovered model.LabelSet, stream string) model.LabelSet {
+	lb := labels.NewBuilder(nil)
+	for name, value := range discovered {
+		lb.Set(string(name), string(value))
+	}
+	lb.Set(logStreamLabel, stream)
+
+	processed := lb.Labels()
+	if len(t.cfg.RelabelConfigs) > 0 {
+		processed = relabel.Process(processed, t.cfg.RelabelConfigs...)
+		if processed == nil {
+			return nil
+		}
+	}
+
+	lset := make(model.LabelSet, len(t.cfg.Labels)+len(processed))
+	for _, l := range processed {
+		if strings.HasPrefix(l.Name, model.ReservedLabelPrefix) {
+			continue
+		}
+		lset[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+	for k, v := range t.cfg.Labels {
+		lset[k] = v
+	}
+	return lset
+}
+
+// Stop stops discovering and reading containers and stops the handler of
+// the Target.
+func (t *Target) Stop() error {
+	t.cancel()
+	t.wg.Wait()
+	t.metrics.containers.WithLabelValues(t.cfg.JobName).Set(0)
+	t.handler.Stop()
+	return nil
+}
@@ -0,0 +1,61 @@
+package fluentforward
+
+import (
+	"fmt"
+
+	"github.com/grafana/agent/pkg/logs/stages"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// Config configures a job which receives logs from Fluentd and Fluent Bit
+// over the forward protocol.
+type Config struct {
+	// JobName identifies the job in metrics and pipeline stages. Required.
+	JobName string `yaml:"job_name"`
+
+	// ListenAddress is the host:port address to listen for forward
+	// connections on. Required.
+	ListenAddress string `yaml:"listen_address"`
+
+	// MessageField is the field of the records holding the log line. If empty,
+	// or the field is missing or isn't a string, the whole record is used as
+	// a JSON object.
+	MessageField string `yaml:"message_field,omitempty"`
+
+	// Labels to add to every log line.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+
+	// UseIncomingTimestamp uses the time of the events instead of the time
+	// they were received.
+	UseIncomingTimestamp bool `yaml:"use_incoming_timestamp,omitempty"`
+
+	// RelabelConfigs are applied to the __fluent_forward_* labels of each
+	// event.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
+
+	// PipelineStages process log lines before they're sent to Loki.
+	PipelineStages stages.PipelineStages `yaml:"pipeline_stages,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = Config{}
+
+	type config Config
+	if err := unmarshal((*config)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate returns an error if c is not valid.
+func (c *Config) Validate() error {
+	switch {
+	case c.JobName == "":
+		return fmt.Errorf("fluent_forward config must have a job_name")
+	case c.ListenAddress == "":
+		return fmt.Errorf("fluent_forward config %s must have a listen_address", c.JobName)
+	}
+	return nil
+}
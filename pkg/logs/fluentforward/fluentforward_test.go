@@ -0,0 +1,160 @@
+package fluentforward
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/hashicorp/go-msgpack/codec"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/require"
+)
+
+// clientHandle encodes messages the way Fluent Bit does, using the str and
+// bin types of the current msgpack spec.
+var clientHandle = &codec.MsgpackHandle{WriteExt: true}
+
+func encode(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, codec.NewEncoder(&buf, clientHandle).Encode(v))
+	return buf.Bytes()
+}
+
+func TestParseMessage(t *testing.T) {
+	ts := time.Unix(1640995200, 0)
+	record := map[string]interface{}{"log": "hello"}
+
+	decode := func(raw []byte) []interface{} {
+		var msg []interface{}
+		require.NoError(t, codec.NewDecoderBytes(raw, msgpackHandle).Decode(&msg))
+		return msg
+	}
+
+	var packed []byte
+	packed = append(packed, encode(t, []interface{}{ts.Unix(), record})...)
+	packed = append(packed, encode(t, []interface{}{ts.Unix() + 1, record})...)
+
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	_, err := gw.Write(packed)
+	require.NoError(t, err)
+	require.NoError(t, gw.Close())
+
+	tt := []struct {
+		name   string
+		msg    []interface{}
+		events int
+		chunk  interface{}
+	}{
+		{
+			name:   "message",
+			msg:    []interface{}{"app", ts.Unix(), record},
+			events: 1,
+		},
+		{
+			name: "forward",
+			msg: []interface{}{"app", []interface{}{
+				[]interface{}{ts.Unix(), record},
+				[]interface{}{ts.Unix() + 1, record},
+			}, map[string]interface{}{"chunk": "abc"}},
+			events: 2,
+			chunk:  "abc",
+		},
+		{
+			name:   "packed forward",
+			msg:    []interface{}{"app", packed},
+			events: 2,
+		},
+		{
+			name:   "compressed packed forward",
+			msg:    []interface{}{"app", compressed.Bytes(), map[string]interface{}{"compressed": "gzip"}},
+			events: 2,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tag, events, opts, err := parseMessage(decode(encode(t, tc.msg)))
+			require.NoError(t, err)
+			require.Equal(t, "app", tag)
+			require.Len(t, events, tc.events)
+			require.True(t, ts.Equal(events[0].Time))
+			require.Equal(t, record, events[0].Record)
+			require.Equal(t, tc.chunk, opts["chunk"])
+		})
+	}
+
+	_, _, _, err = parseMessage(decode(encode(t, []interface{}{"app", "not msgpack"})))
+	require.Error(t, err)
+}
+
+func TestParseTime(t *testing.T) {
+	ts, err := parseTime(codec.RawExt{
+		Tag:  eventTimeExt,
+		Data: []byte{0x61, 0xcf, 0x99, 0x80, 0x00, 0x00, 0x01, 0xf4},
+	})
+	require.NoError(t, err)
+	require.True(t, time.Unix(1640995200, 500).Equal(ts))
+
+	ts, err = parseTime(float64(1640995200.5))
+	require.NoError(t, err)
+	require.True(t, time.Unix(1640995200, 5e8).Equal(ts))
+
+	_, err = parseTime("yesterday")
+	require.Error(t, err)
+}
+
+func TestTarget(t *testing.T) {
+	cfg := &Config{
+		JobName:              "fluent",
+		ListenAddress:        "127.0.0.1:0",
+		MessageField:         "log",
+		Labels:               model.LabelSet{"job": "fluent"},
+		UseIncomingTimestamp: true,
+		RelabelConfigs: []*relabel.Config{
+			{
+				SourceLabels: model.LabelNames{tagLabel},
+				Regex:        relabel.MustNewRegexp("(.*)"),
+				Replacement:  "$1",
+				TargetLabel:  "tag",
+				Action:       relabel.Replace,
+			},
+		},
+	}
+
+	entries := make(chan api.Entry, 10)
+	target, err := NewTarget(log.NewNopLogger(), NewMetrics(nil), api.NewEntryHandler(entries, func() {}), cfg)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, target.Stop()) }()
+
+	conn, err := net.Dial("tcp", target.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ts := time.Unix(1640995200, 0)
+	_, err = conn.Write(encode(t, []interface{}{"app.web", []interface{}{
+		[]interface{}{ts.Unix(), map[string]interface{}{"log": "hello"}},
+		[]interface{}{ts.Unix(), map[string]interface{}{"level": "info"}},
+	}, map[string]interface{}{"chunk": "chunk-1"}}))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var ack map[string]interface{}
+	require.NoError(t, codec.NewDecoder(conn, msgpackHandle).Decode(&ack))
+	require.Equal(t, "chunk-1", ack["ack"])
+
+	require.Len(t, entries, 2)
+	e := <-entries
+	require.Equal(t, model.LabelSet{"job": "fluent", "tag": "app.web"}, e.Labels)
+	require.Equal(t, "hello", e.Line)
+	require.True(t, ts.Equal(e.Timestamp))
+
+	e = <-entries
+	require.Equal(t, `{"level":"info"}`, e.Line)
+}
@@ -0,0 +1,38 @@
+package fluentforward
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics holds metrics for fluent forward targets.
+type Metrics struct {
+	entries     *prometheus.CounterVec
+	parseErrors *prometheus.CounterVec
+	connections *prometheus.GaugeVec
+}
+
+// NewMetrics creates a new set of metrics. Metrics will be registered to reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{}
+
+	m.entries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "fluent_forward_target_entries_total",
+		Help:      "Total number of log lines received over the fluent forward protocol.",
+	}, []string{"job"})
+
+	m.parseErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "fluent_forward_target_parsing_errors_total",
+		Help:      "Total number of fluent forward connections closed because of invalid messages.",
+	}, []string{"job"})
+
+	m.connections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "promtail",
+		Name:      "fluent_forward_target_connections",
+		Help:      "Number of open fluent forward connections.",
+	}, []string{"job"})
+
+	if reg != nil {
+		reg.MustRegister(m.entries, m.parseErrors, m.connections)
+	}
+	return m
+}
@@ -0,0 +1,475 @@
+package fluentforward
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/hashicorp/go-msgpack/codec"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// tagLabel is the label holding the tag of an event which can be used in
+// relabel_configs.
+const tagLabel = model.ReservedLabelPrefix + "fluent_forward_tag"
+
+// eventTimeExt is the msgpack extension type of the EventTime format, which
+// encodes seconds and nanoseconds as big endian 32-bit integers.
+const eventTimeExt = 0
+
+// msgpackHandle decodes strings as Go strings and maps as
+// map[string]interface{}, which is the shape of fluent records.
+var msgpackHandle = func() *codec.MsgpackHandle {
+	h := &codec.MsgpackHandle{RawToString: true}
+	h.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	return h
+}()
+
+// Target receives logs over the forward protocol of Fluentd and Fluent Bit.
+// The Message, Forward, PackedForward, and CompressedPackedForward modes are
+// supported. Chunks are acknowledged once their events have been sent to the
+// handler.
+type Target struct {
+	log     log.Logger
+	metrics *Metrics
+	cfg     *Config
+	handler api.EntryHandler
+	lis     net.Listener
+
+	wg    sync.WaitGroup
+	quit  chan struct{}
+	mut   sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// NewTarget creates a new Target which listens on the address of cfg.
+// Entries are sent to handler, which is stopped when the Target is stopped.
+func NewTarget(l log.Logger, m *Metrics, handler api.EntryHandler, cfg *Config) (*Target, error) {
+	lis, err := net.Listen("tcp", cfg.ListenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", cfg.ListenAddress, err)
+	}
+
+	t := &Target{
+		log:     log.With(l, "job", cfg.JobName),
+		metrics: m,
+		cfg:     cfg,
+		handler: handler,
+		lis:     lis,
+		quit:    make(chan struct{}),
+		conns:   make(map[net.Conn]struct{}),
+	}
+
+	t.wg.Add(1)
+	go t.run()
+	return t, nil
+}
+
+// Addr returns the address the Target listens on.
+func (t *Target) Addr() net.Addr { return t.lis.Addr() }
+
+func (t *Target) run() {
+	defer t.wg.Done()
+
+	for {
+		conn, err := t.lis.Accept()
+		if err != nil {
+			select {
+			case <-t.quit:
+				return
+			default:
+			}
+			level.Warn(t.log).Log("msg", "failed to accept connection", "err", err)
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			time.Sleep(time.Second)
+			continue
+		}
+
+		if !t.track(conn) {
+			conn.Close()
+			return
+		}
+		t.wg.Add(1)
+		go t.handleConn(conn)
+	}
+}
+
+// track records conn as open so it can be closed when the Target is stopped.
+// Returns false if the Target is already stopped.
+func (t *Target) track(conn net.Conn) bool {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	select {
+	case <-t.quit:
+		return false
+	default:
+	}
+	t.conns[conn] = struct{}{}
+	t.metrics.connections.WithLabelValues(t.cfg.JobName).Inc()
+	return true
+}
+
+func (t *Target) untrack(conn net.Conn) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	if _, ok := t.conns[conn]; ok {
+		delete(t.conns, conn)
+		t.metrics.connections.WithLabelValues(t.cfg.JobName).Dec()
+	}
+}
+
+func (t *Target) handleConn(conn net.Conn) {
+	defer t.wg.Done()
+	defer conn.Close()
+	defer t.untrack(conn)
+
+	l := log.With(t.log, "remote_addr", conn.RemoteAddr())
+	dec := codec.NewDecoder(bufio.NewReader(conn), msgpackHandle)
+	enc := codec.NewEncoder(conn, msgpackHandle)
+
+	for {
+		var msg []interface{}
+		if err := dec.Decode(&msg); err != nil {
+			select {
+			case <-t.quit:
+				return
+			default:
+			}
+			if !errors.Is(err, io.EOF) {
+				level.Warn(l).Log("msg", "failed to read message", "err", err)
+				t.metrics.parseErrors.WithLabelValues(t.cfg.JobName).Inc()
+			}
+			return
+		}
+
+		tag, events, opts, err := parseMessage(msg)
+		if err != nil {
+			level.Warn(l).Log("msg", "invalid message", "err", err)
+			t.metrics.parseErrors.WithLabelValues(t.cfg.JobName).Inc()
+			return
+		}
+
+		for _, ev := range events {
+			if !t.send(tag, ev) {
+				return
+			}
+		}
+
+		if chunk, ok := opts["chunk"]; ok {
+			if err := enc.Encode(map[string]interface{}{"ack": chunk}); err != nil {
+				level.Warn(l).Log("msg", "failed to acknowledge chunk", "err", err)
+				return
+			}
+		}
+	}
+}
+
+// send sends ev to the handler. Returns false if the Target was stopped
+// before the event could be sent.
+func (t *Target) send(tag string, ev event) bool {
+	lset := t.labels(tag)
+	if lset == nil {
+		return true
+	}
+
+	ts := time.Now()
+	if t.cfg.UseIncomingTimestamp && !ev.Time.IsZero() {
+		ts = ev.Time
+	}
+
+	line, err := t.line(ev.Record)
+	if err != nil {
+		level.Warn(t.log).Log("msg", "failed to encode record", "tag", tag, "err", err)
+		return true
+	}
+
+	entry := api.Entry{
+		Labels: lset,
+		Entry:  logproto.Entry{Timestamp: ts, Line: line},
+	}
+	select {
+	case t.handler.Chan() <- entry:
+		t.metrics.entries.WithLabelValues(t.cfg.JobName).Inc()
+		return true
+	case <-t.quit:
+		return false
+	}
+}
+
+// labels returns the labels of an event with the given tag after relabeling.
+// Returns nil if the event was dropped by relabeling.
+func (t *Target) labels(tag string) model.LabelSet {
+	processed := labels.FromMap(map[string]string{tagLabel: tag})
+	if len(t.cfg.RelabelConfigs) > 0 {
+		processed = relabel.Process(processed, t.cfg.RelabelConfigs...)
+		if processed == nil {
+			return nil
+		}
+	}
+
+	lset := make(model.LabelSet, len(t.cfg.Labels)+len(processed))
+	for _, l := range processed {
+		if strings.HasPrefix(l.Name, model.ReservedLabelPrefix) {
+			continue
+		}
+		lset[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+	for k, v := range t.cfg.Labels {
+		lset[k] = v
+	}
+	return lset
+}
+
+// line returns the log line of record.
+func (t *Target) line(record map[string]interface{}) (string, error) {
+	if t.cfg.MessageField != "" {
+		switch v := record[t.cfg.MessageField].(type) {
+		case string:
+			return v, nil
+		case []byte:
+			return string(v), nil
+		}
+	}
+
+	bb, err := json.Marshal(jsonValue(record))
+	if err != nil {
+		return "", err
+	}
+	return string(bb), nil
+}
+
+// jsonValue converts byte slices nested in v to strings so they're encoded
+// as text rather than base64.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			out[k] = jsonValue(e)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, e := range v {
+			out[i] = jsonValue(e)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// Stop stops receiving, closes all connections, and stops the handler of the
+// Target.
+func (t *Target) Stop() error {
+	t.mut.Lock()
+	close(t.quit)
+	err := t.lis.Close()
+	for conn := range t.conns {
+		conn.Close()
+	}
+	t.mut.Unlock()
+
+	t.wg.Wait()
+	t.handler.Stop()
+	return err
+}
+
+// event is a single record received from a fluent client.
+type event struct {
+	Time   time.Time
+	Record map[string]interface{}
+}
+
+// parseMessage parses a forward protocol message into its tag, events, and
+// options. msg is one of:
+//
+//	[tag, time, record, option?]        (Message)
+//	[tag, [[time, record], ...], option?] (Forward)
+//	[tag, entries, option?]             (PackedForward)
+//
+// where entries is a msgpack stream of [time, record] arrays, gzip
+// compressed when the compressed option is "gzip".
+func parseMessage(msg []interface{}) (tag string, events []event, opts map[string]interface{}, err error) {
+	if len(msg) < 2 {
+		return "", nil, nil, fmt.Errorf("expected at least 2 elements in message, got %d", len(msg))
+	}
+	tag, ok := toString(msg[0])
+	if !ok {
+		return "", nil, nil, fmt.Errorf("invalid tag of type %T", msg[0])
+	}
+
+	switch entries := msg[1].(type) {
+	case []interface{}:
+		if opts, err = parseOptions(msg, 2); err != nil {
+			return "", nil, nil, err
+		}
+		for _, e := range entries {
+			ev, err := parseEntry(e)
+			if err != nil {
+				return "", nil, nil, err
+			}
+			events = append(events, ev)
+		}
+
+	case string, []byte:
+		if opts, err = parseOptions(msg, 2); err != nil {
+			return "", nil, nil, err
+		}
+		raw, _ := toBytes(entries)
+		if events, err = parsePackedEntries(raw, opts["compressed"]); err != nil {
+			return "", nil, nil, err
+		}
+
+	default:
+		if len(msg) < 3 {
+			return "", nil, nil, fmt.Errorf("expected record in message")
+		}
+		if opts, err = parseOptions(msg, 3); err != nil {
+			return "", nil, nil, err
+		}
+		ev, err := parseEntry([]interface{}{msg[1], msg[2]})
+		if err != nil {
+			return "", nil, nil, err
+		}
+		events = append(events, ev)
+	}
+	return tag, events, opts, nil
+}
+
+// parseOptions returns the option map at index i of msg, if there is one.
+func parseOptions(msg []interface{}, i int) (map[string]interface{}, error) {
+	if len(msg) <= i || msg[i] == nil {
+		return nil, nil
+	}
+	opts, ok := msg[i].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid options of type %T", msg[i])
+	}
+	for k, v := range opts {
+		if s, ok := v.([]byte); ok {
+			opts[k] = string(s)
+		}
+	}
+	return opts, nil
+}
+
+// parsePackedEntries decodes a stream of [time, record] arrays.
+func parsePackedEntries(raw []byte, compressed interface{}) ([]event, error) {
+	var r io.Reader = bytes.NewReader(raw)
+	switch compressed {
+	case nil, "", "text":
+	case "gzip":
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip entries: %w", err)
+		}
+		defer gr.Close()
+		r = gr
+	default:
+		return nil, fmt.Errorf("unsupported compression %v", compressed)
+	}
+
+	var events []event
+	dec := codec.NewDecoder(bufio.NewReader(r), msgpackHandle)
+	for {
+		var entry []interface{}
+		if err := dec.Decode(&entry); errors.Is(err, io.EOF) {
+			return events, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid packed entries: %w", err)
+		}
+
+		ev, err := parseEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+}
+
+// parseEntry parses a [time, record] array.
+func parseEntry(v interface{}) (event, error) {
+	entry, ok := v.([]interface{})
+	if !ok || len(entry) < 2 {
+		return event{}, fmt.Errorf("expected [time, record] entry, got %T", v)
+	}
+
+	ts, err := parseTime(entry[0])
+	if err != nil {
+		return event{}, err
+	}
+	record, ok := entry[1].(map[string]interface{})
+	if !ok {
+		return event{}, fmt.Errorf("invalid record of type %T", entry[1])
+	}
+	return event{Time: ts, Record: record}, nil
+}
+
+// parseTime parses the time of an entry, which is either a number of seconds
+// or an EventTime.
+func parseTime(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case int64:
+		return time.Unix(v, 0), nil
+	case uint64:
+		return time.Unix(int64(v), 0), nil
+	case float64:
+		sec, frac := math.Modf(v)
+		return time.Unix(int64(sec), int64(frac*1e9)), nil
+	case codec.RawExt:
+		if v.Tag != eventTimeExt || len(v.Data) != 8 {
+			return time.Time{}, fmt.Errorf("invalid time extension type %d", v.Tag)
+		}
+		sec := binary.BigEndian.Uint32(v.Data[:4])
+		nsec := binary.BigEndian.Uint32(v.Data[4:])
+		return time.Unix(int64(sec), int64(nsec)), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid time of type %T", v)
+	}
+}
+
+func toString(v interface{}) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	default:
+		return "", false
+	}
+}
+
+func toBytes(v interface{}) ([]byte, bool) {
+	switch v := v.(type) {
+	case string:
+		return []byte(v), true
+	case []byte:
+		return v, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,82 @@
+package gelf
+
+import (
+	"fmt"
+
+	"github.com/grafana/agent/pkg/logs/stages"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// Protocol is a transport GELF messages are received over.
+type Protocol string
+
+// Supported protocols.
+const (
+	// ProtocolUDP receives GELF messages as UDP datagrams, which may be
+	// chunked and compressed with gzip or zlib.
+	ProtocolUDP Protocol = "udp"
+
+	// ProtocolTCP receives uncompressed GELF messages delimited by null bytes.
+	ProtocolTCP Protocol = "tcp"
+)
+
+// DefaultConfig holds the default settings for a GELF config.
+var DefaultConfig = Config{
+	Protocol: ProtocolUDP,
+}
+
+// Config configures a job which receives logs in the Graylog Extended Log
+// Format.
+type Config struct {
+	// JobName identifies the job in metrics and pipeline stages. Required.
+	JobName string `yaml:"job_name"`
+
+	// ListenAddress is the host:port address to listen for messages on.
+	// Required.
+	ListenAddress string `yaml:"listen_address"`
+
+	// Protocol to receive messages over: udp or tcp.
+	Protocol Protocol `yaml:"protocol,omitempty"`
+
+	// Labels to add to every log line.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+
+	// UseIncomingTimestamp uses the timestamp of the messages instead of the
+	// time they were received.
+	UseIncomingTimestamp bool `yaml:"use_incoming_timestamp,omitempty"`
+
+	// RelabelConfigs are applied to the __gelf_* labels of each message.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
+
+	// PipelineStages process log lines before they're sent to Loki.
+	PipelineStages stages.PipelineStages `yaml:"pipeline_stages,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type config Config
+	if err := unmarshal((*config)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate returns an error if c is not valid.
+func (c *Config) Validate() error {
+	switch {
+	case c.JobName == "":
+		return fmt.Errorf("gelf config must have a job_name")
+	case c.ListenAddress == "":
+		return fmt.Errorf("gelf config %s must have a listen_address", c.JobName)
+	}
+
+	switch c.Protocol {
+	case ProtocolUDP, ProtocolTCP:
+	default:
+		return fmt.Errorf("gelf config %s has unsupported protocol %q", c.JobName, c.Protocol)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/require"
+)
+
+const testMessage = `{"version":"1.1","host":"web-1","short_message":"hello","timestamp":1640995200.25,"level":6,"_app":"shop","_request.id":42,"_id":"ignored"}`
+
+func TestParseMessage(t *testing.T) {
+	msg, err := parseMessage([]byte(testMessage))
+	require.NoError(t, err)
+	require.Equal(t, "1.1", msg.Version)
+	require.Equal(t, "web-1", msg.Host)
+	require.Equal(t, "hello", msg.ShortMessage)
+	require.Equal(t, "6", msg.Level)
+	require.True(t, time.Unix(1640995200, 25e7).Equal(msg.Timestamp))
+	require.Equal(t, map[string]string{"app": "shop", "request.id": "42"}, msg.Fields)
+
+	for _, invalid := range []string{
+		`not json`,
+		`{"host":"web-1"}`,
+		`{"short_message":"hello"}`,
+	} {
+		_, err := parseMessage([]byte(invalid))
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	var gz bytes.Buffer
+	gw := gzip.NewWriter(&gz)
+	_, _ = gw.Write([]byte(testMessage))
+	require.NoError(t, gw.Close())
+
+	var zl bytes.Buffer
+	zw := zlib.NewWriter(&zl)
+	_, _ = zw.Write([]byte(testMessage))
+	require.NoError(t, zw.Close())
+
+	for _, payload := range [][]byte{[]byte(testMessage), gz.Bytes(), zl.Bytes()} {
+		out, err := decompress(payload)
+		require.NoError(t, err)
+		require.Equal(t, testMessage, string(out))
+	}
+}
+
+// chunks splits payload into count chunks of the message with the given ID.
+func chunks(id byte, payload []byte, count int) [][]byte {
+	size := (len(payload) + count - 1) / count
+	var out [][]byte
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(payload) {
+			end = len(payload)
+		}
+		chunk := []byte{chunkMagic0, chunkMagic1, id, 0, 0, 0, 0, 0, 0, 0, byte(i), byte(count)}
+		out = append(out, append(chunk, payload[i*size:end]...))
+	}
+	return out
+}
+
+func TestAssembler(t *testing.T) {
+	now := time.Now()
+	a := newAssembler()
+
+	cc := chunks(1, []byte(testMessage), 3)
+	for _, i := range []int{2, 0, 0} {
+		payload, err := a.add(now, cc[i])
+		require.NoError(t, err)
+		require.Nil(t, payload)
+	}
+	payload, err := a.add(now, cc[1])
+	require.NoError(t, err)
+	require.Equal(t, testMessage, string(payload))
+
+	_, err = a.add(now, chunks(2, []byte(testMessage), 2)[0])
+	require.NoError(t, err)
+	require.Equal(t, 0, a.expire(now.Add(chunkTimeout)))
+	require.Equal(t, 1, a.expire(now.Add(chunkTimeout+time.Second)))
+
+	_, err = a.add(now, []byte{chunkMagic0, chunkMagic1, 3, 0, 0, 0, 0, 0, 0, 0, 0, maxChunks + 1})
+	require.Error(t, err)
+}
+
+func TestTarget(t *testing.T) {
+	for _, protocol := range []Protocol{ProtocolUDP, ProtocolTCP} {
+		t.Run(string(protocol), func(t *testing.T) {
+			cfg := &Config{
+				JobName:              "gelf",
+				ListenAddress:        "127.0.0.1:0",
+				Protocol:             protocol,
+				Labels:               model.LabelSet{"job": "gelf"},
+				UseIncomingTimestamp: true,
+				RelabelConfigs: []*relabel.Config{
+					{
+						SourceLabels: model.LabelNames{fieldLabelPrefix + "app"},
+						Regex:        relabel.MustNewRegexp("(.*)"),
+						Replacement:  "$1",
+						TargetLabel:  "app",
+						Action:       relabel.Replace,
+					},
+				},
+			}
+
+			entries := make(chan api.Entry, 10)
+			target, err := NewTarget(log.NewNopLogger(), NewMetrics(nil), api.NewEntryHandler(entries, func() {}), cfg)
+			require.NoError(t, err)
+			defer func() { require.NoError(t, target.Stop()) }()
+
+			conn, err := net.Dial(string(protocol), target.Addr().String())
+			require.NoError(t, err)
+			defer conn.Close()
+
+			if protocol == ProtocolUDP {
+				for _, c := range chunks(1, []byte(testMessage), 2) {
+					_, err = conn.Write(c)
+					require.NoError(t, err)
+				}
+			} else {
+				_, err = conn.Write(append([]byte(testMessage), 0))
+				require.NoError(t, err)
+			}
+
+			select {
+			case e := <-entries:
+				require.Equal(t, model.LabelSet{"job": "gelf", "app": "shop"}, e.Labels)
+				require.Equal(t, testMessage, e.Line)
+				require.True(t, time.Unix(1640995200, 25e7).Equal(e.Timestamp))
+			case <-time.After(5 * time.Second):
+				require.FailNow(t, "timed out waiting for entry")
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// Layout of the header of a chunked UDP message: two magic bytes, an 8-byte
+// message ID, the sequence number of the chunk, and the number of chunks.
+const (
+	chunkMagic0     = 0x1e
+	chunkMagic1     = 0x0f
+	chunkHeaderSize = 12
+
+	// maxChunks is the most chunks a message may be split into.
+	maxChunks = 128
+
+	// chunkTimeout is how long to wait for all chunks of a message to
+	// arrive before dropping it.
+	chunkTimeout = 5 * time.Second
+
+	// maxPendingMessages is the most chunked messages to reassemble at once.
+	maxPendingMessages = 1000
+)
+
+// message is a decoded GELF message.
+type message struct {
+	Version      string
+	Host         string
+	ShortMessage string
+	Timestamp    time.Time
+	Level        string
+	Facility     string
+
+	// Fields holds the additional fields of the message without their
+	// leading underscore.
+	Fields map[string]string
+
+	// Raw is the JSON of the message.
+	Raw []byte
+}
+
+// parseMessage decodes the JSON payload of a GELF message.
+func parseMessage(raw []byte) (message, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	var obj map[string]interface{}
+	if err := dec.Decode(&obj); err != nil {
+		return message{}, fmt.Errorf("invalid json: %w", err)
+	}
+
+	msg := message{
+		Version:      stringValue(obj["version"]),
+		Host:         stringValue(obj["host"]),
+		ShortMessage: stringValue(obj["short_message"]),
+		Level:        stringValue(obj["level"]),
+		Facility:     stringValue(obj["facility"]),
+		Fields:       make(map[string]string),
+		Raw:          raw,
+	}
+	switch {
+	case msg.Host == "":
+		return message{}, fmt.Errorf("message has no host")
+	case msg.ShortMessage == "":
+		return message{}, fmt.Errorf("message has no short_message")
+	}
+
+	if n, ok := obj["timestamp"].(json.Number); ok {
+		f, err := n.Float64()
+		if err != nil {
+			return message{}, fmt.Errorf("invalid timestamp %q: %w", n, err)
+		}
+		sec, frac := math.Modf(f)
+		msg.Timestamp = time.Unix(int64(sec), int64(frac*1e9))
+	}
+
+	for k, v := range obj {
+		if !strings.HasPrefix(k, "_") || k == "_id" {
+			continue
+		}
+		msg.Fields[strings.TrimPrefix(k, "_")] = stringValue(v)
+	}
+	return msg, nil
+}
+
+// stringValue returns v as a string. Numbers are returned as they were
+// written in the JSON and other non-string values are returned as JSON.
+func stringValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		bb, _ := json.Marshal(v)
+		return string(bb)
+	}
+}
+
+// decompress returns the decompressed payload of a GELF message, which may
+// be compressed with gzip or zlib.
+func decompress(payload []byte) ([]byte, error) {
+	var (
+		r   io.ReadCloser
+		err error
+	)
+	switch {
+	case len(payload) >= 2 && payload[0] == 0x1f && payload[1] == 0x8b:
+		r, err = gzip.NewReader(bytes.NewReader(payload))
+	case len(payload) >= 2 && payload[0] == 0x78 && (int(payload[0])<<8|int(payload[1]))%31 == 0:
+		r, err = zlib.NewReader(bytes.NewReader(payload))
+	default:
+		return payload, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// isChunk returns true if packet is a chunk of a message.
+func isChunk(packet []byte) bool {
+	return len(packet) >= 2 && packet[0] == chunkMagic0 && packet[1] == chunkMagic1
+}
+
+// assembler reassembles chunked UDP messages. It's not safe for concurrent
+// use.
+type assembler struct {
+	pending map[[8]byte]*chunkedMessage
+}
+
+type chunkedMessage struct {
+	chunks   [][]byte
+	received int
+	first    time.Time
+}
+
+func newAssembler() *assembler {
+	return &assembler{pending: make(map[[8]byte]*chunkedMessage)}
+}
+
+// add adds a chunk received at now. Once all chunks of its message are
+// received, the payload of the message is returned. Otherwise, nil is
+// returned.
+func (a *assembler) add(now time.Time, chunk []byte) ([]byte, error) {
+	if len(chunk) < chunkHeaderSize {
+		return nil, fmt.Errorf("chunk of %d bytes is too short", len(chunk))
+	}
+
+	var id [8]byte
+	copy(id[:], chunk[2:10])
+	seq, count := int(chunk[10]), int(chunk[11])
+	switch {
+	case count == 0 || count > maxChunks:
+		return nil, fmt.Errorf("invalid chunk count %d", count)
+	case seq >= count:
+		return nil, fmt.Errorf("chunk %d out of range of %d chunks", seq, count)
+	}
+
+	m, ok := a.pending[id]
+	if !ok {
+		if len(a.pending) >= maxPendingMessages {
+			return nil, fmt.Errorf("too many chunked messages pending")
+		}
+		m = &chunkedMessage{chunks: make([][]byte, count), first: now}
+		a.pending[id] = m
+	}
+	if len(m.chunks) != count {
+		delete(a.pending, id)
+		return nil, fmt.Errorf("chunk count %d doesn't match %d of previous chunks", count, len(m.chunks))
+	}
+	if m.chunks[seq] != nil {
+		return nil, nil
+	}
+	m.chunks[seq] = chunk[chunkHeaderSize:]
+	m.received++
+
+	if m.received < count {
+		return nil, nil
+	}
+	delete(a.pending, id)
+	return bytes.Join(m.chunks, nil), nil
+}
+
+// expire drops messages which didn't receive all of their chunks within
+// chunkTimeout and returns the number of dropped messages.
+func (a *assembler) expire(now time.Time) int {
+	var expired int
+	for id, m := range a.pending {
+		if now.Sub(m.first) > chunkTimeout {
+			delete(a.pending, id)
+			expired++
+		}
+	}
+	return expired
+}
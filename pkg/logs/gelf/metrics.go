@@ -0,0 +1,38 @@
+package gelf
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics holds metrics for GELF targets.
+type Metrics struct {
+	entries       *prometheus.CounterVec
+	parseErrors   *prometheus.CounterVec
+	expiredChunks *prometheus.CounterVec
+}
+
+// NewMetrics creates a new set of metrics. Metrics will be registered to reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{}
+
+	m.entries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "gelf_target_entries_total",
+		Help:      "Total number of GELF messages received.",
+	}, []string{"job"})
+
+	m.parseErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "gelf_target_parsing_errors_total",
+		Help:      "Total number of GELF messages which couldn't be parsed.",
+	}, []string{"job"})
+
+	m.expiredChunks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "gelf_target_expired_messages_total",
+		Help:      "Total number of chunked GELF messages dropped before all of their chunks were received.",
+	}, []string{"job"})
+
+	if reg != nil {
+		reg.MustRegister(m.entries, m.parseErrors, m.expiredChunks)
+	}
+	return m
+}
@@ -0,0 +1,317 @@
+package gelf
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/prometheus/prometheus/util/strutil"
+)
+
+// Labels discovered for every message which can be used in relabel_configs.
+// Additional fields are discovered as fieldLabelPrefix followed by their
+// sanitized name.
+const (
+	hostLabel        = model.ReservedLabelPrefix + "gelf_message_host"
+	levelLabel       = model.ReservedLabelPrefix + "gelf_message_level"
+	versionLabel     = model.ReservedLabelPrefix + "gelf_message_version"
+	facilityLabel    = model.ReservedLabelPrefix + "gelf_message_facility"
+	fieldLabelPrefix = model.ReservedLabelPrefix + "gelf_field_"
+)
+
+// maxPacketSize is the largest UDP datagram which can be received.
+const maxPacketSize = 65536
+
+// Target receives GELF messages over UDP or TCP. Chunked and compressed UDP
+// messages are supported. Each message is sent as a log line holding its
+// JSON.
+type Target struct {
+	log     log.Logger
+	metrics *Metrics
+	cfg     *Config
+	handler api.EntryHandler
+
+	// Only one of packetConn and lis is set, depending on the protocol.
+	packetConn net.PacketConn
+	lis        net.Listener
+
+	wg    sync.WaitGroup
+	quit  chan struct{}
+	mut   sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// NewTarget creates a new Target which listens on the address of cfg.
+// Entries are sent to handler, which is stopped when the Target is stopped.
+func NewTarget(l log.Logger, m *Metrics, handler api.EntryHandler, cfg *Config) (*Target, error) {
+	t := &Target{
+		log:     log.With(l, "job", cfg.JobName),
+		metrics: m,
+		cfg:     cfg,
+		handler: handler,
+		quit:    make(chan struct{}),
+		conns:   make(map[net.Conn]struct{}),
+	}
+
+	var err error
+	switch cfg.Protocol {
+	case ProtocolTCP:
+		t.lis, err = net.Listen("tcp", cfg.ListenAddress)
+	default:
+		t.packetConn, err = net.ListenPacket("udp", cfg.ListenAddress)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", cfg.ListenAddress, err)
+	}
+
+	t.wg.Add(1)
+	if t.lis != nil {
+		go t.runTCP()
+	} else {
+		go t.runUDP()
+	}
+	return t, nil
+}
+
+// Addr returns the address the Target listens on.
+func (t *Target) Addr() net.Addr {
+	if t.lis != nil {
+		return t.lis.Addr()
+	}
+	return t.packetConn.LocalAddr()
+}
+
+func (t *Target) runUDP() {
+	defer t.wg.Done()
+
+	var (
+		buf       = make([]byte, maxPacketSize)
+		assembler = newAssembler()
+	)
+	for {
+		// Wake up regularly so messages with missing chunks are expired even
+		// if no more packets arrive.
+		_ = t.packetConn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := t.packetConn.ReadFrom(buf)
+
+		if expired := assembler.expire(time.Now()); expired > 0 {
+			t.metrics.expiredChunks.WithLabelValues(t.cfg.JobName).Add(float64(expired))
+		}
+
+		if err != nil {
+			select {
+			case <-t.quit:
+				return
+			default:
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			level.Warn(t.log).Log("msg", "failed to read packet", "err", err)
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		payload := append([]byte(nil), buf[:n]...)
+		if isChunk(payload) {
+			payload, err = assembler.add(time.Now(), payload)
+			if err != nil {
+				level.Warn(t.log).Log("msg", "invalid chunk", "err", err)
+				t.metrics.parseErrors.WithLabelValues(t.cfg.JobName).Inc()
+				continue
+			} else if payload == nil {
+				continue
+			}
+		}
+
+		payload, err = decompress(payload)
+		if err != nil {
+			level.Warn(t.log).Log("msg", "failed to decompress message", "err", err)
+			t.metrics.parseErrors.WithLabelValues(t.cfg.JobName).Inc()
+			continue
+		}
+		if !t.process(payload) {
+			return
+		}
+	}
+}
+
+func (t *Target) runTCP() {
+	defer t.wg.Done()
+
+	for {
+		conn, err := t.lis.Accept()
+		if err != nil {
+			select {
+			case <-t.quit:
+				return
+			default:
+			}
+			level.Warn(t.log).Log("msg", "failed to accept connection", "err", err)
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			time.Sleep(time.Second)
+			continue
+		}
+
+		if !t.track(conn) {
+			conn.Close()
+			return
+		}
+		t.wg.Add(1)
+		go t.handleConn(conn)
+	}
+}
+
+// track records conn as open so it can be closed when the Target is stopped.
+// Returns false if the Target is already stopped.
+func (t *Target) track(conn net.Conn) bool {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	select {
+	case <-t.quit:
+		return false
+	default:
+	}
+	t.conns[conn] = struct{}{}
+	return true
+}
+
+func (t *Target) untrack(conn net.Conn) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	delete(t.conns, conn)
+}
+
+// handleConn receives messages delimited by null bytes from conn.
+func (t *Target) handleConn(conn net.Conn) {
+	defer t.wg.Done()
+	defer conn.Close()
+	defer t.untrack(conn)
+
+	r := bufio.NewReader(conn)
+	for {
+		payload, err := r.ReadBytes(0)
+		payload = bytes.TrimSpace(bytes.TrimSuffix(payload, []byte{0}))
+		if len(payload) > 0 && !t.process(payload) {
+			return
+		}
+
+		if err != nil {
+			select {
+			case <-t.quit:
+			default:
+				if !errors.Is(err, io.EOF) {
+					level.Warn(t.log).Log("msg", "failed to read message", "remote_addr", conn.RemoteAddr(), "err", err)
+				}
+			}
+			return
+		}
+	}
+}
+
+// process parses payload and sends it to the handler. Returns false if the
+// Target was stopped before the message could be sent.
+func (t *Target) process(payload []byte) bool {
+	msg, err := parseMessage(payload)
+	if err != nil {
+		level.Warn(t.log).Log("msg", "invalid message", "err", err)
+		t.metrics.parseErrors.WithLabelValues(t.cfg.JobName).Inc()
+		return true
+	}
+
+	lset := t.labels(msg)
+	if lset == nil {
+		return true
+	}
+
+	ts := time.Now()
+	if t.cfg.UseIncomingTimestamp && !msg.Timestamp.IsZero() {
+		ts = msg.Timestamp
+	}
+
+	entry := api.Entry{
+		Labels: lset,
+		Entry:  logproto.Entry{Timestamp: ts, Line: string(msg.Raw)},
+	}
+	select {
+	case t.handler.Chan() <- entry:
+		t.metrics.entries.WithLabelValues(t.cfg.JobName).Inc()
+		return true
+	case <-t.quit:
+		return false
+	}
+}
+
+// labels returns the labels of msg after relabeling. Returns nil if the
+// message was dropped by relabeling.
+func (t *Target) labels(msg message) model.LabelSet {
+	discovered := map[string]string{
+		hostLabel:     msg.Host,
+		levelLabel:    msg.Level,
+		versionLabel:  msg.Version,
+		facilityLabel: msg.Facility,
+	}
+	for name, value := range msg.Fields {
+		discovered[fieldLabelPrefix+strutil.SanitizeLabelName(name)] = value
+	}
+
+	processed := labels.FromMap(discovered)
+	if len(t.cfg.RelabelConfigs) > 0 {
+		processed = relabel.Process(processed, t.cfg.RelabelConfigs...)
+		if processed == nil {
+			return nil
+		}
+	}
+
+	lset := make(model.LabelSet, len(t.cfg.Labels)+len(processed))
+	for _, l := range processed {
+		if strings.HasPrefix(l.Name, model.ReservedLabelPrefix) {
+			continue
+		}
+		lset[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+	for k, v := range t.cfg.Labels {
+		lset[k] = v
+	}
+	return lset
+}
+
+// Stop stops receiving, closes all connections, and stops the handler of the
+// Target.
+func (t *Target) Stop() error {
+	t.mut.Lock()
+	close(t.quit)
+	var err error
+	if t.lis != nil {
+		err = t.lis.Close()
+	} else {
+		err = t.packetConn.Close()
+	}
+	for conn := range t.conns {
+		conn.Close()
+	}
+	t.mut.Unlock()
+
+	t.wg.Wait()
+	t.handler.Stop()
+	return err
+}
@@ -0,0 +1,55 @@
+package heroku
+
+import (
+	"fmt"
+
+	"github.com/grafana/agent/pkg/logs/stages"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// Config configures a job which receives logs from Heroku HTTPS drains.
+type Config struct {
+	// JobName identifies the job in metrics and pipeline stages. Required.
+	JobName string `yaml:"job_name"`
+
+	// ListenAddress is the host:port address to listen for drain requests on.
+	// Required.
+	ListenAddress string `yaml:"listen_address"`
+
+	// Labels to add to every log line.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+
+	// UseIncomingTimestamp uses the timestamp of the messages instead of the
+	// time they were received.
+	UseIncomingTimestamp bool `yaml:"use_incoming_timestamp,omitempty"`
+
+	// RelabelConfigs are applied to the __heroku_drain_* labels of each
+	// message.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
+
+	// PipelineStages process log lines before they're sent to Loki.
+	PipelineStages stages.PipelineStages `yaml:"pipeline_stages,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = Config{}
+
+	type config Config
+	if err := unmarshal((*config)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate returns an error if c is not valid.
+func (c *Config) Validate() error {
+	switch {
+	case c.JobName == "":
+		return fmt.Errorf("heroku_drain config must have a job_name")
+	case c.ListenAddress == "":
+		return fmt.Errorf("heroku_drain config %s must have a listen_address", c.JobName)
+	}
+	return nil
+}
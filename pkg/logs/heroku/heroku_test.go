@@ -0,0 +1,115 @@
+package heroku
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/require"
+)
+
+// frame returns msg as an octet-counted syslog frame.
+func frame(msg string) string {
+	return fmt.Sprintf("%d %s", len(msg), msg)
+}
+
+func TestParseFrames(t *testing.T) {
+	body := frame("<158>1 2022-01-01T00:00:00.5+00:00 host heroku router - at=info method=GET path=\"/\"\n") +
+		frame("<190>1 2022-01-01T00:00:01+00:00 host app web.1 - Listening on port 5000\n")
+
+	msgs, err := parseFramesString(body)
+	require.NoError(t, err)
+	require.Equal(t, []message{
+		{
+			Timestamp: time.Date(2022, 1, 1, 0, 0, 0, 500*int(time.Millisecond), time.UTC),
+			Hostname:  "host",
+			AppName:   "heroku",
+			ProcID:    "router",
+			Text:      `at=info method=GET path="/"`,
+		},
+		{
+			Timestamp: time.Date(2022, 1, 1, 0, 0, 1, 0, time.UTC),
+			Hostname:  "host",
+			AppName:   "app",
+			ProcID:    "web.1",
+			Text:      "Listening on port 5000",
+		},
+	}, normalize(msgs))
+
+	for _, invalid := range []string{
+		"abc <158>1 ...",
+		"100 <158>1 2022-01-01T00:00:00Z host app web.1 - truncated",
+		frame("not syslog"),
+		frame("<158>1 yesterday host app web.1 - hello"),
+	} {
+		_, err := parseFramesString(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestTarget(t *testing.T) {
+	cfg := &Config{
+		JobName:              "heroku",
+		ListenAddress:        "127.0.0.1:0",
+		Labels:               model.LabelSet{"job": "heroku"},
+		UseIncomingTimestamp: true,
+		RelabelConfigs: []*relabel.Config{
+			{
+				SourceLabels: model.LabelNames{procLabel},
+				Regex:        relabel.MustNewRegexp("router"),
+				Action:       relabel.Drop,
+			},
+			{
+				SourceLabels: model.LabelNames{appLabel},
+				Regex:        relabel.MustNewRegexp("(.*)"),
+				Replacement:  "$1",
+				TargetLabel:  "app",
+				Action:       relabel.Replace,
+			},
+		},
+	}
+
+	entries := make(chan api.Entry, 10)
+	target, err := NewTarget(log.NewNopLogger(), NewMetrics(nil), api.NewEntryHandler(entries, func() {}), cfg)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, target.Stop()) }()
+
+	url := fmt.Sprintf("http://%s%s", target.Addr(), DrainPath)
+	body := frame("<158>1 2022-01-01T00:00:00+00:00 host heroku router - at=info\n") +
+		frame("<190>1 2022-01-01T00:00:01+00:00 host my-app web.1 - hello\n")
+
+	resp, err := http.Post(url, "application/logplex-1", strings.NewReader(body))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	require.Len(t, entries, 1)
+	e := <-entries
+	require.Equal(t, model.LabelSet{"job": "heroku", "app": "my-app"}, e.Labels)
+	require.Equal(t, "hello", e.Line)
+	require.True(t, time.Date(2022, 1, 1, 0, 0, 1, 0, time.UTC).Equal(e.Timestamp))
+
+	resp, err = http.Post(url, "application/logplex-1", strings.NewReader("garbage"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func parseFramesString(s string) ([]message, error) {
+	return parseFrames(bufio.NewReader(strings.NewReader(s)))
+}
+
+// normalize converts timestamps of msgs to UTC so they can be compared.
+func normalize(msgs []message) []message {
+	for i := range msgs {
+		msgs[i].Timestamp = msgs[i].Timestamp.UTC()
+	}
+	return msgs
+}
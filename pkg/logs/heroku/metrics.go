@@ -0,0 +1,31 @@
+package heroku
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics holds metrics for Heroku drain targets.
+type Metrics struct {
+	entries     *prometheus.CounterVec
+	parseErrors *prometheus.CounterVec
+}
+
+// NewMetrics creates a new set of metrics. Metrics will be registered to reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{}
+
+	m.entries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "heroku_drain_target_entries_total",
+		Help:      "Total number of log lines received from Heroku drains.",
+	}, []string{"job"})
+
+	m.parseErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "heroku_drain_target_parsing_errors_total",
+		Help:      "Total number of Heroku drain requests which couldn't be parsed.",
+	}, []string{"job"})
+
+	if reg != nil {
+		reg.MustRegister(m.entries, m.parseErrors)
+	}
+	return m
+}
@@ -0,0 +1,236 @@
+package heroku
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// DrainPath is the path drain requests are received on.
+const DrainPath = "/heroku/api/v1/drain"
+
+// Labels discovered for every message which can be used in relabel_configs.
+const (
+	hostLabel  = model.ReservedLabelPrefix + "heroku_drain_host"
+	appLabel   = model.ReservedLabelPrefix + "heroku_drain_app"
+	procLabel  = model.ReservedLabelPrefix + "heroku_drain_proc"
+	logIDLabel = model.ReservedLabelPrefix + "heroku_drain_log_id"
+)
+
+// maxFrameSize is the largest syslog message accepted in a drain request.
+const maxFrameSize = 1024 * 1024
+
+// Target receives logs from Heroku HTTPS drains. Drains POST batches of
+// octet-counted syslog messages in the logplex format.
+type Target struct {
+	log     log.Logger
+	metrics *Metrics
+	cfg     *Config
+	handler api.EntryHandler
+
+	srv  *http.Server
+	addr net.Addr
+	done chan struct{}
+}
+
+// NewTarget creates a new Target which listens on the address of cfg.
+// Entries are sent to handler, which is stopped when the Target is stopped.
+func NewTarget(l log.Logger, m *Metrics, handler api.EntryHandler, cfg *Config) (*Target, error) {
+	lis, err := net.Listen("tcp", cfg.ListenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", cfg.ListenAddress, err)
+	}
+
+	t := &Target{
+		log:     log.With(l, "job", cfg.JobName),
+		metrics: m,
+		cfg:     cfg,
+		handler: handler,
+		addr:    lis.Addr(),
+		done:    make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(DrainPath, t.handleDrain)
+	t.srv = &http.Server{Handler: mux}
+
+	go func() {
+		defer close(t.done)
+		if err := t.srv.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			level.Error(t.log).Log("msg", "heroku drain server stopped", "err", err)
+		}
+	}()
+	return t, nil
+}
+
+// Addr returns the address the Target listens on.
+func (t *Target) Addr() net.Addr { return t.addr }
+
+func (t *Target) handleDrain(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	msgs, err := parseFrames(bufio.NewReader(r.Body))
+	if err != nil {
+		level.Warn(t.log).Log("msg", "failed to parse drain request", "err", err)
+		t.metrics.parseErrors.WithLabelValues(t.cfg.JobName).Inc()
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, msg := range msgs {
+		lset := t.labels(msg)
+		if lset == nil {
+			continue
+		}
+
+		ts := time.Now()
+		if t.cfg.UseIncomingTimestamp && !msg.Timestamp.IsZero() {
+			ts = msg.Timestamp
+		}
+
+		entry := api.Entry{
+			Labels: lset,
+			Entry:  logproto.Entry{Timestamp: ts, Line: msg.Text},
+		}
+		select {
+		case t.handler.Chan() <- entry:
+		case <-r.Context().Done():
+			return
+		}
+		t.metrics.entries.WithLabelValues(t.cfg.JobName).Inc()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// labels returns the labels of msg after relabeling. Returns nil if the
+// message was dropped by relabeling.
+func (t *Target) labels(msg message) model.LabelSet {
+	processed := labels.FromMap(map[string]string{
+		hostLabel:  msg.Hostname,
+		appLabel:   msg.AppName,
+		procLabel:  msg.ProcID,
+		logIDLabel: msg.MsgID,
+	})
+	if len(t.cfg.RelabelConfigs) > 0 {
+		processed = relabel.Process(processed, t.cfg.RelabelConfigs...)
+		if processed == nil {
+			return nil
+		}
+	}
+
+	lset := make(model.LabelSet, len(t.cfg.Labels)+len(processed))
+	for _, l := range processed {
+		if strings.HasPrefix(l.Name, model.ReservedLabelPrefix) {
+			continue
+		}
+		lset[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+	for k, v := range t.cfg.Labels {
+		lset[k] = v
+	}
+	return lset
+}
+
+// Stop stops receiving and stops the handler of the Target.
+func (t *Target) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := t.srv.Shutdown(ctx)
+	<-t.done
+	t.handler.Stop()
+	return err
+}
+
+// message is a syslog message sent by a Heroku drain.
+type message struct {
+	Timestamp time.Time
+	Hostname  string
+	AppName   string
+	ProcID    string
+	MsgID     string
+	Text      string
+}
+
+// parseFrames parses the octet-counted syslog messages of a drain request
+// body, where every message is prefixed with its length and a space.
+func parseFrames(r *bufio.Reader) ([]message, error) {
+	var msgs []message
+	for {
+		prefix, err := r.ReadString(' ')
+		if errors.Is(err, io.EOF) && strings.TrimSpace(prefix) == "" {
+			return msgs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read frame length: %w", err)
+		}
+
+		size, err := strconv.Atoi(strings.TrimSpace(prefix))
+		if err != nil || size <= 0 || size > maxFrameSize {
+			return nil, fmt.Errorf("invalid frame length %q", strings.TrimSpace(prefix))
+		}
+
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(r, frame); err != nil {
+			return nil, fmt.Errorf("failed to read frame: %w", err)
+		}
+
+		msg, err := parseMessage(string(frame))
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+// parseMessage parses a syslog message in the format used by Heroku:
+//
+//	<PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID MSG
+func parseMessage(s string) (message, error) {
+	parts := strings.SplitN(strings.TrimRight(s, "\n"), " ", 7)
+	if len(parts) < 6 || !strings.HasPrefix(parts[0], "<") || !strings.Contains(parts[0], ">") {
+		return message{}, fmt.Errorf("invalid syslog message %q", s)
+	}
+
+	ts, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return message{}, fmt.Errorf("invalid syslog timestamp %q: %w", parts[1], err)
+	}
+
+	msg := message{
+		Timestamp: ts,
+		Hostname:  nilValue(parts[2]),
+		AppName:   nilValue(parts[3]),
+		ProcID:    nilValue(parts[4]),
+		MsgID:     nilValue(parts[5]),
+	}
+	if len(parts) == 7 {
+		msg.Text = parts[6]
+	}
+	return msg, nil
+}
+
+// nilValue returns an empty string for the syslog nil value "-".
+func nilValue(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
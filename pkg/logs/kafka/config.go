@@ -0,0 +1,261 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/grafana/agent/pkg/logs/stages"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// DefaultConfig holds the default settings for a Kafka scrape config.
+var DefaultConfig = Config{
+	GroupID: "grafana-agent",
+	Version: sarama.V2_2_0_0.String(),
+	Format:  FormatRaw,
+	JSON: JSONConfig{
+		MessageField: "message",
+	},
+	Authentication: Authentication{
+		Type: AuthenticationTypeNone,
+	},
+}
+
+// Config configures a job which consumes log lines from Kafka topics.
+type Config struct {
+	// JobName identifies the job in metrics and pipeline stages. Required.
+	JobName string `yaml:"job_name"`
+
+	// Brokers is the list of host:port addresses of Kafka brokers to bootstrap
+	// from. Required.
+	Brokers []string `yaml:"brokers"`
+
+	// Topics to consume from. Required.
+	Topics []string `yaml:"topics"`
+
+	// GroupID is the consumer group to join. Instances sharing a group ID
+	// split the partitions of the topics between them.
+	GroupID string `yaml:"group_id,omitempty"`
+
+	// Version is the Kafka protocol version to use.
+	Version string `yaml:"version,omitempty"`
+
+	// Authentication configures how to connect to the brokers.
+	Authentication Authentication `yaml:"authentication,omitempty"`
+
+	// Format of the message values: raw or json.
+	Format Format `yaml:"format,omitempty"`
+
+	// JSON configures decoding of messages when Format is json.
+	JSON JSONConfig `yaml:"json,omitempty"`
+
+	// Labels to add to every log line.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+
+	// UseIncomingTimestamp uses the timestamp of the Kafka message instead of
+	// the time the message was consumed.
+	UseIncomingTimestamp bool `yaml:"use_incoming_timestamp,omitempty"`
+
+	// RelabelConfigs are applied to the __meta_kafka_* labels of each message.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
+
+	// PipelineStages process log lines before they're sent to Loki.
+	PipelineStages stages.PipelineStages `yaml:"pipeline_stages,omitempty"`
+
+	// Decoder overrides how messages are decoded, ignoring Format. It's set
+	// by log sources built on top of Kafka rather than from YAML.
+	Decoder Decoder `yaml:"-"`
+}
+
+// Decoder decodes a Kafka message into records. Messages for which an error
+// is returned are dropped.
+type Decoder func(msg *sarama.ConsumerMessage) ([]Record, error)
+
+// Record is a log line decoded from a Kafka message.
+type Record struct {
+	Line string
+	// Timestamp of the record, used instead of the timestamp of the message
+	// when set and use_incoming_timestamp is enabled.
+	Timestamp time.Time
+	// Labels are discovered labels of the record which can be used in
+	// relabel_configs, in addition to the __meta_kafka_* labels.
+	Labels model.LabelSet
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type config Config
+	if err := unmarshal((*config)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate returns an error if c is not valid.
+func (c *Config) Validate() error {
+	if c.JobName == "" {
+		return fmt.Errorf("kafka config must have a job_name")
+	}
+	if len(c.Brokers) == 0 {
+		return fmt.Errorf("kafka config %s must have at least one broker", c.JobName)
+	}
+	if len(c.Topics) == 0 {
+		return fmt.Errorf("kafka config %s must have at least one topic", c.JobName)
+	}
+	if c.GroupID == "" {
+		return fmt.Errorf("kafka config %s must have a group_id", c.JobName)
+	}
+	if _, err := sarama.ParseKafkaVersion(c.Version); err != nil {
+		return fmt.Errorf("kafka config %s: %w", c.JobName, err)
+	}
+	switch c.Format {
+	case FormatRaw:
+	case FormatJSON:
+		if c.JSON.MessageField == "" {
+			return fmt.Errorf("kafka config %s must have a json.message_field when format is json", c.JobName)
+		}
+	default:
+		return fmt.Errorf("kafka config %s has unknown format %q", c.JobName, c.Format)
+	}
+	if err := c.Authentication.Validate(); err != nil {
+		return fmt.Errorf("kafka config %s: %w", c.JobName, err)
+	}
+	return nil
+}
+
+// Format is the format of Kafka message values.
+type Format string
+
+// Supported formats.
+const (
+	// FormatRaw uses the message value as the log line.
+	FormatRaw Format = "raw"
+	// FormatJSON decodes the message value as a JSON object and uses one of
+	// its fields as the log line.
+	FormatJSON Format = "json"
+)
+
+// JSONConfig configures decoding of JSON messages.
+type JSONConfig struct {
+	// MessageField is the field of the JSON object holding the log line. If
+	// the field is missing or isn't a string, the whole object is used.
+	MessageField string `yaml:"message_field,omitempty"`
+}
+
+// AuthenticationType is a method to authenticate to Kafka brokers.
+type AuthenticationType string
+
+// Supported authentication types.
+const (
+	AuthenticationTypeNone AuthenticationType = "none"
+	AuthenticationTypeSSL  AuthenticationType = "ssl"
+	AuthenticationTypeSASL AuthenticationType = "sasl"
+)
+
+// Authentication configures how to authenticate to Kafka brokers.
+type Authentication struct {
+	// Type of authentication: none, ssl, or sasl.
+	Type AuthenticationType `yaml:"type,omitempty"`
+
+	// TLSConfig is used when Type is ssl, or when Type is sasl and
+	// SASLConfig.UseTLS is set.
+	TLSConfig config_util.TLSConfig `yaml:"tls_config,omitempty"`
+
+	// SASLConfig is used when Type is sasl.
+	SASLConfig SASLConfig `yaml:"sasl_config,omitempty"`
+}
+
+// Validate returns an error if a is not valid.
+func (a *Authentication) Validate() error {
+	switch a.Type {
+	case AuthenticationTypeNone, AuthenticationTypeSSL:
+		return nil
+	case AuthenticationTypeSASL:
+		return a.SASLConfig.Validate()
+	default:
+		return fmt.Errorf("unknown authentication type %q", a.Type)
+	}
+}
+
+// SASLConfig configures SASL authentication.
+type SASLConfig struct {
+	// Mechanism is one of PLAIN, SCRAM-SHA-256, or SCRAM-SHA-512.
+	Mechanism sarama.SASLMechanism `yaml:"mechanism,omitempty"`
+	User      string               `yaml:"user,omitempty"`
+	Password  config_util.Secret   `yaml:"password,omitempty"`
+	// UseTLS enables TLS in addition to SASL, configured by
+	// Authentication.TLSConfig.
+	UseTLS bool `yaml:"use_tls,omitempty"`
+}
+
+// Validate returns an error if c is not valid.
+func (c *SASLConfig) Validate() error {
+	switch c.Mechanism {
+	case sarama.SASLTypePlaintext, sarama.SASLTypeSCRAMSHA256, sarama.SASLTypeSCRAMSHA512:
+	default:
+		return fmt.Errorf("unsupported sasl mechanism %q", c.Mechanism)
+	}
+	if c.User == "" || c.Password == "" {
+		return fmt.Errorf("sasl authentication requires a user and a password")
+	}
+	return nil
+}
+
+// saramaConfig builds the sarama client config for c.
+func (c *Config) saramaConfig() (*sarama.Config, error) {
+	version, err := sarama.ParseKafkaVersion(c.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := sarama.NewConfig()
+	cfg.Version = version
+	cfg.ClientID = "grafana-agent"
+	cfg.Consumer.Offsets.Initial = sarama.OffsetOldest
+
+	if err := c.Authentication.Apply(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Apply configures cfg to authenticate to Kafka brokers according to a.
+func (a *Authentication) Apply(cfg *sarama.Config) error {
+	switch a.Type {
+	case AuthenticationTypeSSL:
+		return setTLS(cfg, a.TLSConfig)
+	case AuthenticationTypeSASL:
+		sasl := a.SASLConfig
+		cfg.Net.SASL.Enable = true
+		cfg.Net.SASL.Mechanism = sasl.Mechanism
+		cfg.Net.SASL.User = sasl.User
+		cfg.Net.SASL.Password = string(sasl.Password)
+		if strings.HasPrefix(string(sasl.Mechanism), "SCRAM") {
+			cfg.Net.SASL.SCRAMClientGeneratorFunc = scramClientGenerator(sasl.Mechanism)
+		}
+		if sasl.UseTLS {
+			return setTLS(cfg, a.TLSConfig)
+		}
+	}
+	return nil
+}
+
+func setTLS(cfg *sarama.Config, tlsConfig config_util.TLSConfig) error {
+	tc, err := config_util.NewTLSConfig(&tlsConfig)
+	if err != nil {
+		return fmt.Errorf("invalid tls_config: %w", err)
+	}
+	if tc.MinVersion == 0 {
+		tc.MinVersion = tls.VersionTLS12
+	}
+	cfg.Net.TLS.Enable = true
+	cfg.Net.TLS.Config = tc
+	return nil
+}
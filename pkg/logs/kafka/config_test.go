@@ -0,0 +1,100 @@
+package kafka
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig_Defaults(t *testing.T) {
+	in := `
+job_name: kafka
+brokers: [localhost:9092]
+topics: [logs]
+`
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(in), &cfg))
+	require.Equal(t, "grafana-agent", cfg.GroupID)
+	require.Equal(t, FormatRaw, cfg.Format)
+	require.Equal(t, AuthenticationTypeNone, cfg.Authentication.Type)
+
+	sc, err := cfg.saramaConfig()
+	require.NoError(t, err)
+	require.False(t, sc.Net.TLS.Enable)
+	require.False(t, sc.Net.SASL.Enable)
+}
+
+func TestConfig_SASL(t *testing.T) {
+	in := `
+job_name: kafka
+brokers: [localhost:9092]
+topics: [logs]
+authentication:
+  type: sasl
+  sasl_config:
+    mechanism: SCRAM-SHA-512
+    user: agent
+    password: hunter2
+    use_tls: true
+`
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte(in), &cfg))
+
+	sc, err := cfg.saramaConfig()
+	require.NoError(t, err)
+	require.True(t, sc.Net.SASL.Enable)
+	require.Equal(t, sarama.SASLMechanism(sarama.SASLTypeSCRAMSHA512), sc.Net.SASL.Mechanism)
+	require.Equal(t, "hunter2", sc.Net.SASL.Password)
+	require.NotNil(t, sc.Net.SASL.SCRAMClientGeneratorFunc)
+	require.True(t, sc.Net.TLS.Enable)
+	require.NoError(t, sc.Validate())
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tt := []struct {
+		name   string
+		in     string
+		expect string
+	}{
+		{
+			name:   "missing job name",
+			in:     `{brokers: [localhost:9092], topics: [logs]}`,
+			expect: "kafka config must have a job_name",
+		},
+		{
+			name:   "missing brokers",
+			in:     `{job_name: kafka, topics: [logs]}`,
+			expect: "kafka config kafka must have at least one broker",
+		},
+		{
+			name:   "missing topics",
+			in:     `{job_name: kafka, brokers: [localhost:9092]}`,
+			expect: "kafka config kafka must have at least one topic",
+		},
+		{
+			name:   "unknown format",
+			in:     `{job_name: kafka, brokers: [localhost:9092], topics: [logs], format: xml}`,
+			expect: `kafka config kafka has unknown format "xml"`,
+		},
+		{
+			name:   "unknown authentication",
+			in:     `{job_name: kafka, brokers: [localhost:9092], topics: [logs], authentication: {type: kerberos}}`,
+			expect: `kafka config kafka: unknown authentication type "kerberos"`,
+		},
+		{
+			name:   "sasl without password",
+			in:     `{job_name: kafka, brokers: [localhost:9092], topics: [logs], authentication: {type: sasl, sasl_config: {mechanism: PLAIN, user: agent}}}`,
+			expect: "kafka config kafka: sasl authentication requires a user and a password",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			err := yaml.UnmarshalStrict([]byte(tc.in), &cfg)
+			require.EqualError(t, err, tc.expect)
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package kafka
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics holds metrics for Kafka targets.
+type Metrics struct {
+	entries       *prometheus.CounterVec
+	decodeErrors  *prometheus.CounterVec
+	consumeErrors *prometheus.CounterVec
+}
+
+// NewMetrics creates a new set of metrics. Metrics will be registered to reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{}
+
+	m.entries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "kafka_target_entries_total",
+		Help:      "Total number of log lines read from Kafka.",
+	}, []string{"job", "topic"})
+
+	m.decodeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "kafka_target_decode_errors_total",
+		Help:      "Total number of Kafka messages which couldn't be decoded and were dropped.",
+	}, []string{"job", "topic"})
+
+	m.consumeErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "kafka_target_consume_errors_total",
+		Help:      "Total number of errors while consuming from the Kafka consumer group.",
+	}, []string{"job"})
+
+	if reg != nil {
+		reg.MustRegister(m.entries, m.decodeErrors, m.consumeErrors)
+	}
+	return m
+}
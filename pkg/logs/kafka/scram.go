@@ -0,0 +1,46 @@
+package kafka
+
+import (
+	"crypto/sha512"
+	"hash"
+
+	"github.com/Shopify/sarama"
+	"github.com/xdg-go/scram"
+)
+
+// scramClient implements sarama.SCRAMClient.
+type scramClient struct {
+	hashGenerator scram.HashGeneratorFcn
+
+	conversation *scram.ClientConversation
+}
+
+func scramClientGenerator(mechanism sarama.SASLMechanism) func() sarama.SCRAMClient {
+	hashGenerator := scram.SHA256
+	if mechanism == sarama.SASLTypeSCRAMSHA512 {
+		hashGenerator = func() hash.Hash { return sha512.New() }
+	}
+	return func() sarama.SCRAMClient {
+		return &scramClient{hashGenerator: hashGenerator}
+	}
+}
+
+// Begin implements sarama.SCRAMClient.
+func (c *scramClient) Begin(user, password, authzID string) error {
+	client, err := c.hashGenerator.NewClient(user, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.conversation = client.NewConversation()
+	return nil
+}
+
+// Step implements sarama.SCRAMClient.
+func (c *scramClient) Step(challenge string) (string, error) {
+	return c.conversation.Step(challenge)
+}
+
+// Done implements sarama.SCRAMClient.
+func (c *scramClient) Done() bool {
+	return c.conversation.Done()
+}
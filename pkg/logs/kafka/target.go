@@ -0,0 +1,221 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// Labels discovered for every message which can be used in relabel_configs.
+const (
+	metaLabelPrefix    = model.MetaLabelPrefix + "kafka_"
+	topicLabel         = metaLabelPrefix + "topic"
+	partitionLabel     = metaLabelPrefix + "partition"
+	groupIDLabel       = metaLabelPrefix + "group_id"
+	memberIDLabel      = metaLabelPrefix + "member_id"
+	messageKeyLabel    = metaLabelPrefix + "message_key"
+	consumeRetryPeriod = 5 * time.Second
+)
+
+// Target consumes log lines from the topics of a Kafka config as part of a
+// consumer group.
+type Target struct {
+	log     log.Logger
+	metrics *Metrics
+	cfg     *Config
+	handler api.EntryHandler
+	group   sarama.ConsumerGroup
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewTarget creates a new Target and starts consuming. Entries are sent to
+// handler, which is stopped when the Target is stopped.
+func NewTarget(l log.Logger, m *Metrics, handler api.EntryHandler, cfg *Config) (*Target, error) {
+	saramaConfig, err := cfg.saramaConfig()
+	if err != nil {
+		return nil, err
+	}
+	group, err := sarama.NewConsumerGroup(cfg.Brokers, cfg.GroupID, saramaConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer group: %w", err)
+	}
+
+	t := newTarget(l, m, handler, cfg, group)
+	t.wg.Add(1)
+	go t.run()
+	return t, nil
+}
+
+func newTarget(l log.Logger, m *Metrics, handler api.EntryHandler, cfg *Config, group sarama.ConsumerGroup) *Target {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Target{
+		log:     log.With(l, "job", cfg.JobName),
+		metrics: m,
+		cfg:     cfg,
+		handler: handler,
+		group:   group,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// run consumes from the group until the Target is stopped. Consume returns
+// whenever the group rebalances, so it's called in a loop.
+func (t *Target) run() {
+	defer t.wg.Done()
+
+	for t.ctx.Err() == nil {
+		err := t.group.Consume(t.ctx, t.cfg.Topics, t)
+		if err == nil || t.ctx.Err() != nil {
+			continue
+		}
+
+		level.Error(t.log).Log("msg", "failed to consume from kafka", "err", err)
+		t.metrics.consumeErrors.WithLabelValues(t.cfg.JobName).Inc()
+
+		select {
+		case <-t.ctx.Done():
+		case <-time.After(consumeRetryPeriod):
+		}
+	}
+}
+
+// Setup implements sarama.ConsumerGroupHandler.
+func (t *Target) Setup(sarama.ConsumerGroupSession) error { return nil }
+
+// Cleanup implements sarama.ConsumerGroupHandler.
+func (t *Target) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+// ConsumeClaim implements sarama.ConsumerGroupHandler. Messages are marked as
+// consumed once all of their entries have been passed to the handler.
+func (t *Target) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		for _, entry := range t.entries(session.MemberID(), msg) {
+			select {
+			case t.handler.Chan() <- entry:
+			case <-session.Context().Done():
+				return nil
+			}
+			t.metrics.entries.WithLabelValues(t.cfg.JobName, msg.Topic).Inc()
+		}
+		session.MarkMessage(msg, "")
+	}
+	return nil
+}
+
+// entries converts msg into log entries. Records of msg which are dropped by
+// relabeling are skipped.
+func (t *Target) entries(memberID string, msg *sarama.ConsumerMessage) []api.Entry {
+	decode := t.cfg.Decoder
+	if decode == nil {
+		decode = t.cfg.decode
+	}
+	records, err := decode(msg)
+	if err != nil {
+		level.Debug(t.log).Log("msg", "dropping kafka message", "topic", msg.Topic, "partition", msg.Partition, "offset", msg.Offset, "err", err)
+		t.metrics.decodeErrors.WithLabelValues(t.cfg.JobName, msg.Topic).Inc()
+		return nil
+	}
+
+	entries := make([]api.Entry, 0, len(records))
+	for _, r := range records {
+		lset := t.labels(memberID, msg, r.Labels)
+		if lset == nil {
+			continue
+		}
+
+		ts := time.Now()
+		if t.cfg.UseIncomingTimestamp {
+			switch {
+			case !r.Timestamp.IsZero():
+				ts = r.Timestamp
+			case !msg.Timestamp.IsZero():
+				ts = msg.Timestamp
+			}
+		}
+
+		entries = append(entries, api.Entry{
+			Labels: lset,
+			Entry:  logproto.Entry{Timestamp: ts, Line: r.Line},
+		})
+	}
+	return entries
+}
+
+// labels returns the labels of a record of msg after relabeling. Returns nil
+// if the record was dropped by relabeling.
+func (t *Target) labels(memberID string, msg *sarama.ConsumerMessage, recordLabels model.LabelSet) model.LabelSet {
+	discovered := map[string]string{
+		topicLabel:      msg.Topic,
+		partitionLabel:  strconv.Itoa(int(msg.Partition)),
+		groupIDLabel:    t.cfg.GroupID,
+		memberIDLabel:   memberID,
+		messageKeyLabel: string(msg.Key),
+	}
+	for k, v := range recordLabels {
+		discovered[string(k)] = string(v)
+	}
+
+	processed := labels.FromMap(discovered)
+	if len(t.cfg.RelabelConfigs) > 0 {
+		processed = relabel.Process(processed, t.cfg.RelabelConfigs...)
+		if processed == nil {
+			return nil
+		}
+	}
+
+	lset := make(model.LabelSet, len(t.cfg.Labels)+len(processed))
+	for _, l := range processed {
+		if strings.HasPrefix(l.Name, model.ReservedLabelPrefix) {
+			continue
+		}
+		lset[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+	for k, v := range t.cfg.Labels {
+		lset[k] = v
+	}
+	return lset
+}
+
+// decode decodes msg into a single record according to the format of c.
+func (c *Config) decode(msg *sarama.ConsumerMessage) ([]Record, error) {
+	if c.Format != FormatJSON {
+		return []Record{{Line: string(msg.Value)}}, nil
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(msg.Value, &obj); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+
+	var line string
+	if raw, ok := obj[c.JSON.MessageField]; ok && json.Unmarshal(raw, &line) == nil {
+		return []Record{{Line: line}}, nil
+	}
+	return []Record{{Line: string(msg.Value)}}, nil
+}
+
+// Stop stops consuming and stops the handler of the Target.
+func (t *Target) Stop() error {
+	t.cancel()
+	err := t.group.Close()
+	t.wg.Wait()
+	t.handler.Stop()
+	return err
+}
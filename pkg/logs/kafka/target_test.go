@@ -0,0 +1,146 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTarget_ConsumeClaim(t *testing.T) {
+	incoming := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name   string
+		cfg    Config
+		msgs   []*sarama.ConsumerMessage
+		expect []api.Entry
+	}{
+		{
+			name: "raw",
+			cfg: Config{
+				Format:               FormatRaw,
+				Labels:               model.LabelSet{"job": "kafka"},
+				UseIncomingTimestamp: true,
+			},
+			msgs: []*sarama.ConsumerMessage{
+				{Topic: "logs", Value: []byte("hello"), Timestamp: incoming},
+			},
+			expect: []api.Entry{
+				entry(model.LabelSet{"job": "kafka"}, incoming, "hello"),
+			},
+		},
+		{
+			name: "json",
+			cfg: Config{
+				Format:               FormatJSON,
+				JSON:                 JSONConfig{MessageField: "message"},
+				UseIncomingTimestamp: true,
+			},
+			msgs: []*sarama.ConsumerMessage{
+				{Topic: "logs", Value: []byte(`{"message": "hello", "level": "info"}`), Timestamp: incoming},
+				{Topic: "logs", Value: []byte(`not json`), Timestamp: incoming},
+				{Topic: "logs", Value: []byte(`{"msg": "no message field"}`), Timestamp: incoming},
+			},
+			expect: []api.Entry{
+				entry(model.LabelSet{}, incoming, "hello"),
+				entry(model.LabelSet{}, incoming, `{"msg": "no message field"}`),
+			},
+		},
+		{
+			name: "relabel",
+			cfg: Config{
+				Format:               FormatRaw,
+				UseIncomingTimestamp: true,
+				RelabelConfigs: []*relabel.Config{
+					{
+						SourceLabels: model.LabelNames{topicLabel},
+						Regex:        relabel.MustNewRegexp("debug"),
+						Action:       relabel.Drop,
+					},
+					{
+						SourceLabels: model.LabelNames{topicLabel},
+						Regex:        relabel.MustNewRegexp("(.*)"),
+						Replacement:  "$1",
+						TargetLabel:  "topic",
+						Action:       relabel.Replace,
+					},
+				},
+			},
+			msgs: []*sarama.ConsumerMessage{
+				{Topic: "debug", Value: []byte("dropped"), Timestamp: incoming},
+				{Topic: "logs", Value: []byte("kept"), Timestamp: incoming},
+			},
+			expect: []api.Entry{
+				entry(model.LabelSet{"topic": "logs"}, incoming, "kept"),
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.cfg.JobName = "kafka"
+			tc.cfg.GroupID = "grafana-agent"
+
+			entries := make(chan api.Entry, len(tc.msgs))
+			handler := api.NewEntryHandler(entries, func() {})
+			target := newTarget(log.NewNopLogger(), NewMetrics(nil), handler, &tc.cfg, nil)
+
+			msgs := make(chan *sarama.ConsumerMessage, len(tc.msgs))
+			for i, msg := range tc.msgs {
+				msg.Offset = int64(i)
+				msgs <- msg
+			}
+			close(msgs)
+
+			session := &fakeSession{ctx: context.Background()}
+			require.NoError(t, target.ConsumeClaim(session, &fakeClaim{msgs: msgs}))
+			close(entries)
+
+			var actual []api.Entry
+			for e := range entries {
+				actual = append(actual, e)
+			}
+			require.Equal(t, tc.expect, actual)
+
+			// Every message is marked, including the ones which were dropped.
+			require.Len(t, session.marked, len(tc.msgs))
+		})
+	}
+}
+
+func entry(lset model.LabelSet, ts time.Time, line string) api.Entry {
+	var e api.Entry
+	e.Labels = lset
+	e.Timestamp = ts
+	e.Line = line
+	return e
+}
+
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+
+	ctx    context.Context
+	marked []int64
+}
+
+func (s *fakeSession) MemberID() string         { return "member-1" }
+func (s *fakeSession) Context() context.Context { return s.ctx }
+
+func (s *fakeSession) MarkMessage(msg *sarama.ConsumerMessage, _ string) {
+	s.marked = append(s.marked, msg.Offset)
+}
+
+type fakeClaim struct {
+	sarama.ConsumerGroupClaim
+
+	msgs chan *sarama.ConsumerMessage
+}
+
+func (c *fakeClaim) Messages() <-chan *sarama.ConsumerMessage { return c.msgs }
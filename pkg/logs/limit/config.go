@@ -0,0 +1,81 @@
+package limit
+
+import "fmt"
+
+// Config configures rate limits of the entries of a logs instance. Limits
+// which are 0 are disabled. Entries over a limit are dropped.
+type Config struct {
+	// LinesPerSecond limits the number of entries of the instance.
+	LinesPerSecond float64 `yaml:"lines_per_second,omitempty"`
+	// LinesBurst is the number of entries which may exceed LinesPerSecond at
+	// once. Defaults to LinesPerSecond.
+	LinesBurst int `yaml:"lines_burst,omitempty"`
+
+	// BytesPerSecond limits the size of the lines of the instance.
+	BytesPerSecond float64 `yaml:"bytes_per_second,omitempty"`
+	// BytesBurst is the number of bytes which may exceed BytesPerSecond at
+	// once. Lines bigger than BytesBurst are always dropped. Defaults to
+	// BytesPerSecond.
+	BytesBurst int `yaml:"bytes_burst,omitempty"`
+
+	// StreamLinesPerSecond limits the number of entries of each stream.
+	StreamLinesPerSecond float64 `yaml:"stream_lines_per_second,omitempty"`
+	// StreamLinesBurst is the number of entries of a stream which may exceed
+	// StreamLinesPerSecond at once. Defaults to StreamLinesPerSecond.
+	StreamLinesBurst int `yaml:"stream_lines_burst,omitempty"`
+
+	// StreamBytesPerSecond limits the size of the lines of each stream.
+	StreamBytesPerSecond float64 `yaml:"stream_bytes_per_second,omitempty"`
+	// StreamBytesBurst is the number of bytes of a stream which may exceed
+	// StreamBytesPerSecond at once. Defaults to StreamBytesPerSecond.
+	StreamBytesBurst int `yaml:"stream_bytes_burst,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = Config{}
+
+	type config Config
+	if err := unmarshal((*config)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate returns an error if c is not valid.
+func (c *Config) Validate() error {
+	for _, l := range c.limits() {
+		if l.rate < 0 || l.burst < 0 {
+			return fmt.Errorf("%s and its burst must not be negative", l.name)
+		}
+	}
+	return nil
+}
+
+// Enabled returns true if any limit is enabled.
+func (c *Config) Enabled() bool {
+	for _, l := range c.limits() {
+		if l.rate > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// limitConfig is a single limit of Config.
+type limitConfig struct {
+	name   string
+	rate   float64
+	burst  int
+	stream bool // Limits each stream instead of the instance.
+	bytes  bool // Limits the size of lines instead of their number.
+}
+
+func (c *Config) limits() []limitConfig {
+	return []limitConfig{
+		{name: "stream_lines_per_second", rate: c.StreamLinesPerSecond, burst: c.StreamLinesBurst, stream: true},
+		{name: "stream_bytes_per_second", rate: c.StreamBytesPerSecond, burst: c.StreamBytesBurst, stream: true, bytes: true},
+		{name: "lines_per_second", rate: c.LinesPerSecond, burst: c.LinesBurst},
+		{name: "bytes_per_second", rate: c.BytesPerSecond, burst: c.BytesBurst, bytes: true},
+	}
+}
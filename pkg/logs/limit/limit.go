@@ -0,0 +1,197 @@
+// Package limit implements rate limits for the log entries of a logs
+// instance, so that a single noisy source can't flood the pipeline and Loki.
+package limit
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"golang.org/x/time/rate"
+)
+
+// streamIdleTimeout is the time after which the limiters of a stream which
+// didn't receive entries are removed.
+const streamIdleTimeout = time.Minute
+
+// limiter applies a single limit.
+type limiter struct {
+	cfg limitConfig
+	lim *rate.Limiter
+}
+
+func newLimiters(cfgs []limitConfig) []limiter {
+	res := make([]limiter, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		burst := cfg.burst
+		if burst == 0 {
+			burst = int(math.Max(1, math.Ceil(cfg.rate)))
+		}
+		res = append(res, limiter{cfg: cfg, lim: rate.NewLimiter(rate.Limit(cfg.rate), burst)})
+	}
+	return res
+}
+
+// stream holds the limiters of a stream.
+type stream struct {
+	limiters []limiter
+	lastSeen time.Time
+	warned   bool // Whether dropping entries of the stream was logged.
+}
+
+// Limiter is an api.EntryHandler which drops entries over the limits of its
+// config and sends the other entries to another api.EntryHandler.
+type Limiter struct {
+	log     log.Logger
+	metrics *Metrics
+	next    api.EntryHandler
+	now     func() time.Time
+
+	streamLimits []limitConfig
+	instance     []limiter
+	warned       bool // Whether dropping entries by an instance limit was logged.
+	streams      map[model.Fingerprint]*stream
+	lastSweep    time.Time
+
+	in   chan api.Entry
+	once sync.Once
+	done chan struct{}
+}
+
+// New creates a Limiter which sends entries within the limits of cfg to
+// next. Stopping the Limiter doesn't stop next.
+func New(l log.Logger, reg prometheus.Registerer, cfg Config, next api.EntryHandler) *Limiter {
+	lim := &Limiter{
+		log:     log.With(l, "component", "rate_limit"),
+		metrics: NewMetrics(reg),
+		next:    next,
+		now:     time.Now,
+		streams: map[model.Fingerprint]*stream{},
+		in:      make(chan api.Entry),
+		done:    make(chan struct{}),
+	}
+
+	var instanceLimits []limitConfig
+	for _, lc := range cfg.limits() {
+		switch {
+		case lc.rate == 0:
+			continue
+		case lc.stream:
+			lim.streamLimits = append(lim.streamLimits, lc)
+		default:
+			instanceLimits = append(instanceLimits, lc)
+		}
+	}
+	lim.instance = newLimiters(instanceLimits)
+
+	go lim.run()
+	return lim
+}
+
+func (l *Limiter) run() {
+	defer close(l.done)
+
+	for e := range l.in {
+		if l.allow(e) {
+			l.next.Chan() <- e
+		}
+	}
+}
+
+// allow returns true if e is within all limits. Limits of the stream of e
+// are checked first, so that a stream over its limit doesn't use up the
+// limits of the instance.
+func (l *Limiter) allow(e api.Entry) bool {
+	now := l.now()
+	l.sweep(now)
+
+	var s *stream
+	if len(l.streamLimits) > 0 {
+		fp := e.Labels.FastFingerprint()
+		s = l.streams[fp]
+		if s == nil {
+			s = &stream{limiters: newLimiters(l.streamLimits)}
+			l.streams[fp] = s
+			l.metrics.streams.Set(float64(len(l.streams)))
+		}
+		s.lastSeen = now
+	}
+
+	var limiters []limiter
+	if s != nil {
+		limiters = append(limiters, s.limiters...)
+	}
+	limiters = append(limiters, l.instance...)
+
+	reservations := make([]*rate.Reservation, 0, len(limiters))
+	for _, lim := range limiters {
+		n := 1
+		if lim.cfg.bytes {
+			n = len(e.Line)
+		}
+
+		r := lim.lim.ReserveN(now, n)
+		if r.OK() && r.DelayFrom(now) == 0 {
+			reservations = append(reservations, r)
+			continue
+		}
+
+		// Return the tokens of the limits which allowed e.
+		r.CancelAt(now)
+		for _, r := range reservations {
+			r.CancelAt(now)
+		}
+		l.drop(e, lim.cfg, s)
+		return false
+	}
+	return true
+}
+
+func (l *Limiter) drop(e api.Entry, cfg limitConfig, s *stream) {
+	l.metrics.droppedEntries.WithLabelValues(cfg.name).Inc()
+	l.metrics.droppedBytes.WithLabelValues(cfg.name).Add(float64(len(e.Line)))
+
+	// Dropping entries is logged once per stream or instance between sweeps
+	// to find noisy streams without flooding the logs.
+	switch {
+	case cfg.stream && !s.warned:
+		s.warned = true
+		level.Warn(l.log).Log("msg", "dropping entries of stream over rate limit", "limit", cfg.name, "labels", e.Labels)
+	case !cfg.stream && !l.warned:
+		l.warned = true
+		level.Warn(l.log).Log("msg", "dropping entries over rate limit", "limit", cfg.name)
+	}
+}
+
+// sweep removes the limiters of idle streams at most once per
+// streamIdleTimeout.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < streamIdleTimeout {
+		return
+	}
+	l.lastSweep = now
+
+	l.warned = false
+	for fp, s := range l.streams {
+		if now.Sub(s.lastSeen) >= streamIdleTimeout {
+			delete(l.streams, fp)
+			continue
+		}
+		s.warned = false
+	}
+	l.metrics.streams.Set(float64(len(l.streams)))
+}
+
+// Chan implements api.EntryHandler.
+func (l *Limiter) Chan() chan<- api.Entry { return l.in }
+
+// Stop implements api.EntryHandler.
+func (l *Limiter) Stop() {
+	l.once.Do(func() { close(l.in) })
+	<-l.done
+}
@@ -0,0 +1,103 @@
+package limit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func testEntry(pod, line string) api.Entry {
+	return api.Entry{
+		Labels: model.LabelSet{"pod": model.LabelValue(pod)},
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: line},
+	}
+}
+
+// newTestLimiter returns a Limiter whose clock is controlled by the returned
+// function, which advances the clock by the given duration.
+func newTestLimiter(t *testing.T, cfg Config) (*Limiter, func(time.Duration)) {
+	t.Helper()
+
+	l := New(log.NewNopLogger(), nil, cfg, api.NewEntryHandler(make(chan api.Entry), func() {}))
+	t.Cleanup(l.Stop)
+
+	now := time.Unix(1640995200, 0)
+	l.now = func() time.Time { return now }
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestConfig_Unmarshal(t *testing.T) {
+	var cfg Config
+	require.NoError(t, yaml.UnmarshalStrict([]byte("stream_lines_per_second: 10"), &cfg))
+	require.True(t, cfg.Enabled())
+
+	require.NoError(t, yaml.UnmarshalStrict([]byte("{}"), &cfg))
+	require.False(t, cfg.Enabled())
+
+	err := yaml.UnmarshalStrict([]byte("bytes_burst: -1"), &cfg)
+	require.EqualError(t, err, "bytes_per_second and its burst must not be negative")
+}
+
+func TestLimiter_Stream(t *testing.T) {
+	l, advance := newTestLimiter(t, Config{StreamLinesPerSecond: 2})
+
+	// A noisy stream doesn't affect other streams.
+	require.True(t, l.allow(testEntry("a", "1")))
+	require.True(t, l.allow(testEntry("a", "2")))
+	require.False(t, l.allow(testEntry("a", "3")))
+	require.True(t, l.allow(testEntry("b", "1")))
+
+	advance(500 * time.Millisecond)
+	require.True(t, l.allow(testEntry("a", "4")))
+	require.False(t, l.allow(testEntry("a", "5")))
+
+	require.Equal(t, 2.0, testutil.ToFloat64(l.metrics.droppedEntries.WithLabelValues("stream_lines_per_second")))
+	require.Equal(t, 2.0, testutil.ToFloat64(l.metrics.droppedBytes.WithLabelValues("stream_lines_per_second")))
+	require.Equal(t, 2.0, testutil.ToFloat64(l.metrics.streams))
+
+	// Limiters of idle streams are removed.
+	advance(streamIdleTimeout)
+	require.True(t, l.allow(testEntry("a", "6")))
+	require.Equal(t, 1.0, testutil.ToFloat64(l.metrics.streams))
+}
+
+func TestLimiter_Instance(t *testing.T) {
+	l, advance := newTestLimiter(t, Config{
+		StreamLinesPerSecond: 1,
+		BytesPerSecond:       10,
+		BytesBurst:           20,
+	})
+
+	require.True(t, l.allow(testEntry("a", strings.Repeat("x", 15))))
+	require.False(t, l.allow(testEntry("b", strings.Repeat("x", 15))), "instance bytes must be exceeded")
+
+	// The stream limit of b wasn't used by the dropped entry.
+	advance(time.Second)
+	require.True(t, l.allow(testEntry("b", strings.Repeat("x", 15))))
+
+	// Lines bigger than the burst are always dropped.
+	advance(time.Minute)
+	require.False(t, l.allow(testEntry("c", strings.Repeat("x", 21))))
+
+	require.Equal(t, 2.0, testutil.ToFloat64(l.metrics.droppedEntries.WithLabelValues("bytes_per_second")))
+	require.Equal(t, 36.0, testutil.ToFloat64(l.metrics.droppedBytes.WithLabelValues("bytes_per_second")))
+}
+
+func TestLimiter_Chan(t *testing.T) {
+	next := make(chan api.Entry, 10)
+	l := New(log.NewNopLogger(), nil, Config{StreamLinesPerSecond: 0.001, StreamLinesBurst: 2}, api.NewEntryHandler(next, func() {}))
+
+	for i := 0; i < 5; i++ {
+		l.Chan() <- testEntry("a", "line")
+	}
+	l.Stop()
+	require.Len(t, next, 2)
+}
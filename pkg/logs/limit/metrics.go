@@ -0,0 +1,35 @@
+package limit
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics holds metrics for rate limits.
+type Metrics struct {
+	droppedEntries *prometheus.CounterVec
+	droppedBytes   *prometheus.CounterVec
+	streams        prometheus.Gauge
+}
+
+// NewMetrics creates a new set of metrics. Metrics will be registered to reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{}
+
+	m.droppedEntries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agent_logs_rate_limited_entries_total",
+		Help: "Total number of log entries dropped by a rate limit.",
+	}, []string{"limit"})
+
+	m.droppedBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agent_logs_rate_limited_bytes_total",
+		Help: "Total size of the lines of log entries dropped by a rate limit.",
+	}, []string{"limit"})
+
+	m.streams = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "agent_logs_rate_limit_streams",
+		Help: "Number of streams tracked for per-stream rate limits.",
+	})
+
+	if reg != nil {
+		reg.MustRegister(m.droppedEntries, m.droppedBytes, m.streams)
+	}
+	return m
+}
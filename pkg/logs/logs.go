@@ -5,17 +5,28 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
+	"github.com/grafana/agent/pkg/logs/cloudflare"
+	"github.com/grafana/agent/pkg/logs/docker"
+	"github.com/grafana/agent/pkg/logs/fluentforward"
+	"github.com/grafana/agent/pkg/logs/gelf"
+	"github.com/grafana/agent/pkg/logs/heroku"
+	"github.com/grafana/agent/pkg/logs/kafka"
+	"github.com/grafana/agent/pkg/logs/limit"
+	"github.com/grafana/agent/pkg/logs/output"
+	"github.com/grafana/agent/pkg/logs/stages"
+	"github.com/grafana/agent/pkg/logs/syslog"
+	"github.com/grafana/agent/pkg/logs/wal"
 	"github.com/grafana/agent/pkg/util"
-	"github.com/grafana/loki/clients/pkg/promtail"
 	"github.com/grafana/loki/clients/pkg/promtail/api"
 	"github.com/grafana/loki/clients/pkg/promtail/client"
-	"github.com/grafana/loki/clients/pkg/promtail/config"
-	"github.com/grafana/loki/clients/pkg/promtail/server"
+	"github.com/grafana/loki/clients/pkg/promtail/targets"
+	lokiflag "github.com/grafana/loki/pkg/util/flagext"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/version"
 )
@@ -116,7 +127,19 @@ type Instance struct {
 	log log.Logger
 	reg *util.Unregisterer
 
-	promtail *promtail.Promtail
+	client     api.EntryHandler // Loki clients or the WAL.
+	fanout     *output.Fanout
+	limiter    *limit.Limiter
+	entries    api.EntryHandler // Receives the entries of all targets.
+	scrape     api.EntryHandler // Runs the agent stages of scrape_configs.
+	targets    *targets.TargetManagers
+	kafka      *kafka.TargetManager
+	cloudflare *cloudflare.TargetManager
+	heroku     *heroku.TargetManager
+	fluent     *fluentforward.TargetManager
+	gelf       *gelf.TargetManager
+	docker     *docker.TargetManager
+	syslog     *syslog.TargetManager
 }
 
 // NewInstance creates and starts a Logs instance.
@@ -153,10 +176,7 @@ func (i *Instance) ApplyConfig(c *InstanceConfig) error {
 		level.Warn(i.log).Log("msg", "failed to create the positions directory. logs may be unable to save their position", "path", positionsDir, "err", err)
 	}
 
-	if i.promtail != nil {
-		i.promtail.Shutdown()
-		i.promtail = nil
-	}
+	i.stop()
 
 	// Unregister all existing metrics before trying to create a new instance.
 	if !i.reg.UnregisterAll() {
@@ -170,32 +190,115 @@ func (i *Instance) ApplyConfig(c *InstanceConfig) error {
 		return nil
 	}
 
-	p, err := promtail.New(config.Config{
-		ServerConfig:    server.Config{Disable: true},
-		ClientConfigs:   c.ClientConfigs,
-		PositionsConfig: c.PositionsConfig,
-		ScrapeConfig:    c.ScrapeConfig,
-		TargetConfig:    c.TargetConfig,
-	}, false, promtail.WithLogger(i.log), promtail.WithRegisterer(i.reg))
+	// Entries of all targets and SendEntry go through the rate limits and the
+	// outputs before they're sent to the clients. Promtail always creates its
+	// own clients, so the clients and the targets of scrape_configs are
+	// created without Promtail.
+	var cl api.EntryHandler
+	if c.WAL.Enabled {
+		cl, err = wal.NewClient(i.log, i.reg, c.WAL, c.ClientConfigs)
+	} else {
+		// Like Promtail, metrics of the clients are registered globally.
+		cl, err = client.NewMulti(prometheus.DefaultRegisterer, i.log, lokiflag.LabelSet{}, c.ClientConfigs...)
+	}
 	if err != nil {
+		return fmt.Errorf("unable to create logs clients: %w", err)
+	}
+	i.client = cl
+
+	f, err := output.NewFanout(i.log, i.reg, cl, c.Outputs)
+	if err != nil {
+		i.stop()
+		return fmt.Errorf("unable to create log outputs: %w", err)
+	}
+	i.fanout = f
+	i.entries = f
+
+	if c.Limits.Enabled() {
+		i.limiter = limit.New(i.log, i.reg, c.Limits, f)
+		i.entries = i.limiter
+	}
+
+	scrapeConfigs, scrape, err := stages.WrapScrapeConfigs(i.log, i.reg, c.ScrapeConfig, i.entries)
+	if err != nil {
+		i.stop()
 		return fmt.Errorf("unable to create logs instance: %w", err)
 	}
+	i.scrape = scrape
 
-	i.promtail = p
+	tms, err := targets.NewTargetManagers(stdinShutdown{}, i.reg, i.log, c.PositionsConfig, i.scrape, scrapeConfigs, &c.TargetConfig)
+	if err != nil {
+		i.stop()
+		return fmt.Errorf("unable to create logs instance: %w", err)
+	}
+	i.targets = tms
+
+	km, err := kafka.NewTargetManager(i.reg, i.log, i.entries, c.kafkaConfigs())
+	if err != nil {
+		i.stop()
+		return fmt.Errorf("unable to create kafka targets: %w", err)
+	}
+	i.kafka = km
+
+	// Cloudflare targets store their positions next to the positions file
+	// of Promtail, which only supports positions of files and journals.
+	positionsFile := c.PositionsConfig.PositionsFile
+	positionsPrefix := strings.TrimSuffix(positionsFile, filepath.Ext(positionsFile)) + "-cloudflare-"
+	cm, err := cloudflare.NewTargetManager(i.reg, i.log, i.entries, positionsPrefix, c.CloudflareConfigs)
+	if err != nil {
+		i.stop()
+		return fmt.Errorf("unable to create cloudflare targets: %w", err)
+	}
+	i.cloudflare = cm
+
+	hm, err := heroku.NewTargetManager(i.reg, i.log, i.entries, c.HerokuDrainConfigs)
+	if err != nil {
+		i.stop()
+		return fmt.Errorf("unable to create heroku drain targets: %w", err)
+	}
+	i.heroku = hm
+
+	fm, err := fluentforward.NewTargetManager(i.reg, i.log, i.entries, c.FluentForwardConfigs)
+	if err != nil {
+		i.stop()
+		return fmt.Errorf("unable to create fluent forward targets: %w", err)
+	}
+	i.fluent = fm
+
+	gm, err := gelf.NewTargetManager(i.reg, i.log, i.entries, c.GELFConfigs)
+	if err != nil {
+		i.stop()
+		return fmt.Errorf("unable to create gelf targets: %w", err)
+	}
+	i.gelf = gm
+
+	dm, err := docker.NewTargetManager(i.reg, i.log, i.entries, c.DockerConfigs)
+	if err != nil {
+		i.stop()
+		return fmt.Errorf("unable to create docker targets: %w", err)
+	}
+	i.docker = dm
+
+	sm, err := syslog.NewTargetManager(i.reg, i.log, i.entries, c.SyslogConfigs)
+	if err != nil {
+		i.stop()
+		return fmt.Errorf("unable to create syslog targets: %w", err)
+	}
+	i.syslog = sm
 	return nil
 }
 
-// SendEntry passes an entry to the internal promtail client and returns true if successfully sent. It is
-// best effort and not guaranteed to succeed.
+// SendEntry passes an entry to the clients and the outputs of the instance and returns true if successfully sent.
+// It is best effort and not guaranteed to succeed.
 func (i *Instance) SendEntry(entry api.Entry, dur time.Duration) bool {
 	i.mut.Lock()
 	defer i.mut.Unlock()
 
-	// promtail is nil it has been stopped
-	if i.promtail != nil {
+	// entries is nil it has been stopped
+	if i.entries != nil {
 		// send non blocking so we don't block the mutex. this is best effort
 		select {
-		case i.promtail.Client().Chan() <- entry:
+		case i.entries.Chan() <- entry:
 			return true
 		case <-time.After(dur):
 		}
@@ -209,8 +312,66 @@ func (i *Instance) Stop() {
 	i.mut.Lock()
 	defer i.mut.Unlock()
 
-	if i.promtail != nil {
-		i.promtail.Shutdown()
-		i.promtail = nil
+	i.stop()
+}
+
+// stop stops the targets, the rate limits, the outputs, and then the clients.
+// Each is stopped before what it sends entries to. i.mut must be held when
+// calling stop.
+func (i *Instance) stop() {
+	if i.syslog != nil {
+		i.syslog.Stop()
+		i.syslog = nil
+	}
+	if i.docker != nil {
+		i.docker.Stop()
+		i.docker = nil
+	}
+	if i.gelf != nil {
+		i.gelf.Stop()
+		i.gelf = nil
+	}
+	if i.fluent != nil {
+		i.fluent.Stop()
+		i.fluent = nil
+	}
+	if i.heroku != nil {
+		i.heroku.Stop()
+		i.heroku = nil
+	}
+	if i.cloudflare != nil {
+		i.cloudflare.Stop()
+		i.cloudflare = nil
+	}
+	if i.kafka != nil {
+		i.kafka.Stop()
+		i.kafka = nil
+	}
+	if i.targets != nil {
+		i.targets.Stop()
+		i.targets = nil
+	}
+	if i.scrape != nil {
+		i.scrape.Stop()
+		i.scrape = nil
+	}
+	if i.limiter != nil {
+		i.limiter.Stop()
+		i.limiter = nil
+	}
+	if i.fanout != nil {
+		i.fanout.Stop()
+		i.fanout = nil
+	}
+	i.entries = nil
+	if i.client != nil {
+		i.client.Stop()
+		i.client = nil
 	}
 }
+
+// stdinShutdown implements stdin.Shutdownable for targets which read from
+// stdin. Unlike Promtail, the agent keeps running after stdin is read.
+type stdinShutdown struct{}
+
+func (stdinShutdown) Shutdown() {}
@@ -0,0 +1,184 @@
+package output
+
+import (
+	"fmt"
+
+	"github.com/Shopify/sarama"
+	"github.com/grafana/agent/pkg/logs/kafka"
+)
+
+// Format of entries written to an output.
+type Format string
+
+// Supported formats.
+const (
+	// FormatJSON writes each entry as a JSON object holding its labels,
+	// timestamp, and line.
+	FormatJSON Format = "json"
+
+	// FormatRaw writes only the line of each entry.
+	FormatRaw Format = "raw"
+)
+
+// defaultBufferSize is the default number of entries buffered for an output.
+const defaultBufferSize = 10000
+
+// Config configures outputs which receive the entries of a logs instance in
+// addition to its clients.
+type Config struct {
+	// Kafka outputs produce entries to Kafka topics.
+	Kafka []KafkaConfig `yaml:"kafka,omitempty"`
+
+	// File outputs write entries to local files.
+	File []FileConfig `yaml:"file,omitempty"`
+}
+
+// Validate returns an error if c is not valid.
+func (c *Config) Validate() error {
+	for i := range c.Kafka {
+		if err := c.Kafka[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for i := range c.File {
+		if err := c.File[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DefaultKafkaConfig holds the default settings for a Kafka output.
+var DefaultKafkaConfig = KafkaConfig{
+	Version:    sarama.V2_2_0_0.String(),
+	Format:     FormatJSON,
+	BufferSize: defaultBufferSize,
+	Authentication: kafka.Authentication{
+		Type: kafka.AuthenticationTypeNone,
+	},
+}
+
+// KafkaConfig configures an output which produces entries to a Kafka topic.
+type KafkaConfig struct {
+	// Brokers is the list of host:port addresses of Kafka brokers to bootstrap
+	// from. Required.
+	Brokers []string `yaml:"brokers"`
+
+	// Topic to produce to. Required.
+	Topic string `yaml:"topic"`
+
+	// Version is the Kafka protocol version to use.
+	Version string `yaml:"version,omitempty"`
+
+	// Authentication configures how to connect to the brokers.
+	Authentication kafka.Authentication `yaml:"authentication,omitempty"`
+
+	// Format of the message values: json or raw. Messages are keyed by the
+	// labels of their entries.
+	Format Format `yaml:"format,omitempty"`
+
+	// BufferSize is the number of entries buffered for the output. Entries
+	// are dropped for the output when the buffer is full.
+	BufferSize int `yaml:"buffer_size,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *KafkaConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultKafkaConfig
+
+	type config KafkaConfig
+	if err := unmarshal((*config)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate returns an error if c is not valid.
+func (c *KafkaConfig) Validate() error {
+	switch {
+	case len(c.Brokers) == 0:
+		return fmt.Errorf("kafka output must have at least one broker")
+	case c.Topic == "":
+		return fmt.Errorf("kafka output must have a topic")
+	case c.BufferSize <= 0:
+		return fmt.Errorf("kafka output %s must have a positive buffer_size", c.Topic)
+	}
+
+	if _, err := sarama.ParseKafkaVersion(c.Version); err != nil {
+		return fmt.Errorf("kafka output %s has invalid version: %w", c.Topic, err)
+	}
+	if err := validateFormat(c.Format); err != nil {
+		return fmt.Errorf("kafka output %s: %w", c.Topic, err)
+	}
+	if err := c.Authentication.Validate(); err != nil {
+		return fmt.Errorf("kafka output %s: %w", c.Topic, err)
+	}
+	return nil
+}
+
+// DefaultFileConfig holds the default settings for a file output.
+var DefaultFileConfig = FileConfig{
+	Format:     FormatJSON,
+	MaxSizeMB:  100,
+	MaxFiles:   5,
+	BufferSize: defaultBufferSize,
+}
+
+// FileConfig configures an output which writes entries to a local file, one
+// entry per line.
+type FileConfig struct {
+	// Path of the file to write to. Required.
+	Path string `yaml:"path"`
+
+	// Format of the lines: json or raw.
+	Format Format `yaml:"format,omitempty"`
+
+	// MaxSizeMB is the size in megabytes after which the file is rotated.
+	MaxSizeMB int `yaml:"max_size_mb,omitempty"`
+
+	// MaxFiles is the number of rotated files to keep, named after Path with
+	// a .1, .2, ... suffix.
+	MaxFiles int `yaml:"max_files,omitempty"`
+
+	// BufferSize is the number of entries buffered for the output. Entries
+	// are dropped for the output when the buffer is full.
+	BufferSize int `yaml:"buffer_size,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *FileConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultFileConfig
+
+	type config FileConfig
+	if err := unmarshal((*config)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate returns an error if c is not valid.
+func (c *FileConfig) Validate() error {
+	switch {
+	case c.Path == "":
+		return fmt.Errorf("file output must have a path")
+	case c.MaxSizeMB <= 0:
+		return fmt.Errorf("file output %s must have a positive max_size_mb", c.Path)
+	case c.MaxFiles < 0:
+		return fmt.Errorf("file output %s must not have a negative max_files", c.Path)
+	case c.BufferSize <= 0:
+		return fmt.Errorf("file output %s must have a positive buffer_size", c.Path)
+	}
+	if err := validateFormat(c.Format); err != nil {
+		return fmt.Errorf("file output %s: %w", c.Path, err)
+	}
+	return nil
+}
+
+func validateFormat(f Format) error {
+	switch f {
+	case FormatJSON, FormatRaw:
+		return nil
+	default:
+		return fmt.Errorf("unsupported format %q", f)
+	}
+}
@@ -0,0 +1,118 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+)
+
+// fileWriter writes entries to a file, one entry per line. The file is
+// rotated once it grows past its maximum size.
+type fileWriter struct {
+	cfg     FileConfig
+	maxSize int64
+
+	f    *os.File
+	buf  *bufio.Writer
+	size int64
+}
+
+func newFileWriter(cfg FileConfig) (*fileWriter, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory of file output %s: %w", cfg.Path, err)
+	}
+
+	w := &fileWriter{cfg: cfg, maxSize: int64(cfg.MaxSizeMB) * 1024 * 1024}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func fileOutputName(cfg FileConfig) string { return "file:" + cfg.Path }
+
+func (w *fileWriter) open() error {
+	f, err := os.OpenFile(w.cfg.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open file output: %w", err)
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open file output: %w", err)
+	}
+
+	w.f, w.buf, w.size = f, bufio.NewWriter(f), fi.Size()
+	return nil
+}
+
+// Write implements writer.
+func (w *fileWriter) Write(e api.Entry) error {
+	line, err := encode(e, w.cfg.Format)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if w.size > 0 && w.size+int64(len(line)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := w.buf.Write(line)
+	w.size += int64(n)
+	return err
+}
+
+// rotate closes the current file, shifts the names of rotated files, and
+// opens a new file. The oldest file is removed once there are more than
+// MaxFiles rotated files.
+func (w *fileWriter) rotate() error {
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	if w.cfg.MaxFiles == 0 {
+		if err := os.Remove(w.cfg.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return w.open()
+	}
+
+	oldest := rotatedPath(w.cfg.Path, w.cfg.MaxFiles)
+	if err := os.Remove(oldest); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := w.cfg.MaxFiles - 1; i >= 1; i-- {
+		err := os.Rename(rotatedPath(w.cfg.Path, i), rotatedPath(w.cfg.Path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(w.cfg.Path, rotatedPath(w.cfg.Path, 1)); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+func rotatedPath(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+// Flush implements writer.
+func (w *fileWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+// Close implements writer.
+func (w *fileWriter) Close() error {
+	if err := w.buf.Flush(); err != nil {
+		w.f.Close()
+		return err
+	}
+	return w.f.Close()
+}
@@ -0,0 +1,89 @@
+package output
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+)
+
+// kafkaWriter produces entries to a Kafka topic.
+type kafkaWriter struct {
+	log      log.Logger
+	metrics  *Metrics
+	cfg      KafkaConfig
+	producer sarama.AsyncProducer
+	wg       sync.WaitGroup
+}
+
+func newKafkaWriter(l log.Logger, m *Metrics, cfg KafkaConfig) (*kafkaWriter, error) {
+	version, err := sarama.ParseKafkaVersion(cfg.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := sarama.NewConfig()
+	sc.Version = version
+	sc.ClientID = "grafana-agent"
+	sc.Producer.Return.Errors = true
+	if err := cfg.Authentication.Apply(sc); err != nil {
+		return nil, fmt.Errorf("kafka output %s: %w", cfg.Topic, err)
+	}
+
+	producer, err := sarama.NewAsyncProducer(cfg.Brokers, sc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kafka output %s: %w", cfg.Topic, err)
+	}
+	return startKafkaWriter(l, m, cfg, producer), nil
+}
+
+// startKafkaWriter creates a kafkaWriter which produces to producer. Errors
+// returned by producer are logged and counted.
+func startKafkaWriter(l log.Logger, m *Metrics, cfg KafkaConfig, producer sarama.AsyncProducer) *kafkaWriter {
+	name := kafkaOutputName(cfg)
+	w := &kafkaWriter{
+		log:      log.With(l, "output", name),
+		metrics:  m,
+		cfg:      cfg,
+		producer: producer,
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		for err := range producer.Errors() {
+			level.Warn(w.log).Log("msg", "failed to produce entry", "err", err)
+			w.metrics.errors.WithLabelValues(name).Inc()
+		}
+	}()
+	return w
+}
+
+func kafkaOutputName(cfg KafkaConfig) string { return "kafka:" + cfg.Topic }
+
+// Write implements writer. Entries are produced asynchronously.
+func (w *kafkaWriter) Write(e api.Entry) error {
+	value, err := encode(e, w.cfg.Format)
+	if err != nil {
+		return err
+	}
+	w.producer.Input() <- &sarama.ProducerMessage{
+		Topic: w.cfg.Topic,
+		Key:   sarama.StringEncoder(e.Labels.String()),
+		Value: sarama.ByteEncoder(value),
+	}
+	return nil
+}
+
+// Flush implements writer. The producer flushes messages on its own.
+func (w *kafkaWriter) Flush() error { return nil }
+
+// Close implements writer.
+func (w *kafkaWriter) Close() error {
+	w.producer.AsyncClose()
+	w.wg.Wait()
+	return nil
+}
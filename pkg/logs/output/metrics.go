@@ -0,0 +1,35 @@
+package output
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics holds metrics for outputs.
+type Metrics struct {
+	entries *prometheus.CounterVec
+	dropped *prometheus.CounterVec
+	errors  *prometheus.CounterVec
+}
+
+// NewMetrics creates a new set of metrics. Metrics will be registered to reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{}
+
+	m.entries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agent_logs_output_entries_total",
+		Help: "Total number of log entries written to an output.",
+	}, []string{"output"})
+
+	m.dropped = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agent_logs_output_dropped_entries_total",
+		Help: "Total number of log entries dropped because the buffer of an output was full.",
+	}, []string{"output"})
+
+	m.errors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agent_logs_output_errors_total",
+		Help: "Total number of log entries which failed to be written to an output.",
+	}, []string{"output"})
+
+	if reg != nil {
+		reg.MustRegister(m.entries, m.dropped, m.errors)
+	}
+	return m
+}
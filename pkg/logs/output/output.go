@@ -0,0 +1,175 @@
+// Package output implements outputs which receive the log entries of a logs
+// instance in addition to its Loki clients.
+package output
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// writer writes entries to an output.
+type writer interface {
+	// Write writes a single entry.
+	Write(e api.Entry) error
+	// Flush flushes buffered entries. It's called whenever no more entries
+	// are queued.
+	Flush() error
+	// Close flushes buffered entries and closes the writer.
+	Close() error
+}
+
+// Fanout is an api.EntryHandler which sends entries to a client and to a set
+// of outputs. Sending to the client blocks as it would without outputs. Each
+// output has its own bounded queue, so a slow output drops its own entries
+// rather than blocking the client or other outputs.
+type Fanout struct {
+	in     chan api.Entry
+	queues []*queue
+	once   sync.Once
+	done   chan struct{}
+}
+
+// NewFanout creates a Fanout which sends entries to client and the outputs
+// of cfg. Stopping the Fanout stops the outputs but not client.
+func NewFanout(l log.Logger, reg prometheus.Registerer, client api.EntryHandler, cfg Config) (*Fanout, error) {
+	f := &Fanout{
+		in:   make(chan api.Entry),
+		done: make(chan struct{}),
+	}
+
+	var metrics *Metrics
+	if len(cfg.Kafka) > 0 || len(cfg.File) > 0 {
+		metrics = NewMetrics(reg)
+	}
+	for _, kc := range cfg.Kafka {
+		w, err := newKafkaWriter(l, metrics, kc)
+		if err != nil {
+			f.stopQueues()
+			return nil, err
+		}
+		f.queues = append(f.queues, newQueue(l, metrics, kafkaOutputName(kc), w, kc.BufferSize))
+	}
+	for _, fc := range cfg.File {
+		w, err := newFileWriter(fc)
+		if err != nil {
+			f.stopQueues()
+			return nil, err
+		}
+		f.queues = append(f.queues, newQueue(l, metrics, fileOutputName(fc), w, fc.BufferSize))
+	}
+
+	go func() {
+		defer close(f.done)
+		for e := range f.in {
+			client.Chan() <- e
+			for _, q := range f.queues {
+				q.enqueue(e)
+			}
+		}
+	}()
+	return f, nil
+}
+
+// Chan implements api.EntryHandler.
+func (f *Fanout) Chan() chan<- api.Entry { return f.in }
+
+// Stop implements api.EntryHandler. Entries queued for outputs are written
+// before Stop returns.
+func (f *Fanout) Stop() {
+	f.once.Do(func() { close(f.in) })
+	<-f.done
+	f.stopQueues()
+}
+
+func (f *Fanout) stopQueues() {
+	for _, q := range f.queues {
+		q.stop()
+	}
+	f.queues = nil
+}
+
+// queue writes entries to a writer from its own goroutine.
+type queue struct {
+	log     log.Logger
+	metrics *Metrics
+	name    string
+	w       writer
+	ch      chan api.Entry
+	done    chan struct{}
+}
+
+func newQueue(l log.Logger, m *Metrics, name string, w writer, size int) *queue {
+	q := &queue{
+		log:     log.With(l, "output", name),
+		metrics: m,
+		name:    name,
+		w:       w,
+		ch:      make(chan api.Entry, size),
+		done:    make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// enqueue queues e to be written, dropping it if the queue is full.
+func (q *queue) enqueue(e api.Entry) {
+	select {
+	case q.ch <- e:
+	default:
+		q.metrics.dropped.WithLabelValues(q.name).Inc()
+	}
+}
+
+func (q *queue) run() {
+	defer close(q.done)
+
+	for e := range q.ch {
+		if err := q.w.Write(e); err != nil {
+			level.Warn(q.log).Log("msg", "failed to write entry", "err", err)
+			q.metrics.errors.WithLabelValues(q.name).Inc()
+		} else {
+			q.metrics.entries.WithLabelValues(q.name).Inc()
+		}
+
+		if len(q.ch) == 0 {
+			if err := q.w.Flush(); err != nil {
+				level.Warn(q.log).Log("msg", "failed to flush entries", "err", err)
+			}
+		}
+	}
+}
+
+// stop writes all queued entries and closes the writer.
+func (q *queue) stop() {
+	close(q.ch)
+	<-q.done
+	if err := q.w.Close(); err != nil {
+		level.Warn(q.log).Log("msg", "failed to close output", "err", err)
+	}
+}
+
+// jsonEntry is the JSON format of an entry.
+type jsonEntry struct {
+	Labels    map[string]string `json:"labels"`
+	Timestamp time.Time         `json:"timestamp"`
+	Line      string            `json:"line"`
+}
+
+// encode returns e in the given format.
+func encode(e api.Entry, format Format) ([]byte, error) {
+	if format == FormatRaw {
+		return []byte(e.Line), nil
+	}
+
+	labels := make(map[string]string, len(e.Labels))
+	for name, value := range e.Labels {
+		labels[string(name)] = string(value)
+	}
+	return json.Marshal(jsonEntry{Labels: labels, Timestamp: e.Timestamp, Line: e.Line})
+}
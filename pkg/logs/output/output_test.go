@@ -0,0 +1,168 @@
+package output
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/mocks"
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func testEntry(line string) api.Entry {
+	return api.Entry{
+		Labels: model.LabelSet{"job": "test"},
+		Entry:  logproto.Entry{Timestamp: time.Unix(1640995200, 0).UTC(), Line: line},
+	}
+}
+
+func TestConfig_Unmarshal(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+kafka:
+- brokers: [localhost:9092]
+  topic: logs
+file:
+- path: /var/log/agent/logs.jsonl
+  format: raw
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, FormatJSON, cfg.Kafka[0].Format)
+	require.Equal(t, defaultBufferSize, cfg.Kafka[0].BufferSize)
+	require.Equal(t, FormatRaw, cfg.File[0].Format)
+	require.Equal(t, 100, cfg.File[0].MaxSizeMB)
+
+	for _, invalid := range []string{
+		"kafka:\n- topic: logs",
+		"kafka:\n- brokers: [localhost:9092]\n  topic: logs\n  format: xml",
+		"file:\n- max_size_mb: 1",
+		"file:\n- path: /tmp/logs\n  buffer_size: 0",
+	} {
+		require.Error(t, yaml.UnmarshalStrict([]byte(invalid), &cfg), invalid)
+	}
+}
+
+func TestFanout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs.jsonl")
+	fc := DefaultFileConfig
+	fc.Path = path
+
+	client := make(chan api.Entry, 10)
+	f, err := NewFanout(log.NewNopLogger(), prometheus.NewRegistry(), api.NewEntryHandler(client, func() {}), Config{
+		File: []FileConfig{fc},
+	})
+	require.NoError(t, err)
+
+	f.Chan() <- testEntry("hello")
+	f.Chan() <- testEntry("world")
+	f.Stop()
+
+	require.Len(t, client, 2)
+	bb, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, `{"labels":{"job":"test"},"timestamp":"2022-01-01T00:00:00Z","line":"hello"}
+{"labels":{"job":"test"},"timestamp":"2022-01-01T00:00:00Z","line":"world"}
+`, string(bb))
+}
+
+// blockingWriter blocks all writes until unblock is closed.
+type blockingWriter struct{ unblock chan struct{} }
+
+func (w *blockingWriter) Write(api.Entry) error { <-w.unblock; return nil }
+func (w *blockingWriter) Flush() error          { return nil }
+func (w *blockingWriter) Close() error          { return nil }
+
+func TestFanout_SlowOutput(t *testing.T) {
+	metrics := NewMetrics(nil)
+	w := &blockingWriter{unblock: make(chan struct{})}
+
+	client := make(chan api.Entry, 10)
+	f, err := NewFanout(log.NewNopLogger(), nil, api.NewEntryHandler(client, func() {}), Config{})
+	require.NoError(t, err)
+	f.queues = []*queue{newQueue(log.NewNopLogger(), metrics, "slow", w, 1)}
+
+	// The slow output doesn't block the client. One entry is being written,
+	// one is queued, and the others are dropped.
+	for i := 0; i < 5; i++ {
+		f.Chan() <- testEntry("line")
+	}
+	require.Eventually(t, func() bool { return len(client) == 5 }, time.Second, 10*time.Millisecond)
+
+	close(w.unblock)
+	f.Stop()
+	require.GreaterOrEqual(t, testutil.ToFloat64(metrics.dropped.WithLabelValues("slow")), 2.0)
+	require.Equal(t, 5.0, testutil.ToFloat64(metrics.dropped.WithLabelValues("slow"))+testutil.ToFloat64(metrics.entries.WithLabelValues("slow")))
+}
+
+func TestFileWriter_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs")
+	cfg := DefaultFileConfig
+	cfg.Path = path
+	cfg.Format = FormatRaw
+	cfg.MaxFiles = 2
+
+	w, err := newFileWriter(cfg)
+	require.NoError(t, err)
+	w.maxSize = 12
+
+	for _, line := range []string{"one", "two", "three", "four", "five", "six", "seven"} {
+		require.NoError(t, w.Write(testEntry(line)))
+	}
+	require.NoError(t, w.Close())
+
+	for file, expect := range map[string]string{
+		path:                 "seven\n",
+		rotatedPath(path, 1): "five\nsix\n",
+		rotatedPath(path, 2): "three\nfour\n",
+		rotatedPath(path, 3): "",
+	} {
+		bb, err := os.ReadFile(file)
+		if expect == "" {
+			require.True(t, os.IsNotExist(err), file)
+			continue
+		}
+		require.NoError(t, err)
+		require.Equal(t, expect, string(bb), file)
+	}
+}
+
+func TestKafkaWriter(t *testing.T) {
+	cfg := DefaultKafkaConfig
+	cfg.Brokers = []string{"localhost:9092"}
+	cfg.Topic = "logs"
+
+	producer := mocks.NewAsyncProducer(t, nil)
+	producer.ExpectInputWithMessageCheckerFunctionAndSucceed(func(msg *sarama.ProducerMessage) error {
+		key, _ := msg.Key.Encode()
+		value, _ := msg.Value.Encode()
+		switch {
+		case msg.Topic != "logs":
+			return errors.New("unexpected topic " + msg.Topic)
+		case string(key) != `{job="test"}`:
+			return errors.New("unexpected key " + string(key))
+		case !strings.Contains(string(value), `"line":"hello"`):
+			return errors.New("unexpected value " + string(value))
+		}
+		return nil
+	})
+	producer.ExpectInputAndFail(errors.New("broker unavailable"))
+
+	metrics := NewMetrics(nil)
+	w := startKafkaWriter(log.NewNopLogger(), metrics, cfg, producer)
+	require.NoError(t, w.Write(testEntry("hello")))
+	require.NoError(t, w.Write(testEntry("world")))
+	require.NoError(t, w.Close())
+
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.errors.WithLabelValues("kafka:logs")))
+}
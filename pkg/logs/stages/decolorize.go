@@ -0,0 +1,39 @@
+package stages
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+)
+
+// ansiEscape matches ANSI escape sequences, such as the SGR sequences used to
+// color terminal output.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-?]*[ -/]*[@-~]|\x1b[@-Z\\-_]`)
+
+// decolorizeStage removes ANSI escape sequences from log lines.
+type decolorizeStage struct{}
+
+func newDecolorizeStage(cfg interface{}) (stages.Stage, error) {
+	switch cfg := cfg.(type) {
+	case nil:
+	case map[interface{}]interface{}:
+		if len(cfg) > 0 {
+			return nil, fmt.Errorf("decolorize stage doesn't take any options")
+		}
+	default:
+		return nil, fmt.Errorf("decolorize stage doesn't take any options")
+	}
+	return decolorizeStage{}, nil
+}
+
+// Run implements stages.Stage.
+func (decolorizeStage) Run(in chan stages.Entry) chan stages.Entry {
+	return stages.RunWith(in, func(e stages.Entry) stages.Entry {
+		e.Line = ansiEscape.ReplaceAllString(e.Line, "")
+		return e
+	})
+}
+
+// Name implements stages.Stage.
+func (decolorizeStage) Name() string { return StageTypeDecolorize }
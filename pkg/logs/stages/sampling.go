@@ -0,0 +1,88 @@
+package stages
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/mitchellh/mapstructure"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SamplingConfig configures a sampling stage.
+type SamplingConfig struct {
+	// Rate is the probability of keeping each log line, from 0 to 1.
+	Rate float64 `mapstructure:"rate"`
+
+	// DropReason is the reason label of the dropped lines metric for lines
+	// dropped by the stage.
+	DropReason string `mapstructure:"drop_counter_reason"`
+}
+
+// samplingStage keeps a random subset of log lines.
+type samplingStage struct {
+	cfg       SamplingConfig
+	rand      *rand.Rand
+	dropCount *prometheus.CounterVec
+}
+
+func newSamplingStage(cfg interface{}, reg prometheus.Registerer) (stages.Stage, error) {
+	var sc SamplingConfig
+	if err := mapstructure.WeakDecode(cfg, &sc); err != nil {
+		return nil, err
+	}
+	if sc.Rate <= 0 || sc.Rate > 1 {
+		return nil, fmt.Errorf("sampling stage rate must be greater than 0 and at most 1, got %v", sc.Rate)
+	}
+	if sc.DropReason == "" {
+		sc.DropReason = "sampling_stage"
+	}
+
+	return &samplingStage{
+		cfg:       sc,
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+		dropCount: dropCountMetric(reg),
+	}, nil
+}
+
+// Run implements stages.Stage.
+func (s *samplingStage) Run(in chan stages.Entry) chan stages.Entry {
+	out := make(chan stages.Entry)
+	go func() {
+		defer close(out)
+		for e := range in {
+			if s.rand.Float64() < s.cfg.Rate {
+				out <- e
+				continue
+			}
+			s.dropCount.WithLabelValues(s.cfg.DropReason).Inc()
+		}
+	}()
+	return out
+}
+
+// Name implements stages.Stage.
+func (s *samplingStage) Name() string { return StageTypeSampling }
+
+// dropCountMetric returns the dropped lines metric shared with the drop and
+// match stages of Promtail.
+func dropCountMetric(reg prometheus.Registerer) *prometheus.CounterVec {
+	dropCount := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "logentry",
+		Name:      "dropped_lines_total",
+		Help:      "A count of all log lines dropped as a result of a pipeline stage",
+	}, []string{"reason"})
+	if reg == nil {
+		return dropCount
+	}
+
+	if err := reg.Register(dropCount); err != nil {
+		existing, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			panic(err)
+		}
+		dropCount = existing.ExistingCollector.(*prometheus.CounterVec)
+	}
+	return dropCount
+}
@@ -0,0 +1,126 @@
+package stages
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/clients/pkg/promtail/scrapeconfig"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// pipelineLabel is set by Promtail on the entries of scrape_configs whose
+// pipelines are run by the agent. Its value is the index of the scrape
+// config.
+const pipelineLabel model.LabelName = "__agent_pipeline"
+
+// WrapScrapeConfigs allows scrape_configs to use the stages implemented by
+// the agent. Promtail runs the pipelines of scrape_configs and doesn't know
+// these stages, so the pipeline of every scrape config using them is
+// replaced by one which only sets a label on its entries. The returned
+// handler runs entries with this label through the original pipeline of
+// their scrape config and sends all entries to next.
+//
+// The returned scrape configs should be passed to Promtail along with the
+// returned handler. Stopping the handler waits for entries in the pipelines
+// to be sent but doesn't stop next.
+func WrapScrapeConfigs(l log.Logger, reg prometheus.Registerer, scs []scrapeconfig.Config, next api.EntryHandler) ([]scrapeconfig.Config, api.EntryHandler, error) {
+	var (
+		out       = make([]scrapeconfig.Config, len(scs))
+		pipelines = make(map[model.LabelValue]api.EntryHandler)
+	)
+	stopPipelines := func() {
+		for _, p := range pipelines {
+			p.Stop()
+		}
+	}
+
+	for i, sc := range scs {
+		out[i] = sc
+		if !usesAgentStages(sc.PipelineStages) {
+			continue
+		}
+
+		jobName := sc.JobName
+		p, err := NewPipeline(log.With(l, "component", "scrape_pipeline"), sc.PipelineStages, &jobName, reg)
+		if err != nil {
+			stopPipelines()
+			return nil, nil, fmt.Errorf("failed to create pipeline for job %s: %w", sc.JobName, err)
+		}
+
+		value := strconv.Itoa(i)
+		pipelines[model.LabelValue(value)] = p.Wrap(next)
+		out[i].PipelineStages = labelStages(value)
+	}
+
+	var (
+		in   = make(chan api.Entry)
+		wg   sync.WaitGroup
+		once sync.Once
+	)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for e := range in {
+			value, ok := e.Labels[pipelineLabel]
+			if !ok {
+				next.Chan() <- e
+				continue
+			}
+
+			e.Labels = e.Labels.Clone()
+			delete(e.Labels, pipelineLabel)
+			if p, ok := pipelines[value]; ok {
+				p.Chan() <- e
+			} else {
+				next.Chan() <- e
+			}
+		}
+	}()
+
+	return out, api.NewEntryHandler(in, func() {
+		once.Do(func() { close(in) })
+		wg.Wait()
+		stopPipelines()
+	}), nil
+}
+
+// labelStages returns the pipeline run by Promtail for a scrape config whose
+// pipeline is run by the agent. It sets pipelineLabel to value.
+func labelStages(value string) PipelineStages {
+	return PipelineStages{
+		stages.PipelineStage{
+			stages.StageTypeTemplate: stages.PipelineStage{
+				"source":   string(pipelineLabel),
+				"template": value,
+			},
+		},
+		stages.PipelineStage{
+			stages.StageTypeLabel: stages.PipelineStage{
+				string(pipelineLabel): nil,
+			},
+		},
+	}
+}
+
+// usesAgentStages returns whether stgs uses stages implemented by the agent.
+func usesAgentStages(stgs PipelineStages) bool {
+	for _, s := range stgs {
+		stage, ok := s.(stages.PipelineStage)
+		if !ok {
+			continue
+		}
+		for key := range stage {
+			switch key {
+			case StageTypeDecolorize, StageTypeSampling:
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+// Package stages extends the pipeline stages of Promtail with stages which
+// are implemented by the agent.
+package stages
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Stage types which are implemented by the agent.
+const (
+	StageTypeDecolorize = "decolorize"
+	StageTypeSampling   = "sampling"
+)
+
+// PipelineStages contains the configuration of each stage of a pipeline.
+type PipelineStages = stages.PipelineStages
+
+// Pipeline runs log entries through a sequence of stages. Stages which
+// aren't implemented by the agent are created by Promtail.
+type Pipeline struct {
+	stages []stages.Stage
+}
+
+// NewPipeline creates a new Pipeline from its configuration.
+func NewPipeline(l log.Logger, stgs PipelineStages, jobName *string, reg prometheus.Registerer) (*Pipeline, error) {
+	p := &Pipeline{}
+	for _, s := range stgs {
+		stage, ok := s.(stages.PipelineStage)
+		if !ok {
+			return nil, fmt.Errorf("invalid YAML config, make sure each stage of your pipeline is a YAML object (must end with a `:`), check stage `- %s`", s)
+		}
+		if len(stage) > 1 {
+			return nil, fmt.Errorf("pipeline stage must contain only one key")
+		}
+		for key, cfg := range stage {
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("pipeline stage key must be a string")
+			}
+			st, err := newStage(l, jobName, name, cfg, reg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s stage config: %w", name, err)
+			}
+			p.stages = append(p.stages, st)
+		}
+	}
+	return p, nil
+}
+
+func newStage(l log.Logger, jobName *string, name string, cfg interface{}, reg prometheus.Registerer) (stages.Stage, error) {
+	switch name {
+	case StageTypeDecolorize:
+		return newDecolorizeStage(cfg)
+	case StageTypeSampling:
+		return newSamplingStage(cfg, reg)
+	default:
+		return stages.New(l, jobName, name, cfg, reg)
+	}
+}
+
+// Run implements stages.Stage.
+func (p *Pipeline) Run(in chan stages.Entry) chan stages.Entry {
+	in = stages.RunWith(in, func(e stages.Entry) stages.Entry {
+		// Initialize the extracted map with the initial labels so stages can
+		// operate on them.
+		for name, value := range e.Labels {
+			e.Extracted[string(name)] = string(value)
+		}
+		return e
+	})
+	for _, s := range p.stages {
+		in = s.Run(in)
+	}
+	return in
+}
+
+// Name implements stages.Stage.
+func (p *Pipeline) Name() string {
+	return stages.StageTypePipeline
+}
+
+// Wrap returns an api.EntryHandler which runs entries through the pipeline
+// before sending them to next. Stopping the returned handler waits for
+// entries in the pipeline to be sent but doesn't stop next.
+func (p *Pipeline) Wrap(next api.EntryHandler) api.EntryHandler {
+	var (
+		handlerIn   = make(chan api.Entry)
+		pipelineIn  = make(chan stages.Entry)
+		pipelineOut = p.Run(pipelineIn)
+
+		wg   sync.WaitGroup
+		once sync.Once
+	)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for e := range pipelineOut {
+			next.Chan() <- e.Entry
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		defer close(pipelineIn)
+		for e := range handlerIn {
+			pipelineIn <- stages.Entry{
+				Extracted: map[string]interface{}{},
+				Entry:     e,
+			}
+		}
+	}()
+
+	return api.NewEntryHandler(handlerIn, func() {
+		once.Do(func() { close(handlerIn) })
+		wg.Wait()
+	})
+}
+
+// Step holds the entries after a stage of a Pipeline was run by Trace.
+type Step struct {
+	// Stage is the type of the stage.
+	Stage   string
+	Entries []stages.Entry
+}
+
+// Trace runs entries through the pipeline one stage at a time and returns
+// the entries after each stage. Every stage processes all entries before the
+// next stage starts, so entries buffered by a stage, such as lines joined by
+// a multiline stage, are flushed after each stage.
+func (p *Pipeline) Trace(entries []api.Entry) []Step {
+	current := make([]stages.Entry, 0, len(entries))
+	for _, e := range entries {
+		extracted := make(map[string]interface{}, len(e.Labels))
+		for name, value := range e.Labels {
+			extracted[string(name)] = string(value)
+		}
+		e.Labels = e.Labels.Clone()
+		current = append(current, stages.Entry{Extracted: extracted, Entry: e})
+	}
+
+	steps := make([]Step, 0, len(p.stages))
+	for _, s := range p.stages {
+		in := make(chan stages.Entry)
+		out := s.Run(in)
+		go func(entries []stages.Entry) {
+			defer close(in)
+			for _, e := range entries {
+				in <- e
+			}
+		}(current)
+
+		var next []stages.Entry
+		for e := range out {
+			next = append(next, e)
+		}
+		steps = append(steps, Step{Stage: s.Name(), Entries: cloneEntries(next)})
+		current = next
+	}
+	return steps
+}
+
+// cloneEntries deep copies the labels and extracted values of entries so
+// later stages can't modify them.
+func cloneEntries(entries []stages.Entry) []stages.Entry {
+	out := make([]stages.Entry, len(entries))
+	for i, e := range entries {
+		extracted := make(map[string]interface{}, len(e.Extracted))
+		for k, v := range e.Extracted {
+			extracted[k] = v
+		}
+		e.Extracted = extracted
+		e.Labels = e.Labels.Clone()
+		out[i] = e
+	}
+	return out
+}
@@ -0,0 +1,204 @@
+package stages
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/clients/pkg/logentry/stages"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/clients/pkg/promtail/client"
+	"github.com/grafana/loki/clients/pkg/promtail/scrapeconfig"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func newTestPipeline(t *testing.T, cfg string) *Pipeline {
+	t.Helper()
+
+	var stgs PipelineStages
+	require.NoError(t, yaml.Unmarshal([]byte(cfg), &stgs))
+
+	jobName := "test"
+	p, err := NewPipeline(log.NewNopLogger(), stgs, &jobName, prometheus.NewRegistry())
+	require.NoError(t, err)
+	return p
+}
+
+func TestPipeline(t *testing.T) {
+	p := newTestPipeline(t, `
+- decolorize:
+- regex:
+    expression: "^(?P<level>\\w+) "
+- labels:
+    level:
+`)
+
+	entries := make(chan api.Entry, 10)
+	handler := p.Wrap(api.NewEntryHandler(entries, func() {}))
+	handler.Chan() <- api.Entry{
+		Labels: model.LabelSet{"job": "test"},
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: "\x1b[32mINFO\x1b[0m started \x1b[1;31mserver\x1b[0m"},
+	}
+	handler.Stop()
+
+	require.Len(t, entries, 1)
+	e := <-entries
+	require.Equal(t, "INFO started server", e.Line)
+	require.Equal(t, model.LabelSet{"job": "test", "level": "INFO"}, e.Labels)
+}
+
+func TestPipeline_Tenant(t *testing.T) {
+	p := newTestPipeline(t, `
+- json:
+    expressions:
+      team:
+- tenant:
+    source: namespace
+- match:
+    selector: '{namespace="shared"}'
+    stages:
+    - tenant:
+        source: team
+`)
+
+	entries := make(chan api.Entry, 10)
+	handler := p.Wrap(api.NewEntryHandler(entries, func() {}))
+	for _, namespace := range []string{"payments", "shared"} {
+		handler.Chan() <- api.Entry{
+			Labels: model.LabelSet{"namespace": model.LabelValue(namespace)},
+			Entry:  logproto.Entry{Timestamp: time.Now(), Line: `{"team":"search"}`},
+		}
+	}
+	handler.Stop()
+
+	require.Len(t, entries, 2)
+	for _, tenant := range []model.LabelValue{"payments", "search"} {
+		e := <-entries
+		require.Equal(t, tenant, e.Labels[client.ReservedLabelTenantID])
+	}
+}
+
+func TestPipeline_Trace(t *testing.T) {
+	p := newTestPipeline(t, `
+- multiline:
+    firstline: "^start"
+    max_wait_time: 1s
+- regex:
+    expression: "^start (?P<id>\\d+)"
+- labels:
+    id:
+`)
+
+	var entries []api.Entry
+	for _, line := range []string{"start 1", "  detail", "start 2"} {
+		entries = append(entries, api.Entry{
+			Labels: model.LabelSet{"job": "test"},
+			Entry:  logproto.Entry{Timestamp: time.Now(), Line: line},
+		})
+	}
+
+	steps := p.Trace(entries)
+	require.Len(t, steps, 3)
+	require.Equal(t, []string{"multiline", "regex", "labels"}, []string{steps[0].Stage, steps[1].Stage, steps[2].Stage})
+
+	require.Len(t, steps[0].Entries, 2)
+	require.Equal(t, "start 1\n  detail", steps[0].Entries[0].Line)
+	require.Equal(t, model.LabelSet{"job": "test"}, steps[0].Entries[0].Labels)
+
+	require.Equal(t, "1", steps[1].Entries[0].Extracted["id"])
+	require.Equal(t, model.LabelSet{"job": "test"}, steps[1].Entries[0].Labels)
+
+	require.Equal(t, model.LabelSet{"job": "test", "id": "2"}, steps[2].Entries[1].Labels)
+	require.Equal(t, model.LabelSet{"job": "test"}, entries[0].Labels)
+}
+
+func TestWrapScrapeConfigs(t *testing.T) {
+	var scs []scrapeconfig.Config
+	require.NoError(t, yaml.Unmarshal([]byte(`
+- job_name: plain
+  pipeline_stages:
+  - regex:
+      expression: "^(?P<level>\\w+) "
+- job_name: colored
+  pipeline_stages:
+  - decolorize:
+  - regex:
+      expression: "^(?P<level>\\w+) "
+  - labels:
+      level:
+`), &scs))
+
+	entries := make(chan api.Entry, 10)
+	wrapped, handler, err := WrapScrapeConfigs(log.NewNopLogger(), prometheus.NewRegistry(), scs, api.NewEntryHandler(entries, func() {}))
+	require.NoError(t, err)
+	require.Equal(t, scs[0], wrapped[0])
+	require.Equal(t, "colored", wrapped[1].JobName)
+	require.Len(t, scs[1].PipelineStages, 3)
+
+	// Run the entries through the pipelines which Promtail creates for the
+	// wrapped scrape configs.
+	var handlers []api.EntryHandler
+	for _, sc := range wrapped {
+		jobName := sc.JobName
+		p, err := stages.NewPipeline(log.NewNopLogger(), sc.PipelineStages, &jobName, prometheus.NewRegistry())
+		require.NoError(t, err)
+		handlers = append(handlers, p.Wrap(handler))
+	}
+	for _, h := range handlers {
+		h.Chan() <- api.Entry{
+			Labels: model.LabelSet{"job": "test"},
+			Entry:  logproto.Entry{Timestamp: time.Now(), Line: "\x1b[32mINFO\x1b[0m started"},
+		}
+		h.Stop()
+	}
+	handler.Stop()
+
+	require.Len(t, entries, 2)
+	e := <-entries
+	require.Equal(t, "\x1b[32mINFO\x1b[0m started", e.Line)
+	require.Equal(t, model.LabelSet{"job": "test"}, e.Labels)
+	e = <-entries
+	require.Equal(t, "INFO started", e.Line)
+	require.Equal(t, model.LabelSet{"job": "test", "level": "INFO"}, e.Labels)
+}
+
+func TestNewPipeline_Invalid(t *testing.T) {
+	for _, cfg := range []string{
+		"- decolorize:\n    enabled: true",
+		"- sampling:\n    rate: 0",
+		"- sampling:\n    rate: 1.5",
+		"- unknown: {}",
+	} {
+		var stgs PipelineStages
+		require.NoError(t, yaml.Unmarshal([]byte(cfg), &stgs))
+
+		_, err := NewPipeline(log.NewNopLogger(), stgs, nil, prometheus.NewRegistry())
+		require.Error(t, err, cfg)
+	}
+}
+
+func TestSamplingStage(t *testing.T) {
+	s, err := newSamplingStage(map[interface{}]interface{}{"rate": "0.25"}, nil)
+	require.NoError(t, err)
+	s.(*samplingStage).rand = rand.New(rand.NewSource(0))
+
+	in := make(chan stages.Entry)
+	out := s.Run(in)
+	go func() {
+		defer close(in)
+		for i := 0; i < 10000; i++ {
+			in <- stages.Entry{Extracted: map[string]interface{}{}}
+		}
+	}()
+
+	var kept int
+	for range out {
+		kept++
+	}
+	require.InDelta(t, 2500, kept, 200)
+}
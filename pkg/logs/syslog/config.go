@@ -0,0 +1,185 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/grafana/agent/pkg/logs/stages"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// Protocol is a transport syslog messages are received over.
+type Protocol string
+
+// Supported protocols.
+const (
+	// ProtocolTCP receives a stream of messages per connection, framed with
+	// octet counting or terminated by newlines. Connections may use TLS.
+	ProtocolTCP Protocol = "tcp"
+
+	// ProtocolUDP receives a message per datagram.
+	ProtocolUDP Protocol = "udp"
+)
+
+// DefaultConfig holds the default settings for a syslog config.
+var DefaultConfig = Config{
+	Protocol:         ProtocolTCP,
+	IdleTimeout:      120 * time.Second,
+	MaxMessageLength: 8192,
+}
+
+// Config configures a job which receives syslog messages in the RFC5424 or
+// RFC3164 (BSD) format.
+type Config struct {
+	// JobName identifies the job in metrics and pipeline stages. Required.
+	JobName string `yaml:"job_name"`
+
+	// ListenAddress is the host:port address to listen for messages on.
+	// Required.
+	ListenAddress string `yaml:"listen_address"`
+
+	// Protocol to receive messages over: tcp or udp.
+	Protocol Protocol `yaml:"protocol,omitempty"`
+
+	// IdleTimeout closes TCP connections which didn't send data for the
+	// duration.
+	IdleTimeout time.Duration `yaml:"idle_timeout,omitempty"`
+
+	// MaxMessageLength is the size of the largest message which is accepted.
+	// Larger messages are dropped.
+	MaxMessageLength int `yaml:"max_message_length,omitempty"`
+
+	// RFC3164Timezone is the IANA name of the time zone of RFC3164
+	// timestamps, which don't include one. Defaults to the local time zone.
+	RFC3164Timezone string `yaml:"rfc3164_timezone,omitempty"`
+
+	// TLSConfig enables TLS for TCP connections.
+	TLSConfig *TLSConfig `yaml:"tls_config,omitempty"`
+
+	// Labels to add to every log line.
+	Labels model.LabelSet `yaml:"labels,omitempty"`
+
+	// LabelStructuredData discovers the structured data of RFC5424 messages
+	// as labels.
+	LabelStructuredData bool `yaml:"label_structured_data,omitempty"`
+
+	// UseIncomingTimestamp uses the timestamp of the messages instead of the
+	// time they were received.
+	UseIncomingTimestamp bool `yaml:"use_incoming_timestamp,omitempty"`
+
+	// RelabelConfigs are applied to the __syslog_* labels of each message.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
+
+	// PipelineStages process log lines before they're sent to Loki.
+	PipelineStages stages.PipelineStages `yaml:"pipeline_stages,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type config Config
+	if err := unmarshal((*config)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate returns an error if c is not valid.
+func (c *Config) Validate() error {
+	switch {
+	case c.JobName == "":
+		return fmt.Errorf("syslog config must have a job_name")
+	case c.ListenAddress == "":
+		return fmt.Errorf("syslog config %s must have a listen_address", c.JobName)
+	case c.IdleTimeout <= 0:
+		return fmt.Errorf("syslog config %s must have a positive idle_timeout", c.JobName)
+	case c.MaxMessageLength <= 0:
+		return fmt.Errorf("syslog config %s must have a positive max_message_length", c.JobName)
+	}
+
+	switch c.Protocol {
+	case ProtocolTCP:
+	case ProtocolUDP:
+		if c.TLSConfig != nil {
+			return fmt.Errorf("syslog config %s can't use tls_config with protocol udp", c.JobName)
+		}
+	default:
+		return fmt.Errorf("syslog config %s has unsupported protocol %q", c.JobName, c.Protocol)
+	}
+
+	if _, err := time.LoadLocation(c.RFC3164Timezone); err != nil {
+		return fmt.Errorf("syslog config %s has invalid rfc3164_timezone: %w", c.JobName, err)
+	}
+	if c.TLSConfig != nil {
+		if err := c.TLSConfig.Validate(); err != nil {
+			return fmt.Errorf("syslog config %s has invalid tls_config: %w", c.JobName, err)
+		}
+	}
+	return nil
+}
+
+// ClientAuthType configures whether TLS clients must present a certificate.
+type ClientAuthType string
+
+// Supported client auth types, named like the types of crypto/tls.
+const (
+	NoClientCert               ClientAuthType = "NoClientCert"
+	RequestClientCert          ClientAuthType = "RequestClientCert"
+	RequireAnyClientCert       ClientAuthType = "RequireAnyClientCert"
+	VerifyClientCertIfGiven    ClientAuthType = "VerifyClientCertIfGiven"
+	RequireAndVerifyClientCert ClientAuthType = "RequireAndVerifyClientCert"
+)
+
+var clientAuthTypes = map[ClientAuthType]tls.ClientAuthType{
+	NoClientCert:               tls.NoClientCert,
+	RequestClientCert:          tls.RequestClientCert,
+	RequireAnyClientCert:       tls.RequireAnyClientCert,
+	VerifyClientCertIfGiven:    tls.VerifyClientCertIfGiven,
+	RequireAndVerifyClientCert: tls.RequireAndVerifyClientCert,
+}
+
+// TLSConfig configures TLS for the connections of a syslog target.
+type TLSConfig struct {
+	// CertFile and KeyFile hold the certificate of the server. Required.
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+
+	// ClientCAFile holds the CAs client certificates are verified with.
+	ClientCAFile string `yaml:"client_ca_file,omitempty"`
+
+	// ClientAuthType configures whether clients must present a certificate.
+	// Defaults to RequireAndVerifyClientCert when ClientCAFile is set, and
+	// to NoClientCert otherwise.
+	ClientAuthType ClientAuthType `yaml:"client_auth_type,omitempty"`
+}
+
+// Validate returns an error if c is not valid.
+func (c *TLSConfig) Validate() error {
+	if c.CertFile == "" || c.KeyFile == "" {
+		return fmt.Errorf("cert_file and key_file are required")
+	}
+
+	authType, ok := clientAuthTypes[c.ClientAuthType]
+	switch {
+	case c.ClientAuthType == "":
+	case !ok:
+		return fmt.Errorf("unsupported client_auth_type %q", c.ClientAuthType)
+	case c.ClientCAFile == "" && (authType == tls.VerifyClientCertIfGiven || authType == tls.RequireAndVerifyClientCert):
+		return fmt.Errorf("client_auth_type %s requires a client_ca_file", c.ClientAuthType)
+	}
+	return nil
+}
+
+// clientAuth returns the client auth type of c.
+func (c *TLSConfig) clientAuth() tls.ClientAuthType {
+	if c.ClientAuthType == "" {
+		if c.ClientCAFile != "" {
+			return tls.RequireAndVerifyClientCert
+		}
+		return tls.NoClientCert
+	}
+	return clientAuthTypes[c.ClientAuthType]
+}
@@ -0,0 +1,227 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	gosyslog "github.com/influxdata/go-syslog/v3"
+	"github.com/influxdata/go-syslog/v3/rfc3164"
+	"github.com/influxdata/go-syslog/v3/rfc5424"
+)
+
+// Formats of syslog messages.
+const (
+	formatRFC5424 = "rfc5424"
+	formatRFC3164 = "rfc3164"
+)
+
+// message is a parsed syslog message. Fields which the message doesn't
+// hold are empty.
+type message struct {
+	Format         string
+	Timestamp      time.Time
+	Facility       string
+	Severity       string
+	Hostname       string
+	AppName        string
+	ProcID         string
+	MsgID          string
+	StructuredData map[string]map[string]string
+	Text           string
+}
+
+// errMessageTooLong is returned for frames larger than the max message
+// length. The frame is skipped, so the next frame can still be read.
+var errMessageTooLong = errors.New("message exceeds max_message_length")
+
+// readFrame reads the next message of a stream. The framing is detected per
+// message: messages starting with a digit are octet-counted (RFC6587
+// 3.4.1), other messages are terminated by a newline (RFC6587 3.4.2).
+// Messages larger than maxLength are skipped and errMessageTooLong is
+// returned. Other errors leave the stream at an unknown position.
+func readFrame(r *bufio.Reader, maxLength int) ([]byte, error) {
+	// Skip empty lines and trailers between messages.
+	for {
+		b, err := r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if b[0] != '\n' && b[0] != '\r' && b[0] != 0 {
+			break
+		}
+		_, _ = r.ReadByte()
+	}
+
+	b, _ := r.Peek(1)
+	if b[0] >= '0' && b[0] <= '9' {
+		return readOctetCounted(r, maxLength)
+	}
+	return readNonTransparent(r, maxLength)
+}
+
+func readOctetCounted(r *bufio.Reader, maxLength int) ([]byte, error) {
+	// The octet count is at most as long as the largest int and followed by
+	// a space.
+	var lenText []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		if c == ' ' {
+			break
+		}
+		lenText = append(lenText, c)
+		if c < '0' || c > '9' || len(lenText) > 9 {
+			return nil, fmt.Errorf("invalid octet count %q", lenText)
+		}
+	}
+	n, err := strconv.Atoi(string(lenText))
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid octet count %q", lenText)
+	}
+
+	if n > maxLength {
+		if _, err := r.Discard(n); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		return nil, errMessageTooLong
+	}
+
+	frame := make([]byte, n)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return bytes.TrimRight(frame, "\r\n\x00"), nil
+}
+
+func readNonTransparent(r *bufio.Reader, maxLength int) ([]byte, error) {
+	var (
+		frame   []byte
+		tooLong bool
+	)
+	for {
+		line, err := r.ReadSlice('\n')
+		if !tooLong {
+			frame = append(frame, line...)
+			if len(bytes.TrimRight(frame, "\r\n")) > maxLength {
+				tooLong, frame = true, nil
+			}
+		}
+
+		switch {
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case err != nil && !(errors.Is(err, io.EOF) && len(frame) > 0):
+			return nil, err
+		case tooLong:
+			return nil, errMessageTooLong
+		default:
+			// The last message of a stream may be missing its newline.
+			return bytes.TrimRight(frame, "\r\n\x00"), nil
+		}
+	}
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// parser parses RFC5424 and RFC3164 messages.
+type parser struct {
+	rfc5424 gosyslog.Machine
+	rfc3164 gosyslog.Machine
+}
+
+// newParser creates a parser which reads the timestamps of RFC3164
+// messages in loc.
+func newParser(loc *time.Location) *parser {
+	return &parser{
+		rfc5424: rfc5424.NewParser(),
+		rfc3164: rfc3164.NewParser(
+			rfc3164.WithYear(rfc3164.CurrentYear{}),
+			rfc3164.WithTimezone(loc),
+			rfc3164.WithRFC3339(),
+			rfc3164.WithBestEffort(),
+		),
+	}
+}
+
+// parse parses a message. The format is detected from the version, which
+// follows the priority of RFC5424 messages and is missing in RFC3164
+// messages.
+func (p *parser) parse(b []byte) (message, error) {
+	if isRFC5424(b) {
+		res, err := p.rfc5424.Parse(b)
+		if err != nil {
+			return message{}, err
+		}
+		msg := res.(*rfc5424.SyslogMessage)
+		m := fromBase(formatRFC5424, &msg.Base)
+		if msg.StructuredData != nil {
+			m.StructuredData = *msg.StructuredData
+		}
+		return m, nil
+	}
+
+	// Messages of legacy devices often don't follow RFC3164 exactly, so the
+	// fields which could be parsed are used.
+	res, err := p.rfc3164.Parse(b)
+	if res == nil || !res.Valid() {
+		if err == nil {
+			err = fmt.Errorf("invalid message")
+		}
+		return message{}, err
+	}
+	msg := res.(*rfc3164.SyslogMessage)
+	return fromBase(formatRFC3164, &msg.Base), nil
+}
+
+// isRFC5424 returns true if b starts with a priority followed by a version.
+func isRFC5424(b []byte) bool {
+	end := bytes.IndexByte(b, '>')
+	if len(b) == 0 || b[0] != '<' || end < 0 {
+		return false
+	}
+
+	rest := b[end+1:]
+	var digits int
+	for digits < len(rest) && rest[digits] >= '0' && rest[digits] <= '9' {
+		digits++
+	}
+	return digits > 0 && digits <= 2 && len(rest) > digits && rest[digits] == ' '
+}
+
+func fromBase(format string, b *gosyslog.Base) message {
+	m := message{Format: format}
+	if b.Timestamp != nil {
+		m.Timestamp = *b.Timestamp
+	}
+	if v := b.FacilityLevel(); v != nil {
+		m.Facility = *v
+	}
+	if v := b.SeverityLevel(); v != nil {
+		m.Severity = *v
+	}
+	m.Hostname = stringValue(b.Hostname)
+	m.AppName = stringValue(b.Appname)
+	m.ProcID = stringValue(b.ProcID)
+	m.MsgID = stringValue(b.MsgID)
+	m.Text = stringValue(b.Message)
+	return m
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
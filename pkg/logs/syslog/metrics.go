@@ -0,0 +1,31 @@
+package syslog
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics holds metrics for syslog targets.
+type Metrics struct {
+	entries     *prometheus.CounterVec
+	parseErrors *prometheus.CounterVec
+}
+
+// NewMetrics creates a new set of metrics. Metrics will be registered to reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{}
+
+	m.entries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "syslog_receiver_entries_total",
+		Help:      "Total number of syslog messages received.",
+	}, []string{"job", "format"})
+
+	m.parseErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "promtail",
+		Name:      "syslog_receiver_parsing_errors_total",
+		Help:      "Total number of syslog messages which couldn't be parsed.",
+	}, []string{"job"})
+
+	if reg != nil {
+		reg.MustRegister(m.entries, m.parseErrors)
+	}
+	return m
+}
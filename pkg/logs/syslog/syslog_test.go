@@ -0,0 +1,320 @@
+package syslog
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestConfig(t *testing.T) {
+	var cfg Config
+	err := yaml.UnmarshalStrict([]byte(`
+job_name: syslog
+listen_address: 0.0.0.0:601
+tls_config:
+  cert_file: server.crt
+  key_file: server.key
+  client_ca_file: ca.crt
+`), &cfg)
+	require.NoError(t, err)
+	require.Equal(t, ProtocolTCP, cfg.Protocol)
+	require.Equal(t, tls.RequireAndVerifyClientCert, cfg.TLSConfig.clientAuth())
+
+	err = yaml.UnmarshalStrict([]byte(`
+job_name: syslog
+listen_address: 0.0.0.0:601
+tls_config:
+  cert_file: server.crt
+  key_file: server.key
+  client_auth_type: RequireAndVerifyClientCert
+`), &cfg)
+	require.EqualError(t, err, "syslog config syslog has invalid tls_config: client_auth_type RequireAndVerifyClientCert requires a client_ca_file")
+
+	err = yaml.UnmarshalStrict([]byte(`
+job_name: syslog
+listen_address: 0.0.0.0:514
+protocol: udp
+rfc3164_timezone: Nowhere/Nowhere
+`), &cfg)
+	require.Error(t, err)
+}
+
+func TestReadFrame(t *testing.T) {
+	stream := "<13>1 2022-01-01T00:00:00Z host app - - - newline\n" +
+		"\n" +
+		frame("<13>1 2022-01-01T00:00:00Z host app - - - counted") +
+		"<13>Jan  1 00:00:00 host app: this line is too long and dropped\n" +
+		frame("<13>1 2022-01-01T00:00:00Z host app - - - counted, too long") +
+		"<13>Jan  1 00:00:00 host app: last without newline"
+
+	r := bufio.NewReader(strings.NewReader(stream))
+	var (
+		frames  []string
+		dropped int
+	)
+	for {
+		f, err := readFrame(r, 50)
+		if err == errMessageTooLong {
+			dropped++
+			continue
+		} else if err != nil {
+			require.Equal(t, "EOF", err.Error())
+			break
+		}
+		frames = append(frames, string(f))
+	}
+
+	require.Equal(t, []string{
+		"<13>1 2022-01-01T00:00:00Z host app - - - newline",
+		"<13>1 2022-01-01T00:00:00Z host app - - - counted",
+		"<13>Jan  1 00:00:00 host app: last without newline",
+	}, frames)
+	require.Equal(t, 2, dropped)
+
+	_, err := readFrame(bufio.NewReader(strings.NewReader("12x <13>")), 50)
+	require.EqualError(t, err, `invalid octet count "12x"`)
+}
+
+func TestParse(t *testing.T) {
+	p := newParser(time.UTC)
+
+	msg, err := p.parse([]byte(`<165>1 2022-01-01T10:00:00.5Z router-1 sshd 42 ID47 [origin@32473 ip="10.0.0.1"] login failed`))
+	require.NoError(t, err)
+	require.Equal(t, message{
+		Format:         formatRFC5424,
+		Timestamp:      time.Date(2022, 1, 1, 10, 0, 0, 500*int(time.Millisecond), time.UTC),
+		Facility:       "local4",
+		Severity:       "notice",
+		Hostname:       "router-1",
+		AppName:        "sshd",
+		ProcID:         "42",
+		MsgID:          "ID47",
+		StructuredData: map[string]map[string]string{"origin@32473": {"ip": "10.0.0.1"}},
+		Text:           "login failed",
+	}, msg)
+
+	msg, err = p.parse([]byte(`<34>Oct 11 22:14:15 switch-2 su[123]: 'su root' failed for lonvick on /dev/pts/8`))
+	require.NoError(t, err)
+	require.Equal(t, formatRFC3164, msg.Format)
+	require.Equal(t, "auth", msg.Facility)
+	require.Equal(t, "critical", msg.Severity)
+	require.Equal(t, "switch-2", msg.Hostname)
+	require.Equal(t, "su", msg.AppName)
+	require.Equal(t, "123", msg.ProcID)
+	require.Equal(t, "'su root' failed for lonvick on /dev/pts/8", msg.Text)
+	require.Equal(t, time.Date(time.Now().Year(), 10, 11, 22, 14, 15, 0, time.UTC), msg.Timestamp)
+
+	_, err = p.parse([]byte("not syslog"))
+	require.Error(t, err)
+}
+
+func TestTarget_TCP(t *testing.T) {
+	cfg := testConfig(ProtocolTCP)
+	entries := make(chan api.Entry, 10)
+	target, err := NewTarget(log.NewNopLogger(), NewMetrics(nil), api.NewEntryHandler(entries, func() {}), cfg)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, target.Stop()) }()
+
+	conn, err := net.Dial("tcp", target.Addr().String())
+	require.NoError(t, err)
+	_, err = fmt.Fprint(conn,
+		"<13>Jan  1 00:00:00 switch-2 kernel: link down\n"+
+			frame("<14>1 2022-01-01T00:00:00Z router-1 sshd - - - accepted"))
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	e := receive(t, entries)
+	require.Equal(t, "link down", e.Line)
+	require.Equal(t, model.LabelSet{"job": "syslog", "host": "switch-2", "format": "rfc3164"}, e.Labels)
+
+	e = receive(t, entries)
+	require.Equal(t, "accepted", e.Line)
+	require.Equal(t, model.LabelSet{"job": "syslog", "host": "router-1", "format": "rfc5424"}, e.Labels)
+	require.True(t, time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC).Equal(e.Timestamp))
+}
+
+func TestTarget_UDP(t *testing.T) {
+	cfg := testConfig(ProtocolUDP)
+	entries := make(chan api.Entry, 10)
+	target, err := NewTarget(log.NewNopLogger(), NewMetrics(nil), api.NewEntryHandler(entries, func() {}), cfg)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, target.Stop()) }()
+
+	conn, err := net.Dial("udp", target.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("<13>Jan  1 00:00:00 switch-2 kernel: link up"))
+	require.NoError(t, err)
+
+	e := receive(t, entries)
+	require.Equal(t, "link up", e.Line)
+	require.Equal(t, model.LabelSet{"job": "syslog", "host": "switch-2", "format": "rfc3164"}, e.Labels)
+}
+
+func TestTarget_TLSClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := testCA(t)
+	serverCert := testCert(t, ca, caKey, "server", x509.ExtKeyUsageServerAuth)
+	clientCert := testCert(t, ca, caKey, "switch-2", x509.ExtKeyUsageClientAuth)
+	writeCert(t, filepath.Join(dir, "ca.crt"), ca.Raw, nil)
+	writeCert(t, filepath.Join(dir, "server.crt"), serverCert.Certificate[0], serverCert.PrivateKey.(*ecdsa.PrivateKey))
+
+	cfg := testConfig(ProtocolTCP)
+	cfg.TLSConfig = &TLSConfig{
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.crt.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+	cfg.RelabelConfigs = append(cfg.RelabelConfigs, &relabel.Config{
+		SourceLabels: model.LabelNames{tlsCommonNameLabel},
+		Regex:        relabel.MustNewRegexp("(.*)"),
+		Replacement:  "$1",
+		TargetLabel:  "client",
+		Action:       relabel.Replace,
+	})
+
+	entries := make(chan api.Entry, 10)
+	target, err := NewTarget(log.NewNopLogger(), NewMetrics(nil), api.NewEntryHandler(entries, func() {}), cfg)
+	require.NoError(t, err)
+	defer func() { require.NoError(t, target.Stop()) }()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	// Clients without a certificate are rejected.
+	conn, err := tls.Dial("tcp", target.Addr().String(), &tls.Config{RootCAs: roots, ServerName: "server"})
+	if err == nil {
+		_, _ = fmt.Fprint(conn, "<13>Jan  1 00:00:00 intruder app: hello\n")
+		_, err = conn.Read(make([]byte, 1))
+		conn.Close()
+	}
+	require.Error(t, err)
+
+	conn, err = tls.Dial("tcp", target.Addr().String(), &tls.Config{
+		RootCAs:      roots,
+		ServerName:   "server",
+		Certificates: []tls.Certificate{clientCert},
+	})
+	require.NoError(t, err)
+	_, err = fmt.Fprint(conn, "<13>Jan  1 00:00:00 switch-2 kernel: link down\n")
+	require.NoError(t, err)
+	require.NoError(t, conn.Close())
+
+	e := receive(t, entries)
+	require.Equal(t, "link down", e.Line)
+	require.Equal(t, model.LabelSet{"job": "syslog", "host": "switch-2", "format": "rfc3164", "client": "switch-2"}, e.Labels)
+	require.Empty(t, entries)
+}
+
+func testConfig(protocol Protocol) *Config {
+	cfg := DefaultConfig
+	cfg.JobName = "syslog"
+	cfg.ListenAddress = "127.0.0.1:0"
+	cfg.Protocol = protocol
+	cfg.RFC3164Timezone = "UTC"
+	cfg.UseIncomingTimestamp = true
+	cfg.Labels = model.LabelSet{"job": "syslog"}
+	cfg.RelabelConfigs = []*relabel.Config{
+		{
+			SourceLabels: model.LabelNames{hostnameLabel},
+			Regex:        relabel.MustNewRegexp("(.*)"),
+			Replacement:  "$1",
+			TargetLabel:  "host",
+			Action:       relabel.Replace,
+		},
+		{
+			SourceLabels: model.LabelNames{formatLabel},
+			Regex:        relabel.MustNewRegexp("(.*)"),
+			Replacement:  "$1",
+			TargetLabel:  "format",
+			Action:       relabel.Replace,
+		},
+	}
+	return &cfg
+}
+
+// frame returns msg as an octet-counted frame.
+func frame(msg string) string {
+	return fmt.Sprintf("%d %s", len(msg), msg)
+}
+
+func receive(t *testing.T, entries <-chan api.Entry) api.Entry {
+	t.Helper()
+	select {
+	case e := <-entries:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for entry")
+		return api.Entry{}
+	}
+}
+
+func testCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return ca, key
+}
+
+func testCert(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, name string, usage x509.ExtKeyUsage) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// writeCert writes the PEM of der to path, and the PEM of key to path.key
+// if key isn't nil.
+func writeCert(t *testing.T, path string, der []byte, key *ecdsa.PrivateKey) {
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	require.NoError(t, os.WriteFile(path, certPEM, 0600))
+	if key == nil {
+		return
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	require.NoError(t, os.WriteFile(path+".key", keyPEM, 0600))
+}
@@ -0,0 +1,402 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/prometheus/prometheus/util/strutil"
+)
+
+// Labels discovered for every message which can be used in relabel_configs.
+// Structured data of RFC5424 messages is discovered as sdLabelPrefix
+// followed by the sanitized ID and parameter name when
+// label_structured_data is enabled.
+const (
+	ipLabel            = model.ReservedLabelPrefix + "syslog_connection_ip_address"
+	tlsCommonNameLabel = model.ReservedLabelPrefix + "syslog_connection_tls_common_name"
+	formatLabel        = model.ReservedLabelPrefix + "syslog_message_format"
+	severityLabel      = model.ReservedLabelPrefix + "syslog_message_severity"
+	facilityLabel      = model.ReservedLabelPrefix + "syslog_message_facility"
+	hostnameLabel      = model.ReservedLabelPrefix + "syslog_message_hostname"
+	appNameLabel       = model.ReservedLabelPrefix + "syslog_message_app_name"
+	procIDLabel        = model.ReservedLabelPrefix + "syslog_message_proc_id"
+	msgIDLabel         = model.ReservedLabelPrefix + "syslog_message_msg_id"
+	sdLabelPrefix      = model.ReservedLabelPrefix + "syslog_message_sd_"
+)
+
+// maxPacketSize is the largest UDP datagram which can be received.
+const maxPacketSize = 65536
+
+// Target receives syslog messages over TCP or UDP. The framing and the
+// format of each message are detected automatically.
+type Target struct {
+	log     log.Logger
+	metrics *Metrics
+	cfg     *Config
+	handler api.EntryHandler
+	parser  *parser
+
+	// Only one of packetConn and lis is set, depending on the protocol.
+	packetConn net.PacketConn
+	lis        net.Listener
+
+	wg    sync.WaitGroup
+	quit  chan struct{}
+	mut   sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// NewTarget creates a new Target which listens on the address of cfg.
+// Entries are sent to handler, which is stopped when the Target is stopped.
+func NewTarget(l log.Logger, m *Metrics, handler api.EntryHandler, cfg *Config) (*Target, error) {
+	loc, err := time.LoadLocation(cfg.RFC3164Timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Target{
+		log:     log.With(l, "job", cfg.JobName),
+		metrics: m,
+		cfg:     cfg,
+		handler: handler,
+		parser:  newParser(loc),
+		quit:    make(chan struct{}),
+		conns:   make(map[net.Conn]struct{}),
+	}
+
+	switch cfg.Protocol {
+	case ProtocolUDP:
+		t.packetConn, err = net.ListenPacket("udp", cfg.ListenAddress)
+	default:
+		var tlsConfig *tls.Config
+		if cfg.TLSConfig != nil {
+			if tlsConfig, err = newTLSConfig(cfg.TLSConfig); err != nil {
+				return nil, err
+			}
+		}
+		t.lis, err = net.Listen("tcp", cfg.ListenAddress)
+		if err == nil && tlsConfig != nil {
+			t.lis = tls.NewListener(t.lis, tlsConfig)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", cfg.ListenAddress, err)
+	}
+
+	t.wg.Add(1)
+	if t.lis != nil {
+		go t.runTCP()
+	} else {
+		go t.runUDP()
+	}
+	return t, nil
+}
+
+func newTLSConfig(cfg *TLSConfig) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load server certificate or key: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   cfg.clientAuth(),
+	}
+	if cfg.ClientCAFile != "" {
+		bb, err := ioutil.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read client CA file %s: %w", cfg.ClientCAFile, err)
+		}
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(bb) {
+			return nil, fmt.Errorf("no certificates found in client CA file %s", cfg.ClientCAFile)
+		}
+	}
+	return tlsConfig, nil
+}
+
+// Addr returns the address the Target listens on.
+func (t *Target) Addr() net.Addr {
+	if t.lis != nil {
+		return t.lis.Addr()
+	}
+	return t.packetConn.LocalAddr()
+}
+
+func (t *Target) runUDP() {
+	defer t.wg.Done()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := t.packetConn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-t.quit:
+				return
+			default:
+			}
+			level.Warn(t.log).Log("msg", "failed to read packet", "err", err)
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		// Datagrams usually hold a single message, but relays may send
+		// several framed messages at once.
+		connLabels := t.connectionLabels(addr, nil)
+		r := bufio.NewReader(bytes.NewReader(buf[:n]))
+		if !t.readFrames(r, connLabels, addr) {
+			return
+		}
+	}
+}
+
+func (t *Target) runTCP() {
+	defer t.wg.Done()
+
+	for {
+		conn, err := t.lis.Accept()
+		if err != nil {
+			select {
+			case <-t.quit:
+				return
+			default:
+			}
+			level.Warn(t.log).Log("msg", "failed to accept connection", "err", err)
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			time.Sleep(time.Second)
+			continue
+		}
+
+		if !t.track(conn) {
+			conn.Close()
+			return
+		}
+		t.wg.Add(1)
+		go t.handleConn(conn)
+	}
+}
+
+// track records conn as open so it can be closed when the Target is stopped.
+// Returns false if the Target is already stopped.
+func (t *Target) track(conn net.Conn) bool {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	select {
+	case <-t.quit:
+		return false
+	default:
+	}
+	t.conns[conn] = struct{}{}
+	return true
+}
+
+func (t *Target) untrack(conn net.Conn) {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	delete(t.conns, conn)
+}
+
+// handleConn receives messages from conn until it's closed or idle.
+func (t *Target) handleConn(conn net.Conn) {
+	defer t.wg.Done()
+	defer conn.Close()
+	defer t.untrack(conn)
+
+	// The handshake is done before reading so the client certificate can be
+	// used for the labels of the connection.
+	var state *tls.ConnectionState
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		_ = conn.SetDeadline(time.Now().Add(t.cfg.IdleTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			level.Warn(t.log).Log("msg", "TLS handshake failed", "remote_addr", conn.RemoteAddr(), "err", err)
+			return
+		}
+		cs := tlsConn.ConnectionState()
+		state = &cs
+	}
+
+	connLabels := t.connectionLabels(conn.RemoteAddr(), state)
+	r := bufio.NewReader(&idleTimeoutConn{Conn: conn, timeout: t.cfg.IdleTimeout})
+	t.readFrames(r, connLabels, conn.RemoteAddr())
+}
+
+// readFrames processes the messages of r until it ends. Returns false if
+// the Target was stopped.
+func (t *Target) readFrames(r *bufio.Reader, connLabels labels.Labels, addr net.Addr) bool {
+	for {
+		frame, err := readFrame(r, t.cfg.MaxMessageLength)
+		switch {
+		case errors.Is(err, errMessageTooLong):
+			level.Warn(t.log).Log("msg", "dropping message", "remote_addr", addr, "err", err)
+			t.metrics.parseErrors.WithLabelValues(t.cfg.JobName).Inc()
+			continue
+		case err != nil:
+			select {
+			case <-t.quit:
+				return false
+			default:
+			}
+			var netErr net.Error
+			switch {
+			case errors.Is(err, io.EOF):
+			case errors.As(err, &netErr) && netErr.Timeout():
+				level.Debug(t.log).Log("msg", "closing idle connection", "remote_addr", addr)
+			default:
+				level.Warn(t.log).Log("msg", "failed to read message", "remote_addr", addr, "err", err)
+				t.metrics.parseErrors.WithLabelValues(t.cfg.JobName).Inc()
+			}
+			return true
+		}
+
+		if !t.process(frame, connLabels) {
+			return false
+		}
+	}
+}
+
+// process parses frame and sends it to the handler. Returns false if the
+// Target was stopped before the message could be sent.
+func (t *Target) process(frame []byte, connLabels labels.Labels) bool {
+	msg, err := t.parser.parse(frame)
+	if err != nil {
+		level.Warn(t.log).Log("msg", "invalid message", "err", err)
+		t.metrics.parseErrors.WithLabelValues(t.cfg.JobName).Inc()
+		return true
+	}
+
+	lset := t.labels(msg, connLabels)
+	if lset == nil {
+		return true
+	}
+
+	ts := time.Now()
+	if t.cfg.UseIncomingTimestamp && !msg.Timestamp.IsZero() {
+		ts = msg.Timestamp
+	}
+
+	entry := api.Entry{
+		Labels: lset,
+		Entry:  logproto.Entry{Timestamp: ts, Line: msg.Text},
+	}
+	select {
+	case t.handler.Chan() <- entry:
+		t.metrics.entries.WithLabelValues(t.cfg.JobName, msg.Format).Inc()
+		return true
+	case <-t.quit:
+		return false
+	}
+}
+
+// connectionLabels returns the labels discovered for the connection from
+// addr. state is nil for connections without TLS.
+func (t *Target) connectionLabels(addr net.Addr, state *tls.ConnectionState) labels.Labels {
+	lb := labels.NewBuilder(nil)
+	if host, _, err := net.SplitHostPort(addr.String()); err == nil {
+		lb.Set(ipLabel, host)
+	}
+	if state != nil && len(state.PeerCertificates) > 0 {
+		lb.Set(tlsCommonNameLabel, state.PeerCertificates[0].Subject.CommonName)
+	}
+	return lb.Labels()
+}
+
+// labels returns the labels of msg after relabeling. Returns nil if the
+// message was dropped by relabeling.
+func (t *Target) labels(msg message, connLabels labels.Labels) model.LabelSet {
+	lb := labels.NewBuilder(connLabels)
+	for name, value := range map[string]string{
+		formatLabel:   msg.Format,
+		severityLabel: msg.Severity,
+		facilityLabel: msg.Facility,
+		hostnameLabel: msg.Hostname,
+		appNameLabel:  msg.AppName,
+		procIDLabel:   msg.ProcID,
+		msgIDLabel:    msg.MsgID,
+	} {
+		if value != "" {
+			lb.Set(name, value)
+		}
+	}
+	if t.cfg.LabelStructuredData {
+		for id, params := range msg.StructuredData {
+			for name, value := range params {
+				lb.Set(sdLabelPrefix+strutil.SanitizeLabelName(id+"_"+name), value)
+			}
+		}
+	}
+
+	processed := lb.Labels()
+	if len(t.cfg.RelabelConfigs) > 0 {
+		processed = relabel.Process(processed, t.cfg.RelabelConfigs...)
+		if processed == nil {
+			return nil
+		}
+	}
+
+	lset := make(model.LabelSet, len(t.cfg.Labels)+len(processed))
+	for _, l := range processed {
+		if strings.HasPrefix(l.Name, model.ReservedLabelPrefix) {
+			continue
+		}
+		lset[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+	for k, v := range t.cfg.Labels {
+		lset[k] = v
+	}
+	return lset
+}
+
+// Stop stops receiving, closes all connections, and stops the handler of the
+// Target.
+func (t *Target) Stop() error {
+	t.mut.Lock()
+	close(t.quit)
+	var err error
+	if t.lis != nil {
+		err = t.lis.Close()
+	} else {
+		err = t.packetConn.Close()
+	}
+	for conn := range t.conns {
+		conn.Close()
+	}
+	t.mut.Unlock()
+
+	t.wg.Wait()
+	t.handler.Stop()
+	return err
+}
+
+// idleTimeoutConn extends the read deadline of a connection before every
+// read.
+type idleTimeoutConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleTimeoutConn) Read(b []byte) (int, error) {
+	_ = c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Read(b)
+}
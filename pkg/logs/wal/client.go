@@ -0,0 +1,214 @@
+package wal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/clients/pkg/promtail/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v2"
+)
+
+// positionsFile is the name of the file in the WAL directory which holds the
+// position up to which each client sent entries.
+const positionsFile = "positions.yml"
+
+// Client is an api.EntryHandler which appends entries to a WAL and sends them
+// from the WAL to Loki clients. Each client sends entries from the position up
+// to which it sent entries before, so that entries which weren't sent when
+// the Client is stopped are sent after it's created again.
+type Client struct {
+	log log.Logger
+	wal *WAL
+
+	in   chan api.Entry
+	once sync.Once
+	done chan struct{}
+
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	senders []*sender
+
+	mut       sync.Mutex
+	positions map[string]Position // Position of each client by key.
+}
+
+// NewClient creates a Client which keeps its WAL in cfg.Dir and sends entries
+// to clients. The retry limit of clients is ignored: sending is retried until
+// it succeeds, fails with a non-retryable error, or the Client is stopped.
+func NewClient(l log.Logger, reg prometheus.Registerer, cfg Config, clients []client.Config) (*Client, error) {
+	if len(clients) == 0 {
+		return nil, errors.New("wal needs at least one client")
+	}
+
+	metrics := NewMetrics(reg)
+	w, err := Open(cfg.Dir, int64(cfg.MaxSizeMB)*1024*1024, metrics)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, posErr := readPositions(filepath.Join(cfg.Dir, positionsFile))
+	if posErr != nil {
+		level.Warn(l).Log("msg", "failed to read wal positions, sending entries of the wal from the start", "err", posErr)
+	}
+
+	c := &Client{
+		log:       log.With(l, "component", "wal"),
+		wal:       w,
+		in:        make(chan api.Entry),
+		done:      make(chan struct{}),
+		positions: make(map[string]Position, len(clients)),
+	}
+
+	for _, cc := range clients {
+		key := clientKey(cc)
+		if _, ok := c.positions[key]; ok {
+			w.Close()
+			return nil, fmt.Errorf("clients must have different urls or tenant_ids, found %s twice", key)
+		}
+
+		// Clients which didn't send from the WAL before only send new entries.
+		// Positions of clients which were removed are forgotten.
+		pos, ok := saved[key]
+		if !ok && posErr == nil {
+			pos = w.Head()
+		}
+		c.positions[key] = pos
+
+		s, err := newSender(c.log, metrics, cc, key, w.NewReader(pos), c.markSent)
+		if err != nil {
+			w.Close()
+			return nil, err
+		}
+		c.senders = append(c.senders, s)
+	}
+
+	// Positions are saved right away so that clients which didn't send entries
+	// before they're stopped resume from their starting position.
+	c.mut.Lock()
+	c.savePositions()
+	c.mut.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	for _, s := range c.senders {
+		c.wg.Add(1)
+		go func(s *sender) {
+			defer c.wg.Done()
+			s.run(ctx)
+		}(s)
+	}
+
+	go c.run()
+	return c, nil
+}
+
+func clientKey(cfg client.Config) string {
+	if cfg.TenantID != "" {
+		return cfg.URL.String() + "#" + cfg.TenantID
+	}
+	return cfg.URL.String()
+}
+
+func (c *Client) run() {
+	defer close(c.done)
+
+	// Entries are flushed one at a time so that they're written to the
+	// segment file before the sender of the entry continues.
+	for e := range c.in {
+		if err := c.wal.Append(e); err != nil {
+			level.Error(c.log).Log("msg", "failed to append entry to wal", "err", err)
+			continue
+		}
+		if err := c.wal.Flush(); err != nil {
+			level.Error(c.log).Log("msg", "failed to flush wal", "err", err)
+		}
+	}
+}
+
+// markSent records that the client of key sent all entries before pos and
+// removes segments which all clients sent.
+func (c *Client) markSent(key string, pos Position) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.positions[key] = pos
+	c.savePositions()
+}
+
+// savePositions writes the positions of the clients to disk and removes the
+// segments before the oldest position. c.mut must be held when calling
+// savePositions.
+func (c *Client) savePositions() {
+	if err := writePositions(filepath.Join(c.wal.dir, positionsFile), c.positions); err != nil {
+		level.Warn(c.log).Log("msg", "failed to save wal positions", "err", err)
+	}
+
+	var min Position
+	first := true
+	for _, pos := range c.positions {
+		if first || pos.Segment < min.Segment {
+			min, first = pos, false
+		}
+	}
+	c.wal.Truncate(min)
+}
+
+// Chan implements api.EntryHandler.
+func (c *Client) Chan() chan<- api.Entry { return c.in }
+
+// Stop implements api.EntryHandler. Stop returns once all received entries
+// are appended to the WAL, without waiting for them to be sent.
+func (c *Client) Stop() {
+	c.once.Do(func() { close(c.in) })
+	<-c.done
+
+	c.cancel()
+	c.wg.Wait()
+	for _, s := range c.senders {
+		s.reader.Close()
+	}
+	if err := c.wal.Close(); err != nil {
+		level.Error(c.log).Log("msg", "failed to close wal", "err", err)
+	}
+}
+
+type positionsYAML struct {
+	Positions map[string]Position `yaml:"positions"`
+}
+
+func readPositions(path string) (map[string]Position, error) {
+	bb, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var p positionsYAML
+	if err := yaml.UnmarshalStrict(bb, &p); err != nil {
+		return nil, err
+	}
+	return p.Positions, nil
+}
+
+// writePositions atomically replaces the positions file at path.
+func writePositions(path string, positions map[string]Position) error {
+	bb, err := yaml.Marshal(positionsYAML{Positions: positions})
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, bb, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
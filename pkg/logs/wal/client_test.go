@@ -0,0 +1,184 @@
+package wal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/grafana/dskit/backoff"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/clients/pkg/promtail/client"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLoki is a Loki push API which fails requests while it's down.
+type fakeLoki struct {
+	mut   sync.Mutex
+	down  bool
+	lines map[string][]string // Received lines by tenant.
+}
+
+func (l *fakeLoki) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	if l.down {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+		return
+	}
+
+	compressed, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	buf, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req logproto.PushRequest
+	if err := proto.Unmarshal(buf, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tenant := r.Header.Get("X-Scope-OrgID")
+	for _, s := range req.Streams {
+		for _, e := range s.Entries {
+			l.lines[tenant] = append(l.lines[tenant], s.Labels+" "+e.Line)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (l *fakeLoki) setDown(down bool) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	l.down = down
+}
+
+func (l *fakeLoki) received() map[string][]string {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	res := make(map[string][]string, len(l.lines))
+	for tenant, lines := range l.lines {
+		res[tenant] = append([]string(nil), lines...)
+		sort.Strings(res[tenant])
+	}
+	return res
+}
+
+func testClientConfig(t *testing.T, rawURL string) client.Config {
+	t.Helper()
+
+	u, err := url.Parse(rawURL)
+	require.NoError(t, err)
+	return client.Config{
+		URL:       flagext.URLValue{URL: u},
+		BatchWait: 10 * time.Millisecond,
+		BatchSize: 1024 * 1024,
+		Timeout:   time.Second,
+		BackoffConfig: backoff.Config{
+			MinBackoff: 10 * time.Millisecond,
+			MaxBackoff: 20 * time.Millisecond,
+		},
+	}
+}
+
+func TestClient(t *testing.T) {
+	loki := &fakeLoki{down: true, lines: map[string][]string{}}
+	srv := httptest.NewServer(loki)
+	defer srv.Close()
+
+	cfg := DefaultConfig
+	cfg.Dir = t.TempDir()
+
+	c, err := NewClient(log.NewNopLogger(), nil, cfg, []client.Config{testClientConfig(t, srv.URL)})
+	require.NoError(t, err)
+
+	c.Chan() <- testEntry("one")
+	c.Chan() <- api.Entry{
+		Labels: model.LabelSet{"job": "test", client.ReservedLabelTenantID: "team-a"},
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: "two"},
+	}
+
+	// Entries are retried until Loki is available.
+	time.Sleep(100 * time.Millisecond)
+	require.Empty(t, loki.received())
+	loki.setDown(false)
+
+	require.Eventually(t, func() bool {
+		return len(loki.received()[""]) == 1 && len(loki.received()["team-a"]) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, map[string][]string{
+		"":       {`{job="test"} one`},
+		"team-a": {`{job="test"} two`},
+	}, loki.received())
+	c.Stop()
+}
+
+func TestClient_Replay(t *testing.T) {
+	loki := &fakeLoki{down: true, lines: map[string][]string{}}
+	srv := httptest.NewServer(loki)
+	defer srv.Close()
+
+	cfg := DefaultConfig
+	cfg.Dir = t.TempDir()
+	clients := []client.Config{testClientConfig(t, srv.URL)}
+
+	// Entries which weren't sent before stopping are sent by the next client.
+	c, err := NewClient(log.NewNopLogger(), nil, cfg, clients)
+	require.NoError(t, err)
+	c.Chan() <- testEntry("one")
+	c.Chan() <- testEntry("two")
+	time.Sleep(50 * time.Millisecond)
+	c.Stop()
+
+	loki.setDown(false)
+	c, err = NewClient(log.NewNopLogger(), nil, cfg, clients)
+	require.NoError(t, err)
+	c.Chan() <- testEntry("three")
+
+	require.Eventually(t, func() bool {
+		return len(loki.received()[""]) == 3
+	}, 5*time.Second, 10*time.Millisecond)
+	c.Stop()
+
+	// Sent entries aren't sent again.
+	c, err = NewClient(log.NewNopLogger(), nil, cfg, clients)
+	require.NoError(t, err)
+	c.Chan() <- testEntry("four")
+	require.Eventually(t, func() bool {
+		return len(loki.received()[""]) == 4
+	}, 5*time.Second, 10*time.Millisecond)
+	c.Stop()
+
+	require.Equal(t, []string{
+		`{job="test"} four`,
+		`{job="test"} one`,
+		`{job="test"} three`,
+		`{job="test"} two`,
+	}, loki.received()[""])
+}
+
+func TestNewClient_DuplicateClients(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Dir = t.TempDir()
+
+	cc := testClientConfig(t, "http://localhost:3100/loki/api/v1/push")
+	_, err := NewClient(log.NewNopLogger(), nil, cfg, []client.Config{cc, cc})
+	require.EqualError(t, err, "clients must have different urls or tenant_ids, found http://localhost:3100/loki/api/v1/push twice")
+}
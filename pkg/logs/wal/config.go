@@ -0,0 +1,41 @@
+package wal
+
+import "fmt"
+
+// DefaultConfig holds the default settings for the WAL of a logs instance.
+var DefaultConfig = Config{
+	MaxSizeMB: 1024,
+}
+
+// Config configures the write-ahead log of a logs instance.
+type Config struct {
+	// Enabled buffers entries on disk before they're sent to the clients.
+	Enabled bool `yaml:"enabled,omitempty"`
+
+	// Dir is the directory of the WAL. Defaults to a directory next to the
+	// positions file of the instance.
+	Dir string `yaml:"dir,omitempty"`
+
+	// MaxSizeMB is the size in megabytes after which the oldest entries are
+	// removed from the WAL, even if they weren't sent to all clients.
+	MaxSizeMB int `yaml:"max_size_mb,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultConfig
+
+	type config Config
+	if err := unmarshal((*config)(c)); err != nil {
+		return err
+	}
+	return c.Validate()
+}
+
+// Validate returns an error if c is not valid.
+func (c *Config) Validate() error {
+	if c.Enabled && c.MaxSizeMB <= 0 {
+		return fmt.Errorf("wal must have a positive max_size_mb")
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package wal
+
+import "github.com/prometheus/client_golang/prometheus"
+
+// Metrics holds metrics for the WAL and the clients sending from it.
+type Metrics struct {
+	appended        prometheus.Counter
+	size            prometheus.Gauge
+	droppedSegments prometheus.Counter
+	corruptions     prometheus.Counter
+	sentEntries     *prometheus.CounterVec
+	droppedEntries  *prometheus.CounterVec
+	retries         *prometheus.CounterVec
+}
+
+// NewMetrics creates a new set of metrics. Metrics will be registered to reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{}
+
+	m.appended = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "agent_logs_wal_appended_entries_total",
+		Help: "Total number of log entries appended to the WAL.",
+	})
+
+	m.size = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "agent_logs_wal_size_bytes",
+		Help: "Size of the segments of the WAL in bytes.",
+	})
+
+	m.droppedSegments = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "agent_logs_wal_dropped_segments_total",
+		Help: "Total number of WAL segments removed before all clients sent them because the WAL exceeded its maximum size.",
+	})
+
+	m.corruptions = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "agent_logs_wal_corrupt_records_total",
+		Help: "Total number of corrupt records read from the WAL. The rest of the segment of a corrupt record is skipped.",
+	})
+
+	m.sentEntries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agent_logs_wal_sent_entries_total",
+		Help: "Total number of log entries sent from the WAL to a client.",
+	}, []string{"host"})
+
+	m.droppedEntries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agent_logs_wal_dropped_entries_total",
+		Help: "Total number of log entries from the WAL rejected by a client with a non-retryable error.",
+	}, []string{"host"})
+
+	m.retries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agent_logs_wal_send_retries_total",
+		Help: "Total number of retried requests sending log entries from the WAL to a client.",
+	}, []string{"host"})
+
+	if reg != nil {
+		reg.MustRegister(
+			m.appended,
+			m.size,
+			m.droppedSegments,
+			m.corruptions,
+			m.sentEntries,
+			m.droppedEntries,
+			m.retries,
+		)
+	}
+	return m
+}
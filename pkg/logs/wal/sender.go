@@ -0,0 +1,304 @@
+package wal
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/snappy"
+	"github.com/grafana/dskit/backoff"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/clients/pkg/promtail/client"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/common/config"
+)
+
+const (
+	contentType  = "application/x-protobuf"
+	maxErrMsgLen = 1024
+)
+
+// sender reads entries from the WAL and pushes them to a Loki client in
+// batches.
+type sender struct {
+	log     log.Logger
+	metrics *Metrics
+	cfg     client.Config
+	key     string
+	client  *http.Client
+	reader  *Reader
+
+	// markSent is called with the position after the last entry of each batch
+	// once the batch is sent.
+	markSent func(key string, pos Position)
+}
+
+func newSender(l log.Logger, m *Metrics, cfg client.Config, key string, r *Reader, markSent func(string, Position)) (*sender, error) {
+	if cfg.URL.URL == nil {
+		return nil, fmt.Errorf("client needs target URL")
+	}
+	if err := cfg.Client.Validate(); err != nil {
+		return nil, err
+	}
+
+	hc, err := config.NewClientFromConfig(cfg.Client, "promtail", config.WithHTTP2Disabled())
+	if err != nil {
+		return nil, err
+	}
+	hc.Timeout = cfg.Timeout
+
+	// Initialize counters to 0 so they're exported before the first entry is
+	// sent.
+	m.sentEntries.WithLabelValues(cfg.URL.Host).Add(0)
+	m.droppedEntries.WithLabelValues(cfg.URL.Host).Add(0)
+	m.retries.WithLabelValues(cfg.URL.Host).Add(0)
+
+	return &sender{
+		log:      log.With(l, "host", cfg.URL.Host),
+		metrics:  m,
+		cfg:      cfg,
+		key:      key,
+		client:   hc,
+		reader:   r,
+		markSent: markSent,
+	}, nil
+}
+
+// readEntry is an entry read from the WAL and the position after it.
+type readEntry struct {
+	entry api.Entry
+	pos   Position
+}
+
+// run sends entries until ctx is canceled. Entries of a batch which wasn't
+// sent yet when ctx is canceled are sent again by the next sender.
+func (s *sender) run(ctx context.Context) {
+	entries := make(chan readEntry)
+	go func() {
+		defer close(entries)
+		for {
+			e, pos, err := s.reader.Next(ctx)
+			if err != nil {
+				return
+			}
+			select {
+			case entries <- readEntry{entry: e, pos: pos}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	// Wait for the reader goroutine, which may still use the reader.
+	defer func() {
+		for range entries {
+		}
+	}()
+
+	// Batches are checked 10 times per BatchWait, like the Loki client does.
+	checkFrequency := s.cfg.BatchWait / 10
+	if checkFrequency < 10*time.Millisecond {
+		checkFrequency = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(checkFrequency)
+	defer ticker.Stop()
+
+	var b *batch
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case re, ok := <-entries:
+			if !ok {
+				return
+			}
+			if b != nil && b.bytes+len(re.entry.Line) > s.cfg.BatchSize {
+				if !s.send(ctx, b) {
+					return
+				}
+				b = nil
+			}
+			if b == nil {
+				b = newBatch()
+			}
+			b.add(s.processEntry(re.entry))
+			b.end = re.pos
+
+		case <-ticker.C:
+			if b == nil || time.Since(b.createdAt) < s.cfg.BatchWait {
+				continue
+			}
+			if !s.send(ctx, b) {
+				return
+			}
+			b = nil
+		}
+	}
+}
+
+// processEntry adds the external labels of the client to e and returns the
+// tenant to send e to.
+func (s *sender) processEntry(e api.Entry) (api.Entry, string) {
+	if len(s.cfg.ExternalLabels.LabelSet) > 0 {
+		e.Labels = s.cfg.ExternalLabels.LabelSet.Merge(e.Labels)
+	}
+
+	tenantID := s.cfg.TenantID
+	if value, ok := e.Labels[client.ReservedLabelTenantID]; ok {
+		tenantID = string(value)
+		e.Labels = e.Labels.Clone()
+		delete(e.Labels, client.ReservedLabelTenantID)
+	}
+	return e, tenantID
+}
+
+// send pushes b to Loki and marks its entries as sent. It returns false if
+// ctx was canceled before b was sent.
+func (s *sender) send(ctx context.Context, b *batch) bool {
+	tenants := make([]string, 0, len(b.tenants))
+	for tenantID := range b.tenants {
+		tenants = append(tenants, tenantID)
+	}
+	sort.Strings(tenants)
+
+	for _, tenantID := range tenants {
+		req, entries := b.pushRequest(tenantID)
+		buf, err := proto.Marshal(req)
+		if err != nil {
+			level.Error(s.log).Log("msg", "error encoding batch", "err", err)
+			continue
+		}
+		if !s.push(ctx, tenantID, snappy.Encode(nil, buf), entries) {
+			return false
+		}
+	}
+
+	s.markSent(s.key, b.end)
+	return true
+}
+
+// push sends buf to Loki, retrying until it's sent, fails with a
+// non-retryable error, or ctx is canceled. It returns false if ctx was
+// canceled.
+func (s *sender) push(ctx context.Context, tenantID string, buf []byte, entries int) bool {
+	host := s.cfg.URL.Host
+
+	bo := backoff.New(ctx, backoff.Config{
+		MinBackoff: s.cfg.BackoffConfig.MinBackoff,
+		MaxBackoff: s.cfg.BackoffConfig.MaxBackoff,
+	})
+	for {
+		status, err := s.request(ctx, tenantID, buf)
+		if err == nil {
+			s.metrics.sentEntries.WithLabelValues(host).Add(float64(entries))
+			return true
+		}
+
+		// Only retry 429s, 500s and connection-level errors.
+		if status > 0 && status != 429 && status/100 != 5 {
+			level.Error(s.log).Log("msg", "final error sending batch", "status", status, "err", err)
+			s.metrics.droppedEntries.WithLabelValues(host).Add(float64(entries))
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+
+		level.Warn(s.log).Log("msg", "error sending batch, will retry", "status", status, "err", err)
+		s.metrics.retries.WithLabelValues(host).Inc()
+		bo.Wait()
+		if !bo.Ongoing() {
+			return false
+		}
+	}
+}
+
+func (s *sender) request(ctx context.Context, tenantID string, buf []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", s.cfg.URL.String(), bytes.NewReader(buf))
+	if err != nil {
+		return -1, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", client.UserAgent)
+	if tenantID != "" {
+		req.Header.Set("X-Scope-OrgID", tenantID)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return -1, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		scanner := bufio.NewScanner(io.LimitReader(resp.Body, maxErrMsgLen))
+		line := ""
+		if scanner.Scan() {
+			line = scanner.Text()
+		}
+		err = fmt.Errorf("server returned HTTP status %s (%d): %s", resp.Status, resp.StatusCode, line)
+	}
+	return resp.StatusCode, err
+}
+
+// batch holds consecutive entries of the WAL, grouped by tenant and stream.
+type batch struct {
+	tenants   map[string]map[string]*logproto.Stream
+	bytes     int
+	createdAt time.Time
+	end       Position // Position after the last entry.
+}
+
+func newBatch() *batch {
+	return &batch{
+		tenants:   map[string]map[string]*logproto.Stream{},
+		createdAt: time.Now(),
+	}
+}
+
+func (b *batch) add(e api.Entry, tenantID string) {
+	b.bytes += len(e.Line)
+
+	streams, ok := b.tenants[tenantID]
+	if !ok {
+		streams = map[string]*logproto.Stream{}
+		b.tenants[tenantID] = streams
+	}
+
+	labels := e.Labels.String()
+	if stream, ok := streams[labels]; ok {
+		stream.Entries = append(stream.Entries, e.Entry)
+		return
+	}
+	streams[labels] = &logproto.Stream{
+		Labels:  labels,
+		Entries: []logproto.Entry{e.Entry},
+	}
+}
+
+// pushRequest returns the push request for the streams of tenantID and the
+// number of entries in it.
+func (b *batch) pushRequest(tenantID string) (*logproto.PushRequest, int) {
+	streams := b.tenants[tenantID]
+	req := &logproto.PushRequest{
+		Streams: make([]logproto.Stream, 0, len(streams)),
+	}
+
+	var entries int
+	for _, stream := range streams {
+		req.Streams = append(req.Streams, *stream)
+		entries += len(stream.Entries)
+	}
+	return req, entries
+}
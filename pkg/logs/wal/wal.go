@@ -0,0 +1,512 @@
+// Package wal implements a write-ahead log which buffers the log entries of a
+// logs instance on disk until they're sent to Loki, so that entries aren't
+// lost while Loki is unreachable or when the agent restarts.
+package wal
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/common/model"
+)
+
+// defaultSegmentSize is the size after which a new segment is started.
+const defaultSegmentSize = 16 * 1024 * 1024
+
+// recordHeaderSize is the size of the length and CRC32 checksum which precede
+// each record.
+const recordHeaderSize = 8
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Position is the position of a record in the WAL.
+type Position struct {
+	Segment int   `yaml:"segment"`
+	Offset  int64 `yaml:"offset"`
+}
+
+// segment is a file of the WAL holding consecutive records. size is the
+// number of bytes of the segment readers may read.
+type segment struct {
+	index int
+	size  int64
+}
+
+// WAL is a write-ahead log of entries. The WAL is split into segments, which
+// are removed once they're no longer needed or when the WAL grows past its
+// maximum size. Appended entries are visible to readers once they're
+// flushed.
+type WAL struct {
+	dir         string
+	maxSize     int64
+	segmentSize int64
+	metrics     *Metrics
+
+	mut      sync.Mutex
+	segments []segment // Ordered by index. The last segment is being written.
+	f        *os.File
+	buf      *bufio.Writer
+	written  int64         // Bytes written to the last segment, including unflushed bytes.
+	notify   chan struct{} // Closed when entries are flushed.
+}
+
+// Open opens the WAL in dir, creating dir if it doesn't exist. Appended
+// entries are written to a new segment following the existing segments.
+func Open(dir string, maxSize int64, m *Metrics) (*WAL, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create wal directory: %w", err)
+	}
+
+	w := &WAL{
+		dir:         dir,
+		maxSize:     maxSize,
+		segmentSize: defaultSegmentSize,
+		metrics:     m,
+		notify:      make(chan struct{}),
+	}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) > 0 {
+		// Entries of the last segment may have been partially written if the
+		// agent crashed. Only the records before the first invalid one are kept.
+		last := &segments[len(segments)-1]
+		size, err := repairSegment(w.segmentPath(last.index))
+		if err != nil {
+			return nil, err
+		}
+		last.size = size
+	}
+	w.segments = segments
+
+	next := 0
+	if len(segments) > 0 {
+		next = segments[len(segments)-1].index + 1
+	}
+	if err := w.createSegment(next); err != nil {
+		return nil, err
+	}
+	w.truncateSize()
+	return w, nil
+}
+
+func listSegments(dir string) ([]segment, error) {
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wal directory: %w", err)
+	}
+
+	var segments []segment
+	for _, f := range files {
+		index, err := strconv.Atoi(f.Name())
+		if err != nil || f.IsDir() {
+			continue
+		}
+		fi, err := f.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read wal segment: %w", err)
+		}
+		segments = append(segments, segment{index: index, size: fi.Size()})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].index < segments[j].index })
+	return segments, nil
+}
+
+// repairSegment truncates the segment at path after its last valid record
+// and returns its new size.
+func repairSegment(path string) (int64, error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open wal segment: %w", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open wal segment: %w", err)
+	}
+
+	var (
+		r      = io.NewSectionReader(f, 0, fi.Size())
+		offset int64
+	)
+	for {
+		_, n, err := readRecord(r, offset)
+		if err != nil {
+			break
+		}
+		offset += n
+	}
+	if err := f.Truncate(offset); err != nil {
+		return 0, fmt.Errorf("failed to repair wal segment: %w", err)
+	}
+	return offset, nil
+}
+
+func (w *WAL) segmentPath(index int) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%08d", index))
+}
+
+// createSegment starts writing to a new segment. w.mut must be held when
+// calling createSegment unless w isn't shared yet.
+func (w *WAL) createSegment(index int) error {
+	f, err := os.OpenFile(w.segmentPath(index), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create wal segment: %w", err)
+	}
+	w.f, w.buf, w.written = f, bufio.NewWriter(f), 0
+	w.segments = append(w.segments, segment{index: index})
+	return nil
+}
+
+// Append writes e to the WAL. e is visible to readers after the next call
+// to Flush.
+func (w *WAL) Append(e api.Entry) error {
+	rec := encodeRecord(encodeEntry(e))
+
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if w.written > 0 && w.written+int64(len(rec)) > w.segmentSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := w.buf.Write(rec)
+	w.written += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write to wal: %w", err)
+	}
+	w.metrics.appended.Inc()
+	return nil
+}
+
+// rotate finishes the last segment and starts a new one. w.mut must be held
+// when calling rotate.
+func (w *WAL) rotate() error {
+	if err := w.closeSegment(); err != nil {
+		return err
+	}
+	if err := w.createSegment(w.segments[len(w.segments)-1].index + 1); err != nil {
+		return err
+	}
+	w.truncateSize()
+	return nil
+}
+
+// closeSegment flushes and syncs the last segment and closes its file. w.mut
+// must be held when calling closeSegment.
+func (w *WAL) closeSegment() error {
+	if err := w.flush(); err != nil {
+		w.f.Close()
+		return err
+	}
+	if err := w.f.Sync(); err != nil {
+		w.f.Close()
+		return fmt.Errorf("failed to sync wal segment: %w", err)
+	}
+	return w.f.Close()
+}
+
+// Flush writes appended entries to the segment file and makes them visible
+// to readers.
+func (w *WAL) Flush() error {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	return w.flush()
+}
+
+func (w *WAL) flush() error {
+	if err := w.buf.Flush(); err != nil {
+		return fmt.Errorf("failed to flush wal: %w", err)
+	}
+	if last := &w.segments[len(w.segments)-1]; last.size != w.written {
+		last.size = w.written
+		w.updateSize()
+
+		close(w.notify)
+		w.notify = make(chan struct{})
+	}
+	return nil
+}
+
+// Truncate removes the segments before the segment of pos. The segment
+// being written is never removed.
+func (w *WAL) Truncate(pos Position) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	for len(w.segments) > 1 && w.segments[0].index < pos.Segment {
+		w.removeOldest()
+	}
+	w.updateSize()
+}
+
+// truncateSize removes the oldest segments while the WAL is bigger than its
+// maximum size. w.mut must be held when calling truncateSize.
+func (w *WAL) truncateSize() {
+	for len(w.segments) > 1 && w.totalSize() > w.maxSize {
+		w.removeOldest()
+		w.metrics.droppedSegments.Inc()
+	}
+	w.updateSize()
+}
+
+func (w *WAL) removeOldest() {
+	// Failing to remove the file only wastes space until the segment is
+	// listed again by the next Open and truncated again.
+	_ = os.Remove(w.segmentPath(w.segments[0].index))
+	w.segments = w.segments[1:]
+}
+
+func (w *WAL) totalSize() int64 {
+	var size int64
+	for _, s := range w.segments {
+		size += s.size
+	}
+	return size
+}
+
+func (w *WAL) updateSize() {
+	w.metrics.size.Set(float64(w.totalSize()))
+}
+
+// Head returns the position after the last flushed entry.
+func (w *WAL) Head() Position {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	last := w.segments[len(w.segments)-1]
+	return Position{Segment: last.index, Offset: last.size}
+}
+
+// Close flushes appended entries and closes the WAL.
+func (w *WAL) Close() error {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	return w.closeSegment()
+}
+
+// NewReader returns a Reader which reads entries starting at pos.
+func (w *WAL) NewReader(pos Position) *Reader {
+	return &Reader{w: w, pos: pos}
+}
+
+// Reader reads entries of a WAL in order.
+type Reader struct {
+	w   *WAL
+	pos Position
+	f   *os.File // File of the segment of pos, if opened.
+}
+
+// Next returns the next entry and the position after it. Next blocks until
+// an entry is flushed or ctx is canceled. Entries of segments which were
+// removed before they were read are skipped.
+func (r *Reader) Next(ctx context.Context) (api.Entry, Position, error) {
+	for {
+		size, next, notify := r.state()
+		switch {
+		case r.pos.Offset < size:
+			e, ok := r.read(size)
+			if ok {
+				return e, r.pos, nil
+			}
+		case next >= 0:
+			r.seek(Position{Segment: next})
+		default:
+			select {
+			case <-notify:
+			case <-ctx.Done():
+				return api.Entry{}, r.pos, ctx.Err()
+			}
+		}
+	}
+}
+
+// state returns the readable size of the segment of r.pos and the index of
+// the following segment, or -1 if it's the last segment. If it's the last
+// segment, notify is closed once more entries are flushed. r.pos is moved to
+// the first segment if its segment doesn't exist.
+func (r *Reader) state() (size int64, next int, notify <-chan struct{}) {
+	w := r.w
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	i := sort.Search(len(w.segments), func(i int) bool { return w.segments[i].index >= r.pos.Segment })
+	if i == len(w.segments) || w.segments[i].index != r.pos.Segment {
+		i = 0
+		r.seek(Position{Segment: w.segments[0].index})
+	}
+
+	if i == len(w.segments)-1 {
+		return w.segments[i].size, -1, w.notify
+	}
+	return w.segments[i].size, w.segments[i+1].index, nil
+}
+
+func (r *Reader) seek(pos Position) {
+	if r.f != nil && pos.Segment != r.pos.Segment {
+		r.f.Close()
+		r.f = nil
+	}
+	r.pos = pos
+}
+
+// read reads the entry at r.pos from a segment of the given size. If the
+// record is corrupt, the rest of the segment is skipped.
+func (r *Reader) read(size int64) (api.Entry, bool) {
+	if r.f == nil {
+		f, err := os.Open(r.w.segmentPath(r.pos.Segment))
+		if err != nil {
+			// The segment was removed since calling state.
+			r.pos.Offset = size
+			return api.Entry{}, false
+		}
+		r.f = f
+	}
+
+	payload, n, err := readRecord(io.NewSectionReader(r.f, 0, size), r.pos.Offset)
+	if err == nil {
+		var e api.Entry
+		if e, err = decodeEntry(payload); err == nil {
+			r.pos.Offset += n
+			return e, true
+		}
+	}
+	r.w.metrics.corruptions.Inc()
+	r.pos.Offset = size
+	return api.Entry{}, false
+}
+
+// Close closes r.
+func (r *Reader) Close() {
+	if r.f != nil {
+		r.f.Close()
+		r.f = nil
+	}
+}
+
+var errCorruptRecord = errors.New("corrupt wal record")
+
+// encodeRecord prefixes payload with its length and checksum.
+func encodeRecord(payload []byte) []byte {
+	rec := make([]byte, recordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(rec[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(rec[4:8], crc32.Checksum(payload, castagnoli))
+	copy(rec[recordHeaderSize:], payload)
+	return rec
+}
+
+// readRecord reads the record at offset of r and returns its payload and
+// total size.
+func readRecord(r *io.SectionReader, offset int64) ([]byte, int64, error) {
+	var header [recordHeaderSize]byte
+	if _, err := r.ReadAt(header[:], offset); err != nil {
+		return nil, 0, err
+	}
+
+	length := int64(binary.BigEndian.Uint32(header[0:4]))
+	if length > r.Size()-offset-recordHeaderSize {
+		return nil, 0, errCorruptRecord
+	}
+	payload := make([]byte, length)
+	if _, err := r.ReadAt(payload, offset+recordHeaderSize); err != nil {
+		return nil, 0, errCorruptRecord
+	}
+	if crc32.Checksum(payload, castagnoli) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, errCorruptRecord
+	}
+	return payload, recordHeaderSize + int64(len(payload)), nil
+}
+
+// encodeEntry encodes the labels, timestamp, and line of e.
+func encodeEntry(e api.Entry) []byte {
+	size := binary.MaxVarintLen64 * (2 + 2*len(e.Labels) + 1)
+	for name, value := range e.Labels {
+		size += len(name) + len(value)
+	}
+	size += len(e.Line)
+
+	var (
+		buf = make([]byte, size)
+		n   int
+	)
+	putString := func(s string) {
+		n += binary.PutUvarint(buf[n:], uint64(len(s)))
+		n += copy(buf[n:], s)
+	}
+
+	n += binary.PutUvarint(buf[n:], uint64(len(e.Labels)))
+	for name, value := range e.Labels {
+		putString(string(name))
+		putString(string(value))
+	}
+	n += binary.PutVarint(buf[n:], e.Timestamp.UnixNano())
+	putString(e.Line)
+	return buf[:n]
+}
+
+// decodeEntry decodes an entry encoded by encodeEntry.
+func decodeEntry(b []byte) (api.Entry, error) {
+	var err error
+	uvarint := func() uint64 {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			err = errCorruptRecord
+			return 0
+		}
+		b = b[n:]
+		return v
+	}
+	str := func() string {
+		l := uvarint()
+		if err != nil || l > uint64(len(b)) {
+			err = errCorruptRecord
+			return ""
+		}
+		s := string(b[:l])
+		b = b[l:]
+		return s
+	}
+
+	numLabels := uvarint()
+	if err != nil || numLabels > uint64(len(b)) {
+		return api.Entry{}, errCorruptRecord
+	}
+	labels := make(model.LabelSet, numLabels)
+	for i := uint64(0); i < numLabels; i++ {
+		name := str()
+		labels[model.LabelName(name)] = model.LabelValue(str())
+	}
+
+	ts, n := binary.Varint(b)
+	if n <= 0 {
+		return api.Entry{}, errCorruptRecord
+	}
+	b = b[n:]
+
+	line := str()
+	if err != nil {
+		return api.Entry{}, err
+	}
+	return api.Entry{
+		Labels: labels,
+		Entry:  logproto.Entry{Timestamp: time.Unix(0, ts), Line: line},
+	}, nil
+}
@@ -0,0 +1,142 @@
+package wal
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEntry(line string) api.Entry {
+	return api.Entry{
+		Labels: model.LabelSet{"job": "test"},
+		Entry:  logproto.Entry{Timestamp: time.Unix(1640995200, 0), Line: line},
+	}
+}
+
+// readLines reads the lines of all flushed entries from r.
+func readLines(t *testing.T, r *Reader) []string {
+	t.Helper()
+
+	var lines []string
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		e, _, err := r.Next(ctx)
+		cancel()
+		if err != nil {
+			return lines
+		}
+		lines = append(lines, e.Line)
+	}
+}
+
+func TestWAL(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, 1024*1024, NewMetrics(nil))
+	require.NoError(t, err)
+
+	r := w.NewReader(w.Head())
+	defer r.Close()
+
+	require.NoError(t, w.Append(testEntry("hello")))
+	require.Empty(t, readLines(t, r), "entries must not be visible before flushing")
+
+	require.NoError(t, w.Flush())
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	e, pos, err := r.Next(ctx)
+	require.NoError(t, err)
+	require.Equal(t, model.LabelSet{"job": "test"}, e.Labels)
+	require.True(t, e.Timestamp.Equal(time.Unix(1640995200, 0)))
+	require.Equal(t, "hello", e.Line)
+	require.Equal(t, w.Head(), pos)
+
+	// Readers waiting for entries are woken up by Flush.
+	go func() {
+		assert.NoError(t, w.Append(testEntry("world")))
+		assert.NoError(t, w.Flush())
+	}()
+	e, _, err = r.Next(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "world", e.Line)
+	require.NoError(t, w.Close())
+}
+
+func TestWAL_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	w, err := Open(dir, 1024*1024, NewMetrics(nil))
+	require.NoError(t, err)
+	require.NoError(t, w.Append(testEntry("one")))
+	require.NoError(t, w.Append(testEntry("two")))
+	require.NoError(t, w.Close())
+
+	// Simulate a partially written record of a crash.
+	f, err := os.OpenFile(w.segmentPath(0), os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = f.Write(encodeRecord(encodeEntry(testEntry("three")))[:10])
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	w, err = Open(dir, 1024*1024, NewMetrics(nil))
+	require.NoError(t, err)
+	defer w.Close()
+	require.Equal(t, Position{Segment: 1}, w.Head())
+
+	require.NoError(t, w.Append(testEntry("four")))
+	require.NoError(t, w.Flush())
+
+	r := w.NewReader(Position{})
+	defer r.Close()
+	require.Equal(t, []string{"one", "two", "four"}, readLines(t, r))
+}
+
+func TestWAL_MaxSize(t *testing.T) {
+	metrics := NewMetrics(nil)
+	recordSize := int64(len(encodeRecord(encodeEntry(testEntry("0")))))
+
+	// Each segment holds two records, and the WAL holds two full segments
+	// and the segment being written.
+	w, err := Open(t.TempDir(), 5*recordSize, metrics)
+	require.NoError(t, err)
+	defer w.Close()
+	w.segmentSize = 2 * recordSize
+
+	for i := 0; i < 9; i++ {
+		require.NoError(t, w.Append(testEntry(fmt.Sprint(i))))
+		require.NoError(t, w.Flush())
+	}
+
+	r := w.NewReader(Position{})
+	defer r.Close()
+	require.Equal(t, []string{"4", "5", "6", "7", "8"}, readLines(t, r))
+	require.Equal(t, 2.0, testutil.ToFloat64(metrics.droppedSegments))
+	require.Equal(t, float64(5*recordSize), testutil.ToFloat64(metrics.size))
+
+	w.Truncate(Position{Segment: 3})
+	r = w.NewReader(Position{})
+	defer r.Close()
+	require.Equal(t, []string{"6", "7", "8"}, readLines(t, r))
+}
+
+func TestEntryEncoding(t *testing.T) {
+	e := api.Entry{
+		Labels: model.LabelSet{"job": "test", "__tenant_id__": "team-a"},
+		Entry:  logproto.Entry{Timestamp: time.Unix(0, 1640995200123456789), Line: "line with ünicode"},
+	}
+	actual, err := decodeEntry(encodeEntry(e))
+	require.NoError(t, err)
+	require.Equal(t, e.Labels, actual.Labels)
+	require.True(t, e.Timestamp.Equal(actual.Timestamp))
+	require.Equal(t, e.Line, actual.Line)
+
+	_, err = decodeEntry(encodeEntry(e)[:5])
+	require.Error(t, err)
+}
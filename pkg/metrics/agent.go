@@ -7,6 +7,7 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
@@ -45,6 +46,7 @@ type Config struct {
 	Configs                []instance.Config     `yaml:"configs,omitempty,omitempty"`
 	InstanceRestartBackoff time.Duration         `yaml:"instance_restart_backoff,omitempty"`
 	InstanceMode           instance.Mode         `yaml:"instance_mode,omitempty"`
+	GlobalLimits           instance.LimitsConfig `yaml:"global_limits,omitempty"`
 
 	// Unmarshaled is true when the Config was unmarshaled from YAML.
 	Unmarshaled bool `yaml:"-"`
@@ -77,6 +79,17 @@ func (c *Config) ApplyDefaults() error {
 		return errors.New("cannot use configs when scraping_service mode is enabled")
 	}
 
+	switch c.ServiceConfig.ShardingStrategy {
+	case "", cluster.ShardingStrategyHash, cluster.ShardingStrategyBalanced:
+	default:
+		return fmt.Errorf("scraping_service sharding_strategy must be %q or %q", cluster.ShardingStrategyHash, cluster.ShardingStrategyBalanced)
+	}
+
+	if err := c.GlobalLimits.Validate(); err != nil {
+		return fmt.Errorf("invalid global_limits: %w", err)
+	}
+	c.Global.Limits = c.GlobalLimits
+
 	usedNames := map[string]struct{}{}
 
 	for i := range c.Configs {
@@ -140,6 +153,13 @@ type Agent struct {
 
 	instanceFactory instanceFactory
 
+	// Targets registered through the API. Kept by the Agent so they outlive
+	// instances.
+	targets *instance.TargetRegistry
+
+	// Serves remote_read requests from the WALs of instances.
+	readHandler http.Handler
+
 	cluster *cluster.Cluster
 
 	stopped  bool
@@ -158,6 +178,7 @@ func newAgent(reg prometheus.Registerer, cfg Config, logger log.Logger, fact ins
 	a := &Agent{
 		logger:          log.With(logger, "agent", "prometheus"),
 		instanceFactory: fact,
+		targets:         instance.NewTargetRegistry(),
 		reg:             reg,
 		actor:           make(chan func(), 1),
 	}
@@ -177,6 +198,8 @@ func newAgent(reg prometheus.Registerer, cfg Config, logger log.Logger, fact ins
 		return nil, err
 	}
 
+	a.readHandler = a.newReadHandler()
+
 	if err := a.ApplyConfig(cfg); err != nil {
 		return nil, err
 	}
@@ -199,7 +222,7 @@ func (a *Agent) newInstance(c instance.Config) (instance.ManagedInstance, error)
 		instanceLabel: c.Name,
 	}, a.reg)
 
-	return a.instanceFactory(reg, c, a.cfg.WALDir, a.logger)
+	return a.instanceFactory(reg, c, a.cfg.WALDir, a.targets, a.logger)
 }
 
 // Validate will validate the incoming Config and mutate it to apply defaults.
@@ -367,8 +390,8 @@ func (a *Agent) Stop() {
 	a.stopped = true
 }
 
-type instanceFactory = func(reg prometheus.Registerer, cfg instance.Config, walDir string, logger log.Logger) (instance.ManagedInstance, error)
+type instanceFactory = func(reg prometheus.Registerer, cfg instance.Config, walDir string, targets *instance.TargetRegistry, logger log.Logger) (instance.ManagedInstance, error)
 
-func defaultInstanceFactory(reg prometheus.Registerer, cfg instance.Config, walDir string, logger log.Logger) (instance.ManagedInstance, error) {
-	return instance.New(reg, cfg, walDir, logger)
+func defaultInstanceFactory(reg prometheus.Registerer, cfg instance.Config, walDir string, targets *instance.TargetRegistry, logger log.Logger) (instance.ManagedInstance, error) {
+	return instance.New(reg, cfg, walDir, targets, logger)
 }
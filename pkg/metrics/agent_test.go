@@ -11,7 +11,9 @@ import (
 	"github.com/cortexproject/cortex/pkg/util/test"
 	"github.com/go-kit/log"
 	"github.com/grafana/agent/pkg/metrics/instance"
+	"github.com/grafana/agent/pkg/metrics/wal"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/scrape"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/stretchr/testify/require"
@@ -58,6 +60,11 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			expect: errors.New("prometheus instance names must be unique. found multiple instances with name instance"),
 		},
+		{
+			name:    "negative global limits",
+			mutator: func(c *Config) { c.GlobalLimits.MaxSeriesPerInstance = -1 },
+			expect:  errors.New("invalid global_limits: max_series_per_instance must not be negative"),
+		},
 	}
 
 	for _, tc := range tt {
@@ -293,6 +300,14 @@ func (i *fakeInstance) Appender(ctx context.Context) storage.Appender {
 	return nil
 }
 
+func (i *fakeInstance) LatestSamples(_ int64, _ ...*labels.Matcher) ([]wal.Sample, error) {
+	return nil, nil
+}
+
+func (i *fakeInstance) Querier(_ context.Context, _, _ int64) (storage.Querier, error) {
+	return storage.NoopQuerier(), nil
+}
+
 type fakeInstanceFactory struct {
 	mut   sync.Mutex
 	mocks []*fakeInstance
@@ -310,7 +325,7 @@ func (f *fakeInstanceFactory) Mocks() []*fakeInstance {
 	return f.mocks
 }
 
-func (f *fakeInstanceFactory) factory(_ prometheus.Registerer, cfg instance.Config, _ string, _ log.Logger) (instance.ManagedInstance, error) {
+func (f *fakeInstanceFactory) factory(_ prometheus.Registerer, cfg instance.Config, _ string, _ *instance.TargetRegistry, _ log.Logger) (instance.ManagedInstance, error) {
 	f.created.Add(1)
 
 	f.mut.Lock()
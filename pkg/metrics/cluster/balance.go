@@ -0,0 +1,84 @@
+package cluster
+
+import (
+	"hash/fnv"
+	"sort"
+
+	"github.com/grafana/agent/pkg/metrics/instance"
+)
+
+// Strategies for distributing configs between the agents of a cluster.
+const (
+	// ShardingStrategyHash assigns configs to agents by the hash of their
+	// names using the ring.
+	ShardingStrategyHash = "hash"
+
+	// ShardingStrategyBalanced assigns configs to agents such that the sum of
+	// the size hints of the configs owned by each agent is balanced.
+	ShardingStrategyBalanced = "balanced"
+)
+
+// BalanceFunc should determine which of the configs keyed in weights are
+// owned by the caller when configs are balanced by their weights.
+type BalanceFunc = func(weights map[string]int) (map[string]bool, error)
+
+// configWeight returns the weight of c used for balancing.
+func configWeight(c *instance.Config) int {
+	if c.SizeHint > 0 {
+		return c.SizeHint
+	}
+	return 1
+}
+
+// balanceConfigs assigns each config keyed in weights to one of nodes,
+// returning the node assigned to each config. Configs are assigned from the
+// heaviest to the lightest to the node with the least total weight so far.
+//
+// The assignment only depends on its inputs, so all nodes with the same view
+// of the configs and the cluster agree on it.
+func balanceConfigs(weights map[string]int, nodes []string) map[string]string {
+	owners := make(map[string]string, len(weights))
+	if len(nodes) == 0 {
+		return owners
+	}
+
+	keys := make([]string, 0, len(weights))
+	for key := range weights {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if weights[keys[i]] != weights[keys[j]] {
+			return weights[keys[i]] > weights[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	load := make(map[string]int, len(nodes))
+	for _, key := range keys {
+		var owner string
+		for _, node := range nodes {
+			switch {
+			case owner == "":
+			case load[node] < load[owner]:
+			// Break ties by rendezvous hashing, which moves fewer configs than
+			// an ordering by node when nodes join or leave.
+			case load[node] == load[owner] && rendezvousHash(key, node) > rendezvousHash(key, owner):
+			default:
+				continue
+			}
+			owner = node
+		}
+
+		owners[key] = owner
+		load[owner] += weights[key]
+	}
+	return owners
+}
+
+func rendezvousHash(key, node string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(node))
+	return h.Sum32()
+}
@@ -0,0 +1,36 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBalanceConfigs(t *testing.T) {
+	weights := map[string]int{
+		"big-a":   100,
+		"big-b":   100,
+		"small-a": 1,
+		"small-b": 1,
+		"small-c": 1,
+		"small-d": 1,
+	}
+
+	owners := balanceConfigs(weights, []string{"node-a", "node-b"})
+	require.Len(t, owners, len(weights))
+
+	load := map[string]int{}
+	for key, owner := range owners {
+		load[owner] += weights[key]
+	}
+	require.Equal(t, map[string]int{"node-a": 102, "node-b": 102}, load)
+	require.NotEqual(t, owners["big-a"], owners["big-b"])
+
+	// All nodes must agree on the assignment regardless of the order they see
+	// the nodes in.
+	require.Equal(t, owners, balanceConfigs(weights, []string{"node-b", "node-a"}))
+}
+
+func TestBalanceConfigs_NoNodes(t *testing.T) {
+	require.Empty(t, balanceConfigs(map[string]int{"a": 1}, nil))
+}
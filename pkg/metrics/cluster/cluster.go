@@ -77,7 +77,7 @@ func New(
 	c.storeAPI = configstore.NewAPI(l, c.store, c.storeValidate, cfg.APIEnableGetConfiguration)
 	reg.MustRegister(c.storeAPI)
 
-	c.watcher, err = newConfigWatcher(l, cfg, c.store, im, c.node.Owns, validate)
+	c.watcher, err = newConfigWatcher(l, cfg, c.store, im, c.node.Owns, c.node.Balance, validate)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize configwatcher: %w", err)
 	}
@@ -24,6 +24,10 @@ type Config struct {
 
 	DangerousAllowReadingFiles bool `yaml:"dangerous_allow_reading_files"`
 
+	// How configs are distributed between agents. Either ShardingStrategyHash
+	// or ShardingStrategyBalanced.
+	ShardingStrategy string `yaml:"sharding_strategy"`
+
 	// TODO(rfratto): deprecate scraping_service_client in Agent and replace with this.
 	Client                    client.Config `yaml:"-"`
 	APIEnableGetConfiguration bool          `yaml:"-"`
@@ -55,6 +59,7 @@ func (c *Config) RegisterFlagsWithPrefix(prefix string, f *flag.FlagSet) {
 	f.DurationVar(&c.ReshardInterval, prefix+"reshard-interval", time.Minute*1, "how often to manually refresh configuration")
 	f.DurationVar(&c.ReshardTimeout, prefix+"reshard-timeout", time.Second*30, "timeout for refreshing the configuration. Timeout of 0s disables timeout.")
 	f.DurationVar(&c.ClusterReshardEventTimeout, prefix+"cluster-reshard-event-timeout", time.Second*30, "timeout for the cluster reshard. Timeout of 0s disables timeout.")
+	f.StringVar(&c.ShardingStrategy, prefix+"sharding-strategy", ShardingStrategyHash, "how configs are distributed between agents. Either hash or balanced.")
 	c.KVStore.RegisterFlagsWithPrefix(prefix+"config-store.", "configurations/", f)
 	c.Lifecycler.RegisterFlagsWithPrefix(prefix, f)
 	c.Client.GRPCClientConfig.RegisterFlagsWithPrefix(prefix, f)
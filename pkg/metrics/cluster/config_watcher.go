@@ -35,8 +35,14 @@ type configWatcher struct {
 	store    configstore.Store
 	im       instance.Manager
 	owns     OwnershipFunc
+	balance  BalanceFunc
 	validate ValidationFunc
 
+	// Ownership of configs from the last refresh when configs are balanced.
+	// Configs which aren't in balanced fall back to owns until the next
+	// refresh. Protected by mut.
+	balanced map[string]bool
+
 	refreshCh   chan struct{}
 	instanceMut sync.Mutex
 	instances   map[string]struct{}
@@ -49,8 +55,9 @@ type OwnershipFunc = func(key string) (bool, error)
 type ValidationFunc = func(*instance.Config) error
 
 // newConfigWatcher watches store for changes and checks for each config against
-// owns. It will also poll the configstore at a configurable interval.
-func newConfigWatcher(log log.Logger, cfg Config, store configstore.Store, im instance.Manager, owns OwnershipFunc, validate ValidationFunc) (*configWatcher, error) {
+// owns, or against balance when configs are balanced by their size hints. It
+// will also poll the configstore at a configurable interval.
+func newConfigWatcher(log log.Logger, cfg Config, store configstore.Store, im instance.Manager, owns OwnershipFunc, balance BalanceFunc, validate ValidationFunc) (*configWatcher, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	w := &configWatcher{
@@ -61,6 +68,7 @@ func newConfigWatcher(log log.Logger, cfg Config, store configstore.Store, im in
 		store:    store,
 		im:       im,
 		owns:     owns,
+		balance:  balance,
 		validate: validate,
 
 		refreshCh: make(chan struct{}, 1),
@@ -112,6 +120,12 @@ func (w *configWatcher) run(ctx context.Context) {
 			if err := w.handleEvent(ev); err != nil {
 				level.Error(w.log).Log("msg", "failed to handle changed or deleted config", "key", ev.Key, "err", err)
 			}
+
+			// Any change to the set of configs may change how they're balanced,
+			// which is only determined when refreshing.
+			if w.isBalanced() {
+				w.RequestRefresh()
+			}
 		}
 	}
 }
@@ -153,6 +167,7 @@ func (w *configWatcher) refresh(ctx context.Context) (err error) {
 	w.mut.Lock()
 	enabled := w.cfg.Enabled
 	refreshTimeout := w.cfg.ReshardTimeout
+	balanced := w.cfg.ShardingStrategy == ShardingStrategyBalanced
 	w.mut.Unlock()
 
 	if !enabled {
@@ -186,6 +201,11 @@ func (w *configWatcher) refresh(ctx context.Context) (err error) {
 	deadline, _ := ctx.Deadline()
 	level.Debug(w.log).Log("msg", "deadline before store.all", "deadline", deadline)
 	configs, err := w.store.All(ctx, func(key string) bool {
+		if balanced {
+			// Ownership can only be determined once all configs are known.
+			return true
+		}
+
 		owns, err := w.owns(key)
 		if err != nil {
 			level.Error(w.log).Log("msg", "failed to check for ownership, instance will be deleted if it is running", "key", key, "err", err)
@@ -199,10 +219,7 @@ func (w *configWatcher) refresh(ctx context.Context) (err error) {
 		return fmt.Errorf("failed to get configs from store: %w", err)
 	}
 
-	var (
-		keys       = make(map[string]struct{})
-		firstError error
-	)
+	var all []instance.Config
 
 Outer:
 	for {
@@ -214,16 +231,36 @@ Outer:
 			if !ok {
 				break Outer
 			}
+			all = append(all, cfg)
+		}
+	}
 
-			if err := w.handleEvent(configstore.WatchEvent{Key: cfg.Name, Config: &cfg}); err != nil {
-				level.Error(w.log).Log("msg", "failed to process changed config", "key", cfg.Name, "err", err)
-				if firstError == nil {
-					firstError = err
-				}
-			}
+	if balanced {
+		if err := w.rebalance(all); err != nil {
+			return fmt.Errorf("failed to balance configs: %w", err)
+		}
+	} else {
+		w.mut.Lock()
+		w.balanced = nil
+		w.mut.Unlock()
+	}
 
-			keys[cfg.Name] = struct{}{}
+	var (
+		keys       = make(map[string]struct{})
+		firstError error
+	)
+	for i := range all {
+		cfg := &all[i]
+
+		// Unowned configs which weren't running are ignored by handleEvent.
+		if err := w.handleEvent(configstore.WatchEvent{Key: cfg.Name, Config: cfg}); err != nil {
+			level.Error(w.log).Log("msg", "failed to process changed config", "key", cfg.Name, "err", err)
+			if firstError == nil {
+				firstError = err
+			}
 		}
+
+		keys[cfg.Name] = struct{}{}
 	}
 
 	// Any config we used to be running that disappeared from this most recent
@@ -249,6 +286,42 @@ Outer:
 	return firstError
 }
 
+// rebalance determines which of configs are owned when configs are balanced
+// by their size hints.
+func (w *configWatcher) rebalance(configs []instance.Config) error {
+	if w.balance == nil {
+		return fmt.Errorf("balancing is not supported")
+	}
+
+	weights := make(map[string]int, len(configs))
+	for i := range configs {
+		weights[configs[i].Name] = configWeight(&configs[i])
+	}
+	owned, err := w.balance(weights)
+	if err != nil {
+		return err
+	}
+
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	w.balanced = owned
+	return nil
+}
+
+func (w *configWatcher) isBalanced() bool {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	return w.cfg.ShardingStrategy == ShardingStrategyBalanced
+}
+
+// ownsKey determines if key is owned. ownsKey must be called with w.mut held.
+func (w *configWatcher) ownsKey(key string) (bool, error) {
+	if owned, ok := w.balanced[key]; ok {
+		return owned, nil
+	}
+	return w.owns(key)
+}
+
 func (w *configWatcher) handleEvent(ev configstore.WatchEvent) error {
 	w.mut.Lock()
 	defer w.mut.Unlock()
@@ -260,7 +333,7 @@ func (w *configWatcher) handleEvent(ev configstore.WatchEvent) error {
 	w.instanceMut.Lock()
 	defer w.instanceMut.Unlock()
 
-	owned, err := w.owns(ev.Key)
+	owned, err := w.ownsKey(ev.Key)
 	if err != nil {
 		level.Error(w.log).Log("msg", "failed to see if config is owned. instance will be deleted if it is running", "err", err)
 	}
@@ -31,7 +31,7 @@ func Test_configWatcher_Refresh(t *testing.T) {
 	cfg.Enabled = true
 	cfg.ReshardInterval = time.Hour
 
-	w, err := newConfigWatcher(log, cfg, &store, &im, owned, validate)
+	w, err := newConfigWatcher(log, cfg, &store, &im, owned, nil, validate)
 	require.NoError(t, err)
 	t.Cleanup(func() { _ = w.Stop() })
 
@@ -71,6 +71,54 @@ func Test_configWatcher_Refresh(t *testing.T) {
 	im.AssertCalled(t, "DeleteConfig", "hello")
 }
 
+func Test_configWatcher_Refresh_Balanced(t *testing.T) {
+	var (
+		log = util.TestLogger(t)
+
+		cfg   = DefaultConfig
+		store = configstore.Mock{
+			WatchFunc: func() <-chan configstore.WatchEvent {
+				return make(chan configstore.WatchEvent)
+			},
+			AllFunc: func(ctx context.Context, keep func(key string) bool) (<-chan instance.Config, error) {
+				ch := make(chan instance.Config, 2)
+				ch <- instance.Config{Name: "big", SizeHint: 100}
+				ch <- instance.Config{Name: "small"}
+				close(ch)
+				return ch, nil
+			},
+		}
+
+		im mockConfigManager
+
+		validate = func(*instance.Config) error { return nil }
+		unowned  = func(key string) (bool, error) { return false, nil }
+
+		weights map[string]int
+		balance = func(w map[string]int) (map[string]bool, error) {
+			weights = w
+			return map[string]bool{"big": false, "small": true}, nil
+		}
+	)
+	cfg.Enabled = true
+	cfg.ReshardInterval = time.Hour
+	cfg.ShardingStrategy = ShardingStrategyBalanced
+
+	w, err := newConfigWatcher(log, cfg, &store, &im, unowned, balance, validate)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = w.Stop() })
+
+	im.On("ApplyConfig", mock.Anything).Return(nil)
+	im.On("DeleteConfig", mock.Anything).Return(nil)
+
+	err = w.refresh(context.Background())
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]int{"big": 100, "small": 1}, weights)
+	im.AssertCalled(t, "ApplyConfig", instance.Config{Name: "small"})
+	im.AssertNumberOfCalls(t, "ApplyConfig", 1)
+}
+
 func Test_configWatcher_handleEvent(t *testing.T) {
 	var (
 		cfg   = DefaultConfig
@@ -93,7 +141,7 @@ func Test_configWatcher_handleEvent(t *testing.T) {
 			im  mockConfigManager
 		)
 
-		w, err := newConfigWatcher(log, cfg, &store, &im, owned, validate)
+		w, err := newConfigWatcher(log, cfg, &store, &im, owned, nil, validate)
 		require.NoError(t, err)
 		t.Cleanup(func() { _ = w.Stop() })
 
@@ -112,7 +160,7 @@ func Test_configWatcher_handleEvent(t *testing.T) {
 			im  mockConfigManager
 		)
 
-		w, err := newConfigWatcher(log, cfg, &store, &im, owned, validate)
+		w, err := newConfigWatcher(log, cfg, &store, &im, owned, nil, validate)
 		require.NoError(t, err)
 		t.Cleanup(func() { _ = w.Stop() })
 
@@ -135,7 +183,7 @@ func Test_configWatcher_handleEvent(t *testing.T) {
 			im  mockConfigManager
 		)
 
-		w, err := newConfigWatcher(log, cfg, &store, &im, unowned, validate)
+		w, err := newConfigWatcher(log, cfg, &store, &im, unowned, nil, validate)
 		require.NoError(t, err)
 		t.Cleanup(func() { _ = w.Stop() })
 
@@ -159,7 +207,7 @@ func Test_configWatcher_handleEvent(t *testing.T) {
 			owns    = func(key string) (bool, error) { return isOwned, nil }
 		)
 
-		w, err := newConfigWatcher(log, cfg, &store, &im, owns, validate)
+		w, err := newConfigWatcher(log, cfg, &store, &im, owns, nil, validate)
 		require.NoError(t, err)
 		t.Cleanup(func() { _ = w.Stop() })
 
@@ -187,7 +235,7 @@ func Test_configWatcher_handleEvent(t *testing.T) {
 			im mockConfigManager
 		)
 
-		w, err := newConfigWatcher(log, cfg, &store, &im, owned, validate)
+		w, err := newConfigWatcher(log, cfg, &store, &im, owned, nil, validate)
 		require.NoError(t, err)
 		t.Cleanup(func() { _ = w.Stop() })
 
@@ -57,6 +57,30 @@ type GetConfigurationResponse struct {
 	Value string `json:"value"`
 }
 
+// PutConfigurationsResponse is contained inside an APIResponse and provides
+// the names of the configurations changed by a batch. Returned by
+// PutConfigurations.
+type PutConfigurationsResponse struct {
+	// DryRun is true when the batch was only validated and no changes were
+	// made to the KV store.
+	DryRun bool `json:"dry_run"`
+
+	// Created, Updated, and Deleted are the names of the configurations
+	// created, updated, and deleted by the batch.
+	Created []string `json:"created,omitempty"`
+	Updated []string `json:"updated,omitempty"`
+	Deleted []string `json:"deleted,omitempty"`
+}
+
+// ExportConfigurationsResponse is contained inside an APIResponse and
+// provides all configurations known to the KV store. Returned by
+// ExportConfigurations.
+type ExportConfigurationsResponse struct {
+	// Value is the stream of YAML configurations, separated by document
+	// markers.
+	Value string `json:"value"`
+}
+
 // WriteResponse writes a response object to the provided ResponseWriter w and with a
 // status code of statusCode. resp is marshaled to JSON.
 func WriteResponse(w http.ResponseWriter, statusCode int, resp interface{}) error {
@@ -376,6 +376,34 @@ func (n *node) Owns(key string) (bool, error) {
 	return false, nil
 }
 
+// Balance assigns the configs keyed in weights to the healthy nodes in the
+// ring, balancing the total weight of the configs assigned to each node.
+// Balance returns which of the configs are owned by this node.
+func (n *node) Balance(weights map[string]int) (map[string]bool, error) {
+	n.mut.RLock()
+	defer n.mut.RUnlock()
+
+	if n.ring == nil || n.lc == nil {
+		return nil, fmt.Errorf("node is not part of a ring")
+	}
+
+	rs, err := n.ring.GetAllHealthy(ring.Write)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]string, 0, len(rs.Instances))
+	for _, r := range rs.Instances {
+		nodes = append(nodes, r.Addr)
+	}
+
+	owners := balanceConfigs(weights, nodes)
+	owned := make(map[string]bool, len(owners))
+	for key, owner := range owners {
+		owned[key] = owner == n.lc.Addr
+	}
+	return owned, nil
+}
+
 func keyHash(key string) uint32 {
 	h := fnv.New32()
 	_, _ = h.Write([]byte(key))
@@ -1,15 +1,24 @@
 package metrics
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
 	"sort"
 	"time"
 
 	"github.com/go-kit/log/level"
 	"github.com/gorilla/mux"
 	"github.com/grafana/agent/pkg/metrics/cluster/configapi"
+	"github.com/grafana/agent/pkg/metrics/instance"
+	"github.com/grafana/agent/pkg/metrics/wal"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+	"github.com/prometheus/prometheus/promql/parser"
 	"github.com/prometheus/prometheus/scrape"
 )
 
@@ -19,6 +28,13 @@ func (a *Agent) WireAPI(r *mux.Router) {
 
 	r.HandleFunc("/agent/api/v1/instances", a.ListInstancesHandler).Methods("GET")
 	r.HandleFunc("/agent/api/v1/targets", a.ListTargetsHandler).Methods("GET")
+
+	r.HandleFunc("/agent/api/v1/instances/{instance}/targets", a.ListRegisteredTargetsHandler).Methods("GET")
+	r.HandleFunc("/agent/api/v1/instances/{instance}/targets/{job}/{group}", a.PutTargetsHandler).Methods("PUT", "POST")
+	r.HandleFunc("/agent/api/v1/instances/{instance}/targets/{job}/{group}", a.DeleteTargetsHandler).Methods("DELETE")
+
+	r.HandleFunc("/federate", a.FederateHandler).Methods("GET")
+	r.HandleFunc("/api/v1/read", a.RemoteReadHandler).Methods("POST")
 }
 
 // ListInstancesHandler writes the set of currently running instances to the http.ResponseWriter.
@@ -36,6 +52,233 @@ func (a *Agent) ListInstancesHandler(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
+// ListRegisteredTargetsHandler writes the groups of targets registered to the
+// jobs of an instance config to the http.ResponseWriter.
+func (a *Agent) ListRegisteredTargetsHandler(w http.ResponseWriter, r *http.Request) {
+	vars, err := pathVars(r, "instance")
+	if err != nil {
+		a.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	a.writeResponse(w, http.StatusOK, ListRegisteredTargetsResponse(a.targets.List(vars["instance"])))
+}
+
+// PutTargetsHandler registers a named group of targets to a job of an
+// instance config, replacing any existing group with the same name. The
+// instance config and the job must exist.
+func (a *Agent) PutTargetsHandler(w http.ResponseWriter, r *http.Request) {
+	vars, err := pathVars(r, "instance", "job", "group")
+	if err != nil {
+		a.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	cfg, ok := a.mm.ListConfigs()[vars["instance"]]
+	if !ok {
+		a.writeError(w, http.StatusNotFound, fmt.Errorf("instance %q does not exist", vars["instance"]))
+		return
+	}
+	var jobExists bool
+	for _, sc := range cfg.ScrapeConfigs {
+		jobExists = jobExists || sc.JobName == vars["job"]
+	}
+	if !jobExists {
+		a.writeError(w, http.StatusNotFound, fmt.Errorf("job %q does not exist in instance %q", vars["job"], vars["instance"]))
+		return
+	}
+
+	var tg instance.TargetGroup
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&tg); err != nil {
+		a.writeError(w, http.StatusBadRequest, fmt.Errorf("could not unmarshal target group: %w", err))
+		return
+	}
+	if err := tg.Validate(); err != nil {
+		a.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid target group: %w", err))
+		return
+	}
+
+	if a.targets.Put(vars["instance"], vars["job"], vars["group"], tg) {
+		a.writeResponse(w, http.StatusCreated, nil)
+	} else {
+		a.writeResponse(w, http.StatusOK, nil)
+	}
+}
+
+// DeleteTargetsHandler deregisters a named group of targets from a job of an
+// instance config.
+func (a *Agent) DeleteTargetsHandler(w http.ResponseWriter, r *http.Request) {
+	vars, err := pathVars(r, "instance", "job", "group")
+	if err != nil {
+		a.writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if !a.targets.Delete(vars["instance"], vars["job"], vars["group"]) {
+		a.writeError(w, http.StatusNotFound, fmt.Errorf("target group %q does not exist", vars["group"]))
+		return
+	}
+	a.writeResponse(w, http.StatusOK, nil)
+}
+
+func (a *Agent) writeError(w http.ResponseWriter, statusCode int, writeErr error) {
+	err := configapi.WriteError(w, statusCode, writeErr)
+	if err != nil {
+		level.Error(a.logger).Log("msg", "failed to write response", "err", err)
+	}
+}
+
+func (a *Agent) writeResponse(w http.ResponseWriter, statusCode int, v interface{}) {
+	err := configapi.WriteResponse(w, statusCode, v)
+	if err != nil {
+		level.Error(a.logger).Log("msg", "failed to write response", "err", err)
+	}
+}
+
+// pathVars returns the decoded values of the named route variables. Route
+// variables may be URL-encoded.
+func pathVars(r *http.Request, names ...string) (map[string]string, error) {
+	vars := mux.Vars(r)
+	res := make(map[string]string, len(names))
+	for _, name := range names {
+		v, err := url.PathUnescape(vars[name])
+		if err != nil {
+			return nil, fmt.Errorf("could not decode %s: %w", name, err)
+		}
+		res[name] = v
+	}
+	return res, nil
+}
+
+// federationLookback is how far back FederateHandler looks for samples.
+// Series without samples in the lookback are considered gone. It matches the
+// default lookback delta of Prometheus queries.
+const federationLookback = 5 * time.Minute
+
+// FederateHandler writes the newest sample of each series in the WAL of the
+// running instances matching at least one of the match[] selectors, using the
+// Prometheus exposition format negotiated with the client. The optional
+// instance parameter only serves series from the named instance.
+//
+// Only the samples held in the WAL are served, so series which were
+// truncated from the WAL are omitted.
+func (a *Agent) FederateHandler(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		a.writeError(w, http.StatusBadRequest, fmt.Errorf("error parsing form values: %w", err))
+		return
+	}
+
+	var selectors [][]*labels.Matcher
+	for _, s := range r.Form["match[]"] {
+		matchers, err := parser.ParseMetricSelector(s)
+		if err != nil {
+			a.writeError(w, http.StatusBadRequest, fmt.Errorf("invalid match[] %q: %w", s, err))
+			return
+		}
+		selectors = append(selectors, matchers)
+	}
+	if len(selectors) == 0 {
+		a.writeError(w, http.StatusBadRequest, fmt.Errorf("at least one match[] must be provided"))
+		return
+	}
+
+	instances := a.mm.ListInstances()
+	if name := r.Form.Get("instance"); name != "" {
+		inst, ok := instances[name]
+		if !ok {
+			a.writeError(w, http.StatusNotFound, fmt.Errorf("instance %q does not exist", name))
+			return
+		}
+		instances = map[string]instance.ManagedInstance{name: inst}
+	}
+
+	var (
+		mint = timestamp.FromTime(time.Now().Add(-federationLookback))
+
+		// The same series may be matched by multiple selectors or collected by
+		// multiple instances; only the newest sample of each series is kept.
+		latest = make(map[string]wal.Sample)
+	)
+	for name, inst := range instances {
+		for _, matchers := range selectors {
+			samples, err := inst.LatestSamples(mint, matchers...)
+			if err != nil {
+				level.Warn(a.logger).Log("msg", "skipping instance for federation", "instance", name, "err", err)
+				break
+			}
+			for _, s := range samples {
+				key := s.Labels.String()
+				if prev, ok := latest[key]; ok && prev.T >= s.T {
+					continue
+				}
+				latest[key] = s
+			}
+		}
+	}
+
+	format := expfmt.Negotiate(r.Header)
+	w.Header().Set("Content-Type", string(format))
+
+	enc := expfmt.NewEncoder(w, format)
+	for _, mf := range federatedFamilies(latest) {
+		if err := enc.Encode(mf); err != nil {
+			level.Error(a.logger).Log("msg", "failed to write federation response", "err", err)
+			return
+		}
+	}
+}
+
+// federatedFamilies groups samples into untyped metric families, sorted by
+// metric name and then by labels.
+func federatedFamilies(samples map[string]wal.Sample) []*dto.MetricFamily {
+	sorted := make([]wal.Sample, 0, len(samples))
+	for _, s := range samples {
+		sorted = append(sorted, s)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return labels.Compare(sorted[i].Labels, sorted[j].Labels) < 0
+	})
+
+	var (
+		res      []*dto.MetricFamily
+		families = make(map[string]*dto.MetricFamily)
+	)
+	for _, s := range sorted {
+		s := s
+		name := s.Labels.Get(labels.MetricName)
+		if name == "" {
+			continue
+		}
+
+		mf, ok := families[name]
+		if !ok {
+			mf = &dto.MetricFamily{
+				Name: &name,
+				Type: dto.MetricType_UNTYPED.Enum(),
+			}
+			families[name] = mf
+			res = append(res, mf)
+		}
+
+		m := &dto.Metric{
+			Untyped:     &dto.Untyped{Value: &s.V},
+			TimestampMs: &s.T,
+		}
+		for _, l := range s.Labels {
+			l := l
+			if l.Name == labels.MetricName {
+				continue
+			}
+			m.Label = append(m.Label, &dto.LabelPair{Name: &l.Name, Value: &l.Value})
+		}
+		mf.Metric = append(mf.Metric, m)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].GetName() < res[j].GetName() })
+	return res
+}
+
 // ListTargetsHandler retrieves the full set of targets across all instances and shows
 // information on them.
 func (a *Agent) ListTargetsHandler(w http.ResponseWriter, r *http.Request) {
@@ -106,6 +349,10 @@ func ListTargetsHandler(targets map[string]TargetSet) http.Handler {
 	})
 }
 
+// ListRegisteredTargetsResponse is returned by ListRegisteredTargetsHandler.
+// It holds the groups of targets registered to each job, keyed by group name.
+type ListRegisteredTargetsResponse map[string]map[string]instance.TargetGroup
+
 // TargetSet is a set of targets for an individual scraper.
 type TargetSet map[string][]*scrape.Target
 
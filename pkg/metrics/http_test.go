@@ -2,16 +2,22 @@ package metrics
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/cortexproject/cortex/pkg/util/test"
 	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
 	"github.com/grafana/agent/pkg/metrics/instance"
+	"github.com/grafana/agent/pkg/metrics/wal"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
 	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/scrape"
 	"github.com/stretchr/testify/require"
@@ -132,6 +138,178 @@ func TestAgent_ListTargetsHandler(t *testing.T) {
 	})
 }
 
+func TestAgent_RegisteredTargetsHandlers(t *testing.T) {
+	fact := newFakeInstanceFactory()
+	a, err := newAgent(prometheus.NewRegistry(), Config{
+		WALDir: "/tmp/agent",
+	}, log.NewNopLogger(), fact.factory)
+	require.NoError(t, err)
+	defer a.Stop()
+
+	cfg := makeInstanceConfig("foo")
+	cfg.ScrapeConfigs = []*config.ScrapeConfig{{JobName: "pushed"}}
+	require.NoError(t, a.mm.ApplyConfig(cfg))
+
+	router := mux.NewRouter()
+	a.WireAPI(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	do := func(method, path, body string) (int, string) {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		bb, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(bb)
+	}
+
+	group := `{"targets": ["localhost:9100"], "labels": {"env": "dev"}}`
+
+	t.Run("unknown instance", func(t *testing.T) {
+		code, body := do("PUT", "/agent/api/v1/instances/bar/targets/pushed/group_a", group)
+		require.Equal(t, http.StatusNotFound, code)
+		require.JSONEq(t, `{"status": "error", "data": {"error": "instance \"bar\" does not exist"}}`, body)
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		code, _ := do("PUT", "/agent/api/v1/instances/foo/targets/other/group_a", group)
+		require.Equal(t, http.StatusNotFound, code)
+	})
+
+	t.Run("invalid group", func(t *testing.T) {
+		code, body := do("PUT", "/agent/api/v1/instances/foo/targets/pushed/group_a", `{"targets": []}`)
+		require.Equal(t, http.StatusBadRequest, code)
+		require.JSONEq(t, `{"status": "error", "data": {"error": "invalid target group: targets must not be empty"}}`, body)
+	})
+
+	t.Run("register", func(t *testing.T) {
+		code, _ := do("PUT", "/agent/api/v1/instances/foo/targets/pushed/group_a", group)
+		require.Equal(t, http.StatusCreated, code)
+		code, _ = do("PUT", "/agent/api/v1/instances/foo/targets/pushed/group_a", group)
+		require.Equal(t, http.StatusOK, code)
+
+		code, body := do("GET", "/agent/api/v1/instances/foo/targets", "")
+		require.Equal(t, http.StatusOK, code)
+		require.JSONEq(t, `{
+			"status": "success",
+			"data": {
+				"pushed": {
+					"group_a": {"targets": ["localhost:9100"], "labels": {"env": "dev"}}
+				}
+			}
+		}`, body)
+	})
+
+	t.Run("deregister", func(t *testing.T) {
+		code, _ := do("DELETE", "/agent/api/v1/instances/foo/targets/pushed/group_a", "")
+		require.Equal(t, http.StatusOK, code)
+		code, _ = do("DELETE", "/agent/api/v1/instances/foo/targets/pushed/group_a", "")
+		require.Equal(t, http.StatusNotFound, code)
+
+		code, body := do("GET", "/agent/api/v1/instances/foo/targets", "")
+		require.Equal(t, http.StatusOK, code)
+		require.JSONEq(t, `{"status": "success", "data": {}}`, body)
+	})
+}
+
+func TestAgent_FederateHandler(t *testing.T) {
+	fact := newFakeInstanceFactory()
+	a, err := newAgent(prometheus.NewRegistry(), Config{
+		WALDir: "/tmp/agent",
+	}, log.NewNopLogger(), fact.factory)
+	require.NoError(t, err)
+
+	mockManager := &instance.MockManager{
+		ListInstancesFunc: func() map[string]instance.ManagedInstance {
+			return map[string]instance.ManagedInstance{
+				"instance_a": &mockInstanceSamples{samples: []wal.Sample{
+					{Labels: labels.FromStrings("__name__", "up", "job", "a"), T: 1000, V: 1},
+					{Labels: labels.FromStrings("__name__", "up", "job", "shared"), T: 1000, V: 0},
+					{Labels: labels.FromStrings("__name__", "node_load1", "job", "a"), T: 2000, V: 0.5},
+				}},
+				"instance_b": &mockInstanceSamples{samples: []wal.Sample{
+					{Labels: labels.FromStrings("__name__", "up", "job", "b"), T: 3000, V: 1},
+					{Labels: labels.FromStrings("__name__", "up", "job", "shared"), T: 4000, V: 1},
+				}},
+			}
+		},
+		ListConfigsFunc:  func() map[string]instance.Config { return nil },
+		ApplyConfigFunc:  func(_ instance.Config) error { return nil },
+		DeleteConfigFunc: func(name string) error { return nil },
+		StopFunc:         func() {},
+	}
+	a.mm, err = instance.NewModalManager(prometheus.NewRegistry(), a.logger, mockManager, instance.ModeDistinct)
+	require.NoError(t, err)
+
+	do := func(query string) (int, string) {
+		rr := httptest.NewRecorder()
+		a.FederateHandler(rr, httptest.NewRequest("GET", "/federate?"+query, nil))
+		return rr.Result().StatusCode, rr.Body.String()
+	}
+
+	t.Run("missing match", func(t *testing.T) {
+		code, _ := do("")
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("invalid match", func(t *testing.T) {
+		code, _ := do("match[]=" + url.QueryEscape("up{"))
+		require.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("all instances", func(t *testing.T) {
+		code, body := do("match[]=up&match[]=" + url.QueryEscape(`{job="a"}`))
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, strings.Join([]string{
+			"# TYPE node_load1 untyped",
+			`node_load1{job="a"} 0.5 2000`,
+			"# TYPE up untyped",
+			`up{job="a"} 1 1000`,
+			`up{job="b"} 1 3000`,
+			`up{job="shared"} 1 4000`,
+			"",
+		}, "\n"), body)
+	})
+
+	t.Run("single instance", func(t *testing.T) {
+		code, body := do("match[]=up&instance=instance_a")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, strings.Join([]string{
+			"# TYPE up untyped",
+			`up{job="a"} 1 1000`,
+			`up{job="shared"} 0 1000`,
+			"",
+		}, "\n"), body)
+	})
+
+	t.Run("unknown instance", func(t *testing.T) {
+		code, _ := do("match[]=up&instance=instance_c")
+		require.Equal(t, http.StatusNotFound, code)
+	})
+}
+
+type mockInstanceSamples struct {
+	instance.NoOpInstance
+	samples []wal.Sample
+}
+
+func (i *mockInstanceSamples) LatestSamples(_ int64, matchers ...*labels.Matcher) ([]wal.Sample, error) {
+	var res []wal.Sample
+outer:
+	for _, s := range i.samples {
+		for _, m := range matchers {
+			if !m.Matches(s.Labels.Get(m.Name)) {
+				continue outer
+			}
+		}
+		res = append(res, s)
+	}
+	return res, nil
+}
+
 type mockInstanceScrape struct {
 	instance.NoOpInstance
 	tgts map[string][]*scrape.Target
@@ -1,11 +1,14 @@
 package configstore
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -67,10 +70,14 @@ func (api *API) WireAPI(r *mux.Router) {
 
 	r.HandleFunc("/agent/api/v1/configs", api.ListConfigurations).Methods("GET")
 	getConfigHandler := messageHandlerFunc(http.StatusNotFound, "404 - config endpoint is disabled")
+	exportConfigsHandler := messageHandlerFunc(http.StatusNotFound, "404 - config endpoint is disabled")
 	if api.enableGet {
 		getConfigHandler = api.GetConfiguration
+		exportConfigsHandler = api.ExportConfigurations
 	}
 	r.HandleFunc("/agent/api/v1/configs/{name}", getConfigHandler).Methods("GET")
+	r.HandleFunc("/agent/api/v1/configs:export", exportConfigsHandler).Methods("GET")
+	r.HandleFunc("/agent/api/v1/configs:batch", api.PutConfigurations).Methods("PUT", "POST")
 	r.HandleFunc("/agent/api/v1/config/{name}", api.PutConfiguration).Methods("PUT", "POST")
 	r.HandleFunc("/agent/api/v1/config/{name}", api.DeleteConfiguration).Methods("DELETE")
 }
@@ -234,6 +241,255 @@ func (api *API) DeleteConfiguration(rw http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// ExportConfigurations gets all configurations as a stream of YAML documents,
+// in the format accepted by PutConfigurations. Secrets are scrubbed from the
+// export, so configurations which hold secrets must have them filled back in
+// before the export can be imported again.
+func (api *API) ExportConfigurations(rw http.ResponseWriter, r *http.Request) {
+	api.storeMut.Lock()
+	defer api.storeMut.Unlock()
+	if api.store == nil {
+		api.writeError(rw, http.StatusNotFound, fmt.Errorf("no config store running"))
+		return
+	}
+
+	existing, err := api.allConfigs(r.Context())
+	if errors.Is(err, ErrNotConnected) {
+		api.writeError(rw, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		api.writeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	cfgs := make([]*instance.Config, 0, len(existing))
+	for _, name := range sortedKeys(existing) {
+		cfg := existing[name]
+		cfgs = append(cfgs, &cfg)
+	}
+	bb, err := instance.MarshalConfigs(cfgs, true)
+	if err != nil {
+		api.writeError(rw, http.StatusInternalServerError, fmt.Errorf("could not marshal configs for response: %w", err))
+		return
+	}
+	api.writeResponse(rw, http.StatusOK, &configapi.ExportConfigurationsResponse{
+		Value: string(bb),
+	})
+}
+
+// PutConfigurations creates or updates a batch of configurations, read as a
+// stream of YAML documents. Nothing is written unless every configuration in
+// the batch is valid. Configurations holding scrubbed secrets, as returned by
+// ExportConfigurations, are rejected.
+//
+// The store has no multi-key transactions, so the batch is written one
+// configuration at a time. If writing the batch fails part way, the changes
+// which were already made are reverted. Configurations which can't be
+// reverted are named in the returned error and are left as the batch wrote
+// them.
+//
+// When the prune query parameter is true, configurations which aren't in the
+// batch are deleted. When the dry_run query parameter is true, the batch is
+// validated and the changes it would make are returned without being applied.
+func (api *API) PutConfigurations(rw http.ResponseWriter, r *http.Request) {
+	api.storeMut.Lock()
+	defer api.storeMut.Unlock()
+	if api.store == nil {
+		api.writeError(rw, http.StatusNotFound, fmt.Errorf("no config store running"))
+		return
+	}
+
+	dryRun, err := getBoolParam(r, "dry_run")
+	if err != nil {
+		api.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	prune, err := getBoolParam(r, "prune")
+	if err != nil {
+		api.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+
+	cfgs, err := instance.UnmarshalConfigs(r.Body)
+	if err != nil {
+		api.writeError(rw, http.StatusBadRequest, fmt.Errorf("could not unmarshal configs: %w", err))
+		return
+	}
+
+	existing, err := api.allConfigs(r.Context())
+	if errors.Is(err, ErrNotConnected) {
+		api.writeError(rw, http.StatusNotFound, err)
+		return
+	} else if err != nil {
+		api.writeError(rw, http.StatusInternalServerError, err)
+		return
+	}
+
+	resp := configapi.PutConfigurationsResponse{DryRun: dryRun}
+	batch := make(map[string]*instance.Config, len(cfgs))
+	for _, cfg := range cfgs {
+		if _, ok := existing[cfg.Name]; ok {
+			resp.Updated = append(resp.Updated, cfg.Name)
+		} else {
+			resp.Created = append(resp.Created, cfg.Name)
+		}
+		batch[cfg.Name] = cfg
+	}
+	if prune {
+		for _, name := range sortedKeys(existing) {
+			if _, ok := batch[name]; !ok {
+				resp.Deleted = append(resp.Deleted, name)
+			}
+		}
+	}
+
+	if err := api.validateBatch(cfgs, existing, resp.Deleted); err != nil {
+		api.writeError(rw, http.StatusBadRequest, err)
+		return
+	}
+	if dryRun {
+		api.writeResponse(rw, http.StatusOK, &resp)
+		return
+	}
+
+	err = api.applyBatch(r.Context(), cfgs, resp.Deleted, existing)
+	switch {
+	case errors.Is(err, ErrNotConnected):
+		api.writeError(rw, http.StatusNotFound, err)
+	case errors.As(err, &NotUniqueError{}):
+		api.writeError(rw, http.StatusBadRequest, err)
+	case err != nil:
+		api.writeError(rw, http.StatusInternalServerError, err)
+	default:
+		api.totalCreatedConfigs.Add(float64(len(resp.Created)))
+		api.totalUpdatedConfigs.Add(float64(len(resp.Updated)))
+		api.totalDeletedConfigs.Add(float64(len(resp.Deleted)))
+		api.writeResponse(rw, http.StatusOK, &resp)
+	}
+}
+
+// allConfigs returns all configs in the store by name.
+func (api *API) allConfigs(ctx context.Context) (map[string]instance.Config, error) {
+	ch, err := api.store.All(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]instance.Config)
+	for cfg := range ch {
+		res[cfg.Name] = cfg
+	}
+	return res, nil
+}
+
+// validateBatch validates the configs of a batch, and that no two configs
+// share a job_name once the batch is applied to the existing configs.
+func (api *API) validateBatch(cfgs []*instance.Config, existing map[string]instance.Config, deletes []string) error {
+	final := make(map[string]instance.Config, len(existing)+len(cfgs))
+	for name, cfg := range existing {
+		final[name] = cfg
+	}
+	for _, name := range deletes {
+		delete(final, name)
+	}
+
+	names := make(map[string]struct{}, len(cfgs))
+	for i, cfg := range cfgs {
+		if cfg.Name == "" {
+			return fmt.Errorf("config %d in batch is missing a name", i+1)
+		}
+		if _, exist := names[cfg.Name]; exist {
+			return fmt.Errorf("found multiple configs in batch with name %q", cfg.Name)
+		}
+		if scrubbed, err := instance.HasScrubbedSecrets(cfg); err != nil {
+			return fmt.Errorf("could not check config %q for secrets: %w", cfg.Name, err)
+		} else if scrubbed {
+			return fmt.Errorf("config %q contains a scrubbed <secret> value, secrets must be set before importing it", cfg.Name)
+		}
+		names[cfg.Name] = struct{}{}
+		final[cfg.Name] = *cfg
+
+		if api.validator == nil {
+			continue
+		}
+		validateCfg, err := cfg.Clone()
+		if err != nil {
+			return fmt.Errorf("could not copy config %q: %w", cfg.Name, err)
+		}
+		if err := api.validator(&validateCfg); err != nil {
+			return fmt.Errorf("failed to validate config %q: %w", cfg.Name, err)
+		}
+	}
+
+	jobNames := make(map[string]struct{})
+	for _, name := range sortedKeys(final) {
+		for _, sc := range final[name].ScrapeConfigs {
+			if _, exist := jobNames[sc.JobName]; exist {
+				return fmt.Errorf("failed to validate config %q: %w", name, NotUniqueError{ScrapeJob: sc.JobName})
+			}
+			jobNames[sc.JobName] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// applyBatch deletes configs and then puts cfgs into the store. If any
+// change fails, the changes made so far are reverted to the existing
+// configs. Reverting is best effort: configs which fail to revert are named
+// in the returned error.
+func (api *API) applyBatch(ctx context.Context, cfgs []*instance.Config, deletes []string, existing map[string]instance.Config) error {
+	var (
+		deleted []string
+		written []string
+	)
+	revert := func(msg string, cause error) error {
+		// The request context may be the reason for the failure, so reverting
+		// uses its own context. Puts are reverted before deletes so restored
+		// configs don't conflict with job names moved by the batch.
+		ctx := context.Background()
+
+		var failed []string
+		for i := len(written) - 1; i >= 0; i-- {
+			name := written[i]
+			var err error
+			if prev, ok := existing[name]; ok {
+				_, err = api.store.Put(ctx, prev)
+			} else {
+				err = api.store.Delete(ctx, name)
+			}
+			if err != nil {
+				level.Error(api.log).Log("msg", "failed to revert config from batch", "name", name, "err", err)
+				failed = append(failed, name)
+			}
+		}
+		for _, name := range deleted {
+			if _, err := api.store.Put(ctx, existing[name]); err != nil {
+				level.Error(api.log).Log("msg", "failed to revert config from batch", "name", name, "err", err)
+				failed = append(failed, name)
+			}
+		}
+
+		if len(failed) > 0 {
+			sort.Strings(failed)
+			return fmt.Errorf("%s, batch was partially applied and configs %s could not be reverted: %w", msg, strings.Join(failed, ", "), cause)
+		}
+		return fmt.Errorf("%s, batch was reverted: %w", msg, cause)
+	}
+
+	for _, name := range deletes {
+		if err := api.store.Delete(ctx, name); err != nil {
+			return revert(fmt.Sprintf("failed to delete config %q", name), err)
+		}
+		deleted = append(deleted, name)
+	}
+	for _, cfg := range cfgs {
+		if _, err := api.store.Put(ctx, *cfg); err != nil {
+			return revert(fmt.Sprintf("failed to put config %q", cfg.Name), err)
+		}
+		written = append(written, cfg.Name)
+	}
+	return nil
+}
+
 func (api *API) writeError(rw http.ResponseWriter, statusCode int, writeErr error) {
 	err := configapi.WriteError(rw, statusCode, writeErr)
 	if err != nil {
@@ -260,6 +516,29 @@ func getConfigName(r *http.Request) (string, error) {
 	return name, nil
 }
 
+// getBoolParam parses the query parameter key as a bool. A missing parameter
+// is false.
+func getBoolParam(r *http.Request, key string) (bool, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return b, nil
+}
+
+func sortedKeys(m map[string]instance.Config) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 func messageHandlerFunc(statusCode int, msg string) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
 		rw.WriteHeader(statusCode)
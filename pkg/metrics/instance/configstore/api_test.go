@@ -18,6 +18,7 @@ import (
 	"github.com/grafana/agent/pkg/client"
 	"github.com/grafana/agent/pkg/metrics/cluster/configapi"
 	"github.com/grafana/agent/pkg/metrics/instance"
+	"github.com/prometheus/prometheus/config"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -387,6 +388,248 @@ func TestServer_URLEncoded(t *testing.T) {
 	require.Equal(t, http.StatusOK, resp.StatusCode)
 }
 
+func TestServer_PutConfigurations(t *testing.T) {
+	store := newMapStore(
+		instance.Config{Name: "a", ScrapeConfigs: scrapeJobs("job-a")},
+		instance.Config{Name: "b", ScrapeConfigs: scrapeJobs("job-b")},
+	)
+	api := NewAPI(log.NewNopLogger(), store.Mock(), nil, true)
+	env := newAPITestEnvironment(t, api)
+	cli := client.New(env.srv.URL)
+
+	batch := []*instance.Config{
+		{Name: "a", ScrapeConfigs: scrapeJobs("job-a", "job-b")},
+		{Name: "c", ScrapeConfigs: scrapeJobs("job-c")},
+	}
+
+	t.Run("Dry run", func(t *testing.T) {
+		resp, err := cli.PutConfigurations(context.Background(), batch, true, true)
+		require.NoError(t, err)
+		require.Equal(t, &configapi.PutConfigurationsResponse{
+			DryRun:  true,
+			Created: []string{"c"},
+			Updated: []string{"a"},
+			Deleted: []string{"b"},
+		}, resp)
+		require.ElementsMatch(t, []string{"a", "b"}, store.Names())
+	})
+
+	t.Run("Not unique without prune", func(t *testing.T) {
+		_, err := cli.PutConfigurations(context.Background(), batch, false, false)
+		require.EqualError(t, err, `failed to validate config "b": found multiple scrape configs in config store with job name "job-b"`)
+		require.ElementsMatch(t, []string{"a", "b"}, store.Names())
+	})
+
+	t.Run("Applied", func(t *testing.T) {
+		resp, err := cli.PutConfigurations(context.Background(), batch, true, false)
+		require.NoError(t, err)
+		require.Equal(t, &configapi.PutConfigurationsResponse{
+			Created: []string{"c"},
+			Updated: []string{"a"},
+			Deleted: []string{"b"},
+		}, resp)
+		require.ElementsMatch(t, []string{"a", "c"}, store.Names())
+		require.Len(t, store.configs["a"].ScrapeConfigs, 2)
+	})
+}
+
+func TestServer_PutConfigurations_Invalid(t *testing.T) {
+	store := newMapStore()
+	api := NewAPI(log.NewNopLogger(), store.Mock(), func(c *instance.Config) error {
+		if c.Name == "bad" {
+			return fmt.Errorf("custom validation error")
+		}
+		return nil
+	}, true)
+	env := newAPITestEnvironment(t, api)
+
+	tt := []struct {
+		name   string
+		body   string
+		expect string
+	}{
+		{
+			name:   "validator",
+			body:   "name: good\n---\nname: bad\n",
+			expect: `failed to validate config \"bad\": custom validation error`,
+		},
+		{
+			name:   "missing name",
+			body:   "name: good\n---\nhost_filter: true\n",
+			expect: "config 2 in batch is missing a name",
+		},
+		{
+			name:   "duplicate name",
+			body:   "name: good\n---\nname: good\n",
+			expect: `found multiple configs in batch with name \"good\"`,
+		},
+		{
+			name:   "scrubbed secret",
+			body:   "name: good\nremote_write:\n- url: http://localhost:9009/api/prom/push\n  basic_auth:\n    username: user\n    password: <secret>\n",
+			expect: `config \"good\" contains a scrubbed <secret> value, secrets must be set before importing it`,
+		},
+		{
+			name:   "duplicate job",
+			body:   "name: good\nscrape_configs:\n- job_name: job\n---\nname: other\nscrape_configs:\n- job_name: job\n",
+			expect: `failed to validate config \"other\": found multiple scrape configs in config store with job name \"job\"`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Post(env.srv.URL+"/agent/api/v1/configs:batch", "", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			require.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+			require.JSONEq(t, fmt.Sprintf(`{"status": "error", "data": {"error": "%s"}}`, tc.expect), string(body))
+			require.Empty(t, store.Names())
+		})
+	}
+}
+
+func TestServer_PutConfigurations_Revert(t *testing.T) {
+	store := newMapStore(
+		instance.Config{Name: "a", HostFilter: true},
+		instance.Config{Name: "b"},
+	)
+	s := store.Mock()
+	put := s.PutFunc
+	s.PutFunc = func(ctx context.Context, c instance.Config) (bool, error) {
+		if c.Name == "fail" {
+			return false, fmt.Errorf("put failed")
+		}
+		return put(ctx, c)
+	}
+
+	api := NewAPI(log.NewNopLogger(), s, nil, true)
+	env := newAPITestEnvironment(t, api)
+	cli := client.New(env.srv.URL)
+
+	_, err := cli.PutConfigurations(context.Background(), []*instance.Config{
+		{Name: "a"},
+		{Name: "c"},
+		{Name: "fail"},
+	}, true, false)
+	require.EqualError(t, err, `failed to put config "fail", batch was reverted: put failed`)
+
+	require.ElementsMatch(t, []string{"a", "b"}, store.Names())
+	require.True(t, store.configs["a"].HostFilter)
+}
+
+func TestServer_PutConfigurations_RevertFailed(t *testing.T) {
+	store := newMapStore(
+		instance.Config{Name: "a", HostFilter: true},
+		instance.Config{Name: "b"},
+	)
+	s := store.Mock()
+	put := s.PutFunc
+	s.PutFunc = func(ctx context.Context, c instance.Config) (bool, error) {
+		// Fail the batch on "fail" and the revert of "a", leaving "a" as the
+		// batch wrote it.
+		if c.Name == "fail" || (c.Name == "a" && c.HostFilter) {
+			return false, fmt.Errorf("put failed")
+		}
+		return put(ctx, c)
+	}
+
+	api := NewAPI(log.NewNopLogger(), s, nil, true)
+	env := newAPITestEnvironment(t, api)
+	cli := client.New(env.srv.URL)
+
+	_, err := cli.PutConfigurations(context.Background(), []*instance.Config{
+		{Name: "a"},
+		{Name: "c"},
+		{Name: "fail"},
+	}, true, false)
+	require.EqualError(t, err, `failed to put config "fail", batch was partially applied and configs a could not be reverted: put failed`)
+
+	// "c" was reverted and "b" was restored, but "a" keeps the batch's version.
+	require.ElementsMatch(t, []string{"a", "b"}, store.Names())
+	require.False(t, store.configs["a"].HostFilter)
+}
+
+func TestServer_ExportConfigurations(t *testing.T) {
+	a := instance.DefaultConfig
+	a.Name = "a"
+	b := instance.DefaultConfig
+	b.Name = "b"
+	b.HostFilter = true
+
+	store := newMapStore(b, a)
+	api := NewAPI(log.NewNopLogger(), store.Mock(), nil, true)
+	env := newAPITestEnvironment(t, api)
+
+	cli := client.New(env.srv.URL)
+	cfgs, err := cli.ExportConfigurations(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []*instance.Config{&a, &b}, cfgs)
+
+	t.Run("Disabled", func(t *testing.T) {
+		api := NewAPI(log.NewNopLogger(), store.Mock(), nil, false)
+		env := newAPITestEnvironment(t, api)
+
+		resp, err := http.Get(env.srv.URL + "/agent/api/v1/configs:export")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+// mapStore is an in-memory store of configs for testing batch operations.
+type mapStore struct {
+	configs map[string]instance.Config
+}
+
+func newMapStore(cfgs ...instance.Config) *mapStore {
+	s := &mapStore{configs: make(map[string]instance.Config)}
+	for _, cfg := range cfgs {
+		s.configs[cfg.Name] = cfg
+	}
+	return s
+}
+
+func (s *mapStore) Names() []string {
+	names := make([]string, 0, len(s.configs))
+	for name := range s.configs {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (s *mapStore) Mock() *Mock {
+	return &Mock{
+		PutFunc: func(_ context.Context, c instance.Config) (bool, error) {
+			_, exist := s.configs[c.Name]
+			s.configs[c.Name] = c
+			return !exist, nil
+		},
+		DeleteFunc: func(_ context.Context, key string) error {
+			if _, exist := s.configs[key]; !exist {
+				return NotExistError{Key: key}
+			}
+			delete(s.configs, key)
+			return nil
+		},
+		AllFunc: func(_ context.Context, _ func(key string) bool) (<-chan instance.Config, error) {
+			ch := make(chan instance.Config, len(s.configs))
+			for _, cfg := range s.configs {
+				ch <- cfg
+			}
+			close(ch)
+			return ch, nil
+		},
+	}
+}
+
+func scrapeJobs(names ...string) []*config.ScrapeConfig {
+	res := make([]*config.ScrapeConfig, 0, len(names))
+	for _, name := range names {
+		res = append(res, &config.ScrapeConfig{JobName: name})
+	}
+	return res
+}
+
 type apiTestEnvironment struct {
 	srv    *httptest.Server
 	router *mux.Router
@@ -11,6 +11,9 @@ var DefaultGlobalConfig = GlobalConfig{
 type GlobalConfig struct {
 	Prometheus  config.GlobalConfig         `yaml:",inline"`
 	RemoteWrite []*config.RemoteWriteConfig `yaml:"remote_write,omitempty"`
+
+	// Limits are set from the global_limits of the metrics config.
+	Limits LimitsConfig `yaml:"-"`
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
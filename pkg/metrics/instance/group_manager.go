@@ -263,9 +263,10 @@ func hashConfig(c Config) (string, error) {
 		return "", err
 	}
 
-	// Ignore name and scrape configs when hashing
+	// Ignore name, scrape configs, and size hint when hashing
 	groupable.Name = ""
 	groupable.ScrapeConfigs = nil
+	groupable.SizeHint = 0
 
 	// Assign names to remote_write configs if they're not present already.
 	// This is also done in AssignDefaults but is duplicated here for the sake
@@ -352,6 +353,12 @@ func groupConfigs(groupName string, grouped groupedConfigs) (Config, error) {
 	// config? (e.g., job_name = "config_name/job_name").
 	for _, cfg := range cfgs {
 		combined.ScrapeConfigs = append(combined.ScrapeConfigs, cfg.ScrapeConfigs...)
+		for _, sc := range cfg.ScrapeConfigs {
+			if combined.jobConfigs == nil {
+				combined.jobConfigs = make(map[string]string)
+			}
+			combined.jobConfigs[sc.JobName] = cfg.Name
+		}
 	}
 
 	return combined, nil
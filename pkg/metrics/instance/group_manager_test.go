@@ -86,6 +86,7 @@ scrape_configs:
     - targets: [127.0.0.1:12345]
 remote_write: []
 `, gm.groupLookup["configA"]))
+		expect.jobConfigs = map[string]string{"test_job": "configB"}
 
 		innerConfigs := inner.ListConfigs()
 		require.Equal(t, 1, len(innerConfigs))
@@ -125,6 +126,7 @@ scrape_configs:
     - targets: [127.0.0.1:12345]
 remote_write: []
 `, gm.groupLookup["configA"]))
+		expect.jobConfigs = map[string]string{"test_job": "configA"}
 		actual := inner.ListConfigs()[gm.groupLookup["configA"]]
 		require.Equal(t, expect, actual)
 	})
@@ -233,6 +235,7 @@ scrape_configs:
   static_configs:
     - targets: [127.0.0.1:12345]
 remote_write: []`, gm.groupLookup["configB"]))
+		expect.jobConfigs = map[string]string{"test_job2": "configB"}
 		actual := inner.ListConfigs()[gm.groupLookup["configB"]]
 		require.Equal(t, expect, actual)
 		require.Equal(t, 1, len(gm.groups))
@@ -418,6 +421,10 @@ remote_write:
 
 	expect, err := UnmarshalConfig(strings.NewReader(expectText))
 	require.NoError(t, err)
+	expect.jobConfigs = map[string]string{
+		"test_job":  "configA",
+		"test_job2": "configB",
+	}
 
 	// Generate expected remote_write names
 	for _, rwConfig := range expect.RemoteWrite {
@@ -34,6 +34,11 @@ var HostFilterLabelMatchers = []string{
 	"__host__",
 }
 
+// HostFilterOwnedLabel can be set to "true" by host_filter_relabel_configs to
+// keep a target regardless of the host it's running on. This allows custom
+// ownership rules, such as scraping all targets in the same zone as the agent.
+const HostFilterOwnedLabel = "__host_filter_owned__"
+
 // DiscoveredGroups is a set of groups found via service discovery.
 type DiscoveredGroups = map[string][]*targetgroup.Group
 
@@ -74,7 +79,20 @@ func NewHostFilter(host string, relabels []*relabel.Config) *HostFilter {
 // PatchSD patches services discoveries to optimize performance for host
 // filtering. The discovered targets will be pruned to as close to the set
 // that HostFilter will output as possible.
+//
+// Service discoveries aren't patched when the relabeling rules may claim
+// ownership of targets running on other hosts.
 func (f *HostFilter) PatchSD(scrapes []*config.ScrapeConfig) {
+	f.relabelMut.Lock()
+	relabels := f.relabels
+	f.relabelMut.Unlock()
+
+	for _, rc := range relabels {
+		if rc.TargetLabel == HostFilterOwnedLabel {
+			return
+		}
+	}
+
 	for _, sc := range scrapes {
 		for _, d := range sc.ServiceDiscoveryConfigs {
 			switch d := d.(type) {
@@ -137,6 +155,9 @@ func (f *HostFilter) SyncCh() GroupChannel {
 //
 // If the discovered address is localhost or 127.0.0.1, the group is never
 // filtered out.
+//
+// Targets dropped by configs are always filtered out, and targets which have
+// HostFilterOwnedLabel set to "true" by configs are never filtered out.
 func FilterGroups(in DiscoveredGroups, host string, configs []*relabel.Config) DiscoveredGroups {
 	out := make(DiscoveredGroups, len(in))
 
@@ -153,8 +174,11 @@ func FilterGroups(in DiscoveredGroups, host string, configs []*relabel.Config) D
 			for _, target := range group.Targets {
 				allLabels := mergeSets(target, group.Labels)
 				processedLabels := relabel.Process(toLabelSlice(allLabels), configs...)
+				if processedLabels == nil {
+					continue
+				}
 
-				if !shouldFilterTarget(processedLabels, host) {
+				if processedLabels.Get(HostFilterOwnedLabel) == "true" || !shouldFilterTarget(processedLabels, host) {
 					newGroup.Targets = append(newGroup.Targets, target)
 				}
 			}
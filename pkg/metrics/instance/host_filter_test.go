@@ -6,6 +6,7 @@ import (
 	"github.com/grafana/agent/pkg/util"
 	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/discovery/kubernetes"
 	"github.com/prometheus/prometheus/discovery/targetgroup"
 	"github.com/prometheus/prometheus/pkg/relabel"
 	"github.com/stretchr/testify/require"
@@ -195,3 +196,55 @@ func TestHostFilter_PatchSD(t *testing.T) {
 	require.NoError(t, err)
 	require.YAMLEq(t, expect, string(output))
 }
+
+func TestFilterGroups_Ownership(t *testing.T) {
+	// Own every target in the zone of the agent, and drop targets from other
+	// zones even if they're running on the agent's host.
+	relabelConfig := []*relabel.Config{
+		{
+			SourceLabels: model.LabelNames{"__meta_zone"},
+			Action:       relabel.Keep,
+			Separator:    ";",
+			Regex:        relabel.MustNewRegexp("zone-a"),
+		},
+		{
+			SourceLabels: model.LabelNames{"__meta_zone"},
+			Action:       relabel.Replace,
+			Separator:    ";",
+			Regex:        relabel.MustNewRegexp("zone-a"),
+			Replacement:  "true",
+			TargetLabel:  HostFilterOwnedLabel,
+		},
+	}
+
+	group := makeGroup([]model.LabelSet{
+		{model.AddressLabel: "otherhost:80", "__meta_zone": "zone-a"},
+		{model.AddressLabel: "myhost:80", "__meta_zone": "zone-b"},
+	})
+	groups := DiscoveredGroups{"test": []*targetgroup.Group{group}}
+
+	result := FilterGroups(groups, "myhost", relabelConfig)
+	require.Equal(t, []model.LabelSet{
+		{model.AddressLabel: "otherhost:80", "__meta_zone": "zone-a"},
+	}, result["test"][0].Targets)
+}
+
+func TestHostFilter_PatchSD_Ownership(t *testing.T) {
+	var input []*config.ScrapeConfig
+	err := yaml.Unmarshal([]byte(util.Untab(`
+- job_name: default
+  kubernetes_sd_configs:
+	  - role: pod`)), &input)
+	require.NoError(t, err)
+
+	// Targets on other hosts may be owned, so they must still be discovered.
+	NewHostFilter("myhost", []*relabel.Config{{
+		Action:      relabel.Replace,
+		Regex:       relabel.MustNewRegexp("(.*)"),
+		Replacement: "true",
+		TargetLabel: HostFilterOwnedLabel,
+	}}).PatchSD(input)
+
+	sd := input[0].ServiceDiscoveryConfigs[0].(*kubernetes.SDConfig)
+	require.Empty(t, sd.Selectors)
+}
@@ -16,6 +16,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/alecthomas/units"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/grafana/agent/pkg/build"
@@ -25,6 +26,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/config"
 	"github.com/prometheus/prometheus/discovery"
+	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/pkg/relabel"
 	"github.com/prometheus/prometheus/pkg/timestamp"
 	"github.com/prometheus/prometheus/scrape"
@@ -45,6 +47,9 @@ func init() {
 var (
 	remoteWriteMetricName = "queue_highest_sent_timestamp_seconds"
 	managerMtx            sync.Mutex
+
+	// How frequently the size of the WAL is compared against max_wal_size.
+	walSizeCheckFrequency = time.Minute
 )
 
 // Default configuration values
@@ -69,6 +74,9 @@ type Config struct {
 	ScrapeConfigs            []*config.ScrapeConfig      `yaml:"scrape_configs,omitempty"`
 	RemoteWrite              []*config.RemoteWriteConfig `yaml:"remote_write,omitempty"`
 
+	// Spreads series across remote_write endpoints by the hash of their labels.
+	RemoteWriteSharding *RemoteWriteShardingConfig `yaml:"remote_write_sharding,omitempty"`
+
 	// How frequently the WAL should be truncated.
 	WALTruncateFrequency time.Duration `yaml:"wal_truncate_frequency,omitempty"`
 
@@ -79,7 +87,27 @@ type Config struct {
 	RemoteFlushDeadline  time.Duration `yaml:"remote_flush_deadline,omitempty"`
 	WriteStaleOnShutdown bool          `yaml:"write_stale_on_shutdown,omitempty"`
 
+	// How far behind the newest sample of a series a sample may be before it
+	// is rejected as out of order. 0 accepts samples regardless of order.
+	OutOfOrderTimeWindow time.Duration `yaml:"out_of_order_time_window,omitempty"`
+
+	// Maximum size of the WAL on disk, and what to do when it's exceeded. 0
+	// doesn't limit the size of the WAL. WALFullPolicy defaults to
+	// wal.FullPolicyDropOldest.
+	MaxWALSize    units.Base2Bytes `yaml:"max_wal_size,omitempty"`
+	WALFullPolicy wal.FullPolicy   `yaml:"wal_full_policy,omitempty"`
+
+	RecordingRules RecordingRulesConfig `yaml:"recording_rules,omitempty"`
+
+	// Expected relative size of the instance, such as its number of targets or
+	// series. Used by the scraping service to balance configs between agents.
+	SizeHint int `yaml:"size_hint,omitempty"`
+
 	global GlobalConfig `yaml:"-"`
+
+	// Names of the configs that scrape jobs were taken from when the Config
+	// combines a group of configs. Jobs not in the map belong to this Config.
+	jobConfigs map[string]string
 }
 
 // UnmarshalYAML implements yaml.Unmarshaler.
@@ -109,6 +137,20 @@ func (c Config) MarshalYAML() (interface{}, error) {
 	return m, nil
 }
 
+// MarshalUnredactedYAML implements util.UnredactedMarshaler.
+func (c Config) MarshalUnredactedYAML() (interface{}, error) {
+	bb, err := MarshalConfig(&c, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var m yaml.MapSlice
+	if err := yaml.Unmarshal(bb, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // ApplyDefaults applies default configurations to the configuration to all
 // values that have not been changed to their non-zero value. ApplyDefaults
 // also validates the config.
@@ -126,6 +168,18 @@ func (c *Config) ApplyDefaults(global GlobalConfig) error {
 		return errors.New("remote_flush_deadline must be greater than 0s")
 	case c.MinWALTime > c.MaxWALTime:
 		return errors.New("min_wal_time must be less than max_wal_time")
+	case c.OutOfOrderTimeWindow < 0:
+		return errors.New("out_of_order_time_window must not be negative")
+	case c.MaxWALSize < 0:
+		return errors.New("max_wal_size must not be negative")
+	case c.SizeHint < 0:
+		return errors.New("size_hint must not be negative")
+	case c.WALFullPolicy != "" && c.WALFullPolicy != wal.FullPolicyDropOldest && c.WALFullPolicy != wal.FullPolicyPauseScraping:
+		return fmt.Errorf("wal_full_policy must be %q or %q", wal.FullPolicyDropOldest, wal.FullPolicyPauseScraping)
+	}
+
+	if err := c.RecordingRules.Validate(); err != nil {
+		return fmt.Errorf("invalid recording_rules: %w", err)
 	}
 
 	jobNames := map[string]struct{}{}
@@ -196,6 +250,12 @@ func (c *Config) ApplyDefaults(global GlobalConfig) error {
 		rwNames[cfg.Name] = struct{}{}
 	}
 
+	if c.RemoteWriteSharding != nil {
+		if err := c.RemoteWriteSharding.Validate(); err != nil {
+			return fmt.Errorf("invalid remote_write_sharding: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -240,11 +300,14 @@ type Instance struct {
 	readyScrapeManager *readyScrapeManager
 	remoteStore        *remote.Storage
 	storage            storage.Storage
+	appendable         storage.Appendable // WAL storage with limits enforced.
+	rules              *ruleEvaluator     // nil when there are no recording rules.
 
 	// ready is set to true after the initialization process finishes
 	ready atomic.Bool
 
 	hostFilter *HostFilter
+	targets    *TargetRegistry // nil when targets can't be registered at runtime.
 
 	logger log.Logger
 
@@ -254,18 +317,31 @@ type Instance struct {
 	vc *MetricValueCollector
 }
 
-// New creates a new Instance with a directory for storing the WAL. The instance
-// will not start until Run is called on the instance.
-func New(reg prometheus.Registerer, cfg Config, walDir string, logger log.Logger) (*Instance, error) {
+// New creates a new Instance with a directory for storing the WAL. Targets
+// registered in targets are scraped in addition to discovered targets;
+// targets may be nil. The instance will not start until Run is called on the
+// instance.
+func New(reg prometheus.Registerer, cfg Config, walDir string, targets *TargetRegistry, logger log.Logger) (*Instance, error) {
 	logger = log.With(logger, "instance", cfg.Name)
 
 	instWALDir := filepath.Join(walDir, cfg.Name)
 
 	newWal := func(reg prometheus.Registerer) (walStorage, error) {
-		return wal.NewStorage(logger, reg, instWALDir)
+		s, err := wal.NewStorage(logger, reg, instWALDir)
+		if err != nil {
+			return nil, err
+		}
+		s.SetOutOfOrderTimeWindow(cfg.OutOfOrderTimeWindow)
+		s.SetMaxSeries(cfg.global.Limits.MaxSeriesPerInstance)
+		return s, nil
 	}
 
-	return newInstance(cfg, reg, logger, newWal)
+	i, err := newInstance(cfg, reg, logger, newWal)
+	if err != nil {
+		return nil, err
+	}
+	i.targets = targets
+	return i, nil
 }
 
 func newInstance(cfg Config, reg prometheus.Registerer, logger log.Logger, newWal walStorageFactory) (*Instance, error) {
@@ -349,6 +425,43 @@ func (i *Instance) Run(ctx context.Context) error {
 			},
 		)
 	}
+	if cfg.MaxWALSize > 0 {
+		// WAL size loop
+		ctx, contextCancel := context.WithCancel(context.Background())
+		defer contextCancel()
+		rg.Add(
+			func() error {
+				i.walSizeLoop(ctx, i.wal, &cfg)
+				level.Info(i.logger).Log("msg", "WAL size loop stopped")
+				return nil
+			},
+			func(err error) {
+				level.Info(i.logger).Log("msg", "stopping WAL size loop...")
+				contextCancel()
+			},
+		)
+	}
+	if i.rules != nil {
+		// Rule manager
+		ctx, contextCancel := context.WithCancel(context.Background())
+		defer contextCancel()
+		stopped := make(chan struct{})
+		rg.Add(
+			func() error {
+				defer close(stopped)
+				i.rules.Run(ctx)
+				level.Info(i.logger).Log("msg", "rule manager stopped")
+				return nil
+			},
+			func(err error) {
+				// Rule evaluation must stop before the storage it writes to is
+				// closed.
+				level.Info(i.logger).Log("msg", "stopping rule manager...")
+				contextCancel()
+				<-stopped
+			},
+		)
+	}
 	{
 		sm, err := i.readyScrapeManager.Get()
 		if err != nil {
@@ -395,6 +508,11 @@ func (i *Instance) Run(ctx context.Context) error {
 	if err != nil {
 		level.Error(i.logger).Log("msg", "agent instance stopped with error", "err", err)
 	}
+	if i.rules != nil {
+		if err := i.rules.Close(); err != nil {
+			level.Error(i.logger).Log("msg", "error closing rule storage", "err", err)
+		}
+	}
 	return err
 }
 
@@ -429,7 +547,7 @@ func (i *Instance) initialize(ctx context.Context, reg prometheus.Registerer, cf
 	i.remoteStore = remote.NewStorage(remoteLogger, reg, i.wal.StartTime, i.wal.Directory(), cfg.RemoteFlushDeadline, i.readyScrapeManager)
 	err = i.remoteStore.ApplyConfig(&config.Config{
 		GlobalConfig:       cfg.global.Prometheus,
-		RemoteWriteConfigs: cfg.RemoteWrite,
+		RemoteWriteConfigs: shardRemoteWrite(cfg),
 	})
 	if err != nil {
 		return fmt.Errorf("failed applying config to remote storage: %w", err)
@@ -437,7 +555,32 @@ func (i *Instance) initialize(ctx context.Context, reg prometheus.Registerer, cf
 
 	i.storage = storage.NewFanout(i.logger, i.wal, i.remoteStore)
 
-	scrapeManager := newScrapeManager(log.With(i.logger, "component", "scrape manager"), i.storage)
+	var (
+		walAppendable     storage.Appendable = i.wal
+		storageAppendable storage.Appendable = i.storage
+	)
+	i.rules = nil
+	if len(cfg.RecordingRules.Groups) > 0 {
+		rulesLogger := log.With(i.logger, "component", "rules")
+		rulesDir := filepath.Join(i.wal.Directory(), "rules")
+		i.rules, err = newRuleEvaluator(rulesLogger, reg, rulesDir, i.storage, cfg)
+		if err != nil {
+			return fmt.Errorf("error creating rule manager: %w", err)
+		}
+
+		// Samples must also be written to the rule storage so rules can be
+		// evaluated against them.
+		walAppendable = i.rules.Appendable(i.wal)
+		storageAppendable = i.rules.Appendable(i.storage)
+	}
+
+	i.appendable = &limitsAppendable{Appendable: walAppendable, instance: cfg.Name, limits: cfg.global.Limits}
+
+	scrapeManager := newScrapeManager(log.With(i.logger, "component", "scrape manager"), &limitsAppendable{
+		Appendable: storageAppendable,
+		instance:   cfg.Name,
+		limits:     cfg.global.Limits,
+	})
 	err = scrapeManager.ApplyConfig(&config.Config{
 		GlobalConfig:  cfg.global.Prometheus,
 		ScrapeConfigs: cfg.ScrapeConfigs,
@@ -457,32 +600,54 @@ func (i *Instance) Ready() bool {
 	return i.ready.Load()
 }
 
-// Update accepts a new Config for the Instance and will dynamically update any
-// running Prometheus components with the new values from Config. Update will
-// return an ErrInvalidUpdate if the Update could not be applied.
-func (i *Instance) Update(c Config) (err error) {
-	i.mut.Lock()
-	defer i.mut.Unlock()
-
+// CheckUpdate returns an ErrInvalidUpdate if an instance running prev can't
+// be updated to next with Update and has to be restarted instead.
+func CheckUpdate(prev, next Config) (err error) {
 	// It's only (currently) valid to update scrape_configs and remote_write, so
 	// if any other field has changed here, return the error.
 	switch {
 	// This first case should never happen in practice but it's included here for
 	// completions sake.
-	case i.cfg.Name != c.Name:
+	case prev.Name != next.Name:
 		err = errImmutableField{Field: "name"}
-	case i.cfg.HostFilter != c.HostFilter:
+	case prev.HostFilter != next.HostFilter:
 		err = errImmutableField{Field: "host_filter"}
-	case i.cfg.WALTruncateFrequency != c.WALTruncateFrequency:
+	case prev.WALTruncateFrequency != next.WALTruncateFrequency:
 		err = errImmutableField{Field: "wal_truncate_frequency"}
-	case i.cfg.RemoteFlushDeadline != c.RemoteFlushDeadline:
+	case prev.RemoteFlushDeadline != next.RemoteFlushDeadline:
 		err = errImmutableField{Field: "remote_flush_deadline"}
-	case i.cfg.WriteStaleOnShutdown != c.WriteStaleOnShutdown:
+	case prev.WriteStaleOnShutdown != next.WriteStaleOnShutdown:
 		err = errImmutableField{Field: "write_stale_on_shutdown"}
+	case prev.OutOfOrderTimeWindow != next.OutOfOrderTimeWindow:
+		err = errImmutableField{Field: "out_of_order_time_window"}
+	case prev.MaxWALSize != next.MaxWALSize:
+		err = errImmutableField{Field: "max_wal_size"}
+	case prev.WALFullPolicy != next.WALFullPolicy:
+		err = errImmutableField{Field: "wal_full_policy"}
+	case prev.global.Limits != next.global.Limits:
+		err = errImmutableField{Field: "global_limits"}
+	case prev.RecordingRules.Retention != next.RecordingRules.Retention:
+		err = errImmutableField{Field: "recording_rules.retention"}
+	case (len(prev.RecordingRules.Groups) > 0) != (len(next.RecordingRules.Groups) > 0):
+		// The rule storage is only created when there are rules to evaluate.
+		err = errImmutableField{Field: "recording_rules"}
 	}
 	if err != nil {
 		return ErrInvalidUpdate{Inner: err}
 	}
+	return nil
+}
+
+// Update accepts a new Config for the Instance and will dynamically update any
+// running Prometheus components with the new values from Config. Update will
+// return an ErrInvalidUpdate if the Update could not be applied.
+func (i *Instance) Update(c Config) (err error) {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	if err = CheckUpdate(i.cfg, c); err != nil {
+		return err
+	}
 
 	// Check to see if the components exist yet.
 	if i.discovery == nil || i.remoteStore == nil || i.readyScrapeManager == nil {
@@ -498,8 +663,9 @@ func (i *Instance) Update(c Config) (err error) {
 	//
 	// 1. Local config
 	// 2. Remote Store
-	// 3. Scrape Manager
-	// 4. Discovery Manager
+	// 3. Rule Manager
+	// 4. Scrape Manager
+	// 5. Discovery Manager
 
 	originalConfig := i.cfg
 	defer func() {
@@ -518,12 +684,18 @@ func (i *Instance) Update(c Config) (err error) {
 
 	err = i.remoteStore.ApplyConfig(&config.Config{
 		GlobalConfig:       c.global.Prometheus,
-		RemoteWriteConfigs: c.RemoteWrite,
+		RemoteWriteConfigs: shardRemoteWrite(&c),
 	})
 	if err != nil {
 		return fmt.Errorf("error applying new remote_write configs: %w", err)
 	}
 
+	if i.rules != nil {
+		if err := i.rules.ApplyConfig(&c); err != nil {
+			return err
+		}
+	}
+
 	sm, err := i.readyScrapeManager.Get()
 	if err != nil {
 		return fmt.Errorf("couldn't get scrape manager to apply new scrape configs: %w", err)
@@ -536,11 +708,7 @@ func (i *Instance) Update(c Config) (err error) {
 		return fmt.Errorf("error applying updated configs to scrape manager: %w", err)
 	}
 
-	sdConfigs := map[string]discovery.Configs{}
-	for _, v := range c.ScrapeConfigs {
-		sdConfigs[v.JobName] = v.ServiceDiscoveryConfigs
-	}
-	err = i.discovery.Manager.ApplyConfig(sdConfigs)
+	err = i.discovery.Manager.ApplyConfig(i.discoveryConfigs(&c))
 	if err != nil {
 		return fmt.Errorf("failed applying configs to discovery manager: %w", err)
 	}
@@ -576,7 +744,33 @@ func (i *Instance) StorageDirectory() string {
 
 // Appender returns a storage.Appender from the instance's WAL
 func (i *Instance) Appender(ctx context.Context) storage.Appender {
-	return i.wal.Appender(ctx)
+	return i.appendable.Appender(ctx)
+}
+
+// LatestSamples returns the newest sample of every series in the instance's
+// WAL matching all matchers. Series without samples since mint are skipped.
+func (i *Instance) LatestSamples(mint int64, matchers ...*labels.Matcher) ([]wal.Sample, error) {
+	i.mut.Lock()
+	w := i.wal
+	i.mut.Unlock()
+
+	if w == nil {
+		return nil, errWALNotReady
+	}
+	return w.LatestSamples(mint, matchers...), nil
+}
+
+// Querier returns a storage.Querier which reads samples between mint and maxt
+// from the instance's WAL.
+func (i *Instance) Querier(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+	i.mut.Lock()
+	w := i.wal
+	i.mut.Unlock()
+
+	if w == nil {
+		return nil, errWALNotReady
+	}
+	return w.Querier(ctx, mint, maxt)
 }
 
 type discoveryService struct {
@@ -591,6 +785,30 @@ func (s *discoveryService) Run() error           { return s.RunFunc() }
 func (s *discoveryService) Stop(err error)       { s.StopFunc(err) }
 func (s *discoveryService) SyncCh() GroupChannel { return s.SyncChFunc() }
 
+// discoveryConfigs returns the service discovery configs of each job in cfg.
+// Jobs also discover the targets registered to them in i.targets.
+func (i *Instance) discoveryConfigs(cfg *Config) map[string]discovery.Configs {
+	c := map[string]discovery.Configs{}
+	for _, v := range cfg.ScrapeConfigs {
+		c[v.JobName] = v.ServiceDiscoveryConfigs
+		if i.targets == nil {
+			continue
+		}
+
+		configName, ok := cfg.jobConfigs[v.JobName]
+		if !ok {
+			configName = cfg.Name
+		}
+		sdConfigs := make(discovery.Configs, 0, len(v.ServiceDiscoveryConfigs)+1)
+		sdConfigs = append(sdConfigs, v.ServiceDiscoveryConfigs...)
+		c[v.JobName] = append(sdConfigs, &registrySDConfig{
+			registry: i.targets,
+			key:      targetKey{Instance: configName, Job: v.JobName},
+		})
+	}
+	return c
+}
+
 // newDiscoveryManager returns an implementation of a runnable service
 // that outputs discovered targets to a channel. The implementation
 // uses the Prometheus Discovery Manager. Targets will be filtered
@@ -601,13 +819,8 @@ func (i *Instance) newDiscoveryManager(ctx context.Context, cfg *Config) (*disco
 	logger := log.With(i.logger, "component", "discovery manager")
 	manager := discovery.NewManager(ctx, logger, discovery.Name("scrape"))
 
-	// TODO(rfratto): refactor this to a function?
 	// TODO(rfratto): ensure job name name is unique
-	c := map[string]discovery.Configs{}
-	for _, v := range cfg.ScrapeConfigs {
-		c[v.JobName] = v.ServiceDiscoveryConfigs
-	}
-	err := manager.ApplyConfig(c)
+	err := manager.ApplyConfig(i.discoveryConfigs(cfg))
 	if err != nil {
 		cancel()
 		level.Error(i.logger).Log("msg", "failed applying config to discovery manager", "err", err)
@@ -699,6 +912,30 @@ func (i *Instance) truncateLoop(ctx context.Context, wal walStorage, cfg *Config
 	}
 }
 
+// walSizeLoop periodically enforces the max_wal_size of cfg.
+func (i *Instance) walSizeLoop(ctx context.Context, w walStorage, cfg *Config) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(walSizeCheckFrequency):
+			// Dropping the oldest samples keeps the last min_wal_time of data,
+			// regardless of what has been sent over remote_write.
+			mint := timestamp.FromTime(time.Now().Add(-cfg.MinWALTime))
+
+			policy := cfg.WALFullPolicy
+			if policy == "" {
+				policy = wal.FullPolicyDropOldest
+			}
+
+			err := w.EnforceMaxSize(int64(cfg.MaxWALSize), policy, mint)
+			if err != nil {
+				level.Warn(i.logger).Log("msg", "could not enforce max_wal_size", "err", err)
+			}
+		}
+	}
+}
+
 // getRemoteWriteTimestamp looks up the last successful remote write timestamp.
 // This is passed to wal.Storage for its truncation. If no remote write sections
 // are configured, getRemoteWriteTimestamp returns the current time.
@@ -741,7 +978,7 @@ func (i *Instance) getRemoteWriteTimestamp() int64 {
 
 // walStorage is an interface satisfied by wal.Storage, and created for testing.
 type walStorage interface {
-	// walStorage implements Queryable/ChunkQueryable for compatibility, but is unused.
+	// walStorage implements ChunkQueryable for compatibility, but is unused.
 	storage.Queryable
 	storage.ChunkQueryable
 
@@ -751,6 +988,8 @@ type walStorage interface {
 	WriteStalenessMarkers(remoteTsFunc func() int64) error
 	Appender(context.Context) storage.Appender
 	Truncate(mint int64) error
+	EnforceMaxSize(maxSize int64, policy wal.FullPolicy, mint int64) error
+	LatestSamples(mint int64, matchers ...*labels.Matcher) []wal.Sample
 
 	Close() error
 }
@@ -898,6 +1137,8 @@ func (rg *runGroupContext) Stop(_ error) { rg.cancel() }
 // initialized yet.
 var ErrNotReady = errors.New("Scrape manager not ready")
 
+var errWALNotReady = errors.New("WAL not ready")
+
 // readyScrapeManager allows a scrape manager to be retrieved. Even if it's set at a later point in time.
 type readyScrapeManager struct {
 	mtx sync.RWMutex
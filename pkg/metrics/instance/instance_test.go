@@ -12,8 +12,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alecthomas/units"
 	"github.com/cortexproject/cortex/pkg/util/test"
 	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/metrics/wal"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/prometheus/common/model"
@@ -54,6 +56,22 @@ remote_write:
 	}
 }
 
+func TestConfig_Unmarshal_MaxWALSize(t *testing.T) {
+	cfgText := `name: test
+max_wal_size: 2GiB
+wal_full_policy: pause_scraping`
+
+	cfg, err := UnmarshalConfig(strings.NewReader(cfgText))
+	require.NoError(t, err)
+	require.Equal(t, 2*units.GiB, cfg.MaxWALSize)
+	require.Equal(t, wal.FullPolicyPauseScraping, cfg.WALFullPolicy)
+
+	// Marshaling and unmarshaling the config must retain the size.
+	cp, err := cfg.Clone()
+	require.NoError(t, err)
+	require.Equal(t, cfg.MaxWALSize, cp.MaxWALSize)
+}
+
 func TestConfig_ApplyDefaults_Validations(t *testing.T) {
 	global := DefaultGlobalConfig
 	cfg := DefaultConfig
@@ -135,6 +153,33 @@ func TestConfig_ApplyDefaults_Validations(t *testing.T) {
 			},
 			fmt.Errorf("found duplicate remote write configs with name \"foo\""),
 		},
+		{
+			"invalid wal full policy",
+			func(c *Config) { c.WALFullPolicy = "drop_newest" },
+			fmt.Errorf("wal_full_policy must be \"drop_oldest\" or \"pause_scraping\""),
+		},
+		{
+			"remote_write sharding without source labels",
+			func(c *Config) { c.RemoteWriteSharding = &RemoteWriteShardingConfig{} },
+			fmt.Errorf("invalid remote_write_sharding: source_labels must not be empty"),
+		},
+		{
+			"multiple rule groups with same name",
+			func(c *Config) {
+				c.RecordingRules.Groups = []RecordingRuleGroup{{Name: "foo"}, {Name: "foo"}}
+			},
+			fmt.Errorf("invalid recording_rules: found multiple rule groups with name \"foo\""),
+		},
+		{
+			"invalid recording rule name",
+			func(c *Config) {
+				c.RecordingRules.Groups = []RecordingRuleGroup{{
+					Name:  "foo",
+					Rules: []RecordingRule{{Record: "job:up sum", Expr: "sum(up)"}},
+				}}
+			},
+			fmt.Errorf("invalid recording_rules: invalid record name \"job:up sum\" in rule group \"foo\""),
+		},
 	}
 
 	for _, tc := range tt {
@@ -200,7 +245,7 @@ func TestInstance_Path(t *testing.T) {
 	cfg.RemoteFlushDeadline = time.Hour
 
 	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
-	inst, err := New(prometheus.NewRegistry(), cfg, walDir, logger)
+	inst, err := New(prometheus.NewRegistry(), cfg, walDir, nil, logger)
 	require.NoError(t, err)
 	runInstance(t, inst)
 
@@ -264,7 +309,7 @@ func TestInstance_Recreate(t *testing.T) {
 	cfg.RemoteFlushDeadline = time.Hour
 
 	logger := log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr))
-	inst, err := New(prometheus.NewRegistry(), cfg, walDir, logger)
+	inst, err := New(prometheus.NewRegistry(), cfg, walDir, nil, logger)
 	require.NoError(t, err)
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -284,7 +329,7 @@ func TestInstance_Recreate(t *testing.T) {
 
 	// Recreate the instance, no panic should happen.
 	require.NotPanics(t, func() {
-		inst, err := New(prometheus.NewRegistry(), cfg, walDir, logger)
+		inst, err := New(prometheus.NewRegistry(), cfg, walDir, nil, logger)
 		require.NoError(t, err)
 		runInstance(t, inst)
 
@@ -416,6 +461,13 @@ func (s *mockWalStorage) StartTime() (int64, error)                  { return 0,
 func (s *mockWalStorage) WriteStalenessMarkers(f func() int64) error { return nil }
 func (s *mockWalStorage) Close() error                               { return nil }
 func (s *mockWalStorage) Truncate(mint int64) error                  { return nil }
+func (s *mockWalStorage) EnforceMaxSize(int64, wal.FullPolicy, int64) error {
+	return nil
+}
+
+func (s *mockWalStorage) LatestSamples(int64, ...*labels.Matcher) []wal.Sample {
+	return nil
+}
 
 func (s *mockWalStorage) Appender(context.Context) storage.Appender {
 	return &mockAppender{s: s}
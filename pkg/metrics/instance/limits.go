@@ -0,0 +1,87 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/agent/pkg/metrics/wal"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/value"
+	"github.com/prometheus/prometheus/storage"
+)
+
+var limitsExceeded = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "agent_metrics_limits_exceeded_total",
+	Help: "Total number of times a limit from global_limits was exceeded",
+}, []string{"instance_name", "limit"})
+
+// LimitsConfig holds limits enforced on every instance, protecting the agent
+// against cardinality explosions. A limit of 0 is not enforced.
+type LimitsConfig struct {
+	// Maximum number of active series in the WAL of an instance. Samples for
+	// new series are dropped once the limit is reached.
+	MaxSeriesPerInstance int `yaml:"max_series_per_instance,omitempty"`
+
+	// Maximum number of samples a single scrape of a target may append. Scrapes
+	// exceeding the limit fail, like scrapes exceeding sample_limit.
+	MaxSamplesPerTarget int `yaml:"max_samples_per_target,omitempty"`
+}
+
+// Validate returns an error if c is invalid.
+func (c LimitsConfig) Validate() error {
+	switch {
+	case c.MaxSeriesPerInstance < 0:
+		return errors.New("max_series_per_instance must not be negative")
+	case c.MaxSamplesPerTarget < 0:
+		return errors.New("max_samples_per_target must not be negative")
+	}
+	return nil
+}
+
+// limitsAppendable wraps an Appendable, enforcing limits on its Appenders.
+// Each Appender is used for a single scrape of a single target.
+type limitsAppendable struct {
+	storage.Appendable
+
+	instance string
+	limits   LimitsConfig
+}
+
+func (a *limitsAppendable) Appender(ctx context.Context) storage.Appender {
+	app := a.Appendable.Appender(ctx)
+	if a.limits == (LimitsConfig{}) {
+		return app
+	}
+	return &limitsAppender{Appender: app, instance: a.instance, limits: a.limits}
+}
+
+type limitsAppender struct {
+	storage.Appender
+
+	instance string
+	limits   LimitsConfig
+	samples  int
+}
+
+func (a *limitsAppender) Append(ref uint64, l labels.Labels, t int64, v float64) (uint64, error) {
+	// Staleness markers are written when a scrape fails, and mustn't count
+	// against the limit.
+	if !value.IsStaleNaN(v) {
+		a.samples++
+	}
+	if a.limits.MaxSamplesPerTarget > 0 && a.samples > a.limits.MaxSamplesPerTarget {
+		if a.samples == a.limits.MaxSamplesPerTarget+1 {
+			limitsExceeded.WithLabelValues(a.instance, "max_samples_per_target").Inc()
+		}
+		return 0, fmt.Errorf("target exceeded max_samples_per_target of %d", a.limits.MaxSamplesPerTarget)
+	}
+
+	ref, err := a.Appender.Append(ref, l, t, v)
+	if errors.Is(err, wal.ErrSeriesLimit) {
+		limitsExceeded.WithLabelValues(a.instance, "max_series_per_instance").Inc()
+	}
+	return ref, err
+}
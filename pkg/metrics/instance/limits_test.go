@@ -0,0 +1,79 @@
+package instance
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/grafana/agent/pkg/metrics/wal"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimitsAppender_MaxSamplesPerTarget(t *testing.T) {
+	s := &mockWalStorage{series: make(map[uint64]int)}
+	app := (&limitsAppendable{
+		Appendable: s,
+		instance:   t.Name(),
+		limits:     LimitsConfig{MaxSamplesPerTarget: 2},
+	}).Appender(context.Background())
+
+	exceeded := limitsExceeded.WithLabelValues(t.Name(), "max_samples_per_target")
+
+	_, err := app.Append(0, labels.FromStrings("a", "1"), 0, 1)
+	require.NoError(t, err)
+	_, err = app.Append(0, labels.FromStrings("a", "2"), 0, 1)
+	require.NoError(t, err)
+
+	// Staleness markers aren't counted.
+	_, err = app.Append(0, labels.FromStrings("a", "3"), 0, math.Float64frombits(value.StaleNaN))
+	require.NoError(t, err)
+
+	_, err = app.Append(0, labels.FromStrings("a", "4"), 0, 1)
+	require.EqualError(t, err, "target exceeded max_samples_per_target of 2")
+	_, err = app.Append(0, labels.FromStrings("a", "5"), 0, 1)
+	require.Error(t, err)
+	require.Equal(t, float64(1), testutil.ToFloat64(exceeded))
+
+	// Limits apply to each Appender separately.
+	app = (&limitsAppendable{
+		Appendable: s,
+		instance:   t.Name(),
+		limits:     LimitsConfig{MaxSamplesPerTarget: 2},
+	}).Appender(context.Background())
+	_, err = app.Append(0, labels.FromStrings("a", "1"), 0, 1)
+	require.NoError(t, err)
+}
+
+func TestLimitsAppender_MaxSeriesPerInstance(t *testing.T) {
+	app := (&limitsAppendable{
+		Appendable: seriesLimitAppendable{},
+		instance:   t.Name(),
+		limits:     LimitsConfig{MaxSeriesPerInstance: 1},
+	}).Appender(context.Background())
+
+	_, err := app.Append(0, labels.FromStrings("a", "1"), 0, 1)
+	require.ErrorIs(t, err, wal.ErrSeriesLimit)
+
+	exceeded := limitsExceeded.WithLabelValues(t.Name(), "max_series_per_instance")
+	require.Equal(t, float64(1), testutil.ToFloat64(exceeded))
+}
+
+// seriesLimitAppendable returns Appenders which reject all samples with
+// wal.ErrSeriesLimit.
+type seriesLimitAppendable struct{}
+
+func (seriesLimitAppendable) Appender(context.Context) storage.Appender {
+	return seriesLimitAppender{}
+}
+
+type seriesLimitAppender struct {
+	storage.Appender
+}
+
+func (seriesLimitAppender) Append(uint64, labels.Labels, int64, float64) (uint64, error) {
+	return 0, wal.ErrSeriesLimit
+}
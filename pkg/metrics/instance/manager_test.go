@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	"github.com/go-kit/log"
+	"github.com/grafana/agent/pkg/metrics/wal"
+	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/scrape"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/stretchr/testify/require"
@@ -104,6 +106,8 @@ type mockInstance struct {
 	TargetsActiveFunc    func() map[string][]*scrape.Target
 	StorageDirectoryFunc func() string
 	AppenderFunc         func() storage.Appender
+	LatestSamplesFunc    func(mint int64, matchers ...*labels.Matcher) ([]wal.Sample, error)
+	QuerierFunc          func(mint, maxt int64) (storage.Querier, error)
 }
 
 func (m mockInstance) Run(ctx context.Context) error {
@@ -147,3 +151,17 @@ func (m mockInstance) Appender(_ context.Context) storage.Appender {
 	}
 	panic("AppenderFunc not provided")
 }
+
+func (m mockInstance) LatestSamples(mint int64, matchers ...*labels.Matcher) ([]wal.Sample, error) {
+	if m.LatestSamplesFunc != nil {
+		return m.LatestSamplesFunc(mint, matchers...)
+	}
+	panic("LatestSamplesFunc not provided")
+}
+
+func (m mockInstance) Querier(_ context.Context, mint, maxt int64) (storage.Querier, error) {
+	if m.QuerierFunc != nil {
+		return m.QuerierFunc(mint, maxt)
+	}
+	panic("QuerierFunc not provided")
+}
@@ -2,7 +2,10 @@ package instance
 
 import (
 	"bytes"
+	"errors"
+	"fmt"
 	"io"
+	"io/ioutil"
 
 	config_util "github.com/prometheus/common/config"
 	"gopkg.in/yaml.v2"
@@ -18,6 +21,25 @@ func UnmarshalConfig(r io.Reader) (*Config, error) {
 	return &cfg, err
 }
 
+// UnmarshalConfigs unmarshals a stream of YAML documents from a reader, where
+// each document is an instance config.
+func UnmarshalConfigs(r io.Reader) ([]*Config, error) {
+	dec := yaml.NewDecoder(r)
+	dec.SetStrict(true)
+
+	var cfgs []*Config
+	for {
+		var cfg Config
+		err := dec.Decode(&cfg)
+		if errors.Is(err, io.EOF) {
+			return cfgs, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("document %d: %w", len(cfgs)+1, err)
+		}
+		cfgs = append(cfgs, &cfg)
+	}
+}
+
 // MarshalConfig marshals an instance config based on a provided content type.
 func MarshalConfig(c *Config, scrubSecrets bool) ([]byte, error) {
 	var buf bytes.Buffer
@@ -25,6 +47,21 @@ func MarshalConfig(c *Config, scrubSecrets bool) ([]byte, error) {
 	return buf.Bytes(), err
 }
 
+// MarshalConfigs marshals a set of instance configs as a stream of YAML
+// documents which can be read by UnmarshalConfigs.
+func MarshalConfigs(cs []*Config, scrubSecrets bool) ([]byte, error) {
+	var buf bytes.Buffer
+	for i, c := range cs {
+		if i > 0 {
+			buf.WriteString("---\n")
+		}
+		if err := MarshalConfigToWriter(c, &buf, scrubSecrets); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
 // MarshalConfigToWriter marshals a config to an io.Writer.
 func MarshalConfigToWriter(c *Config, w io.Writer, scrubSecrets bool) error {
 	enc := yaml.NewEncoder(w)
@@ -48,3 +85,29 @@ func MarshalConfigToWriter(c *Config, w io.Writer, scrubSecrets bool) error {
 	type plain Config
 	return enc.Encode((*plain)(c))
 }
+
+// scrubbedSecret is the value Secrets are replaced with when a config is
+// marshaled with scrubbed secrets.
+const scrubbedSecret = "<secret>"
+
+// HasScrubbedSecrets returns true if any Secret in c holds the placeholder
+// written by marshaling with scrubbed secrets. Such a config was read back
+// from scrubbed output and would overwrite the real secrets if stored.
+func HasScrubbedSecrets(c *Config) (bool, error) {
+	var found bool
+
+	enc := yaml.NewEncoder(ioutil.Discard)
+	enc.SetHook(func(in interface{}) (ok bool, out interface{}, err error) {
+		if v, ok := in.(config_util.Secret); ok {
+			if v == scrubbedSecret {
+				found = true
+			}
+			return true, string(v), nil
+		}
+		return false, nil, nil
+	})
+
+	type plain Config
+	err := enc.Encode((*plain)(c))
+	return found, err
+}
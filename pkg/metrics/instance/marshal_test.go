@@ -2,6 +2,7 @@ package instance
 
 import (
 	"bytes"
+	"fmt"
 	"strings"
 	"testing"
 
@@ -158,3 +159,45 @@ remote_flush_deadline: 1m0s
 		require.YAMLEq(t, scrub(cfg), string(out))
 	})
 }
+
+func TestMarshal_UnmarshalConfigs(t *testing.T) {
+	a := DefaultConfig
+	a.Name = "a"
+	b := DefaultConfig
+	b.Name = "b"
+	b.HostFilter = true
+
+	bb, err := MarshalConfigs([]*Config{&a, &b}, false)
+	require.NoError(t, err)
+
+	cfgs, err := UnmarshalConfigs(bytes.NewReader(bb))
+	require.NoError(t, err)
+	require.Equal(t, []*Config{&a, &b}, cfgs)
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := UnmarshalConfigs(strings.NewReader("name: a\n---\nfoo: bar\n"))
+		require.EqualError(t, err, "document 2: yaml: unmarshal errors:\n  line 3: field foo not found in type instance.plain")
+	})
+}
+
+func TestHasScrubbedSecrets(t *testing.T) {
+	cfg := `name: test
+remote_write:
+- url: http://localhost:9009/api/prom/push
+  basic_auth:
+    username: user
+    password: %s
+`
+
+	c, err := UnmarshalConfig(strings.NewReader(fmt.Sprintf(cfg, "secretpassword")))
+	require.NoError(t, err)
+	scrubbed, err := HasScrubbedSecrets(c)
+	require.NoError(t, err)
+	require.False(t, scrubbed)
+
+	c, err = UnmarshalConfig(strings.NewReader(fmt.Sprintf(cfg, "<secret>")))
+	require.NoError(t, err)
+	scrubbed, err = HasScrubbedSecrets(c)
+	require.NoError(t, err)
+	require.True(t, scrubbed)
+}
@@ -3,6 +3,8 @@ package instance
 import (
 	"context"
 
+	"github.com/grafana/agent/pkg/metrics/wal"
+	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/scrape"
 	"github.com/prometheus/prometheus/storage"
 )
@@ -41,3 +43,13 @@ func (NoOpInstance) StorageDirectory() string {
 func (NoOpInstance) Appender(_ context.Context) storage.Appender {
 	return nil
 }
+
+// LatestSamples implements Instance.
+func (NoOpInstance) LatestSamples(_ int64, _ ...*labels.Matcher) ([]wal.Sample, error) {
+	return nil, nil
+}
+
+// Querier implements Instance.
+func (NoOpInstance) Querier(_ context.Context, _, _ int64) (storage.Querier, error) {
+	return storage.NoopQuerier(), nil
+}
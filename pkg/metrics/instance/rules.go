@@ -0,0 +1,318 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/rulefmt"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/rules"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// DefaultRecordingRulesRetention is how long scraped samples are kept in
+	// memory for evaluating recording rules when no retention is configured.
+	DefaultRecordingRulesRetention = 10 * time.Minute
+
+	// How frequently samples older than the retention are removed from the
+	// in-memory storage used for evaluating recording rules.
+	ruleStorageTruncateFrequency = time.Minute
+)
+
+// RecordingRulesConfig configures recording rules which are evaluated
+// locally against scraped samples. The resulting series are written to the
+// WAL and sent over remote_write along with the scraped series.
+type RecordingRulesConfig struct {
+	// How long scraped samples are kept in memory for evaluating rules. Rules
+	// can't query samples older than the retention. Defaults to
+	// DefaultRecordingRulesRetention when 0.
+	Retention time.Duration `yaml:"retention,omitempty"`
+
+	Groups []RecordingRuleGroup `yaml:"groups,omitempty"`
+}
+
+// RecordingRuleGroup is a group of recording rules which are evaluated
+// sequentially at the same interval.
+type RecordingRuleGroup struct {
+	Name string `yaml:"name"`
+
+	// How frequently rules in the group are evaluated. Defaults to the global
+	// evaluation_interval when 0.
+	Interval time.Duration `yaml:"interval,omitempty"`
+
+	Rules []RecordingRule `yaml:"rules"`
+}
+
+// RecordingRule records the result of a PromQL expression as a new series.
+type RecordingRule struct {
+	Record string            `yaml:"record"`
+	Expr   string            `yaml:"expr"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+}
+
+// Validate returns an error if c is invalid.
+func (c RecordingRulesConfig) Validate() error {
+	if c.Retention < 0 {
+		return errors.New("retention must not be negative")
+	}
+
+	groupNames := map[string]struct{}{}
+	for _, g := range c.Groups {
+		if g.Name == "" {
+			return errors.New("missing rule group name")
+		}
+		if _, exists := groupNames[g.Name]; exists {
+			return fmt.Errorf("found multiple rule groups with name %q", g.Name)
+		}
+		groupNames[g.Name] = struct{}{}
+
+		if g.Interval < 0 {
+			return fmt.Errorf("interval of rule group %q must not be negative", g.Name)
+		}
+
+		for _, r := range g.Rules {
+			if !model.IsValidMetricName(model.LabelValue(r.Record)) {
+				return fmt.Errorf("invalid record name %q in rule group %q", r.Record, g.Name)
+			}
+			if _, err := parser.ParseExpr(r.Expr); err != nil {
+				return fmt.Errorf("invalid expr for rule %q in rule group %q: %w", r.Record, g.Name, err)
+			}
+			for name := range r.Labels {
+				if !model.LabelName(name).IsValid() {
+					return fmt.Errorf("invalid label name %q for rule %q in rule group %q", name, r.Record, g.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ruleEvaluator evaluates recording rules against an in-memory TSDB head
+// holding recently scraped samples. Samples written through the Appendables
+// returned by Appendable are added to the head in addition to being written
+// to their destination.
+type ruleEvaluator struct {
+	logger   log.Logger
+	dir      string
+	instance string
+
+	head      *tsdb.Head
+	retention time.Duration
+
+	loader  *ruleGroupLoader
+	manager *rules.Manager
+}
+
+// newRuleEvaluator creates a ruleEvaluator which writes the results of rules
+// to next. The head's memory-mapped chunks are stored in dir, which is
+// removed when the ruleEvaluator is closed.
+func newRuleEvaluator(logger log.Logger, reg prometheus.Registerer, dir string, next storage.Appendable, cfg *Config) (*ruleEvaluator, error) {
+	// Data in the head is never persisted, so any chunks left behind by a
+	// previous run are stale.
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to clean rule storage directory: %w", err)
+	}
+
+	opts := tsdb.DefaultHeadOptions()
+	opts.ChunkDirRoot = dir
+	head, err := tsdb.NewHead(nil, logger, nil, opts, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rule storage: %w", err)
+	}
+	if err := head.Init(math.MinInt64); err != nil {
+		_ = head.Close()
+		return nil, fmt.Errorf("failed to initialize rule storage: %w", err)
+	}
+
+	e := &ruleEvaluator{
+		logger:    logger,
+		dir:       dir,
+		instance:  cfg.Name,
+		head:      head,
+		retention: cfg.RecordingRules.Retention,
+		loader:    &ruleGroupLoader{},
+	}
+	if e.retention == 0 {
+		e.retention = DefaultRecordingRulesRetention
+	}
+
+	interval := time.Duration(cfg.global.Prometheus.EvaluationInterval)
+	engine := promql.NewEngine(promql.EngineOpts{
+		Logger:     log.With(logger, "component", "query engine"),
+		Reg:        reg,
+		MaxSamples: 50000000,
+		Timeout:    2 * time.Minute,
+		NoStepSubqueryIntervalFn: func(int64) int64 {
+			return interval.Milliseconds()
+		},
+	})
+
+	queryable := storage.QueryableFunc(func(_ context.Context, mint, maxt int64) (storage.Querier, error) {
+		return tsdb.NewBlockQuerier(tsdb.NewRangeHead(head, mint, maxt), mint, maxt)
+	})
+
+	e.manager = rules.NewManager(&rules.ManagerOptions{
+		Appendable:  e.Appendable(next),
+		Queryable:   queryable,
+		QueryFunc:   rules.EngineQueryFunc(engine, queryable),
+		Context:     context.Background(),
+		Logger:      logger,
+		Registerer:  reg,
+		GroupLoader: e.loader,
+		NotifyFunc:  func(context.Context, string, ...*rules.Alert) {},
+	})
+
+	if err := e.ApplyConfig(cfg); err != nil {
+		_ = e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// ApplyConfig updates the rules evaluated by e. Groups which didn't change
+// keep being evaluated without interruption.
+func (e *ruleEvaluator) ApplyConfig(cfg *Config) error {
+	e.loader.SetGroups(cfg.RecordingRules.Groups)
+
+	interval := time.Duration(cfg.global.Prometheus.EvaluationInterval)
+	if err := e.manager.Update(interval, []string{e.instance}, cfg.global.Prometheus.ExternalLabels, ""); err != nil {
+		return fmt.Errorf("failed to apply recording rules: %w", err)
+	}
+	return nil
+}
+
+// Appendable returns an Appendable which writes samples to both next and the
+// in-memory storage used for evaluating rules.
+func (e *ruleEvaluator) Appendable(next storage.Appendable) storage.Appendable {
+	return &ruleAppendable{next: next, head: e.head}
+}
+
+// Run evaluates rules until ctx is canceled.
+func (e *ruleEvaluator) Run(ctx context.Context) {
+	go e.manager.Run()
+	defer e.manager.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ruleStorageTruncateFrequency):
+			e.truncate()
+		}
+	}
+}
+
+// truncate removes samples older than the retention from the head.
+func (e *ruleEvaluator) truncate() {
+	mint := timestamp.FromTime(time.Now().Add(-e.retention))
+	if err := e.head.Truncate(mint); err != nil {
+		level.Error(e.logger).Log("msg", "failed to truncate rule storage", "err", err)
+	}
+}
+
+// Close closes the in-memory storage and removes its directory. Close must
+// not be called while e is running.
+func (e *ruleEvaluator) Close() error {
+	err := e.head.Close()
+	if rerr := os.RemoveAll(e.dir); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// ruleGroupLoader implements rules.GroupLoader, returning the configured
+// recording rule groups regardless of the identifier being loaded.
+type ruleGroupLoader struct {
+	mut    sync.Mutex
+	groups []RecordingRuleGroup
+}
+
+func (l *ruleGroupLoader) SetGroups(groups []RecordingRuleGroup) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	l.groups = groups
+}
+
+func (l *ruleGroupLoader) Load(string) (*rulefmt.RuleGroups, []error) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	var rgs rulefmt.RuleGroups
+	for _, g := range l.groups {
+		rg := rulefmt.RuleGroup{
+			Name:     g.Name,
+			Interval: model.Duration(g.Interval),
+		}
+		for _, r := range g.Rules {
+			rg.Rules = append(rg.Rules, rulefmt.RuleNode{
+				Record: yaml.Node{Kind: yaml.ScalarNode, Value: r.Record},
+				Expr:   yaml.Node{Kind: yaml.ScalarNode, Value: r.Expr},
+				Labels: r.Labels,
+			})
+		}
+		rgs.Groups = append(rgs.Groups, rg)
+	}
+	return &rgs, nil
+}
+
+func (l *ruleGroupLoader) Parse(query string) (parser.Expr, error) {
+	return parser.ParseExpr(query)
+}
+
+// ruleAppendable writes samples to next and to a head used for evaluating
+// rules. The head only holds a copy of the samples, so failing to write to
+// it doesn't fail the append.
+type ruleAppendable struct {
+	next storage.Appendable
+	head *tsdb.Head
+}
+
+func (a *ruleAppendable) Appender(ctx context.Context) storage.Appender {
+	return &ruleAppender{
+		Appender: a.next.Appender(ctx),
+		head:     a.head.Appender(ctx),
+	}
+}
+
+type ruleAppender struct {
+	storage.Appender
+
+	head storage.Appender
+}
+
+func (a *ruleAppender) Append(ref uint64, l labels.Labels, t int64, v float64) (uint64, error) {
+	ref, err := a.Appender.Append(ref, l, t, v)
+	if err != nil {
+		return ref, err
+	}
+
+	// References returned by the head aren't valid for next, so the head
+	// always looks up the series by its labels.
+	_, _ = a.head.Append(0, l, t, v)
+	return ref, nil
+}
+
+func (a *ruleAppender) Commit() error {
+	_ = a.head.Commit()
+	return a.Appender.Commit()
+}
+
+func (a *ruleAppender) Rollback() error {
+	_ = a.head.Rollback()
+	return a.Appender.Rollback()
+}
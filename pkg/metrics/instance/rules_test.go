@@ -0,0 +1,63 @@
+package instance
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/timestamp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleEvaluator(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Name = "test"
+	cfg.global = DefaultGlobalConfig
+	cfg.RecordingRules.Groups = []RecordingRuleGroup{{
+		Name:     "test",
+		Interval: 10 * time.Millisecond,
+		Rules: []RecordingRule{{
+			Record: "job:up:sum",
+			Expr:   "sum by (job) (up)",
+			Labels: map[string]string{"source": "agent"},
+		}},
+	}}
+
+	s := &mockWalStorage{series: make(map[uint64]int)}
+	e, err := newRuleEvaluator(log.NewNopLogger(), prometheus.NewRegistry(), filepath.Join(t.TempDir(), "rules"), s, &cfg)
+	require.NoError(t, err)
+
+	// Samples written through the evaluator's Appendable are written to s and
+	// are available for evaluating rules.
+	ts := timestamp.FromTime(time.Now().Add(-time.Second))
+	app := e.Appendable(s).Appender(context.Background())
+	for _, instance := range []string{"a", "b"} {
+		_, err := app.Append(0, labels.FromStrings("__name__", "up", "job", "test", "instance", instance), ts, 1)
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+	require.Len(t, s.series, 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		e.Run(ctx)
+	}()
+
+	recorded := labels.FromStrings("__name__", "job:up:sum", "job", "test", "source", "agent").Hash()
+	require.Eventually(t, func() bool {
+		s.mut.Lock()
+		defer s.mut.Unlock()
+		_, ok := s.series[recorded]
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	<-stopped
+	require.NoError(t, e.Close())
+}
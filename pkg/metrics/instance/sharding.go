@@ -0,0 +1,77 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// shardLabel is the temporary label holding the shard a series is routed
+// to. It's removed before series are sent.
+const shardLabel = "__agent_remote_write_shard"
+
+// RemoteWriteShardingConfig configures spreading series across the
+// remote_write endpoints of an instance. Each series is sent to exactly one
+// endpoint, chosen by the hash of the values of its source labels.
+type RemoteWriteShardingConfig struct {
+	// Labels whose values are hashed to pick the endpoint of a series.
+	SourceLabels model.LabelNames `yaml:"source_labels,flow"`
+}
+
+// Validate returns an error if c is invalid.
+func (c *RemoteWriteShardingConfig) Validate() error {
+	if len(c.SourceLabels) == 0 {
+		return errors.New("source_labels must not be empty")
+	}
+	for _, l := range c.SourceLabels {
+		if !l.IsValid() {
+			return fmt.Errorf("invalid source label %q", l)
+		}
+	}
+	return nil
+}
+
+// shardRemoteWrite returns the remote_write configs to apply for cfg. When
+// sharding is configured, the returned configs are copies of the ones in cfg
+// with relabel rules prepended to their write_relabel_configs, keeping only
+// the series of their shard. The shard of an endpoint is its index in the
+// remote_write list.
+func shardRemoteWrite(cfg *Config) []*config.RemoteWriteConfig {
+	sharding := cfg.RemoteWriteSharding
+	if sharding == nil {
+		return cfg.RemoteWrite
+	}
+
+	var (
+		res    = make([]*config.RemoteWriteConfig, 0, len(cfg.RemoteWrite))
+		shards = uint64(len(cfg.RemoteWrite))
+	)
+	for shard, rw := range cfg.RemoteWrite {
+		cp := *rw
+		cp.WriteRelabelConfigs = append([]*relabel.Config{
+			{
+				SourceLabels: sharding.SourceLabels,
+				Separator:    relabel.DefaultRelabelConfig.Separator,
+				Modulus:      shards,
+				TargetLabel:  shardLabel,
+				Action:       relabel.HashMod,
+			},
+			{
+				SourceLabels: model.LabelNames{shardLabel},
+				Separator:    relabel.DefaultRelabelConfig.Separator,
+				Regex:        relabel.MustNewRegexp(strconv.Itoa(shard)),
+				Action:       relabel.Keep,
+			},
+			{
+				Regex:  relabel.MustNewRegexp(shardLabel),
+				Action: relabel.LabelDrop,
+			},
+		}, rw.WriteRelabelConfigs...)
+		res = append(res, &cp)
+	}
+	return res
+}
@@ -0,0 +1,66 @@
+package instance
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardRemoteWrite(t *testing.T) {
+	dropDebug := &relabel.Config{
+		SourceLabels: model.LabelNames{"__name__"},
+		Regex:        relabel.MustNewRegexp("debug_.*"),
+		Action:       relabel.Drop,
+	}
+
+	cfg := DefaultConfig
+	cfg.RemoteWrite = []*config.RemoteWriteConfig{
+		{Name: "a"},
+		{Name: "b", WriteRelabelConfigs: []*relabel.Config{dropDebug}},
+		{Name: "c"},
+	}
+	cfg.RemoteWriteSharding = &RemoteWriteShardingConfig{
+		SourceLabels: model.LabelNames{"__name__", "instance"},
+	}
+
+	sharded := shardRemoteWrite(&cfg)
+	require.Len(t, sharded, 3)
+
+	// The remote_write configs of the instance are left untouched.
+	require.Empty(t, cfg.RemoteWrite[0].WriteRelabelConfigs)
+	require.Len(t, cfg.RemoteWrite[1].WriteRelabelConfigs, 1)
+
+	// Existing write_relabel_configs are kept after the sharding rules.
+	require.Equal(t, dropDebug, sharded[1].WriteRelabelConfigs[len(sharded[1].WriteRelabelConfigs)-1])
+
+	perShard := make([]int, len(sharded))
+	for i := 0; i < 300; i++ {
+		lset := labels.FromStrings("__name__", "up", "instance", fmt.Sprintf("host-%d", i))
+
+		var shards int
+		for shard, rw := range sharded {
+			res := relabel.Process(lset, rw.WriteRelabelConfigs...)
+			if res == nil {
+				continue
+			}
+			require.Equal(t, lset, res, "shard label must be removed")
+			shards++
+			perShard[shard]++
+		}
+		require.Equal(t, 1, shards, "series %s must be sent to exactly one endpoint", lset)
+	}
+	for shard, n := range perShard {
+		require.NotZero(t, n, "shard %d received no series", shard)
+	}
+}
+
+func TestShardRemoteWrite_Disabled(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.RemoteWrite = []*config.RemoteWriteConfig{{Name: "a"}, {Name: "b"}}
+	require.Equal(t, cfg.RemoteWrite, shardRemoteWrite(&cfg))
+}
@@ -0,0 +1,241 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+)
+
+// TargetGroup is a group of scrape targets registered at runtime. Targets are
+// host:port addresses which share the set of labels.
+type TargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// Validate returns an error if g is invalid.
+func (g TargetGroup) Validate() error {
+	if len(g.Targets) == 0 {
+		return errors.New("targets must not be empty")
+	}
+	for _, t := range g.Targets {
+		if t == "" {
+			return errors.New("targets must not contain empty addresses")
+		}
+	}
+	for name, value := range g.Labels {
+		if !model.LabelName(name).IsValid() {
+			return fmt.Errorf("invalid label name %q", name)
+		}
+		if !utf8.ValidString(value) {
+			return fmt.Errorf("invalid value for label %q", name)
+		}
+	}
+	return nil
+}
+
+// targetKey identifies the registered targets of a scrape job from an
+// instance config.
+type targetKey struct {
+	Instance, Job string
+}
+
+// TargetRegistry holds scrape targets which are registered at runtime instead
+// of being discovered. Registered targets are scraped by the scrape job they
+// are registered to in addition to the job's discovered targets.
+//
+// Targets are held by the registry rather than by instances so they're kept
+// when instances are restarted by a config reload.
+type TargetRegistry struct {
+	mut    sync.Mutex
+	groups map[targetKey]map[string]TargetGroup
+	subs   map[targetKey]map[chan struct{}]struct{}
+}
+
+// NewTargetRegistry creates an empty TargetRegistry.
+func NewTargetRegistry() *TargetRegistry {
+	return &TargetRegistry{
+		groups: make(map[targetKey]map[string]TargetGroup),
+		subs:   make(map[targetKey]map[chan struct{}]struct{}),
+	}
+}
+
+// Put registers a named group of targets to the job of the instance config
+// named instance, replacing any existing group with the same name. Put
+// returns true if the group didn't exist before.
+func (r *TargetRegistry) Put(instance, job, name string, g TargetGroup) (created bool) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	key := targetKey{Instance: instance, Job: job}
+	groups, ok := r.groups[key]
+	if !ok {
+		groups = make(map[string]TargetGroup)
+		r.groups[key] = groups
+	}
+	_, exists := groups[name]
+	groups[name] = g
+
+	r.notify(key)
+	return !exists
+}
+
+// Delete deregisters a named group of targets. Delete returns false if the
+// group didn't exist.
+func (r *TargetRegistry) Delete(instance, job, name string) bool {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	key := targetKey{Instance: instance, Job: job}
+	if _, exists := r.groups[key][name]; !exists {
+		return false
+	}
+	delete(r.groups[key], name)
+	if len(r.groups[key]) == 0 {
+		delete(r.groups, key)
+	}
+
+	r.notify(key)
+	return true
+}
+
+// List returns the groups registered to the instance config named instance,
+// keyed by job and then by group name.
+func (r *TargetRegistry) List(instance string) map[string]map[string]TargetGroup {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	res := make(map[string]map[string]TargetGroup)
+	for key, groups := range r.groups {
+		if key.Instance != instance {
+			continue
+		}
+		res[key.Job] = make(map[string]TargetGroup, len(groups))
+		for name, g := range groups {
+			res[key.Job][name] = g
+		}
+	}
+	return res
+}
+
+// targetGroups returns the groups registered for key, sorted by name.
+func (r *TargetRegistry) targetGroups(key targetKey) []*targetgroup.Group {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	res := make([]*targetgroup.Group, 0, len(r.groups[key]))
+	for name, g := range r.groups[key] {
+		tg := &targetgroup.Group{
+			Source:  name,
+			Targets: make([]model.LabelSet, 0, len(g.Targets)),
+			Labels:  make(model.LabelSet, len(g.Labels)),
+		}
+		for _, t := range g.Targets {
+			tg.Targets = append(tg.Targets, model.LabelSet{model.AddressLabel: model.LabelValue(t)})
+		}
+		for name, value := range g.Labels {
+			tg.Labels[model.LabelName(name)] = model.LabelValue(value)
+		}
+		res = append(res, tg)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Source < res[j].Source })
+	return res
+}
+
+// subscribe returns a channel which receives a value when the groups
+// registered for key change. The returned func must be called to stop the
+// subscription.
+func (r *TargetRegistry) subscribe(key targetKey) (<-chan struct{}, func()) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	ch := make(chan struct{}, 1)
+	if r.subs[key] == nil {
+		r.subs[key] = make(map[chan struct{}]struct{})
+	}
+	r.subs[key][ch] = struct{}{}
+
+	return ch, func() {
+		r.mut.Lock()
+		defer r.mut.Unlock()
+		delete(r.subs[key], ch)
+		if len(r.subs[key]) == 0 {
+			delete(r.subs, key)
+		}
+	}
+}
+
+// notify must be called with r.mut held.
+func (r *TargetRegistry) notify(key targetKey) {
+	for ch := range r.subs[key] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// registrySDConfig implements discovery.Config, discovering the targets
+// registered to a job in a TargetRegistry. It's added to the service
+// discovery configs of jobs by instances and can't be configured in YAML.
+type registrySDConfig struct {
+	registry *TargetRegistry
+	key      targetKey
+}
+
+// Name implements discovery.Config.
+func (c *registrySDConfig) Name() string { return "agent_api" }
+
+// NewDiscoverer implements discovery.Config.
+func (c *registrySDConfig) NewDiscoverer(discovery.DiscovererOptions) (discovery.Discoverer, error) {
+	return &registryDiscoverer{registry: c.registry, key: c.key}, nil
+}
+
+type registryDiscoverer struct {
+	registry *TargetRegistry
+	key      targetKey
+}
+
+// Run implements discovery.Discoverer.
+func (d *registryDiscoverer) Run(ctx context.Context, up chan<- []*targetgroup.Group) {
+	changed, unsubscribe := d.registry.subscribe(d.key)
+	defer unsubscribe()
+
+	// Sources sent in the previous update. Sources which are no longer
+	// registered are sent as empty groups so their targets are removed.
+	prev := map[string]struct{}{}
+
+	for {
+		groups := d.registry.targetGroups(d.key)
+
+		next := make(map[string]struct{}, len(groups))
+		for _, g := range groups {
+			next[g.Source] = struct{}{}
+		}
+		for source := range prev {
+			if _, ok := next[source]; !ok {
+				groups = append(groups, &targetgroup.Group{Source: source})
+			}
+		}
+		prev = next
+
+		select {
+		case <-ctx.Done():
+			return
+		case up <- groups:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package instance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/discovery"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTargetGroup_Validate(t *testing.T) {
+	tt := []struct {
+		name   string
+		group  TargetGroup
+		expect string
+	}{
+		{
+			name:  "valid",
+			group: TargetGroup{Targets: []string{"localhost:9090"}, Labels: map[string]string{"env": "dev"}},
+		},
+		{
+			name:   "no targets",
+			group:  TargetGroup{},
+			expect: "targets must not be empty",
+		},
+		{
+			name:   "empty target",
+			group:  TargetGroup{Targets: []string{""}},
+			expect: "targets must not contain empty addresses",
+		},
+		{
+			name:   "invalid label name",
+			group:  TargetGroup{Targets: []string{"localhost:9090"}, Labels: map[string]string{"not-valid": "dev"}},
+			expect: `invalid label name "not-valid"`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.group.Validate()
+			if tc.expect == "" {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expect)
+			}
+		})
+	}
+}
+
+func TestTargetRegistry(t *testing.T) {
+	r := NewTargetRegistry()
+
+	require.True(t, r.Put("inst", "job", "a", TargetGroup{Targets: []string{"a:80"}}))
+	require.False(t, r.Put("inst", "job", "a", TargetGroup{Targets: []string{"a:8080"}}))
+	require.True(t, r.Put("inst", "other_job", "b", TargetGroup{Targets: []string{"b:80"}}))
+	require.True(t, r.Put("other_inst", "job", "c", TargetGroup{Targets: []string{"c:80"}}))
+
+	require.Equal(t, map[string]map[string]TargetGroup{
+		"job":       {"a": {Targets: []string{"a:8080"}}},
+		"other_job": {"b": {Targets: []string{"b:80"}}},
+	}, r.List("inst"))
+
+	require.True(t, r.Delete("inst", "job", "a"))
+	require.False(t, r.Delete("inst", "job", "a"))
+	require.Equal(t, map[string]map[string]TargetGroup{
+		"other_job": {"b": {Targets: []string{"b:80"}}},
+	}, r.List("inst"))
+}
+
+func TestTargetRegistry_Discoverer(t *testing.T) {
+	r := NewTargetRegistry()
+	r.Put("inst", "job", "a", TargetGroup{
+		Targets: []string{"a:80"},
+		Labels:  map[string]string{"env": "dev"},
+	})
+
+	cfg := &registrySDConfig{registry: r, key: targetKey{Instance: "inst", Job: "job"}}
+	d, err := cfg.NewDiscoverer(discovery.DiscovererOptions{})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	up := make(chan []*targetgroup.Group)
+	go d.Run(ctx, up)
+
+	require.Equal(t, []*targetgroup.Group{{
+		Source:  "a",
+		Targets: []model.LabelSet{{model.AddressLabel: "a:80"}},
+		Labels:  model.LabelSet{"env": "dev"},
+	}}, receiveGroups(t, up))
+
+	// Groups registered to other jobs aren't discovered.
+	r.Put("inst", "other_job", "b", TargetGroup{Targets: []string{"b:80"}})
+	r.Put("inst", "job", "c", TargetGroup{Targets: []string{"c:80"}})
+	require.Equal(t, []string{"a", "c"}, groupSources(receiveGroups(t, up)))
+
+	// Deleted groups are sent without targets.
+	r.Delete("inst", "job", "a")
+	groups := receiveGroups(t, up)
+	require.Equal(t, []string{"c", "a"}, groupSources(groups))
+	require.Empty(t, groups[1].Targets)
+}
+
+func TestInstance_discoveryConfigs(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.Name = "group"
+	cfg.ScrapeConfigs = []*config.ScrapeConfig{{JobName: "a"}, {JobName: "b"}}
+	cfg.jobConfigs = map[string]string{"b": "config_b"}
+
+	t.Run("without registry", func(t *testing.T) {
+		i := &Instance{}
+		for _, sdConfigs := range i.discoveryConfigs(&cfg) {
+			require.Empty(t, sdConfigs)
+		}
+	})
+
+	t.Run("with registry", func(t *testing.T) {
+		i := &Instance{targets: NewTargetRegistry()}
+		sdConfigs := i.discoveryConfigs(&cfg)
+		require.Equal(t, targetKey{Instance: "group", Job: "a"}, sdConfigs["a"][0].(*registrySDConfig).key)
+		require.Equal(t, targetKey{Instance: "config_b", Job: "b"}, sdConfigs["b"][0].(*registrySDConfig).key)
+	})
+}
+
+func receiveGroups(t *testing.T, up <-chan []*targetgroup.Group) []*targetgroup.Group {
+	t.Helper()
+	select {
+	case groups := <-up:
+		return groups
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "timed out waiting for target groups")
+		return nil
+	}
+}
+
+func groupSources(groups []*targetgroup.Group) []string {
+	res := make([]string, 0, len(groups))
+	for _, g := range groups {
+		res = append(res, g.Source)
+	}
+	return res
+}
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/go-kit/log/level"
+	"github.com/grafana/agent/pkg/metrics/instance"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote"
+)
+
+// Limits for serving remote_read requests. They match the defaults of
+// Prometheus.
+const (
+	remoteReadSampleLimit      = 5e7
+	remoteReadConcurrencyLimit = 10
+	remoteReadMaxBytesInFrame  = 1024 * 1024
+)
+
+// RemoteReadHandler serves Prometheus remote_read requests from the WALs of
+// the running instances. The optional instance parameter only reads from the
+// named instance.
+func (a *Agent) RemoteReadHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("instance")
+	if name != "" {
+		if _, ok := a.mm.ListInstances()[name]; !ok {
+			a.writeError(w, http.StatusNotFound, fmt.Errorf("instance %q does not exist", name))
+			return
+		}
+	}
+
+	ctx := context.WithValue(r.Context(), readInstanceKey{}, name)
+	a.readHandler.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// newReadHandler creates the handler used by RemoteReadHandler. The global
+// external labels are added to the series which are read, just as they are
+// when series are sent over remote_write.
+func (a *Agent) newReadHandler() http.Handler {
+	return remote.NewReadHandler(
+		a.logger,
+		a.reg,
+		&instancesQueryable{a: a},
+		func() config.Config {
+			a.mut.RLock()
+			defer a.mut.RUnlock()
+			return config.Config{GlobalConfig: a.cfg.Global.Prometheus}
+		},
+		remoteReadSampleLimit,
+		remoteReadConcurrencyLimit,
+		remoteReadMaxBytesInFrame,
+	)
+}
+
+// readInstanceKey is the context key holding the name of the instance to
+// read from. All instances are read from when it's empty.
+type readInstanceKey struct{}
+
+// instancesQueryable implements storage.SampleAndChunkQueryable, merging
+// the queriers of the running instances.
+type instancesQueryable struct {
+	a *Agent
+}
+
+func (q *instancesQueryable) Querier(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+	instances := q.a.mm.ListInstances()
+	if name, _ := ctx.Value(readInstanceKey{}).(string); name != "" {
+		instances = map[string]instance.ManagedInstance{name: instances[name]}
+	}
+
+	queriers := make([]storage.Querier, 0, len(instances))
+	for name, inst := range instances {
+		if inst == nil {
+			continue
+		}
+		querier, err := inst.Querier(ctx, mint, maxt)
+		if err != nil {
+			level.Warn(q.a.logger).Log("msg", "skipping instance for remote read", "instance", name, "err", err)
+			continue
+		}
+		queriers = append(queriers, querier)
+	}
+	return storage.NewMergeQuerier(queriers, nil, storage.ChainedSeriesMerge), nil
+}
+
+func (q *instancesQueryable) ChunkQuerier(ctx context.Context, mint, maxt int64) (storage.ChunkQuerier, error) {
+	querier, err := q.Querier(ctx, mint, maxt)
+	if err != nil {
+		return nil, err
+	}
+	return &chunkQuerier{Querier: querier}, nil
+}
+
+// chunkQuerier implements storage.ChunkQuerier by encoding the series
+// returned by a storage.Querier into chunks.
+type chunkQuerier struct {
+	storage.Querier
+}
+
+func (q *chunkQuerier) Select(sortSeries bool, hints *storage.SelectHints, matchers ...*labels.Matcher) storage.ChunkSeriesSet {
+	return storage.NewSeriesSetToChunkSet(q.Querier.Select(sortSeries, hints, matchers...))
+}
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/gorilla/mux"
+	"github.com/grafana/agent/pkg/metrics/instance"
+	"github.com/grafana/agent/pkg/metrics/wal"
+	"github.com/prometheus/client_golang/prometheus"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgent_RemoteReadHandler(t *testing.T) {
+	fact := newFakeInstanceFactory()
+	a, err := newAgent(prometheus.NewRegistry(), Config{
+		WALDir: "/tmp/agent",
+	}, log.NewNopLogger(), fact.factory)
+	require.NoError(t, err)
+	a.cfg.Global.Prometheus.ExternalLabels = labels.FromStrings("cluster", "dev")
+
+	walA := newTestWAL(t, wal.Sample{Labels: labels.FromStrings("__name__", "up", "job", "a"), T: 1000, V: 1})
+	walB := newTestWAL(t, wal.Sample{Labels: labels.FromStrings("__name__", "up", "job", "b"), T: 2000, V: 0})
+
+	mockManager := &instance.MockManager{
+		ListInstancesFunc: func() map[string]instance.ManagedInstance {
+			return map[string]instance.ManagedInstance{
+				"instance_a": &mockInstanceQuerier{wal: walA},
+				"instance_b": &mockInstanceQuerier{wal: walB},
+			}
+		},
+		ListConfigsFunc:  func() map[string]instance.Config { return nil },
+		ApplyConfigFunc:  func(_ instance.Config) error { return nil },
+		DeleteConfigFunc: func(name string) error { return nil },
+		StopFunc:         func() {},
+	}
+	a.mm, err = instance.NewModalManager(prometheus.NewRegistry(), a.logger, mockManager, instance.ModeDistinct)
+	require.NoError(t, err)
+
+	router := mux.NewRouter()
+	a.WireAPI(router)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	read := func(t *testing.T, path string) (*prompb.QueryResult, error) {
+		u, err := url.Parse(srv.URL + path)
+		require.NoError(t, err)
+		client, err := remote.NewReadClient("test", &remote.ClientConfig{
+			URL:     &config_util.URL{URL: u},
+			Timeout: model.Duration(time.Minute),
+		})
+		require.NoError(t, err)
+
+		query, err := remote.ToQuery(0, 5000, []*labels.Matcher{
+			labels.MustNewMatcher(labels.MatchEqual, "__name__", "up"),
+		}, nil)
+		require.NoError(t, err)
+		return client.Read(context.Background(), query)
+	}
+
+	t.Run("all instances", func(t *testing.T) {
+		res, err := read(t, "/api/v1/read")
+		require.NoError(t, err)
+		require.Equal(t, &prompb.QueryResult{Timeseries: []*prompb.TimeSeries{
+			{
+				Labels:  []prompb.Label{{Name: "__name__", Value: "up"}, {Name: "cluster", Value: "dev"}, {Name: "job", Value: "a"}},
+				Samples: []prompb.Sample{{Timestamp: 1000, Value: 1}},
+			},
+			{
+				Labels:  []prompb.Label{{Name: "__name__", Value: "up"}, {Name: "cluster", Value: "dev"}, {Name: "job", Value: "b"}},
+				Samples: []prompb.Sample{{Timestamp: 2000, Value: 0}},
+			},
+		}}, res)
+	})
+
+	t.Run("single instance", func(t *testing.T) {
+		res, err := read(t, "/api/v1/read?instance=instance_b")
+		require.NoError(t, err)
+		require.Len(t, res.Timeseries, 1)
+		require.Equal(t, "b", labelValue(res.Timeseries[0].Labels, "job"))
+	})
+
+	t.Run("unknown instance", func(t *testing.T) {
+		_, err := read(t, "/api/v1/read?instance=instance_c")
+		require.Error(t, err)
+	})
+}
+
+func labelValue(lbls []prompb.Label, name string) string {
+	for _, l := range lbls {
+		if l.Name == name {
+			return l.Value
+		}
+	}
+	return ""
+}
+
+// newTestWAL creates a WAL holding samples which is removed when the test
+// finishes.
+func newTestWAL(t *testing.T, samples ...wal.Sample) *wal.Storage {
+	t.Helper()
+
+	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(walDir) })
+
+	s, err := wal.NewStorage(log.NewNopLogger(), nil, walDir)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+
+	app := s.Appender(context.Background())
+	for _, sample := range samples {
+		_, err := app.Append(0, sample.Labels, sample.T, sample.V)
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+	return s
+}
+
+type mockInstanceQuerier struct {
+	instance.NoOpInstance
+	wal *wal.Storage
+}
+
+func (i *mockInstanceQuerier) Querier(ctx context.Context, mint, maxt int64) (storage.Querier, error) {
+	return i.wal.Querier(ctx, mint, maxt)
+}
@@ -0,0 +1,302 @@
+package wal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/go-kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/record"
+	"github.com/prometheus/prometheus/tsdb/tsdbutil"
+	"github.com/prometheus/prometheus/tsdb/wal"
+)
+
+// Querier implements storage.Queryable. The returned Querier reads samples
+// between mint and maxt from the WAL on disk, so only samples which haven't
+// been truncated from the WAL can be queried.
+//
+// There's no index of the samples in the WAL; every Select reads through the
+// entire WAL. Querying is expensive and meant for occasional use, such as
+// when the remote_write endpoint is unreachable. The WAL isn't locked while
+// it's read, so queries don't block appending samples or truncating the WAL.
+func (w *Storage) Querier(_ context.Context, mint, maxt int64) (storage.Querier, error) {
+	return &querier{w: w, mint: mint, maxt: maxt}, nil
+}
+
+type querier struct {
+	w          *Storage
+	mint, maxt int64
+}
+
+// Select implements storage.Querier. Returned series are always sorted.
+func (q *querier) Select(_ bool, _ *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	series, err := q.w.readSeries(q.mint, q.maxt, matchers)
+	if err != nil {
+		return storage.ErrSeriesSet(err)
+	}
+	return &seriesSet{series: series, cur: -1}
+}
+
+// LabelValues implements storage.Querier. Values are taken from the series
+// held in memory, so they aren't limited to the queried time range.
+func (q *querier) LabelValues(name string, matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	values := map[string]struct{}{}
+	for _, lset := range q.w.seriesLabels(matchers) {
+		if v := lset.Get(name); v != "" {
+			values[v] = struct{}{}
+		}
+	}
+	return sortedKeys(values), nil, nil
+}
+
+// LabelNames implements storage.Querier. Names are taken from the series
+// held in memory, so they aren't limited to the queried time range.
+func (q *querier) LabelNames(matchers ...*labels.Matcher) ([]string, storage.Warnings, error) {
+	names := map[string]struct{}{}
+	for _, lset := range q.w.seriesLabels(matchers) {
+		for _, l := range lset {
+			names[l.Name] = struct{}{}
+		}
+	}
+	return sortedKeys(names), nil, nil
+}
+
+// Close implements storage.Querier.
+func (q *querier) Close() error { return nil }
+
+func sortedKeys(m map[string]struct{}) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// seriesLabels returns the labels of the series in memory matching all
+// matchers.
+func (w *Storage) seriesLabels(matchers []*labels.Matcher) []labels.Labels {
+	var res []labels.Labels
+
+	it := w.series.iterator()
+	for series := range it.Channel() {
+		if matchLabels(series.lset, matchers) {
+			res = append(res, series.lset)
+		}
+	}
+	return res
+}
+
+// walSnapshot is the checkpoint and the range of segments of the WAL at
+// the time a query started.
+type walSnapshot struct {
+	dir         string
+	checkpoint  string // Empty if there's no checkpoint.
+	first, last int
+}
+
+// snapshot returns the files to read for a query. The WAL lock is only held
+// while listing the files, so reading them doesn't block appends and
+// truncations.
+func (w *Storage) snapshot() (walSnapshot, error) {
+	w.walMtx.RLock()
+	defer w.walMtx.RUnlock()
+
+	if w.walClosed {
+		return walSnapshot{}, ErrWALClosed
+	}
+
+	s := walSnapshot{dir: w.wal.Dir()}
+	cpDir, cpIndex, err := wal.LastCheckpoint(s.dir)
+	if err != nil && err != record.ErrNotFound {
+		return walSnapshot{}, errors.Wrap(err, "find last checkpoint")
+	}
+
+	s.first, s.last, err = wal.Segments(s.dir)
+	if err != nil {
+		return walSnapshot{}, errors.Wrap(err, "find WAL segments")
+	}
+	if cpDir != "" {
+		s.checkpoint = cpDir
+		if cpIndex+1 > s.first {
+			s.first = cpIndex + 1
+		}
+	}
+	return s, nil
+}
+
+// readSeries reads the samples between mint and maxt of the series matching
+// all matchers from the WAL. Series without samples in the range are
+// omitted. Series are sorted by labels and samples are sorted by timestamp.
+//
+// The WAL isn't locked while it's read, so it may be truncated in the
+// meantime. Reading starts over from a new snapshot when the checkpoint was
+// removed before it could be opened.
+func (w *Storage) readSeries(mint, maxt int64, matchers []*labels.Matcher) ([]storage.Series, error) {
+	const maxAttempts = 3
+
+	for attempt := 1; ; attempt++ {
+		s, err := w.snapshot()
+		if err != nil {
+			return nil, err
+		}
+		res, err := w.readSnapshot(s, mint, maxt, matchers)
+		if os.IsNotExist(errors.Cause(err)) && attempt < maxAttempts {
+			level.Debug(w.logger).Log("msg", "WAL checkpoint removed while querying, retrying", "checkpoint", s.checkpoint)
+			continue
+		}
+		return res, err
+	}
+}
+
+// readSnapshot reads the series of readSeries from the files of s.
+func (w *Storage) readSnapshot(s walSnapshot, mint, maxt int64, matchers []*labels.Matcher) ([]storage.Series, error) {
+	var (
+		dec record.Decoder
+
+		// The same series may have been written with multiple refs if it was
+		// garbage collected and created again, so samples are collected by
+		// the labels of their series rather than by ref.
+		refs    = map[uint64]string{} // Refs of matching series to their key.
+		lsets   = map[string]labels.Labels{}
+		samples = map[string][]tsdbutil.Sample{}
+	)
+
+	readRecord := func(rec []byte) error {
+		switch dec.Type(rec) {
+		case record.Series:
+			series, err := dec.Series(rec, nil)
+			if err != nil {
+				return errors.Wrap(err, "decode series")
+			}
+			for _, s := range series {
+				if matchLabels(s.Labels, matchers) {
+					key := s.Labels.String()
+					refs[s.Ref] = key
+					lsets[key] = s.Labels
+				}
+			}
+		case record.Samples:
+			recSamples, err := dec.Samples(rec, nil)
+			if err != nil {
+				return errors.Wrap(err, "decode samples")
+			}
+			for _, s := range recSamples {
+				key, ok := refs[s.Ref]
+				if !ok || s.T < mint || s.T > maxt {
+					continue
+				}
+				samples[key] = append(samples[key], querySample{t: s.T, v: s.V})
+			}
+		}
+		return nil
+	}
+
+	if s.checkpoint != "" {
+		if err := readCheckpoint(s.checkpoint, readRecord); err != nil {
+			return nil, err
+		}
+	}
+
+	lrMetrics := wal.NewLiveReaderMetrics(nil)
+	for i := s.first; i <= s.last; i++ {
+		err := w.readSegment(wal.SegmentName(s.dir, i), lrMetrics, readRecord)
+		if os.IsNotExist(errors.Cause(err)) {
+			// The segment was removed by a truncation while reading.
+			level.Debug(w.logger).Log("msg", "WAL segment removed while querying", "segment", i)
+			continue
+		} else if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("read WAL segment: %d", i))
+		}
+	}
+
+	res := make([]storage.Series, 0, len(samples))
+	for key, ss := range samples {
+		res = append(res, storage.NewListSeries(lsets[key], sortSamples(ss)))
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return labels.Compare(res[i].Labels(), res[j].Labels()) < 0
+	})
+	return res, nil
+}
+
+func readCheckpoint(dir string, readRecord func([]byte) error) error {
+	sr, err := wal.NewSegmentsReader(dir)
+	if err != nil {
+		return errors.Wrap(err, "open checkpoint")
+	}
+	defer sr.Close()
+
+	r := wal.NewReader(sr)
+	for r.Next() {
+		if err := readRecord(r.Record()); err != nil {
+			return errors.Wrap(err, "read checkpoint")
+		}
+	}
+	return errors.Wrap(r.Err(), "read checkpoint")
+}
+
+// readSegment reads the records of a segment. The segment may be written to
+// while it's read, so records which haven't been fully written are ignored.
+func (w *Storage) readSegment(name string, metrics *wal.LiveReaderMetrics, readRecord func([]byte) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := wal.NewLiveReader(w.logger, metrics, f)
+	for r.Next() {
+		if err := readRecord(r.Record()); err != nil {
+			return err
+		}
+	}
+	if err := r.Err(); err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// sortSamples sorts samples by timestamp. Out-of-order samples may have been
+// appended to the WAL, in which case the last sample written for a timestamp
+// is kept.
+func sortSamples(ss []tsdbutil.Sample) []tsdbutil.Sample {
+	sort.SliceStable(ss, func(i, j int) bool { return ss[i].T() < ss[j].T() })
+
+	res := ss[:0]
+	for i, s := range ss {
+		if i+1 < len(ss) && ss[i+1].T() == s.T() {
+			continue
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+type querySample struct {
+	t int64
+	v float64
+}
+
+func (s querySample) T() int64   { return s.t }
+func (s querySample) V() float64 { return s.v }
+
+// seriesSet implements storage.SeriesSet over a list of series.
+type seriesSet struct {
+	series []storage.Series
+	cur    int
+}
+
+func (s *seriesSet) Next() bool {
+	s.cur++
+	return s.cur < len(s.series)
+}
+
+func (s *seriesSet) At() storage.Series         { return s.series[s.cur] }
+func (s *seriesSet) Err() error                 { return nil }
+func (s *seriesSet) Warnings() storage.Warnings { return nil }
@@ -15,6 +15,10 @@ type memSeries struct {
 	lset   labels.Labels
 	lastTs int64
 
+	// Value of the sample at lastTs. Only used for serving the latest samples
+	// of series; the WAL is the source of truth for sample values.
+	lastValue float64
+
 	// TODO(rfratto): this solution below isn't perfect, and there's still
 	// the possibility for a series to be deleted before it's
 	// completely gone from the WAL. Rather, we should have gc return
@@ -34,7 +38,11 @@ type memSeries struct {
 }
 
 func (s *memSeries) updateTs(ts int64) {
-	s.lastTs = ts
+	// Samples within the out-of-order time window may be older than the
+	// newest sample; lastTs must never move backwards.
+	if ts > s.lastTs {
+		s.lastTs = ts
+	}
 	s.willDelete = false
 	s.pendingCommit = true
 }
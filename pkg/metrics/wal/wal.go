@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"os"
 	"sync"
 	"time"
 	"unicode/utf8"
@@ -27,6 +28,28 @@ import (
 // storage has already been closed.
 var ErrWALClosed = fmt.Errorf("WAL storage closed")
 
+// ErrSeriesLimit is an error returned when appending a sample for a new
+// series would exceed the maximum number of active series. It wraps
+// storage.ErrOutOfBounds so scrapes only drop the rejected samples.
+var ErrSeriesLimit = errors.Wrap(storage.ErrOutOfBounds, "WAL storage has reached its maximum number of active series")
+
+// ErrWALFull is an error returned when appending to a WAL which exceeds its
+// maximum size and has paused appends.
+var ErrWALFull = fmt.Errorf("WAL storage exceeds its maximum size")
+
+// FullPolicy controls what happens when the WAL exceeds its maximum size.
+type FullPolicy string
+
+const (
+	// FullPolicyDropOldest truncates the WAL, dropping its oldest samples even
+	// if they haven't been sent over remote_write yet.
+	FullPolicyDropOldest FullPolicy = "drop_oldest"
+
+	// FullPolicyPauseScraping rejects appends until the WAL is truncated below
+	// its maximum size.
+	FullPolicyPauseScraping FullPolicy = "pause_scraping"
+)
+
 type storageMetrics struct {
 	r prometheus.Registerer
 
@@ -36,6 +59,10 @@ type storageMetrics struct {
 	totalRemovedSeries     prometheus.Counter
 	totalAppendedSamples   prometheus.Counter
 	totalAppendedExemplars prometheus.Counter
+	totalOutOfOrderSamples prometheus.Counter
+	storageSize            prometheus.Gauge
+	appendsPaused          prometheus.Gauge
+	totalSizeTruncations   prometheus.Counter
 }
 
 func newStorageMetrics(r prometheus.Registerer) *storageMetrics {
@@ -70,6 +97,26 @@ func newStorageMetrics(r prometheus.Registerer) *storageMetrics {
 		Help: "Total number of exemplars appended to the WAL",
 	})
 
+	m.totalOutOfOrderSamples = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "agent_wal_out_of_order_samples_total",
+		Help: "Total number of samples rejected for being older than the out-of-order time window",
+	})
+
+	m.storageSize = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "agent_wal_storage_size_bytes",
+		Help: "Size of the WAL on disk, as of the last size check",
+	})
+
+	m.appendsPaused = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "agent_wal_appends_paused",
+		Help: "Whether appends are paused because the WAL exceeds its maximum size",
+	})
+
+	m.totalSizeTruncations = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "agent_wal_size_truncations_total",
+		Help: "Total number of truncations caused by the WAL exceeding its maximum size",
+	})
+
 	if r != nil {
 		r.MustRegister(
 			m.numActiveSeries,
@@ -78,6 +125,10 @@ func newStorageMetrics(r prometheus.Registerer) *storageMetrics {
 			m.totalRemovedSeries,
 			m.totalAppendedSamples,
 			m.totalAppendedExemplars,
+			m.totalOutOfOrderSamples,
+			m.storageSize,
+			m.appendsPaused,
+			m.totalSizeTruncations,
 		)
 	}
 
@@ -95,16 +146,20 @@ func (m *storageMetrics) Unregister() {
 		m.totalRemovedSeries,
 		m.totalAppendedSamples,
 		m.totalAppendedExemplars,
+		m.totalOutOfOrderSamples,
+		m.storageSize,
+		m.appendsPaused,
+		m.totalSizeTruncations,
 	}
 	for _, c := range cs {
 		m.r.Unregister(c)
 	}
 }
 
-// Storage implements storage.Storage, and just writes to the WAL.
+// Storage implements storage.Storage, and just writes to the WAL. Samples can
+// be queried back from the WAL on disk through Querier.
 type Storage struct {
-	// Embed Queryable/ChunkQueryable for compatibility, but don't actually implement it.
-	storage.Queryable
+	// Embed ChunkQueryable for compatibility, but don't actually implement it.
 	storage.ChunkQueryable
 
 	// Operations against the WAL must be protected by a mutex so it doesn't get
@@ -127,6 +182,18 @@ type Storage struct {
 	deletedMtx sync.Mutex
 	deleted    map[uint64]int // Deleted series, and what WAL segment they must be kept until.
 
+	// How far behind the newest sample of a series a sample may be. Zero
+	// disables out-of-order checks.
+	outOfOrderTimeWindow *atomic.Duration
+
+	// Set when appends are rejected because the WAL is too large.
+	appendsPaused *atomic.Bool
+
+	// Number of series in memory, and how many may be active at once. Zero
+	// disables the limit.
+	activeSeries *atomic.Int64
+	maxSeries    *atomic.Int64
+
 	metrics *storageMetrics
 }
 
@@ -145,6 +212,11 @@ func NewStorage(logger log.Logger, registerer prometheus.Registerer, path string
 		series:  newStripeSeries(),
 		metrics: newStorageMetrics(registerer),
 		ref:     atomic.NewUint64(0),
+
+		outOfOrderTimeWindow: atomic.NewDuration(0),
+		appendsPaused:        atomic.NewBool(false),
+		activeSeries:         atomic.NewInt64(0),
+		maxSeries:            atomic.NewInt64(0),
 	}
 
 	storage.bufPool.New = func() interface{} {
@@ -171,6 +243,61 @@ func NewStorage(logger log.Logger, registerer prometheus.Registerer, path string
 	return storage, nil
 }
 
+// SetOutOfOrderTimeWindow sets how far behind the newest sample of its
+// series a sample may be before it is rejected with
+// storage.ErrOutOfOrderSample. Samples within the window are accepted. A
+// window of zero, the default, accepts all samples regardless of order.
+func (w *Storage) SetOutOfOrderTimeWindow(window time.Duration) {
+	w.outOfOrderTimeWindow.Store(window)
+}
+
+// SetMaxSeries sets the maximum number of active series. Appending a sample
+// for a new series fails with ErrSeriesLimit once the limit is reached, while
+// samples for existing series are still accepted. A limit of zero, the
+// default, doesn't limit the number of series.
+func (w *Storage) SetMaxSeries(limit int) {
+	w.maxSeries.Store(int64(limit))
+}
+
+// EnforceMaxSize applies policy if the WAL on disk is larger than maxSize
+// bytes. The FullPolicyDropOldest policy checkpoints the oldest segments of
+// the WAL until it's below maxSize, keeping samples newer than mint. The
+// FullPolicyPauseScraping policy rejects appends with ErrWALFull until a later
+// call to EnforceMaxSize finds the WAL below maxSize again.
+func (w *Storage) EnforceMaxSize(maxSize int64, policy FullPolicy, mint int64) error {
+	size, err := w.wal.Size()
+	if err != nil {
+		return errors.Wrap(err, "get WAL size")
+	}
+	w.metrics.storageSize.Set(float64(size))
+
+	full := size > maxSize
+	switch policy {
+	case FullPolicyDropOldest:
+		if !full {
+			return nil
+		}
+		level.Warn(w.logger).Log("msg", "WAL exceeds its maximum size, dropping oldest samples", "size", size, "max_size", maxSize)
+		w.metrics.totalSizeTruncations.Inc()
+		return w.dropOldest(mint, size-maxSize)
+
+	case FullPolicyPauseScraping:
+		if w.appendsPaused.Swap(full) != full {
+			if full {
+				level.Warn(w.logger).Log("msg", "WAL exceeds its maximum size, pausing appends", "size", size, "max_size", maxSize)
+				w.metrics.appendsPaused.Set(1)
+			} else {
+				level.Info(w.logger).Log("msg", "WAL is below its maximum size, resuming appends", "size", size, "max_size", maxSize)
+				w.metrics.appendsPaused.Set(0)
+			}
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown WAL full policy %q", policy)
+	}
+}
+
 func (w *Storage) replayWAL() error {
 	w.walMtx.RLock()
 	defer w.walMtx.RUnlock()
@@ -310,6 +437,7 @@ func (w *Storage) loadWAL(r *wal.Reader) (err error) {
 					series := &memSeries{ref: s.Ref, lset: s.Labels, lastTs: 0}
 					w.series.set(s.Labels.Hash(), series)
 
+					w.activeSeries.Inc()
 					w.metrics.numActiveSeries.Inc()
 					w.metrics.totalCreatedSeries.Inc()
 
@@ -331,8 +459,9 @@ func (w *Storage) loadWAL(r *wal.Reader) (err error) {
 				}
 
 				series.Lock()
-				if s.T > series.lastTs {
+				if s.T >= series.lastTs {
 					series.lastTs = s.T
+					series.lastValue = s.V
 				}
 				series.Unlock()
 			}
@@ -378,6 +507,46 @@ func (*Storage) StartTime() (int64, error) {
 // Truncate removes all data from the WAL prior to the timestamp specified by
 // mint.
 func (w *Storage) Truncate(mint int64) error {
+	return w.truncate(mint, func(first, last int) (int, error) {
+		last-- // Never consider last segment for checkpoint.
+
+		// The lower two thirds of segments should contain mostly obsolete
+		// samples. If we have less than two segments, it's not worth
+		// checkpointing yet.
+		if to := first + (last-first)*2/3; to > first {
+			return to, nil
+		}
+		return first - 1, nil
+	})
+}
+
+// dropOldest checkpoints the oldest segments of the WAL, dropping their
+// samples before mint, until at least excess bytes of segments are removed.
+// Unlike Truncate, it may checkpoint every segment but the one currently
+// written to.
+func (w *Storage) dropOldest(mint, excess int64) error {
+	return w.truncate(mint, func(first, last int) (int, error) {
+		var removed int64
+		for i := first; i <= last; i++ {
+			fi, err := os.Stat(wal.SegmentName(w.wal.Dir(), i))
+			if err != nil {
+				return 0, errors.Wrap(err, "get segment size")
+			}
+			removed += fi.Size()
+			if removed >= excess {
+				return i, nil
+			}
+		}
+		return last, nil
+	})
+}
+
+// truncate garbage collects series before mint and checkpoints the segments
+// from the first one through the one chosen by checkpointTo. checkpointTo is
+// given the range of segments which may be checkpointed, which excludes the
+// segment currently written to; returning a segment before first skips
+// checkpointing.
+func (w *Storage) truncate(mint int64, checkpointTo func(first, last int) (int, error)) error {
 	w.walMtx.RLock()
 	defer w.walMtx.RUnlock()
 
@@ -403,15 +572,15 @@ func (w *Storage) Truncate(mint int64) error {
 		return errors.Wrap(err, "next segment")
 	}
 
-	last-- // Never consider last segment for checkpoint.
 	if last < 0 {
 		return nil // no segments yet.
 	}
 
-	// The lower two thirds of segments should contain mostly obsolete samples.
-	// If we have less than two segments, it's not worth checkpointing yet.
-	last = first + (last-first)*2/3
-	if last <= first {
+	last, err = checkpointTo(first, last)
+	if err != nil {
+		return err
+	}
+	if last < first {
 		return nil
 	}
 
@@ -462,6 +631,7 @@ func (w *Storage) Truncate(mint int64) error {
 // gc removes data before the minimum timestamp from the head.
 func (w *Storage) gc(mint int64) {
 	deleted := w.series.gc(mint)
+	w.activeSeries.Sub(int64(len(deleted)))
 	w.metrics.numActiveSeries.Sub(float64(len(deleted)))
 
 	_, last, _ := wal.Segments(w.wal.Dir())
@@ -543,6 +713,46 @@ func (w *Storage) WriteStalenessMarkers(remoteTsFunc func() int64) error {
 	return lastErr
 }
 
+// Sample is the newest sample of a series.
+type Sample struct {
+	Labels labels.Labels
+	T      int64
+	V      float64
+}
+
+// LatestSamples returns the newest sample of every series matching all
+// matchers, skipping series without samples since mint and series whose
+// newest sample is a staleness marker. Samples which have been appended but
+// not committed yet may be returned.
+func (w *Storage) LatestSamples(mint int64, matchers ...*labels.Matcher) []Sample {
+	var res []Sample
+
+	it := w.series.iterator()
+	for series := range it.Channel() {
+		if series.lastTs < mint || value.IsStaleNaN(series.lastValue) {
+			continue
+		}
+		if !matchLabels(series.lset, matchers) {
+			continue
+		}
+		res = append(res, Sample{
+			Labels: series.lset,
+			T:      series.lastTs,
+			V:      series.lastValue,
+		})
+	}
+	return res
+}
+
+func matchLabels(lset labels.Labels, matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if !m.Matches(lset.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
 // Close closes the storage and all its underlying resources.
 func (w *Storage) Close() error {
 	w.walMtx.Lock()
@@ -567,6 +777,10 @@ type appender struct {
 }
 
 func (a *appender) Append(ref uint64, l labels.Labels, t int64, v float64) (uint64, error) {
+	if a.w.appendsPaused.Load() {
+		return 0, ErrWALFull
+	}
+
 	series := a.w.series.getByID(ref)
 	if series == nil {
 		// Ensure no empty or duplicate labels have gotten through. This mirrors the
@@ -580,6 +794,12 @@ func (a *appender) Append(ref uint64, l labels.Labels, t int64, v float64) (uint
 			return 0, errors.Wrap(tsdb.ErrInvalidSample, fmt.Sprintf(`label name "%s" is not unique`, lbl))
 		}
 
+		// The limit is checked before the series is created; concurrent appends
+		// may overshoot it slightly.
+		if limit := a.w.maxSeries.Load(); limit > 0 && a.w.activeSeries.Load() >= limit && a.w.series.getByHash(l.Hash(), l) == nil {
+			return 0, ErrSeriesLimit
+		}
+
 		var created bool
 		series, created = a.getOrCreate(l)
 		if created {
@@ -588,6 +808,7 @@ func (a *appender) Append(ref uint64, l labels.Labels, t int64, v float64) (uint
 				Labels: l,
 			})
 
+			a.w.activeSeries.Inc()
 			a.w.metrics.numActiveSeries.Inc()
 			a.w.metrics.totalCreatedSeries.Inc()
 		}
@@ -596,6 +817,15 @@ func (a *appender) Append(ref uint64, l labels.Labels, t int64, v float64) (uint
 	series.Lock()
 	defer series.Unlock()
 
+	if window := a.w.outOfOrderTimeWindow.Load(); window > 0 && t < series.lastTs-window.Milliseconds() {
+		a.w.metrics.totalOutOfOrderSamples.Inc()
+		return 0, storage.ErrOutOfOrderSample
+	}
+
+	if t >= series.lastTs {
+		series.lastValue = v
+	}
+
 	// Update last recorded timestamp. Used by Storage.gc to determine if a
 	// series is stale.
 	series.updateTs(t)
@@ -624,6 +854,10 @@ func (a *appender) getOrCreate(l labels.Labels) (series *memSeries, created bool
 }
 
 func (a *appender) AppendExemplar(ref uint64, _ labels.Labels, e exemplar.Exemplar) (uint64, error) {
+	if a.w.appendsPaused.Load() {
+		return 0, ErrWALFull
+	}
+
 	s := a.w.series.getByID(ref)
 	if s == nil {
 		return 0, fmt.Errorf("unknown series ref. when trying to add exemplar: %d", ref)
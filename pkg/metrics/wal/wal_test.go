@@ -11,12 +11,14 @@ import (
 
 	"github.com/go-kit/log"
 	"github.com/grafana/agent/pkg/util"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/prometheus/prometheus/pkg/exemplar"
 	"github.com/prometheus/prometheus/pkg/labels"
 	"github.com/prometheus/prometheus/pkg/value"
 	"github.com/prometheus/prometheus/storage"
 	"github.com/prometheus/prometheus/tsdb"
 	"github.com/prometheus/prometheus/tsdb/record"
+	"github.com/prometheus/prometheus/tsdb/wal"
 	"github.com/stretchr/testify/require"
 )
 
@@ -145,6 +147,229 @@ func TestStorage_DuplicateExemplarsIgnored(t *testing.T) {
 	require.Equal(t, 4, len(collector.exemplars))
 }
 
+func TestStorage_OutOfOrderTimeWindow(t *testing.T) {
+	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(walDir)
+
+	s, err := NewStorage(log.NewNopLogger(), nil, walDir)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, s.Close())
+	}()
+	s.SetOutOfOrderTimeWindow(time.Minute)
+
+	app := s.Appender(context.Background())
+	lset := labels.Labels{{Name: "a", Value: "1"}}
+
+	now := int64(10 * time.Minute / time.Millisecond)
+	ref, err := app.Append(0, lset, now, 1)
+	require.NoError(t, err)
+
+	_, err = app.Append(ref, lset, now-(30*time.Second).Milliseconds(), 2)
+	require.NoError(t, err, "should accept sample within the window")
+
+	_, err = app.Append(ref, lset, now-(2*time.Minute).Milliseconds(), 3)
+	require.ErrorIs(t, err, storage.ErrOutOfOrderSample, "should reject sample outside the window")
+
+	require.NoError(t, app.Commit())
+
+	series := s.series.getByID(ref)
+	require.Equal(t, now, series.lastTs, "late sample should not move lastTs backwards")
+
+	collector := walDataCollector{}
+	replayer := walReplayer{w: &collector}
+	require.NoError(t, replayer.Replay(s.wal.Dir()))
+	require.Len(t, collector.samples, 2)
+}
+
+func TestStorage_LatestSamples(t *testing.T) {
+	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(walDir)
+
+	s, err := NewStorage(log.NewNopLogger(), nil, walDir)
+	require.NoError(t, err)
+
+	app := s.Appender(context.Background())
+	for _, sample := range []Sample{
+		{Labels: labels.FromStrings("__name__", "up", "job", "a"), T: 100, V: 1},
+		{Labels: labels.FromStrings("__name__", "up", "job", "a"), T: 200, V: 0},
+		{Labels: labels.FromStrings("__name__", "up", "job", "b"), T: 200, V: 1},
+		{Labels: labels.FromStrings("__name__", "up", "job", "old"), T: 50, V: 1},
+		{Labels: labels.FromStrings("__name__", "up", "job", "stale"), T: 200, V: math.Float64frombits(value.StaleNaN)},
+		{Labels: labels.FromStrings("__name__", "other", "job", "a"), T: 200, V: 1},
+	} {
+		_, err := app.Append(0, sample.Labels, sample.T, sample.V)
+		require.NoError(t, err)
+	}
+	require.NoError(t, app.Commit())
+
+	expect := []Sample{
+		{Labels: labels.FromStrings("__name__", "up", "job", "a"), T: 200, V: 0},
+		{Labels: labels.FromStrings("__name__", "up", "job", "b"), T: 200, V: 1},
+	}
+	latestSamples := func(s *Storage) []Sample {
+		res := s.LatestSamples(100, labels.MustNewMatcher(labels.MatchEqual, "__name__", "up"))
+		sort.Slice(res, func(i, j int) bool { return labels.Compare(res[i].Labels, res[j].Labels) < 0 })
+		return res
+	}
+	require.Equal(t, expect, latestSamples(s))
+
+	// The latest samples should be restored when replaying the WAL.
+	require.NoError(t, s.Close())
+	s, err = NewStorage(log.NewNopLogger(), nil, walDir)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, s.Close())
+	}()
+	require.Equal(t, expect, latestSamples(s))
+}
+
+func TestStorage_Querier(t *testing.T) {
+	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(walDir)
+
+	s, err := NewStorage(log.NewNopLogger(), nil, walDir)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, s.Close())
+	}()
+	s.SetOutOfOrderTimeWindow(time.Minute)
+
+	var (
+		upA   = labels.FromStrings("__name__", "up", "job", "a")
+		upB   = labels.FromStrings("__name__", "up", "job", "b")
+		other = labels.FromStrings("__name__", "other", "job", "a")
+	)
+	for _, samples := range [][]Sample{
+		{{Labels: upA, T: 100, V: 1}, {Labels: upB, T: 100, V: 1}, {Labels: other, T: 200, V: 1}},
+		{{Labels: upA, T: 300, V: 3}, {Labels: upA, T: 200, V: 2}},
+		// Out-of-order sample for an existing timestamp; the last one written
+		// should be returned.
+		{{Labels: upA, T: 200, V: 4}},
+	} {
+		app := s.Appender(context.Background())
+		for _, sample := range samples {
+			_, err := app.Append(0, sample.Labels, sample.T, sample.V)
+			require.NoError(t, err)
+		}
+		require.NoError(t, app.Commit())
+	}
+
+	q, err := s.Querier(context.Background(), 150, 300)
+	require.NoError(t, err)
+	defer q.Close()
+
+	type result struct {
+		Labels  labels.Labels
+		Samples []Sample
+	}
+	var actual []result
+
+	ss := q.Select(false, nil, labels.MustNewMatcher(labels.MatchEqual, "__name__", "up"))
+	for ss.Next() {
+		res := result{Labels: ss.At().Labels()}
+		it := ss.At().Iterator()
+		for it.Next() {
+			ts, v := it.At()
+			res.Samples = append(res.Samples, Sample{T: ts, V: v})
+		}
+		require.NoError(t, it.Err())
+		actual = append(actual, res)
+	}
+	require.NoError(t, ss.Err())
+
+	require.Equal(t, []result{{
+		Labels:  upA,
+		Samples: []Sample{{T: 200, V: 4}, {T: 300, V: 3}},
+	}}, actual)
+
+	values, _, err := q.LabelValues("job", labels.MustNewMatcher(labels.MatchEqual, "__name__", "up"))
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, values)
+}
+
+func TestStorage_MaxSeries(t *testing.T) {
+	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(walDir)
+
+	s, err := NewStorage(log.NewNopLogger(), nil, walDir)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, s.Close())
+	}()
+	s.SetMaxSeries(2)
+
+	app := s.Appender(context.Background())
+	_, err = app.Append(0, labels.FromStrings("a", "1"), 10, 1)
+	require.NoError(t, err)
+	_, err = app.Append(0, labels.FromStrings("a", "2"), 10, 1)
+	require.NoError(t, err)
+
+	_, err = app.Append(0, labels.FromStrings("a", "3"), 10, 1)
+	require.ErrorIs(t, err, ErrSeriesLimit, "should reject new series over the limit")
+	require.ErrorIs(t, err, storage.ErrOutOfBounds)
+
+	_, err = app.Append(0, labels.FromStrings("a", "1"), 20, 1)
+	require.NoError(t, err, "should accept samples for existing series")
+	require.NoError(t, app.Commit())
+}
+
+func TestStorage_EnforceMaxSize(t *testing.T) {
+	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(walDir)
+
+	s, err := NewStorage(log.NewNopLogger(), nil, walDir)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, s.Close())
+	}()
+
+	lset := labels.Labels{{Name: "a", Value: "1"}}
+	app := s.Appender(context.Background())
+	_, err = app.Append(0, lset, 10, 1)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	t.Run("drop_oldest", func(t *testing.T) {
+		require.NoError(t, s.EnforceMaxSize(1<<40, FullPolicyDropOldest, 0))
+		require.Equal(t, float64(0), testutil.ToFloat64(s.metrics.totalSizeTruncations))
+
+		require.NoError(t, s.EnforceMaxSize(1, FullPolicyDropOldest, 0))
+		require.Equal(t, float64(1), testutil.ToFloat64(s.metrics.totalSizeTruncations))
+
+		// The only segment with data is checkpointed even though there are
+		// fewer segments than Truncate would consider.
+		first, _, err := wal.Segments(s.wal.Dir())
+		require.NoError(t, err)
+		require.Equal(t, 1, first)
+		_, cpIndex, err := wal.LastCheckpoint(s.wal.Dir())
+		require.NoError(t, err)
+		require.Equal(t, 0, cpIndex)
+	})
+
+	t.Run("pause_scraping", func(t *testing.T) {
+		ref := s.series.getByHash(lset.Hash(), lset).ref
+		ex := exemplar.Exemplar{Labels: labels.FromStrings("trace_id", "abc"), Value: 1, Ts: 20, HasTs: true}
+
+		require.NoError(t, s.EnforceMaxSize(1, FullPolicyPauseScraping, 0))
+		_, err := s.Appender(context.Background()).Append(0, lset, 20, 1)
+		require.ErrorIs(t, err, ErrWALFull)
+		_, err = s.Appender(context.Background()).AppendExemplar(ref, lset, ex)
+		require.ErrorIs(t, err, ErrWALFull, "exemplars of existing series must be rejected too")
+
+		require.NoError(t, s.EnforceMaxSize(1<<40, FullPolicyPauseScraping, 0))
+		_, err = s.Appender(context.Background()).Append(0, lset, 20, 1)
+		require.NoError(t, err)
+		_, err = s.Appender(context.Background()).AppendExemplar(ref, lset, ex)
+		require.NoError(t, err)
+	})
+}
+
 func TestStorage_ExistingWAL(t *testing.T) {
 	walDir, err := ioutil.TempDir(os.TempDir(), "wal")
 	require.NoError(t, err)
@@ -0,0 +1,16 @@
+package v1alpha1
+
+// v1alpha1 is the conversion hub of the custom resources. Other API versions
+// are converted to and from v1alpha1 by the conversion webhook.
+
+// Hub marks GrafanaAgent as a conversion hub.
+func (*GrafanaAgent) Hub() {}
+
+// Hub marks MetricsInstance as a conversion hub.
+func (*MetricsInstance) Hub() {}
+
+// Hub marks LogsInstance as a conversion hub.
+func (*LogsInstance) Hub() {}
+
+// Hub marks PodLogs as a conversion hub.
+func (*PodLogs) Hub() {}
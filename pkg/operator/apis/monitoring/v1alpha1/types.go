@@ -3,6 +3,7 @@ package v1alpha1
 import (
 	prom_v1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	v1 "k8s.io/api/core/v1"
+	networking_v1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -11,6 +12,8 @@ import (
 // +kubebuilder:resource:path="grafanaagents"
 // +kubebuilder:resource:singular="grafanaagent"
 // +kubebuilder:resource:categories="agent-operator"
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
 
 // GrafanaAgent defines a Grafana Agent deployment.
 type GrafanaAgent struct {
@@ -20,6 +23,9 @@ type GrafanaAgent struct {
 	// Spec holds the specification of the desired behavior for the Grafana Agent
 	// cluster.
 	Spec GrafanaAgentSpec `json:"spec,omitempty"`
+	// Status holds the most recently observed status of the Grafana Agent
+	// cluster.
+	Status GrafanaAgentStatus `json:"status,omitempty"`
 }
 
 // MetricsInstanceSelector returns a selector to find MetricsInstances.
@@ -94,6 +100,15 @@ type GrafanaAgentSpec struct {
 	// VolumeMounts in the Grafana Agent container that are generated as a result
 	// of StorageSpec objects.
 	VolumeMounts []v1.VolumeMount `json:"volumeMounts,omitempty"`
+	// Env holds additional environment variables to set in the grafana-agent
+	// container. Grafana Agent runs with -config.expand-env, so variables can
+	// be referenced in generated config values such as external labels.
+	// Variables set by the operator, such as SHARD and SHARDS, can't be
+	// overridden.
+	Env []v1.EnvVar `json:"env,omitempty"`
+	// EnvFrom holds additional sources of environment variables to set in the
+	// grafana-agent container.
+	EnvFrom []v1.EnvFromSource `json:"envFrom,omitempty"`
 	// Resources holds requests and limits for individual pods.
 	Resources v1.ResourceRequirements `json:"resources,omitempty"`
 	// NodeSelector defines which nodes pods should be scheduling on.
@@ -142,6 +157,9 @@ type GrafanaAgentSpec struct {
 	PriorityClassName string `json:"priorityClassName,omitempty"`
 	// Port name used for the pods and governing service. This defaults to agent-metrics.
 	PortName string `json:"portName,omitempty"`
+	// NetworkPolicy, if set, creates a NetworkPolicy which only allows
+	// ingress to Grafana Agent pods from the listed peers.
+	NetworkPolicy *NetworkPolicySpec `json:"networkPolicy,omitempty"`
 
 	// Metrics controls the metrics subsystem of the Agent and settings
 	// unique to metrics-specific pods that are deployed.
@@ -151,12 +169,72 @@ type GrafanaAgentSpec struct {
 	// logging-specific pods that are deployed.
 	Logs LogsSubsystemSpec `json:"logs,omitempty"`
 
+	// Integrations controls the integrations which run on every node of the
+	// cluster, such as node_exporter and cadvisor.
+	Integrations IntegrationsSubsystemSpec `json:"integrations,omitempty"`
+
 	// enableConfigReadAPI enables the read API for viewing currently running
 	// config port 8080 on the agent.
 	// +kubebuilder:default=false
 	EnableConfigReadAPI bool `json:"enableConfigReadAPI,omitempty"`
 }
 
+// Condition types reported in the status of GrafanaAgent, MetricsInstance,
+// and LogsInstance resources.
+const (
+	// ConditionConfigGenerated reports whether the Grafana Agent config was
+	// generated and written to its Secret.
+	ConditionConfigGenerated = "ConfigGenerated"
+	// ConditionWorkloadReady reports whether all pods of the StatefulSets and
+	// DaemonSets running Grafana Agent are ready.
+	ConditionWorkloadReady = "WorkloadReady"
+	// ConditionRemoteWriteHealthy reports whether metrics instances have
+	// remote_write endpoints to send samples to. The Operator doesn't observe
+	// whether samples are delivered, so it's Unknown when endpoints are
+	// configured.
+	ConditionRemoteWriteHealthy = "RemoteWriteHealthy"
+)
+
+// GrafanaAgentStatus is the most recently observed status of a Grafana Agent
+// cluster.
+type GrafanaAgentStatus struct {
+	// Conditions describe the current state of the Grafana Agent cluster.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// NetworkPolicySpec controls the NetworkPolicy generated for Grafana Agent
+// pods.
+type NetworkPolicySpec struct {
+	// From lists the peers allowed to connect to the HTTP port of Grafana
+	// Agent pods, such as the operator and Prometheus servers scraping the
+	// metrics of the Grafana Agent. Ingress from all other peers is denied.
+	From []networking_v1.NetworkPolicyPeer `json:"from,omitempty"`
+}
+
+// PodSchedulingSpec controls how the pods of a subsystem are scheduled. Set
+// fields override the corresponding fields of the GrafanaAgentSpec, allowing
+// metrics and logs pods to be scheduled onto different nodes.
+type PodSchedulingSpec struct {
+	// NodeSelector, if specified, overrides the nodeSelector of the
+	// GrafanaAgent for pods of this subsystem.
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+	// Affinity, if specified, overrides the affinity of the GrafanaAgent for
+	// pods of this subsystem.
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+	// Tolerations, if specified, override the tolerations of the GrafanaAgent
+	// for pods of this subsystem.
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+	// TopologySpreadConstraints, if specified, override the topology spread
+	// constraints of the GrafanaAgent for pods of this subsystem.
+	TopologySpreadConstraints []v1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+	// PriorityClassName, if specified, overrides the priority class of the
+	// GrafanaAgent for pods of this subsystem.
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+}
+
 // ObjectSelector is a set of selectors to use for finding an object in the
 // resource hierarchy. When NamespaceSelector is nil, objects should be
 // searched directly in the ParentNamespace.
@@ -165,4 +243,9 @@ type ObjectSelector struct {
 	ParentNamespace   string
 	NamespaceSelector *metav1.LabelSelector
 	Labels            *metav1.LabelSelector
+
+	// AllowedNamespaceSelector, when set, restricts found objects to
+	// namespaces matching the selector, regardless of ParentNamespace and
+	// NamespaceSelector.
+	AllowedNamespaceSelector *metav1.LabelSelector
 }
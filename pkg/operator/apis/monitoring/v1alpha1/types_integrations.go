@@ -0,0 +1,42 @@
+package v1alpha1
+
+// IntegrationsSubsystemSpec defines integrations which run on every node of
+// the cluster. Integrations are run by a DaemonSet separate from the logs
+// pods and send their metrics to the remote_write endpoints of the metrics
+// subsystem.
+type IntegrationsSubsystemSpec struct {
+	// NodeExporter enables the node_exporter integration, which collects
+	// hardware and OS metrics of each node. The root, /proc, and /sys
+	// filesystems of the host are mounted into the integrations pods, which
+	// run in the PID and network namespaces of the host.
+	NodeExporter *NodeExporterIntegrationSpec `json:"nodeExporter,omitempty"`
+	// Cadvisor enables the cadvisor integration, which collects resource usage
+	// metrics of the containers running on each node. The integrations pods
+	// run privileged to read cgroup statistics of the host.
+	Cadvisor *CadvisorIntegrationSpec `json:"cadvisor,omitempty"`
+
+	// PodSchedulingSpec overrides how integrations pods are scheduled.
+	PodSchedulingSpec `json:",inline"`
+}
+
+// NodeExporterIntegrationSpec configures the node_exporter integration.
+type NodeExporterIntegrationSpec struct {
+	// EnableCollectors is a list of collectors to enable in addition to the
+	// collectors enabled by default.
+	EnableCollectors []string `json:"enableCollectors,omitempty"`
+	// DisableCollectors is a list of collectors to disable.
+	DisableCollectors []string `json:"disableCollectors,omitempty"`
+}
+
+// CadvisorIntegrationSpec configures the cadvisor integration.
+type CadvisorIntegrationSpec struct {
+	// DockerOnly only reports metrics of Docker containers, in addition to
+	// root stats.
+	DockerOnly bool `json:"dockerOnly,omitempty"`
+	// EnabledMetrics is a list of metrics to enable. If set, overrides
+	// DisabledMetrics.
+	EnabledMetrics []string `json:"enabledMetrics,omitempty"`
+	// DisabledMetrics is a list of metrics to disable. If set, overrides the
+	// metrics disabled by default.
+	DisabledMetrics []string `json:"disabledMetrics,omitempty"`
+}
@@ -24,6 +24,11 @@ type LogsSubsystemSpec struct {
 	// namespaces to watch for LogInstances. If not provided, only checks own
 	// namespace.
 	InstanceNamespaceSelector *metav1.LabelSelector `json:"instanceNamespaceSelector,omitempty"`
+	// AllowedNamespaceSelector restricts the namespaces PodLogs are discovered
+	// from for all LogsInstances of this GrafanaAgent. PodLogs in other
+	// namespaces are ignored, even if the selectors of a LogsInstance match
+	// them. If not provided, PodLogs from any namespace can be discovered.
+	AllowedNamespaceSelector *metav1.LabelSelector `json:"allowedNamespaceSelector,omitempty"`
 
 	// IgnoreNamespaceSelectors, if true, will ignore NamespaceSelector settings
 	// from the PodLogs configs, and they will only discover endpoints within
@@ -33,6 +38,9 @@ type LogsSubsystemSpec struct {
 	// each metric that is user-created. The label value will always be the
 	// namespace of the object that is being created.
 	EnforcedNamespaceLabel string `json:"enforcedNamespaceLabel,omitempty"`
+
+	// PodSchedulingSpec overrides how logs pods are scheduled.
+	PodSchedulingSpec `json:",inline"`
 }
 
 // LogsClientSpec defines the client integration for logs, indicating which
@@ -88,6 +96,8 @@ type LogsBackoffConfigSpec struct {
 // +kubebuilder:resource:path="logsinstances"
 // +kubebuilder:resource:singular="logsinstance"
 // +kubebuilder:resource:categories="agent-operator"
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
 
 // LogsInstance controls an individual logs instance within a Grafana Agent
 // deployment.
@@ -98,6 +108,18 @@ type LogsInstance struct {
 	// Spec holds the specification of the desired behavior for the logs
 	// instance.
 	Spec LogsInstanceSpec `json:"spec,omitempty"`
+	// Status holds the most recently observed status of the logs instance.
+	Status LogsInstanceStatus `json:"status,omitempty"`
+}
+
+// LogsInstanceStatus is the most recently observed status of a logs instance.
+type LogsInstanceStatus struct {
+	// Conditions describe the current state of the logs instance, as reported
+	// by the GrafanaAgent which selected it.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // PodLogsSelector returns the selector to discover PodLogs.
@@ -164,6 +186,7 @@ type LogsInstanceList struct {
 
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:categories="agent-operator"
+// +kubebuilder:storageversion
 
 // PodLogs defines how to collect logs for a pod.
 type PodLogs struct {
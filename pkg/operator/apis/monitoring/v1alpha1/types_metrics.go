@@ -4,6 +4,7 @@ import (
 	prom_v1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // MetricsSubsystemSpec defines global settings to apply across the
@@ -23,6 +24,19 @@ type MetricsSubsystemSpec struct {
 	// continue to be available from the same instances. Sharding is performed on
 	// the content of the __address__ target meta-label.
 	Shards *int32 `json:"shards,omitempty"`
+	// WorkloadType is the type of workload created for each shard of metrics
+	// pods. Defaults to StatefulSet. Deployment may only be used when the WAL
+	// isn't stored on a PersistentVolume and with at most 1 replica, and lets
+	// pods be rescheduled faster, such as in clusters of spot instances.
+	// +kubebuilder:validation:Enum=StatefulSet;Deployment
+	WorkloadType MetricsWorkloadType `json:"workloadType,omitempty"`
+	// Autoscaling, if set, lets the operator change the number of shards based
+	// on the number of active targets. Shards is ignored when autoscaling is
+	// set.
+	Autoscaling *MetricsAutoscalingSpec `json:"autoscaling,omitempty"`
+	// PodDisruptionBudget, if set, creates a PodDisruptionBudget for each
+	// shard of metrics pods.
+	PodDisruptionBudget *PodDisruptionBudgetSpec `json:"podDisruptionBudget,omitempty"`
 	// ReplicaExternalLabelName is the name of the metrics external label used
 	// to denote replica name. Defaults to __replica__. External label will _not_
 	// be added when value is set to the empty string.
@@ -39,6 +53,10 @@ type MetricsSubsystemSpec struct {
 	// ExternalLabels are labels to add to any time series when sending data over
 	// remote_write.
 	ExternalLabels map[string]string `json:"externalLabels,omitempty"`
+	// ExternalLabelsFrom are external labels whose values are read from fields
+	// of the Grafana Agent pods through the downward API, such as the name of
+	// the pod or of the node it runs on.
+	ExternalLabelsFrom []ExternalLabelSource `json:"externalLabelsFrom,omitempty"`
 	// ArbitraryFSAccessThroughSMs configures whether configuration based on a
 	// ServiceMonitor can access arbitrary files on the file system of the
 	// Grafana Agent container e.g. bearer token files.
@@ -78,6 +96,67 @@ type MetricsSubsystemSpec struct {
 	// InstanceNamespaceSelector are the set of labels to determine which
 	// namespaces to watch for MetricsInstances. If not provided, only checks own namespace.
 	InstanceNamespaceSelector *metav1.LabelSelector `json:"instanceNamespaceSelector,omitempty"`
+	// AllowedNamespaceSelector restricts the namespaces ServiceMonitors,
+	// PodMonitors, and Probes are discovered from for all MetricsInstances of
+	// this GrafanaAgent. Resources in other namespaces are ignored, even if the
+	// selectors of a MetricsInstance match them. If not provided, resources
+	// from any namespace can be discovered.
+	AllowedNamespaceSelector *metav1.LabelSelector `json:"allowedNamespaceSelector,omitempty"`
+
+	// PodSchedulingSpec overrides how metrics pods are scheduled.
+	PodSchedulingSpec `json:",inline"`
+}
+
+// MetricsWorkloadType is the type of workload created for metrics pods.
+type MetricsWorkloadType string
+
+// Supported values for MetricsWorkloadType.
+const (
+	// MetricsWorkloadStatefulSet runs metrics pods as StatefulSets.
+	MetricsWorkloadStatefulSet MetricsWorkloadType = "StatefulSet"
+	// MetricsWorkloadDeployment runs metrics pods as Deployments, storing the
+	// WAL in an emptyDir volume.
+	MetricsWorkloadDeployment MetricsWorkloadType = "Deployment"
+)
+
+// ExternalLabelSource is an external label whose value is read from a field
+// of the Grafana Agent pod.
+type ExternalLabelSource struct {
+	// Name of the external label.
+	Name string `json:"name"`
+	// FieldRef selects the field of the pod to use as the value. Supports
+	// metadata.name, metadata.namespace, metadata.labels['<KEY>'],
+	// metadata.annotations['<KEY>'], spec.nodeName, spec.serviceAccountName,
+	// status.hostIP, and status.podIP.
+	FieldRef v1.ObjectFieldSelector `json:"fieldRef"`
+}
+
+// MetricsAutoscalingSpec controls how the number of metrics shards is
+// changed based on the number of active targets.
+type MetricsAutoscalingSpec struct {
+	// MinShards is the minimum number of shards. Defaults to 1.
+	// +kubebuilder:validation:Minimum=1
+	MinShards *int32 `json:"minShards,omitempty"`
+	// MaxShards is the maximum number of shards.
+	// +kubebuilder:validation:Minimum=1
+	MaxShards int32 `json:"maxShards"`
+	// TargetsPerShard is the number of active targets each shard should
+	// scrape. The number of shards is the total number of active targets
+	// divided by TargetsPerShard, rounded up.
+	// +kubebuilder:validation:Minimum=1
+	TargetsPerShard int32 `json:"targetsPerShard"`
+}
+
+// PodDisruptionBudgetSpec controls the PodDisruptionBudgets generated for
+// shards of metrics pods. Only one of MinAvailable and MaxUnavailable may be
+// set. When neither is set, MaxUnavailable defaults to 1.
+type PodDisruptionBudgetSpec struct {
+	// MinAvailable is the number or percentage of pods of a shard which must
+	// be available after an eviction.
+	MinAvailable *intstr.IntOrString `json:"minAvailable,omitempty"`
+	// MaxUnavailable is the number or percentage of pods of a shard which may
+	// be unavailable after an eviction.
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
 }
 
 // RemoteWriteSpec defines the remote_write configuration for Prometheus.
@@ -167,6 +246,8 @@ type MetadataConfig struct {
 // +kubebuilder:resource:path="metricsinstances"
 // +kubebuilder:resource:singular="metricsinstance"
 // +kubebuilder:resource:categories="agent-operator"
+// +kubebuilder:subresource:status
+// +kubebuilder:storageversion
 
 // MetricsInstance controls an individual Metrics instance within a
 // Grafana Agent deployment.
@@ -177,6 +258,19 @@ type MetricsInstance struct {
 	// Spec holds the specification of the desired behavior for the Metrics
 	// instance.
 	Spec MetricsInstanceSpec `json:"spec,omitempty"`
+	// Status holds the most recently observed status of the Metrics instance.
+	Status MetricsInstanceStatus `json:"status,omitempty"`
+}
+
+// MetricsInstanceStatus is the most recently observed status of a Metrics
+// instance.
+type MetricsInstanceStatus struct {
+	// Conditions describe the current state of the Metrics instance, as
+	// reported by the GrafanaAgent which selected it.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 // ServiceMonitorSelector returns a selector to find ServiceMonitors.
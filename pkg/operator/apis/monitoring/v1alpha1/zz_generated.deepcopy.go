@@ -7,8 +7,10 @@ package v1alpha1
 import (
 	"github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -26,6 +28,31 @@ func (in *CRIStageSpec) DeepCopy() *CRIStageSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CadvisorIntegrationSpec) DeepCopyInto(out *CadvisorIntegrationSpec) {
+	*out = *in
+	if in.EnabledMetrics != nil {
+		in, out := &in.EnabledMetrics, &out.EnabledMetrics
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DisabledMetrics != nil {
+		in, out := &in.DisabledMetrics, &out.DisabledMetrics
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CadvisorIntegrationSpec.
+func (in *CadvisorIntegrationSpec) DeepCopy() *CadvisorIntegrationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CadvisorIntegrationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DockerStageSpec) DeepCopyInto(out *DockerStageSpec) {
 	*out = *in
@@ -56,12 +83,29 @@ func (in *DropStageSpec) DeepCopy() *DropStageSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalLabelSource) DeepCopyInto(out *ExternalLabelSource) {
+	*out = *in
+	out.FieldRef = in.FieldRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalLabelSource.
+func (in *ExternalLabelSource) DeepCopy() *ExternalLabelSource {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalLabelSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GrafanaAgent) DeepCopyInto(out *GrafanaAgent) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaAgent.
@@ -160,6 +204,20 @@ func (in *GrafanaAgentSpec) DeepCopyInto(out *GrafanaAgentSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]corev1.EnvFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	in.Resources.DeepCopyInto(&out.Resources)
 	if in.NodeSelector != nil {
 		in, out := &in.NodeSelector, &out.NodeSelector
@@ -216,8 +274,14 @@ func (in *GrafanaAgentSpec) DeepCopyInto(out *GrafanaAgentSpec) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.NetworkPolicy != nil {
+		in, out := &in.NetworkPolicy, &out.NetworkPolicy
+		*out = new(NetworkPolicySpec)
+		(*in).DeepCopyInto(*out)
+	}
 	in.Metrics.DeepCopyInto(&out.Metrics)
 	in.Logs.DeepCopyInto(&out.Logs)
+	in.Integrations.DeepCopyInto(&out.Integrations)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaAgentSpec.
@@ -230,6 +294,54 @@ func (in *GrafanaAgentSpec) DeepCopy() *GrafanaAgentSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GrafanaAgentStatus) DeepCopyInto(out *GrafanaAgentStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GrafanaAgentStatus.
+func (in *GrafanaAgentStatus) DeepCopy() *GrafanaAgentStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GrafanaAgentStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IntegrationsSubsystemSpec) DeepCopyInto(out *IntegrationsSubsystemSpec) {
+	*out = *in
+	if in.NodeExporter != nil {
+		in, out := &in.NodeExporter, &out.NodeExporter
+		*out = new(NodeExporterIntegrationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cadvisor != nil {
+		in, out := &in.Cadvisor, &out.Cadvisor
+		*out = new(CadvisorIntegrationSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	in.PodSchedulingSpec.DeepCopyInto(&out.PodSchedulingSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IntegrationsSubsystemSpec.
+func (in *IntegrationsSubsystemSpec) DeepCopy() *IntegrationsSubsystemSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IntegrationsSubsystemSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *JSONStageSpec) DeepCopyInto(out *JSONStageSpec) {
 	*out = *in
@@ -310,6 +422,7 @@ func (in *LogsInstance) DeepCopyInto(out *LogsInstance) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogsInstance.
@@ -408,6 +521,28 @@ func (in *LogsInstanceSpec) DeepCopy() *LogsInstanceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LogsInstanceStatus) DeepCopyInto(out *LogsInstanceStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogsInstanceStatus.
+func (in *LogsInstanceStatus) DeepCopy() *LogsInstanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LogsInstanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *LogsSubsystemSpec) DeepCopyInto(out *LogsSubsystemSpec) {
 	*out = *in
@@ -433,6 +568,12 @@ func (in *LogsSubsystemSpec) DeepCopyInto(out *LogsSubsystemSpec) {
 		*out = new(metav1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AllowedNamespaceSelector != nil {
+		in, out := &in.AllowedNamespaceSelector, &out.AllowedNamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	in.PodSchedulingSpec.DeepCopyInto(&out.PodSchedulingSpec)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LogsSubsystemSpec.
@@ -490,12 +631,33 @@ func (in *MetadataConfig) DeepCopy() *MetadataConfig {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricsAutoscalingSpec) DeepCopyInto(out *MetricsAutoscalingSpec) {
+	*out = *in
+	if in.MinShards != nil {
+		in, out := &in.MinShards, &out.MinShards
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsAutoscalingSpec.
+func (in *MetricsAutoscalingSpec) DeepCopy() *MetricsAutoscalingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricsAutoscalingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetricsInstance) DeepCopyInto(out *MetricsInstance) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsInstance.
@@ -614,6 +776,28 @@ func (in *MetricsInstanceSpec) DeepCopy() *MetricsInstanceSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricsInstanceStatus) DeepCopyInto(out *MetricsInstanceStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsInstanceStatus.
+func (in *MetricsInstanceStatus) DeepCopy() *MetricsInstanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricsInstanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetricsStageSpec) DeepCopyInto(out *MetricsStageSpec) {
 	*out = *in
@@ -664,6 +848,16 @@ func (in *MetricsSubsystemSpec) DeepCopyInto(out *MetricsSubsystemSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(MetricsAutoscalingSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PodDisruptionBudget != nil {
+		in, out := &in.PodDisruptionBudget, &out.PodDisruptionBudget
+		*out = new(PodDisruptionBudgetSpec)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.ReplicaExternalLabelName != nil {
 		in, out := &in.ReplicaExternalLabelName, &out.ReplicaExternalLabelName
 		*out = new(string)
@@ -681,6 +875,11 @@ func (in *MetricsSubsystemSpec) DeepCopyInto(out *MetricsSubsystemSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.ExternalLabelsFrom != nil {
+		in, out := &in.ExternalLabelsFrom, &out.ExternalLabelsFrom
+		*out = make([]ExternalLabelSource, len(*in))
+		copy(*out, *in)
+	}
 	out.ArbitraryFSAccessThroughSMs = in.ArbitraryFSAccessThroughSMs
 	if in.EnforcedSampleLimit != nil {
 		in, out := &in.EnforcedSampleLimit, &out.EnforcedSampleLimit
@@ -702,6 +901,12 @@ func (in *MetricsSubsystemSpec) DeepCopyInto(out *MetricsSubsystemSpec) {
 		*out = new(metav1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AllowedNamespaceSelector != nil {
+		in, out := &in.AllowedNamespaceSelector, &out.AllowedNamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	in.PodSchedulingSpec.DeepCopyInto(&out.PodSchedulingSpec)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsSubsystemSpec.
@@ -729,6 +934,53 @@ func (in *MultilineStageSpec) DeepCopy() *MultilineStageSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkPolicySpec) DeepCopyInto(out *NetworkPolicySpec) {
+	*out = *in
+	if in.From != nil {
+		in, out := &in.From, &out.From
+		*out = make([]networkingv1.NetworkPolicyPeer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkPolicySpec.
+func (in *NetworkPolicySpec) DeepCopy() *NetworkPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeExporterIntegrationSpec) DeepCopyInto(out *NodeExporterIntegrationSpec) {
+	*out = *in
+	if in.EnableCollectors != nil {
+		in, out := &in.EnableCollectors, &out.EnableCollectors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DisableCollectors != nil {
+		in, out := &in.DisableCollectors, &out.DisableCollectors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeExporterIntegrationSpec.
+func (in *NodeExporterIntegrationSpec) DeepCopy() *NodeExporterIntegrationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeExporterIntegrationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectSelector) DeepCopyInto(out *ObjectSelector) {
 	*out = *in
@@ -742,6 +994,11 @@ func (in *ObjectSelector) DeepCopyInto(out *ObjectSelector) {
 		*out = new(metav1.LabelSelector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.AllowedNamespaceSelector != nil {
+		in, out := &in.AllowedNamespaceSelector, &out.AllowedNamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectSelector.
@@ -893,6 +1150,31 @@ func (in *PipelineStageSpec) DeepCopy() *PipelineStageSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodDisruptionBudgetSpec) DeepCopyInto(out *PodDisruptionBudgetSpec) {
+	*out = *in
+	if in.MinAvailable != nil {
+		in, out := &in.MinAvailable, &out.MinAvailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+	if in.MaxUnavailable != nil {
+		in, out := &in.MaxUnavailable, &out.MaxUnavailable
+		*out = new(intstr.IntOrString)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodDisruptionBudgetSpec.
+func (in *PodDisruptionBudgetSpec) DeepCopy() *PodDisruptionBudgetSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodDisruptionBudgetSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *PodLogs) DeepCopyInto(out *PodLogs) {
 	*out = *in
@@ -999,6 +1281,47 @@ func (in *PodLogsSpec) DeepCopy() *PodLogsSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodSchedulingSpec) DeepCopyInto(out *PodSchedulingSpec) {
+	*out = *in
+	if in.NodeSelector != nil {
+		in, out := &in.NodeSelector, &out.NodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Affinity != nil {
+		in, out := &in.Affinity, &out.Affinity
+		*out = new(corev1.Affinity)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.TopologySpreadConstraints != nil {
+		in, out := &in.TopologySpreadConstraints, &out.TopologySpreadConstraints
+		*out = make([]corev1.TopologySpreadConstraint, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodSchedulingSpec.
+func (in *PodSchedulingSpec) DeepCopy() *PodSchedulingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PodSchedulingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *QueueConfig) DeepCopyInto(out *QueueConfig) {
 	*out = *in
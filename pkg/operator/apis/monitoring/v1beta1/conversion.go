@@ -0,0 +1,109 @@
+package v1beta1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// ConvertTo converts a to the hub version.
+func (a *GrafanaAgent) ConvertTo(hub conversion.Hub) error {
+	dst, ok := hub.(*v1alpha1.GrafanaAgent)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", hub)
+	}
+	dst.ObjectMeta = *a.ObjectMeta.DeepCopy()
+	return convertFields(&a.Spec, &dst.Spec, &a.Status, &dst.Status)
+}
+
+// ConvertFrom converts the hub version to a.
+func (a *GrafanaAgent) ConvertFrom(hub conversion.Hub) error {
+	src, ok := hub.(*v1alpha1.GrafanaAgent)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", hub)
+	}
+	a.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	return convertFields(&src.Spec, &a.Spec, &src.Status, &a.Status)
+}
+
+// ConvertTo converts i to the hub version.
+func (i *MetricsInstance) ConvertTo(hub conversion.Hub) error {
+	dst, ok := hub.(*v1alpha1.MetricsInstance)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", hub)
+	}
+	dst.ObjectMeta = *i.ObjectMeta.DeepCopy()
+	return convertFields(&i.Spec, &dst.Spec, &i.Status, &dst.Status)
+}
+
+// ConvertFrom converts the hub version to i.
+func (i *MetricsInstance) ConvertFrom(hub conversion.Hub) error {
+	src, ok := hub.(*v1alpha1.MetricsInstance)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", hub)
+	}
+	i.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	return convertFields(&src.Spec, &i.Spec, &src.Status, &i.Status)
+}
+
+// ConvertTo converts i to the hub version.
+func (i *LogsInstance) ConvertTo(hub conversion.Hub) error {
+	dst, ok := hub.(*v1alpha1.LogsInstance)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", hub)
+	}
+	dst.ObjectMeta = *i.ObjectMeta.DeepCopy()
+	return convertFields(&i.Spec, &dst.Spec, &i.Status, &dst.Status)
+}
+
+// ConvertFrom converts the hub version to i.
+func (i *LogsInstance) ConvertFrom(hub conversion.Hub) error {
+	src, ok := hub.(*v1alpha1.LogsInstance)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", hub)
+	}
+	i.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	return convertFields(&src.Spec, &i.Spec, &src.Status, &i.Status)
+}
+
+// ConvertTo converts p to the hub version.
+func (p *PodLogs) ConvertTo(hub conversion.Hub) error {
+	dst, ok := hub.(*v1alpha1.PodLogs)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", hub)
+	}
+	dst.ObjectMeta = *p.ObjectMeta.DeepCopy()
+	return convertFields(&p.Spec, &dst.Spec)
+}
+
+// ConvertFrom converts the hub version to p.
+func (p *PodLogs) ConvertFrom(hub conversion.Hub) error {
+	src, ok := hub.(*v1alpha1.PodLogs)
+	if !ok {
+		return fmt.Errorf("unexpected hub type %T", hub)
+	}
+	p.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	return convertFields(&src.Spec, &p.Spec)
+}
+
+// convertFields converts pairs of source and destination fields through their
+// JSON representation. The schemas of v1alpha1 and v1beta1 are currently
+// identical; fields which get renamed or restructured in v1beta1 must be
+// converted explicitly after calling convertFields.
+func convertFields(pairs ...interface{}) error {
+	if len(pairs)%2 != 0 {
+		return fmt.Errorf("fields must be passed in pairs of source and destination")
+	}
+	for n := 0; n < len(pairs); n += 2 {
+		bb, err := json.Marshal(pairs[n])
+		if err != nil {
+			return fmt.Errorf("failed to marshal %T: %w", pairs[n], err)
+		}
+		if err := json.Unmarshal(bb, pairs[n+1]); err != nil {
+			return fmt.Errorf("failed to unmarshal %T: %w", pairs[n+1], err)
+		}
+	}
+	return nil
+}
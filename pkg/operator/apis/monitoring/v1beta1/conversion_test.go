@@ -0,0 +1,114 @@
+package v1beta1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	"github.com/stretchr/testify/require"
+	apix "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+)
+
+func TestConvertible(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, AddToScheme(scheme))
+
+	for _, obj := range []runtime.Object{
+		&GrafanaAgent{},
+		&MetricsInstance{},
+		&LogsInstance{},
+		&PodLogs{},
+	} {
+		ok, err := conversion.IsConvertible(scheme, obj)
+		require.NoError(t, err)
+		require.True(t, ok, "%T isn't convertible", obj)
+	}
+}
+
+func TestConversionWebhook(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	require.NoError(t, AddToScheme(scheme))
+
+	wh := &conversion.Webhook{}
+	require.NoError(t, wh.InjectScheme(scheme))
+
+	agent := &v1alpha1.GrafanaAgent{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: v1alpha1.SchemeGroupVersion.String(),
+			Kind:       "GrafanaAgent",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "agent",
+			Namespace: "default",
+			Labels:    map[string]string{"app": "grafana-agent"},
+		},
+		Spec: v1alpha1.GrafanaAgentSpec{
+			LogLevel: "debug",
+			Metrics: v1alpha1.MetricsSubsystemSpec{
+				Shards: pointer.Int32(2),
+				InstanceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"agent": "grafana-agent"},
+				},
+			},
+		},
+		Status: v1alpha1.GrafanaAgentStatus{
+			Conditions: []metav1.Condition{{
+				Type:   v1alpha1.ConditionConfigGenerated,
+				Status: metav1.ConditionTrue,
+				Reason: "ConfigGenerated",
+			}},
+		},
+	}
+
+	convert := func(obj runtime.Object, desiredAPIVersion string) []byte {
+		raw, err := json.Marshal(obj)
+		require.NoError(t, err)
+
+		review := apix.ConversionReview{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: apix.SchemeGroupVersion.String(),
+				Kind:       "ConversionReview",
+			},
+			Request: &apix.ConversionRequest{
+				UID:               "test",
+				DesiredAPIVersion: desiredAPIVersion,
+				Objects:           []runtime.RawExtension{{Raw: raw}},
+			},
+		}
+		body, err := json.Marshal(review)
+		require.NoError(t, err)
+
+		req := httptest.NewRequest(http.MethodPost, "/convert", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		wh.ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp apix.ConversionReview
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Equal(t, metav1.StatusSuccess, resp.Response.Result.Status, resp.Response.Result.Message)
+		require.Len(t, resp.Response.ConvertedObjects, 1)
+		return resp.Response.ConvertedObjects[0].Raw
+	}
+
+	var converted GrafanaAgent
+	require.NoError(t, json.Unmarshal(convert(agent, SchemeGroupVersion.String()), &converted))
+	require.Equal(t, SchemeGroupVersion.String(), converted.APIVersion)
+	require.Equal(t, agent.ObjectMeta, converted.ObjectMeta)
+	require.Equal(t, "debug", converted.Spec.LogLevel)
+	require.Equal(t, int32(2), *converted.Spec.Metrics.Shards)
+	require.Len(t, converted.Status.Conditions, 1)
+
+	var roundTripped v1alpha1.GrafanaAgent
+	require.NoError(t, json.Unmarshal(convert(&converted, v1alpha1.SchemeGroupVersion.String()), &roundTripped))
+	require.Equal(t, agent, &roundTripped)
+}
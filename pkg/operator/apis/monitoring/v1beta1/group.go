@@ -0,0 +1,37 @@
+// +kubebuilder:object:generate=true
+// +groupName=monitoring.grafana.com
+
+// Package v1beta1 holds the v1beta1 API of the custom resources of the
+// Grafana Agent Operator. Resources are converted to and from v1alpha1, which
+// is the version stored by the API server and used by the Operator.
+package v1beta1
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/scheme"
+)
+
+var (
+	// SchemeGroupVersion is the group version used to register CRDs for this
+	// package.
+	SchemeGroupVersion = schema.GroupVersion{Group: "monitoring.grafana.com", Version: "v1beta1"}
+
+	// SchemeBuilder is used to add Go types to the GroupVersionKind scheme.
+	SchemeBuilder = &scheme.Builder{GroupVersion: SchemeGroupVersion}
+
+	// AddToScheme is required by client packages.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+func init() {
+	SchemeBuilder.Register(
+		&GrafanaAgent{},
+		&GrafanaAgentList{},
+		&MetricsInstance{},
+		&MetricsInstanceList{},
+		&LogsInstance{},
+		&LogsInstanceList{},
+		&PodLogs{},
+		&PodLogsList{},
+	)
+}
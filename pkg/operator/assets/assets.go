@@ -3,7 +3,10 @@
 package assets
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"sort"
 
 	prom_v1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	v1 "k8s.io/api/core/v1"
@@ -26,6 +29,25 @@ type Key string
 // static secrets in generated configuration files.
 type SecretStore map[Key]string
 
+// Hash returns a hash of the keys and values in the SecretStore. The hash
+// changes whenever any value changes, and can be used to restart pods which
+// read the values from disk.
+func (s SecretStore) Hash() string {
+	keys := make([]string, 0, len(s))
+	for k := range s {
+		keys = append(keys, string(k))
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		// Separate keys and values with a NUL byte so different splits of
+		// the same bytes don't produce the same hash.
+		fmt.Fprintf(h, "%s\x00%s\x00", k, s[Key(k)])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // KeyForSecret returns the key for a given namespace and a secret key
 // selector.
 func KeyForSecret(namespace string, sel *v1.SecretKeySelector) Key {
@@ -69,6 +69,9 @@ func buildHierarchy(ctx context.Context, l log.Logger, cli client.Client, root *
 			{List: &podMonitors, Selector: metricsInst.PodMonitorSelector()},
 			{List: &probes, Selector: metricsInst.ProbeSelector()},
 		}
+		for i := range children {
+			children[i].Selector.AllowedNamespaceSelector = root.Spec.Metrics.AllowedNamespaceSelector
+		}
 		if err := search(children); err != nil {
 			return deployment, nil, err
 		}
@@ -77,7 +80,7 @@ func buildHierarchy(ctx context.Context, l log.Logger, cli client.Client, root *
 			Instance:        metricsInst,
 			ServiceMonitors: filterServiceMonitors(l, root, &serviceMonitors).Items,
 			PodMonitors:     podMonitors.Items,
-			Probes:          probes.Items,
+			Probes:          filterProbes(l, root, &probes).Items,
 		})
 	}
 
@@ -89,6 +92,9 @@ func buildHierarchy(ctx context.Context, l log.Logger, cli client.Client, root *
 		var children = []hierarchyResource{
 			{List: &podLogs, Selector: logsInst.PodLogsSelector()},
 		}
+		for i := range children {
+			children[i].Selector.AllowedNamespaceSelector = root.Spec.Logs.AllowedNamespaceSelector
+		}
 		if err := search(children); err != nil {
 			return deployment, nil, err
 		}
@@ -139,6 +145,14 @@ func toSelector(os grafana.ObjectSelector) (hierarchy.Selector, error) {
 		res.NamespaceLabels = sel
 	}
 
+	if os.AllowedNamespaceSelector != nil {
+		sel, err := metav1.LabelSelectorAsSelector(os.AllowedNamespaceSelector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed namespace selector: %w", err)
+		}
+		res.AllowedNamespaceLabels = sel
+	}
+
 	sel, err := metav1.LabelSelectorAsSelector(os.Labels)
 	if err != nil {
 		return nil, fmt.Errorf("invalid label selector: %w", err)
@@ -178,6 +192,31 @@ Item:
 	}
 }
 
+// filterProbes removes probes which can't be converted into a scrape config
+// from list.
+func filterProbes(l log.Logger, root *grafana.GrafanaAgent, list *prom.ProbeList) *prom.ProbeList {
+	items := make([]*prom.Probe, 0, len(list.Items))
+
+	for _, item := range list.Items {
+		if item.Spec.Targets.StaticConfig == nil && item.Spec.Targets.Ingress == nil {
+			level.Warn(l).Log(
+				"msg", "skipping probe",
+				"agent", client.ObjectKeyFromObject(root),
+				"probe", client.ObjectKeyFromObject(item),
+				"err", "probe needs at least one target of type staticConfig or ingress",
+			)
+			continue
+		}
+		items = append(items, item)
+	}
+
+	return &prom.ProbeList{
+		TypeMeta: list.TypeMeta,
+		ListMeta: *list.ListMeta.DeepCopy(),
+		Items:    items,
+	}
+}
+
 func testForArbitraryFSAccess(e prom.Endpoint) error {
 	if e.BearerTokenFile != "" {
 		return fmt.Errorf("it accesses file system via bearer token file which is disallowed via GrafanaAgent specification")
@@ -8,6 +8,8 @@ import (
 
 	apps_v1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	networking_v1 "k8s.io/api/networking/v1"
+	policy_v1 "k8s.io/api/policy/v1"
 	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -115,6 +117,62 @@ func CreateOrUpdateEndpoints(ctx context.Context, c client.Client, eps *v1.Endpo
 	return nil
 }
 
+// CreateOrUpdatePodDisruptionBudget applies the given PodDisruptionBudget against the client.
+func CreateOrUpdatePodDisruptionBudget(ctx context.Context, c client.Client, pdb *policy_v1.PodDisruptionBudget) error {
+	var exist policy_v1.PodDisruptionBudget
+	err := c.Get(ctx, client.ObjectKeyFromObject(pdb), &exist)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return fmt.Errorf("failed to retrieve existing pod disruption budget: %w", err)
+	}
+
+	if k8s_errors.IsNotFound(err) {
+		err := c.Create(ctx, pdb)
+		if err != nil {
+			return fmt.Errorf("failed to create pod disruption budget: %w", err)
+		}
+	} else {
+		pdb.ResourceVersion = exist.ResourceVersion
+		pdb.SetOwnerReferences(mergeOwnerReferences(pdb.GetOwnerReferences(), exist.GetOwnerReferences()))
+		pdb.SetLabels(mergeMaps(pdb.Labels, exist.Labels))
+		pdb.SetAnnotations(mergeMaps(pdb.Annotations, exist.Annotations))
+
+		err := c.Update(ctx, pdb)
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return fmt.Errorf("failed to update pod disruption budget: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// CreateOrUpdateNetworkPolicy applies the given NetworkPolicy against the client.
+func CreateOrUpdateNetworkPolicy(ctx context.Context, c client.Client, np *networking_v1.NetworkPolicy) error {
+	var exist networking_v1.NetworkPolicy
+	err := c.Get(ctx, client.ObjectKeyFromObject(np), &exist)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return fmt.Errorf("failed to retrieve existing network policy: %w", err)
+	}
+
+	if k8s_errors.IsNotFound(err) {
+		err := c.Create(ctx, np)
+		if err != nil {
+			return fmt.Errorf("failed to create network policy: %w", err)
+		}
+	} else {
+		np.ResourceVersion = exist.ResourceVersion
+		np.SetOwnerReferences(mergeOwnerReferences(np.GetOwnerReferences(), exist.GetOwnerReferences()))
+		np.SetLabels(mergeMaps(np.Labels, exist.Labels))
+		np.SetAnnotations(mergeMaps(np.Annotations, exist.Annotations))
+
+		err := c.Update(ctx, np)
+		if err != nil && !k8s_errors.IsNotFound(err) {
+			return fmt.Errorf("failed to update network policy: %w", err)
+		}
+	}
+
+	return nil
+}
+
 // CreateOrUpdateStatefulSet applies the given StatefulSet against the client.
 func CreateOrUpdateStatefulSet(ctx context.Context, c client.Client, ss *apps_v1.StatefulSet) error {
 	var exist apps_v1.StatefulSet
@@ -195,6 +253,46 @@ func CreateOrUpdateDaemonSet(ctx context.Context, c client.Client, ss *apps_v1.D
 	return nil
 }
 
+// CreateOrUpdateDeployment applies the given Deployment against the client.
+func CreateOrUpdateDeployment(ctx context.Context, c client.Client, d *apps_v1.Deployment) error {
+	var exist apps_v1.Deployment
+	err := c.Get(ctx, client.ObjectKeyFromObject(d), &exist)
+	if err != nil && !k8s_errors.IsNotFound(err) {
+		return fmt.Errorf("failed to retrieve existing deployment: %w", err)
+	}
+
+	if k8s_errors.IsNotFound(err) {
+		err := c.Create(ctx, d)
+		if err != nil {
+			return fmt.Errorf("failed to create deployment: %w", err)
+		}
+	} else {
+		d.ResourceVersion = exist.ResourceVersion
+		d.SetOwnerReferences(mergeOwnerReferences(d.GetOwnerReferences(), exist.GetOwnerReferences()))
+		d.SetLabels(mergeMaps(d.Labels, exist.Labels))
+		d.SetAnnotations(mergeMaps(d.Annotations, exist.Annotations))
+
+		err := c.Update(ctx, d)
+		if k8s_errors.IsNotAcceptable(err) || k8s_errors.IsInvalid(err) {
+			// Resource version should only be set when updating
+			d.ResourceVersion = ""
+
+			err = c.Delete(ctx, d)
+			if err != nil {
+				return fmt.Errorf("failed to update deployment when deleting old deployment: %w", err)
+			}
+			err = c.Create(ctx, d)
+			if err != nil {
+				return fmt.Errorf("failed to update deployment when creating replacement deployment: %w", err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to update deployment: %w", err)
+		}
+	}
+
+	return nil
+}
+
 func mergeOwnerReferences(new, old []meta_v1.OwnerReference) []meta_v1.OwnerReference {
 	existing := make(map[types.UID]bool)
 	for _, ref := range old {
@@ -27,6 +27,8 @@ const (
 	MetricsType Type = iota + 1
 	// LogsType generates a configuration for logs.
 	LogsType
+	// IntegrationsType generates a configuration for integrations.
+	IntegrationsType
 )
 
 // String returns the string form of Type.
@@ -36,6 +38,8 @@ func (t Type) String() string {
 		return "metrics"
 	case LogsType:
 		return "logs"
+	case IntegrationsType:
+		return "integrations"
 	default:
 		return fmt.Sprintf("unknown (%d)", int(t))
 	}
@@ -101,10 +105,19 @@ func (d *Deployment) DeepCopy() *Deployment {
 		})
 	}
 
+	var secrets assets.SecretStore
+	if d.Secrets != nil {
+		secrets = make(assets.SecretStore, len(d.Secrets))
+		for k, v := range d.Secrets {
+			secrets[k] = v
+		}
+	}
+
 	return &Deployment{
 		Agent:   d.Agent.DeepCopy(),
 		Metrics: p,
 		Logs:    l,
+		Secrets: secrets,
 	}
 }
 
@@ -129,6 +142,8 @@ func (d *Deployment) BuildConfig(secrets assets.SecretStore, ty Type) (string, e
 		return vm.EvaluateFile("./agent-metrics.libsonnet")
 	case LogsType:
 		return vm.EvaluateFile("./agent-logs.libsonnet")
+	case IntegrationsType:
+		return vm.EvaluateFile("./agent-integrations.libsonnet")
 	default:
 		panic(fmt.Sprintf("unexpected config type %v", ty))
 	}
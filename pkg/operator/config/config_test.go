@@ -214,6 +214,113 @@ metrics:
 	}
 }
 
+func TestAdditionalScrapeConfigsMetricsSharding(t *testing.T) {
+	var store = make(assets.SecretStore)
+
+	additionalSelector := &v1.SecretKeySelector{
+		LocalObjectReference: v1.LocalObjectReference{Name: "configs"},
+		Key:                  "configs",
+	}
+
+	shards := int32(2)
+	input := Deployment{
+		Agent: &grafana.GrafanaAgent{
+			ObjectMeta: meta_v1.ObjectMeta{
+				Namespace: "operator",
+				Name:      "agent",
+			},
+			Spec: grafana.GrafanaAgentSpec{
+				Image:              strPointer("grafana/agent:latest"),
+				ServiceAccountName: "agent",
+				Metrics: grafana.MetricsSubsystemSpec{
+					InstanceSelector: &meta_v1.LabelSelector{
+						MatchLabels: map[string]string{"agent": "agent"},
+					},
+					Shards: &shards,
+				},
+			},
+		},
+		Metrics: []MetricsInstance{{
+			Instance: &grafana.MetricsInstance{
+				ObjectMeta: meta_v1.ObjectMeta{
+					Namespace: "operator",
+					Name:      "primary",
+				},
+				Spec: grafana.MetricsInstanceSpec{
+					RemoteWrite: []grafana.RemoteWriteSpec{{
+						URL: "http://cortex:80/api/prom/push",
+					}},
+					AdditionalScrapeConfigs: additionalSelector,
+				},
+			},
+		}},
+	}
+
+	// The second job already shards its targets and shouldn't be changed.
+	store[assets.KeyForSecret("operator", additionalSelector)] = util.Untab(`
+	- job_name: job
+		kubernetes_sd_configs:
+		- role: node
+	- job_name: sharded
+		kubernetes_sd_configs:
+		- role: node
+		relabel_configs:
+		- source_labels: [__meta_kubernetes_node_name]
+			target_label: __tmp_hash
+			modulus: $(SHARDS)
+			action: hashmod
+		- source_labels: [__tmp_hash]
+			regex: $(SHARD)
+			action: keep
+	`)
+
+	expect := util.Untab(`
+server:
+  http_listen_port: 8080
+
+metrics:
+  wal_directory: /var/lib/grafana-agent/data
+  global:
+    external_labels:
+      __replica__: replica-$(STATEFULSET_ORDINAL_NUMBER)
+      cluster: operator/agent
+  configs:
+  - name: operator/primary
+    remote_write:
+    - url: http://cortex:80/api/prom/push
+    scrape_configs:
+    - job_name: job
+      kubernetes_sd_configs:
+      - role: node
+      relabel_configs:
+      - source_labels: [__address__]
+        target_label: __tmp_hash
+        modulus: 2
+        action: hashmod
+      - source_labels: [__tmp_hash]
+        regex: $(SHARD)
+        action: keep
+    - job_name: sharded
+      kubernetes_sd_configs:
+      - role: node
+      relabel_configs:
+      - source_labels: [__meta_kubernetes_node_name]
+        target_label: __tmp_hash
+        modulus: $(SHARDS)
+        action: hashmod
+      - source_labels: [__tmp_hash]
+        regex: $(SHARD)
+        action: keep
+	`)
+
+	result, err := input.BuildConfig(store, MetricsType)
+	require.NoError(t, err)
+
+	if !assert.YAMLEq(t, expect, result) {
+		fmt.Println(result)
+	}
+}
+
 func TestBuildConfigLogs(t *testing.T) {
 	var store = make(assets.SecretStore)
 
@@ -259,4 +366,90 @@ func TestBuildConfigLogs(t *testing.T) {
 	}
 }
 
+func TestDeploymentDeepCopy(t *testing.T) {
+	key := assets.Key("/secrets/default/example-secret/key")
+	d := Deployment{
+		Agent:   &grafana.GrafanaAgent{},
+		Secrets: assets.SecretStore{key: "somesecret"},
+	}
+
+	cp := d.DeepCopy()
+	require.Equal(t, d.Secrets, cp.Secrets)
+
+	// Changing the copy must not change the original.
+	cp.Secrets[key] = "changed"
+	cp.Secrets[assets.Key("/secrets/default/other-secret/key")] = "added"
+	require.Equal(t, assets.SecretStore{key: "somesecret"}, d.Secrets)
+}
+
 func strPointer(s string) *string { return &s }
+
+func TestBuildConfigIntegrations(t *testing.T) {
+	var store = make(assets.SecretStore)
+
+	tt := []struct {
+		input  string
+		expect string
+	}{
+		{
+			input: util.Untab(`
+				metadata:
+					name: example
+					namespace: default
+				spec:
+					logLevel: debug
+					metrics:
+						scrapeInterval: 15s
+						remoteWrite:
+						- url: http://localhost:9090/api/v1/write
+					integrations:
+						nodeExporter:
+							disableCollectors: [ipvs]
+						cadvisor:
+							dockerOnly: true
+			`),
+			expect: util.Untab(`
+				server:
+					http_listen_port: 8080
+					log_level: debug
+
+				metrics:
+					wal_directory: /var/lib/grafana-agent/data
+					global:
+						scrape_interval: 15s
+						external_labels:
+							cluster: default/example
+							__replica__: replica-$(STATEFULSET_ORDINAL_NUMBER)
+						remote_write:
+						- url: http://localhost:9090/api/v1/write
+
+				integrations:
+					node_exporter:
+						enabled: true
+						rootfs_path: /host/root
+						sysfs_path: /host/sys
+						procfs_path: /host/proc
+						disable_collectors: [ipvs]
+					cadvisor:
+						enabled: true
+						docker_only: true
+			`),
+		},
+	}
+
+	for i, tc := range tt {
+		t.Run(fmt.Sprintf("index_%d", i), func(t *testing.T) {
+			var spec grafana.GrafanaAgent
+			err := k8s_yaml.Unmarshal([]byte(tc.input), &spec)
+			require.NoError(t, err)
+
+			d := Deployment{Agent: &spec}
+			result, err := d.BuildConfig(store, IntegrationsType)
+			require.NoError(t, err)
+
+			if !assert.YAMLEq(t, tc.expect, result) {
+				fmt.Println(result)
+			}
+		})
+	}
+}
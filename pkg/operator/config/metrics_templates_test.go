@@ -82,6 +82,50 @@ func TestExternalLabels(t *testing.T) {
 				replica: replica-$(STATEFULSET_ORDINAL_NUMBER)
 			`),
 		},
+		{
+			name: "external labels from pod fields",
+			input: Deployment{
+				Agent: &v1alpha1.GrafanaAgent{
+					ObjectMeta: meta_v1.ObjectMeta{
+						Namespace: "operator",
+						Name:      "agent",
+					},
+					Spec: v1alpha1.GrafanaAgentSpec{
+						Metrics: v1alpha1.MetricsSubsystemSpec{
+							ExternalLabelsFrom: []v1alpha1.ExternalLabelSource{{
+								Name:     "node",
+								FieldRef: v1.ObjectFieldSelector{FieldPath: "spec.nodeName"},
+							}},
+						},
+					},
+				},
+			},
+			expect: util.Untab(`
+				cluster: operator/agent
+				node: ${EXTERNAL_LABEL_node}
+				__replica__: replica-$(STATEFULSET_ORDINAL_NUMBER)
+			`),
+		},
+		{
+			name: "deployment",
+			input: Deployment{
+				Agent: &v1alpha1.GrafanaAgent{
+					ObjectMeta: meta_v1.ObjectMeta{
+						Namespace: "operator",
+						Name:      "agent",
+					},
+					Spec: v1alpha1.GrafanaAgentSpec{
+						Metrics: v1alpha1.MetricsSubsystemSpec{
+							WorkloadType: v1alpha1.MetricsWorkloadDeployment,
+						},
+					},
+				},
+			},
+			expect: util.Untab(`
+				cluster: operator/agent
+				__replica__: replica-0
+			`),
+		},
 	}
 
 	for _, tc := range tt {
@@ -378,6 +422,70 @@ func TestProbe(t *testing.T) {
 					target_label: instance
 				- replacement: ""
 					target_label: __address__
+				- source_labels: [__param_target]
+					target_label: __tmp_hash
+					action: hashmod
+					modulus: 1
+				- source_labels: [__tmp_hash]
+					action: keep
+					regex: $(SHARD)
+			`),
+		},
+		{
+			name: "static targets",
+			input: map[string]interface{}{
+				"agentNamespace": "operator",
+				"probe": prom_v1.Probe{
+					ObjectMeta: meta_v1.ObjectMeta{
+						Namespace: "operator",
+						Name:      "probe",
+					},
+					Spec: prom_v1.ProbeSpec{
+						Module:     "mod",
+						ProberSpec: prom_v1.ProberSpec{URL: "blackbox:9115"},
+						Targets: prom_v1.ProbeTargets{
+							StaticConfig: &prom_v1.ProbeTargetStaticConfig{
+								Targets: []string{"https://grafana.com"},
+								Labels:  map[string]string{"namespace": "custom", "env": "prod"},
+							},
+						},
+					},
+				},
+				"apiServer":                prom_v1.APIServerConfig{},
+				"overrideHonorTimestamps":  false,
+				"ignoreNamespaceSelectors": false,
+				"enforcedNamespaceLabel":   "",
+				"enforcedSampleLimit":      nil,
+				"enforcedTargetLimit":      nil,
+				"shards":                   2,
+			},
+			expect: util.Untab(`
+				job_name: probe/operator/probe
+				honor_timestamps: true
+				metrics_path: /probe
+				params:
+					module: ["mod"]
+				static_configs:
+				- targets: ["https://grafana.com"]
+					labels:
+						namespace: custom
+						env: prod
+				relabel_configs:
+				- source_labels: [job]
+					target_label: __tmp_prometheus_job_name
+				- source_labels: [__address__]
+					target_label: __param_target
+				- source_labels: [__param_target]
+					target_label: instance
+				- replacement: blackbox:9115
+					target_label: __address__
+				- source_labels: [__param_target]
+					target_label: __tmp_hash
+					action: hashmod
+					modulus: 2
+				- source_labels: [__tmp_hash]
+					action: keep
+					regex: $(SHARD)
 			`),
 		},
 	}
@@ -0,0 +1,57 @@
+// agent-integrations.libsonnet is the entrypoint for rendering a Grafana
+// Agent config file for integrations based on the Operator custom resources.
+//
+// When writing an object, any field will null will be removed from the final
+// YAML. This is useful as we don't want to always translate unfilled values
+// from the custom resources to a field in the YAML.
+//
+// A series of helper methods to convert default values into null (so they can
+// be trimmed) are in ./ext/optionals.libsonnet.
+//
+// When writing a new function, please document the expected types of the
+// arguments.
+
+local marshal = import 'ext/marshal.libsonnet';
+local optionals = import 'ext/optionals.libsonnet';
+
+local new_cadvisor = import 'component/integrations/cadvisor.libsonnet';
+local new_node_exporter = import 'component/integrations/node_exporter.libsonnet';
+local new_external_labels = import 'component/metrics/external_labels.libsonnet';
+local new_remote_write = import 'component/metrics/remote_write.libsonnet';
+
+// @param {config.Deployment} ctx
+function(ctx) marshal.YAML(optionals.trim({
+  local spec = ctx.Agent.Spec,
+  local prometheus = spec.Metrics,
+  local integrations = spec.Integrations,
+
+  server: {
+    http_listen_port: 8080,
+    log_level: optionals.string(spec.LogLevel),
+    log_format: optionals.string(spec.LogFormat),
+  },
+
+  // Integrations don't have remote_write settings of their own and use the
+  // global settings of the metrics subsystem.
+  metrics: {
+    wal_directory: '/var/lib/grafana-agent/data',
+    global: {
+      external_labels: optionals.object(new_external_labels(ctx)),
+      scrape_interval: optionals.string(prometheus.ScrapeInterval),
+      scrape_timeout: optionals.string(prometheus.ScrapeTimeout),
+      remote_write: optionals.array(std.map(
+        function(rw) new_remote_write(ctx.Agent.ObjectMeta.Namespace, rw),
+        prometheus.RemoteWrite,
+      )),
+    },
+  },
+
+  integrations: {
+    node_exporter:
+      if integrations.NodeExporter == null then null
+      else new_node_exporter(integrations.NodeExporter),
+    cadvisor:
+      if integrations.Cadvisor == null then null
+      else new_cadvisor(integrations.Cadvisor),
+  },
+}))
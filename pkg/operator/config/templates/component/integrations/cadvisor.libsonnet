@@ -0,0 +1,12 @@
+local optionals = import 'ext/optionals.libsonnet';
+
+// Generates the cadvisor integration config.
+//
+// @param {CadvisorIntegrationSpec} spec
+function(spec) {
+  enabled: true,
+
+  docker_only: optionals.bool(spec.DockerOnly),
+  enabled_metrics: optionals.array(spec.EnabledMetrics),
+  disabled_metrics: optionals.array(spec.DisabledMetrics),
+}
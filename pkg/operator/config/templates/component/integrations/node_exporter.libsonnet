@@ -0,0 +1,16 @@
+local optionals = import 'ext/optionals.libsonnet';
+
+// Generates the node_exporter integration config. The filesystems of the host
+// are mounted into the integrations pods under /host.
+//
+// @param {NodeExporterIntegrationSpec} spec
+function(spec) {
+  enabled: true,
+
+  rootfs_path: '/host/root',
+  sysfs_path: '/host/sys',
+  procfs_path: '/host/proc',
+
+  enable_collectors: optionals.array(spec.EnableCollectors),
+  disable_collectors: optionals.array(spec.DisableCollectors),
+}
@@ -24,10 +24,23 @@ function(ctx) (
     else metrics.ExternalLabels
   ) +
 
+  // Then add in labels read from the pod. Their values are passed to the
+  // Grafana Agent container as environment variables by the Operator.
+  (
+    if metrics.ExternalLabelsFrom == null then {}
+    else {
+      [source.Name]: '${EXTERNAL_LABEL_%s}' % source.Name
+      for source in metrics.ExternalLabelsFrom
+    }
+  ) +
+
   // Finally, add the replica label. We don't want the user to overrwrite the
-  // replica label since it can cause duplicate sample problems.
+  // replica label since it can cause duplicate sample problems. Pods of a
+  // Deployment have no ordinal, but Deployments only run a single replica.
   (
-    local replicaValue = 'replica-$(STATEFULSET_ORDINAL_NUMBER)';
+    local replicaValue =
+      if metrics.WorkloadType == 'Deployment' then 'replica-0'
+      else 'replica-$(STATEFULSET_ORDINAL_NUMBER)';
     local replicaLabel = metrics.ReplicaExternalLabelName;
 
     if replicaLabel == null then { __replica__: replicaValue }
@@ -4,6 +4,7 @@ local k8s = import 'utils/k8s.libsonnet';
 
 local new_kube_sd_config = import './kube_sd_config.libsonnet';
 local new_relabel_config = import './relabel_config.libsonnet';
+local new_shard_relabel_configs = import './shard_relabel_configs.libsonnet';
 local new_tls_config = import './tls_config.libsonnet';
 
 // Genrates a scrape_config from a Probe.
@@ -63,12 +64,12 @@ function(
   // Generate static_configs section if StaticConfig is provided.
   static_configs: optionals.array(if probe.Spec.Targets.StaticConfig != null then [{
     targets: probe.Spec.Targets.StaticConfig.Targets,
-    labels: (
+    // The namespace label defaults to the namespace of the probe but may be
+    // overridden.
+    labels: { namespace: meta.Namespace } + (
       if probe.Spec.Targets.StaticConfig.Labels != null
-      then probe.Spec.Targets.StaticConfig.Labels {
-        namespace: meta.Namespace,
-      }
-      else { namespace: meta.Namespace }
+      then probe.Spec.Targets.StaticConfig.Labels
+      else {}
     ),
   }]),
 
@@ -211,6 +212,10 @@ function(
         target_label: enforcedNamespaceLabel,
         replacement: probe.ObjectMeta.Namespace,
       },
-    ])
+    ]) +
+
+    // __address__ is the address of the prober for all targets, so probes are
+    // sharded by their target instead.
+    new_shard_relabel_configs('__param_target', shards)
   ),
 }
@@ -0,0 +1,19 @@
+// Generates the relabel_configs which distribute targets across shards. Only
+// targets belonging to the shard of the current pod, given by the $(SHARD)
+// environment variable, are kept.
+//
+// @param {string} sourceLabel - label to hash targets by.
+// @param {number} shards
+function(sourceLabel, shards) [
+  {
+    source_labels: [sourceLabel],
+    target_label: '__tmp_hash',
+    modulus: shards,
+    action: 'hashmod',
+  },
+  {
+    source_labels: ['__tmp_hash'],
+    regex: '$(SHARD)',
+    action: 'keep',
+  },
+]
@@ -7,6 +7,28 @@ local new_pod_monitor = import 'component/metrics/pod_monitor.libsonnet';
 local new_probe = import 'component/metrics/probe.libsonnet';
 local new_remote_write = import 'component/metrics/remote_write.libsonnet';
 local new_service_monitor = import 'component/metrics/service_monitor.libsonnet';
+local new_shard_relabel_configs = import 'component/metrics/shard_relabel_configs.libsonnet';
+
+// Adds shard relabel_configs to an additional scrape config when there's more
+// than one shard. Scrape configs which already shard their targets with a
+// hashmod relabel_config are left alone.
+//
+// @param {object} scrapeConfig
+// @param {number} shards
+local shard_scrape_config(scrapeConfig, shards) =
+  local relabelConfigs = k8s.array(
+    if std.objectHas(scrapeConfig, 'relabel_configs')
+    then scrapeConfig.relabel_configs
+  );
+  local sharded = std.length(std.filter(
+    function(r) std.objectHas(r, 'action') && std.asciiLower(r.action) == 'hashmod',
+    relabelConfigs,
+  )) > 0;
+
+  if shards <= 1 || sharded then scrapeConfig
+  else scrapeConfig {
+    relabel_configs: relabelConfigs + new_shard_relabel_configs('__address__', shards),
+  };
 
 // Generates a metrics_instance.
 //
@@ -122,11 +144,14 @@ function(
 
     // Finally, if the user specified additional scrape configs, we need to
     // extract their value from the secret and then unmarshal them into the
-    // array.
+    // array. Additional scrape configs are sharded like the other jobs.
     k8s.array(
       if spec.AdditionalScrapeConfigs != null then (
         local rawYAML = secrets.valueForSecret(namespace, spec.AdditionalScrapeConfigs);
-        marshal.fromYAML(rawYAML)
+        std.map(
+          function(sc) shard_scrape_config(sc, shards),
+          k8s.array(marshal.fromYAML(rawYAML)),
+        )
       )
     ),
   ),
@@ -135,10 +135,3 @@ func TestNotifier(t *testing.T) {
 		})
 	}
 }
-
-func parseSelector(t *testing.T, selector string) labels.Selector {
-	t.Helper()
-	s, err := labels.Parse(selector)
-	require.NoError(t, err)
-	return s
-}
@@ -35,6 +35,11 @@ type LabelsSelector struct {
 	// Labels discovers all objects whose labels match the selector. If nil,
 	// no objects will be discovered.
 	Labels labels.Selector
+
+	// AllowedNamespaceLabels, when set, restricts discovered objects to
+	// namespaces whose labels match AllowedNamespaceLabels. This applies on
+	// top of NamespaceName and NamespaceLabels.
+	AllowedNamespaceLabels labels.Selector
 }
 
 var _ Selector = (*LabelsSelector)(nil)
@@ -54,8 +59,10 @@ func (ls *LabelsSelector) Matches(ctx context.Context, cli client.Client, o clie
 	}
 
 	// Fast path: we don't need to retrieve the labels of the namespace.
-	if ls.NamespaceLabels == nil {
+	if ls.NamespaceLabels == nil && ls.AllowedNamespaceLabels == nil {
 		return o.GetNamespace() == ls.NamespaceName, nil
+	} else if ls.NamespaceLabels == nil && o.GetNamespace() != ls.NamespaceName {
+		return false, nil
 	}
 
 	// Slow path: we need to look up the namespace to see if its labels match. As
@@ -64,7 +71,12 @@ func (ls *LabelsSelector) Matches(ctx context.Context, cli client.Client, o clie
 	if err := cli.Get(ctx, client.ObjectKey{Name: o.GetNamespace()}, &ns); err != nil {
 		return false, fmt.Errorf("error looking up namespace %q: %w", o.GetNamespace(), err)
 	}
-	return ls.NamespaceLabels.Matches(labels.Set(ns.GetLabels())), nil
+	nsLabels := labels.Set(ns.GetLabels())
+
+	if ls.AllowedNamespaceLabels != nil && !ls.AllowedNamespaceLabels.Matches(nsLabels) {
+		return false, nil
+	}
+	return ls.NamespaceLabels == nil || ls.NamespaceLabels.Matches(nsLabels), nil
 }
 
 // KeySelector is used for discovering a single object based on namespace and
@@ -0,0 +1,94 @@
+package hierarchy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLabelsSelector_AllowedNamespaceLabels(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, v1.AddToScheme(scheme))
+
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"team": "a"}}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"team": "b"}}},
+	).Build()
+
+	podIn := func(namespace string) *v1.Pod {
+		return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:      "pod",
+			Namespace: namespace,
+			Labels:    map[string]string{"fizz": "buzz"},
+		}}
+	}
+
+	tt := []struct {
+		name   string
+		sel    *LabelsSelector
+		pod    *v1.Pod
+		expect bool
+	}{
+		{
+			name: "allowed namespace selector matches",
+			sel: &LabelsSelector{
+				NamespaceLabels:        labels.Everything(),
+				Labels:                 parseSelector(t, "fizz in (buzz)"),
+				AllowedNamespaceLabels: parseSelector(t, "team in (a)"),
+			},
+			pod:    podIn("team-a"),
+			expect: true,
+		},
+		{
+			name: "allowed namespace selector doesn't match",
+			sel: &LabelsSelector{
+				NamespaceLabels:        labels.Everything(),
+				Labels:                 parseSelector(t, "fizz in (buzz)"),
+				AllowedNamespaceLabels: parseSelector(t, "team in (a)"),
+			},
+			pod:    podIn("team-b"),
+			expect: false,
+		},
+		{
+			name: "own namespace isn't allowed",
+			sel: &LabelsSelector{
+				NamespaceName:          "team-b",
+				Labels:                 parseSelector(t, "fizz in (buzz)"),
+				AllowedNamespaceLabels: parseSelector(t, "team in (a)"),
+			},
+			pod:    podIn("team-b"),
+			expect: false,
+		},
+		{
+			name: "own namespace is allowed",
+			sel: &LabelsSelector{
+				NamespaceName:          "team-a",
+				Labels:                 parseSelector(t, "fizz in (buzz)"),
+				AllowedNamespaceLabels: parseSelector(t, "team in (a)"),
+			},
+			pod:    podIn("team-a"),
+			expect: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			matches, err := tc.sel.Matches(context.Background(), cli, tc.pod)
+			require.NoError(t, err)
+			require.Equal(t, tc.expect, matches)
+		})
+	}
+}
+
+func parseSelector(t *testing.T, selector string) labels.Selector {
+	t.Helper()
+	s, err := labels.Parse(selector)
+	require.NoError(t, err)
+	return s
+}
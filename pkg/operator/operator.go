@@ -18,13 +18,18 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
 
 	grafana_v1alpha1 "github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	grafana_v1beta1 "github.com/grafana/agent/pkg/operator/apis/monitoring/v1beta1"
 	"github.com/grafana/agent/pkg/operator/hierarchy"
+	"github.com/grafana/agent/pkg/operator/validation"
 	promop_v1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	promop "github.com/prometheus-operator/prometheus-operator/pkg/operator"
 	apps_v1 "k8s.io/api/apps/v1"
 	core_v1 "k8s.io/api/core/v1"
+	networking_v1 "k8s.io/api/networking/v1"
+	policy_v1 "k8s.io/api/policy/v1"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	// Needed for clients.
@@ -41,6 +46,11 @@ type Config struct {
 	AgentSelector       string
 	KubelsetServiceName string
 
+	// EnableWebhook serves a validating admission webhook for GrafanaAgent,
+	// MetricsInstance, and LogsInstance resources, and a conversion webhook
+	// for all custom resources.
+	EnableWebhook bool
+
 	// RestConfig used to connect to cluster. One will be generated based on the
 	// environment if not set.
 	RestConfig *rest.Config
@@ -79,6 +89,9 @@ func (c *Config) registerFlags(f *flag.FlagSet) error {
 	f.StringVar(&c.Controller.MetricsBindAddress, "metrics-listen-address", ":8080", "Address to expose Operator metrics on")
 	f.StringVar(&c.Controller.HealthProbeBindAddress, "health-listen-address", "", "Address to expose Operator health probes on")
 
+	f.BoolVar(&c.EnableWebhook, "enable-webhook", false, "Serve a validating admission webhook for GrafanaAgent, MetricsInstance, and LogsInstance resources and a conversion webhook for all custom resources on the listen port. A ValidatingWebhookConfiguration must be created to use validation, and the CRDs must use the webhook conversion strategy to use conversion.")
+	f.StringVar(&c.Controller.CertDir, "webhook-cert-dir", "", "Directory holding the tls.crt and tls.key files used by the webhook server. Defaults to <temp-dir>/k8s-webhook-server/serving-certs.")
+
 	f.StringVar(&c.KubelsetServiceName, "kubelet-service", "", "Service and Endpoints objects to write kubelets into. Allows for monitoring Kubelet and cAdvisor metrics using a ServiceMonitor. Must be in format \"namespace/name\". If empty, nothing will be created.")
 
 	// Custom initial values for the endpoint names.
@@ -89,7 +102,10 @@ func (c *Config) registerFlags(f *flag.FlagSet) error {
 	for _, add := range []func(*runtime.Scheme) error{
 		core_v1.AddToScheme,
 		apps_v1.AddToScheme,
+		networking_v1.AddToScheme,
+		policy_v1.AddToScheme,
 		grafana_v1alpha1.AddToScheme,
+		grafana_v1beta1.AddToScheme,
 		promop_v1.AddToScheme,
 	} {
 		if err := add(c.Controller.Scheme); err != nil {
@@ -136,6 +152,20 @@ func New(l log.Logger, c *Config) (*Operator, error) {
 		level.Warn(l).Log("msg", "failed to set up 'running' healthz check", "err", err)
 	}
 
+	if c.EnableWebhook {
+		err := validation.Register(manager.GetWebhookServer(), manager.GetScheme(), manager.GetAPIReader())
+		if err != nil {
+			return nil, fmt.Errorf("failed to register validating webhooks: %w", err)
+		}
+
+		// Convert custom resources between v1alpha1 and v1beta1.
+		convert := &conversion.Webhook{}
+		if err := convert.InjectScheme(manager.GetScheme()); err != nil {
+			return nil, fmt.Errorf("failed to create conversion webhook: %w", err)
+		}
+		manager.GetWebhookServer().Register("/convert", convert)
+	}
+
 	var (
 		agentPredicates []predicate.Predicate
 
@@ -184,7 +214,10 @@ func New(l log.Logger, c *Config) (*Operator, error) {
 	err = controller.NewControllerManagedBy(manager).
 		For(&grafana_v1alpha1.GrafanaAgent{}, builder.WithPredicates(agentPredicates...)).
 		Owns(&apps_v1.StatefulSet{}).
+		Owns(&apps_v1.Deployment{}).
 		Owns(&apps_v1.DaemonSet{}).
+		Owns(&policy_v1.PodDisruptionBudget{}).
+		Owns(&networking_v1.NetworkPolicy{}).
 		Owns(&core_v1.Secret{}).
 		Owns(&core_v1.Service{}).
 		Watches(&source.Kind{Type: &core_v1.Secret{}}, notifierHandler).
@@ -205,6 +238,7 @@ func New(l log.Logger, c *Config) (*Operator, error) {
 		Client:   manager.GetClient(),
 		scheme:   manager.GetScheme(),
 		notifier: notifier,
+		recorder: manager.GetEventRecorderFor("grafana-agent-operator"),
 		config:   c,
 	})
 
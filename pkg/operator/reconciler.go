@@ -13,9 +13,12 @@ import (
 	"github.com/grafana/agent/pkg/operator/hierarchy"
 	"github.com/grafana/agent/pkg/operator/logutil"
 	core_v1 "k8s.io/api/core/v1"
+	networking_v1 "k8s.io/api/networking/v1"
 	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	controller "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -26,6 +29,11 @@ type reconciler struct {
 	config *Config
 
 	notifier *hierarchy.Notifier
+	recorder record.EventRecorder
+
+	// countTargets counts the active targets of metrics pods when autoscaling
+	// is enabled. Defaults to querying the targets API of the pods.
+	countTargets targetCounter
 }
 
 func (r *reconciler) Reconcile(ctx context.Context, req controller.Request) (controller.Result, error) {
@@ -52,6 +60,12 @@ func (r *reconciler) Reconcile(ctx context.Context, req controller.Request) (con
 	deployment, watchers, err := buildHierarchy(ctx, l, r.Client, &agent)
 	if err != nil {
 		level.Error(l).Log("msg", "unable to build hierarchy", "err", err)
+		r.setConditions(ctx, l, &agent, &agent.Status.Conditions, []v1.Condition{{
+			Type:    grafana_v1alpha1.ConditionConfigGenerated,
+			Status:  v1.ConditionFalse,
+			Reason:  reasonBuildHierarchyFailed,
+			Message: err.Error(),
+		}}, nil)
 		return controller.Result{}, nil
 	}
 	if err := r.notifier.Notify(watchers...); err != nil {
@@ -59,29 +73,80 @@ func (r *reconciler) Reconcile(ctx context.Context, req controller.Request) (con
 		return controller.Result{}, nil
 	}
 
+	var result controller.Result
+	if deployment.Agent.Spec.Metrics.Autoscaling != nil {
+		shards := r.autoscaleMetricsShards(ctx, l, deployment)
+		deployment.Agent.Spec.Metrics.Shards = &shards
+
+		// Changes to the number of targets don't trigger a reconcile, so check
+		// them periodically.
+		result.RequeueAfter = autoscalingInterval
+	}
+
 	type reconcileFunc func(context.Context, log.Logger, config.Deployment, assets.SecretStore) error
-	actors := []reconcileFunc{
-		// Operator-wide resources
-		r.createSecrets,
-
-		// Metrics resources (may be a no-op if no metrics configured)
-		r.createMetricsConfigurationSecret,
-		r.createMetricsGoverningService,
-		r.createMetricsStatefulSets,
-
-		// Logs resources (may be a no-op if no logs configured)
-		r.createLogsConfigurationSecret,
-		r.createLogsDaemonSet,
+	var (
+		configActors = []reconcileFunc{
+			// Operator-wide resources
+			r.createSecrets,
+
+			// Configs for each subsystem (may be a no-op if a subsystem isn't
+			// configured)
+			r.createMetricsConfigurationSecret,
+			r.createLogsConfigurationSecret,
+			r.createIntegrationsConfigurationSecret,
+		}
+		workloadActors = []reconcileFunc{
+			// Operator-wide resources
+			r.createNetworkPolicy,
+
+			// Metrics resources (may be a no-op if no metrics configured)
+			r.createMetricsGoverningService,
+			r.createMetricsStatefulSets,
+			r.createMetricsDeployments,
+			r.createMetricsPodDisruptionBudgets,
+
+			// Logs resources (may be a no-op if no logs configured)
+			r.createLogsDaemonSet,
+
+			// Integrations resources (may be a no-op if no integrations enabled)
+			r.createIntegrationsDaemonSet,
+		}
+	)
+
+	var status deploymentStatus
+	defer func() { r.updateStatus(ctx, l, deployment, status) }()
+
+	for _, actor := range configActors {
+		err := actor(ctx, l, deployment, deployment.Secrets)
+		if err != nil {
+			level.Error(l).Log("msg", "error during reconciling", "err", err)
+			status.configErr = err
+			return controller.Result{Requeue: true}, nil
+		}
 	}
-	for _, actor := range actors {
+	for _, actor := range workloadActors {
 		err := actor(ctx, l, deployment, deployment.Secrets)
 		if err != nil {
 			level.Error(l).Log("msg", "error during reconciling", "err", err)
+			status.workloadErr = err
 			return controller.Result{Requeue: true}, nil
 		}
 	}
 
-	return controller.Result{}, nil
+	if status.metricsNotReady, err = r.metricsWorkloadsNotReady(ctx, deployment); err != nil {
+		level.Error(l).Log("msg", "unable to check status of metrics workloads", "err", err)
+		status.workloadErr = err
+	}
+	if status.logsNotReady, err = r.logsWorkloadsNotReady(ctx, deployment); err != nil {
+		level.Error(l).Log("msg", "unable to check status of logs workloads", "err", err)
+		status.workloadErr = err
+	}
+	if status.integrationsNotReady, err = r.integrationsWorkloadsNotReady(ctx, deployment); err != nil {
+		level.Error(l).Log("msg", "unable to check status of integrations workloads", "err", err)
+		status.workloadErr = err
+	}
+
+	return result, nil
 }
 
 // createSecrets creates secrets from the secret store.
@@ -124,3 +189,28 @@ func (r *reconciler) createSecrets(
 	}
 	return nil
 }
+
+// createNetworkPolicy creates a NetworkPolicy for the Grafana Agent pods when
+// the GrafanaAgent requests one.
+func (r *reconciler) createNetworkPolicy(
+	ctx context.Context,
+	l log.Logger,
+	d config.Deployment,
+	s assets.SecretStore,
+) error {
+	np := generateNetworkPolicy(r.config, d)
+
+	// Delete the old NetworkPolicy if one exists and none was requested.
+	if d.Agent.Spec.NetworkPolicy == nil {
+		var policy networking_v1.NetworkPolicy
+		key := types.NamespacedName{Namespace: np.Namespace, Name: np.Name}
+		return deleteManagedResource(ctx, r.Client, key, &policy)
+	}
+
+	level.Info(l).Log("msg", "reconciling network policy", "networkpolicy", np.Name)
+	err := clientutil.CreateOrUpdateNetworkPolicy(ctx, r.Client, np)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile network policy: %w", err)
+	}
+	return nil
+}
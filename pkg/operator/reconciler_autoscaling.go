@@ -0,0 +1,226 @@
+package operator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	grafana_v1alpha1 "github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	"github.com/grafana/agent/pkg/operator/config"
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// autoscalingInterval is how often a GrafanaAgent with autoscaling enabled is
+// reconciled to check the number of active targets.
+const autoscalingInterval = time.Minute
+
+// scaleDownHeadroom is the fraction of the capacity of a smaller number of
+// shards which the active targets may use before scaling down. This prevents
+// the shards from flapping when the number of targets hovers around a
+// multiple of TargetsPerShard.
+const scaleDownHeadroom = 0.9
+
+// targetCounter returns the number of active targets of a metrics pod.
+type targetCounter func(ctx context.Context, pod *core_v1.Pod) (int, error)
+
+// autoscaleMetricsShards returns the number of metrics shards to use for d.
+// The number of active targets is counted from the metrics pods; the current
+// number of shards is kept when they couldn't all be counted.
+func (r *reconciler) autoscaleMetricsShards(ctx context.Context, l log.Logger, d config.Deployment) int32 {
+	var (
+		spec      = d.Agent.Spec.Metrics.Autoscaling
+		minShards = int32(1)
+		maxShards = spec.MaxShards
+	)
+	if spec.MinShards != nil && *spec.MinShards > 1 {
+		minShards = *spec.MinShards
+	}
+	if maxShards < minShards {
+		maxShards = minShards
+	}
+	clamp := func(n int32) int32 {
+		switch {
+		case n < minShards:
+			return minShards
+		case n > maxShards:
+			return maxShards
+		}
+		return n
+	}
+
+	current, err := r.currentMetricsShards(ctx, d)
+	if err != nil {
+		level.Warn(l).Log("msg", "unable to get current number of metrics shards", "err", err)
+	}
+	if current == 0 || len(d.Metrics) == 0 {
+		return clamp(current)
+	}
+
+	targets, err := r.countActiveTargets(ctx, d, current)
+	if err != nil {
+		level.Warn(l).Log("msg", "unable to count active targets, keeping current number of metrics shards", "err", err)
+		return clamp(current)
+	}
+
+	desired := desiredShards(targets, current, spec.TargetsPerShard)
+	desired = clamp(desired)
+	if desired != current {
+		level.Info(l).Log("msg", "scaling metrics shards", "from", current, "to", desired, "active_targets", targets)
+		if r.recorder != nil {
+			r.recorder.Eventf(d.Agent, core_v1.EventTypeNormal, "ScaledShards", "scaled metrics shards from %d to %d for %d active targets", current, desired, targets)
+		}
+	}
+	return desired
+}
+
+// desiredShards returns the number of shards needed for targets active
+// targets. Scaling down only happens when the targets fit into the smaller
+// number of shards with headroom to spare.
+func desiredShards(targets int, current, targetsPerShard int32) int32 {
+	if targetsPerShard < 1 {
+		return current
+	}
+	desired := int32((targets + int(targetsPerShard) - 1) / int(targetsPerShard))
+	if desired < current && float64(targets) > float64(desired)*float64(targetsPerShard)*scaleDownHeadroom {
+		desired++
+	}
+	return desired
+}
+
+// currentMetricsShards returns the number of metrics StatefulSets or
+// Deployments which currently exist for d.
+func (r *reconciler) currentMetricsShards(ctx context.Context, d config.Deployment) (int32, error) {
+	listOpts := &client.ListOptions{
+		Namespace: d.Agent.Namespace,
+		LabelSelector: labels.SelectorFromSet(labels.Set{
+			managedByOperatorLabel: managedByOperatorLabelValue,
+			agentNameLabelName:     d.Agent.Name,
+		}),
+	}
+
+	if metricsWorkloadType(d) == grafana_v1alpha1.MetricsWorkloadDeployment {
+		var deployments apps_v1.DeploymentList
+		if err := r.List(ctx, &deployments, listOpts); err != nil {
+			return 0, fmt.Errorf("failed to list deployments: %w", err)
+		}
+		return int32(len(deployments.Items)), nil
+	}
+
+	var statefulSets apps_v1.StatefulSetList
+	if err := r.List(ctx, &statefulSets, listOpts); err != nil {
+		return 0, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	return int32(len(statefulSets.Items)), nil
+}
+
+// countActiveTargets returns the total number of active targets across all
+// shards of d. Replicas of a shard scrape the same targets, so the highest
+// count of a shard's ready pods is used. An error is returned if any of the
+// shards has no ready pods.
+func (r *reconciler) countActiveTargets(ctx context.Context, d config.Deployment, shards int32) (int, error) {
+	var pods core_v1.PodList
+	err := r.List(ctx, &pods, &client.ListOptions{
+		Namespace: d.Agent.Namespace,
+		LabelSelector: labels.SelectorFromSet(labels.Set{
+			managedByOperatorLabel: managedByOperatorLabelValue,
+			agentNameLabelName:     d.Agent.Name,
+			agentTypeLabel:         "metrics",
+		}),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	count := r.countTargets
+	if count == nil {
+		count = httpTargetCounter(http.DefaultClient)
+	}
+
+	perShard := make(map[int32]int, shards)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if !podReady(pod) {
+			continue
+		}
+		shard, err := strconv.ParseInt(pod.Labels[shardLabelName], 10, 32)
+		if err != nil || int32(shard) >= shards {
+			continue
+		}
+
+		n, err := count(ctx, pod)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count targets of pod %s: %w", pod.Name, err)
+		}
+		if prev, ok := perShard[int32(shard)]; !ok || n > prev {
+			perShard[int32(shard)] = n
+		}
+	}
+
+	var total int
+	for shard := int32(0); shard < shards; shard++ {
+		n, ok := perShard[shard]
+		if !ok {
+			return 0, fmt.Errorf("shard %d has no ready pods", shard)
+		}
+		total += n
+	}
+	return total, nil
+}
+
+func podReady(pod *core_v1.Pod) bool {
+	if pod.DeletionTimestamp != nil || pod.Status.PodIP == "" {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == core_v1.PodReady {
+			return cond.Status == core_v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// httpTargetCounter returns a targetCounter which counts the targets listed
+// by the targets API of a Grafana Agent pod.
+func httpTargetCounter(cli *http.Client) targetCounter {
+	return func(ctx context.Context, pod *core_v1.Pod) (int, error) {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+
+		// The Grafana Agent container always listens on port 8080.
+		u := fmt.Sprintf("http://%s/agent/api/v1/targets", net.JoinHostPort(pod.Status.PodIP, "8080"))
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return 0, err
+		}
+		resp, err := cli.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return 0, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+		}
+
+		var body struct {
+			Status string            `json:"status"`
+			Data   []json.RawMessage `json:"data"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return 0, fmt.Errorf("failed to decode response: %w", err)
+		}
+		if body.Status != "success" {
+			return 0, fmt.Errorf("unexpected response status %q", body.Status)
+		}
+		return len(body.Data), nil
+	}
+}
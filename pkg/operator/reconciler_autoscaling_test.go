@@ -0,0 +1,122 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-kit/log"
+	grafana_v1alpha1 "github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	"github.com/grafana/agent/pkg/operator/config"
+	"github.com/stretchr/testify/require"
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_desiredShards(t *testing.T) {
+	tt := []struct {
+		name            string
+		targets         int
+		current         int32
+		targetsPerShard int32
+		expect          int32
+	}{
+		{name: "no targets", targets: 0, current: 1, targetsPerShard: 100, expect: 0},
+		{name: "fits", targets: 150, current: 2, targetsPerShard: 100, expect: 2},
+		{name: "scale up", targets: 201, current: 2, targetsPerShard: 100, expect: 3},
+		{name: "scale down", targets: 150, current: 3, targetsPerShard: 100, expect: 2},
+		{name: "scale down without headroom", targets: 195, current: 3, targetsPerShard: 100, expect: 3},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expect, desiredShards(tc.targets, tc.current, tc.targetsPerShard))
+		})
+	}
+}
+
+func TestAutoscaleMetricsShards(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, core_v1.AddToScheme(scheme))
+	require.NoError(t, apps_v1.AddToScheme(scheme))
+	require.NoError(t, grafana_v1alpha1.AddToScheme(scheme))
+
+	agent := &grafana_v1alpha1.GrafanaAgent{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "agent"},
+		Spec: grafana_v1alpha1.GrafanaAgentSpec{
+			Metrics: grafana_v1alpha1.MetricsSubsystemSpec{
+				Autoscaling: &grafana_v1alpha1.MetricsAutoscalingSpec{
+					MinShards:       pointer.Int32(1),
+					MaxShards:       4,
+					TargetsPerShard: 100,
+				},
+			},
+		},
+	}
+
+	var objects []client.Object
+	for shard := 0; shard < 2; shard++ {
+		name := "agent"
+		if shard > 0 {
+			name = fmt.Sprintf("agent-shard-%d", shard)
+		}
+		objects = append(objects,
+			&apps_v1.StatefulSet{ObjectMeta: meta_v1.ObjectMeta{
+				Namespace: "default",
+				Name:      name,
+				Labels: map[string]string{
+					managedByOperatorLabel: managedByOperatorLabelValue,
+					agentNameLabelName:     "agent",
+					agentTypeLabel:         "metrics",
+				},
+			}},
+			&core_v1.Pod{
+				ObjectMeta: meta_v1.ObjectMeta{
+					Namespace: "default",
+					Name:      name + "-0",
+					Labels: map[string]string{
+						managedByOperatorLabel: managedByOperatorLabelValue,
+						agentNameLabelName:     "agent",
+						agentTypeLabel:         "metrics",
+						shardLabelName:         fmt.Sprintf("%d", shard),
+					},
+				},
+				Status: core_v1.PodStatus{
+					PodIP:      fmt.Sprintf("10.0.0.%d", shard),
+					Conditions: []core_v1.PodCondition{{Type: core_v1.PodReady, Status: core_v1.ConditionTrue}},
+				},
+			},
+		)
+	}
+
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(objects...).Build()
+	d := config.Deployment{
+		Agent:   agent,
+		Metrics: []config.MetricsInstance{{Instance: &grafana_v1alpha1.MetricsInstance{}}},
+	}
+
+	t.Run("scales on active targets", func(t *testing.T) {
+		r := &reconciler{
+			Client: cli,
+			countTargets: func(context.Context, *core_v1.Pod) (int, error) {
+				return 160, nil
+			},
+		}
+		require.Equal(t, int32(4), r.autoscaleMetricsShards(context.Background(), log.NewNopLogger(), d))
+	})
+
+	t.Run("keeps current shards on errors", func(t *testing.T) {
+		r := &reconciler{
+			Client: cli,
+			countTargets: func(context.Context, *core_v1.Pod) (int, error) {
+				return 0, fmt.Errorf("connection refused")
+			},
+		}
+		require.Equal(t, int32(2), r.autoscaleMetricsShards(context.Background(), log.NewNopLogger(), d))
+	})
+}
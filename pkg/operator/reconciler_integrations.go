@@ -0,0 +1,52 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/agent/pkg/operator/assets"
+	"github.com/grafana/agent/pkg/operator/clientutil"
+	"github.com/grafana/agent/pkg/operator/config"
+	apps_v1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// createIntegrationsConfigurationSecret creates the Grafana Agent
+// integrations configuration and stores it into a secret.
+func (r *reconciler) createIntegrationsConfigurationSecret(
+	ctx context.Context,
+	l log.Logger,
+	d config.Deployment,
+	s assets.SecretStore,
+) error {
+	return r.createTelemetryConfigurationSecret(ctx, l, d, s, config.IntegrationsType)
+}
+
+// createIntegrationsDaemonSet creates a DaemonSet for integrations.
+func (r *reconciler) createIntegrationsDaemonSet(
+	ctx context.Context,
+	l log.Logger,
+	d config.Deployment,
+	s assets.SecretStore,
+) error {
+	name := fmt.Sprintf("%s-integrations", d.Agent.Name)
+	ds, err := generateIntegrationsDaemonSet(r.config, name, d)
+	if err != nil {
+		return fmt.Errorf("failed to generate DaemonSet: %w", err)
+	}
+	key := types.NamespacedName{Namespace: ds.Namespace, Name: ds.Name}
+
+	if !integrationsEnabled(d) {
+		var ds apps_v1.DaemonSet
+		return deleteManagedResource(ctx, r.Client, key, &ds)
+	}
+
+	level.Info(l).Log("msg", "reconciling integrations daemonset", "ds", key)
+	err = clientutil.CreateOrUpdateDaemonSet(ctx, r.Client, ds)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile integrations daemonset: %w", err)
+	}
+	return nil
+}
@@ -9,11 +9,13 @@ import (
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/google/go-jsonnet"
+	grafana_v1alpha1 "github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
 	"github.com/grafana/agent/pkg/operator/assets"
 	"github.com/grafana/agent/pkg/operator/clientutil"
 	"github.com/grafana/agent/pkg/operator/config"
 	apps_v1 "k8s.io/api/apps/v1"
 	core_v1 "k8s.io/api/core/v1"
+	policy_v1 "k8s.io/api/policy/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
@@ -50,6 +52,9 @@ func (r *reconciler) createTelemetryConfigurationSecret(
 	case config.LogsType:
 		key.Name = fmt.Sprintf("%s-logs-config", d.Agent.Name)
 		shouldCreate = len(d.Logs) > 0
+	case config.IntegrationsType:
+		key.Name = fmt.Sprintf("%s-integrations-config", d.Agent.Name)
+		shouldCreate = integrationsEnabled(d)
 	default:
 		return fmt.Errorf("unknown telemetry type %s", ty)
 	}
@@ -139,8 +144,9 @@ func (r *reconciler) createMetricsStatefulSets(
 	generated := make(map[string]struct{})
 
 	for shard := int32(0); shard < shards; shard++ {
-		// Don't generate anything if there weren't any instances.
-		if len(d.Metrics) == 0 {
+		// Don't generate anything if there weren't any instances or if the
+		// pods run as Deployments.
+		if len(d.Metrics) == 0 || metricsWorkloadType(d) != grafana_v1alpha1.MetricsWorkloadStatefulSet {
 			continue
 		}
 
@@ -185,3 +191,142 @@ func (r *reconciler) createMetricsStatefulSets(
 
 	return nil
 }
+
+// createMetricsDeployments creates a set of Grafana Agent Deployments, one per
+// shard, when the Deployment workload type is used.
+func (r *reconciler) createMetricsDeployments(
+	ctx context.Context,
+	l log.Logger,
+	d config.Deployment,
+	s assets.SecretStore,
+) error {
+
+	shards := minShards
+	if reqShards := d.Agent.Spec.Metrics.Shards; reqShards != nil && *reqShards > 1 {
+		shards = *reqShards
+	}
+
+	// Keep track of generated deployments so we can delete ones that should no
+	// longer exist.
+	generated := make(map[string]struct{})
+
+	for shard := int32(0); shard < shards; shard++ {
+		// Don't generate anything if there weren't any instances or if the
+		// pods run as StatefulSets.
+		if len(d.Metrics) == 0 || metricsWorkloadType(d) != grafana_v1alpha1.MetricsWorkloadDeployment {
+			continue
+		}
+
+		name := d.Agent.Name
+		if shard > 0 {
+			name = fmt.Sprintf("%s-shard-%d", name, shard)
+		}
+
+		deploy, err := generateMetricsDeployment(r.config, name, d, shard)
+		if err != nil {
+			return fmt.Errorf("failed to generate deployment for shard: %w", err)
+		}
+
+		level.Info(l).Log("msg", "reconciling deployment", "deployment", deploy.Name)
+		err = clientutil.CreateOrUpdateDeployment(ctx, r.Client, deploy)
+		if err != nil {
+			return fmt.Errorf("failed to reconcile deployment for shard: %w", err)
+		}
+		generated[deploy.Name] = struct{}{}
+	}
+
+	// Clean up deployments that should no longer exist.
+	var deployments apps_v1.DeploymentList
+	err := r.List(ctx, &deployments, &client.ListOptions{
+		Namespace: d.Agent.Namespace,
+		LabelSelector: labels.SelectorFromSet(labels.Set{
+			managedByOperatorLabel: managedByOperatorLabelValue,
+			agentNameLabelName:     d.Agent.Name,
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for _, deploy := range deployments.Items {
+		if _, keep := generated[deploy.Name]; keep || !isManagedResource(&deploy) {
+			continue
+		}
+		level.Info(l).Log("msg", "deleting stale deployment", "name", deploy.Name)
+		if err := r.Delete(ctx, &deploy); err != nil {
+			return fmt.Errorf("failed to delete stale deployment %s: %w", deploy.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// createMetricsPodDisruptionBudgets creates a PodDisruptionBudget for each
+// shard of metrics pods when the GrafanaAgent requests them.
+func (r *reconciler) createMetricsPodDisruptionBudgets(
+	ctx context.Context,
+	l log.Logger,
+	d config.Deployment,
+	s assets.SecretStore,
+) error {
+
+	shards := minShards
+	if reqShards := d.Agent.Spec.Metrics.Shards; reqShards != nil && *reqShards > 1 {
+		shards = *reqShards
+	}
+
+	// Keep track of generated PodDisruptionBudgets so we can delete ones that
+	// should no longer exist.
+	generated := make(map[string]struct{})
+
+	for shard := int32(0); shard < shards; shard++ {
+		if len(d.Metrics) == 0 || d.Agent.Spec.Metrics.PodDisruptionBudget == nil {
+			continue
+		}
+
+		name := d.Agent.Name
+		if shard > 0 {
+			name = fmt.Sprintf("%s-shard-%d", name, shard)
+		}
+
+		pdb := generateMetricsPodDisruptionBudget(r.config, name, d, shard)
+		level.Info(l).Log("msg", "reconciling pod disruption budget", "pdb", pdb.Name)
+		err := clientutil.CreateOrUpdatePodDisruptionBudget(ctx, r.Client, pdb)
+		if err != nil {
+			return fmt.Errorf("failed to reconcile pod disruption budget for shard: %w", err)
+		}
+		generated[pdb.Name] = struct{}{}
+	}
+
+	// Clean up PodDisruptionBudgets that should no longer exist.
+	var pdbs policy_v1.PodDisruptionBudgetList
+	err := r.List(ctx, &pdbs, &client.ListOptions{
+		Namespace: d.Agent.Namespace,
+		LabelSelector: labels.SelectorFromSet(labels.Set{
+			managedByOperatorLabel: managedByOperatorLabelValue,
+			agentNameLabelName:     d.Agent.Name,
+		}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list pod disruption budgets: %w", err)
+	}
+	for _, pdb := range pdbs.Items {
+		if _, keep := generated[pdb.Name]; keep || !isManagedResource(&pdb) {
+			continue
+		}
+		level.Info(l).Log("msg", "deleting stale pod disruption budget", "name", pdb.Name)
+		if err := r.Delete(ctx, &pdb); err != nil {
+			return fmt.Errorf("failed to delete stale pod disruption budget %s: %w", pdb.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// metricsWorkloadType returns the type of workload used for the metrics pods
+// of d.
+func metricsWorkloadType(d config.Deployment) grafana_v1alpha1.MetricsWorkloadType {
+	if d.Agent.Spec.Metrics.WorkloadType == "" {
+		return grafana_v1alpha1.MetricsWorkloadStatefulSet
+	}
+	return d.Agent.Spec.Metrics.WorkloadType
+}
@@ -0,0 +1,332 @@
+package operator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	grafana_v1alpha1 "github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	"github.com/grafana/agent/pkg/operator/config"
+	apps_v1 "k8s.io/api/apps/v1"
+	core_v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Reasons used for status conditions and events.
+const (
+	reasonBuildHierarchyFailed = "BuildHierarchyFailed"
+	reasonConfigFailed         = "ConfigFailed"
+	reasonConfigGenerated      = "ConfigGenerated"
+	reasonReconcileFailed      = "ReconcileFailed"
+	reasonWorkloadNotReady     = "WorkloadNotReady"
+	reasonWorkloadReady        = "WorkloadReady"
+	reasonNoEndpoints          = "NoEndpoints"
+	reasonEndpointsConfigured  = "EndpointsConfigured"
+)
+
+// deploymentStatus is the status of a reconciled deployment, used to set
+// the status of the GrafanaAgent and the instances it selected.
+type deploymentStatus struct {
+	// configErr is set when the config couldn't be generated.
+	configErr error
+	// workloadErr is set when the workloads couldn't be reconciled.
+	workloadErr error
+
+	// Messages describing the workloads which aren't ready. Empty when all
+	// workloads are ready.
+	metricsNotReady, logsNotReady, integrationsNotReady []string
+}
+
+// updateStatus updates the status of the GrafanaAgent of d and the instances
+// it selected.
+func (r *reconciler) updateStatus(ctx context.Context, l log.Logger, d config.Deployment, ds deploymentStatus) {
+	agent := d.Agent
+
+	configCond := meta_v1.Condition{
+		Type:    grafana_v1alpha1.ConditionConfigGenerated,
+		Status:  meta_v1.ConditionTrue,
+		Reason:  reasonConfigGenerated,
+		Message: "config generated successfully",
+	}
+	if ds.configErr != nil {
+		configCond.Status = meta_v1.ConditionFalse
+		configCond.Reason = reasonConfigFailed
+		configCond.Message = ds.configErr.Error()
+	}
+
+	workloadCond := func(notReady []string) *meta_v1.Condition {
+		switch {
+		case ds.configErr != nil:
+			// Workloads aren't reconciled when the config can't be generated.
+			return nil
+		case ds.workloadErr != nil:
+			return &meta_v1.Condition{
+				Type:    grafana_v1alpha1.ConditionWorkloadReady,
+				Status:  meta_v1.ConditionFalse,
+				Reason:  reasonReconcileFailed,
+				Message: ds.workloadErr.Error(),
+			}
+		case len(notReady) > 0:
+			return &meta_v1.Condition{
+				Type:    grafana_v1alpha1.ConditionWorkloadReady,
+				Status:  meta_v1.ConditionFalse,
+				Reason:  reasonWorkloadNotReady,
+				Message: strings.Join(notReady, "; "),
+			}
+		default:
+			return &meta_v1.Condition{
+				Type:    grafana_v1alpha1.ConditionWorkloadReady,
+				Status:  meta_v1.ConditionTrue,
+				Reason:  reasonWorkloadReady,
+				Message: "all pods are ready",
+			}
+		}
+	}
+
+	var (
+		agentConds  = []meta_v1.Condition{configCond}
+		noEndpoints []string
+	)
+	var agentNotReady []string
+	agentNotReady = append(agentNotReady, ds.metricsNotReady...)
+	agentNotReady = append(agentNotReady, ds.logsNotReady...)
+	agentNotReady = append(agentNotReady, ds.integrationsNotReady...)
+	if cond := workloadCond(agentNotReady); cond != nil {
+		agentConds = append(agentConds, *cond)
+	}
+
+	for _, mi := range d.Metrics {
+		inst := mi.Instance
+		conds := []meta_v1.Condition{configCond}
+		if cond := workloadCond(ds.metricsNotReady); cond != nil {
+			conds = append(conds, *cond)
+		}
+
+		rwCond := remoteWriteCondition(agent, inst)
+		if rwCond.Status == meta_v1.ConditionFalse {
+			noEndpoints = append(noEndpoints, fmt.Sprintf("%s/%s", inst.Namespace, inst.Name))
+		}
+		conds = append(conds, rwCond)
+
+		r.setConditions(ctx, l, inst, &inst.Status.Conditions, keepMessages(inst.Status.Conditions, conds), nil)
+	}
+
+	for _, li := range d.Logs {
+		inst := li.Instance
+		conds := []meta_v1.Condition{configCond}
+		if cond := workloadCond(ds.logsNotReady); cond != nil {
+			conds = append(conds, *cond)
+		}
+		r.setConditions(ctx, l, inst, &inst.Status.Conditions, keepMessages(inst.Status.Conditions, conds), nil)
+	}
+
+	var removeConds []string
+	switch {
+	case len(d.Metrics) == 0:
+		removeConds = append(removeConds, grafana_v1alpha1.ConditionRemoteWriteHealthy)
+	case len(noEndpoints) > 0:
+		agentConds = append(agentConds, meta_v1.Condition{
+			Type:    grafana_v1alpha1.ConditionRemoteWriteHealthy,
+			Status:  meta_v1.ConditionFalse,
+			Reason:  reasonNoEndpoints,
+			Message: fmt.Sprintf("MetricsInstances without remote_write endpoints: %s", strings.Join(noEndpoints, ", ")),
+		})
+	default:
+		agentConds = append(agentConds, remoteWriteCondition(agent, nil))
+	}
+	r.setConditions(ctx, l, agent, &agent.Status.Conditions, agentConds, removeConds)
+}
+
+// keepMessages returns conds with the messages of the current conditions
+// whose status and reason are unchanged. Instances may be selected by more
+// than one GrafanaAgent, whose messages would otherwise replace each other on
+// every reconcile.
+func keepMessages(current, conds []meta_v1.Condition) []meta_v1.Condition {
+	res := make([]meta_v1.Condition, 0, len(conds))
+	for _, cond := range conds {
+		old := meta.FindStatusCondition(current, cond.Type)
+		if old != nil && old.Status == cond.Status && old.Reason == cond.Reason {
+			cond.Message = old.Message
+		}
+		res = append(res, cond)
+	}
+	return res
+}
+
+// remoteWriteCondition returns the RemoteWriteHealthy condition for inst.
+// When inst is nil, the condition for the GrafanaAgent is returned, assuming
+// all of its instances have remote_write endpoints.
+func remoteWriteCondition(agent *grafana_v1alpha1.GrafanaAgent, inst *grafana_v1alpha1.MetricsInstance) meta_v1.Condition {
+	// Instances without their own remote_write endpoints use the ones from
+	// the GrafanaAgent.
+	endpoints := len(agent.Spec.Metrics.RemoteWrite)
+	if inst != nil && len(inst.Spec.RemoteWrite) > 0 {
+		endpoints = len(inst.Spec.RemoteWrite)
+	}
+
+	if inst != nil && endpoints == 0 {
+		return meta_v1.Condition{
+			Type:    grafana_v1alpha1.ConditionRemoteWriteHealthy,
+			Status:  meta_v1.ConditionFalse,
+			Reason:  reasonNoEndpoints,
+			Message: "no remote_write endpoints are configured; samples are only written to the WAL",
+		}
+	}
+	return meta_v1.Condition{
+		Type:    grafana_v1alpha1.ConditionRemoteWriteHealthy,
+		Status:  meta_v1.ConditionUnknown,
+		Reason:  reasonEndpointsConfigured,
+		Message: "remote_write endpoints are configured; delivery of samples is reported by the prometheus_remote_storage metrics of the Grafana Agent pods",
+	}
+}
+
+// setConditions sets conds and removes the conditions of types remove from
+// the status conditions of obj. The status of obj is only updated if the
+// conditions changed. An event is recorded for every condition whose status
+// changed.
+func (r *reconciler) setConditions(
+	ctx context.Context,
+	l log.Logger,
+	obj client.Object,
+	current *[]meta_v1.Condition,
+	conds []meta_v1.Condition,
+	remove []string,
+) {
+	prev := make([]meta_v1.Condition, len(*current))
+	copy(prev, *current)
+
+	for _, cond := range conds {
+		cond.ObservedGeneration = obj.GetGeneration()
+
+		old := meta.FindStatusCondition(prev, cond.Type)
+		if (old == nil || old.Status != cond.Status) && r.recorder != nil {
+			eventType := core_v1.EventTypeNormal
+			if cond.Status == meta_v1.ConditionFalse {
+				eventType = core_v1.EventTypeWarning
+			}
+			r.recorder.Event(obj, eventType, cond.Reason, fmt.Sprintf("%s is %s: %s", cond.Type, cond.Status, cond.Message))
+		}
+
+		meta.SetStatusCondition(current, cond)
+	}
+	for _, ty := range remove {
+		meta.RemoveStatusCondition(current, ty)
+	}
+
+	if equality.Semantic.DeepEqual(prev, *current) {
+		return
+	}
+	if err := r.Status().Update(ctx, obj); err != nil {
+		level.Error(l).Log("msg", "failed to update status", "object", client.ObjectKeyFromObject(obj), "err", err)
+	}
+}
+
+// metricsWorkloadsNotReady returns messages describing the metrics
+// StatefulSets or Deployments of d which aren't ready.
+func (r *reconciler) metricsWorkloadsNotReady(ctx context.Context, d config.Deployment) ([]string, error) {
+	if len(d.Metrics) == 0 {
+		return nil, nil
+	}
+
+	listOpts := &client.ListOptions{
+		Namespace: d.Agent.Namespace,
+		LabelSelector: labels.SelectorFromSet(labels.Set{
+			managedByOperatorLabel: managedByOperatorLabelValue,
+			agentNameLabelName:     d.Agent.Name,
+		}),
+	}
+
+	if metricsWorkloadType(d) == grafana_v1alpha1.MetricsWorkloadDeployment {
+		var deployments apps_v1.DeploymentList
+		if err := r.List(ctx, &deployments, listOpts); err != nil {
+			return nil, fmt.Errorf("failed to list deployments: %w", err)
+		}
+
+		var notReady []string
+		for _, deploy := range deployments.Items {
+			replicas := int32(1)
+			if deploy.Spec.Replicas != nil {
+				replicas = *deploy.Spec.Replicas
+			}
+			switch {
+			case deploy.Status.ObservedGeneration < deploy.Generation:
+				notReady = append(notReady, fmt.Sprintf("Deployment %s is being updated", deploy.Name))
+			case deploy.Status.UpdatedReplicas < replicas || deploy.Status.ReadyReplicas < replicas:
+				notReady = append(notReady, fmt.Sprintf("Deployment %s has %d/%d updated and %d/%d ready replicas", deploy.Name, deploy.Status.UpdatedReplicas, replicas, deploy.Status.ReadyReplicas, replicas))
+			}
+		}
+		return notReady, nil
+	}
+
+	var statefulSets apps_v1.StatefulSetList
+	if err := r.List(ctx, &statefulSets, listOpts); err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+
+	var notReady []string
+	for _, ss := range statefulSets.Items {
+		replicas := int32(1)
+		if ss.Spec.Replicas != nil {
+			replicas = *ss.Spec.Replicas
+		}
+		switch {
+		case ss.Status.ObservedGeneration < ss.Generation:
+			notReady = append(notReady, fmt.Sprintf("StatefulSet %s is being updated", ss.Name))
+		case ss.Status.UpdatedReplicas < replicas || ss.Status.ReadyReplicas < replicas:
+			notReady = append(notReady, fmt.Sprintf("StatefulSet %s has %d/%d updated and %d/%d ready replicas", ss.Name, ss.Status.UpdatedReplicas, replicas, ss.Status.ReadyReplicas, replicas))
+		}
+	}
+	return notReady, nil
+}
+
+// logsWorkloadsNotReady returns messages describing the logs DaemonSet of d
+// if it isn't ready.
+func (r *reconciler) logsWorkloadsNotReady(ctx context.Context, d config.Deployment) ([]string, error) {
+	if len(d.Logs) == 0 {
+		return nil, nil
+	}
+	key := types.NamespacedName{Namespace: d.Agent.Namespace, Name: fmt.Sprintf("%s-logs", d.Agent.Name)}
+	return r.daemonSetNotReady(ctx, key)
+}
+
+// integrationsWorkloadsNotReady returns messages describing the integrations
+// DaemonSet of d if it isn't ready.
+func (r *reconciler) integrationsWorkloadsNotReady(ctx context.Context, d config.Deployment) ([]string, error) {
+	if !integrationsEnabled(d) {
+		return nil, nil
+	}
+	key := types.NamespacedName{Namespace: d.Agent.Namespace, Name: fmt.Sprintf("%s-integrations", d.Agent.Name)}
+	return r.daemonSetNotReady(ctx, key)
+}
+
+// daemonSetNotReady returns messages describing the DaemonSet identified by
+// key if it isn't ready.
+func (r *reconciler) daemonSetNotReady(ctx context.Context, key types.NamespacedName) ([]string, error) {
+	var ds apps_v1.DaemonSet
+	if err := r.Get(ctx, key, &ds); k8s_errors.IsNotFound(err) {
+		return []string{fmt.Sprintf("DaemonSet %s hasn't been created yet", key.Name)}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get daemonset: %w", err)
+	}
+
+	var (
+		desired = ds.Status.DesiredNumberScheduled
+		updated = ds.Status.UpdatedNumberScheduled
+		ready   = ds.Status.NumberReady
+	)
+	switch {
+	case ds.Status.ObservedGeneration < ds.Generation:
+		return []string{fmt.Sprintf("DaemonSet %s is being updated", ds.Name)}, nil
+	case updated < desired || ready < desired:
+		return []string{fmt.Sprintf("DaemonSet %s has %d/%d updated and %d/%d ready pods", ds.Name, updated, desired, ready, desired)}, nil
+	}
+	return nil, nil
+}
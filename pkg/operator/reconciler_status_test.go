@@ -0,0 +1,143 @@
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-kit/log"
+	grafana_v1alpha1 "github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	"github.com/grafana/agent/pkg/operator/config"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/meta"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestUpdateStatus(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, grafana_v1alpha1.AddToScheme(scheme))
+
+	var (
+		agent = &grafana_v1alpha1.GrafanaAgent{
+			ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "agent"},
+		}
+		withRW = &grafana_v1alpha1.MetricsInstance{
+			ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "with-rw"},
+			Spec: grafana_v1alpha1.MetricsInstanceSpec{
+				RemoteWrite: []grafana_v1alpha1.RemoteWriteSpec{{URL: "http://cortex/api/prom/push"}},
+			},
+		}
+		withoutRW = &grafana_v1alpha1.MetricsInstance{
+			ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "without-rw"},
+		}
+		logs = &grafana_v1alpha1.LogsInstance{
+			ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "logs"},
+		}
+	)
+
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(agent, withRW, withoutRW, logs).Build()
+	recorder := record.NewFakeRecorder(100)
+	r := &reconciler{Client: cli, scheme: scheme, recorder: recorder}
+
+	d := config.Deployment{
+		Agent:   agent,
+		Metrics: []config.MetricsInstance{{Instance: withRW}, {Instance: withoutRW}},
+		Logs:    []config.LogInstance{{Instance: logs}},
+	}
+	r.updateStatus(context.Background(), log.NewNopLogger(), d, deploymentStatus{
+		logsNotReady: []string{"DaemonSet agent-logs has 0/1 updated and 0/1 ready pods"},
+	})
+
+	expect := map[client.Object]map[string]meta_v1.ConditionStatus{
+		agent: {
+			grafana_v1alpha1.ConditionConfigGenerated:    meta_v1.ConditionTrue,
+			grafana_v1alpha1.ConditionWorkloadReady:      meta_v1.ConditionFalse,
+			grafana_v1alpha1.ConditionRemoteWriteHealthy: meta_v1.ConditionFalse,
+		},
+		withRW: {
+			grafana_v1alpha1.ConditionConfigGenerated:    meta_v1.ConditionTrue,
+			grafana_v1alpha1.ConditionWorkloadReady:      meta_v1.ConditionTrue,
+			grafana_v1alpha1.ConditionRemoteWriteHealthy: meta_v1.ConditionUnknown,
+		},
+		withoutRW: {
+			grafana_v1alpha1.ConditionConfigGenerated:    meta_v1.ConditionTrue,
+			grafana_v1alpha1.ConditionWorkloadReady:      meta_v1.ConditionTrue,
+			grafana_v1alpha1.ConditionRemoteWriteHealthy: meta_v1.ConditionFalse,
+		},
+		logs: {
+			grafana_v1alpha1.ConditionConfigGenerated: meta_v1.ConditionTrue,
+			grafana_v1alpha1.ConditionWorkloadReady:   meta_v1.ConditionFalse,
+		},
+	}
+	for obj, conds := range expect {
+		stored := obj.DeepCopyObject().(client.Object)
+		require.NoError(t, cli.Get(context.Background(), client.ObjectKeyFromObject(obj), stored))
+
+		var actual []meta_v1.Condition
+		switch stored := stored.(type) {
+		case *grafana_v1alpha1.GrafanaAgent:
+			actual = stored.Status.Conditions
+		case *grafana_v1alpha1.MetricsInstance:
+			actual = stored.Status.Conditions
+		case *grafana_v1alpha1.LogsInstance:
+			actual = stored.Status.Conditions
+		}
+
+		require.Len(t, actual, len(conds), obj.GetName())
+		for ty, status := range conds {
+			require.True(t, meta.IsStatusConditionPresentAndEqual(actual, ty, status), "%s: %s", obj.GetName(), ty)
+		}
+	}
+
+	// An event is recorded for every new condition.
+	require.Len(t, recorder.Events, 11)
+
+	// Updating with the same status records no new events.
+	r.updateStatus(context.Background(), log.NewNopLogger(), d, deploymentStatus{
+		logsNotReady: []string{"DaemonSet agent-logs has 0/1 updated and 0/1 ready pods"},
+	})
+	require.Len(t, recorder.Events, 11)
+}
+
+func TestUpdateStatus_SharedInstance(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, grafana_v1alpha1.AddToScheme(scheme))
+
+	var (
+		agentA = &grafana_v1alpha1.GrafanaAgent{
+			ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "agent-a"},
+		}
+		agentB = &grafana_v1alpha1.GrafanaAgent{
+			ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "agent-b"},
+		}
+		logs = &grafana_v1alpha1.LogsInstance{
+			ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "logs"},
+		}
+	)
+
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(agentA, agentB, logs).Build()
+	r := &reconciler{Client: cli, scheme: scheme}
+
+	getVersion := func() string {
+		var stored grafana_v1alpha1.LogsInstance
+		require.NoError(t, cli.Get(context.Background(), client.ObjectKeyFromObject(logs), &stored))
+		return stored.ResourceVersion
+	}
+
+	r.updateStatus(context.Background(), log.NewNopLogger(), config.Deployment{
+		Agent: agentA,
+		Logs:  []config.LogInstance{{Instance: logs}},
+	}, deploymentStatus{logsNotReady: []string{"DaemonSet agent-a-logs is being updated"}})
+	version := getVersion()
+
+	// The other GrafanaAgent reports the same conditions with different
+	// messages, which must not update the status of the instance.
+	r.updateStatus(context.Background(), log.NewNopLogger(), config.Deployment{
+		Agent: agentB,
+		Logs:  []config.LogInstance{{Instance: logs}},
+	}, deploymentStatus{logsNotReady: []string{"DaemonSet agent-b-logs is being updated"}})
+	require.Equal(t, version, getVersion())
+}
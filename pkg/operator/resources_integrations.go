@@ -0,0 +1,98 @@
+package operator
+
+import (
+	"github.com/grafana/agent/pkg/operator/config"
+	apps_v1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+)
+
+// integrationsEnabled returns true if any integrations are enabled for d.
+func integrationsEnabled(d config.Deployment) bool {
+	spec := d.Agent.Spec.Integrations
+	return spec.NodeExporter != nil || spec.Cadvisor != nil
+}
+
+func generateIntegrationsDaemonSet(
+	cfg *Config,
+	name string,
+	d config.Deployment,
+) (*apps_v1.DaemonSet, error) {
+	return generateDaemonSet(cfg, name, d, integrationsDaemonSetOptions(d))
+}
+
+// integrationsDaemonSetOptions returns the options of the DaemonSet for
+// integrations, which mounts the paths of the host read by the enabled
+// integrations.
+func integrationsDaemonSetOptions(d config.Deployment) daemonSetOptions {
+	integrations := d.Agent.Spec.Integrations
+
+	opts := daemonSetOptions{
+		agentType:  "integrations",
+		scheduling: integrations.PodSchedulingSpec,
+
+		volumes: []v1.Volume{{
+			// The WAL of integrations. Integrations pods don't share the data
+			// directory of the host with the logs pods, which store positions
+			// there.
+			Name: "data",
+			VolumeSource: v1.VolumeSource{
+				EmptyDir: &v1.EmptyDirVolumeSource{},
+			},
+		}},
+		volumeMounts: []v1.VolumeMount{{
+			Name:      "data",
+			MountPath: "/var/lib/grafana-agent/data",
+		}},
+		env: externalLabelEnvVars(d),
+
+		// Integrations read the filesystems and cgroups of the host, which
+		// requires running as root.
+		securityContext: &v1.SecurityContext{
+			Privileged: pointer.Bool(true),
+			RunAsUser:  pointer.Int64(0),
+		},
+
+		// node_exporter reports the processes and network interfaces of the
+		// host rather than the ones of the pod.
+		hostNetwork: integrations.NodeExporter != nil,
+	}
+
+	// mountHostPath mounts path of the host read-only into the grafana-agent
+	// container. Volumes are shared between integrations which need the same
+	// path of the host.
+	hostVolumes := make(map[string]bool)
+	mountHostPath := func(volumeName, path, mountPath string) {
+		if !hostVolumes[volumeName] {
+			hostVolumes[volumeName] = true
+			opts.volumes = append(opts.volumes, v1.Volume{
+				Name: volumeName,
+				VolumeSource: v1.VolumeSource{
+					HostPath: &v1.HostPathVolumeSource{Path: path},
+				},
+			})
+		}
+		opts.volumeMounts = append(opts.volumeMounts, v1.VolumeMount{
+			Name:      volumeName,
+			ReadOnly:  true,
+			MountPath: mountPath,
+		})
+	}
+
+	if integrations.NodeExporter != nil {
+		// Paths must match the paths in the generated node_exporter config.
+		mountHostPath("rootfs", "/", "/host/root")
+		mountHostPath("sysfs", "/sys", "/host/sys")
+		mountHostPath("procfs", "/proc", "/host/proc")
+	}
+	if integrations.Cadvisor != nil {
+		// cadvisor reads the paths of the host at their usual locations.
+		mountHostPath("rootfs", "/", "/rootfs")
+		mountHostPath("sysfs", "/sys", "/sys")
+		mountHostPath("varrun", "/var/run", "/var/run")
+		mountHostPath("docker", "/var/lib/docker", "/var/lib/docker")
+		mountHostPath("disk", "/dev/disk", "/dev/disk")
+	}
+
+	return opts
+}
@@ -0,0 +1,106 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	"github.com/grafana/agent/pkg/operator/config"
+	"github.com/stretchr/testify/require"
+	core_v1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_generateIntegrationsDaemonSet(t *testing.T) {
+	var (
+		cfg  = &Config{}
+		name = "example-integrations"
+	)
+
+	generate := func(t *testing.T, spec v1alpha1.IntegrationsSubsystemSpec) core_v1.PodSpec {
+		t.Helper()
+		deploy := config.Deployment{
+			Agent: &v1alpha1.GrafanaAgent{
+				ObjectMeta: v1.ObjectMeta{Name: "example", Namespace: "default"},
+				Spec:       v1alpha1.GrafanaAgentSpec{Integrations: spec},
+			},
+		}
+		ds, err := generateIntegrationsDaemonSet(cfg, name, deploy)
+		require.NoError(t, err)
+		require.Equal(t, "integrations", ds.Labels[agentTypeLabel])
+		require.Equal(t, "integrations", ds.Spec.Selector.MatchLabels[agentTypeLabel])
+		return ds.Spec.Template.Spec
+	}
+
+	hostMounts := func(spec core_v1.PodSpec) map[string]string {
+		hostPaths := make(map[string]string)
+		for _, v := range spec.Volumes {
+			if v.HostPath != nil {
+				hostPaths[v.Name] = v.HostPath.Path
+			}
+		}
+		mounts := make(map[string]string)
+		for _, m := range spec.Containers[1].VolumeMounts {
+			if path, ok := hostPaths[m.Name]; ok {
+				require.True(t, m.ReadOnly, m.MountPath)
+				mounts[m.MountPath] = path
+			}
+		}
+		return mounts
+	}
+
+	t.Run("node_exporter", func(t *testing.T) {
+		spec := generate(t, v1alpha1.IntegrationsSubsystemSpec{
+			NodeExporter: &v1alpha1.NodeExporterIntegrationSpec{},
+		})
+		require.Equal(t, map[string]string{
+			"/host/root": "/",
+			"/host/sys":  "/sys",
+			"/host/proc": "/proc",
+		}, hostMounts(spec))
+		require.True(t, spec.HostPID)
+		require.True(t, spec.HostNetwork)
+		require.Equal(t, core_v1.DNSClusterFirstWithHostNet, spec.DNSPolicy)
+		require.True(t, *spec.Containers[1].SecurityContext.Privileged)
+	})
+
+	t.Run("cadvisor", func(t *testing.T) {
+		spec := generate(t, v1alpha1.IntegrationsSubsystemSpec{
+			Cadvisor: &v1alpha1.CadvisorIntegrationSpec{},
+		})
+		require.Equal(t, map[string]string{
+			"/rootfs":         "/",
+			"/sys":            "/sys",
+			"/var/run":        "/var/run",
+			"/var/lib/docker": "/var/lib/docker",
+			"/dev/disk":       "/dev/disk",
+		}, hostMounts(spec))
+		require.False(t, spec.HostPID)
+		require.False(t, spec.HostNetwork)
+		require.True(t, *spec.Containers[1].SecurityContext.Privileged)
+	})
+
+	t.Run("integrations share host volumes", func(t *testing.T) {
+		spec := generate(t, v1alpha1.IntegrationsSubsystemSpec{
+			NodeExporter: &v1alpha1.NodeExporterIntegrationSpec{},
+			Cadvisor:     &v1alpha1.CadvisorIntegrationSpec{},
+		})
+		require.Len(t, hostMounts(spec), 8)
+
+		names := make(map[string]bool)
+		for _, v := range spec.Volumes {
+			require.False(t, names[v.Name], "duplicate volume %s", v.Name)
+			names[v.Name] = true
+		}
+	})
+
+	t.Run("subsystem scheduling overrides agent scheduling", func(t *testing.T) {
+		tolerations := []core_v1.Toleration{{Operator: core_v1.TolerationOpExists}}
+		spec := generate(t, v1alpha1.IntegrationsSubsystemSpec{
+			NodeExporter: &v1alpha1.NodeExporterIntegrationSpec{},
+			PodSchedulingSpec: v1alpha1.PodSchedulingSpec{
+				Tolerations: tolerations,
+			},
+		})
+		require.Equal(t, tolerations, spec.Tolerations)
+	})
+}
@@ -4,8 +4,10 @@ import (
 	"testing"
 
 	"github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	"github.com/grafana/agent/pkg/operator/assets"
 	"github.com/grafana/agent/pkg/operator/config"
 	"github.com/stretchr/testify/require"
+	core_v1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -41,4 +43,95 @@ func Test_generateLogsDaemonSetSpec(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, DefaultAgentBaseImage+":vX.Y.Z", spec.Template.Spec.Containers[1].Image)
 	})
+	t.Run("subsystem scheduling overrides agent scheduling", func(t *testing.T) {
+		agentTolerations := []core_v1.Toleration{{Key: "infra", Operator: core_v1.TolerationOpExists}}
+		gpuTolerations := []core_v1.Toleration{{Key: "gpu", Operator: core_v1.TolerationOpExists}}
+
+		deploy := config.Deployment{
+			Agent: &v1alpha1.GrafanaAgent{
+				ObjectMeta: v1.ObjectMeta{Name: name, Namespace: name},
+				Spec: v1alpha1.GrafanaAgentSpec{
+					NodeSelector:      map[string]string{"pool": "infra"},
+					Tolerations:       agentTolerations,
+					PriorityClassName: "agent",
+					Logs: v1alpha1.LogsSubsystemSpec{
+						PodSchedulingSpec: v1alpha1.PodSchedulingSpec{
+							Tolerations:       gpuTolerations,
+							PriorityClassName: "logs",
+						},
+					},
+					Metrics: v1alpha1.MetricsSubsystemSpec{
+						PodSchedulingSpec: v1alpha1.PodSchedulingSpec{
+							PriorityClassName: "metrics",
+						},
+					},
+				},
+			},
+		}
+
+		spec, err := generateLogsDaemonSetSpec(cfg, name, deploy)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"pool": "infra"}, spec.Template.Spec.NodeSelector)
+		require.Equal(t, gpuTolerations, spec.Template.Spec.Tolerations)
+		require.Equal(t, "logs", spec.Template.Spec.PriorityClassName)
+	})
+	t.Run("secrets hash rolls pods", func(t *testing.T) {
+		generate := func(secrets assets.SecretStore) map[string]string {
+			deploy := config.Deployment{
+				Agent: &v1alpha1.GrafanaAgent{
+					ObjectMeta: v1.ObjectMeta{Name: name, Namespace: name},
+				},
+				Secrets: secrets,
+			}
+			ds, err := generateLogsDaemonSet(cfg, name, deploy)
+			require.NoError(t, err)
+			return ds.Spec.Template.Annotations
+		}
+
+		require.NotContains(t, generate(nil), secretsHashAnnotation)
+
+		var (
+			key      = assets.Key("/secrets/default/creds/password")
+			original = generate(assets.SecretStore{key: "hunter2"})
+			rotated  = generate(assets.SecretStore{key: "hunter3"})
+		)
+		require.NotEmpty(t, original[secretsHashAnnotation])
+		require.NotEqual(t, original[secretsHashAnnotation], rotated[secretsHashAnnotation])
+		require.Equal(t, original, generate(assets.SecretStore{key: "hunter2"}))
+	})
+	t.Run("custom env and volumes", func(t *testing.T) {
+		deploy := config.Deployment{
+			Agent: &v1alpha1.GrafanaAgent{
+				ObjectMeta: v1.ObjectMeta{Name: name, Namespace: name},
+				Spec: v1alpha1.GrafanaAgentSpec{
+					Volumes: []core_v1.Volume{{
+						Name:         "ca-bundle",
+						VolumeSource: core_v1.VolumeSource{ConfigMap: &core_v1.ConfigMapVolumeSource{}},
+					}},
+					VolumeMounts: []core_v1.VolumeMount{{Name: "ca-bundle", MountPath: "/etc/ssl/custom"}},
+					Env:          []core_v1.EnvVar{{Name: "CLUSTER", Value: "prod"}},
+					EnvFrom: []core_v1.EnvFromSource{{
+						SecretRef: &core_v1.SecretEnvSource{LocalObjectReference: core_v1.LocalObjectReference{Name: "creds"}},
+					}},
+				},
+			},
+		}
+
+		spec, err := generateLogsDaemonSetSpec(cfg, name, deploy)
+		require.NoError(t, err)
+
+		agent := spec.Template.Spec.Containers[1]
+		require.Equal(t, "grafana-agent", agent.Name)
+		require.Contains(t, agent.Env, core_v1.EnvVar{Name: "CLUSTER", Value: "prod"})
+		require.Equal(t, deploy.Agent.Spec.EnvFrom, agent.EnvFrom)
+		require.Contains(t, agent.VolumeMounts, core_v1.VolumeMount{Name: "ca-bundle", MountPath: "/etc/ssl/custom"})
+
+		var foundVolume bool
+		for _, v := range spec.Template.Spec.Volumes {
+			if v.Name == "ca-bundle" {
+				foundVolume = true
+			}
+		}
+		require.True(t, foundVolume, "custom volume should be added to the pod")
+	})
 }
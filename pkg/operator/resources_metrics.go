@@ -6,11 +6,13 @@ import (
 	"strings"
 
 	"github.com/grafana/agent/pkg/build"
+	grafana_v1alpha1 "github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
 	"github.com/grafana/agent/pkg/operator/clientutil"
 	"github.com/grafana/agent/pkg/operator/config"
 	prom_operator "github.com/prometheus-operator/prometheus-operator/pkg/operator"
 	apps_v1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
+	policy_v1 "k8s.io/api/policy/v1"
 	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
 	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -30,10 +32,11 @@ var (
 	managedByOperatorLabels           = map[string]string{
 		managedByOperatorLabel: managedByOperatorLabelValue,
 	}
-	shardLabelName            = "operator.agent.grafana.com/shard"
-	agentNameLabelName        = "operator.agent.grafana.com/name"
-	agentTypeLabel            = "operator.agent.grafana.com/type"
-	probeTimeoutSeconds int32 = 3
+	shardLabelName              = "operator.agent.grafana.com/shard"
+	agentNameLabelName          = "operator.agent.grafana.com/name"
+	agentTypeLabel              = "operator.agent.grafana.com/type"
+	secretsHashAnnotation       = "operator.agent.grafana.com/secrets-hash"
+	probeTimeoutSeconds   int32 = 3
 )
 
 // deleteManagedResource deletes a managed resource. Ignores resources that are
@@ -59,6 +62,68 @@ func isManagedResource(obj client.Object) bool {
 	return labelValue == managedByOperatorLabelValue
 }
 
+// podScheduling returns the scheduling settings for the pods of a subsystem.
+// Fields set in override take precedence over the settings of the agent.
+func podScheduling(agent *grafana_v1alpha1.GrafanaAgent, override grafana_v1alpha1.PodSchedulingSpec) grafana_v1alpha1.PodSchedulingSpec {
+	res := grafana_v1alpha1.PodSchedulingSpec{
+		NodeSelector:              agent.Spec.NodeSelector,
+		Affinity:                  agent.Spec.Affinity,
+		Tolerations:               agent.Spec.Tolerations,
+		TopologySpreadConstraints: agent.Spec.TopologySpreadConstraints,
+		PriorityClassName:         agent.Spec.PriorityClassName,
+	}
+	if override.NodeSelector != nil {
+		res.NodeSelector = override.NodeSelector
+	}
+	if override.Affinity != nil {
+		res.Affinity = override.Affinity
+	}
+	if override.Tolerations != nil {
+		res.Tolerations = override.Tolerations
+	}
+	if override.TopologySpreadConstraints != nil {
+		res.TopologySpreadConstraints = override.TopologySpreadConstraints
+	}
+	if override.PriorityClassName != "" {
+		res.PriorityClassName = override.PriorityClassName
+	}
+	return res
+}
+
+// externalLabelEnvVars returns environment variables holding the values of
+// the external labels which are read from the pod through the downward API.
+// The generated config references them as ${EXTERNAL_LABEL_<name>}.
+func externalLabelEnvVars(d config.Deployment) []v1.EnvVar {
+	var envVars []v1.EnvVar
+	for _, source := range d.Agent.Spec.Metrics.ExternalLabelsFrom {
+		fieldRef := source.FieldRef
+		envVars = append(envVars, v1.EnvVar{
+			Name:      "EXTERNAL_LABEL_" + source.Name,
+			ValueFrom: &v1.EnvVarSource{FieldRef: &fieldRef},
+		})
+	}
+	return envVars
+}
+
+// withUserEnv appends the user-provided env of the GrafanaAgent to the env set
+// by the operator. User variables with the same name as one set by the
+// operator, such as SHARD, are dropped so they can't override it.
+func withUserEnv(envVars []v1.EnvVar, d config.Deployment) []v1.EnvVar {
+	set := make(map[string]bool, len(envVars))
+	for _, env := range envVars {
+		set[env.Name] = true
+	}
+
+	res := make([]v1.EnvVar, 0, len(envVars)+len(d.Agent.Spec.Env))
+	res = append(res, envVars...)
+	for _, env := range d.Agent.Spec.Env {
+		if !set[env.Name] {
+			res = append(res, env)
+		}
+	}
+	return res
+}
+
 func governingServiceName(agentName string) string {
 	return fmt.Sprintf("%s-operated", agentName)
 }
@@ -217,6 +282,91 @@ func generateMetricsStatefulSet(
 	return ss, nil
 }
 
+// generateMetricsDeployment generates a Deployment for a shard of metrics
+// pods. The pods are the same as the ones of the StatefulSet generated by
+// generateMetricsStatefulSet, so the WAL can't be stored in a
+// PersistentVolume, and only a single replica is supported.
+func generateMetricsDeployment(
+	cfg *Config,
+	name string,
+	d config.Deployment,
+	shard int32,
+) (*apps_v1.Deployment, error) {
+	if storage := d.Agent.Spec.Storage; storage != nil && storage.EmptyDir == nil {
+		return nil, fmt.Errorf("the %s workload type does not support storing the WAL in a PersistentVolume", grafana_v1alpha1.MetricsWorkloadDeployment)
+	}
+	// Pods of a Deployment have no ordinal to tell replicas apart in the
+	// replica external label.
+	if replicas := d.Agent.Spec.Metrics.Replicas; replicas != nil && *replicas > 1 {
+		return nil, fmt.Errorf("the %s workload type does not support more than 1 replica", grafana_v1alpha1.MetricsWorkloadDeployment)
+	}
+
+	ss, err := generateMetricsStatefulSet(cfg, name, d, shard)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apps_v1.Deployment{
+		ObjectMeta: ss.ObjectMeta,
+		Spec: apps_v1.DeploymentSpec{
+			Replicas: ss.Spec.Replicas,
+			Selector: ss.Spec.Selector,
+			Template: ss.Spec.Template,
+			Strategy: apps_v1.DeploymentStrategy{
+				Type: apps_v1.RollingUpdateDeploymentStrategyType,
+			},
+		},
+	}, nil
+}
+
+// generateMetricsPodDisruptionBudget generates a PodDisruptionBudget for a
+// shard of metrics pods.
+func generateMetricsPodDisruptionBudget(
+	cfg *Config,
+	name string,
+	d config.Deployment,
+	shard int32,
+) *policy_v1.PodDisruptionBudget {
+	spec := policy_v1.PodDisruptionBudgetSpec{
+		Selector: &meta_v1.LabelSelector{
+			MatchLabels: map[string]string{
+				agentNameLabelName: d.Agent.Name,
+				agentTypeLabel:     "metrics",
+				shardLabelName:     fmt.Sprintf("%d", shard),
+			},
+		},
+	}
+	if pdb := d.Agent.Spec.Metrics.PodDisruptionBudget; pdb != nil {
+		spec.MinAvailable = pdb.MinAvailable
+		spec.MaxUnavailable = pdb.MaxUnavailable
+	}
+	if spec.MinAvailable == nil && spec.MaxUnavailable == nil {
+		maxUnavailable := intstr.FromInt(1)
+		spec.MaxUnavailable = &maxUnavailable
+	}
+
+	return &policy_v1.PodDisruptionBudget{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      name,
+			Namespace: d.Agent.Namespace,
+			OwnerReferences: []meta_v1.OwnerReference{{
+				APIVersion:         d.Agent.APIVersion,
+				Kind:               d.Agent.Kind,
+				Name:               d.Agent.Name,
+				BlockOwnerDeletion: pointer.Bool(true),
+				Controller:         pointer.Bool(true),
+				UID:                d.Agent.UID,
+			}},
+			Labels: cfg.Labels.Merge(map[string]string{
+				managedByOperatorLabel: managedByOperatorLabelValue,
+				agentNameLabelName:     d.Agent.Name,
+				agentTypeLabel:         "metrics",
+			}),
+		},
+		Spec: spec,
+	}
+}
+
 func generateMetricsStatefulSetSpec(
 	cfg *Config,
 	name string,
@@ -373,6 +523,11 @@ func generateMetricsStatefulSetSpec(
 	}
 
 	podAnnotations["kubectl.kubernetes.io/default-container"] = "grafana-agent"
+	if len(d.Secrets) > 0 {
+		// Roll the pods when referenced Secrets or ConfigMaps change, as values
+		// read from disk (such as TLS certificates) aren't reloaded otherwise.
+		podAnnotations[secretsHashAnnotation] = d.Secrets.Hash()
+	}
 
 	var (
 		finalSelectorLabels = cfg.Labels.Merge(podSelectorLabels)
@@ -395,6 +550,7 @@ func generateMetricsStatefulSetSpec(
 			Value: fmt.Sprintf("%d", shards),
 		},
 	}
+	envVars = append(envVars, externalLabelEnvVars(d)...)
 
 	operatorContainers := []v1.Container{
 		{
@@ -420,7 +576,8 @@ func generateMetricsStatefulSetSpec(
 			Ports:        ports,
 			Args:         agentArgs,
 			VolumeMounts: volumeMounts,
-			Env:          envVars,
+			Env:          withUserEnv(envVars, d),
+			EnvFrom:      d.Agent.Spec.EnvFrom,
 			ReadinessProbe: &v1.Probe{
 				Handler: v1.Handler{
 					HTTPGet: &v1.HTTPGetAction{
@@ -442,6 +599,8 @@ func generateMetricsStatefulSetSpec(
 		return nil, fmt.Errorf("failed to merge containers spec: %w", err)
 	}
 
+	scheduling := podScheduling(d.Agent, d.Agent.Spec.Metrics.PodSchedulingSpec)
+
 	return &apps_v1.StatefulSetSpec{
 		ServiceName:         governingServiceName(d.Agent.Name),
 		Replicas:            d.Agent.Spec.Metrics.Replicas,
@@ -462,13 +621,13 @@ func generateMetricsStatefulSetSpec(
 				InitContainers:                d.Agent.Spec.InitContainers,
 				SecurityContext:               d.Agent.Spec.SecurityContext,
 				ServiceAccountName:            d.Agent.Spec.ServiceAccountName,
-				NodeSelector:                  d.Agent.Spec.NodeSelector,
-				PriorityClassName:             d.Agent.Spec.PriorityClassName,
+				NodeSelector:                  scheduling.NodeSelector,
+				PriorityClassName:             scheduling.PriorityClassName,
 				TerminationGracePeriodSeconds: pointer.Int64(4800),
 				Volumes:                       volumes,
-				Tolerations:                   d.Agent.Spec.Tolerations,
-				Affinity:                      d.Agent.Spec.Affinity,
-				TopologySpreadConstraints:     d.Agent.Spec.TopologySpreadConstraints,
+				Tolerations:                   scheduling.Tolerations,
+				Affinity:                      scheduling.Affinity,
+				TopologySpreadConstraints:     scheduling.TopologySpreadConstraints,
 			},
 		},
 	}, nil
@@ -1,12 +1,17 @@
 package operator
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	"github.com/grafana/agent/pkg/operator/assets"
 	"github.com/grafana/agent/pkg/operator/config"
+	prom_v1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/stretchr/testify/require"
+	core_v1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func Test_generateMetricsStatefulSetSpec(t *testing.T) {
@@ -42,4 +47,234 @@ func Test_generateMetricsStatefulSetSpec(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, DefaultAgentBaseImage+":vX.Y.Z", spec.Template.Spec.Containers[1].Image)
 	})
+	t.Run("subsystem scheduling overrides agent scheduling", func(t *testing.T) {
+		agentTolerations := []core_v1.Toleration{{Key: "infra", Operator: core_v1.TolerationOpExists}}
+		gpuTolerations := []core_v1.Toleration{{Key: "gpu", Operator: core_v1.TolerationOpExists}}
+
+		deploy := config.Deployment{
+			Agent: &v1alpha1.GrafanaAgent{
+				ObjectMeta: v1.ObjectMeta{Name: name, Namespace: name},
+				Spec: v1alpha1.GrafanaAgentSpec{
+					NodeSelector:      map[string]string{"pool": "infra"},
+					Tolerations:       agentTolerations,
+					PriorityClassName: "agent",
+					Metrics: v1alpha1.MetricsSubsystemSpec{
+						PodSchedulingSpec: v1alpha1.PodSchedulingSpec{
+							Tolerations:       gpuTolerations,
+							PriorityClassName: "metrics",
+						},
+					},
+					Logs: v1alpha1.LogsSubsystemSpec{
+						PodSchedulingSpec: v1alpha1.PodSchedulingSpec{
+							PriorityClassName: "logs",
+						},
+					},
+				},
+			},
+		}
+
+		spec, err := generateMetricsStatefulSetSpec(cfg, name, deploy, shard)
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"pool": "infra"}, spec.Template.Spec.NodeSelector)
+		require.Equal(t, gpuTolerations, spec.Template.Spec.Tolerations)
+		require.Equal(t, "metrics", spec.Template.Spec.PriorityClassName)
+	})
+	t.Run("secrets hash rolls pods", func(t *testing.T) {
+		generate := func(secrets assets.SecretStore) map[string]string {
+			deploy := config.Deployment{
+				Agent: &v1alpha1.GrafanaAgent{
+					ObjectMeta: v1.ObjectMeta{Name: name, Namespace: name},
+				},
+				Secrets: secrets,
+			}
+			ss, err := generateMetricsStatefulSet(cfg, name, deploy, shard)
+			require.NoError(t, err)
+			return ss.Spec.Template.Annotations
+		}
+
+		require.NotContains(t, generate(nil), secretsHashAnnotation)
+
+		var (
+			key      = assets.Key("/secrets/default/creds/password")
+			original = generate(assets.SecretStore{key: "hunter2"})
+			rotated  = generate(assets.SecretStore{key: "hunter3"})
+		)
+		require.NotEmpty(t, original[secretsHashAnnotation])
+		require.NotEqual(t, original[secretsHashAnnotation], rotated[secretsHashAnnotation])
+		require.Equal(t, original, generate(assets.SecretStore{key: "hunter2"}))
+	})
+	t.Run("custom env and volumes", func(t *testing.T) {
+		deploy := config.Deployment{
+			Agent: &v1alpha1.GrafanaAgent{
+				ObjectMeta: v1.ObjectMeta{Name: name, Namespace: name},
+				Spec: v1alpha1.GrafanaAgentSpec{
+					Volumes: []core_v1.Volume{{
+						Name:         "ca-bundle",
+						VolumeSource: core_v1.VolumeSource{ConfigMap: &core_v1.ConfigMapVolumeSource{}},
+					}},
+					VolumeMounts: []core_v1.VolumeMount{{Name: "ca-bundle", MountPath: "/etc/ssl/custom"}},
+					Env: []core_v1.EnvVar{
+						{Name: "CLUSTER", Value: "prod"},
+						{Name: "SHARD", Value: "7"},
+					},
+					EnvFrom: []core_v1.EnvFromSource{{
+						SecretRef: &core_v1.SecretEnvSource{LocalObjectReference: core_v1.LocalObjectReference{Name: "creds"}},
+					}},
+				},
+			},
+		}
+
+		spec, err := generateMetricsStatefulSetSpec(cfg, name, deploy, shard)
+		require.NoError(t, err)
+
+		agent := spec.Template.Spec.Containers[1]
+		require.Equal(t, "grafana-agent", agent.Name)
+		require.Contains(t, agent.Env, core_v1.EnvVar{Name: "CLUSTER", Value: "prod"})
+		require.Contains(t, agent.Env, core_v1.EnvVar{Name: "SHARD", Value: fmt.Sprint(shard)})
+		require.NotContains(t, agent.Env, core_v1.EnvVar{Name: "SHARD", Value: "7"}, "operator env must not be overridden")
+		require.Equal(t, deploy.Agent.Spec.EnvFrom, agent.EnvFrom)
+		require.Contains(t, agent.VolumeMounts, core_v1.VolumeMount{Name: "ca-bundle", MountPath: "/etc/ssl/custom"})
+	})
+
+	t.Run("external labels from pod fields", func(t *testing.T) {
+		fieldRef := core_v1.ObjectFieldSelector{FieldPath: "metadata.labels['topology.kubernetes.io/zone']"}
+		deploy := config.Deployment{
+			Agent: &v1alpha1.GrafanaAgent{
+				ObjectMeta: v1.ObjectMeta{Name: name, Namespace: name},
+				Spec: v1alpha1.GrafanaAgentSpec{
+					Metrics: v1alpha1.MetricsSubsystemSpec{
+						ExternalLabelsFrom: []v1alpha1.ExternalLabelSource{{Name: "zone", FieldRef: fieldRef}},
+					},
+				},
+			},
+		}
+
+		spec, err := generateMetricsStatefulSetSpec(cfg, name, deploy, shard)
+		require.NoError(t, err)
+		require.Contains(t, spec.Template.Spec.Containers[1].Env, core_v1.EnvVar{
+			Name:      "EXTERNAL_LABEL_zone",
+			ValueFrom: &core_v1.EnvVarSource{FieldRef: &fieldRef},
+		})
+	})
+}
+
+func Test_generateMetricsDeployment(t *testing.T) {
+	var (
+		cfg   = &Config{}
+		name  = "example"
+		shard = int32(1)
+	)
+
+	t.Run("uses the statefulset pod template", func(t *testing.T) {
+		deploy := config.Deployment{
+			Agent: &v1alpha1.GrafanaAgent{
+				ObjectMeta: v1.ObjectMeta{Name: name, Namespace: name},
+				Spec: v1alpha1.GrafanaAgentSpec{
+					Metrics: v1alpha1.MetricsSubsystemSpec{WorkloadType: v1alpha1.MetricsWorkloadDeployment},
+				},
+			},
+		}
+
+		ss, err := generateMetricsStatefulSet(cfg, name, deploy, shard)
+		require.NoError(t, err)
+		d, err := generateMetricsDeployment(cfg, name, deploy, shard)
+		require.NoError(t, err)
+
+		require.Equal(t, ss.ObjectMeta, d.ObjectMeta)
+		require.Equal(t, ss.Spec.Selector, d.Spec.Selector)
+		require.Equal(t, ss.Spec.Template, d.Spec.Template)
+
+		var walVolume *core_v1.Volume
+		for i, v := range d.Spec.Template.Spec.Volumes {
+			if v.Name == fmt.Sprintf("%s-wal", name) {
+				walVolume = &d.Spec.Template.Spec.Volumes[i]
+			}
+		}
+		require.NotNil(t, walVolume, "WAL volume should exist")
+		require.NotNil(t, walVolume.EmptyDir, "WAL should be stored in an emptyDir")
+	})
+
+	t.Run("rejects persistent storage", func(t *testing.T) {
+		deploy := config.Deployment{
+			Agent: &v1alpha1.GrafanaAgent{
+				ObjectMeta: v1.ObjectMeta{Name: name, Namespace: name},
+				Spec: v1alpha1.GrafanaAgentSpec{
+					Storage: &prom_v1.StorageSpec{
+						VolumeClaimTemplate: prom_v1.EmbeddedPersistentVolumeClaim{},
+					},
+					Metrics: v1alpha1.MetricsSubsystemSpec{WorkloadType: v1alpha1.MetricsWorkloadDeployment},
+				},
+			},
+		}
+
+		_, err := generateMetricsDeployment(cfg, name, deploy, shard)
+		require.Error(t, err)
+	})
+
+	t.Run("rejects multiple replicas", func(t *testing.T) {
+		replicas := int32(2)
+		deploy := config.Deployment{
+			Agent: &v1alpha1.GrafanaAgent{
+				ObjectMeta: v1.ObjectMeta{Name: name, Namespace: name},
+				Spec: v1alpha1.GrafanaAgentSpec{
+					Metrics: v1alpha1.MetricsSubsystemSpec{
+						Replicas:     &replicas,
+						WorkloadType: v1alpha1.MetricsWorkloadDeployment,
+					},
+				},
+			},
+		}
+
+		_, err := generateMetricsDeployment(cfg, name, deploy, shard)
+		require.Error(t, err)
+	})
+}
+
+func Test_generateMetricsPodDisruptionBudget(t *testing.T) {
+	var (
+		cfg   = &Config{}
+		name  = "example-shard-1"
+		shard = int32(1)
+	)
+
+	t.Run("defaults to one unavailable pod", func(t *testing.T) {
+		deploy := config.Deployment{
+			Agent: &v1alpha1.GrafanaAgent{
+				ObjectMeta: v1.ObjectMeta{Name: "example", Namespace: "example"},
+				Spec: v1alpha1.GrafanaAgentSpec{
+					Metrics: v1alpha1.MetricsSubsystemSpec{
+						PodDisruptionBudget: &v1alpha1.PodDisruptionBudgetSpec{},
+					},
+				},
+			},
+		}
+
+		pdb := generateMetricsPodDisruptionBudget(cfg, name, deploy, shard)
+		require.Equal(t, name, pdb.Name)
+		require.Nil(t, pdb.Spec.MinAvailable)
+		require.Equal(t, intstr.FromInt(1), *pdb.Spec.MaxUnavailable)
+		require.Equal(t, map[string]string{
+			agentNameLabelName: "example",
+			agentTypeLabel:     "metrics",
+			shardLabelName:     "1",
+		}, pdb.Spec.Selector.MatchLabels)
+	})
+
+	t.Run("min available", func(t *testing.T) {
+		minAvailable := intstr.FromString("50%")
+		deploy := config.Deployment{
+			Agent: &v1alpha1.GrafanaAgent{
+				ObjectMeta: v1.ObjectMeta{Name: "example", Namespace: "example"},
+				Spec: v1alpha1.GrafanaAgentSpec{
+					Metrics: v1alpha1.MetricsSubsystemSpec{
+						PodDisruptionBudget: &v1alpha1.PodDisruptionBudgetSpec{MinAvailable: &minAvailable},
+					},
+				},
+			},
+		}
+
+		pdb := generateMetricsPodDisruptionBudget(cfg, name, deploy, shard)
+		require.Equal(t, minAvailable, *pdb.Spec.MinAvailable)
+		require.Nil(t, pdb.Spec.MaxUnavailable)
+	})
 }
@@ -0,0 +1,59 @@
+package operator
+
+import (
+	"github.com/grafana/agent/pkg/operator/config"
+	core_v1 "k8s.io/api/core/v1"
+	networking_v1 "k8s.io/api/networking/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+)
+
+// generateNetworkPolicy generates a NetworkPolicy which only allows ingress
+// to the HTTP port of the metrics and logs pods of d from the peers listed in
+// the GrafanaAgent.
+func generateNetworkPolicy(cfg *Config, d config.Deployment) *networking_v1.NetworkPolicy {
+	var (
+		tcp  = core_v1.ProtocolTCP
+		port = intstr.FromInt(8080)
+	)
+
+	// A rule without peers allows ingress from everywhere, so no rule is
+	// added when there aren't any peers to deny all ingress instead.
+	ingress := []networking_v1.NetworkPolicyIngressRule{}
+	if np := d.Agent.Spec.NetworkPolicy; np != nil && len(np.From) > 0 {
+		ingress = append(ingress, networking_v1.NetworkPolicyIngressRule{
+			Ports: []networking_v1.NetworkPolicyPort{{Protocol: &tcp, Port: &port}},
+			From:  np.From,
+		})
+	}
+
+	return &networking_v1.NetworkPolicy{
+		ObjectMeta: meta_v1.ObjectMeta{
+			Name:      d.Agent.Name,
+			Namespace: d.Agent.Namespace,
+			OwnerReferences: []meta_v1.OwnerReference{{
+				APIVersion:         d.Agent.APIVersion,
+				Kind:               d.Agent.Kind,
+				Name:               d.Agent.Name,
+				BlockOwnerDeletion: pointer.Bool(true),
+				Controller:         pointer.Bool(true),
+				UID:                d.Agent.UID,
+			}},
+			Labels: cfg.Labels.Merge(map[string]string{
+				managedByOperatorLabel: managedByOperatorLabelValue,
+				agentNameLabelName:     d.Agent.Name,
+			}),
+		},
+		Spec: networking_v1.NetworkPolicySpec{
+			PodSelector: meta_v1.LabelSelector{
+				MatchLabels: map[string]string{
+					"app.kubernetes.io/name": "grafana-agent",
+					agentNameLabelName:       d.Agent.Name,
+				},
+			},
+			PolicyTypes: []networking_v1.PolicyType{networking_v1.PolicyTypeIngress},
+			Ingress:     ingress,
+		},
+	}
+}
@@ -0,0 +1,52 @@
+package operator
+
+import (
+	"testing"
+
+	"github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	"github.com/grafana/agent/pkg/operator/config"
+	"github.com/stretchr/testify/require"
+	networking_v1 "k8s.io/api/networking/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_generateNetworkPolicy(t *testing.T) {
+	cfg := &Config{}
+
+	t.Run("allows ingress from peers", func(t *testing.T) {
+		peers := []networking_v1.NetworkPolicyPeer{{
+			NamespaceSelector: &v1.LabelSelector{MatchLabels: map[string]string{"name": "monitoring"}},
+		}}
+		deploy := config.Deployment{
+			Agent: &v1alpha1.GrafanaAgent{
+				ObjectMeta: v1.ObjectMeta{Name: "example", Namespace: "example"},
+				Spec: v1alpha1.GrafanaAgentSpec{
+					NetworkPolicy: &v1alpha1.NetworkPolicySpec{From: peers},
+				},
+			},
+		}
+
+		np := generateNetworkPolicy(cfg, deploy)
+		require.Equal(t, "example", np.Name)
+		require.Equal(t, "example", np.Spec.PodSelector.MatchLabels[agentNameLabelName])
+		require.Equal(t, []networking_v1.PolicyType{networking_v1.PolicyTypeIngress}, np.Spec.PolicyTypes)
+		require.Len(t, np.Spec.Ingress, 1)
+		require.Equal(t, peers, np.Spec.Ingress[0].From)
+		require.Equal(t, 8080, np.Spec.Ingress[0].Ports[0].Port.IntValue())
+	})
+
+	t.Run("denies all ingress without peers", func(t *testing.T) {
+		deploy := config.Deployment{
+			Agent: &v1alpha1.GrafanaAgent{
+				ObjectMeta: v1.ObjectMeta{Name: "example", Namespace: "example"},
+				Spec: v1alpha1.GrafanaAgentSpec{
+					NetworkPolicy: &v1alpha1.NetworkPolicySpec{},
+				},
+			},
+		}
+
+		np := generateNetworkPolicy(cfg, deploy)
+		require.NotNil(t, np.Spec.Ingress)
+		require.Empty(t, np.Spec.Ingress)
+	})
+}
@@ -0,0 +1,326 @@
+// Package validation validates the custom resources handled by the Grafana
+// Agent Operator. It's used by the admission webhook to reject invalid
+// resources when they're applied rather than failing during reconcile.
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	grafana "github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	prom_v1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/relabel"
+	"gopkg.in/yaml.v2"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// GrafanaAgent validates a GrafanaAgent.
+func GrafanaAgent(a *grafana.GrafanaAgent) field.ErrorList {
+	var (
+		errs    field.ErrorList
+		spec    = field.NewPath("spec")
+		metrics = spec.Child("metrics")
+		logs    = spec.Child("logs")
+	)
+
+	switch a.Spec.LogLevel {
+	case "", "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, field.NotSupported(spec.Child("logLevel"), a.Spec.LogLevel, []string{"debug", "info", "warn", "error"}))
+	}
+	switch a.Spec.LogFormat {
+	case "", "logfmt", "json":
+	default:
+		errs = append(errs, field.NotSupported(spec.Child("logFormat"), a.Spec.LogFormat, []string{"logfmt", "json"}))
+	}
+
+	m := a.Spec.Metrics
+	errs = append(errs, validateDuration(metrics.Child("scrapeInterval"), m.ScrapeInterval)...)
+	errs = append(errs, validateDuration(metrics.Child("scrapeTimeout"), m.ScrapeTimeout)...)
+	if m.Replicas != nil && *m.Replicas < 0 {
+		errs = append(errs, field.Invalid(metrics.Child("replicas"), *m.Replicas, "must not be negative"))
+	}
+	if m.Shards != nil && *m.Shards < 0 {
+		errs = append(errs, field.Invalid(metrics.Child("shards"), *m.Shards, "must not be negative"))
+	}
+	if as := m.Autoscaling; as != nil {
+		ap := metrics.Child("autoscaling")
+		if as.MinShards != nil && *as.MinShards < 1 {
+			errs = append(errs, field.Invalid(ap.Child("minShards"), *as.MinShards, "must be at least 1"))
+		}
+		if as.MaxShards < 1 {
+			errs = append(errs, field.Invalid(ap.Child("maxShards"), as.MaxShards, "must be at least 1"))
+		} else if as.MinShards != nil && *as.MinShards > as.MaxShards {
+			errs = append(errs, field.Invalid(ap.Child("maxShards"), as.MaxShards, "must not be less than minShards"))
+		}
+		if as.TargetsPerShard < 1 {
+			errs = append(errs, field.Invalid(ap.Child("targetsPerShard"), as.TargetsPerShard, "must be at least 1"))
+		}
+	}
+	if pdb := m.PodDisruptionBudget; pdb != nil && pdb.MinAvailable != nil && pdb.MaxUnavailable != nil {
+		errs = append(errs, field.Invalid(metrics.Child("podDisruptionBudget"), pdb, "only one of minAvailable and maxUnavailable may be set"))
+	}
+	switch m.WorkloadType {
+	case "", grafana.MetricsWorkloadStatefulSet:
+	case grafana.MetricsWorkloadDeployment:
+		if a.Spec.Storage != nil && a.Spec.Storage.EmptyDir == nil {
+			errs = append(errs, field.Invalid(metrics.Child("workloadType"), m.WorkloadType, "the WAL can't be stored in a PersistentVolume when metrics pods run as Deployments"))
+		}
+		if m.Replicas != nil && *m.Replicas > 1 {
+			errs = append(errs, field.Invalid(metrics.Child("replicas"), *m.Replicas, "must not be greater than 1 when metrics pods run as Deployments"))
+		}
+	default:
+		errs = append(errs, field.NotSupported(metrics.Child("workloadType"), m.WorkloadType, []string{string(grafana.MetricsWorkloadStatefulSet), string(grafana.MetricsWorkloadDeployment)}))
+	}
+	errs = append(errs, validateRemoteWrites(metrics.Child("remoteWrite"), m.RemoteWrite)...)
+	errs = append(errs, validateExternalLabelsFrom(metrics.Child("externalLabelsFrom"), m.ExternalLabelsFrom)...)
+	errs = append(errs, validateSelector(metrics.Child("instanceSelector"), m.InstanceSelector)...)
+	errs = append(errs, validateSelector(metrics.Child("instanceNamespaceSelector"), m.InstanceNamespaceSelector)...)
+	errs = append(errs, validateSelector(metrics.Child("allowedNamespaceSelector"), m.AllowedNamespaceSelector)...)
+
+	errs = append(errs, validateEnv(spec.Child("env"), a.Spec.Env)...)
+
+	l := a.Spec.Logs
+	errs = append(errs, validateLogsClients(logs.Child("clients"), l.Clients)...)
+	errs = append(errs, validateSelector(logs.Child("instanceSelector"), l.InstanceSelector)...)
+	errs = append(errs, validateSelector(logs.Child("instanceNamespaceSelector"), l.InstanceNamespaceSelector)...)
+	errs = append(errs, validateSelector(logs.Child("allowedNamespaceSelector"), l.AllowedNamespaceSelector)...)
+
+	i := a.Spec.Integrations
+	if (i.NodeExporter != nil || i.Cadvisor != nil) && len(m.RemoteWrite) == 0 {
+		errs = append(errs, field.Required(metrics.Child("remoteWrite"), "integrations send their metrics to the remote_write endpoints of the metrics subsystem"))
+	}
+
+	return errs
+}
+
+// MetricsInstance validates a MetricsInstance.
+func MetricsInstance(mi *grafana.MetricsInstance) field.ErrorList {
+	var (
+		errs field.ErrorList
+		spec = field.NewPath("spec")
+		s    = mi.Spec
+	)
+
+	errs = append(errs, validateDuration(spec.Child("walTruncateFrequency"), s.WALTruncateFrequency)...)
+	errs = append(errs, validateDuration(spec.Child("minWALTime"), s.MinWALTime)...)
+	errs = append(errs, validateDuration(spec.Child("maxWALTime"), s.MaxWALTime)...)
+	errs = append(errs, validateDuration(spec.Child("remoteFlushDeadline"), s.RemoteFlushDeadline)...)
+	errs = append(errs, validateRemoteWrites(spec.Child("remoteWrite"), s.RemoteWrite)...)
+
+	for _, sel := range []struct {
+		name string
+		sel  *meta_v1.LabelSelector
+	}{
+		{"serviceMonitorSelector", s.ServiceMonitorSelector},
+		{"serviceMonitorNamespaceSelector", s.ServiceMonitorNamespaceSelector},
+		{"podMonitorSelector", s.PodMonitorSelector},
+		{"podMonitorNamespaceSelector", s.PodMonitorNamespaceSelector},
+		{"probeSelector", s.ProbeSelector},
+		{"probeNamespaceSelector", s.ProbeNamespaceSelector},
+	} {
+		errs = append(errs, validateSelector(spec.Child(sel.name), sel.sel)...)
+	}
+
+	if s.AdditionalScrapeConfigs != nil && (s.AdditionalScrapeConfigs.Name == "" || s.AdditionalScrapeConfigs.Key == "") {
+		errs = append(errs, field.Required(spec.Child("additionalScrapeConfigs"), "name and key must be set"))
+	}
+
+	return errs
+}
+
+// LogsInstance validates a LogsInstance.
+func LogsInstance(li *grafana.LogsInstance) field.ErrorList {
+	var (
+		errs field.ErrorList
+		spec = field.NewPath("spec")
+	)
+
+	errs = append(errs, validateLogsClients(spec.Child("clients"), li.Spec.Clients)...)
+	errs = append(errs, validateSelector(spec.Child("podLogsSelector"), li.Spec.PodLogsSelector)...)
+	errs = append(errs, validateSelector(spec.Child("podLogsNamespaceSelector"), li.Spec.PodLogsNamespaceSelector)...)
+	return errs
+}
+
+func validateRemoteWrites(path *field.Path, rws []grafana.RemoteWriteSpec) field.ErrorList {
+	var errs field.ErrorList
+	for i, rw := range rws {
+		p := path.Index(i)
+		errs = append(errs, validateURL(p.Child("url"), rw.URL, true)...)
+		errs = append(errs, validateURL(p.Child("proxyUrl"), rw.ProxyURL, false)...)
+		errs = append(errs, validateDuration(p.Child("remoteTimeout"), rw.RemoteTimeout)...)
+		errs = append(errs, validateRelabelConfigs(p.Child("writeRelabelConfigs"), rw.WriteRelabelConfigs)...)
+
+		if qc := rw.QueueConfig; qc != nil {
+			qp := p.Child("queueConfig")
+			errs = append(errs, validateDuration(qp.Child("batchSendDeadline"), qc.BatchSendDeadline)...)
+			errs = append(errs, validateDuration(qp.Child("minBackoff"), qc.MinBackoff)...)
+			errs = append(errs, validateDuration(qp.Child("maxBackoff"), qc.MaxBackoff)...)
+		}
+		if mc := rw.MetadataConfig; mc != nil {
+			errs = append(errs, validateDuration(p.Child("metadataConfig", "sendInterval"), mc.SendInterval)...)
+		}
+	}
+	return errs
+}
+
+func validateExternalLabelsFrom(path *field.Path, sources []grafana.ExternalLabelSource) field.ErrorList {
+	var (
+		errs field.ErrorList
+		seen = make(map[string]bool)
+	)
+	for i, source := range sources {
+		p := path.Index(i)
+		switch {
+		case !model.LabelName(source.Name).IsValid():
+			errs = append(errs, field.Invalid(p.Child("name"), source.Name, "must be a valid label name"))
+		case seen[source.Name]:
+			errs = append(errs, field.Duplicate(p.Child("name"), source.Name))
+		}
+		seen[source.Name] = true
+
+		if !validDownwardAPIFieldPath(source.FieldRef.FieldPath) {
+			errs = append(errs, field.Invalid(p.Child("fieldRef", "fieldPath"), source.FieldRef.FieldPath, "must be a pod field supported by the downward API in environment variables"))
+		}
+	}
+	return errs
+}
+
+// reservedEnvNames are the environment variables set by the operator in the
+// grafana-agent container.
+var reservedEnvNames = map[string]bool{
+	"POD_NAME": true,
+	"HOSTNAME": true,
+	"SHARD":    true,
+	"SHARDS":   true,
+}
+
+func validateEnv(path *field.Path, env []core_v1.EnvVar) field.ErrorList {
+	var errs field.ErrorList
+	for i, e := range env {
+		if reservedEnvNames[e.Name] || strings.HasPrefix(e.Name, "EXTERNAL_LABEL_") {
+			errs = append(errs, field.Forbidden(path.Index(i).Child("name"), fmt.Sprintf("%s is set by the operator", e.Name)))
+		}
+	}
+	return errs
+}
+
+// validDownwardAPIFieldPath returns true if path is a pod field which can be
+// exposed as an environment variable through the downward API.
+func validDownwardAPIFieldPath(path string) bool {
+	switch path {
+	case "metadata.name", "metadata.namespace",
+		"spec.nodeName", "spec.serviceAccountName",
+		"status.hostIP", "status.podIP":
+		return true
+	}
+	for _, prefix := range []string{"metadata.labels['", "metadata.annotations['"} {
+		if strings.HasPrefix(path, prefix) && strings.HasSuffix(path, "']") && len(path) > len(prefix)+2 {
+			return true
+		}
+	}
+	return false
+}
+
+func validateLogsClients(path *field.Path, clients []grafana.LogsClientSpec) field.ErrorList {
+	var errs field.ErrorList
+	for i, c := range clients {
+		p := path.Index(i)
+		errs = append(errs, validateURL(p.Child("url"), c.URL, true)...)
+		errs = append(errs, validateURL(p.Child("proxyUrl"), c.ProxyURL, false)...)
+		errs = append(errs, validateDuration(p.Child("batchWait"), c.BatchWait)...)
+		errs = append(errs, validateDuration(p.Child("timeout"), c.Timeout)...)
+
+		if bc := c.BackoffConfig; bc != nil {
+			bp := p.Child("backoffConfig")
+			errs = append(errs, validateDuration(bp.Child("minPeriod"), bc.MinPeriod)...)
+			errs = append(errs, validateDuration(bp.Child("maxPeriod"), bc.MaxPeriod)...)
+		}
+	}
+	return errs
+}
+
+// validateDuration validates a Prometheus-style duration. Empty durations are
+// valid, as they're set to their defaults.
+func validateDuration(path *field.Path, d string) field.ErrorList {
+	if d == "" {
+		return nil
+	}
+	if _, err := model.ParseDuration(d); err != nil {
+		return field.ErrorList{field.Invalid(path, d, err.Error())}
+	}
+	return nil
+}
+
+func validateURL(path *field.Path, u string, required bool) field.ErrorList {
+	if u == "" {
+		if required {
+			return field.ErrorList{field.Required(path, "")}
+		}
+		return nil
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return field.ErrorList{field.Invalid(path, u, err.Error())}
+	}
+	if parsed.Scheme == "" || parsed.Host == "" {
+		return field.ErrorList{field.Invalid(path, u, "must be an absolute URL including the scheme and host")}
+	}
+	return nil
+}
+
+func validateSelector(path *field.Path, sel *meta_v1.LabelSelector) field.ErrorList {
+	if sel == nil {
+		return nil
+	}
+	if _, err := meta_v1.LabelSelectorAsSelector(sel); err != nil {
+		return field.ErrorList{field.Invalid(path, sel.String(), err.Error())}
+	}
+	return nil
+}
+
+// validateRelabelConfigs validates relabel configs the same way the Agent
+// does when it loads the generated config.
+func validateRelabelConfigs(path *field.Path, cfgs []prom_v1.RelabelConfig) field.ErrorList {
+	var errs field.ErrorList
+	for i, cfg := range cfgs {
+		if err := validateRelabelConfig(cfg); err != nil {
+			errs = append(errs, field.Invalid(path.Index(i), cfg, err.Error()))
+		}
+	}
+	return errs
+}
+
+func validateRelabelConfig(cfg prom_v1.RelabelConfig) error {
+	// Mirror the generated config, only setting non-empty fields so the
+	// Prometheus defaults apply.
+	m := map[string]interface{}{}
+	if len(cfg.SourceLabels) > 0 {
+		m["source_labels"] = cfg.SourceLabels
+	}
+	for k, v := range map[string]string{
+		"separator":    cfg.Separator,
+		"regex":        cfg.Regex,
+		"target_label": cfg.TargetLabel,
+		"replacement":  cfg.Replacement,
+		"action":       cfg.Action,
+	} {
+		if v != "" {
+			m[k] = v
+		}
+	}
+	if cfg.Modulus != 0 {
+		m["modulus"] = cfg.Modulus
+	}
+
+	bb, err := yaml.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("failed to marshal relabel config: %w", err)
+	}
+	var rc relabel.Config
+	return yaml.UnmarshalStrict(bb, &rc)
+}
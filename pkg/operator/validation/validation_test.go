@@ -0,0 +1,289 @@
+package validation
+
+import (
+	"context"
+	"testing"
+
+	grafana "github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	prom_v1 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
+	"github.com/stretchr/testify/require"
+	core_v1 "k8s.io/api/core/v1"
+	meta_v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGrafanaAgent(t *testing.T) {
+	one := intstr.FromInt(1)
+
+	tt := []struct {
+		name   string
+		spec   grafana.GrafanaAgentSpec
+		expect []string
+	}{
+		{
+			name: "valid",
+			spec: grafana.GrafanaAgentSpec{
+				LogLevel: "debug",
+				Metrics: grafana.MetricsSubsystemSpec{
+					ScrapeInterval: "15s",
+					RemoteWrite:    []grafana.RemoteWriteSpec{{URL: "http://cortex/api/prom/push"}},
+				},
+				Logs: grafana.LogsSubsystemSpec{
+					Clients: []grafana.LogsClientSpec{{URL: "http://loki/loki/api/v1/push", BatchWait: "1s"}},
+				},
+			},
+		},
+		{
+			name: "invalid",
+			spec: grafana.GrafanaAgentSpec{
+				LogLevel: "verbose",
+				Metrics: grafana.MetricsSubsystemSpec{
+					ScrapeInterval: "15 seconds",
+					InstanceSelector: &meta_v1.LabelSelector{
+						MatchExpressions: []meta_v1.LabelSelectorRequirement{{Key: "app", Operator: "Is"}},
+					},
+				},
+				Logs: grafana.LogsSubsystemSpec{
+					Clients: []grafana.LogsClientSpec{{URL: "loki:3100"}},
+				},
+			},
+			expect: []string{
+				"spec.logLevel",
+				"spec.metrics.scrapeInterval",
+				"spec.metrics.instanceSelector",
+				"spec.logs.clients[0].url",
+			},
+		},
+		{
+			name: "deployment with persistent storage",
+			spec: grafana.GrafanaAgentSpec{
+				Storage: &prom_v1.StorageSpec{
+					VolumeClaimTemplate: prom_v1.EmbeddedPersistentVolumeClaim{},
+				},
+				Metrics: grafana.MetricsSubsystemSpec{
+					WorkloadType: grafana.MetricsWorkloadDeployment,
+				},
+			},
+			expect: []string{"spec.metrics.workloadType"},
+		},
+		{
+			name: "deployment with replicas",
+			spec: grafana.GrafanaAgentSpec{
+				Metrics: grafana.MetricsSubsystemSpec{
+					Replicas:     pointer.Int32(2),
+					WorkloadType: grafana.MetricsWorkloadDeployment,
+				},
+			},
+			expect: []string{"spec.metrics.replicas"},
+		},
+		{
+			name: "env set by the operator",
+			spec: grafana.GrafanaAgentSpec{
+				Env: []core_v1.EnvVar{
+					{Name: "CLUSTER", Value: "prod"},
+					{Name: "SHARD", Value: "1"},
+					{Name: "EXTERNAL_LABEL_zone", Value: "a"},
+				},
+			},
+			expect: []string{"spec.env[1].name", "spec.env[2].name"},
+		},
+		{
+			name: "invalid autoscaling",
+			spec: grafana.GrafanaAgentSpec{
+				Metrics: grafana.MetricsSubsystemSpec{
+					Autoscaling: &grafana.MetricsAutoscalingSpec{
+						MinShards: pointer.Int32(3),
+						MaxShards: 2,
+					},
+				},
+			},
+			expect: []string{
+				"spec.metrics.autoscaling.maxShards",
+				"spec.metrics.autoscaling.targetsPerShard",
+			},
+		},
+		{
+			name: "pod disruption budget with min and max",
+			spec: grafana.GrafanaAgentSpec{
+				Metrics: grafana.MetricsSubsystemSpec{
+					PodDisruptionBudget: &grafana.PodDisruptionBudgetSpec{
+						MinAvailable:   &one,
+						MaxUnavailable: &one,
+					},
+				},
+			},
+			expect: []string{"spec.metrics.podDisruptionBudget"},
+		},
+		{
+			name: "external labels from pod fields",
+			spec: grafana.GrafanaAgentSpec{
+				Metrics: grafana.MetricsSubsystemSpec{
+					ExternalLabelsFrom: []grafana.ExternalLabelSource{
+						{Name: "node", FieldRef: core_v1.ObjectFieldSelector{FieldPath: "spec.nodeName"}},
+						{Name: "zone", FieldRef: core_v1.ObjectFieldSelector{FieldPath: "metadata.labels['topology.kubernetes.io/zone']"}},
+					},
+				},
+			},
+		},
+		{
+			name: "invalid external labels from pod fields",
+			spec: grafana.GrafanaAgentSpec{
+				Metrics: grafana.MetricsSubsystemSpec{
+					ExternalLabelsFrom: []grafana.ExternalLabelSource{
+						{Name: "node", FieldRef: core_v1.ObjectFieldSelector{FieldPath: "spec.nodeName"}},
+						{Name: "node", FieldRef: core_v1.ObjectFieldSelector{FieldPath: "metadata.name"}},
+						{Name: "zone-name", FieldRef: core_v1.ObjectFieldSelector{FieldPath: "spec.containers"}},
+					},
+				},
+			},
+			expect: []string{
+				"spec.metrics.externalLabelsFrom[1].name",
+				"spec.metrics.externalLabelsFrom[2].name",
+				"spec.metrics.externalLabelsFrom[2].fieldRef.fieldPath",
+			},
+		},
+		{
+			name: "integrations without remote_write",
+			spec: grafana.GrafanaAgentSpec{
+				Integrations: grafana.IntegrationsSubsystemSpec{
+					NodeExporter: &grafana.NodeExporterIntegrationSpec{},
+				},
+			},
+			expect: []string{"spec.metrics.remoteWrite"},
+		},
+		{
+			name: "deployment with emptyDir storage",
+			spec: grafana.GrafanaAgentSpec{
+				Storage: &prom_v1.StorageSpec{
+					EmptyDir: &core_v1.EmptyDirVolumeSource{},
+				},
+				Metrics: grafana.MetricsSubsystemSpec{
+					WorkloadType: grafana.MetricsWorkloadDeployment,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := GrafanaAgent(&grafana.GrafanaAgent{Spec: tc.spec})
+			require.Equal(t, tc.expect, errorFields(errs))
+		})
+	}
+}
+
+func TestMetricsInstance(t *testing.T) {
+	tt := []struct {
+		name   string
+		spec   grafana.MetricsInstanceSpec
+		expect []string
+	}{
+		{
+			name: "valid",
+			spec: grafana.MetricsInstanceSpec{
+				WALTruncateFrequency: "1m",
+				RemoteWrite: []grafana.RemoteWriteSpec{{
+					URL: "http://cortex/api/prom/push",
+					WriteRelabelConfigs: []prom_v1.RelabelConfig{
+						{SourceLabels: []string{"__name__"}, Regex: "up", Action: "Drop"},
+						{SourceLabels: []string{"instance"}, TargetLabel: "__tmp_hash", Modulus: 2, Action: "hashmod"},
+					},
+				}},
+			},
+		},
+		{
+			name: "invalid",
+			spec: grafana.MetricsInstanceSpec{
+				MaxWALTime: "forever",
+				RemoteWrite: []grafana.RemoteWriteSpec{{
+					RemoteTimeout: "-1s",
+					WriteRelabelConfigs: []prom_v1.RelabelConfig{
+						{Regex: "(", Action: "drop"},
+						{Action: "hashmod", TargetLabel: "__tmp_hash"},
+						{Action: "explode"},
+					},
+					QueueConfig: &grafana.QueueConfig{MaxBackoff: "1"},
+				}},
+			},
+			expect: []string{
+				"spec.maxWALTime",
+				"spec.remoteWrite[0].url",
+				"spec.remoteWrite[0].remoteTimeout",
+				"spec.remoteWrite[0].writeRelabelConfigs[0]",
+				"spec.remoteWrite[0].writeRelabelConfigs[1]",
+				"spec.remoteWrite[0].writeRelabelConfigs[2]",
+				"spec.remoteWrite[0].queueConfig.maxBackoff",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			errs := MetricsInstance(&grafana.MetricsInstance{Spec: tc.spec})
+			require.Equal(t, tc.expect, errorFields(errs))
+		})
+	}
+}
+
+func TestLogsInstance(t *testing.T) {
+	errs := LogsInstance(&grafana.LogsInstance{
+		Spec: grafana.LogsInstanceSpec{
+			Clients: []grafana.LogsClientSpec{{
+				URL:           "http://loki/loki/api/v1/push",
+				Timeout:       "soon",
+				BackoffConfig: &grafana.LogsBackoffConfigSpec{MinPeriod: "500ms", MaxPeriod: "5"},
+			}},
+		},
+	})
+	require.Equal(t, []string{
+		"spec.clients[0].timeout",
+		"spec.clients[0].backoffConfig.maxPeriod",
+	}, errorFields(errs))
+}
+
+func TestReferences(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, core_v1.AddToScheme(scheme))
+
+	cli := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&core_v1.Secret{
+			ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "creds"},
+			Data:       map[string][]byte{"password": []byte("secret")},
+		},
+	).Build()
+
+	mi := &grafana.MetricsInstance{
+		ObjectMeta: meta_v1.ObjectMeta{Namespace: "default", Name: "primary"},
+		Spec: grafana.MetricsInstanceSpec{
+			RemoteWrite: []grafana.RemoteWriteSpec{{
+				URL: "http://cortex/api/prom/push",
+				BasicAuth: &prom_v1.BasicAuth{
+					Username: core_v1.SecretKeySelector{LocalObjectReference: core_v1.LocalObjectReference{Name: "creds"}, Key: "username"},
+					Password: core_v1.SecretKeySelector{LocalObjectReference: core_v1.LocalObjectReference{Name: "creds"}, Key: "password"},
+				},
+			}},
+			AdditionalScrapeConfigs: &core_v1.SecretKeySelector{
+				LocalObjectReference: core_v1.LocalObjectReference{Name: "missing"},
+				Key:                  "jobs.yaml",
+			},
+		},
+	}
+
+	errs, err := References(context.Background(), cli, mi)
+	require.NoError(t, err)
+	require.Len(t, errs, 2)
+	require.Equal(t, `Secret.default/creds: Not found: "username"`, errs[0].Error())
+	require.Equal(t, `Secret: Not found: "default/missing"`, errs[1].Error())
+}
+
+func errorFields(errs field.ErrorList) []string {
+	var res []string
+	for _, err := range errs {
+		res = append(res, err.Field)
+	}
+	return res
+}
@@ -0,0 +1,148 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	grafana "github.com/grafana/agent/pkg/operator/apis/monitoring/v1alpha1"
+	"github.com/grafana/agent/pkg/operator/config"
+	core_v1 "k8s.io/api/core/v1"
+	k8s_errors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Paths the validating webhooks are served on.
+const (
+	GrafanaAgentPath    = "/validate-grafanaagent"
+	MetricsInstancePath = "/validate-metricsinstance"
+	LogsInstancePath    = "/validate-logsinstance"
+)
+
+// Register registers the validating webhooks for GrafanaAgent,
+// MetricsInstance, and LogsInstance resources to srv. cli is used to check
+// that the Secrets and ConfigMaps referenced by resources exist.
+func Register(srv *webhook.Server, scheme *runtime.Scheme, cli client.Reader) error {
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		return fmt.Errorf("failed to create decoder: %w", err)
+	}
+
+	for path, newObject := range map[string]func() client.Object{
+		GrafanaAgentPath:    func() client.Object { return &grafana.GrafanaAgent{} },
+		MetricsInstancePath: func() client.Object { return &grafana.MetricsInstance{} },
+		LogsInstancePath:    func() client.Object { return &grafana.LogsInstance{} },
+	} {
+		srv.Register(path, &webhook.Admission{
+			Handler: &handler{decoder: decoder, client: cli, newObject: newObject},
+		})
+	}
+	return nil
+}
+
+type handler struct {
+	decoder   *admission.Decoder
+	client    client.Reader
+	newObject func() client.Object
+}
+
+// Handle implements admission.Handler.
+func (h *handler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	obj := h.newObject()
+	if err := h.decoder.Decode(req, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	// The namespace isn't always set on objects being created.
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(req.Namespace)
+	}
+
+	var errs field.ErrorList
+	switch obj := obj.(type) {
+	case *grafana.GrafanaAgent:
+		errs = GrafanaAgent(obj)
+	case *grafana.MetricsInstance:
+		errs = MetricsInstance(obj)
+	case *grafana.LogsInstance:
+		errs = LogsInstance(obj)
+	}
+
+	refErrs, err := References(ctx, h.client, obj)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	errs = append(errs, refErrs...)
+
+	if len(errs) > 0 {
+		return admission.Denied(errs.ToAggregate().Error())
+	}
+	return admission.Allowed("")
+}
+
+// References checks that the Secrets and ConfigMaps referenced by obj exist
+// and contain the referenced keys. An error is returned if the references
+// couldn't be checked.
+func References(ctx context.Context, cli client.Reader, obj client.Object) (field.ErrorList, error) {
+	var errs field.ErrorList
+
+	for _, ref := range config.AssetReferences(obj) {
+		var (
+			name, key, kind string
+			keys            map[string]struct{}
+		)
+
+		switch {
+		case ref.Reference.Secret != nil:
+			name, key, kind = ref.Reference.Secret.Name, ref.Reference.Secret.Key, "Secret"
+
+			var secret core_v1.Secret
+			err := cli.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: name}, &secret)
+			if k8s_errors.IsNotFound(err) {
+				errs = append(errs, field.NotFound(field.NewPath(kind), fmt.Sprintf("%s/%s", ref.Namespace, name)))
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to get Secret %s/%s: %w", ref.Namespace, name, err)
+			}
+			keys = make(map[string]struct{}, len(secret.Data)+len(secret.StringData))
+			for k := range secret.Data {
+				keys[k] = struct{}{}
+			}
+			for k := range secret.StringData {
+				keys[k] = struct{}{}
+			}
+
+		case ref.Reference.ConfigMap != nil:
+			name, key, kind = ref.Reference.ConfigMap.Name, ref.Reference.ConfigMap.Key, "ConfigMap"
+
+			var cm core_v1.ConfigMap
+			err := cli.Get(ctx, types.NamespacedName{Namespace: ref.Namespace, Name: name}, &cm)
+			if k8s_errors.IsNotFound(err) {
+				errs = append(errs, field.NotFound(field.NewPath(kind), fmt.Sprintf("%s/%s", ref.Namespace, name)))
+				continue
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to get ConfigMap %s/%s: %w", ref.Namespace, name, err)
+			}
+			keys = make(map[string]struct{}, len(cm.Data)+len(cm.BinaryData))
+			for k := range cm.Data {
+				keys[k] = struct{}{}
+			}
+			for k := range cm.BinaryData {
+				keys[k] = struct{}{}
+			}
+
+		default:
+			continue
+		}
+
+		if _, ok := keys[key]; !ok {
+			errs = append(errs, field.NotFound(field.NewPath(kind, fmt.Sprintf("%s/%s", ref.Namespace, name)), key))
+		}
+	}
+
+	return errs, nil
+}
@@ -0,0 +1,268 @@
+package profiles
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// Config controls the profiles subsystem.
+type Config struct {
+	Configs []InstanceConfig `yaml:"configs,omitempty"`
+}
+
+// Validate ensures that the Config is valid.
+func (c *Config) Validate() error {
+	names := make(map[string]struct{}, len(c.Configs))
+	for idx, ic := range c.Configs {
+		if ic.Name == "" {
+			return fmt.Errorf("profiles config at index %d is missing a name", idx)
+		}
+		if _, exist := names[ic.Name]; exist {
+			return fmt.Errorf("found multiple profiles configs with name %s", ic.Name)
+		}
+		names[ic.Name] = struct{}{}
+
+		if err := ic.Validate(); err != nil {
+			return fmt.Errorf("failed to validate profiles config %s: %w", ic.Name, err)
+		}
+	}
+	return nil
+}
+
+// InstanceConfig configures an individual profiles instance.
+type InstanceConfig struct {
+	Name string `yaml:"name"`
+
+	// ScrapeConfigs discover Go applications and collect profiles from their
+	// pprof endpoints.
+	ScrapeConfigs []*ScrapeConfig `yaml:"scrape_configs,omitempty"`
+
+	// EBPFConfigs collect CPU profiles of the processes running on the host
+	// with eBPF.
+	EBPFConfigs []*EBPFConfig `yaml:"ebpf_configs,omitempty"`
+
+	// RemoteWrite lists the Pyroscope-compatible endpoints profiles are
+	// pushed to.
+	RemoteWrite []RemoteWriteConfig `yaml:"remote_write,omitempty"`
+}
+
+// Validate ensures that the InstanceConfig is valid.
+func (c *InstanceConfig) Validate() error {
+	jobNames := make(map[string]struct{}, len(c.ScrapeConfigs)+len(c.EBPFConfigs))
+	for i, sc := range c.ScrapeConfigs {
+		if sc == nil {
+			return fmt.Errorf("empty scrape config at index %d", i)
+		}
+		if _, exist := jobNames[sc.JobName]; exist {
+			return fmt.Errorf("found multiple scrape configs with job name %s", sc.JobName)
+		}
+		jobNames[sc.JobName] = struct{}{}
+
+		if err := sc.Validate(); err != nil {
+			return fmt.Errorf("invalid scrape config %s: %w", sc.JobName, err)
+		}
+	}
+
+	for i, ec := range c.EBPFConfigs {
+		if ec == nil {
+			return fmt.Errorf("empty ebpf config at index %d", i)
+		}
+		if _, exist := jobNames[ec.JobName]; exist {
+			return fmt.Errorf("found multiple scrape configs with job name %s", ec.JobName)
+		}
+		jobNames[ec.JobName] = struct{}{}
+
+		if err := ec.Validate(); err != nil {
+			return fmt.Errorf("invalid ebpf config %s: %w", ec.JobName, err)
+		}
+	}
+
+	for i, rw := range c.RemoteWrite {
+		if err := rw.Validate(); err != nil {
+			return fmt.Errorf("invalid remote_write at index %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// profileTypes maps the supported profile types to their path relative to
+// the pprof path prefix of a target.
+var profileTypes = map[string]string{
+	"cpu":       "profile",
+	"heap":      "heap",
+	"allocs":    "allocs",
+	"goroutine": "goroutine",
+	"mutex":     "mutex",
+	"block":     "block",
+}
+
+// DefaultScrapeConfig holds default settings for a ScrapeConfig.
+var DefaultScrapeConfig = ScrapeConfig{
+	ScrapeInterval:     model.Duration(15 * time.Second),
+	ScrapeTimeout:      model.Duration(15 * time.Second),
+	CPUProfileDuration: model.Duration(10 * time.Second),
+	Scheme:             "http",
+	PathPrefix:         "/debug/pprof",
+	Profiles:           []string{"cpu", "heap", "goroutine"},
+}
+
+// ScrapeConfig configures a job which periodically collects profiles from the
+// net/http/pprof endpoints of Go applications.
+type ScrapeConfig struct {
+	JobName string `yaml:"job_name"`
+
+	ScrapeInterval model.Duration `yaml:"scrape_interval,omitempty"`
+	ScrapeTimeout  model.Duration `yaml:"scrape_timeout,omitempty"`
+
+	// CPUProfileDuration is how long the CPU profile is collected for on
+	// every scrape. It must be shorter than ScrapeTimeout.
+	CPUProfileDuration model.Duration `yaml:"cpu_profile_duration,omitempty"`
+
+	Scheme     string   `yaml:"scheme,omitempty"`
+	PathPrefix string   `yaml:"path_prefix,omitempty"`
+	Profiles   []string `yaml:"profiles,omitempty"`
+
+	HTTPClientConfig config_util.HTTPClientConfig `yaml:",inline"`
+
+	ServiceDiscoveryConfigs discovery.Configs `yaml:"-"`
+	RelabelConfigs          []*relabel.Config `yaml:"relabel_configs,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *ScrapeConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultScrapeConfig
+	return discovery.UnmarshalYAMLWithInlineConfigs(c, unmarshal)
+}
+
+// MarshalYAML implements yaml.Marshaler.
+func (c *ScrapeConfig) MarshalYAML() (interface{}, error) {
+	return discovery.MarshalYAMLWithInlineConfigs(c)
+}
+
+// Validate ensures that the ScrapeConfig is valid.
+func (c *ScrapeConfig) Validate() error {
+	if c.JobName == "" {
+		return fmt.Errorf("job_name must not be empty")
+	}
+	if c.Scheme != "http" && c.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", c.Scheme)
+	}
+	if c.ScrapeInterval <= 0 {
+		return fmt.Errorf("scrape_interval must be greater than zero")
+	}
+	if c.ScrapeTimeout <= 0 || c.ScrapeTimeout > c.ScrapeInterval {
+		return fmt.Errorf("scrape_timeout must be greater than zero and not greater than scrape_interval")
+	}
+	if len(c.Profiles) == 0 {
+		return fmt.Errorf("at least one profile type must be collected")
+	}
+	for _, p := range c.Profiles {
+		if _, ok := profileTypes[p]; !ok {
+			return fmt.Errorf("unsupported profile type %q, must be one of %s", p, supportedProfileTypes())
+		}
+		if p == "cpu" && (c.CPUProfileDuration < model.Duration(time.Second) || c.CPUProfileDuration >= c.ScrapeTimeout) {
+			return fmt.Errorf("cpu_profile_duration must be at least 1s and shorter than scrape_timeout")
+		}
+	}
+	for _, rc := range c.RelabelConfigs {
+		if rc == nil {
+			return fmt.Errorf("empty or null relabeling rule")
+		}
+	}
+	return c.HTTPClientConfig.Validate()
+}
+
+// DefaultEBPFConfig holds default settings for an EBPFConfig.
+var DefaultEBPFConfig = EBPFConfig{
+	SampleRate:      97,
+	CollectInterval: model.Duration(15 * time.Second),
+}
+
+// EBPFConfig configures a job which samples the stacks of the processes
+// running on the host with eBPF and periodically pushes them as CPU
+// profiles.
+type EBPFConfig struct {
+	JobName string `yaml:"job_name"`
+
+	// SampleRate is how many times per second the stack running on each CPU
+	// is sampled.
+	SampleRate int `yaml:"sample_rate,omitempty"`
+	// CollectInterval is how often the sampled stacks are pushed.
+	CollectInterval model.Duration `yaml:"collect_interval,omitempty"`
+
+	// RelabelConfigs are applied to the labels of each process. Processes
+	// which are dropped aren't profiled.
+	RelabelConfigs []*relabel.Config `yaml:"relabel_configs,omitempty"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *EBPFConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	*c = DefaultEBPFConfig
+	type plain EBPFConfig
+	return unmarshal((*plain)(c))
+}
+
+// Validate ensures that the EBPFConfig is valid.
+func (c *EBPFConfig) Validate() error {
+	if c.JobName == "" {
+		return fmt.Errorf("job_name must not be empty")
+	}
+	if c.SampleRate < 1 || c.SampleRate > 1000 {
+		return fmt.Errorf("sample_rate must be between 1 and 1000")
+	}
+	if c.CollectInterval < model.Duration(time.Second) {
+		return fmt.Errorf("collect_interval must be at least 1s")
+	}
+	for _, rc := range c.RelabelConfigs {
+		if rc == nil {
+			return fmt.Errorf("empty or null relabeling rule")
+		}
+	}
+	return nil
+}
+
+func supportedProfileTypes() string {
+	names := make([]string, 0, len(profileTypes))
+	for name := range profileTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// RemoteWriteConfig configures an endpoint profiles are pushed to.
+type RemoteWriteConfig struct {
+	// URL is the base URL of the server. Profiles are pushed to its /ingest
+	// path.
+	URL string `yaml:"url"`
+
+	// Headers are added to every push request.
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// TenantID sets the X-Scope-OrgID header.
+	TenantID string `yaml:"tenant_id,omitempty"`
+
+	HTTPClientConfig config_util.HTTPClientConfig `yaml:",inline"`
+}
+
+// Validate ensures that the RemoteWriteConfig is valid.
+func (c *RemoteWriteConfig) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("url must not be empty")
+	}
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid url %s: scheme must be http or https", c.URL)
+	}
+	return c.HTTPClientConfig.Validate()
+}
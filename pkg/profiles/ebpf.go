@@ -0,0 +1,119 @@
+package profiles
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// Labels of processes profiled with eBPF, which are available for
+// relabeling.
+const (
+	processPIDLabel    = model.MetaLabelPrefix + "process_pid"
+	processCommLabel   = model.MetaLabelPrefix + "process_comm"
+	processExeLabel    = model.MetaLabelPrefix + "process_exe"
+	processCgroupLabel = model.MetaLabelPrefix + "process_cgroup"
+)
+
+type ebpfMetrics struct {
+	samples    *prometheus.CounterVec
+	lostStacks *prometheus.CounterVec
+}
+
+func newEBPFMetrics(reg prometheus.Registerer) *ebpfMetrics {
+	m := &ebpfMetrics{
+		samples: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "profiles_ebpf_samples_total",
+			Help: "Total number of stacks sampled with eBPF.",
+		}, []string{"job"}),
+		lostStacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "profiles_ebpf_lost_stacks_total",
+			Help: "Total number of samples whose stacks couldn't be collected.",
+		}, []string{"job"}),
+	}
+	reg.MustRegister(m.samples, m.lostStacks)
+	return m
+}
+
+// process is a process sampled with eBPF.
+type process struct {
+	PID    int
+	Comm   string
+	Exe    string
+	Cgroup string
+}
+
+// processLabels returns the labels of p after relabeling, or nil if p is
+// dropped. Processes are labeled with the job and the host they run on.
+func processLabels(cfg *EBPFConfig, host string, p process) labels.Labels {
+	lb := labels.NewBuilder(nil)
+	lb.Set(model.JobLabel, cfg.JobName)
+	lb.Set(model.InstanceLabel, host)
+	lb.Set(processPIDLabel, strconv.Itoa(p.PID))
+	lb.Set(processCommLabel, p.Comm)
+	lb.Set(processExeLabel, p.Exe)
+	lb.Set(processCgroupLabel, p.Cgroup)
+	return relabel.Process(lb.Labels(), cfg.RelabelConfigs...)
+}
+
+// stackSample counts the samples of a stack.
+type stackSample struct {
+	// Frames are the names of the functions of the stack, starting with the
+	// innermost function.
+	Frames []string
+	Count  uint64
+}
+
+// buildCPUProfile returns a gzipped pprof CPU profile of samples taken every
+// period between from and until.
+func buildCPUProfile(samples []stackSample, period time.Duration, from, until time.Time) ([]byte, error) {
+	p := &profile.Profile{
+		SampleType: []*profile.ValueType{
+			{Type: "samples", Unit: "count"},
+			{Type: "cpu", Unit: "nanoseconds"},
+		},
+		PeriodType:    &profile.ValueType{Type: "cpu", Unit: "nanoseconds"},
+		Period:        period.Nanoseconds(),
+		TimeNanos:     from.UnixNano(),
+		DurationNanos: until.Sub(from).Nanoseconds(),
+	}
+
+	locations := make(map[string]*profile.Location)
+	for _, s := range samples {
+		locs := make([]*profile.Location, 0, len(s.Frames))
+		for _, name := range s.Frames {
+			loc, ok := locations[name]
+			if !ok {
+				fn := &profile.Function{
+					ID:         uint64(len(p.Function) + 1),
+					Name:       name,
+					SystemName: name,
+				}
+				p.Function = append(p.Function, fn)
+				loc = &profile.Location{
+					ID:   uint64(len(p.Location) + 1),
+					Line: []profile.Line{{Function: fn}},
+				}
+				p.Location = append(p.Location, loc)
+				locations[name] = loc
+			}
+			locs = append(locs, loc)
+		}
+		p.Sample = append(p.Sample, &profile.Sample{
+			Location: locs,
+			Value:    []int64{int64(s.Count), int64(s.Count) * period.Nanoseconds()},
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := p.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,542 @@
+//go:build linux
+// +build linux
+
+package profiles
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/rlimit"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// ebpfStackDepth is the maximum number of frames of a sampled stack,
+	// PERF_MAX_STACK_DEPTH.
+	ebpfStackDepth = 127
+	// ebpfMaxStacks is the maximum number of distinct stacks sampled during
+	// a collect_interval.
+	ebpfMaxStacks = 16384
+
+	bpfFUserStack = 1 << 8 // BPF_F_USER_STACK
+	bpfNoExist    = 1      // BPF_NOEXIST
+)
+
+// ebpfSampleKey is the key of the map counting how often each stack was
+// sampled. It's written by the BPF program.
+type ebpfSampleKey struct {
+	PID         uint32
+	UserStack   int32
+	KernelStack int32
+	_           uint32
+	Comm        [16]byte
+}
+
+// ebpfProfiler samples the stacks running on every CPU with a BPF program
+// attached to a perf event and periodically pushes them as CPU profiles.
+type ebpfProfiler struct {
+	cfg     *EBPFConfig
+	log     log.Logger
+	pusher  pusher
+	metrics *ebpfMetrics
+	host    string
+
+	stacks stackMap  // Stack traces by their ID.
+	counts *ebpf.Map // Sample counts by ebpfSampleKey.
+	prog   *ebpf.Program
+	events []int // Perf event FDs, one for each CPU.
+
+	symbols *symbolizer
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// stackMap is the map of stack traces written by the BPF program. It's
+// implemented by *ebpf.Map.
+type stackMap interface {
+	Lookup(key, valueOut interface{}) error
+	Delete(key interface{}) error
+	Close() error
+}
+
+func newEBPFProfiler(l log.Logger, cfg *EBPFConfig, p pusher, metrics *ebpfMetrics) (*ebpfProfiler, error) {
+	// Kernels before 5.11 account the memory of BPF maps to RLIMIT_MEMLOCK.
+	// Creating the maps fails later if the limit is needed but can't be
+	// removed.
+	if err := rlimit.RemoveMemlock(); err != nil {
+		level.Debug(l).Log("msg", "failed to remove memlock limit", "err", err)
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hostname: %w", err)
+	}
+
+	kernel, err := readKernelSymbols()
+	if err != nil {
+		level.Warn(l).Log("msg", "failed to read kernel symbols, kernel functions won't be named", "err", err)
+	}
+
+	ep := &ebpfProfiler{
+		cfg:     cfg,
+		log:     l,
+		pusher:  p,
+		metrics: metrics,
+		host:    host,
+		symbols: newSymbolizer(kernel),
+		done:    make(chan struct{}),
+	}
+	if err := ep.load(); err != nil {
+		ep.close()
+		return nil, err
+	}
+
+	var ctx context.Context
+	ctx, ep.cancel = context.WithCancel(context.Background())
+	go ep.run(ctx)
+	return ep, nil
+}
+
+func readKernelSymbols() (symbolTable, error) {
+	f, err := os.Open("/proc/kallsyms")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readKallsyms(f)
+}
+
+// load creates the maps and the BPF program and attaches the program to a
+// CPU clock perf event on every online CPU.
+func (p *ebpfProfiler) load() error {
+	stacks, err := ebpf.NewMap(&ebpf.MapSpec{
+		Type:       ebpf.StackTrace,
+		KeySize:    4,
+		ValueSize:  8 * ebpfStackDepth,
+		MaxEntries: ebpfMaxStacks,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create stacks map: %w", err)
+	}
+	p.stacks = stacks
+	p.counts, err = ebpf.NewMap(&ebpf.MapSpec{
+		Type:       ebpf.Hash,
+		KeySize:    uint32(unsafe.Sizeof(ebpfSampleKey{})),
+		ValueSize:  8,
+		MaxEntries: ebpfMaxStacks,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create counts map: %w", err)
+	}
+
+	p.prog, err = ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:         ebpf.PerfEvent,
+		Instructions: sampleInstructions(stacks.FD(), p.counts.FD()),
+		License:      "GPL",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load BPF program: %w", err)
+	}
+
+	cpus, err := onlineCPUs()
+	if err != nil {
+		return err
+	}
+	for _, cpu := range cpus {
+		attr := unix.PerfEventAttr{
+			Type:   unix.PERF_TYPE_SOFTWARE,
+			Config: unix.PERF_COUNT_SW_CPU_CLOCK,
+			Size:   uint32(unsafe.Sizeof(unix.PerfEventAttr{})),
+			Sample: uint64(p.cfg.SampleRate),
+			Bits:   unix.PerfBitFreq,
+		}
+		fd, err := unix.PerfEventOpen(&attr, -1, cpu, -1, unix.PERF_FLAG_FD_CLOEXEC)
+		if err != nil {
+			return fmt.Errorf("failed to open perf event on CPU %d: %w", cpu, err)
+		}
+		p.events = append(p.events, fd)
+
+		if err := unix.IoctlSetInt(fd, unix.PERF_EVENT_IOC_SET_BPF, p.prog.FD()); err != nil {
+			return fmt.Errorf("failed to attach BPF program on CPU %d: %w", cpu, err)
+		}
+		if err := unix.IoctlSetInt(fd, unix.PERF_EVENT_IOC_ENABLE, 0); err != nil {
+			return fmt.Errorf("failed to enable perf event on CPU %d: %w", cpu, err)
+		}
+	}
+	return nil
+}
+
+// sampleInstructions returns the BPF program which counts the sampled
+// stacks. It's the equivalent of:
+//
+//	struct key { u32 pid; s32 user_stack; s32 kernel_stack; u32 pad; char comm[16]; };
+//
+//	int sample(struct bpf_perf_event_data *ctx) {
+//	  struct key k = {};
+//	  k.pid = bpf_get_current_pid_tgid() >> 32;
+//	  if (k.pid == 0) return 0; // The CPU is idle.
+//	  bpf_get_current_comm(&k.comm, sizeof(k.comm));
+//	  k.user_stack = bpf_get_stackid(ctx, &stacks, BPF_F_USER_STACK);
+//	  k.kernel_stack = bpf_get_stackid(ctx, &stacks, 0);
+//	  u64 *count = bpf_map_lookup_elem(&counts, &k);
+//	  if (count) {
+//	    __sync_fetch_and_add(count, 1);
+//	  } else {
+//	    u64 one = 1;
+//	    bpf_map_update_elem(&counts, &k, &one, BPF_NOEXIST);
+//	  }
+//	  return 0;
+//	}
+func sampleInstructions(stacksFD, countsFD int) asm.Instructions {
+	const (
+		keyOff   = -32
+		userOff  = keyOff + 4
+		kernOff  = keyOff + 8
+		padOff   = keyOff + 12
+		commOff  = keyOff + 16
+		valueOff = keyOff - 8
+	)
+
+	return asm.Instructions{
+		asm.Mov.Reg(asm.R6, asm.R1),
+
+		asm.FnGetCurrentPidTgid.Call(),
+		asm.RSh.Imm(asm.R0, 32),
+		asm.JEq.Imm(asm.R0, 0, "exit"),
+		asm.StoreMem(asm.RFP, keyOff, asm.R0, asm.Word),
+		asm.StoreImm(asm.RFP, padOff, 0, asm.Word),
+
+		asm.Mov.Reg(asm.R1, asm.RFP),
+		asm.Add.Imm(asm.R1, commOff),
+		asm.Mov.Imm(asm.R2, 16),
+		asm.FnGetCurrentComm.Call(),
+
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.LoadMapPtr(asm.R2, stacksFD),
+		asm.Mov.Imm(asm.R3, bpfFUserStack),
+		asm.FnGetStackid.Call(),
+		asm.StoreMem(asm.RFP, userOff, asm.R0, asm.Word),
+
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.LoadMapPtr(asm.R2, stacksFD),
+		asm.Mov.Imm(asm.R3, 0),
+		asm.FnGetStackid.Call(),
+		asm.StoreMem(asm.RFP, kernOff, asm.R0, asm.Word),
+
+		asm.LoadMapPtr(asm.R1, countsFD),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, keyOff),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "insert"),
+		asm.Mov.Imm(asm.R1, 1),
+		asm.StoreXAdd(asm.R0, asm.R1, asm.DWord),
+		asm.Ja.Label("exit"),
+
+		asm.StoreImm(asm.RFP, valueOff, 1, asm.DWord).Sym("insert"),
+		asm.LoadMapPtr(asm.R1, countsFD),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, keyOff),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, valueOff),
+		asm.Mov.Imm(asm.R4, bpfNoExist),
+		asm.FnMapUpdateElem.Call(),
+
+		asm.Mov.Imm(asm.R0, 0).Sym("exit"),
+		asm.Return(),
+	}
+}
+
+// onlineCPUs returns the IDs of the online CPUs.
+func onlineCPUs() ([]int, error) {
+	buf, err := ioutil.ReadFile("/sys/devices/system/cpu/online")
+	if err != nil {
+		return nil, err
+	}
+	return parseCPUList(strings.TrimSpace(string(buf)))
+}
+
+// parseCPUList parses a list of CPU IDs in the format of the files in
+// /sys/devices/system/cpu, e.g. 0-3,6.
+func parseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for _, r := range strings.Split(list, ",") {
+		bounds := strings.SplitN(r, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU list %q: %w", list, err)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil {
+				return nil, fmt.Errorf("invalid CPU list %q: %w", list, err)
+			}
+		}
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+func (p *ebpfProfiler) run(ctx context.Context) {
+	defer close(p.done)
+
+	ticker := time.NewTicker(time.Duration(p.cfg.CollectInterval))
+	defer ticker.Stop()
+
+	from := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		until := time.Now()
+		if err := p.collect(ctx, from, until); err != nil {
+			level.Warn(p.log).Log("msg", "failed to collect eBPF profiles", "err", err)
+		}
+		from = until
+	}
+}
+
+// collect reads and clears the sampled stacks and pushes them as profiles.
+func (p *ebpfProfiler) collect(ctx context.Context, from, until time.Time) error {
+	counts, err := p.readCounts()
+	if err != nil {
+		return err
+	}
+	return p.pushCounts(ctx, counts, from, until)
+}
+
+// pushCounts groups the sampled counts by the labels of their process and
+// pushes a profile for each group. All stacks referenced by counts are freed,
+// including those of processes dropped by relabeling.
+func (p *ebpfProfiler) pushCounts(ctx context.Context, counts map[ebpfSampleKey]uint64, from, until time.Time) error {
+	defer p.symbols.Prune()
+
+	type group struct {
+		labels  labels.Labels
+		samples []stackSample
+	}
+	var (
+		groups    = make(map[uint64]*group)
+		processes = make(map[uint32]*sampledProcess)
+		stacks    = make(map[int32][]uint64)
+		stackIDs  = make(map[int32]struct{})
+	)
+
+	for key, count := range counts {
+		p.metrics.samples.WithLabelValues(p.cfg.JobName).Add(float64(count))
+		if key.UserStack < 0 && key.KernelStack < 0 {
+			p.metrics.lostStacks.WithLabelValues(p.cfg.JobName).Add(float64(count))
+			continue
+		}
+
+		// Stacks of processes dropped by relabeling are never read, but
+		// they still have to be freed.
+		for _, id := range []int32{key.KernelStack, key.UserStack} {
+			if id >= 0 {
+				stackIDs[id] = struct{}{}
+			}
+		}
+
+		proc, ok := processes[key.PID]
+		if !ok {
+			proc = p.process(key)
+			processes[key.PID] = proc
+		}
+		if proc.labels == nil {
+			continue
+		}
+
+		var frames []string
+		for _, addr := range p.stack(stacks, key.KernelStack) {
+			frames = append(frames, p.symbols.Kernel(addr))
+		}
+		for _, addr := range p.stack(stacks, key.UserStack) {
+			frames = append(frames, p.symbols.User(int(key.PID), proc.mappings, addr))
+		}
+		frames = append(frames, proc.comm)
+
+		hash := proc.labels.Hash()
+		g, ok := groups[hash]
+		if !ok {
+			g = &group{labels: proc.labels}
+			groups[hash] = g
+		}
+		g.samples = append(g.samples, stackSample{Frames: frames, Count: count})
+	}
+
+	for id := range stackIDs {
+		_ = p.stacks.Delete(uint32(id))
+	}
+
+	period := time.Second / time.Duration(p.cfg.SampleRate)
+	for _, g := range groups {
+		data, err := buildCPUProfile(g.samples, period, from, until)
+		if err != nil {
+			return err
+		}
+		err = p.pusher.Push(ctx, Profile{
+			Name:   p.cfg.JobName + ".cpu",
+			Labels: publicLabels(g.labels),
+			From:   from,
+			Until:  until,
+			Data:   data,
+		})
+		if err != nil && ctx.Err() == nil {
+			level.Warn(p.log).Log("msg", "failed to push profile", "err", err)
+		}
+	}
+	return nil
+}
+
+// readCounts reads and removes the sample counts of all stacks.
+func (p *ebpfProfiler) readCounts() (map[ebpfSampleKey]uint64, error) {
+	var (
+		counts = make(map[ebpfSampleKey]uint64)
+		key    ebpfSampleKey
+		count  uint64
+	)
+	it := p.counts.Iterate()
+	for it.Next(&key, &count) {
+		counts[key] = count
+	}
+	if err := it.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read sampled stacks: %w", err)
+	}
+
+	// Samples taken between reading and deleting a key are lost, which is
+	// cheaper than swapping the map on every collection.
+	for key := range counts {
+		if err := p.counts.Delete(key); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+			return nil, fmt.Errorf("failed to clear sampled stacks: %w", err)
+		}
+	}
+	return counts, nil
+}
+
+// stack returns the addresses of the stack with the given ID, starting with
+// the innermost frame. Stacks are cached in cache.
+func (p *ebpfProfiler) stack(cache map[int32][]uint64, id int32) []uint64 {
+	if id < 0 {
+		return nil
+	}
+	if addrs, ok := cache[id]; ok {
+		return addrs
+	}
+
+	var (
+		raw   [ebpfStackDepth]uint64
+		addrs []uint64
+	)
+	if err := p.stacks.Lookup(uint32(id), &raw); err == nil {
+		for _, addr := range raw {
+			if addr == 0 {
+				break
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+	cache[id] = addrs
+	return addrs
+}
+
+// sampledProcess is a process whose stacks were sampled during a
+// collection.
+type sampledProcess struct {
+	comm     string
+	labels   labels.Labels // nil if the process is dropped by relabeling.
+	mappings []mapping
+}
+
+// process returns the labels and mappings of the process of key. Processes
+// which exited since they were sampled only have the labels known from key.
+func (p *ebpfProfiler) process(key ebpfSampleKey) *sampledProcess {
+	info := process{
+		PID:  int(key.PID),
+		Comm: string(bytes.TrimRight(key.Comm[:], "\x00")),
+	}
+	dir := fmt.Sprintf("/proc/%d", key.PID)
+	if comm, err := ioutil.ReadFile(dir + "/comm"); err == nil {
+		info.Comm = strings.TrimSpace(string(comm))
+	}
+	if exe, err := os.Readlink(dir + "/exe"); err == nil {
+		info.Exe = exe
+	}
+	if cgroup, err := ioutil.ReadFile(dir + "/cgroup"); err == nil {
+		info.Cgroup = parseCgroup(string(cgroup))
+	}
+
+	proc := &sampledProcess{
+		comm:   info.Comm,
+		labels: processLabels(p.cfg, p.host, info),
+	}
+	if proc.labels == nil {
+		return proc
+	}
+	if f, err := os.Open(dir + "/maps"); err == nil {
+		proc.mappings, _ = readMappings(f)
+		f.Close()
+	}
+	return proc
+}
+
+// parseCgroup returns the cgroup path from the contents of
+// /proc/<pid>/cgroup, preferring the unified hierarchy of cgroup v2.
+func parseCgroup(contents string) string {
+	var first string
+	for _, line := range strings.Split(contents, "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if parts[0] == "0" && parts[1] == "" {
+			return parts[2]
+		}
+		if first == "" {
+			first = parts[2]
+		}
+	}
+	return first
+}
+
+// Stop stops sampling stacks. Stacks sampled since the last collection
+// aren't pushed.
+func (p *ebpfProfiler) Stop() {
+	p.cancel()
+	<-p.done
+	p.close()
+}
+
+func (p *ebpfProfiler) close() {
+	for _, fd := range p.events {
+		_ = unix.Close(fd)
+	}
+	p.events = nil
+	if p.prog != nil {
+		p.prog.Close()
+	}
+	if p.counts != nil {
+		p.counts.Close()
+	}
+	if p.stacks != nil {
+		p.stacks.Close()
+	}
+}
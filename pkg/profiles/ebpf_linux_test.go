@@ -0,0 +1,187 @@
+//go:build linux
+// +build linux
+
+package profiles
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/pprof/profile"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseCPUList(t *testing.T) {
+	cpus, err := parseCPUList("0-3,6,8-9")
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1, 2, 3, 6, 8, 9}, cpus)
+
+	_, err = parseCPUList("0-a")
+	require.Error(t, err)
+}
+
+func TestReadKallsyms(t *testing.T) {
+	syms, err := readKallsyms(strings.NewReader(`ffffffff81000000 T _stext
+ffffffff81000100 t do_one_initcall
+ffffffff81000200 D some_data
+ffffffff81000300 T schedule [sched]
+`))
+	require.NoError(t, err)
+
+	name, ok := syms.Lookup(0xffffffff81000150)
+	require.True(t, ok)
+	require.Equal(t, "do_one_initcall", name)
+
+	name, ok = syms.Lookup(0xffffffff81000250)
+	require.True(t, ok)
+	require.Equal(t, "do_one_initcall", name)
+
+	_, ok = syms.Lookup(0xff)
+	require.False(t, ok)
+}
+
+func TestSymbolizer(t *testing.T) {
+	f, err := os.Open("/proc/self/maps")
+	require.NoError(t, err)
+	defer f.Close()
+	mappings, err := readMappings(f)
+	require.NoError(t, err)
+
+	s := newSymbolizer(nil)
+	pc := uint64(reflect.ValueOf(TestSymbolizer).Pointer())
+	require.Equal(t, "github.com/grafana/agent/pkg/profiles.TestSymbolizer", s.User(os.Getpid(), mappings, pc))
+	require.Equal(t, "[unknown]", s.User(os.Getpid(), mappings, 1))
+	require.Len(t, s.files, 1)
+
+	s.Prune()
+	require.Len(t, s.files, 1)
+	s.Prune()
+	require.Len(t, s.files, 0)
+}
+
+func TestParseCgroup(t *testing.T) {
+	require.Equal(t, "/system.slice/checkout.service", parseCgroup("0::/system.slice/checkout.service\n"))
+	require.Equal(t, "/kubepods/pod1", parseCgroup("12:cpu,cpuacct:/kubepods/pod1\n11:memory:/kubepods/pod1\n"))
+}
+
+// fakeStackMap is a stackMap which returns the same frame for every stack.
+type fakeStackMap struct {
+	deleted []uint32
+}
+
+func (m *fakeStackMap) Lookup(_, valueOut interface{}) error {
+	valueOut.(*[ebpfStackDepth]uint64)[0] = 0x1000
+	return nil
+}
+
+func (m *fakeStackMap) Delete(key interface{}) error {
+	m.deleted = append(m.deleted, key.(uint32))
+	return nil
+}
+
+func (m *fakeStackMap) Close() error { return nil }
+
+func TestEBPFProfiler_PushCounts(t *testing.T) {
+	cfg := DefaultEBPFConfig
+	cfg.JobName = "node"
+	cfg.SampleRate = 100
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+relabel_configs:
+  - source_labels: [__meta_process_pid]
+    regex: "2147483647"
+    action: drop
+`), &cfg))
+
+	var pushed []Profile
+	stacks := &fakeStackMap{}
+	p := &ebpfProfiler{
+		cfg: &cfg,
+		log: log.NewNopLogger(),
+		pusher: pusherFunc(func(_ context.Context, p Profile) error {
+			pushed = append(pushed, p)
+			return nil
+		}),
+		metrics: newEBPFMetrics(prometheus.NewRegistry()),
+		host:    "host-a",
+		stacks:  stacks,
+		symbols: newSymbolizer(nil),
+	}
+
+	// PIDs which don't exist are labeled with the comm from their key.
+	comm := func(s string) (c [16]byte) {
+		copy(c[:], s)
+		return c
+	}
+	now := time.Now()
+	err := p.pushCounts(context.Background(), map[ebpfSampleKey]uint64{
+		{PID: 2147483646, UserStack: 1, KernelStack: 2, Comm: comm("kept")}:     3,
+		{PID: 2147483647, UserStack: 3, KernelStack: 4, Comm: comm("dropped")}:  5,
+		{PID: 2147483647, UserStack: -1, KernelStack: 2, Comm: comm("dropped")}: 1,
+	}, now.Add(-time.Second), now)
+	require.NoError(t, err)
+
+	require.Len(t, pushed, 1)
+	require.ElementsMatch(t, []uint32{1, 2, 3, 4}, stacks.deleted, "stacks of dropped processes must be freed")
+}
+
+//go:noinline
+func ebpfSpin(ctx context.Context) {
+	for ctx.Err() == nil {
+	}
+}
+
+func TestEBPFProfiler(t *testing.T) {
+	cfg := DefaultEBPFConfig
+	cfg.JobName = "node"
+	cfg.CollectInterval = model.Duration(time.Second)
+
+	profiles := make(chan Profile, 100)
+	push := pusherFunc(func(_ context.Context, p Profile) error {
+		profiles <- p
+		return nil
+	})
+
+	ep, err := newEBPFProfiler(log.NewNopLogger(), &cfg, push, newEBPFMetrics(prometheus.NewRegistry()))
+	if errors.Is(err, unix.EPERM) || errors.Is(err, unix.EACCES) || errors.Is(err, unix.ENOENT) {
+		t.Skipf("eBPF isn't available: %v", err)
+	}
+	require.NoError(t, err)
+	defer ep.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ebpfSpin(ctx)
+
+	host, err := os.Hostname()
+	require.NoError(t, err)
+
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case p := <-profiles:
+			require.Equal(t, "node.cpu", p.Name)
+			require.Equal(t, map[string]string{"job": "node", "instance": host}, p.Labels)
+
+			prof, err := profile.Parse(bytes.NewReader(p.Data))
+			require.NoError(t, err)
+			for _, fn := range prof.Function {
+				if fn.Name == "github.com/grafana/agent/pkg/profiles.ebpfSpin" {
+					return
+				}
+			}
+		case <-timeout:
+			require.FailNow(t, "timed out waiting for a profile with the sampled function")
+		}
+	}
+}
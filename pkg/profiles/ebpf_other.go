@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package profiles
+
+import (
+	"fmt"
+
+	"github.com/go-kit/log"
+)
+
+// ebpfProfiler samples stacks with eBPF, which is only supported on Linux.
+type ebpfProfiler struct{}
+
+func newEBPFProfiler(l log.Logger, cfg *EBPFConfig, p pusher, metrics *ebpfMetrics) (*ebpfProfiler, error) {
+	return nil, fmt.Errorf("ebpf_configs are only supported on Linux")
+}
+
+// Stop implements the ebpfProfiler of Linux.
+func (p *ebpfProfiler) Stop() {}
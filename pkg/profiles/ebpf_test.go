@@ -0,0 +1,97 @@
+package profiles
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestBuildCPUProfile(t *testing.T) {
+	from := time.Unix(1640995200, 0)
+	data, err := buildCPUProfile([]stackSample{
+		{Frames: []string{"main.work", "main.main", "app"}, Count: 3},
+		{Frames: []string{"schedule_[k]", "main.main", "app"}, Count: 1},
+	}, 10*time.Millisecond, from, from.Add(15*time.Second))
+	require.NoError(t, err)
+
+	p, err := profile.Parse(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, int64(10*time.Millisecond), p.Period)
+	require.Equal(t, from.UnixNano(), p.TimeNanos)
+	require.Equal(t, int64(15*time.Second), p.DurationNanos)
+	require.Len(t, p.Location, 4)
+
+	require.Len(t, p.Sample, 2)
+	require.Equal(t, []int64{3, int64(30 * time.Millisecond)}, p.Sample[0].Value)
+	var frames []string
+	for _, loc := range p.Sample[1].Location {
+		frames = append(frames, loc.Line[0].Function.Name)
+	}
+	require.Equal(t, []string{"schedule_[k]", "main.main", "app"}, frames)
+}
+
+func TestProcessLabels(t *testing.T) {
+	var cfg EBPFConfig
+	require.NoError(t, yaml.UnmarshalStrict([]byte(`
+job_name: node
+relabel_configs:
+  - source_labels: [__meta_process_comm]
+    regex: kworker.*
+    action: drop
+  - source_labels: [__meta_process_exe]
+    target_label: service
+`), &cfg))
+
+	lset := processLabels(&cfg, "host-a", process{PID: 42, Comm: "checkout", Exe: "/usr/bin/checkout", Cgroup: "/system.slice/checkout.service"})
+	require.Equal(t, map[string]string{
+		"instance": "host-a",
+		"job":      "node",
+		"service":  "/usr/bin/checkout",
+	}, publicLabels(lset))
+	require.Equal(t, "42", lset.Get(processPIDLabel))
+	require.Equal(t, "/system.slice/checkout.service", lset.Get(processCgroupLabel))
+
+	require.Equal(t, labels.Labels(nil), processLabels(&cfg, "host-a", process{PID: 7, Comm: "kworker/0:1"}))
+}
+
+func TestEBPFConfig_Validate(t *testing.T) {
+	tt := []struct {
+		name          string
+		cfg           string
+		expectedError string
+	}{
+		{
+			name: "defaults",
+			cfg:  `job_name: node`,
+		},
+		{
+			name:          "sample rate too high",
+			cfg:           "job_name: node\nsample_rate: 5000",
+			expectedError: "sample_rate must be between 1 and 1000",
+		},
+		{
+			name:          "collect interval too short",
+			cfg:           "job_name: node\ncollect_interval: 100ms",
+			expectedError: "collect_interval must be at least 1s",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg EBPFConfig
+			require.NoError(t, yaml.UnmarshalStrict([]byte(tc.cfg), &cfg))
+
+			err := cfg.Validate()
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
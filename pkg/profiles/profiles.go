@@ -0,0 +1,207 @@
+// Package profiles implements continuous profiling support for the Grafana
+// Agent. Profiles are pushed to Pyroscope-compatible endpoints.
+package profiles
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/grafana/agent/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Profiles is the profiles subsystem. It runs a set of profiles instances.
+type Profiles struct {
+	mut sync.Mutex
+
+	reg       prometheus.Registerer
+	l         log.Logger
+	instances map[string]*Instance
+}
+
+// New creates and starts the profiles subsystem.
+func New(reg prometheus.Registerer, c Config, l log.Logger) (*Profiles, error) {
+	p := &Profiles{
+		instances: make(map[string]*Instance),
+		reg:       reg,
+		l:         log.With(l, "component", "profiles"),
+	}
+	if err := p.ApplyConfig(c); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ApplyConfig updates Profiles with a new Config.
+func (p *Profiles) ApplyConfig(c Config) error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	newInstances := make(map[string]*Instance, len(c.Configs))
+
+	for _, ic := range c.Configs {
+		// If an old instance existed, update it and move it to the new map.
+		if old, ok := p.instances[ic.Name]; ok {
+			if err := old.ApplyConfig(ic); err != nil {
+				return err
+			}
+			newInstances[ic.Name] = old
+			continue
+		}
+
+		inst, err := NewInstance(p.reg, ic, p.l)
+		if err != nil {
+			return fmt.Errorf("unable to apply config for %s: %w", ic.Name, err)
+		}
+		newInstances[ic.Name] = inst
+	}
+
+	// Any instance in p.instances that isn't in newInstances has been removed
+	// from the config. Stop them before replacing the map.
+	for key, i := range p.instances {
+		if _, exist := newInstances[key]; exist {
+			continue
+		}
+		i.Stop()
+	}
+	p.instances = newInstances
+
+	return nil
+}
+
+// Stop stops the profiles subsystem.
+func (p *Profiles) Stop() {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	for _, i := range p.instances {
+		i.Stop()
+	}
+}
+
+// Instance is used to retrieve a named profiles instance.
+func (p *Profiles) Instance(name string) *Instance {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+
+	return p.instances[name]
+}
+
+// Instance is an individual profiles instance. It collects profiles from the
+// targets of its scrape configs and with eBPF and pushes them, along with the
+// profiles it's given, to its remote_write endpoints.
+type Instance struct {
+	mut sync.Mutex
+
+	cfg InstanceConfig
+	log log.Logger
+	reg *util.Unregisterer
+
+	writers remoteWriters
+	scraper *scraper
+	ebpf    []*ebpfProfiler
+}
+
+// NewInstance creates and starts a profiles instance.
+func NewInstance(reg prometheus.Registerer, c InstanceConfig, l log.Logger) (*Instance, error) {
+	instReg := prometheus.WrapRegistererWith(prometheus.Labels{"profiles_config": c.Name}, reg)
+
+	inst := &Instance{
+		reg: util.WrapWithUnregisterer(instReg),
+		log: log.With(l, "profiles_config", c.Name),
+	}
+	if err := inst.ApplyConfig(c); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
+
+// ApplyConfig applies a new InstanceConfig. Nothing happens if the config
+// hasn't changed.
+func (i *Instance) ApplyConfig(c InstanceConfig) error {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	if util.CompareYAML(c, i.cfg) && i.writers != nil {
+		return nil
+	}
+
+	// The scrape loops and eBPF profilers push to the old writers, so they
+	// have to be stopped before the writers are replaced.
+	i.stopCollectors()
+
+	i.reg.UnregisterAll()
+	metrics := newRemoteWriteMetrics(i.reg)
+
+	writers := make(remoteWriters, 0, len(c.RemoteWrite))
+	for _, rw := range c.RemoteWrite {
+		w, err := newRemoteWriter(rw, metrics)
+		if err != nil {
+			return fmt.Errorf("failed to create remote_write for %s: %w", rw.URL, err)
+		}
+		writers = append(writers, w)
+	}
+
+	if len(c.ScrapeConfigs) > 0 {
+		sc, err := newScraper(i.log, c.ScrapeConfigs, writers, newScrapeMetrics(i.reg))
+		if err != nil {
+			return fmt.Errorf("failed to create scraper: %w", err)
+		}
+		i.scraper = sc
+	}
+
+	if len(c.EBPFConfigs) > 0 {
+		metrics := newEBPFMetrics(i.reg)
+		for _, ec := range c.EBPFConfigs {
+			ep, err := newEBPFProfiler(log.With(i.log, "job", ec.JobName), ec, writers, metrics)
+			if err != nil {
+				i.stopCollectors()
+				return fmt.Errorf("failed to create ebpf profiler for job %s: %w", ec.JobName, err)
+			}
+			i.ebpf = append(i.ebpf, ep)
+		}
+	}
+
+	i.cfg = c
+	i.writers = writers
+	return nil
+}
+
+// Push pushes a profile to every remote_write endpoint of the instance.
+func (i *Instance) Push(ctx context.Context, p Profile) error {
+	i.mut.Lock()
+	writers := i.writers
+	i.mut.Unlock()
+
+	err := writers.Push(ctx, p)
+	if err != nil {
+		level.Debug(i.log).Log("msg", "failed to push profile", "err", err)
+	}
+	return err
+}
+
+// Stop stops the instance.
+func (i *Instance) Stop() {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	i.stopCollectors()
+	i.reg.UnregisterAll()
+	i.writers = nil
+}
+
+// stopCollectors stops the scrape loops and eBPF profilers. i.mut must be
+// held when calling stopCollectors.
+func (i *Instance) stopCollectors() {
+	if i.scraper != nil {
+		i.scraper.Stop()
+		i.scraper = nil
+	}
+	for _, ep := range i.ebpf {
+		ep.Stop()
+	}
+	i.ebpf = nil
+}
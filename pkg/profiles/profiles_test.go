@@ -0,0 +1,158 @@
+package profiles
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+func TestInstance_Push(t *testing.T) {
+	type request struct {
+		path   string
+		query  map[string]string
+		tenant string
+		body   string
+	}
+	requests := make(chan request, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		q := r.URL.Query()
+		requests <- request{
+			path:   r.URL.Path,
+			query:  map[string]string{"name": q.Get("name"), "from": q.Get("from"), "until": q.Get("until"), "format": q.Get("format")},
+			tenant: r.Header.Get("X-Scope-OrgID"),
+			body:   string(body),
+		}
+	}))
+	defer srv.Close()
+
+	cfgText := `
+name: default
+remote_write:
+  - url: ` + srv.URL + `/pyroscope
+    tenant_id: team-a
+`
+	var cfg InstanceConfig
+	require.NoError(t, yaml.UnmarshalStrict([]byte(cfgText), &cfg))
+	require.NoError(t, cfg.Validate())
+
+	reg := prometheus.NewRegistry()
+	inst, err := NewInstance(reg, cfg, log.NewNopLogger())
+	require.NoError(t, err)
+	defer inst.Stop()
+
+	err = inst.Push(context.Background(), Profile{
+		Name:   "checkout.cpu",
+		Labels: map[string]string{"instance": "10.0.0.1:8080", "env": "prod"},
+		From:   time.Unix(100, 0),
+		Until:  time.Unix(110, 0),
+		Data:   []byte("profile"),
+	})
+	require.NoError(t, err)
+
+	req := <-requests
+	require.Equal(t, request{
+		path: "/pyroscope/ingest",
+		query: map[string]string{
+			"name":   "checkout.cpu{env=prod,instance=10.0.0.1:8080}",
+			"from":   "100",
+			"until":  "110",
+			"format": "pprof",
+		},
+		tenant: "team-a",
+		body:   "profile",
+	}, req)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(inst.writers[0].metrics.sent.WithLabelValues(srv.URL+"/pyroscope")))
+}
+
+func TestInstance_PushFailed(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		http.Error(rw, "tenant unknown", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	inst, err := NewInstance(prometheus.NewRegistry(), InstanceConfig{
+		Name:        "default",
+		RemoteWrite: []RemoteWriteConfig{{URL: srv.URL}},
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+	defer inst.Stop()
+
+	err = inst.Push(context.Background(), Profile{Name: "checkout.cpu"})
+	require.EqualError(t, err, "failed to push profile to "+srv.URL+": server returned HTTP status 401 Unauthorized: tenant unknown")
+	require.Equal(t, 1.0, testutil.ToFloat64(inst.writers[0].metrics.failed.WithLabelValues(srv.URL)))
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tt := []struct {
+		name          string
+		cfg           string
+		expectedError string
+	}{
+		{
+			name: "valid",
+			cfg: `
+configs:
+  - name: default
+    remote_write:
+      - url: http://pyroscope:4040
+        basic_auth:
+          username: user
+          password: pass
+`,
+		},
+		{
+			name: "missing name",
+			cfg: `
+configs:
+  - remote_write:
+      - url: http://pyroscope:4040
+`,
+			expectedError: "profiles config at index 0 is missing a name",
+		},
+		{
+			name: "duplicate name",
+			cfg: `
+configs:
+  - name: default
+  - name: default
+`,
+			expectedError: "found multiple profiles configs with name default",
+		},
+		{
+			name: "invalid url",
+			cfg: `
+configs:
+  - name: default
+    remote_write:
+      - url: pyroscope:4040
+`,
+			expectedError: "failed to validate profiles config default: invalid remote_write at index 0: invalid url pyroscope:4040: scheme must be http or https",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg Config
+			require.NoError(t, yaml.UnmarshalStrict([]byte(tc.cfg), &cfg))
+
+			err := cfg.Validate()
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
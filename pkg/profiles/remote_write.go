@@ -0,0 +1,164 @@
+package profiles
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/version"
+	"go.uber.org/multierr"
+)
+
+// Profile is a profile in the pprof format collected from a target.
+type Profile struct {
+	// Name is the application name the profile is stored under.
+	Name string
+	// Labels identify the target the profile was collected from.
+	Labels map[string]string
+
+	// From and Until are the time range covered by the profile.
+	From, Until time.Time
+
+	// Data is the gzipped pprof profile.
+	Data []byte
+}
+
+// remoteWriter pushes profiles to a Pyroscope-compatible ingest endpoint.
+type remoteWriter struct {
+	cfg     RemoteWriteConfig
+	client  *http.Client
+	ingest  *url.URL
+	metrics *remoteWriteMetrics
+}
+
+type remoteWriteMetrics struct {
+	sent   *prometheus.CounterVec
+	failed *prometheus.CounterVec
+}
+
+func newRemoteWriteMetrics(reg prometheus.Registerer) *remoteWriteMetrics {
+	m := &remoteWriteMetrics{
+		sent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "profiles_remote_write_sent_profiles_total",
+			Help: "Total number of profiles pushed to a remote_write endpoint.",
+		}, []string{"url"}),
+		failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "profiles_remote_write_failed_profiles_total",
+			Help: "Total number of profiles which failed to be pushed to a remote_write endpoint.",
+		}, []string{"url"}),
+	}
+	reg.MustRegister(m.sent, m.failed)
+	return m
+}
+
+func newRemoteWriter(cfg RemoteWriteConfig, metrics *remoteWriteMetrics) (*remoteWriter, error) {
+	client, err := config_util.NewClientFromConfig(cfg.HTTPClientConfig, "profiles-remote-write")
+	if err != nil {
+		return nil, err
+	}
+	ingest, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	ingest.Path = path.Join(ingest.Path, "ingest")
+
+	return &remoteWriter{
+		cfg:     cfg,
+		client:  client,
+		ingest:  ingest,
+		metrics: metrics,
+	}, nil
+}
+
+// Push pushes p to the endpoint.
+func (w *remoteWriter) Push(ctx context.Context, p Profile) error {
+	err := w.push(ctx, p)
+	if err != nil {
+		w.metrics.failed.WithLabelValues(w.cfg.URL).Inc()
+		return fmt.Errorf("failed to push profile to %s: %w", w.cfg.URL, err)
+	}
+	w.metrics.sent.WithLabelValues(w.cfg.URL).Inc()
+	return nil
+}
+
+func (w *remoteWriter) push(ctx context.Context, p Profile) error {
+	u := *w.ingest
+	q := u.Query()
+	q.Set("name", appName(p.Name, p.Labels))
+	q.Set("from", strconv.FormatInt(p.From.Unix(), 10))
+	q.Set("until", strconv.FormatInt(p.Until.Unix(), 10))
+	q.Set("format", "pprof")
+	q.Set("spyName", "grafana-agent")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), bytes.NewReader(p.Data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("User-Agent", fmt.Sprintf("GrafanaAgent/%s", version.Version))
+	for k, v := range w.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	if w.cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", w.cfg.TenantID)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("server returned HTTP status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// remoteWriters pushes profiles to every remote_write endpoint of an
+// instance.
+type remoteWriters []*remoteWriter
+
+// Push pushes p to every endpoint, returning all errors encountered.
+func (ws remoteWriters) Push(ctx context.Context, p Profile) error {
+	var errs error
+	for _, w := range ws {
+		errs = multierr.Append(errs, w.Push(ctx, p))
+	}
+	return errs
+}
+
+// appName formats the name of a profile with its labels the way Pyroscope
+// expects them, e.g. checkout.cpu{env=prod,instance=10.0.0.1:8080}.
+func appName(name string, labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	sb.WriteString(name)
+	sb.WriteString("{")
+	for i, k := range keys {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		fmt.Fprintf(&sb, "%s=%s", k, labels[k])
+	}
+	sb.WriteString("}")
+	return sb.String()
+}
@@ -0,0 +1,371 @@
+package profiles
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	config_util "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/common/version"
+	"github.com/prometheus/prometheus/discovery"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/prometheus/prometheus/pkg/labels"
+	"github.com/prometheus/prometheus/pkg/relabel"
+)
+
+// pusher pushes collected profiles.
+type pusher interface {
+	Push(ctx context.Context, p Profile) error
+}
+
+type scrapeMetrics struct {
+	targets  *prometheus.GaugeVec
+	scrapes  *prometheus.CounterVec
+	failures *prometheus.CounterVec
+}
+
+func newScrapeMetrics(reg prometheus.Registerer) *scrapeMetrics {
+	m := &scrapeMetrics{
+		targets: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "profiles_scrape_targets",
+			Help: "Number of targets profiles are collected from.",
+		}, []string{"job"}),
+		scrapes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "profiles_scrapes_total",
+			Help: "Total number of profiles collected from targets.",
+		}, []string{"job", "profile"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "profiles_scrape_failures_total",
+			Help: "Total number of profiles which failed to be collected from targets.",
+		}, []string{"job", "profile"}),
+	}
+	reg.MustRegister(m.targets, m.scrapes, m.failures)
+	return m
+}
+
+// scraper discovers targets for a set of scrape configs and runs a scrape
+// loop for each of them.
+type scraper struct {
+	log     log.Logger
+	pusher  pusher
+	metrics *scrapeMetrics
+
+	configs map[string]*ScrapeConfig
+	clients map[string]*http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mut   sync.Mutex
+	loops map[string]map[uint64]*scrapeLoop
+}
+
+func newScraper(l log.Logger, cfgs []*ScrapeConfig, p pusher, metrics *scrapeMetrics) (*scraper, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &scraper{
+		log:     l,
+		pusher:  p,
+		metrics: metrics,
+		configs: make(map[string]*ScrapeConfig, len(cfgs)),
+		clients: make(map[string]*http.Client, len(cfgs)),
+		ctx:     ctx,
+		cancel:  cancel,
+		loops:   make(map[string]map[uint64]*scrapeLoop, len(cfgs)),
+	}
+
+	sdConfigs := make(map[string]discovery.Configs, len(cfgs))
+	for _, sc := range cfgs {
+		client, err := config_util.NewClientFromConfig(sc.HTTPClientConfig, sc.JobName)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("failed to create client for job %s: %w", sc.JobName, err)
+		}
+		s.configs[sc.JobName] = sc
+		s.clients[sc.JobName] = client
+		sdConfigs[sc.JobName] = sc.ServiceDiscoveryConfigs
+	}
+
+	mgr := discovery.NewManager(ctx, l, discovery.Name("profiles"))
+	if err := mgr.ApplyConfig(sdConfigs); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	s.wg.Add(2)
+	go func() {
+		defer s.wg.Done()
+		err := mgr.Run()
+		if err != nil && err != context.Canceled {
+			level.Error(l).Log("msg", "failed to run profiles target discovery", "err", err)
+		}
+	}()
+	go func() {
+		defer s.wg.Done()
+		for {
+			// SyncCh is never closed, so the context has to be watched too.
+			select {
+			case <-ctx.Done():
+				return
+			case tgs := <-mgr.SyncCh():
+				for job, groups := range tgs {
+					s.sync(job, groups)
+				}
+			}
+		}
+	}()
+
+	return s, nil
+}
+
+// sync starts scrape loops for new targets of job and stops the loops of
+// targets which are gone.
+func (s *scraper) sync(job string, groups []*targetgroup.Group) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	cfg, ok := s.configs[job]
+	if !ok {
+		return
+	}
+
+	oldLoops := s.loops[job]
+	newLoops := make(map[uint64]*scrapeLoop)
+
+	for _, group := range groups {
+		for _, t := range targetsFromGroup(group, cfg) {
+			hash := t.labels.Hash()
+			if _, exist := newLoops[hash]; exist {
+				continue
+			}
+			if l, exist := oldLoops[hash]; exist {
+				newLoops[hash] = l
+				continue
+			}
+
+			l := &scrapeLoop{
+				target:  t,
+				cfg:     cfg,
+				client:  s.clients[job],
+				pusher:  s.pusher,
+				metrics: s.metrics,
+				log:     log.With(s.log, "job", job, "target", t.labels.Get(model.AddressLabel)),
+			}
+			l.start(s.ctx)
+			newLoops[hash] = l
+		}
+	}
+
+	for hash, l := range oldLoops {
+		if _, exist := newLoops[hash]; !exist {
+			l.stop()
+		}
+	}
+
+	s.loops[job] = newLoops
+	s.metrics.targets.WithLabelValues(job).Set(float64(len(newLoops)))
+}
+
+// Stop stops discovering targets and every scrape loop.
+func (s *scraper) Stop() {
+	s.cancel()
+	s.wg.Wait()
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	for _, loops := range s.loops {
+		for _, l := range loops {
+			l.stop()
+		}
+	}
+	s.loops = nil
+}
+
+// target is an application profiles are collected from.
+type target struct {
+	// labels includes the internal labels of the target, which start with
+	// a double underscore.
+	labels labels.Labels
+}
+
+// targetsFromGroup returns the targets of group after relabeling.
+// Targets which are dropped or don't have an address are ignored.
+func targetsFromGroup(group *targetgroup.Group, cfg *ScrapeConfig) []*target {
+	res := make([]*target, 0, len(group.Targets))
+
+	lb := labels.NewBuilder(nil)
+	for _, tlset := range group.Targets {
+		// Discovered labels take precedence over the defaults of the job.
+		lb.Reset(nil)
+		lb.Set(model.JobLabel, cfg.JobName)
+		lb.Set(model.SchemeLabel, cfg.Scheme)
+		for ln, lv := range group.Labels {
+			lb.Set(string(ln), string(lv))
+		}
+		for ln, lv := range tlset {
+			lb.Set(string(ln), string(lv))
+		}
+
+		lset := relabel.Process(lb.Labels(), cfg.RelabelConfigs...)
+		if lset == nil {
+			continue
+		}
+		addr := lset.Get(model.AddressLabel)
+		if addr == "" {
+			continue
+		}
+		if lset.Get(model.InstanceLabel) == "" {
+			lset = labels.NewBuilder(lset).Set(model.InstanceLabel, addr).Labels()
+		}
+		res = append(res, &target{labels: lset})
+	}
+	return res
+}
+
+// URL returns the URL the profile of type profileType is collected from.
+func (t *target) URL(cfg *ScrapeConfig, profileType string) string {
+	u := url.URL{
+		Scheme: t.labels.Get(model.SchemeLabel),
+		Host:   t.labels.Get(model.AddressLabel),
+		Path:   path.Join("/", cfg.PathPrefix, profileTypes[profileType]),
+	}
+	if profileType == "cpu" {
+		seconds := int64(time.Duration(cfg.CPUProfileDuration) / time.Second)
+		u.RawQuery = url.Values{"seconds": []string{strconv.FormatInt(seconds, 10)}}.Encode()
+	}
+	return u.String()
+}
+
+// PublicLabels returns the labels of the target without its internal labels.
+func (t *target) PublicLabels() map[string]string {
+	return publicLabels(t.labels)
+}
+
+// publicLabels returns lset without its internal labels, which start with a
+// double underscore.
+func publicLabels(lset labels.Labels) map[string]string {
+	res := make(map[string]string, len(lset))
+	for _, l := range lset {
+		if strings.HasPrefix(l.Name, model.ReservedLabelPrefix) {
+			continue
+		}
+		res[l.Name] = l.Value
+	}
+	return res
+}
+
+// scrapeLoop periodically collects the profiles of a target.
+type scrapeLoop struct {
+	target  *target
+	cfg     *ScrapeConfig
+	client  *http.Client
+	pusher  pusher
+	metrics *scrapeMetrics
+	log     log.Logger
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (l *scrapeLoop) start(ctx context.Context) {
+	ctx, l.cancel = context.WithCancel(ctx)
+	l.done = make(chan struct{})
+	go l.run(ctx)
+}
+
+func (l *scrapeLoop) run(ctx context.Context) {
+	defer close(l.done)
+
+	ticker := time.NewTicker(time.Duration(l.cfg.ScrapeInterval))
+	defer ticker.Stop()
+
+	for {
+		l.scrape(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scrape collects every configured profile of the target concurrently and
+// pushes them.
+func (l *scrapeLoop) scrape(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, profileType := range l.cfg.Profiles {
+		wg.Add(1)
+		go func(profileType string) {
+			defer wg.Done()
+
+			l.metrics.scrapes.WithLabelValues(l.cfg.JobName, profileType).Inc()
+			p, err := l.fetch(ctx, profileType)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				l.metrics.failures.WithLabelValues(l.cfg.JobName, profileType).Inc()
+				level.Warn(l.log).Log("msg", "failed to collect profile", "profile", profileType, "err", err)
+				return
+			}
+			if err := l.pusher.Push(ctx, p); err != nil && ctx.Err() == nil {
+				level.Warn(l.log).Log("msg", "failed to push profile", "profile", profileType, "err", err)
+			}
+		}(profileType)
+	}
+	wg.Wait()
+}
+
+func (l *scrapeLoop) fetch(ctx context.Context, profileType string) (Profile, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(l.cfg.ScrapeTimeout))
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.target.URL(l.cfg, profileType), nil)
+	if err != nil {
+		return Profile{}, err
+	}
+	req.Header.Set("User-Agent", fmt.Sprintf("GrafanaAgent/%s", version.Version))
+
+	from := time.Now()
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return Profile{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return Profile{}, fmt.Errorf("server returned HTTP status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	return Profile{
+		Name:   l.cfg.JobName + "." + profileType,
+		Labels: l.target.PublicLabels(),
+		From:   from,
+		Until:  time.Now(),
+		Data:   data,
+	}, nil
+}
+
+func (l *scrapeLoop) stop() {
+	l.cancel()
+	<-l.done
+}
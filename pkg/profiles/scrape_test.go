@@ -0,0 +1,159 @@
+package profiles
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+)
+
+type pusherFunc func(ctx context.Context, p Profile) error
+
+func (f pusherFunc) Push(ctx context.Context, p Profile) error { return f(ctx, p) }
+
+func TestScraper(t *testing.T) {
+	requests := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		requests <- r.URL.String()
+		_, _ = rw.Write([]byte(r.URL.Path))
+	}))
+	defer srv.Close()
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+
+	cfgText := `
+job_name: checkout
+scrape_interval: 1m
+cpu_profile_duration: 5s
+profiles: [cpu, heap]
+static_configs:
+  - targets: ['` + u.Host + `']
+    labels:
+      env: prod
+`
+	var cfg ScrapeConfig
+	require.NoError(t, yaml.UnmarshalStrict([]byte(cfgText), &cfg))
+	require.NoError(t, cfg.Validate())
+
+	profiles := make(chan Profile, 10)
+	push := pusherFunc(func(_ context.Context, p Profile) error {
+		profiles <- p
+		return nil
+	})
+
+	s, err := newScraper(log.NewNopLogger(), []*ScrapeConfig{&cfg}, push, newScrapeMetrics(prometheus.NewRegistry()))
+	require.NoError(t, err)
+	defer s.Stop()
+
+	collected := map[string]Profile{}
+	for len(collected) < 2 {
+		select {
+		case p := <-profiles:
+			collected[p.Name] = p
+		case <-time.After(10 * time.Second):
+			require.FailNow(t, "timed out waiting for profiles")
+		}
+	}
+
+	expectLabels := map[string]string{"env": "prod", "instance": u.Host, "job": "checkout"}
+	require.Equal(t, expectLabels, collected["checkout.cpu"].Labels)
+	require.Equal(t, "/debug/pprof/profile", string(collected["checkout.cpu"].Data))
+	require.Equal(t, expectLabels, collected["checkout.heap"].Labels)
+	require.Equal(t, "/debug/pprof/heap", string(collected["checkout.heap"].Data))
+
+	close(requests)
+	var urls []string
+	for r := range requests {
+		urls = append(urls, r)
+	}
+	require.ElementsMatch(t, []string{"/debug/pprof/profile?seconds=5", "/debug/pprof/heap"}, urls)
+}
+
+func TestTargetsFromGroup(t *testing.T) {
+	cfgText := `
+job_name: checkout
+scheme: https
+relabel_configs:
+  - source_labels: [__meta_port]
+    regex: metrics
+    action: drop
+  - source_labels: [__meta_pod]
+    target_label: pod
+`
+	var cfg ScrapeConfig
+	require.NoError(t, yaml.UnmarshalStrict([]byte(cfgText), &cfg))
+
+	targets := targetsFromGroup(&targetgroup.Group{
+		Targets: []model.LabelSet{
+			{model.AddressLabel: "10.0.0.1:6060", "__meta_port": "pprof"},
+			{model.AddressLabel: "10.0.0.1:8080", "__meta_port": "metrics"},
+			{"__meta_port": "pprof"},
+		},
+		Labels: model.LabelSet{"__meta_pod": "checkout-0"},
+	}, &cfg)
+	require.Len(t, targets, 1)
+
+	require.Equal(t, map[string]string{
+		"instance": "10.0.0.1:6060",
+		"job":      "checkout",
+		"pod":      "checkout-0",
+	}, targets[0].PublicLabels())
+	require.Equal(t, "https://10.0.0.1:6060/debug/pprof/goroutine", targets[0].URL(&cfg, "goroutine"))
+	require.Equal(t, "https://10.0.0.1:6060/debug/pprof/profile?seconds=10", targets[0].URL(&cfg, "cpu"))
+}
+
+func TestScrapeConfig_Validate(t *testing.T) {
+	tt := []struct {
+		name          string
+		cfg           string
+		expectedError string
+	}{
+		{
+			name: "defaults",
+			cfg:  `job_name: checkout`,
+		},
+		{
+			name:          "unknown profile",
+			cfg:           "job_name: checkout\nprofiles: [cpu, threadcreate]",
+			expectedError: `unsupported profile type "threadcreate", must be one of allocs, block, cpu, goroutine, heap, mutex`,
+		},
+		{
+			name:          "cpu profile too long",
+			cfg:           "job_name: checkout\ncpu_profile_duration: 15s",
+			expectedError: "cpu_profile_duration must be at least 1s and shorter than scrape_timeout",
+		},
+		{
+			name: "cpu profile not collected",
+			cfg:  "job_name: checkout\ncpu_profile_duration: 15s\nprofiles: [heap]",
+		},
+		{
+			name:          "timeout too long",
+			cfg:           "job_name: checkout\nscrape_timeout: 30s",
+			expectedError: "scrape_timeout must be greater than zero and not greater than scrape_interval",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg ScrapeConfig
+			require.NoError(t, yaml.UnmarshalStrict([]byte(tc.cfg), &cfg))
+
+			err := cfg.Validate()
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
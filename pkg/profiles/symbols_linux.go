@@ -0,0 +1,268 @@
+//go:build linux
+// +build linux
+
+package profiles
+
+import (
+	"bufio"
+	"debug/elf"
+	"debug/gosym"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// symbol is a function of a symbol table.
+type symbol struct {
+	addr uint64
+	size uint64 // Zero if unknown.
+	name string
+}
+
+// symbolTable resolves addresses to the functions containing them. It's
+// sorted by address.
+type symbolTable []symbol
+
+func newSymbolTable(syms []symbol) symbolTable {
+	sort.Slice(syms, func(i, j int) bool { return syms[i].addr < syms[j].addr })
+	return syms
+}
+
+// Lookup returns the name of the function containing addr.
+func (t symbolTable) Lookup(addr uint64) (string, bool) {
+	i := sort.Search(len(t), func(i int) bool { return t[i].addr > addr }) - 1
+	if i < 0 {
+		return "", false
+	}
+	if s := t[i]; s.size == 0 || addr < s.addr+s.size {
+		return s.name, true
+	}
+	return "", false
+}
+
+// readKallsyms reads the functions of the kernel from r, which has the
+// format of /proc/kallsyms. Addresses hidden by kptr_restrict are ignored.
+func readKallsyms(r io.Reader) (symbolTable, error) {
+	var syms []symbol
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		switch fields[1] {
+		case "t", "T", "w", "W":
+		default:
+			continue
+		}
+		addr, err := strconv.ParseUint(fields[0], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", fields[0], err)
+		}
+		if addr == 0 {
+			continue
+		}
+		syms = append(syms, symbol{addr: addr, name: fields[2]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return newSymbolTable(syms), nil
+}
+
+// elfFile holds the functions of an executable or shared library.
+type elfFile struct {
+	symbols symbolTable
+	// progs are the executable segments, which map file offsets to the
+	// addresses of the symbols.
+	progs []elf.ProgHeader
+}
+
+func openELF(path string) (*elfFile, error) {
+	f, err := elf.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Binaries may be stripped of either symbol table.
+	syms, _ := f.Symbols()
+	dynSyms, _ := f.DynamicSymbols()
+
+	var funcs []symbol
+	for _, s := range append(syms, dynSyms...) {
+		if elf.ST_TYPE(s.Info) != elf.STT_FUNC || s.Value == 0 {
+			continue
+		}
+		funcs = append(funcs, symbol{addr: s.Value, size: s.Size, name: s.Name})
+	}
+	if len(funcs) == 0 {
+		funcs = goFuncs(f)
+	}
+
+	ef := &elfFile{symbols: newSymbolTable(funcs)}
+	for _, p := range f.Progs {
+		if p.Type == elf.PT_LOAD && p.Flags&elf.PF_X != 0 {
+			ef.progs = append(ef.progs, p.ProgHeader)
+		}
+	}
+	return ef, nil
+}
+
+// goFuncs returns the functions of a Go binary from its .gopclntab section,
+// which is kept when the binary is stripped of its symbol tables.
+func goFuncs(f *elf.File) []symbol {
+	pclntab, text := f.Section(".gopclntab"), f.Section(".text")
+	if pclntab == nil || text == nil {
+		return nil
+	}
+	data, err := pclntab.Data()
+	if err != nil {
+		return nil
+	}
+	tab, err := gosym.NewTable(nil, gosym.NewLineTable(data, text.Addr))
+	if err != nil {
+		return nil
+	}
+
+	funcs := make([]symbol, 0, len(tab.Funcs))
+	for _, fn := range tab.Funcs {
+		funcs = append(funcs, symbol{addr: fn.Entry, size: fn.End - fn.Entry, name: fn.Name})
+	}
+	return funcs
+}
+
+// Lookup returns the name of the function at the file offset off.
+func (f *elfFile) Lookup(off uint64) (string, bool) {
+	for _, p := range f.progs {
+		if off >= p.Off && off < p.Off+p.Filesz {
+			return f.symbols.Lookup(off - p.Off + p.Vaddr)
+		}
+	}
+	return "", false
+}
+
+// mapping is an executable file mapped into the memory of a process.
+type mapping struct {
+	start, end uint64
+	offset     uint64
+	path       string
+}
+
+// readMappings reads the executable file mappings from r, which has the
+// format of /proc/<pid>/maps.
+func readMappings(r io.Reader) ([]mapping, error) {
+	var res []mapping
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 6 || len(fields[1]) < 3 || fields[1][2] != 'x' {
+			continue
+		}
+		path := strings.Join(fields[5:], " ")
+		if !strings.HasPrefix(path, "/") {
+			continue
+		}
+
+		var m mapping
+		if _, err := fmt.Sscanf(fields[0], "%x-%x", &m.start, &m.end); err != nil {
+			return nil, fmt.Errorf("invalid address range %q: %w", fields[0], err)
+		}
+		offset, err := strconv.ParseUint(fields[2], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid offset %q: %w", fields[2], err)
+		}
+		m.offset = offset
+		m.path = path
+		res = append(res, m)
+	}
+	return res, scanner.Err()
+}
+
+// fileKey identifies a file across mount namespaces.
+type fileKey struct {
+	dev, ino uint64
+}
+
+// symbolizer resolves the addresses of sampled stacks to function names.
+// The functions of files are cached until a collection doesn't use them.
+type symbolizer struct {
+	kernel symbolTable
+
+	files map[fileKey]*elfFile // nil if the file couldn't be read.
+	used  map[fileKey]struct{}
+}
+
+func newSymbolizer(kernel symbolTable) *symbolizer {
+	return &symbolizer{
+		kernel: kernel,
+		files:  make(map[fileKey]*elfFile),
+		used:   make(map[fileKey]struct{}),
+	}
+}
+
+// Kernel returns the name of the kernel function at addr.
+func (s *symbolizer) Kernel(addr uint64) string {
+	if name, ok := s.kernel.Lookup(addr); ok {
+		return name + "_[k]"
+	}
+	return "[kernel]"
+}
+
+// User returns the name of the function at addr in the process pid with the
+// given mappings.
+func (s *symbolizer) User(pid int, mappings []mapping, addr uint64) string {
+	i := sort.Search(len(mappings), func(i int) bool { return mappings[i].end > addr })
+	if i == len(mappings) || addr < mappings[i].start {
+		return "[unknown]"
+	}
+	m := mappings[i]
+
+	if f := s.file(pid, m.path); f != nil {
+		if name, ok := f.Lookup(addr - m.start + m.offset); ok {
+			return name
+		}
+	}
+	return "[" + filepath.Base(m.path) + "]"
+}
+
+// file returns the functions of the file at path in the mount namespace of
+// the process pid.
+func (s *symbolizer) file(pid int, path string) *elfFile {
+	path = fmt.Sprintf("/proc/%d/root%s", pid, path)
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	key := fileKey{dev: uint64(st.Dev), ino: st.Ino}
+	s.used[key] = struct{}{}
+
+	f, ok := s.files[key]
+	if !ok {
+		f, _ = openELF(path)
+		s.files[key] = f
+	}
+	return f
+}
+
+// Prune removes the files which weren't used since the last call to Prune
+// from the cache.
+func (s *symbolizer) Prune() {
+	for key := range s.files {
+		if _, ok := s.used[key]; !ok {
+			delete(s.files, key)
+		}
+	}
+	s.used = make(map[fileKey]struct{})
+}
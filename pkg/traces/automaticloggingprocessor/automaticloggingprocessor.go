@@ -1,10 +1,12 @@
 package automaticloggingprocessor
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"strconv"
+	"text/template"
 	"time"
 
 	util "github.com/cortexproject/cortex/pkg/util/log"
@@ -23,6 +25,7 @@ import (
 	"go.opentelemetry.io/collector/model/pdata"
 	semconv "go.opentelemetry.io/collector/model/semconv/v1.6.1"
 	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
 )
 
 const (
@@ -32,12 +35,14 @@ const (
 	defaultStatusKey   = "status"
 	defaultDurationKey = "dur"
 	defaultTraceIDKey  = "tid"
+	defaultEventKey    = "event"
 
 	defaultTimeout = time.Millisecond
 
 	typeSpan    = "span"
 	typeRoot    = "root"
 	typeProcess = "process"
+	typeEvent   = "event"
 )
 
 type automaticLoggingProcessor struct {
@@ -50,6 +55,9 @@ type automaticLoggingProcessor struct {
 
 	labels map[string]struct{}
 
+	lineTemplate *template.Template
+	limiter      *rate.Limiter
+
 	logger log.Logger
 }
 
@@ -60,8 +68,8 @@ func newTraceProcessor(nextConsumer consumer.Traces, cfg *AutomaticLoggingConfig
 		return nil, componenterror.ErrNilNextConsumer
 	}
 
-	if !cfg.Roots && !cfg.Processes && !cfg.Spans {
-		return nil, errors.New("automaticLoggingProcessor requires one of roots, processes, or spans to be enabled")
+	if !cfg.Roots && !cfg.Processes && !cfg.Spans && !cfg.SpanEvents {
+		return nil, errors.New("automaticLoggingProcessor requires one of roots, processes, spans, or span_events to be enabled")
 	}
 
 	if cfg.Timeout == 0 {
@@ -87,12 +95,36 @@ func newTraceProcessor(nextConsumer consumer.Traces, cfg *AutomaticLoggingConfig
 	cfg.Overrides.StatusKey = override(cfg.Overrides.StatusKey, defaultStatusKey)
 	cfg.Overrides.DurationKey = override(cfg.Overrides.DurationKey, defaultDurationKey)
 	cfg.Overrides.TraceIDKey = override(cfg.Overrides.TraceIDKey, defaultTraceIDKey)
+	cfg.Overrides.EventNameKey = override(cfg.Overrides.EventNameKey, defaultEventKey)
 
 	labels := make(map[string]struct{}, len(cfg.Labels))
 	for _, l := range cfg.Labels {
 		labels[l] = struct{}{}
 	}
 
+	var lineTemplate *template.Template
+	if cfg.LineTemplate != "" {
+		var err error
+		if lineTemplate, err = newLineTemplate(cfg.LineTemplate); err != nil {
+			return nil, err
+		}
+	}
+
+	var limiter *rate.Limiter
+	if cfg.RateLimit != nil {
+		if cfg.RateLimit.Rate <= 0 {
+			return nil, errors.New("automaticLoggingProcessor requires rate_limit.rate to be greater than 0")
+		}
+		burst := cfg.RateLimit.Burst
+		if burst <= 0 {
+			burst = int(cfg.RateLimit.Rate)
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		limiter = rate.NewLimiter(rate.Limit(cfg.RateLimit.Rate), burst)
+	}
+
 	return &automaticLoggingProcessor{
 		nextConsumer: nextConsumer,
 		cfg:          cfg,
@@ -100,9 +132,19 @@ func newTraceProcessor(nextConsumer consumer.Traces, cfg *AutomaticLoggingConfig
 		logger:       logger,
 		done:         atomic.Bool{},
 		labels:       labels,
+		lineTemplate: lineTemplate,
+		limiter:      limiter,
 	}, nil
 }
 
+func newLineTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("line_template").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse line_template: %w", err)
+	}
+	return tmpl, nil
+}
+
 func (p *automaticLoggingProcessor) ConsumeTraces(ctx context.Context, td pdata.Traces) error {
 	rsLen := td.ResourceSpans().Len()
 	for i := 0; i < rsLen; i++ {
@@ -134,6 +176,14 @@ func (p *automaticLoggingProcessor) ConsumeTraces(ctx context.Context, td pdata.
 					p.exportToLogsInstance(typeRoot, traceID, p.spanLabels(keyValues), keyValues...)
 				}
 
+				if p.cfg.SpanEvents {
+					events := span.Events()
+					for l := 0; l < events.Len(); l++ {
+						keyValues := append(p.eventKeyVals(span, events.At(l)), p.processKeyVals(rs.Resource(), svc)...)
+						p.exportToLogsInstance(typeEvent, traceID, p.spanLabels(keyValues), keyValues...)
+					}
+				}
+
 				if p.cfg.Processes && lastTraceID != traceID {
 					lastTraceID = traceID
 					keyValues := p.processKeyVals(rs.Resource(), svc)
@@ -249,20 +299,49 @@ func (p *automaticLoggingProcessor) spanKeyVals(span pdata.Span) []interface{} {
 	return atts
 }
 
+func (p *automaticLoggingProcessor) eventKeyVals(span pdata.Span, event pdata.SpanEvent) []interface{} {
+	atts := make([]interface{}, 0, 4) // 4 for span name and event name
+
+	atts = append(atts, p.cfg.Overrides.SpanNameKey)
+	atts = append(atts, span.Name())
+
+	atts = append(atts, p.cfg.Overrides.EventNameKey)
+	atts = append(atts, event.Name())
+
+	for _, name := range p.cfg.EventAttributes {
+		att, ok := event.Attributes().Get(name)
+		if ok {
+			atts = append(atts, name)
+			atts = append(atts, attributeValue(att))
+		}
+	}
+
+	return atts
+}
+
 func (p *automaticLoggingProcessor) exportToLogsInstance(kind string, traceID string, labels model.LabelSet, keyvals ...interface{}) {
 	if p.done.Load() {
 		return
 	}
 
+	// Lines over the rate limit are dropped.
+	if p.limiter != nil && !p.limiter.Allow() {
+		return
+	}
+
 	keyvals = append(keyvals, []interface{}{p.cfg.Overrides.TraceIDKey, traceID}...)
-	line, err := logfmt.MarshalKeyvals(keyvals...)
+	line, err := p.formatLine(keyvals)
 	if err != nil {
-		level.Warn(p.logger).Log("msg", "unable to marshal keyvals", "err", err)
+		level.Warn(p.logger).Log("msg", "unable to format log line", "err", err)
 		return
 	}
 
 	// if we're logging to stdout, log and bail
 	if p.logToStdout {
+		if p.lineTemplate != nil {
+			level.Info(p.logger).Log("msg", string(line))
+			return
+		}
 		level.Info(p.logger).Log(keyvals...)
 		return
 	}
@@ -283,6 +362,28 @@ func (p *automaticLoggingProcessor) exportToLogsInstance(kind string, traceID st
 	}
 }
 
+// formatLine formats keyvals as logfmt, or with the line template when one is
+// configured. The template is executed with a map of the keyvals, where values
+// are formatted as strings so missing keys render as empty strings.
+func (p *automaticLoggingProcessor) formatLine(keyvals []interface{}) ([]byte, error) {
+	if p.lineTemplate == nil {
+		return logfmt.MarshalKeyvals(keyvals...)
+	}
+
+	data := make(map[string]string, len(keyvals)/2)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if k, ok := keyvals[i].(string); ok {
+			data[k] = fmt.Sprintf("%v", keyvals[i+1])
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := p.lineTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 func spanDuration(span pdata.Span) string {
 	dur := int64(span.EndTimestamp() - span.StartTimestamp())
 	return strconv.FormatInt(dur, 10) + "ns"
@@ -198,6 +198,18 @@ func TestBadConfigs(t *testing.T) {
 				Backend: "stdout",
 			},
 		},
+		{
+			cfg: &AutomaticLoggingConfig{
+				Spans:        true,
+				LineTemplate: "{{ .span",
+			},
+		},
+		{
+			cfg: &AutomaticLoggingConfig{
+				Spans:     true,
+				RateLimit: &RateLimitConfig{},
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -247,6 +259,62 @@ func TestDefaults(t *testing.T) {
 	require.Equal(t, defaultStatusKey, p.(*automaticLoggingProcessor).cfg.Overrides.StatusKey)
 	require.Equal(t, defaultDurationKey, p.(*automaticLoggingProcessor).cfg.Overrides.DurationKey)
 	require.Equal(t, defaultTraceIDKey, p.(*automaticLoggingProcessor).cfg.Overrides.TraceIDKey)
+	require.Equal(t, defaultEventKey, p.(*automaticLoggingProcessor).cfg.Overrides.EventNameKey)
+}
+
+func TestEventKeyVals(t *testing.T) {
+	cfg := &AutomaticLoggingConfig{
+		SpanEvents:      true,
+		EventAttributes: []string{"exception.type", "missing"},
+	}
+	p, err := newTraceProcessor(&automaticLoggingProcessor{}, cfg)
+	require.NoError(t, err)
+
+	span := pdata.NewSpan()
+	span.SetName("checkout")
+	event := span.Events().AppendEmpty()
+	event.SetName("exception")
+	event.Attributes().InsertString("exception.type", "NullPointerException")
+	event.Attributes().InsertString("exception.message", "boom")
+
+	actual := p.(*automaticLoggingProcessor).eventKeyVals(span, event)
+	require.Equal(t, []interface{}{
+		"span", "checkout",
+		"event", "exception",
+		"exception.type", "NullPointerException",
+	}, actual)
+}
+
+func TestFormatLine(t *testing.T) {
+	keyvals := []interface{}{"span", "checkout", "status", pdata.StatusCodeError, "http.method", "GET", "tid", "1234"}
+
+	p, err := newTraceProcessor(&automaticLoggingProcessor{}, &AutomaticLoggingConfig{Spans: true})
+	require.NoError(t, err)
+	line, err := p.(*automaticLoggingProcessor).formatLine(keyvals)
+	require.NoError(t, err)
+	require.Equal(t, "span=checkout status=STATUS_CODE_ERROR http.method=GET tid=1234", string(line))
+
+	p, err = newTraceProcessor(&automaticLoggingProcessor{}, &AutomaticLoggingConfig{
+		Spans:        true,
+		LineTemplate: `{{ .span }} failed with {{ .status }} ({{ index . "http.method" }}) {{ .missing }}trace={{ .tid }}`,
+	})
+	require.NoError(t, err)
+	line, err = p.(*automaticLoggingProcessor).formatLine(keyvals)
+	require.NoError(t, err)
+	require.Equal(t, "checkout failed with STATUS_CODE_ERROR (GET) trace=1234", string(line))
+}
+
+func TestRateLimit(t *testing.T) {
+	p, err := newTraceProcessor(&automaticLoggingProcessor{}, &AutomaticLoggingConfig{
+		Spans:     true,
+		RateLimit: &RateLimitConfig{Rate: 0.001, Burst: 2},
+	})
+	require.NoError(t, err)
+
+	limiter := p.(*automaticLoggingProcessor).limiter
+	require.True(t, limiter.Allow())
+	require.True(t, limiter.Allow())
+	require.False(t, limiter.Allow())
 }
 
 func TestLokiNameMigration(t *testing.T) {
@@ -29,11 +29,16 @@ type AutomaticLoggingConfig struct {
 	Spans             bool           `mapstructure:"spans" yaml:"spans,omitempty"`
 	Roots             bool           `mapstructure:"roots" yaml:"roots,omitempty"`
 	Processes         bool           `mapstructure:"processes" yaml:"processes,omitempty"`
+	SpanEvents        bool           `mapstructure:"span_events" yaml:"span_events,omitempty"`
 	SpanAttributes    []string       `mapstructure:"span_attributes" yaml:"span_attributes,omitempty"`
 	ProcessAttributes []string       `mapstructure:"process_attributes" yaml:"process_attributes,omitempty"`
+	EventAttributes   []string       `mapstructure:"event_attributes" yaml:"event_attributes,omitempty"`
 	Overrides         OverrideConfig `mapstructure:"overrides" yaml:"overrides,omitempty"`
 	Timeout           time.Duration  `mapstructure:"timeout" yaml:"timeout,omitempty"`
 	Labels            []string       `mapstructure:"labels" yaml:"labels,omitempty"`
+	// LineTemplate is a Go template used to format log lines instead of logfmt.
+	LineTemplate string           `mapstructure:"line_template" yaml:"line_template,omitempty"`
+	RateLimit    *RateLimitConfig `mapstructure:"rate_limit" yaml:"rate_limit,omitempty"`
 
 	// Deprecated fields:
 	LokiName string `mapstructure:"loki_name" yaml:"loki_name,omitempty"` // Superseded by LogsName
@@ -49,6 +54,16 @@ func (c *AutomaticLoggingConfig) Validate(logsConfig *logs.Config) error {
 		return fmt.Errorf("must configure at most one of logs_instance_name and loki_name. loki_name is deprecated in favor of logs_instance_name")
 	}
 
+	if c.LineTemplate != "" {
+		if _, err := newLineTemplate(c.LineTemplate); err != nil {
+			return err
+		}
+	}
+
+	if c.RateLimit != nil && c.RateLimit.Rate <= 0 {
+		return fmt.Errorf("rate_limit.rate must be greater than 0")
+	}
+
 	if c.LogsName != "" && logsConfig == nil {
 		return fmt.Errorf("logs instance %s is set but no logs config is provided", c.LogsName)
 	}
@@ -84,14 +99,24 @@ func (c *AutomaticLoggingConfig) Validate(logsConfig *logs.Config) error {
 	return nil
 }
 
+// RateLimitConfig limits the number of log lines written
+type RateLimitConfig struct {
+	// Rate is the number of lines per second that may be written.
+	Rate float64 `mapstructure:"rate" yaml:"rate"`
+	// Burst is the number of lines that may be written at once. Defaults to
+	// Rate.
+	Burst int `mapstructure:"burst" yaml:"burst,omitempty"`
+}
+
 // OverrideConfig contains overrides for various strings
 type OverrideConfig struct {
-	LogsTag     string `mapstructure:"logs_instance_tag" yaml:"logs_instance_tag,omitempty"`
-	ServiceKey  string `mapstructure:"service_key" yaml:"service_key,omitempty"`
-	SpanNameKey string `mapstructure:"span_name_key" yaml:"span_name_key,omitempty"`
-	StatusKey   string `mapstructure:"status_key" yaml:"status_key,omitempty"`
-	DurationKey string `mapstructure:"duration_key" yaml:"duration_key,omitempty"`
-	TraceIDKey  string `mapstructure:"trace_id_key" yaml:"trace_id_key,omitempty"`
+	LogsTag      string `mapstructure:"logs_instance_tag" yaml:"logs_instance_tag,omitempty"`
+	ServiceKey   string `mapstructure:"service_key" yaml:"service_key,omitempty"`
+	SpanNameKey  string `mapstructure:"span_name_key" yaml:"span_name_key,omitempty"`
+	StatusKey    string `mapstructure:"status_key" yaml:"status_key,omitempty"`
+	DurationKey  string `mapstructure:"duration_key" yaml:"duration_key,omitempty"`
+	TraceIDKey   string `mapstructure:"trace_id_key" yaml:"trace_id_key,omitempty"`
+	EventNameKey string `mapstructure:"event_name_key" yaml:"event_name_key,omitempty"`
 
 	// Deprecated fields:
 	LokiTag string `mapstructure:"loki_tag" yaml:"loki_tag,omitempty"` // Superseded by LogsTag
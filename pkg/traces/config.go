@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net"
+	"os"
 	"sort"
 	"strings"
 	"time"
@@ -35,17 +36,28 @@ import (
 	"go.uber.org/multierr"
 
 	"github.com/grafana/agent/pkg/logs"
+	"github.com/grafana/agent/pkg/metrics"
 	"github.com/grafana/agent/pkg/traces/automaticloggingprocessor"
+	"github.com/grafana/agent/pkg/traces/datadogreceiver"
+	"github.com/grafana/agent/pkg/traces/logsexporter"
 	"github.com/grafana/agent/pkg/traces/noopreceiver"
 	"github.com/grafana/agent/pkg/traces/promsdprocessor"
+	"github.com/grafana/agent/pkg/traces/redactionprocessor"
 	"github.com/grafana/agent/pkg/traces/remotewriteexporter"
 	"github.com/grafana/agent/pkg/traces/servicegraphprocessor"
+	"github.com/grafana/agent/pkg/traces/xrayreceiver"
 	"github.com/grafana/agent/pkg/util"
 )
 
 const (
 	spanMetricsPipelineName = "metrics/spanmetrics"
 
+	// Names of the receiver, pipelines and exporter of unified_otlp.
+	unifiedOTLPReceiverName        = "otlp/unified"
+	unifiedOTLPMetricsPipelineName = "metrics/otlp"
+	unifiedOTLPLogsPipelineName    = "logs/otlp"
+	unifiedOTLPMetricsExporterName = "remote_write/otlp"
+
 	// defaultDecisionWait is the default time to wait for a trace before making a sampling decision
 	defaultDecisionWait = time.Second * 5
 
@@ -75,7 +87,7 @@ func (c *Config) UnmarshalYAML(unmarshal func(interface{}) error) error {
 }
 
 // Validate ensures that the Config is valid.
-func (c *Config) Validate(logsConfig *logs.Config) error {
+func (c *Config) Validate(logsConfig *logs.Config, metricsConfig *metrics.Config) error {
 	names := make(map[string]struct{}, len(c.Configs))
 	for idx, c := range c.Configs {
 		if c.Name == "" {
@@ -93,6 +105,21 @@ func (c *Config) Validate(logsConfig *logs.Config) error {
 				return fmt.Errorf("failed to validate automatic_logging for traces config %s: %w", inst.Name, err)
 			}
 		}
+		if inst.Redaction != nil {
+			if err := inst.Redaction.Validate(); err != nil {
+				return fmt.Errorf("failed to validate redaction for traces config %s: %w", inst.Name, err)
+			}
+		}
+		if inst.SpanMetrics != nil {
+			if err := inst.SpanMetrics.Validate(metricsConfig); err != nil {
+				return fmt.Errorf("failed to validate spanmetrics for traces config %s: %w", inst.Name, err)
+			}
+		}
+		if inst.UnifiedOTLP != nil {
+			if err := inst.UnifiedOTLP.Validate(logsConfig, metricsConfig); err != nil {
+				return fmt.Errorf("failed to validate unified_otlp for traces config %s: %w", inst.Name, err)
+			}
+		}
 	}
 
 	return nil
@@ -114,6 +141,9 @@ type InstanceConfig struct {
 	// Attributes: https://github.com/open-telemetry/opentelemetry-collector/blob/7d7ae2eb34b5d387627875c498d7f43619f37ee3/processor/attributesprocessor/config.go#L30
 	Attributes map[string]interface{} `yaml:"attributes,omitempty"`
 
+	// Redaction deletes, hashes or redacts span attributes before they leave the pipeline.
+	Redaction *redactionConfig `yaml:"redaction,omitempty"`
+
 	// prom service discovery config
 	ScrapeConfigs   []interface{} `yaml:"scrape_configs,omitempty"`
 	OperationType   string        `yaml:"prom_sd_operation_type,omitempty"`
@@ -133,6 +163,9 @@ type InstanceConfig struct {
 
 	// ServiceGraphs
 	ServiceGraphs *serviceGraphsConfig `yaml:"service_graphs,omitempty"`
+
+	// UnifiedOTLP receives metrics, logs and traces on a single OTLP listener.
+	UnifiedOTLP *unifiedOTLPConfig `yaml:"unified_otlp,omitempty"`
 }
 
 // ReceiverMap stores a set of receivers. Because receivers may be configured
@@ -148,6 +181,7 @@ func (r ReceiverMap) MarshalYAML() (interface{}, error) {
 const (
 	compressionNone = "none"
 	compressionGzip = "gzip"
+	compressionZstd = "zstd"
 	protocolGRPC    = "grpc"
 	protocolHTTP    = "http"
 )
@@ -220,6 +254,8 @@ type RemoteWriteConfig struct {
 	TLSConfig          *prom_config.TLSConfig `yaml:"tls_config,omitempty"`
 	BasicAuth          *prom_config.BasicAuth `yaml:"basic_auth,omitempty"`
 	Oauth2             *OAuth2Config          `yaml:"oauth2,omitempty"`
+	BearerToken        prom_config.Secret     `yaml:"bearer_token,omitempty"`
+	BearerTokenFile    string                 `yaml:"bearer_token_file,omitempty"`
 	Headers            map[string]string      `yaml:"headers,omitempty"`
 	SendingQueue       map[string]interface{} `yaml:"sending_queue,omitempty"`    // https://github.com/open-telemetry/opentelemetry-collector/blob/7d7ae2eb34b5d387627875c498d7f43619f37ee3/exporter/exporterhelper/queued_retry.go#L30
 	RetryOnFailure     map[string]interface{} `yaml:"retry_on_failure,omitempty"` // https://github.com/open-telemetry/opentelemetry-collector/blob/7d7ae2eb34b5d387627875c498d7f43619f37ee3/exporter/exporterhelper/queued_retry.go#L54
@@ -235,8 +271,15 @@ func (c *RemoteWriteConfig) UnmarshalYAML(unmarshal func(interface{}) error) err
 		return err
 	}
 
-	if c.Compression != compressionGzip && c.Compression != compressionNone {
-		return fmt.Errorf("unsupported compression '%s', expected 'gzip' or 'none'", c.Compression)
+	switch c.Compression {
+	case compressionGzip, compressionNone:
+	case compressionZstd:
+		// The OTLP HTTP exporter only supports gzip.
+		if c.Protocol != protocolGRPC {
+			return fmt.Errorf("compression 'zstd' is only supported with the 'grpc' protocol")
+		}
+	default:
+		return fmt.Errorf("unsupported compression '%s', expected 'gzip', 'zstd' or 'none'", c.Compression)
 	}
 
 	if c.Format != formatOtlp && c.Format != formatJaeger {
@@ -259,6 +302,82 @@ type SpanMetricsConfig struct {
 	HandlerEndpoint string `yaml:"handler_endpoint"`
 }
 
+// Validate ensures that the metrics are exported exactly one way, and that
+// the metrics instance they're pushed to exists.
+func (c *SpanMetricsConfig) Validate(metricsConfig *metrics.Config) error {
+	if (c.MetricsInstance == "") == (c.HandlerEndpoint == "") {
+		return fmt.Errorf("must specify a prometheus instance or a metrics handler endpoint to export the metrics")
+	}
+	if c.MetricsInstance == "" {
+		return nil
+	}
+
+	// Instances of the scraping service are created at runtime, so they
+	// can't be checked.
+	if metricsConfig == nil || metricsConfig.ServiceConfig.Enabled {
+		return nil
+	}
+	for _, inst := range metricsConfig.Configs {
+		if inst.Name == c.MetricsInstance {
+			return nil
+		}
+	}
+	return fmt.Errorf("specified metrics instance %s not found in agent config", c.MetricsInstance)
+}
+
+// unifiedOTLPConfig configures an OTLP receiver that accepts all signals.
+// Traces are sent through the pipeline of the traces config, metrics to a
+// metrics instance and logs to a logs instance.
+type unifiedOTLPConfig struct {
+	// Protocols configures the grpc and http protocols like the otlp
+	// receiver. Both are enabled with their default endpoints when empty.
+	Protocols map[string]interface{} `yaml:"protocols,omitempty"`
+
+	MetricsInstance string `yaml:"metrics_instance,omitempty"`
+	LogsInstance    string `yaml:"logs_instance,omitempty"`
+}
+
+// Validate ensures that the unifiedOTLPConfig is valid.
+func (c *unifiedOTLPConfig) Validate(logsConfig *logs.Config, metricsConfig *metrics.Config) error {
+	for protocol := range c.Protocols {
+		if protocol != protocolGRPC && protocol != protocolHTTP {
+			return fmt.Errorf("unsupported protocol '%s', expected 'grpc' or 'http'", protocol)
+		}
+	}
+
+	if c.MetricsInstance != "" && metricsConfig != nil && !metricsConfig.ServiceConfig.Enabled {
+		found := false
+		for _, inst := range metricsConfig.Configs {
+			found = found || inst.Name == c.MetricsInstance
+		}
+		if !found {
+			return fmt.Errorf("specified metrics instance %s not found in agent config", c.MetricsInstance)
+		}
+	}
+
+	if c.LogsInstance != "" {
+		if logsConfig == nil {
+			return fmt.Errorf("logs instance %s is set but no logs config is provided", c.LogsInstance)
+		}
+		found := false
+		for _, inst := range logsConfig.Configs {
+			found = found || inst.Name == c.LogsInstance
+		}
+		if !found {
+			return fmt.Errorf("specified logs instance %s not found in agent config", c.LogsInstance)
+		}
+	}
+	return nil
+}
+
+// protocols returns the protocols of the otlp receiver.
+func (c *unifiedOTLPConfig) protocols() map[string]interface{} {
+	if len(c.Protocols) == 0 {
+		return map[string]interface{}{protocolGRPC: nil, protocolHTTP: nil}
+	}
+	return c.Protocols
+}
+
 // tailSamplingConfig is the configuration for tail-based sampling
 type tailSamplingConfig struct {
 	// Policies are the strategies used for sampling. Multiple policies can be used in the same pipeline.
@@ -275,6 +394,9 @@ type loadBalancingConfig struct {
 	Resolver map[string]interface{} `yaml:"resolver"`
 	// ReceiverPort is the port the instance will use to receive load balanced traces
 	ReceiverPort string `yaml:"receiver_port"`
+
+	// peers are the addresses discovered by the kubernetes resolver.
+	peers []string
 }
 
 // exporterConfig defined the config for a otlp exporter for load balancing
@@ -286,6 +408,21 @@ type exporterConfig struct {
 	Format             string                 `yaml:"format,omitempty"`
 }
 
+// redactionConfig configures the redaction processor.
+type redactionConfig struct {
+	Rules       []redactionprocessor.Rule `yaml:"rules"`
+	Placeholder string                    `yaml:"placeholder,omitempty"`
+}
+
+// Validate ensures that the rules are valid.
+func (c *redactionConfig) Validate() error {
+	if len(c.Rules) == 0 {
+		return errors.New("must configure at least one rule")
+	}
+	cfg := redactionprocessor.Config{Rules: c.Rules}
+	return cfg.Validate()
+}
+
 type serviceGraphsConfig struct {
 	Enabled  bool          `yaml:"enabled,omitempty"`
 	Wait     time.Duration `yaml:"wait,omitempty"`
@@ -303,8 +440,21 @@ func exporter(rwCfg RemoteWriteConfig) (map[string]interface{}, error) {
 		headers = rwCfg.Headers
 	}
 
-	if rwCfg.BasicAuth != nil && rwCfg.Oauth2 != nil {
-		return nil, fmt.Errorf("Only one auth type may be configured per exporter (basic_auth or oauth2)")
+	var authTypes int
+	for _, configured := range []bool{
+		rwCfg.BasicAuth != nil,
+		rwCfg.Oauth2 != nil,
+		rwCfg.BearerToken != "" || rwCfg.BearerTokenFile != "",
+	} {
+		if configured {
+			authTypes++
+		}
+	}
+	if authTypes > 1 {
+		return nil, fmt.Errorf("Only one auth type may be configured per exporter (basic_auth, oauth2 or bearer_token)")
+	}
+	if rwCfg.BearerToken != "" && rwCfg.BearerTokenFile != "" {
+		return nil, fmt.Errorf("at most one of bearer_token and bearer_token_file may be configured")
 	}
 
 	if rwCfg.BasicAuth != nil {
@@ -322,6 +472,20 @@ func exporter(rwCfg RemoteWriteConfig) (map[string]interface{}, error) {
 		headers["authorization"] = "Basic " + encodedAuth
 	}
 
+	if rwCfg.BearerToken != "" || rwCfg.BearerTokenFile != "" {
+		token := string(rwCfg.BearerToken)
+
+		if len(rwCfg.BearerTokenFile) > 0 {
+			buff, err := ioutil.ReadFile(rwCfg.BearerTokenFile)
+			if err != nil {
+				return nil, fmt.Errorf("unable to load bearer token file %s: %w", rwCfg.BearerTokenFile, err)
+			}
+			token = strings.TrimSpace(string(buff))
+		}
+
+		headers["authorization"] = "Bearer " + token
+	}
+
 	compression := rwCfg.Compression
 	if compression == compressionNone {
 		compression = ""
@@ -439,15 +603,24 @@ func (c *InstanceConfig) extensions() (map[string]interface{}, error) {
 	return extensions, nil
 }
 
-func resolver(config map[string]interface{}) (map[string]interface{}, error) {
+func resolver(config map[string]interface{}, peers []string) (map[string]interface{}, error) {
 	if len(config) == 0 {
-		return nil, fmt.Errorf("must configure one resolver (dns or static)")
+		return nil, fmt.Errorf("must configure one resolver (dns, static or kubernetes)")
 	}
 	resolverCfg := make(map[string]interface{})
 	for typ, cfg := range config {
 		switch typ {
 		case dnsTagName, staticTagName:
 			resolverCfg[typ] = cfg
+		case kubernetesTagName:
+			// The OTel exporter can't discover peers in Kubernetes, so the
+			// discovered peers are passed as a static list.
+			if len(peers) == 0 {
+				return nil, fmt.Errorf("no load balancing peers discovered yet")
+			}
+			resolverCfg[staticTagName] = map[string]interface{}{
+				"hostnames": peers,
+			}
 		default:
 			return nil, fmt.Errorf("unsupported resolver config type: %s", typ)
 		}
@@ -469,7 +642,7 @@ func (c *InstanceConfig) loadBalancingExporter() (map[string]interface{}, error)
 	if err != nil {
 		return nil, err
 	}
-	resolverCfg, err := resolver(c.LoadBalancing.Resolver)
+	resolverCfg, err := resolver(c.LoadBalancing.Resolver, c.LoadBalancing.peers)
 	if err != nil {
 		return nil, err
 	}
@@ -511,9 +684,12 @@ func formatPolicies(cfg []map[string]interface{}) ([]map[string]interface{}, err
 func (c *InstanceConfig) otelConfig() (*config.Config, error) {
 	otelMapStructure := map[string]interface{}{}
 
-	if len(c.Receivers) == 0 {
+	if len(c.Receivers) == 0 && c.UnifiedOTLP == nil {
 		return nil, errors.New("must have at least one configured receiver")
 	}
+	if c.Receivers == nil {
+		c.Receivers = ReceiverMap{}
+	}
 
 	extensions, err := c.extensions()
 	if err != nil {
@@ -556,6 +732,18 @@ func (c *InstanceConfig) otelConfig() (*config.Config, error) {
 		}
 	}
 
+	if c.Redaction != nil {
+		placeholder := redactionprocessor.DefaultPlaceholder
+		if c.Redaction.Placeholder != "" {
+			placeholder = c.Redaction.Placeholder
+		}
+		processorNames = append(processorNames, redactionprocessor.TypeStr)
+		processors[redactionprocessor.TypeStr] = map[string]interface{}{
+			"rules":       c.Redaction.Rules,
+			"placeholder": placeholder,
+		}
+	}
+
 	if c.Attributes != nil {
 		processors["attributes"] = c.Attributes
 		processorNames = append(processorNames, "attributes")
@@ -612,6 +800,34 @@ func (c *InstanceConfig) otelConfig() (*config.Config, error) {
 		receiverNames = append(receiverNames, name)
 	}
 
+	if c.UnifiedOTLP != nil {
+		if _, ok := c.Receivers[unifiedOTLPReceiverName]; ok {
+			return nil, fmt.Errorf("receiver %s is reserved for unified_otlp", unifiedOTLPReceiverName)
+		}
+		receiverNames = append(receiverNames, unifiedOTLPReceiverName)
+
+		// Metrics and logs skip the span processors and are sent directly
+		// to their instances.
+		if c.UnifiedOTLP.MetricsInstance != "" {
+			exporters[unifiedOTLPMetricsExporterName] = map[string]interface{}{
+				"metrics_instance": c.UnifiedOTLP.MetricsInstance,
+			}
+			pipelines[unifiedOTLPMetricsPipelineName] = map[string]interface{}{
+				"receivers": []string{unifiedOTLPReceiverName},
+				"exporters": []string{unifiedOTLPMetricsExporterName},
+			}
+		}
+		if c.UnifiedOTLP.LogsInstance != "" {
+			exporters[logsexporter.TypeStr] = map[string]interface{}{
+				"logs_instance": c.UnifiedOTLP.LogsInstance,
+			}
+			pipelines[unifiedOTLPLogsPipelineName] = map[string]interface{}{
+				"receivers": []string{unifiedOTLPReceiverName},
+				"exporters": []string{logsexporter.TypeStr},
+			}
+		}
+	}
+
 	if c.TailSampling != nil {
 		wait := defaultDecisionWait
 		if c.TailSampling.DecisionWait != 0 {
@@ -639,14 +855,10 @@ func (c *InstanceConfig) otelConfig() (*config.Config, error) {
 		}
 		exporters["loadbalancing"] = internalExporter
 
-		receiverPort := defaultLoadBalancingPort
-		if c.LoadBalancing.ReceiverPort != "" {
-			receiverPort = c.LoadBalancing.ReceiverPort
-		}
 		c.Receivers["otlp/lb"] = map[string]interface{}{
 			"protocols": map[string]interface{}{
 				"grpc": map[string]interface{}{
-					"endpoint": net.JoinHostPort("0.0.0.0", receiverPort),
+					"endpoint": net.JoinHostPort("0.0.0.0", c.LoadBalancing.receiverPort()),
 				},
 			},
 		}
@@ -690,7 +902,16 @@ func (c *InstanceConfig) otelConfig() (*config.Config, error) {
 		c.Receivers[noopreceiver.TypeStr] = nil
 	}
 
-	receiversMap := map[string]interface{}(c.Receivers)
+	receivers, err := remoteSamplingReceivers(c.Receivers, os.TempDir())
+	if err != nil {
+		return nil, err
+	}
+	if c.UnifiedOTLP != nil {
+		receivers[unifiedOTLPReceiverName] = map[string]interface{}{
+			"protocols": c.UnifiedOTLP.protocols(),
+		}
+	}
+	receiversMap := map[string]interface{}(receivers)
 
 	otelMapStructure["extensions"] = extensions
 	otelMapStructure["exporters"] = exporters
@@ -741,6 +962,8 @@ func tracingFactories() (component.Factories, error) {
 		opencensusreceiver.NewFactory(),
 		kafkareceiver.NewFactory(),
 		noopreceiver.NewFactory(),
+		xrayreceiver.NewFactory(),
+		datadogreceiver.NewFactory(),
 	)
 	if err != nil {
 		return component.Factories{}, err
@@ -753,6 +976,7 @@ func tracingFactories() (component.Factories, error) {
 		loadbalancingexporter.NewFactory(),
 		prometheusexporter.NewFactory(),
 		remotewriteexporter.NewFactory(),
+		logsexporter.NewFactory(),
 	)
 	if err != nil {
 		return component.Factories{}, err
@@ -762,6 +986,7 @@ func tracingFactories() (component.Factories, error) {
 		batchprocessor.NewFactory(),
 		attributesprocessor.NewFactory(),
 		promsdprocessor.NewFactory(),
+		redactionprocessor.NewFactory(),
 		spanmetricsprocessor.NewFactory(),
 		automaticloggingprocessor.NewFactory(),
 		tailsamplingprocessor.NewFactory(),
@@ -784,12 +1009,13 @@ func tracingFactories() (component.Factories, error) {
 // sets: before and after load balancing
 func orderProcessors(processors []string, splitPipelines bool) [][]string {
 	order := map[string]int{
-		"attributes":        0,
-		"spanmetrics":       1,
-		"service_graphs":    2,
-		"tail_sampling":     3,
-		"automatic_logging": 4,
-		"batch":             5,
+		"redaction":         0,
+		"attributes":        1,
+		"spanmetrics":       2,
+		"service_graphs":    3,
+		"tail_sampling":     4,
+		"automatic_logging": 5,
+		"batch":             6,
 	}
 
 	sort.Slice(processors, func(i, j int) bool {
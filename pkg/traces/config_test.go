@@ -7,6 +7,11 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/grafana/agent/pkg/logs"
+	"github.com/grafana/agent/pkg/metrics"
+	"github.com/grafana/agent/pkg/metrics/cluster"
+	"github.com/grafana/agent/pkg/metrics/instance"
+	"github.com/grafana/agent/pkg/traces/redactionprocessor"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/collector/config"
@@ -40,6 +45,9 @@ func TestOTelConfig(t *testing.T) {
 	passwordFileExtraNewline, teardown := tmpFile(t, password+"\n")
 	defer teardown()
 
+	tokenFile, teardown := tmpFile(t, "token_in_file\n")
+	defer teardown()
+
 	// tests!
 	tt := []struct {
 		name           string
@@ -292,6 +300,46 @@ service:
       exporters: ["otlp/0"]
       processors: []
       receivers: ["jaeger"]
+`,
+		},
+		{
+			name: "zstd compression and queue tuning",
+			cfg: `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+remote_write:
+  - endpoint: example.com:12345
+    compression: zstd
+    sending_queue:
+      num_consumers: 20
+      queue_size: 10000
+    retry_on_failure:
+      initial_interval: 1s
+      max_interval: 10s`,
+			expectedConfig: `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+exporters:
+  otlp/0:
+    endpoint: example.com:12345
+    compression: zstd
+    sending_queue:
+      num_consumers: 20
+      queue_size: 10000
+    retry_on_failure:
+      initial_interval: 1s
+      max_interval: 10s
+      max_elapsed_time: 60s
+service:
+  pipelines:
+    traces:
+      exporters: ["otlp/0"]
+      processors: []
+      receivers: ["jaeger"]
 `,
 		},
 		{
@@ -875,6 +923,74 @@ service:
       receivers: ["jaeger"]
 `,
 		},
+		{
+			name: "otlp http exporter with bearer token and tenant",
+			cfg: `
+receivers:
+  otlp:
+    protocols:
+      http:
+remote_write:
+  - endpoint: https://tempo.example.com:4318
+    protocol: http
+    bearer_token_file: ` + tokenFile.Name() + `
+    headers:
+      X-Scope-OrgID: team-a
+    tls_config:
+      ca_file: /etc/tempo/ca.pem
+  - endpoint: tempo.example.com:4317
+    bearer_token: other-token
+    headers:
+      X-Scope-OrgID: team-b
+`,
+			expectedConfig: `
+receivers:
+  otlp:
+    protocols:
+      http:
+exporters:
+  otlphttp/0:
+    endpoint: https://tempo.example.com:4318
+    compression: gzip
+    headers:
+      X-Scope-OrgID: team-a
+      authorization: Bearer token_in_file
+    tls:
+      ca_file: /etc/tempo/ca.pem
+    retry_on_failure:
+      max_elapsed_time: 60s
+  otlp/1:
+    endpoint: tempo.example.com:4317
+    compression: gzip
+    headers:
+      X-Scope-OrgID: team-b
+      authorization: Bearer other-token
+    retry_on_failure:
+      max_elapsed_time: 60s
+service:
+  pipelines:
+    traces:
+      exporters: ["otlphttp/0", "otlp/1"]
+      processors: []
+      receivers: ["otlp"]
+`,
+		},
+		{
+			name: "bearer token and basic auth",
+			cfg: `
+receivers:
+  otlp:
+    protocols:
+      http:
+remote_write:
+  - endpoint: tempo.example.com:4317
+    bearer_token: token
+    basic_auth:
+      username: test
+      password: blerg
+`,
+			expectedError: true,
+		},
 		{
 			name: "prom SD config",
 			cfg: `
@@ -944,6 +1060,120 @@ service:
       exporters: ["otlp/0"]
       processors: ["service_graphs"]
       receivers: ["jaeger"]
+`,
+		},
+		{
+			name: "redaction",
+			cfg: `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+remote_write:
+  - endpoint: example.com:12345
+redaction:
+  rules:
+    - action: delete
+      keys: [user.email]
+    - action: redact
+      value_pattern: token=\w+
+`,
+			expectedConfig: `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+exporters:
+  otlp/0:
+    endpoint: example.com:12345
+    compression: gzip
+    retry_on_failure:
+      max_elapsed_time: 60s
+processors:
+  redaction:
+    placeholder: <redacted>
+    rules:
+      - action: delete
+        keys: [user.email]
+      - action: redact
+        value_pattern: token=\w+
+service:
+  pipelines:
+    traces:
+      exporters: ["otlp/0"]
+      processors: ["redaction"]
+      receivers: ["jaeger"]
+`,
+		},
+		{
+			name: "awsxray and datadog receivers",
+			cfg: `
+receivers:
+  awsxray:
+    endpoint: 0.0.0.0:2001
+  datadog:
+remote_write:
+  - endpoint: example.com:12345
+`,
+			expectedConfig: `
+receivers:
+  awsxray:
+    endpoint: 0.0.0.0:2001
+  datadog:
+exporters:
+  otlp/0:
+    endpoint: example.com:12345
+    compression: gzip
+    retry_on_failure:
+      max_elapsed_time: 60s
+service:
+  pipelines:
+    traces:
+      exporters: ["otlp/0"]
+      processors: []
+      receivers: ["awsxray", "datadog"]
+`,
+		},
+		{
+			name: "unified otlp",
+			cfg: `
+unified_otlp:
+  protocols:
+    grpc:
+      endpoint: 0.0.0.0:4317
+  metrics_instance: default
+  logs_instance: default
+remote_write:
+  - endpoint: example.com:12345
+`,
+			expectedConfig: `
+receivers:
+  otlp/unified:
+    protocols:
+      grpc:
+        endpoint: 0.0.0.0:4317
+exporters:
+  otlp/0:
+    endpoint: example.com:12345
+    compression: gzip
+    retry_on_failure:
+      max_elapsed_time: 60s
+  remote_write/otlp:
+    metrics_instance: default
+  logs_instance:
+    logs_instance: default
+service:
+  pipelines:
+    traces:
+      exporters: ["otlp/0"]
+      processors: []
+      receivers: ["otlp/unified"]
+    metrics/otlp:
+      exporters: ["remote_write/otlp"]
+      receivers: ["otlp/unified"]
+    logs/otlp:
+      exporters: ["logs_instance"]
+      receivers: ["otlp/unified"]
 `,
 		},
 		{
@@ -1399,9 +1629,14 @@ tail_sampling:
           - value2
 service_graphs:
   enabled: true
+redaction:
+  rules:
+    - action: delete
+      keys: [user.email]
 `,
 			expectedProcessors: map[string][]config.ComponentID{
 				"traces": {
+					config.NewComponentID("redaction"),
 					config.NewComponentID("attributes"),
 					config.NewComponentID("spanmetrics"),
 					config.NewComponentID("service_graphs"),
@@ -1678,6 +1913,256 @@ receivers:
 	assert.True(t, strings.Contains(string(data), "<secret>"))
 }
 
+func TestSpanMetricsValidate(t *testing.T) {
+	metricsConfig := &metrics.Config{
+		Configs: []instance.Config{{Name: "traces"}},
+	}
+
+	tt := []struct {
+		name          string
+		cfg           SpanMetricsConfig
+		metricsConfig *metrics.Config
+		expectedError string
+	}{
+		{
+			name:          "existing metrics instance",
+			cfg:           SpanMetricsConfig{MetricsInstance: "traces"},
+			metricsConfig: metricsConfig,
+		},
+		{
+			name:          "handler endpoint",
+			cfg:           SpanMetricsConfig{HandlerEndpoint: "0.0.0.0:8889"},
+			metricsConfig: metricsConfig,
+		},
+		{
+			name:          "missing metrics instance",
+			cfg:           SpanMetricsConfig{MetricsInstance: "missing"},
+			metricsConfig: metricsConfig,
+			expectedError: "specified metrics instance missing not found in agent config",
+		},
+		{
+			name: "scraping service instance",
+			cfg:  SpanMetricsConfig{MetricsInstance: "missing"},
+			metricsConfig: &metrics.Config{
+				ServiceConfig: cluster.Config{Enabled: true},
+			},
+		},
+		{
+			name:          "metrics instance and handler endpoint",
+			cfg:           SpanMetricsConfig{MetricsInstance: "traces", HandlerEndpoint: "0.0.0.0:8889"},
+			metricsConfig: metricsConfig,
+			expectedError: "must specify a prometheus instance or a metrics handler endpoint to export the metrics",
+		},
+		{
+			name:          "no exporter",
+			metricsConfig: metricsConfig,
+			expectedError: "must specify a prometheus instance or a metrics handler endpoint to export the metrics",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate(tc.metricsConfig)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRemoteWriteCompression(t *testing.T) {
+	tt := []struct {
+		name          string
+		cfg           string
+		expectedError string
+	}{
+		{
+			name: "zstd over grpc",
+			cfg: `
+endpoint: example.com:12345
+compression: zstd`,
+		},
+		{
+			name: "zstd over http",
+			cfg: `
+endpoint: example.com:12345
+protocol: http
+compression: zstd`,
+			expectedError: "compression 'zstd' is only supported with the 'grpc' protocol",
+		},
+		{
+			name: "unsupported compression",
+			cfg: `
+endpoint: example.com:12345
+compression: snappy`,
+			expectedError: "unsupported compression 'snappy', expected 'gzip', 'zstd' or 'none'",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var cfg RemoteWriteConfig
+			err := yaml.Unmarshal([]byte(tc.cfg), &cfg)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestRedactionValidate(t *testing.T) {
+	tt := []struct {
+		name          string
+		cfg           redactionConfig
+		expectedError string
+	}{
+		{
+			name: "valid rules",
+			cfg: redactionConfig{Rules: []redactionprocessor.Rule{
+				{Action: "delete", Keys: []string{"user.email"}},
+				{Action: "hash", KeyPattern: "^auth\\."},
+			}},
+		},
+		{
+			name:          "no rules",
+			expectedError: "must configure at least one rule",
+		},
+		{
+			name: "invalid pattern",
+			cfg: redactionConfig{Rules: []redactionprocessor.Rule{
+				{Action: "redact", ValuePattern: "["},
+			}},
+			expectedError: "invalid redaction rule at index 0: invalid value_pattern: error parsing regexp: missing closing ]: `[`",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate()
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestUnifiedOTLPValidate(t *testing.T) {
+	metricsConfig := &metrics.Config{
+		Configs: []instance.Config{{Name: "default"}},
+	}
+	logsConfig := &logs.Config{
+		Configs: []*logs.InstanceConfig{{Name: "default"}},
+	}
+
+	tt := []struct {
+		name          string
+		cfg           unifiedOTLPConfig
+		logsConfig    *logs.Config
+		expectedError string
+	}{
+		{
+			name:       "existing instances",
+			cfg:        unifiedOTLPConfig{MetricsInstance: "default", LogsInstance: "default"},
+			logsConfig: logsConfig,
+		},
+		{
+			name:          "missing metrics instance",
+			cfg:           unifiedOTLPConfig{MetricsInstance: "missing"},
+			logsConfig:    logsConfig,
+			expectedError: "specified metrics instance missing not found in agent config",
+		},
+		{
+			name:          "missing logs instance",
+			cfg:           unifiedOTLPConfig{LogsInstance: "missing"},
+			logsConfig:    logsConfig,
+			expectedError: "specified logs instance missing not found in agent config",
+		},
+		{
+			name:          "no logs config",
+			cfg:           unifiedOTLPConfig{LogsInstance: "default"},
+			expectedError: "logs instance default is set but no logs config is provided",
+		},
+		{
+			name:          "unsupported protocol",
+			cfg:           unifiedOTLPConfig{Protocols: map[string]interface{}{"thrift_http": nil}},
+			expectedError: "unsupported protocol 'thrift_http', expected 'grpc' or 'http'",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.cfg.Validate(tc.logsConfig, metricsConfig)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestKubernetesResolver(t *testing.T) {
+	test := `
+receivers:
+  jaeger:
+    protocols:
+      grpc:
+remote_write:
+  - endpoint: example.com:12345
+load_balancing:
+  receiver_port: 8080
+  exporter:
+    insecure: true
+  resolver:
+    kubernetes:
+      service: agent
+      namespace: tracing
+`
+	var cfg InstanceConfig
+	err := yaml.Unmarshal([]byte(test), &cfg)
+	require.NoError(t, err)
+
+	k8sResolver, err := cfg.LoadBalancing.kubernetesResolver()
+	require.NoError(t, err)
+	require.Equal(t, &kubernetesResolverConfig{
+		Service:   "agent",
+		Namespace: "tracing",
+		Port:      "8080",
+	}, k8sResolver)
+
+	// The pipeline can't be built before peers are discovered.
+	_, err = cfg.otelConfig()
+	require.Error(t, err)
+
+	cfg.LoadBalancing.peers = []string{"10.0.0.1:8080", "10.0.0.2:8080"}
+	exporter, err := cfg.loadBalancingExporter()
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"static": map[string]interface{}{
+			"hostnames": []string{"10.0.0.1:8080", "10.0.0.2:8080"},
+		},
+	}, exporter["resolver"])
+
+	_, err = cfg.otelConfig()
+	require.NoError(t, err)
+}
+
+func TestKubernetesResolver_Invalid(t *testing.T) {
+	lb := loadBalancingConfig{
+		Resolver: map[string]interface{}{
+			"kubernetes": map[interface{}]interface{}{"namespace": "tracing"},
+		},
+	}
+	_, err := lb.kubernetesResolver()
+	require.EqualError(t, err, "kubernetes resolver must have a service")
+}
+
 // sortPipelines is a helper function to lexicographically sort a pipeline's exporters
 func sortPipelines(cfg *config.Config) {
 	tracePipeline := cfg.Pipelines[config.NewComponentID(config.TracesDataType)]
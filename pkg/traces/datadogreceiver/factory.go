@@ -0,0 +1,53 @@
+package datadogreceiver
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/config/confighttp"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/receiver/receiverhelper"
+)
+
+const (
+	// TypeStr is the unique identifier for the Datadog receiver.
+	TypeStr = "datadog"
+
+	// DefaultEndpoint is the address Datadog tracers send traces to by
+	// default.
+	DefaultEndpoint = "0.0.0.0:8126"
+)
+
+// Config defines configuration for the Datadog receiver.
+type Config struct {
+	config.ReceiverSettings       `mapstructure:",squash"`
+	confighttp.HTTPServerSettings `mapstructure:",squash"`
+}
+
+// NewFactory creates a factory for the Datadog receiver.
+func NewFactory() component.ReceiverFactory {
+	return receiverhelper.NewFactory(
+		TypeStr,
+		createDefaultConfig,
+		receiverhelper.WithTraces(createTracesReceiver),
+	)
+}
+
+func createDefaultConfig() config.Receiver {
+	return &Config{
+		ReceiverSettings: config.NewReceiverSettings(config.NewComponentID(TypeStr)),
+		HTTPServerSettings: confighttp.HTTPServerSettings{
+			Endpoint: DefaultEndpoint,
+		},
+	}
+}
+
+func createTracesReceiver(
+	_ context.Context,
+	settings component.ReceiverCreateSettings,
+	cfg config.Receiver,
+	nextConsumer consumer.Traces,
+) (component.TracesReceiver, error) {
+	return newReceiver(cfg.(*Config), nextConsumer, settings)
+}
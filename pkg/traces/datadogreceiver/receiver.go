@@ -0,0 +1,138 @@
+package datadogreceiver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/go-msgpack/codec"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenterror"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/obsreport"
+	"go.uber.org/zap"
+)
+
+const (
+	transport = "http"
+	format    = "datadog"
+)
+
+type receiver struct {
+	cfg          *Config
+	nextConsumer consumer.Traces
+	settings     component.ReceiverCreateSettings
+	obsrecv      *obsreport.Receiver
+
+	server *http.Server
+	wg     sync.WaitGroup
+}
+
+func newReceiver(cfg *Config, nextConsumer consumer.Traces, settings component.ReceiverCreateSettings) (*receiver, error) {
+	if nextConsumer == nil {
+		return nil, componenterror.ErrNilNextConsumer
+	}
+	if cfg.Endpoint == "" {
+		return nil, errors.New("endpoint must not be empty")
+	}
+
+	return &receiver{
+		cfg:          cfg,
+		nextConsumer: nextConsumer,
+		settings:     settings,
+		obsrecv: obsreport.NewReceiver(obsreport.ReceiverSettings{
+			ReceiverID:             cfg.ID(),
+			Transport:              transport,
+			ReceiverCreateSettings: settings,
+		}),
+	}, nil
+}
+
+// Start implements the Component interface.
+func (r *receiver) Start(_ context.Context, host component.Host) error {
+	lis, err := r.cfg.ToListener()
+	if err != nil {
+		return err
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/v0.3/traces", r.handleTraces(false)).Methods(http.MethodPost, http.MethodPut)
+	router.HandleFunc("/v0.4/traces", r.handleTraces(true)).Methods(http.MethodPost, http.MethodPut)
+	r.server = r.cfg.ToServer(router, r.settings.TelemetrySettings)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		if err := r.server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			host.ReportFatalError(err)
+		}
+	}()
+	return nil
+}
+
+// handleTraces handles a payload of traces. Since v0.4, tracers expect the
+// sampling rates to apply in the response.
+func (r *receiver) handleTraces(withRates bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := r.obsrecv.StartTracesOp(req.Context())
+
+		traces, err := decodeTraces(req)
+		if err != nil {
+			r.obsrecv.EndTracesOp(ctx, format, 0, err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		td := toTraces(traces)
+		if td.SpanCount() > 0 {
+			err = r.nextConsumer.ConsumeTraces(ctx, td)
+		}
+		r.obsrecv.EndTracesOp(ctx, format, td.SpanCount(), err)
+		if err != nil {
+			r.settings.Logger.Debug("failed to consume Datadog traces", zap.Error(err))
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if !withRates {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"rate_by_service":{}}`))
+	}
+}
+
+// decodeTraces decodes a JSON or MessagePack payload, depending on its
+// content type.
+func decodeTraces(req *http.Request) ([][]span, error) {
+	var traces [][]span
+
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json", "text/json":
+		if err := json.NewDecoder(req.Body).Decode(&traces); err != nil {
+			return nil, fmt.Errorf("invalid JSON payload: %w", err)
+		}
+	default:
+		if err := codec.NewDecoder(req.Body, &codec.MsgpackHandle{}).Decode(&traces); err != nil {
+			return nil, fmt.Errorf("invalid MessagePack payload: %w", err)
+		}
+	}
+	return traces, nil
+}
+
+// Shutdown implements the Component interface.
+func (r *receiver) Shutdown(ctx context.Context) error {
+	if r.server == nil {
+		return nil
+	}
+	err := r.server.Shutdown(ctx)
+	r.wg.Wait()
+	return err
+}
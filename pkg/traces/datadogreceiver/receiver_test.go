@@ -0,0 +1,139 @@
+package datadogreceiver
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/go-msgpack/codec"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/model/pdata"
+	semconv "go.opentelemetry.io/collector/model/semconv/v1.6.1"
+)
+
+var testTraces = [][]span{{
+	{
+		Service:  "shop",
+		Name:     "http.request",
+		Resource: "GET /cart",
+		TraceID:  42,
+		SpanID:   1,
+		Start:    1000,
+		Duration: 500,
+		Error:    1,
+		Type:     "web",
+		Meta:     map[string]string{"http.method": "GET", "error.msg": "boom"},
+	},
+	{
+		Service:  "redis",
+		Name:     "redis.command",
+		Resource: "GET",
+		TraceID:  42,
+		SpanID:   2,
+		ParentID: 1,
+		Start:    1100,
+		Duration: 100,
+		Meta:     map[string]string{"span.kind": "client"},
+		Metrics:  map[string]float64{"redis.args_length": 2},
+	},
+}}
+
+func TestTranslate(t *testing.T) {
+	td := toTraces(testTraces)
+	require.Equal(t, 2, td.ResourceSpans().Len())
+
+	rs := td.ResourceSpans().At(0)
+	serviceName, _ := rs.Resource().Attributes().Get(semconv.AttributeServiceName)
+	require.Equal(t, "shop", serviceName.StringVal())
+
+	root := rs.InstrumentationLibrarySpans().At(0).Spans().At(0)
+	require.Equal(t, "0000000000000000000000000000002a", root.TraceID().HexString())
+	require.Equal(t, "0000000000000001", root.SpanID().HexString())
+	require.True(t, root.ParentSpanID().IsEmpty())
+	require.Equal(t, "http.request", root.Name())
+	require.Equal(t, pdata.Timestamp(1500), root.EndTimestamp())
+	require.Equal(t, pdata.SpanKindServer, root.Kind())
+	require.Equal(t, pdata.StatusCodeError, root.Status().Code())
+	require.Equal(t, "boom", root.Status().Message())
+	require.Equal(t, map[string]interface{}{
+		attributeResource: "GET /cart",
+		attributeSpanType: "web",
+		"http.method":     "GET",
+		"error.msg":       "boom",
+	}, root.Attributes().AsRaw())
+
+	child := td.ResourceSpans().At(1).InstrumentationLibrarySpans().At(0).Spans().At(0)
+	require.Equal(t, root.SpanID(), child.ParentSpanID())
+	require.Equal(t, pdata.SpanKindClient, child.Kind())
+	require.Equal(t, map[string]interface{}{
+		attributeResource:   "GET",
+		"redis.args_length": 2.0,
+	}, child.Attributes().AsRaw())
+}
+
+func TestReceiver(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = addr
+
+	sink := new(consumertest.TracesSink)
+	r, err := createTracesReceiver(context.Background(), componenttest.NewNopReceiverCreateSettings(), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, r.Start(context.Background(), componenttest.NewNopHost()))
+	t.Cleanup(func() { require.NoError(t, r.Shutdown(context.Background())) })
+
+	var msgpackBody bytes.Buffer
+	require.NoError(t, codec.NewEncoder(&msgpackBody, &codec.MsgpackHandle{}).Encode(testTraces))
+
+	tt := []struct {
+		name        string
+		path        string
+		contentType string
+		body        []byte
+		expectBody  string
+	}{
+		{
+			name:        "v0.4 msgpack",
+			path:        "/v0.4/traces",
+			contentType: "application/msgpack",
+			body:        msgpackBody.Bytes(),
+			expectBody:  `{"rate_by_service":{}}`,
+		},
+		{
+			name:        "v0.3 json",
+			path:        "/v0.3/traces",
+			contentType: "application/json",
+			body: []byte(`[[{"service": "shop", "name": "http.request", "trace_id": 42, "span_id": 1, "start": 1000, "duration": 500},
+				{"service": "shop", "name": "db.query", "trace_id": 42, "span_id": 2, "parent_id": 1, "start": 1100, "duration": 100}]]`),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sink.Reset()
+
+			req, err := http.NewRequest(http.MethodPut, "http://"+addr+tc.path, bytes.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", tc.contentType)
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+			require.Equal(t, tc.expectBody, string(body))
+			require.Equal(t, 2, sink.SpanCount())
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package datadogreceiver
+
+import (
+	"encoding/binary"
+
+	"go.opentelemetry.io/collector/model/pdata"
+	semconv "go.opentelemetry.io/collector/model/semconv/v1.6.1"
+)
+
+// span is a span of the Datadog trace agent API.
+// https://docs.datadoghq.com/tracing/guide/send_traces_to_agent_by_api/
+type span struct {
+	Service  string             `codec:"service" json:"service"`
+	Name     string             `codec:"name" json:"name"`
+	Resource string             `codec:"resource" json:"resource"`
+	TraceID  uint64             `codec:"trace_id" json:"trace_id"`
+	SpanID   uint64             `codec:"span_id" json:"span_id"`
+	ParentID uint64             `codec:"parent_id" json:"parent_id"`
+	Start    int64              `codec:"start" json:"start"`
+	Duration int64              `codec:"duration" json:"duration"`
+	Error    int32              `codec:"error" json:"error"`
+	Meta     map[string]string  `codec:"meta" json:"meta"`
+	Metrics  map[string]float64 `codec:"metrics" json:"metrics"`
+	Type     string             `codec:"type" json:"type"`
+}
+
+// Attribute keys for Datadog fields without a semantic convention.
+const (
+	attributeResource = "dd.resource"
+	attributeSpanType = "dd.span.type"
+
+	metaSpanKind     = "span.kind"
+	metaErrorMessage = "error.msg"
+)
+
+// toTraces translates Datadog traces into OTLP spans, grouping them by
+// service.
+func toTraces(traces [][]span) pdata.Traces {
+	td := pdata.NewTraces()
+	services := make(map[string]pdata.SpanSlice)
+
+	for _, trace := range traces {
+		for i := range trace {
+			s := &trace[i]
+
+			spans, ok := services[s.Service]
+			if !ok {
+				rs := td.ResourceSpans().AppendEmpty()
+				rs.Resource().Attributes().InsertString(semconv.AttributeServiceName, s.Service)
+				spans = rs.InstrumentationLibrarySpans().AppendEmpty().Spans()
+				services[s.Service] = spans
+			}
+			addSpan(spans, s)
+		}
+	}
+	return td
+}
+
+func addSpan(spans pdata.SpanSlice, s *span) {
+	out := spans.AppendEmpty()
+	out.SetTraceID(toTraceID(s.TraceID))
+	out.SetSpanID(toSpanID(s.SpanID))
+	if s.ParentID != 0 {
+		out.SetParentSpanID(toSpanID(s.ParentID))
+	}
+	out.SetName(s.Name)
+	out.SetStartTimestamp(pdata.Timestamp(s.Start))
+	out.SetEndTimestamp(pdata.Timestamp(s.Start + s.Duration))
+	out.SetKind(toKind(s))
+
+	if s.Error != 0 {
+		out.Status().SetCode(pdata.StatusCodeError)
+		out.Status().SetMessage(s.Meta[metaErrorMessage])
+	}
+
+	attrs := out.Attributes()
+	if s.Resource != "" {
+		attrs.InsertString(attributeResource, s.Resource)
+	}
+	if s.Type != "" {
+		attrs.InsertString(attributeSpanType, s.Type)
+	}
+	for k, v := range s.Meta {
+		if k != metaSpanKind {
+			attrs.InsertString(k, v)
+		}
+	}
+	for k, v := range s.Metrics {
+		attrs.InsertDouble(k, v)
+	}
+}
+
+// toKind returns the kind of a span from its span.kind tag, falling back to
+// its type.
+func toKind(s *span) pdata.SpanKind {
+	switch s.Meta[metaSpanKind] {
+	case "server":
+		return pdata.SpanKindServer
+	case "client":
+		return pdata.SpanKindClient
+	case "producer":
+		return pdata.SpanKindProducer
+	case "consumer":
+		return pdata.SpanKindConsumer
+	case "internal":
+		return pdata.SpanKindInternal
+	}
+
+	switch s.Type {
+	case "web":
+		return pdata.SpanKindServer
+	case "http", "grpc", "db", "sql", "cache", "redis", "memcached", "mongodb", "cassandra", "elasticsearch":
+		return pdata.SpanKindClient
+	default:
+		return pdata.SpanKindInternal
+	}
+}
+
+// toTraceID converts a 64-bit Datadog trace ID to a 128-bit trace ID, with
+// the upper half left empty.
+func toTraceID(id uint64) pdata.TraceID {
+	var raw [16]byte
+	binary.BigEndian.PutUint64(raw[8:], id)
+	return pdata.NewTraceID(raw)
+}
+
+func toSpanID(id uint64) pdata.SpanID {
+	var raw [8]byte
+	binary.BigEndian.PutUint64(raw[:], id)
+	return pdata.NewSpanID(raw)
+}
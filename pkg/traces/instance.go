@@ -6,6 +6,8 @@ import (
 	"sync"
 	"time"
 
+	cortex_log "github.com/cortexproject/cortex/pkg/util/log"
+	"github.com/go-kit/log"
 	"github.com/prometheus/client_golang/prometheus"
 	"go.opencensus.io/stats/view"
 	"go.opentelemetry.io/collector/component"
@@ -35,6 +37,14 @@ type Instance struct {
 	exporter   builder.Exporters
 	pipelines  builder.BuiltPipelines
 	receivers  builder.Receivers
+
+	// peerDiscovery rebuilds the pipeline when the load balancing peers of a
+	// kubernetes resolver change. The dependencies of the pipeline are kept
+	// for rebuilding it.
+	peerDiscovery       *peerDiscovery
+	logsSubsystem       *logs.Logs
+	promInstanceManager instance.Manager
+	reg                 prometheus.Registerer
 }
 
 // NewInstance creates and starts an instance of tracing pipelines.
@@ -64,10 +74,25 @@ func (i *Instance) ApplyConfig(logsSubsystem *logs.Logs, promInstanceManager ins
 		return nil
 	}
 	i.cfg = cfg
+	i.logsSubsystem = logsSubsystem
+	i.promInstanceManager = promInstanceManager
+	i.reg = reg
 
 	// Shut down any existing pipeline
+	i.stopPeerDiscovery()
 	i.stop()
 
+	if cfg.LoadBalancing != nil {
+		k8sResolver, err := cfg.LoadBalancing.kubernetesResolver()
+		if err != nil {
+			return fmt.Errorf("failed to create pipeline: %w", err)
+		}
+		if k8sResolver != nil {
+			// The pipeline is built once the first peers are discovered.
+			return i.startPeerDiscovery(k8sResolver)
+		}
+	}
+
 	err := i.buildAndStartPipeline(context.Background(), cfg, logsSubsystem, promInstanceManager, reg)
 	if err != nil {
 		return fmt.Errorf("failed to create pipeline: %w", err)
@@ -76,11 +101,66 @@ func (i *Instance) ApplyConfig(logsSubsystem *logs.Logs, promInstanceManager ins
 	return nil
 }
 
+func (i *Instance) startPeerDiscovery(cfg *kubernetesResolverConfig) error {
+	var pd *peerDiscovery
+	logger := log.With(cortex_log.Logger, "component", "traces load balancing peers")
+
+	// onChange is called from the discovery goroutine and may race with a
+	// config change, so it only applies to the current discovery.
+	onChange := func(peers []string) {
+		i.mut.Lock()
+		defer i.mut.Unlock()
+		if pd == nil || i.peerDiscovery != pd {
+			return
+		}
+		i.applyPeers(peers)
+	}
+
+	var err error
+	pd, err = newPeerDiscovery(logger, cfg, onChange)
+	if err != nil {
+		return fmt.Errorf("failed to start load balancing peer discovery: %w", err)
+	}
+	i.peerDiscovery = pd
+	i.logger.Info("waiting for load balancing peers to be discovered before starting the pipeline")
+	return nil
+}
+
+// applyPeers rebuilds the pipeline to load balance spans between peers.
+// The load balancing exporter can't update a static resolver, so the
+// whole pipeline is restarted. Must be called with i.mut held.
+func (i *Instance) applyPeers(peers []string) {
+	i.stop()
+	if len(peers) == 0 {
+		i.logger.Warn("no load balancing peers discovered, stopping the pipeline")
+		return
+	}
+
+	// Copy the load balancing config so i.cfg isn't modified.
+	cfg := i.cfg
+	lb := *cfg.LoadBalancing
+	lb.peers = peers
+	cfg.LoadBalancing = &lb
+
+	err := i.buildAndStartPipeline(context.Background(), cfg, i.logsSubsystem, i.promInstanceManager, i.reg)
+	if err != nil {
+		i.logger.Error("failed to create pipeline for load balancing peers", zap.Error(err))
+	}
+}
+
+func (i *Instance) stopPeerDiscovery() {
+	if i.peerDiscovery != nil {
+		i.peerDiscovery.Stop()
+		i.peerDiscovery = nil
+	}
+}
+
 // Stop stops the OpenTelemetry collector subsystem
 func (i *Instance) Stop() {
 	i.mut.Lock()
 	defer i.mut.Unlock()
 
+	i.stopPeerDiscovery()
 	i.stop()
 	view.Unregister(i.metricViews...)
 }
@@ -164,7 +244,8 @@ func (i *Instance) buildAndStartPipeline(ctx context.Context, cfg InstanceConfig
 		}
 	}
 
-	if cfg.SpanMetrics != nil && len(cfg.SpanMetrics.MetricsInstance) != 0 {
+	if (cfg.SpanMetrics != nil && len(cfg.SpanMetrics.MetricsInstance) != 0) ||
+		(cfg.UnifiedOTLP != nil && len(cfg.UnifiedOTLP.MetricsInstance) != 0) {
 		ctx = context.WithValue(ctx, contextkeys.Metrics, instManager)
 	}
 
@@ -173,7 +254,8 @@ func (i *Instance) buildAndStartPipeline(ctx context.Context, cfg InstanceConfig
 			"Load balancing is required for those features to properly work in multi agent deployments")
 	}
 
-	if cfg.AutomaticLogging != nil && cfg.AutomaticLogging.Backend != automaticloggingprocessor.BackendStdout {
+	if (cfg.AutomaticLogging != nil && cfg.AutomaticLogging.Backend != automaticloggingprocessor.BackendStdout) ||
+		(cfg.UnifiedOTLP != nil && len(cfg.UnifiedOTLP.LogsInstance) != 0) {
 		ctx = context.WithValue(ctx, contextkeys.Logs, logs)
 	}
 
@@ -246,6 +328,29 @@ func (i *Instance) buildAndStartPipeline(ctx context.Context, cfg InstanceConfig
 	return i.extensions.NotifyPipelineReady()
 }
 
+// TracesExporters returns the exporters of the running pipeline which accept
+// traces. Spans sent to them skip the processors of the pipeline.
+func (i *Instance) TracesExporters() []component.TracesExporter {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	if i.exporter == nil {
+		return nil
+	}
+	var exporters []component.TracesExporter
+	for id, exp := range i.exporter.ToMapByDataType()[config.TracesDataType] {
+		// With load balancing, spans are only exported by the agent owning
+		// their trace.
+		if i.cfg.LoadBalancing != nil && id.Type() != "loadbalancing" {
+			continue
+		}
+		if te, ok := exp.(component.TracesExporter); ok {
+			exporters = append(exporters, te)
+		}
+	}
+	return exporters
+}
+
 // ReportFatalError implements component.Host
 func (i *Instance) ReportFatalError(err error) {
 	i.logger.Error("fatal error reported", zap.Error(err))
@@ -0,0 +1,166 @@
+package logsexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-logfmt/logfmt"
+	"github.com/grafana/agent/pkg/logs"
+	"github.com/grafana/agent/pkg/traces/contextkeys"
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/grafana/loki/pkg/logproto"
+	"github.com/prometheus/common/model"
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/model/pdata"
+	semconv "go.opentelemetry.io/collector/model/semconv/v1.6.1"
+	"go.uber.org/atomic"
+)
+
+const (
+	serviceLabel = "service"
+	levelLabel   = "level"
+
+	traceIDKey = "tid"
+	spanIDKey  = "sid"
+)
+
+// sender sends entries to a logs instance.
+type sender interface {
+	SendEntry(entry api.Entry, dur time.Duration) bool
+}
+
+type logsExporter struct {
+	done         atomic.Bool
+	logsName     string
+	timeout      time.Duration
+	logsInstance sender
+}
+
+func newLogsExporter(cfg *Config) (component.LogsExporter, error) {
+	if cfg.LogsInstance == "" {
+		return nil, errors.New("logs_instance must not be empty")
+	}
+	return &logsExporter{
+		logsName: cfg.LogsInstance,
+		timeout:  cfg.Timeout,
+	}, nil
+}
+
+func (e *logsExporter) Start(ctx context.Context, _ component.Host) error {
+	logs, ok := ctx.Value(contextkeys.Logs).(*logs.Logs)
+	if !ok || logs == nil {
+		return fmt.Errorf("key does not contain a logs instance")
+	}
+	inst := logs.Instance(e.logsName)
+	if inst == nil {
+		return fmt.Errorf("logs instance %s not found", e.logsName)
+	}
+	e.logsInstance = inst
+	return nil
+}
+
+func (e *logsExporter) Shutdown(_ context.Context) error {
+	e.done.Store(true)
+	return nil
+}
+
+func (e *logsExporter) Capabilities() consumer.Capabilities {
+	return consumer.Capabilities{}
+}
+
+func (e *logsExporter) ConsumeLogs(_ context.Context, ld pdata.Logs) error {
+	if e.done.Load() {
+		return nil
+	}
+
+	var dropped int
+	rls := ld.ResourceLogs()
+	for i := 0; i < rls.Len(); i++ {
+		rl := rls.At(i)
+		service := ""
+		if v, ok := rl.Resource().Attributes().Get(semconv.AttributeServiceName); ok {
+			service = v.AsString()
+		}
+
+		ills := rl.InstrumentationLibraryLogs()
+		for j := 0; j < ills.Len(); j++ {
+			records := ills.At(j).Logs()
+			for k := 0; k < records.Len(); k++ {
+				entry, err := toEntry(service, records.At(k))
+				if err != nil {
+					return err
+				}
+				if !e.logsInstance.SendEntry(entry, e.timeout) {
+					dropped++
+				}
+			}
+		}
+	}
+
+	if dropped > 0 {
+		return fmt.Errorf("failed to send %d log records to logs instance %s", dropped, e.logsName)
+	}
+	return nil
+}
+
+// toEntry converts a log record to an entry. The body of the record is the
+// line, followed by its attributes and trace context formatted as logfmt.
+func toEntry(service string, lr pdata.LogRecord) (api.Entry, error) {
+	labels := model.LabelSet{}
+	if service != "" {
+		labels[serviceLabel] = model.LabelValue(service)
+	}
+	if severity := lr.SeverityText(); severity != "" {
+		labels[levelLabel] = model.LabelValue(strings.ToLower(severity))
+	}
+
+	attrs := lr.Attributes()
+	keys := make([]string, 0, attrs.Len())
+	attrs.Range(func(k string, _ pdata.AttributeValue) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Strings(keys)
+
+	keyvals := make([]interface{}, 0, 2*len(keys)+4)
+	for _, k := range keys {
+		v, _ := attrs.Get(k)
+		keyvals = append(keyvals, k, v.AsString())
+	}
+	if !lr.TraceID().IsEmpty() {
+		keyvals = append(keyvals, traceIDKey, lr.TraceID().HexString())
+	}
+	if !lr.SpanID().IsEmpty() {
+		keyvals = append(keyvals, spanIDKey, lr.SpanID().HexString())
+	}
+
+	line := lr.Body().AsString()
+	if len(keyvals) > 0 {
+		bb, err := logfmt.MarshalKeyvals(keyvals...)
+		if err != nil {
+			return api.Entry{}, fmt.Errorf("failed to format log record: %w", err)
+		}
+		if line != "" {
+			line += " "
+		}
+		line += string(bb)
+	}
+
+	ts := lr.Timestamp().AsTime()
+	if lr.Timestamp() == 0 {
+		ts = time.Now()
+	}
+
+	return api.Entry{
+		Labels: labels,
+		Entry: logproto.Entry{
+			Timestamp: ts,
+			Line:      line,
+		},
+	}, nil
+}
@@ -0,0 +1,61 @@
+package logsexporter
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/clients/pkg/promtail/api"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/model/pdata"
+	semconv "go.opentelemetry.io/collector/model/semconv/v1.6.1"
+)
+
+func TestLogsExporter_ConsumeLogs(t *testing.T) {
+	ts := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	ld := pdata.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().InsertString(semconv.AttributeServiceName, "checkout")
+	records := rl.InstrumentationLibraryLogs().AppendEmpty().Logs()
+
+	lr := records.AppendEmpty()
+	lr.SetTimestamp(pdata.NewTimestampFromTime(ts))
+	lr.SetSeverityText("WARN")
+	lr.Body().SetStringVal("payment declined")
+	lr.Attributes().InsertString("user", "alice")
+	lr.Attributes().InsertInt("attempt", 2)
+	lr.SetTraceID(pdata.NewTraceID([16]byte{1}))
+
+	records.AppendEmpty().Body().SetStringVal("plain line")
+
+	s := &mockSender{}
+	exp := &logsExporter{logsName: "default", logsInstance: s}
+	require.NoError(t, exp.ConsumeLogs(context.Background(), ld))
+	require.Len(t, s.entries, 2)
+
+	require.Equal(t, model.LabelSet{"service": "checkout", "level": "warn"}, s.entries[0].Labels)
+	require.Equal(t, ts, s.entries[0].Timestamp)
+	require.Equal(t, "payment declined attempt=2 user=alice tid=01000000000000000000000000000000", s.entries[0].Line)
+
+	require.Equal(t, model.LabelSet{"service": "checkout"}, s.entries[1].Labels)
+	require.Equal(t, "plain line", s.entries[1].Line)
+	require.False(t, s.entries[1].Timestamp.IsZero())
+
+	s.reject = true
+	require.EqualError(t, exp.ConsumeLogs(context.Background(), ld), "failed to send 2 log records to logs instance default")
+}
+
+type mockSender struct {
+	entries []api.Entry
+	reject  bool
+}
+
+func (s *mockSender) SendEntry(entry api.Entry, _ time.Duration) bool {
+	if s.reject {
+		return false
+	}
+	s.entries = append(s.entries, entry)
+	return true
+}
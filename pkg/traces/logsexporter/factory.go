@@ -0,0 +1,53 @@
+package logsexporter
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
+)
+
+const (
+	// TypeStr is the unique identifier for the logs instance exporter.
+	TypeStr = "logs_instance"
+
+	defaultTimeout = time.Millisecond
+)
+
+var _ config.Exporter = (*Config)(nil)
+
+// Config holds the configuration for the logs instance exporter.
+type Config struct {
+	config.ExporterSettings `mapstructure:",squash"`
+
+	// LogsInstance is the name of the logs instance to send log records to.
+	LogsInstance string `mapstructure:"logs_instance"`
+	// Timeout is how long to wait for the logs instance to accept a record.
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
+// NewFactory returns a new factory for the logs instance exporter.
+func NewFactory() component.ExporterFactory {
+	return exporterhelper.NewFactory(
+		TypeStr,
+		createDefaultConfig,
+		exporterhelper.WithLogs(createLogsExporter),
+	)
+}
+
+func createDefaultConfig() config.Exporter {
+	return &Config{
+		ExporterSettings: config.NewExporterSettings(config.NewComponentID(TypeStr)),
+		Timeout:          defaultTimeout,
+	}
+}
+
+func createLogsExporter(
+	_ context.Context,
+	_ component.ExporterCreateSettings,
+	cfg config.Exporter,
+) (component.LogsExporter, error) {
+	return newLogsExporter(cfg.(*Config))
+}
@@ -0,0 +1,176 @@
+package traces
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery"
+	"github.com/prometheus/prometheus/discovery/kubernetes"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// kubernetesTagName discovers the load balancing peers from the endpoints
+	// of a Kubernetes service. It's converted into a static resolver.
+	kubernetesTagName = "kubernetes"
+
+	endpointReadyLabel model.LabelName = "__meta_kubernetes_endpoint_ready"
+)
+
+// kubernetesResolverConfig configures discovering the agents to load
+// balance spans between from the endpoints of a Kubernetes service.
+type kubernetesResolverConfig struct {
+	// Service is the name of the service which selects the agents. Required.
+	Service string `yaml:"service"`
+	// Namespace of the service. Services in all namespaces are discovered
+	// when unset.
+	Namespace string `yaml:"namespace,omitempty"`
+	// Port spans are sent to. Defaults to the receiver port.
+	Port string `yaml:"port,omitempty"`
+	// KubeconfigFile is used to connect to the API server when the agent
+	// doesn't run in the cluster.
+	KubeconfigFile string `yaml:"kubeconfig_file,omitempty"`
+}
+
+// kubernetesResolver returns the kubernetes resolver config of c, or nil if
+// c doesn't load balance with a kubernetes resolver.
+func (c *loadBalancingConfig) kubernetesResolver() (*kubernetesResolverConfig, error) {
+	raw, ok := c.Resolver[kubernetesTagName]
+	if !ok {
+		return nil, nil
+	}
+	bb, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var cfg kubernetesResolverConfig
+	if err := yaml.UnmarshalStrict(bb, &cfg); err != nil {
+		return nil, fmt.Errorf("invalid kubernetes resolver: %w", err)
+	}
+	if cfg.Service == "" {
+		return nil, fmt.Errorf("kubernetes resolver must have a service")
+	}
+	if cfg.Port == "" {
+		cfg.Port = c.receiverPort()
+	}
+	return &cfg, nil
+}
+
+// receiverPort returns the port load balanced spans are received on.
+func (c *loadBalancingConfig) receiverPort() string {
+	if c.ReceiverPort != "" {
+		return c.ReceiverPort
+	}
+	return defaultLoadBalancingPort
+}
+
+// peerDiscovery watches the ready endpoints of a Kubernetes service and
+// calls onChange with the sorted peers to load balance spans between
+// whenever they change.
+type peerDiscovery struct {
+	cancel context.CancelFunc
+}
+
+func newPeerDiscovery(logger log.Logger, cfg *kubernetesResolverConfig, onChange func(peers []string)) (*peerDiscovery, error) {
+	sdConfig := kubernetes.DefaultSDConfig
+	sdConfig.Role = kubernetes.RoleEndpoint
+	sdConfig.KubeConfig = cfg.KubeconfigFile
+	sdConfig.Selectors = []kubernetes.SelectorConfig{{
+		Role:  kubernetes.RoleEndpoint,
+		Field: "metadata.name=" + cfg.Service,
+	}}
+	if cfg.Namespace != "" {
+		sdConfig.NamespaceDiscovery.Names = []string{cfg.Namespace}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mgr := discovery.NewManager(ctx, logger, discovery.Name("traces load balancing peers"))
+	err := mgr.ApplyConfig(map[string]discovery.Configs{
+		cfg.Service: {&sdConfig},
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		err := mgr.Run()
+		if err != nil && err != context.Canceled {
+			level.Error(logger).Log("msg", "failed to run load balancing peer discovery", "err", err)
+		}
+	}()
+
+	go func() {
+		var last []string
+		for {
+			// SyncCh is never closed, so the context has to be watched too.
+			select {
+			case <-ctx.Done():
+				return
+			case tgs := <-mgr.SyncCh():
+				peers := discoveredPeers(tgs[cfg.Service], cfg.Port)
+				if equalPeers(peers, last) {
+					continue
+				}
+				level.Info(logger).Log("msg", "load balancing peers changed", "peers", fmt.Sprint(peers))
+				last = peers
+				onChange(peers)
+			}
+		}
+	}()
+
+	return &peerDiscovery{cancel: cancel}, nil
+}
+
+// Stop stops discovering peers.
+func (d *peerDiscovery) Stop() {
+	d.cancel()
+}
+
+// discoveredPeers returns the sorted, deduplicated host:port addresses of
+// the ready endpoints in groups.
+func discoveredPeers(groups []*targetgroup.Group, port string) []string {
+	seen := make(map[string]struct{})
+	for _, g := range groups {
+		for _, t := range g.Targets {
+			if t[endpointReadyLabel] == "false" {
+				continue
+			}
+			addr := string(t[model.AddressLabel])
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				// Endpoints without ports are discovered without one.
+				host = addr
+			}
+			if host == "" {
+				continue
+			}
+			seen[net.JoinHostPort(host, port)] = struct{}{}
+		}
+	}
+
+	peers := make([]string, 0, len(seen))
+	for p := range seen {
+		peers = append(peers, p)
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+func equalPeers(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,31 @@
+package traces
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiscoveredPeers(t *testing.T) {
+	groups := []*targetgroup.Group{
+		{
+			Targets: []model.LabelSet{
+				// Both ports of the same endpoint result in one peer.
+				{model.AddressLabel: "10.0.0.2:4317", endpointReadyLabel: "true"},
+				{model.AddressLabel: "10.0.0.2:14250", endpointReadyLabel: "true"},
+				{model.AddressLabel: "10.0.0.3:4317", endpointReadyLabel: "false"},
+			},
+		},
+		{
+			Targets: []model.LabelSet{
+				{model.AddressLabel: "10.0.0.1", endpointReadyLabel: "true"},
+			},
+		},
+	}
+
+	peers := discoveredPeers(groups, "4318")
+	require.Equal(t, []string{"10.0.0.1:4318", "10.0.0.2:4318"}, peers)
+	require.Empty(t, discoveredPeers(nil, "4318"))
+}
@@ -0,0 +1,93 @@
+package redactionprocessor
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/processor/processorhelper"
+)
+
+const (
+	// TypeStr is the unique identifier for the redaction processor.
+	TypeStr = "redaction"
+
+	// DefaultPlaceholder is the value redacted attributes are replaced with.
+	DefaultPlaceholder = "<redacted>"
+)
+
+// Actions supported by a redaction rule.
+const (
+	ActionDelete = "delete"
+	ActionHash   = "hash"
+	ActionRedact = "redact"
+)
+
+// Config holds the configuration for the redaction processor.
+type Config struct {
+	config.ProcessorSettings `mapstructure:",squash"`
+
+	Rules []Rule `mapstructure:"rules"`
+	// Placeholder replaces redacted values. Defaults to DefaultPlaceholder.
+	Placeholder string `mapstructure:"placeholder"`
+}
+
+// Rule selects span attributes and describes what to do with them.
+//
+// An attribute is selected if its key is one of Keys or matches KeyPattern.
+// When neither is set, every attribute is selected. If ValuePattern is set,
+// only string attributes whose value matches it are selected.
+type Rule struct {
+	Action       string   `mapstructure:"action" yaml:"action"`
+	Keys         []string `mapstructure:"keys" yaml:"keys,omitempty"`
+	KeyPattern   string   `mapstructure:"key_pattern" yaml:"key_pattern,omitempty"`
+	ValuePattern string   `mapstructure:"value_pattern" yaml:"value_pattern,omitempty"`
+}
+
+// Validate ensures that the Config is valid.
+func (c *Config) Validate() error {
+	for i, r := range c.Rules {
+		if _, err := compileRule(r); err != nil {
+			return fmt.Errorf("invalid redaction rule at index %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// NewFactory returns a new factory for the redaction processor.
+func NewFactory() component.ProcessorFactory {
+	return processorhelper.NewFactory(
+		TypeStr,
+		createDefaultConfig,
+		processorhelper.WithTraces(createTracesProcessor),
+	)
+}
+
+func createDefaultConfig() config.Processor {
+	return &Config{
+		ProcessorSettings: config.NewProcessorSettings(config.NewComponentIDWithName(TypeStr, TypeStr)),
+		Placeholder:       DefaultPlaceholder,
+	}
+}
+
+func createTracesProcessor(
+	_ context.Context,
+	_ component.ProcessorCreateSettings,
+	cfg config.Processor,
+	nextConsumer consumer.Traces,
+) (component.TracesProcessor, error) {
+	rCfg := cfg.(*Config)
+
+	p, err := newProcessor(rCfg)
+	if err != nil {
+		return nil, err
+	}
+	return processorhelper.NewTracesProcessor(
+		cfg,
+		nextConsumer,
+		p.processTraces,
+		processorhelper.WithCapabilities(consumer.Capabilities{MutatesData: true}),
+	)
+}
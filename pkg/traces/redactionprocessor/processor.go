@@ -0,0 +1,149 @@
+package redactionprocessor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+
+	"go.opentelemetry.io/collector/model/pdata"
+)
+
+type rule struct {
+	action       string
+	keys         map[string]struct{}
+	keyPattern   *regexp.Regexp
+	valuePattern *regexp.Regexp
+}
+
+func compileRule(r Rule) (*rule, error) {
+	switch r.Action {
+	case ActionDelete, ActionHash, ActionRedact:
+	default:
+		return nil, fmt.Errorf("unsupported action %q, expected %q, %q or %q", r.Action, ActionDelete, ActionHash, ActionRedact)
+	}
+
+	compiled := &rule{
+		action: r.Action,
+		keys:   make(map[string]struct{}, len(r.Keys)),
+	}
+	for _, k := range r.Keys {
+		compiled.keys[k] = struct{}{}
+	}
+
+	var err error
+	if r.KeyPattern != "" {
+		if compiled.keyPattern, err = regexp.Compile(r.KeyPattern); err != nil {
+			return nil, fmt.Errorf("invalid key_pattern: %w", err)
+		}
+	}
+	if r.ValuePattern != "" {
+		if compiled.valuePattern, err = regexp.Compile(r.ValuePattern); err != nil {
+			return nil, fmt.Errorf("invalid value_pattern: %w", err)
+		}
+	}
+	return compiled, nil
+}
+
+// matches returns true if the attribute is selected by the rule.
+func (r *rule) matches(key string, value pdata.AttributeValue) bool {
+	if len(r.keys) > 0 || r.keyPattern != nil {
+		_, found := r.keys[key]
+		if !found && (r.keyPattern == nil || !r.keyPattern.MatchString(key)) {
+			return false
+		}
+	}
+	if r.valuePattern != nil {
+		return value.Type() == pdata.AttributeValueTypeString && r.valuePattern.MatchString(value.StringVal())
+	}
+	return true
+}
+
+type processor struct {
+	rules       []*rule
+	placeholder string
+}
+
+func newProcessor(cfg *Config) (*processor, error) {
+	placeholder := cfg.Placeholder
+	if placeholder == "" {
+		placeholder = DefaultPlaceholder
+	}
+
+	rules := make([]*rule, 0, len(cfg.Rules))
+	for i, r := range cfg.Rules {
+		compiled, err := compileRule(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction rule at index %d: %w", i, err)
+		}
+		rules = append(rules, compiled)
+	}
+
+	return &processor{
+		rules:       rules,
+		placeholder: placeholder,
+	}, nil
+}
+
+func (p *processor) processTraces(_ context.Context, td pdata.Traces) (pdata.Traces, error) {
+	rss := td.ResourceSpans()
+	for i := 0; i < rss.Len(); i++ {
+		ilss := rss.At(i).InstrumentationLibrarySpans()
+		for j := 0; j < ilss.Len(); j++ {
+			spans := ilss.At(j).Spans()
+			for k := 0; k < spans.Len(); k++ {
+				span := spans.At(k)
+				p.processAttributes(span.Attributes())
+
+				events := span.Events()
+				for l := 0; l < events.Len(); l++ {
+					p.processAttributes(events.At(l).Attributes())
+				}
+			}
+		}
+	}
+	return td, nil
+}
+
+// processAttributes applies every rule, in order, to attrs.
+func (p *processor) processAttributes(attrs pdata.AttributeMap) {
+	for _, r := range p.rules {
+		// Attributes can't be modified while ranging over them, so changes
+		// are collected first.
+		var (
+			deleted []string
+			updated = map[string]string{}
+		)
+		attrs.Range(func(k string, v pdata.AttributeValue) bool {
+			if !r.matches(k, v) {
+				return true
+			}
+			switch r.action {
+			case ActionDelete:
+				deleted = append(deleted, k)
+			case ActionHash:
+				updated[k] = hash(v.AsString())
+			case ActionRedact:
+				if r.valuePattern != nil {
+					updated[k] = r.valuePattern.ReplaceAllLiteralString(v.StringVal(), p.placeholder)
+				} else {
+					updated[k] = p.placeholder
+				}
+			}
+			return true
+		})
+
+		for _, k := range deleted {
+			attrs.Delete(k)
+		}
+		for k, v := range updated {
+			attrs.UpdateString(k, v)
+		}
+	}
+}
+
+func hash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
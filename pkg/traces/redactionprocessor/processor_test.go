@@ -0,0 +1,95 @@
+package redactionprocessor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/model/pdata"
+)
+
+func TestProcessTraces(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		rules    []Rule
+		expected map[string]interface{}
+	}{
+		{
+			name:  "delete by key",
+			rules: []Rule{{Action: ActionDelete, Keys: []string{"user.email"}}},
+			expected: map[string]interface{}{
+				"http.url":    "http://example.com/login?token=abc123",
+				"http.status": int64(200),
+				"auth.token":  "abc123",
+			},
+		},
+		{
+			name:  "hash by key pattern",
+			rules: []Rule{{Action: ActionHash, KeyPattern: `^(user|auth)\.`}},
+			expected: map[string]interface{}{
+				"http.url":    "http://example.com/login?token=abc123",
+				"http.status": int64(200),
+				"user.email":  hash("jane@example.com"),
+				"auth.token":  hash("abc123"),
+			},
+		},
+		{
+			name:  "redact matching values",
+			rules: []Rule{{Action: ActionRedact, ValuePattern: `[a-z]+@example\.com|token=\w+`}},
+			expected: map[string]interface{}{
+				"http.url":    "http://example.com/login?" + DefaultPlaceholder,
+				"http.status": int64(200),
+				"user.email":  DefaultPlaceholder,
+				"auth.token":  "abc123",
+			},
+		},
+		{
+			name:  "redact whole value by key",
+			rules: []Rule{{Action: ActionRedact, Keys: []string{"auth.token", "http.status"}}},
+			expected: map[string]interface{}{
+				"http.url":    "http://example.com/login?token=abc123",
+				"http.status": DefaultPlaceholder,
+				"user.email":  "jane@example.com",
+				"auth.token":  DefaultPlaceholder,
+			},
+		},
+		{
+			name: "rules apply in order",
+			rules: []Rule{
+				{Action: ActionRedact, Keys: []string{"user.email"}},
+				{Action: ActionDelete, ValuePattern: "^" + DefaultPlaceholder + "$"},
+			},
+			expected: map[string]interface{}{
+				"http.url":    "http://example.com/login?token=abc123",
+				"http.status": int64(200),
+				"auth.token":  "abc123",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := newProcessor(&Config{Rules: tc.rules})
+			require.NoError(t, err)
+
+			td := pdata.NewTraces()
+			span := td.ResourceSpans().AppendEmpty().InstrumentationLibrarySpans().AppendEmpty().Spans().AppendEmpty()
+			span.Attributes().InsertString("http.url", "http://example.com/login?token=abc123")
+			span.Attributes().InsertInt("http.status", 200)
+			span.Attributes().InsertString("user.email", "jane@example.com")
+			span.Attributes().InsertString("auth.token", "abc123")
+
+			td, err = p.processTraces(context.Background(), td)
+			require.NoError(t, err)
+
+			attrs := td.ResourceSpans().At(0).InstrumentationLibrarySpans().At(0).Spans().At(0).Attributes()
+			require.Equal(t, tc.expected, attrs.AsRaw())
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := &Config{Rules: []Rule{{Action: ActionDelete, KeyPattern: "("}}}
+	require.EqualError(t, cfg.Validate(), "invalid redaction rule at index 0: invalid key_pattern: error parsing regexp: missing closing ): `(`")
+
+	cfg = &Config{Rules: []Rule{{Action: "mask"}}}
+	require.EqualError(t, cfg.Validate(), `invalid redaction rule at index 0: unsupported action "mask", expected "delete", "hash" or "redact"`)
+}
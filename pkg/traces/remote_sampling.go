@@ -0,0 +1,120 @@
+package traces
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+	k8s_yaml "sigs.k8s.io/yaml"
+)
+
+const (
+	jaegerReceiverType = "jaeger"
+
+	// strategiesKey is the key of the remote_sampling block of a jaeger
+	// receiver that holds inline sampling strategies. It's handled by the
+	// Agent and never passed to the receiver.
+	strategiesKey = "strategies"
+)
+
+// remoteSamplingReceivers returns a copy of receivers where the inline
+// sampling strategies of jaeger receivers are replaced by a strategy_file.
+// The jaeger receiver can only load strategies from a file or fetch them from
+// an upstream, so inline strategies are written to dir.
+func remoteSamplingReceivers(receivers ReceiverMap, dir string) (ReceiverMap, error) {
+	res := make(ReceiverMap, len(receivers))
+	for name, cfg := range receivers {
+		res[name] = cfg
+
+		if typ := strings.SplitN(name, "/", 2)[0]; typ != jaegerReceiverType {
+			continue
+		}
+		receiverCfg, ok := cfg.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		samplingCfg, ok := receiverCfg["remote_sampling"].(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		strategies, ok := samplingCfg[strategiesKey]
+		if !ok {
+			continue
+		}
+
+		if samplingCfg["strategy_file"] != nil || samplingCfg["endpoint"] != nil {
+			return nil, fmt.Errorf("receiver %s: remote_sampling must configure only one of strategies, strategy_file and endpoint", name)
+		}
+		path, err := writeStrategies(strategies, dir)
+		if err != nil {
+			return nil, fmt.Errorf("receiver %s: %w", name, err)
+		}
+
+		newSamplingCfg := make(map[interface{}]interface{}, len(samplingCfg))
+		for k, v := range samplingCfg {
+			if k != strategiesKey {
+				newSamplingCfg[k] = v
+			}
+		}
+		newSamplingCfg["strategy_file"] = path
+
+		// The HTTP sampling endpoint fetches the strategies from the gRPC
+		// server of the receiver itself, which doesn't use TLS by default.
+		if grpcEndpoint := receiverGRPCEndpoint(receiverCfg); grpcEndpoint != "" {
+			newSamplingCfg["endpoint"] = grpcEndpoint
+		}
+		if _, ok := newSamplingCfg["tls"]; !ok {
+			newSamplingCfg["tls"] = map[interface{}]interface{}{"insecure": true}
+		}
+
+		newReceiverCfg := make(map[interface{}]interface{}, len(receiverCfg))
+		for k, v := range receiverCfg {
+			newReceiverCfg[k] = v
+		}
+		newReceiverCfg["remote_sampling"] = newSamplingCfg
+		res[name] = newReceiverCfg
+	}
+	return res, nil
+}
+
+// receiverGRPCEndpoint returns the endpoint of the gRPC protocol of a jaeger
+// receiver, if one is configured.
+func receiverGRPCEndpoint(receiverCfg map[interface{}]interface{}) string {
+	protocols, _ := receiverCfg["protocols"].(map[interface{}]interface{})
+	grpcCfg, _ := protocols["grpc"].(map[interface{}]interface{})
+	endpoint, _ := grpcCfg["endpoint"].(string)
+	return endpoint
+}
+
+// writeStrategies writes strategies as JSON to a file in dir named after its
+// content, so applying the same config again reuses the same file.
+func writeStrategies(strategies interface{}, dir string) (string, error) {
+	if strategies == nil {
+		return "", errors.New("remote_sampling strategies must not be empty")
+	}
+	bb, err := yaml.Marshal(strategies)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal remote_sampling strategies: %w", err)
+	}
+	bb, err = k8s_yaml.YAMLToJSON(bb)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert remote_sampling strategies to JSON: %w", err)
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("agent-jaeger-strategies-%x.json", sha256.Sum256(bb)))
+	if existing, err := ioutil.ReadFile(path); err == nil && string(existing) == string(bb) {
+		return path, nil
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", fmt.Errorf("failed to create remote_sampling strategies directory: %w", err)
+	}
+	if err := ioutil.WriteFile(path, bb, 0640); err != nil {
+		return "", fmt.Errorf("failed to write remote_sampling strategies: %w", err)
+	}
+	return path, nil
+}
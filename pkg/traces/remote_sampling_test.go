@@ -0,0 +1,130 @@
+package traces
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/agent/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"github.com/weaveworks/common/logging"
+	"gopkg.in/yaml.v2"
+)
+
+func TestRemoteSamplingReceivers(t *testing.T) {
+	cfgText := `
+jaeger:
+  protocols:
+    grpc:
+  remote_sampling:
+    host_endpoint: 0.0.0.0:5778
+    strategies:
+      default_strategy:
+        type: probabilistic
+        param: 0.5
+otlp:
+  protocols:
+    grpc:
+`
+	var receivers ReceiverMap
+	require.NoError(t, yaml.Unmarshal([]byte(cfgText), &receivers))
+
+	dir := t.TempDir()
+	actual, err := remoteSamplingReceivers(receivers, dir)
+	require.NoError(t, err)
+	require.Equal(t, receivers["otlp"], actual["otlp"])
+
+	samplingCfg := actual["jaeger"].(map[interface{}]interface{})["remote_sampling"].(map[interface{}]interface{})
+	require.NotContains(t, samplingCfg, "strategies")
+	require.Equal(t, "0.0.0.0:5778", samplingCfg["host_endpoint"])
+	require.Equal(t, map[interface{}]interface{}{"insecure": true}, samplingCfg["tls"])
+
+	bb, err := ioutil.ReadFile(samplingCfg["strategy_file"].(string))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"default_strategy": {"type": "probabilistic", "param": 0.5}}`, string(bb))
+
+	// The original config must not be modified.
+	originalCfg := receivers["jaeger"].(map[interface{}]interface{})["remote_sampling"].(map[interface{}]interface{})
+	require.Contains(t, originalCfg, "strategies")
+
+	// Applying the same config reuses the same file.
+	again, err := remoteSamplingReceivers(receivers, dir)
+	require.NoError(t, err)
+	require.Equal(t, actual, again)
+}
+
+func TestRemoteSamplingReceivers_Invalid(t *testing.T) {
+	cfgText := `
+jaeger:
+  protocols:
+    grpc:
+  remote_sampling:
+    strategy_file: strategies.json
+    strategies:
+      default_strategy:
+        type: probabilistic
+        param: 0.5
+`
+	var receivers ReceiverMap
+	require.NoError(t, yaml.Unmarshal([]byte(cfgText), &receivers))
+
+	_, err := remoteSamplingReceivers(receivers, t.TempDir())
+	require.EqualError(t, err, "receiver jaeger: remote_sampling must configure only one of strategies, strategy_file and endpoint")
+}
+
+func TestRemoteSampling_Serve(t *testing.T) {
+	grpcAddr, samplingAddr := freeAddr(t), freeAddr(t)
+
+	tracesCfgText := util.Untab(fmt.Sprintf(`
+configs:
+- name: default
+  receivers:
+    jaeger:
+      protocols:
+        grpc:
+          endpoint: %s
+      remote_sampling:
+        host_endpoint: %s
+        strategies:
+          default_strategy:
+            type: probabilistic
+            param: 0.25
+  remote_write:
+    - endpoint: 127.0.0.1:80
+      insecure: true
+	`, grpcAddr, samplingAddr))
+
+	var cfg Config
+	dec := yaml.NewDecoder(strings.NewReader(tracesCfgText))
+	dec.SetStrict(true)
+	require.NoError(t, dec.Decode(&cfg))
+
+	traces, err := New(nil, nil, prometheus.NewRegistry(), cfg, logrus.InfoLevel, logging.Format{})
+	require.NoError(t, err)
+	t.Cleanup(traces.Stop)
+
+	require.Eventually(t, func() bool {
+		resp, err := http.Get(fmt.Sprintf("http://%s/sampling?service=foo", samplingAddr))
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		bb, err := ioutil.ReadAll(resp.Body)
+		return err == nil && resp.StatusCode == http.StatusOK && strings.Contains(string(bb), `"samplingRate":0.25`)
+	}, 10*time.Second, 100*time.Millisecond)
+}
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	return lis.Addr().String()
+}
@@ -27,6 +27,7 @@ const (
 	countSuffix   = "count"
 	bucketSuffix  = "bucket"
 	leStr         = "le"
+	quantileStr   = "quantile"
 	infBucket     = "+Inf"
 	counterSuffix = "total"
 	noSuffix      = ""
@@ -113,7 +114,9 @@ func (e *remoteWriteExporter) ConsumeMetrics(ctx context.Context, md pdata.Metri
 						return fmt.Errorf("failed to process metric %s", err)
 					}
 				case pdata.MetricDataTypeSummary:
-					return fmt.Errorf("%s processing unimplemented", m.DataType())
+					if err := e.processSummaryMetrics(app, m); err != nil {
+						return fmt.Errorf("failed to process metric %s", err)
+					}
 				default:
 					return fmt.Errorf("unsupported m data type %s", m.DataType())
 				}
@@ -162,11 +165,39 @@ func (e *remoteWriteExporter) handleHistogramIntDataPoints(app storage.Appender,
 	return nil
 }
 
+func (e *remoteWriteExporter) processSummaryMetrics(app storage.Appender, m pdata.Metric) error {
+	dataPoints := m.Summary().DataPoints()
+	for ix := 0; ix < dataPoints.Len(); ix++ {
+		dataPoint := dataPoints.At(ix)
+		if err := e.appendDataPoint(app, m.Name(), sumSuffix, dataPoint, dataPoint.Sum()); err != nil {
+			return err
+		}
+		if err := e.appendDataPoint(app, m.Name(), countSuffix, dataPoint, float64(dataPoint.Count())); err != nil {
+			return err
+		}
+
+		quantiles := dataPoint.QuantileValues()
+		for qx := 0; qx < quantiles.Len(); qx++ {
+			q := quantiles.At(qx)
+			ls := labels.Labels{{Name: quantileStr, Value: strconv.FormatFloat(q.Quantile(), 'f', -1, 64)}}
+			if err := e.appendDataPointWithLabels(app, m.Name(), noSuffix, dataPoint, q.Value(), ls); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (e *remoteWriteExporter) processScalarMetric(app storage.Appender, m pdata.Metric) error {
 	switch m.DataType() {
 	case pdata.MetricDataTypeSum:
+		// Only monotonic sums are counters, others are exported as gauges.
+		suffix := noSuffix
+		if m.Sum().IsMonotonic() {
+			suffix = counterSuffix
+		}
 		dataPoints := m.Sum().DataPoints()
-		if err := e.handleScalarIntDataPoints(app, m.Name(), counterSuffix, dataPoints); err != nil {
+		if err := e.handleScalarIntDataPoints(app, m.Name(), suffix, dataPoints); err != nil {
 			return err
 		}
 	case pdata.MetricDataTypeGauge:
@@ -181,7 +212,11 @@ func (e *remoteWriteExporter) processScalarMetric(app storage.Appender, m pdata.
 func (e *remoteWriteExporter) handleScalarIntDataPoints(app storage.Appender, name, suffix string, dataPoints pdata.NumberDataPointSlice) error {
 	for ix := 0; ix < dataPoints.Len(); ix++ {
 		dataPoint := dataPoints.At(ix)
-		if err := e.appendDataPoint(app, name, suffix, dataPoint, float64(dataPoint.IntVal())); err != nil {
+		v := float64(dataPoint.IntVal())
+		if dataPoint.Type() == pdata.MetricValueTypeDouble {
+			v = dataPoint.DoubleVal()
+		}
+		if err := e.appendDataPoint(app, name, suffix, dataPoint, v); err != nil {
 			return err
 		}
 	}
@@ -208,8 +243,8 @@ func (e *remoteWriteExporter) createLabelSet(name, suffix string, labelMap pdata
 	// Labels from spanmetrics processor
 	labelMap.Range(func(k string, v pdata.AttributeValue) bool {
 		ls = append(ls, labels.Label{
-			Name:  strings.Replace(k, ".", "_", -1),
-			Value: v.StringVal(),
+			Name:  sanitizeName(k),
+			Value: v.AsString(),
 		})
 		return true
 	})
@@ -226,8 +261,27 @@ func (e *remoteWriteExporter) createLabelSet(name, suffix string, labelMap pdata
 }
 
 func metricName(namespace, metric, suffix string) string {
+	name := sanitizeName(metric)
+	if len(namespace) != 0 {
+		name = fmt.Sprintf("%s_%s", namespace, name)
+	}
+	// Counters that already end in _total aren't suffixed twice.
+	if suffix == counterSuffix && strings.HasSuffix(name, "_"+counterSuffix) {
+		return name
+	}
 	if len(suffix) != 0 {
-		return fmt.Sprintf("%s_%s_%s", namespace, metric, suffix)
+		name = fmt.Sprintf("%s_%s", name, suffix)
 	}
-	return fmt.Sprintf("%s_%s", namespace, metric)
+	return name
+}
+
+// sanitizeName replaces the characters of OTel metric and attribute names
+// that aren't valid in Prometheus names with underscores.
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == ':' {
+			return r
+		}
+		return '_'
+	}, name)
 }
@@ -148,3 +148,46 @@ func (a *mockAppender) Rollback() error { return nil }
 func (a *mockAppender) AppendExemplar(_ uint64, _ labels.Labels, _ exemplar.Exemplar) (uint64, error) {
 	return 0, nil
 }
+
+func TestRemoteWriteExporter_processSummaryMetrics(t *testing.T) {
+	manager := &mockManager{}
+	exp := remoteWriteExporter{
+		manager:      manager,
+		promInstance: "default",
+	}
+	instance, _ := manager.GetInstance("default")
+	app := instance.Appender(context.TODO())
+
+	m := pdata.NewMetric()
+	m.SetName("http.server.duration")
+	m.SetDataType(pdata.MetricDataTypeSummary)
+	dp := m.Summary().DataPoints().AppendEmpty()
+	dp.Attributes().InsertString("http.method", "GET")
+	dp.SetCount(10)
+	dp.SetSum(25)
+	q := dp.QuantileValues().AppendEmpty()
+	q.SetQuantile(0.99)
+	q.SetValue(4.5)
+
+	require.NoError(t, exp.processSummaryMetrics(app, m))
+
+	sum := manager.instance.GetAppended("http_server_duration_sum")
+	require.Len(t, sum, 1)
+	require.Equal(t, 25.0, sum[0].v)
+	require.Equal(t, "GET", sum[0].l.Get("http_method"))
+
+	count := manager.instance.GetAppended("http_server_duration_count")
+	require.Len(t, count, 1)
+	require.Equal(t, 10.0, count[0].v)
+
+	quantiles := manager.instance.GetAppended("http_server_duration")
+	require.Len(t, quantiles, 1)
+	require.Equal(t, 4.5, quantiles[0].v)
+	require.Equal(t, "0.99", quantiles[0].l.Get(quantileStr))
+}
+
+func TestMetricName(t *testing.T) {
+	require.Equal(t, "traces_spanmetrics_calls_total", metricName("traces_spanmetrics", "calls", counterSuffix))
+	require.Equal(t, "http_requests_total", metricName("", "http.requests_total", counterSuffix))
+	require.Equal(t, "queue_size", metricName("", "queue.size", noSuffix))
+}
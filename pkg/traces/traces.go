@@ -100,6 +100,14 @@ func (t *Traces) ApplyConfig(logsSubsystem *logs.Logs, promInstanceManager insta
 	return nil
 }
 
+// Instance returns the traces instance named name, or nil if it doesn't
+// exist.
+func (t *Traces) Instance(name string) *Instance {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+	return t.instances[name]
+}
+
 // Stop stops the OpenTelemetry collector subsystem
 func (t *Traces) Stop() {
 	t.mut.Lock()
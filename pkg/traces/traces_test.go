@@ -48,7 +48,8 @@ configs:
 	var loggingLevel logging.Level
 	require.NoError(t, loggingLevel.Set("debug"))
 
-	traces, err := New(nil, nil, prometheus.NewRegistry(), cfg, logrus.InfoLevel, logging.Format{})
+	reg := prometheus.NewRegistry()
+	traces, err := New(nil, nil, reg, cfg, logrus.InfoLevel, logging.Format{})
 	require.NoError(t, err)
 	t.Cleanup(traces.Stop)
 
@@ -63,6 +64,20 @@ configs:
 		require.Equal(t, 1, tr.SpanCount())
 		// Nothing to do, send succeeded.
 	}
+
+	// The sending queue metrics are exposed alongside the pipeline metrics.
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+	names := make(map[string]struct{}, len(mfs))
+	for _, mf := range mfs {
+		names[mf.GetName()] = struct{}{}
+	}
+	for _, name := range []string{
+		"traces_exporter_queue_size",
+		"traces_exporter_enqueue_failed_spans",
+	} {
+		require.Contains(t, names, name)
+	}
 }
 
 func TestTrace_ApplyConfig(t *testing.T) {
@@ -0,0 +1,52 @@
+package xrayreceiver
+
+import (
+	"context"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/receiver/receiverhelper"
+)
+
+const (
+	// TypeStr is the unique identifier for the AWS X-Ray receiver.
+	TypeStr = "awsxray"
+
+	// DefaultEndpoint is the UDP address X-Ray SDKs send segments to by
+	// default.
+	DefaultEndpoint = "0.0.0.0:2000"
+)
+
+// Config defines configuration for the AWS X-Ray receiver.
+type Config struct {
+	config.ReceiverSettings `mapstructure:",squash"`
+
+	// Endpoint is the UDP address to receive segments on.
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+// NewFactory creates a factory for the AWS X-Ray receiver.
+func NewFactory() component.ReceiverFactory {
+	return receiverhelper.NewFactory(
+		TypeStr,
+		createDefaultConfig,
+		receiverhelper.WithTraces(createTracesReceiver),
+	)
+}
+
+func createDefaultConfig() config.Receiver {
+	return &Config{
+		ReceiverSettings: config.NewReceiverSettings(config.NewComponentID(TypeStr)),
+		Endpoint:         DefaultEndpoint,
+	}
+}
+
+func createTracesReceiver(
+	_ context.Context,
+	settings component.ReceiverCreateSettings,
+	cfg config.Receiver,
+	nextConsumer consumer.Traces,
+) (component.TracesReceiver, error) {
+	return newReceiver(cfg.(*Config), nextConsumer, settings)
+}
@@ -0,0 +1,116 @@
+package xrayreceiver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/component/componenterror"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/obsreport"
+	"go.uber.org/zap"
+)
+
+const (
+	transport = "udp"
+	format    = "xray"
+
+	// maxPacketSize is the maximum size of a UDP datagram.
+	maxPacketSize = 64 * 1024
+)
+
+type receiver struct {
+	cfg          *Config
+	nextConsumer consumer.Traces
+	logger       *zap.Logger
+	obsrecv      *obsreport.Receiver
+
+	conn net.PacketConn
+	wg   sync.WaitGroup
+}
+
+func newReceiver(cfg *Config, nextConsumer consumer.Traces, settings component.ReceiverCreateSettings) (*receiver, error) {
+	if nextConsumer == nil {
+		return nil, componenterror.ErrNilNextConsumer
+	}
+	if cfg.Endpoint == "" {
+		return nil, errors.New("endpoint must not be empty")
+	}
+
+	return &receiver{
+		cfg:          cfg,
+		nextConsumer: nextConsumer,
+		logger:       settings.Logger,
+		obsrecv: obsreport.NewReceiver(obsreport.ReceiverSettings{
+			ReceiverID:             cfg.ID(),
+			Transport:              transport,
+			ReceiverCreateSettings: settings,
+		}),
+	}, nil
+}
+
+// Start implements the Component interface.
+func (r *receiver) Start(_ context.Context, _ component.Host) error {
+	conn, err := net.ListenPacket("udp", r.cfg.Endpoint)
+	if err != nil {
+		return err
+	}
+	r.conn = conn
+
+	r.wg.Add(1)
+	go r.run()
+	return nil
+}
+
+func (r *receiver) run() {
+	defer r.wg.Done()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, _, err := r.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			r.logger.Warn("failed to read X-Ray segment", zap.Error(err))
+			continue
+		}
+		r.handlePacket(buf[:n])
+	}
+}
+
+func (r *receiver) handlePacket(packet []byte) {
+	ctx := r.obsrecv.StartTracesOp(context.Background())
+
+	seg, err := parseSegment(packet)
+	if err != nil {
+		r.logger.Debug("dropping invalid X-Ray segment", zap.Error(err))
+		r.obsrecv.EndTracesOp(ctx, format, 0, err)
+		return
+	}
+	td, err := toTraces(seg)
+	if err != nil {
+		r.logger.Debug("dropping invalid X-Ray segment", zap.Error(err))
+		r.obsrecv.EndTracesOp(ctx, format, 0, err)
+		return
+	}
+	if td.SpanCount() == 0 {
+		r.obsrecv.EndTracesOp(ctx, format, 0, nil)
+		return
+	}
+
+	err = r.nextConsumer.ConsumeTraces(ctx, td)
+	r.obsrecv.EndTracesOp(ctx, format, td.SpanCount(), err)
+}
+
+// Shutdown implements the Component interface.
+func (r *receiver) Shutdown(context.Context) error {
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.wg.Wait()
+	return err
+}
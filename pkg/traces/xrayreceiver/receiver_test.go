@@ -0,0 +1,120 @@
+package xrayreceiver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumertest"
+	"go.opentelemetry.io/collector/model/pdata"
+	semconv "go.opentelemetry.io/collector/model/semconv/v1.6.1"
+)
+
+const testSegment = `{"format": "json", "version": 1}
+{
+  "name": "checkout",
+  "id": "70de5b6f19ff9a0a",
+  "trace_id": "1-581cf771-a006649127e371903a2de979",
+  "start_time": 1478293361.271,
+  "end_time": 1478293361.449,
+  "fault": true,
+  "cause": {"exceptions": [{"type": "RuntimeError", "message": "out of stock"}]},
+  "http": {
+    "request": {"method": "POST", "url": "https://example.com/checkout"},
+    "response": {"status": 500}
+  },
+  "annotations": {"customer": "acme", "items": 3},
+  "subsegments": [
+    {
+      "name": "DynamoDB",
+      "id": "53995c3f42cd8ad8",
+      "namespace": "aws",
+      "start_time": 1478293361.3,
+      "end_time": 1478293361.4
+    },
+    {
+      "name": "pending",
+      "id": "53995c3f42cd8ad9",
+      "start_time": 1478293361.3,
+      "in_progress": true
+    }
+  ]
+}`
+
+func TestTranslate(t *testing.T) {
+	seg, err := parseSegment([]byte(testSegment))
+	require.NoError(t, err)
+	td, err := toTraces(seg)
+	require.NoError(t, err)
+	require.Equal(t, 2, td.SpanCount())
+
+	rs := td.ResourceSpans().At(0)
+	serviceName, _ := rs.Resource().Attributes().Get(semconv.AttributeServiceName)
+	require.Equal(t, "checkout", serviceName.StringVal())
+
+	spans := rs.InstrumentationLibrarySpans().At(0).Spans()
+	root, child := spans.At(0), spans.At(1)
+
+	require.Equal(t, "581cf771a006649127e371903a2de979", root.TraceID().HexString())
+	require.Equal(t, "70de5b6f19ff9a0a", root.SpanID().HexString())
+	require.True(t, root.ParentSpanID().IsEmpty())
+	require.Equal(t, pdata.SpanKindServer, root.Kind())
+	require.Equal(t, pdata.StatusCodeError, root.Status().Code())
+	require.Equal(t, "out of stock", root.Status().Message())
+	require.Equal(t, uint64(178e6), uint64(root.EndTimestamp()-root.StartTimestamp()))
+	require.Equal(t, map[string]interface{}{
+		semconv.AttributeHTTPMethod:     "POST",
+		semconv.AttributeHTTPURL:        "https://example.com/checkout",
+		semconv.AttributeHTTPStatusCode: int64(500),
+		"customer":                      "acme",
+		"items":                         int64(3),
+	}, root.Attributes().AsRaw())
+	require.Equal(t, 1, root.Events().Len())
+	require.Equal(t, exceptionEventName, root.Events().At(0).Name())
+
+	require.Equal(t, root.TraceID(), child.TraceID())
+	require.Equal(t, root.SpanID(), child.ParentSpanID())
+	require.Equal(t, pdata.SpanKindClient, child.Kind())
+	require.Equal(t, pdata.StatusCodeUnset, child.Status().Code())
+}
+
+func TestTranslate_Invalid(t *testing.T) {
+	_, err := parseSegment([]byte(`{"name": "checkout"}`))
+	require.EqualError(t, err, "missing X-Ray header")
+
+	seg, err := parseSegment([]byte(`{"format": "json", "version": 1}
+{"name": "checkout", "id": "70de5b6f19ff9a0a", "trace_id": "581cf771"}`))
+	require.NoError(t, err)
+	_, err = toTraces(seg)
+	require.EqualError(t, err, `invalid X-Ray trace ID "581cf771"`)
+}
+
+func TestReceiver(t *testing.T) {
+	lis, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.LocalAddr().String()
+	require.NoError(t, lis.Close())
+
+	cfg := createDefaultConfig().(*Config)
+	cfg.Endpoint = addr
+
+	sink := new(consumertest.TracesSink)
+	r, err := createTracesReceiver(context.Background(), componenttest.NewNopReceiverCreateSettings(), cfg, sink)
+	require.NoError(t, err)
+	require.NoError(t, r.Start(context.Background(), componenttest.NewNopHost()))
+	t.Cleanup(func() { require.NoError(t, r.Shutdown(context.Background())) })
+
+	conn, err := net.Dial("udp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(testSegment))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return sink.SpanCount() == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,245 @@
+package xrayreceiver
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"go.opentelemetry.io/collector/model/pdata"
+	semconv "go.opentelemetry.io/collector/model/semconv/v1.6.1"
+)
+
+// segment is an X-Ray segment or subsegment document.
+// https://docs.aws.amazon.com/xray/latest/devguide/xray-api-segmentdocuments.html
+type segment struct {
+	Name        string                 `json:"name"`
+	ID          string                 `json:"id"`
+	TraceID     string                 `json:"trace_id"`
+	ParentID    string                 `json:"parent_id"`
+	Type        string                 `json:"type"`
+	Namespace   string                 `json:"namespace"`
+	Origin      string                 `json:"origin"`
+	StartTime   float64                `json:"start_time"`
+	EndTime     float64                `json:"end_time"`
+	InProgress  bool                   `json:"in_progress"`
+	Error       bool                   `json:"error"`
+	Fault       bool                   `json:"fault"`
+	Throttle    bool                   `json:"throttle"`
+	Cause       *cause                 `json:"cause"`
+	HTTP        *httpData              `json:"http"`
+	Annotations map[string]interface{} `json:"annotations"`
+	Subsegments []segment              `json:"subsegments"`
+}
+
+type cause struct {
+	Exceptions []struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+	} `json:"exceptions"`
+}
+
+type httpData struct {
+	Request *struct {
+		Method    string `json:"method"`
+		URL       string `json:"url"`
+		ClientIP  string `json:"client_ip"`
+		UserAgent string `json:"user_agent"`
+	} `json:"request"`
+	Response *struct {
+		Status        int64 `json:"status"`
+		ContentLength int64 `json:"content_length"`
+	} `json:"response"`
+}
+
+// Attribute keys for X-Ray fields without a semantic convention.
+const (
+	attributeOrigin    = "aws.xray.origin"
+	attributeNamespace = "aws.xray.namespace"
+	attributeThrottle  = "aws.xray.throttle"
+
+	exceptionEventName = "exception"
+)
+
+// parseSegment parses a UDP packet made of the X-Ray daemon header and a
+// segment document.
+func parseSegment(packet []byte) (*segment, error) {
+	parts := strings.SplitN(string(packet), "\n", 2)
+	if len(parts) != 2 {
+		return nil, errors.New("missing X-Ray header")
+	}
+
+	var header struct {
+		Format  string `json:"format"`
+		Version int    `json:"version"`
+	}
+	if err := json.Unmarshal([]byte(parts[0]), &header); err != nil {
+		return nil, fmt.Errorf("invalid X-Ray header: %w", err)
+	}
+	if header.Format != "json" {
+		return nil, fmt.Errorf("unsupported X-Ray format %q", header.Format)
+	}
+
+	var seg segment
+	if err := json.Unmarshal([]byte(parts[1]), &seg); err != nil {
+		return nil, fmt.Errorf("invalid X-Ray segment: %w", err)
+	}
+	return &seg, nil
+}
+
+// toTraces translates a segment and its subsegments into OTLP spans.
+// Segments that are still in progress are skipped, SDKs send them again once
+// they're complete.
+func toTraces(seg *segment) (pdata.Traces, error) {
+	td := pdata.NewTraces()
+	if seg.InProgress {
+		return td, nil
+	}
+
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.Resource().Attributes().InsertString(semconv.AttributeServiceName, seg.Name)
+	if seg.Origin != "" {
+		rs.Resource().Attributes().InsertString(semconv.AttributeCloudProvider, semconv.AttributeCloudProviderAWS)
+		rs.Resource().Attributes().InsertString(attributeOrigin, seg.Origin)
+	}
+	spans := rs.InstrumentationLibrarySpans().AppendEmpty().Spans()
+
+	traceID, err := parseTraceID(seg.TraceID)
+	if err != nil {
+		return td, err
+	}
+	return td, addSpans(spans, traceID, seg, seg.ParentID, seg.Type != "subsegment")
+}
+
+func addSpans(spans pdata.SpanSlice, traceID pdata.TraceID, seg *segment, parentID string, root bool) error {
+	if seg.InProgress {
+		return nil
+	}
+
+	spanID, err := parseSpanID(seg.ID)
+	if err != nil {
+		return err
+	}
+
+	span := spans.AppendEmpty()
+	span.SetTraceID(traceID)
+	span.SetSpanID(spanID)
+	if parentID != "" {
+		parentSpanID, err := parseSpanID(parentID)
+		if err != nil {
+			return err
+		}
+		span.SetParentSpanID(parentSpanID)
+	}
+	span.SetName(seg.Name)
+	span.SetStartTimestamp(toTimestamp(seg.StartTime))
+	span.SetEndTimestamp(toTimestamp(seg.EndTime))
+
+	switch {
+	case seg.Namespace == "remote" || seg.Namespace == "aws":
+		span.SetKind(pdata.SpanKindClient)
+	case root:
+		span.SetKind(pdata.SpanKindServer)
+	default:
+		span.SetKind(pdata.SpanKindInternal)
+	}
+
+	if seg.Error || seg.Fault {
+		span.Status().SetCode(pdata.StatusCodeError)
+		if seg.Cause != nil && len(seg.Cause.Exceptions) > 0 {
+			span.Status().SetMessage(seg.Cause.Exceptions[0].Message)
+		}
+	}
+
+	attrs := span.Attributes()
+	if seg.Namespace != "" {
+		attrs.InsertString(attributeNamespace, seg.Namespace)
+	}
+	if seg.Throttle {
+		attrs.InsertBool(attributeThrottle, true)
+	}
+	if seg.HTTP != nil {
+		if req := seg.HTTP.Request; req != nil {
+			insertNonEmpty(attrs, semconv.AttributeHTTPMethod, req.Method)
+			insertNonEmpty(attrs, semconv.AttributeHTTPURL, req.URL)
+			insertNonEmpty(attrs, semconv.AttributeHTTPClientIP, req.ClientIP)
+			insertNonEmpty(attrs, semconv.AttributeHTTPUserAgent, req.UserAgent)
+		}
+		if resp := seg.HTTP.Response; resp != nil {
+			if resp.Status != 0 {
+				attrs.InsertInt(semconv.AttributeHTTPStatusCode, resp.Status)
+			}
+			if resp.ContentLength != 0 {
+				attrs.InsertInt(semconv.AttributeHTTPResponseContentLength, resp.ContentLength)
+			}
+		}
+	}
+	for k, v := range seg.Annotations {
+		switch v := v.(type) {
+		case string:
+			attrs.InsertString(k, v)
+		case bool:
+			attrs.InsertBool(k, v)
+		case float64:
+			if v == math.Trunc(v) {
+				attrs.InsertInt(k, int64(v))
+			} else {
+				attrs.InsertDouble(k, v)
+			}
+		}
+	}
+	if seg.Cause != nil {
+		for _, exc := range seg.Cause.Exceptions {
+			event := span.Events().AppendEmpty()
+			event.SetName(exceptionEventName)
+			event.SetTimestamp(span.EndTimestamp())
+			insertNonEmpty(event.Attributes(), semconv.AttributeExceptionType, exc.Type)
+			insertNonEmpty(event.Attributes(), semconv.AttributeExceptionMessage, exc.Message)
+		}
+	}
+
+	for i := range seg.Subsegments {
+		if err := addSpans(spans, traceID, &seg.Subsegments[i], seg.ID, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertNonEmpty(attrs pdata.AttributeMap, key, value string) {
+	if value != "" {
+		attrs.InsertString(key, value)
+	}
+}
+
+// parseTraceID parses an X-Ray trace ID of the form 1-<8 hex digits>-<24 hex
+// digits>.
+func parseTraceID(id string) (pdata.TraceID, error) {
+	parts := strings.Split(id, "-")
+	if len(parts) != 3 || parts[0] != "1" || len(parts[1]) != 8 || len(parts[2]) != 24 {
+		return pdata.InvalidTraceID(), fmt.Errorf("invalid X-Ray trace ID %q", id)
+	}
+	var raw [16]byte
+	if _, err := hex.Decode(raw[:], []byte(parts[1]+parts[2])); err != nil {
+		return pdata.InvalidTraceID(), fmt.Errorf("invalid X-Ray trace ID %q: %w", id, err)
+	}
+	return pdata.NewTraceID(raw), nil
+}
+
+func parseSpanID(id string) (pdata.SpanID, error) {
+	var raw [8]byte
+	if len(id) != 16 {
+		return pdata.InvalidSpanID(), fmt.Errorf("invalid X-Ray segment ID %q", id)
+	}
+	if _, err := hex.Decode(raw[:], []byte(id)); err != nil {
+		return pdata.InvalidSpanID(), fmt.Errorf("invalid X-Ray segment ID %q: %w", id, err)
+	}
+	return pdata.NewSpanID(raw), nil
+}
+
+// toTimestamp converts X-Ray epoch seconds to a timestamp.
+func toTimestamp(seconds float64) pdata.Timestamp {
+	return pdata.Timestamp(uint64(math.Round(seconds * 1e6)) * 1e3)
+}
@@ -3,19 +3,55 @@ package util
 import (
 	"bytes"
 
+	config_util "github.com/prometheus/common/config"
 	"gopkg.in/yaml.v2"
 )
 
 // CompareYAML marshals a and b to YAML and ensures that their contents are
 // equal. If either Marshal fails, CompareYAML returns false.
+//
+// Secrets are compared by their values rather than by the "<secret>" text
+// they are marshaled to, so changing only a secret is detected.
 func CompareYAML(a, b interface{}) bool {
-	aBytes, err := yaml.Marshal(a)
+	aBytes, err := marshalUnredacted(a)
 	if err != nil {
 		return false
 	}
-	bBytes, err := yaml.Marshal(b)
+	bBytes, err := marshalUnredacted(b)
 	if err != nil {
 		return false
 	}
 	return bytes.Equal(aBytes, bBytes)
 }
+
+// UnredactedMarshaler is implemented by types whose MarshalYAML redacts
+// secrets on its own, which CompareYAML can't prevent. MarshalUnredactedYAML
+// returns the value to marshal in place of the type with its secrets kept.
+type UnredactedMarshaler interface {
+	MarshalUnredactedYAML() (interface{}, error)
+}
+
+// marshalUnredacted marshals v to YAML, keeping the values of secrets.
+func marshalUnredacted(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := yaml.NewEncoder(&buf)
+	enc.SetHook(func(in interface{}) (ok bool, out interface{}, err error) {
+		switch v := in.(type) {
+		case config_util.Secret:
+			return true, string(v), nil
+		case UnredactedMarshaler:
+			out, err := v.MarshalUnredactedYAML()
+			return true, out, err
+		default:
+			return false, nil, nil
+		}
+	})
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
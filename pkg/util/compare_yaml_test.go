@@ -0,0 +1,23 @@
+package util
+
+import (
+	"testing"
+
+	config_util "github.com/prometheus/common/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareYAML(t *testing.T) {
+	type config struct {
+		Username string             `yaml:"username"`
+		Password config_util.Secret `yaml:"password"`
+	}
+
+	a := config{Username: "admin", Password: "secret"}
+	require.True(t, CompareYAML(a, a))
+	require.False(t, CompareYAML(a, config{Username: "root", Password: "secret"}))
+
+	// Secrets marshal to "<secret>", but changing only a secret must still be
+	// detected.
+	require.False(t, CompareYAML(a, config{Username: "admin", Password: "rotated"}))
+}
@@ -2,8 +2,10 @@ package util
 
 import (
 	"errors"
+	"net/url"
 	"regexp"
 	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v2"
 )
@@ -123,3 +125,103 @@ func UnmarshalYAMLMerged(bb []byte, vv ...interface{}) error {
 }
 
 var notFoundErrRegex = regexp.MustCompile(`^(line \d+: field .* not found) in type .*$`)
+
+// RedactedSecret replaces secrets in redacted YAML.
+const RedactedSecret = "<secret>"
+
+// RedactYAML redacts the secrets of the YAML document bb. typed must be bb
+// after unmarshaling it into its Go types and marshaling it again, which
+// replaces the values of secret types with RedactedSecret. Every value of bb
+// is replaced with RedactedSecret when typed holds RedactedSecret at the same
+// path, so bb keeps its layout and only loses its secrets.
+//
+// Additionally, the values of headers maps are redacted, as headers often
+// hold credentials, and passwords in URLs are replaced with "xxxxx".
+func RedactYAML(bb, typed []byte) ([]byte, error) {
+	var ms, typedMS yaml.MapSlice
+	if err := yaml.Unmarshal(bb, &ms); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(typed, &typedMS); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(redactValue(ms, typedMS))
+}
+
+// redactValue redacts v. typed is the value at the same path in the typed
+// document, or nil if it doesn't have one.
+func redactValue(v, typed interface{}) interface{} {
+	switch v := v.(type) {
+	case yaml.MapSlice:
+		typedMS, _ := typed.(yaml.MapSlice)
+		for i, item := range v {
+			if item.Key == "headers" {
+				v[i].Value = redactHeaders(item.Value)
+				continue
+			}
+			v[i].Value = redactValue(item.Value, lookupKey(typedMS, item.Key))
+		}
+		return v
+	case []interface{}:
+		typedList, _ := typed.([]interface{})
+		for i, item := range v {
+			var typedItem interface{}
+			if i < len(typedList) {
+				typedItem = typedList[i]
+			}
+			v[i] = redactValue(item, typedItem)
+		}
+		return v
+	case nil:
+		return v
+	case string:
+		if v != "" && typed == RedactedSecret {
+			return RedactedSecret
+		}
+		return redactURL(v)
+	default:
+		if typed == RedactedSecret {
+			return RedactedSecret
+		}
+		return v
+	}
+}
+
+func lookupKey(ms yaml.MapSlice, key interface{}) interface{} {
+	for _, item := range ms {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// redactHeaders redacts the values of a headers map.
+func redactHeaders(v interface{}) interface{} {
+	ms, ok := v.(yaml.MapSlice)
+	if !ok {
+		return v
+	}
+	for i, item := range ms {
+		if item.Value != nil && item.Value != "" {
+			ms[i].Value = RedactedSecret
+		}
+	}
+	return ms
+}
+
+// redactURL replaces the password of s with "xxxxx" if s is a URL with a
+// password. Other strings are returned unchanged.
+func redactURL(s string) string {
+	if !strings.Contains(s, "://") {
+		return s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	if _, ok := u.User.Password(); !ok {
+		return s
+	}
+	return u.Redacted()
+}